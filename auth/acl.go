@@ -17,6 +17,7 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"strings"
@@ -36,6 +37,155 @@ type ACL struct {
 type Grantee struct {
 	Permission types.Permission
 	Access     string
+	Type       types.Type `json:",omitempty"`
+}
+
+const (
+	// AllUsersGroup grants access to every requester
+	AllUsersGroup = "http://acs.amazonaws.com/groups/global/AllUsers"
+	// AuthenticatedUsersGroup grants access to any authenticated account
+	AuthenticatedUsersGroup = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+)
+
+// Grants holds the x-amz-grant-* header values of a request
+type Grants struct {
+	FullControl *string
+	Read        *string
+	ReadACP     *string
+	Write       *string
+	WriteACP    *string
+}
+
+func (g Grants) isEmpty() bool {
+	return getString(g.FullControl) == "" && getString(g.Read) == "" &&
+		getString(g.ReadACP) == "" && getString(g.Write) == "" &&
+		getString(g.WriteACP) == ""
+}
+
+// grantees expands the grant header values into grantees and returns
+// them along with the accounts referenced
+func (g Grants) grantees() ([]Grantee, []string) {
+	grantees := []Grantee{}
+	accs := []string{}
+
+	for _, grt := range []struct {
+		list       *string
+		permission types.Permission
+	}{
+		{g.FullControl, types.PermissionFullControl},
+		{g.Read, types.PermissionRead},
+		{g.ReadACP, types.PermissionReadAcp},
+		{g.Write, types.PermissionWrite},
+		{g.WriteACP, types.PermissionWriteAcp},
+	} {
+		if getString(grt.list) == "" {
+			continue
+		}
+		for _, str := range splitUnique(*grt.list, ",") {
+			grantees = append(grantees, Grantee{Access: str, Permission: grt.permission})
+			accs = append(accs, str)
+		}
+	}
+
+	return grantees, accs
+}
+
+func getString(str *string) string {
+	if str == nil {
+		return ""
+	}
+	return *str
+}
+
+// IsValidBucketCannedACL checks the canned acl can be applied to a bucket
+func IsValidBucketCannedACL(acl string) bool {
+	switch types.BucketCannedACL(acl) {
+	case types.BucketCannedACLPrivate,
+		types.BucketCannedACLPublicRead,
+		types.BucketCannedACLPublicReadWrite,
+		types.BucketCannedACLAuthenticatedRead:
+		return true
+	}
+	return false
+}
+
+// IsValidObjectCannedACL checks the canned acl can be applied to an object
+func IsValidObjectCannedACL(acl string) bool {
+	return IsValidBucketCannedACL(acl) ||
+		types.ObjectCannedACL(acl) == types.ObjectCannedACLBucketOwnerFullControl
+}
+
+// ExpandCannedACL returns the grants implied by a canned acl. The owner
+// always keeps full control, bucketOwner is only used for the
+// bucket-owner-full-control object acl.
+func ExpandCannedACL(acl types.BucketCannedACL, owner, bucketOwner string) []Grantee {
+	grantees := []Grantee{{Access: owner, Permission: types.PermissionFullControl}}
+
+	switch acl {
+	case types.BucketCannedACLPublicRead:
+		grantees = append(grantees,
+			Grantee{Access: AllUsersGroup, Type: types.TypeGroup, Permission: types.PermissionRead})
+	case types.BucketCannedACLPublicReadWrite:
+		grantees = append(grantees,
+			Grantee{Access: AllUsersGroup, Type: types.TypeGroup, Permission: types.PermissionRead},
+			Grantee{Access: AllUsersGroup, Type: types.TypeGroup, Permission: types.PermissionWrite})
+	case types.BucketCannedACLAuthenticatedRead:
+		grantees = append(grantees,
+			Grantee{Access: AuthenticatedUsersGroup, Type: types.TypeGroup, Permission: types.PermissionRead})
+	case types.BucketCannedACL(types.ObjectCannedACLBucketOwnerFullControl):
+		if bucketOwner != "" && bucketOwner != owner {
+			grantees = append(grantees,
+				Grantee{Access: bucketOwner, Permission: types.PermissionFullControl})
+		}
+	}
+
+	return grantees
+}
+
+// NewObjectACL builds the acl of a new object from the canned acl or grant
+// headers sent with the upload. It returns false if neither was sent and
+// the object keeps the default acl.
+func NewObjectACL(owner, bucketOwner string, canned types.ObjectCannedACL, grants Grants) (ACL, bool) {
+	acl := ACL{Owner: owner}
+	if canned != "" {
+		acl.ACL = types.BucketCannedACL(canned)
+		acl.Grantees = ExpandCannedACL(acl.ACL, owner, bucketOwner)
+		return acl, true
+	}
+	if grants.isEmpty() {
+		return acl, false
+	}
+
+	acl.Grantees, _ = grants.grantees()
+	return acl, true
+}
+
+// ValidateObjectACLRequest checks the canned acl and grant headers sent
+// with an object upload
+func ValidateObjectACLRequest(canned string, grants Grants, iam IAMService) error {
+	if canned == "" && grants.isEmpty() {
+		return nil
+	}
+	if canned != "" && !grants.isEmpty() {
+		return s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
+	if canned != "" {
+		if !IsValidObjectCannedACL(canned) {
+			return s3err.GetAPIError(s3err.ErrInvalidRequest)
+		}
+		return nil
+	}
+
+	_, accs := grants.grantees()
+	accList, err := CheckIfAccountsExist(accs, iam)
+	if err != nil {
+		return err
+	}
+	if len(accList) > 0 {
+		return fmt.Errorf("accounts does not exist: %s", strings.Join(accList, ", "))
+	}
+
+	return nil
 }
 
 type GetBucketAclOutput struct {
@@ -43,6 +193,14 @@ type GetBucketAclOutput struct {
 	AccessControlList AccessControlList
 }
 
+// GetObjectAclOutput is encoded as the AccessControlPolicy document
+// returned by GetObjectAcl
+type GetObjectAclOutput struct {
+	XMLName           xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ AccessControlPolicy"`
+	Owner             *types.Owner
+	AccessControlList AccessControlList
+}
+
 type AccessControlList struct {
 	Grants []types.Grant `xml:"Grant"`
 }
@@ -73,6 +231,10 @@ func ParseACLOutput(data []byte) (GetBucketAclOutput, error) {
 
 	for _, elem := range acl.Grantees {
 		acs := elem.Access
+		if elem.Type == types.TypeGroup {
+			grants = append(grants, types.Grant{Grantee: &types.Grantee{Type: types.TypeGroup, URI: &acs}, Permission: elem.Permission})
+			continue
+		}
 		grants = append(grants, types.Grant{Grantee: &types.Grantee{ID: &acs}, Permission: elem.Permission})
 	}
 
@@ -86,10 +248,29 @@ func ParseACLOutput(data []byte) (GetBucketAclOutput, error) {
 	}, nil
 }
 
+func ParseObjectACLOutput(data []byte) (GetObjectAclOutput, error) {
+	acl, err := ParseACLOutput(data)
+	if err != nil {
+		return GetObjectAclOutput{}, err
+	}
+
+	return GetObjectAclOutput{
+		Owner:             acl.Owner,
+		AccessControlList: acl.AccessControlList,
+	}, nil
+}
+
 func UpdateACL(input *s3.PutBucketAclInput, acl ACL, iam IAMService) ([]byte, error) {
+	return updateACL(input, acl, acl.Owner, iam)
+}
+
+func updateACL(input *s3.PutBucketAclInput, acl ACL, bucketOwner string, iam IAMService) ([]byte, error) {
 	if input == nil {
 		return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
 	}
+	if input.AccessControlPolicy == nil || input.AccessControlPolicy.Owner == nil || input.AccessControlPolicy.Owner.ID == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
 	if acl.Owner != *input.AccessControlPolicy.Owner.ID {
 		return nil, s3err.GetAPIError(s3err.ErrAccessDenied)
 	}
@@ -97,50 +278,34 @@ func UpdateACL(input *s3.PutBucketAclInput, acl ACL, iam IAMService) ([]byte, er
 	// if the ACL is specified, set the ACL, else replace the grantees
 	if input.ACL != "" {
 		acl.ACL = input.ACL
-		acl.Grantees = []Grantee{}
+		acl.Grantees = ExpandCannedACL(input.ACL, acl.Owner, bucketOwner)
 	} else {
 		grantees := []Grantee{}
 		accs := []string{}
 
+		grants := Grants{
+			FullControl: input.GrantFullControl,
+			Read:        input.GrantRead,
+			ReadACP:     input.GrantReadACP,
+			Write:       input.GrantWrite,
+			WriteACP:    input.GrantWriteACP,
+		}
 		if input.GrantRead != nil || input.GrantReadACP != nil || input.GrantFullControl != nil || input.GrantWrite != nil || input.GrantWriteACP != nil {
-			fullControlList, readList, readACPList, writeList, writeACPList := []string{}, []string{}, []string{}, []string{}, []string{}
-
-			if input.GrantFullControl != nil && *input.GrantFullControl != "" {
-				fullControlList = splitUnique(*input.GrantFullControl, ",")
-				for _, str := range fullControlList {
-					grantees = append(grantees, Grantee{Access: str, Permission: "FULL_CONTROL"})
-				}
-			}
-			if input.GrantRead != nil && *input.GrantRead != "" {
-				readList = splitUnique(*input.GrantRead, ",")
-				for _, str := range readList {
-					grantees = append(grantees, Grantee{Access: str, Permission: "READ"})
-				}
-			}
-			if input.GrantReadACP != nil && *input.GrantReadACP != "" {
-				readACPList = splitUnique(*input.GrantReadACP, ",")
-				for _, str := range readACPList {
-					grantees = append(grantees, Grantee{Access: str, Permission: "READ_ACP"})
-				}
-			}
-			if input.GrantWrite != nil && *input.GrantWrite != "" {
-				writeList = splitUnique(*input.GrantWrite, ",")
-				for _, str := range writeList {
-					grantees = append(grantees, Grantee{Access: str, Permission: "WRITE"})
-				}
-			}
-			if input.GrantWriteACP != nil && *input.GrantWriteACP != "" {
-				writeACPList = splitUnique(*input.GrantWriteACP, ",")
-				for _, str := range writeACPList {
-					grantees = append(grantees, Grantee{Access: str, Permission: "WRITE_ACP"})
-				}
-			}
-
-			accs = append(append(append(append(fullControlList, readList...), writeACPList...), readACPList...), writeList...)
+			grantees, accs = grants.grantees()
 		} else {
 			cache := make(map[string]bool)
 			for _, grt := range input.AccessControlPolicy.Grants {
-				if grt.Grantee == nil || grt.Grantee.ID == nil || grt.Permission == "" {
+				if grt.Grantee == nil || grt.Permission == "" {
+					return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+				}
+				if grt.Grantee.URI != nil {
+					if *grt.Grantee.URI != AllUsersGroup && *grt.Grantee.URI != AuthenticatedUsersGroup {
+						return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+					}
+					grantees = append(grantees, Grantee{Access: *grt.Grantee.URI, Type: types.TypeGroup, Permission: grt.Permission})
+					continue
+				}
+				if grt.Grantee.ID == nil {
 					return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
 				}
 				grantees = append(grantees, Grantee{Access: *grt.Grantee.ID, Permission: grt.Permission})
@@ -172,6 +337,25 @@ func UpdateACL(input *s3.PutBucketAclInput, acl ACL, iam IAMService) ([]byte, er
 	return result, nil
 }
 
+// UpdateObjectACL applies the object acl request to the current object acl
+// the same way as for buckets
+func UpdateObjectACL(input *s3.PutObjectAclInput, acl ACL, bucketOwner string, iam IAMService) ([]byte, error) {
+	if input == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
+
+	return updateACL(&s3.PutBucketAclInput{
+		Bucket:              input.Bucket,
+		ACL:                 types.BucketCannedACL(input.ACL),
+		AccessControlPolicy: input.AccessControlPolicy,
+		GrantFullControl:    input.GrantFullControl,
+		GrantRead:           input.GrantRead,
+		GrantReadACP:        input.GrantReadACP,
+		GrantWrite:          input.GrantWrite,
+		GrantWriteACP:       input.GrantWriteACP,
+	}, acl, bucketOwner, iam)
+}
+
 func CheckIfAccountsExist(accs []string, iam IAMService) ([]string, error) {
 	result := []string{}
 
@@ -207,32 +391,20 @@ func splitUnique(s, divider string) []string {
 }
 
 func verifyACL(acl ACL, access string, permission types.Permission) error {
-	if acl.ACL != "" {
-		if (permission == "READ" || permission == "READ_ACP") && (acl.ACL != "public-read" && acl.ACL != "public-read-write") {
-			return s3err.GetAPIError(s3err.ErrAccessDenied)
-		}
-		if (permission == "WRITE" || permission == "WRITE_ACP") && acl.ACL != "public-read-write" {
-			return s3err.GetAPIError(s3err.ErrAccessDenied)
-		}
-
+	grantees := acl.Grantees
+	if acl.ACL != "" && len(grantees) == 0 {
+		// canned acls stored before they were expanded into grants
+		grantees = ExpandCannedACL(acl.ACL, acl.Owner, acl.Owner)
+	}
+	if len(grantees) == 0 {
 		return nil
-	} else {
-		if len(acl.Grantees) == 0 {
-			return nil
-		}
-		grantee := Grantee{Access: access, Permission: permission}
-		granteeFullCtrl := Grantee{Access: access, Permission: "FULL_CONTROL"}
-
-		isFound := false
+	}
 
-		for _, grt := range acl.Grantees {
-			if grt == grantee || grt == granteeFullCtrl {
-				isFound = true
-				break
-			}
+	for _, grt := range grantees {
+		if grt.Permission != permission && grt.Permission != types.PermissionFullControl {
+			continue
 		}
-
-		if isFound {
+		if grt.matches(access) {
 			return nil
 		}
 	}
@@ -240,12 +412,27 @@ func verifyACL(acl ACL, access string, permission types.Permission) error {
 	return s3err.GetAPIError(s3err.ErrAccessDenied)
 }
 
+// matches checks if the grantee covers the account
+func (g Grantee) matches(access string) bool {
+	if g.Type == types.TypeGroup {
+		switch g.Access {
+		case AllUsersGroup:
+			return true
+		case AuthenticatedUsersGroup:
+			return access != ""
+		}
+		return false
+	}
+
+	return g.Access == access
+}
+
 func MayCreateBucket(acct Account, isRoot bool) error {
 	if isRoot {
 		return nil
 	}
 
-	if acct.Role == RoleUser {
+	if acct.Role == RoleUser || acct.IsAnonymous() {
 		return s3err.GetAPIError(s3err.ErrAccessDenied)
 	}
 
@@ -254,7 +441,7 @@ func MayCreateBucket(acct Account, isRoot bool) error {
 
 func IsAdminOrOwner(acct Account, isRoot bool, acl ACL) error {
 	// Owner check
-	if acct.Access == acl.Owner {
+	if !acct.IsAnonymous() && acct.Access == acl.Owner {
 		return nil
 	}
 
@@ -272,6 +459,33 @@ func IsAdminOrOwner(acct Account, isRoot bool, acl ACL) error {
 	return s3err.GetAPIError(s3err.ErrAccessDenied)
 }
 
+// VerifyUploadAccess verifies that the account of the request may upload
+// parts to, complete or abort a multipart upload. Only the initiator of
+// the upload, the bucket owner, and the root and admin accounts have
+// access. Uploads without a recorded initiator, and requests without an
+// account, are not restricted.
+func VerifyUploadAccess(ctx context.Context, initiator, bucketOwner string) error {
+	acct, ok := ctx.Value("account").(Account)
+	if !ok || initiator == "" {
+		return nil
+	}
+
+	if isRoot, _ := ctx.Value("isRoot").(bool); isRoot {
+		return nil
+	}
+
+	if acct.Role == RoleAdmin {
+		return nil
+	}
+
+	if !acct.IsAnonymous() &&
+		(acct.Access == initiator || acct.Access == bucketOwner) {
+		return nil
+	}
+
+	return s3err.GetAPIError(s3err.ErrAccessDenied)
+}
+
 type AccessOptions struct {
 	Acl           ACL
 	AclPermission types.Permission
@@ -295,7 +509,7 @@ func VerifyAccess(ctx context.Context, be backend.Backend, opts AccessOptions) e
 	if opts.Acc.Role == RoleAdmin {
 		return nil
 	}
-	if opts.Acc.Access == opts.Acl.Owner {
+	if !opts.Acc.IsAnonymous() && opts.Acc.Access == opts.Acl.Owner {
 		return nil
 	}
 
@@ -304,21 +518,75 @@ func VerifyAccess(ctx context.Context, be backend.Backend, opts AccessOptions) e
 		return policyErr
 	}
 
+	publicAccessBlock, err := getPublicAccessBlock(ctx, be, opts.Bucket)
+	if err != nil {
+		return err
+	}
+	acl := opts.Acl
+	if publicAccessBlock.IgnorePublicAcls {
+		acl = acl.withoutPublicGrants()
+	}
+
 	// If bucket policy is not set and the ACL is default, only the owner has access
-	if errors.Is(policyErr, s3err.GetAPIError(s3err.ErrNoSuchBucketPolicy)) && opts.Acl.ACL == "" && len(opts.Acl.Grantees) == 0 {
+	if errors.Is(policyErr, s3err.GetAPIError(s3err.ErrNoSuchBucketPolicy)) && acl.ACL == "" && len(acl.Grantees) == 0 {
 		return s3err.GetAPIError(s3err.ErrAccessDenied)
 	}
 
-	if err := verifyBucketPolicy(policy, opts.Acc.Access, opts.Bucket, opts.Object, opts.Action); err != nil {
+	if err := verifyBucketPolicy(policy, opts.Acc, opts.Bucket, opts.Object, opts.Action, requestContext(ctx), publicAccessBlock.RestrictPublicBuckets); err != nil {
 		return err
 	}
-	if err := verifyACL(opts.Acl, opts.Acc.Access, opts.AclPermission); err != nil {
+	if err := verifyACL(acl, opts.Acc.Access, opts.AclPermission); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// VerifyObjectAccess verifies the access to an object. Access granted on
+// the bucket applies to all of its objects, otherwise the object owner
+// and the object acl grants are checked.
+func VerifyObjectAccess(ctx context.Context, be backend.Backend, opts AccessOptions) error {
+	err := VerifyAccess(ctx, be, opts)
+	if err == nil || !errors.Is(err, s3err.GetAPIError(s3err.ErrAccessDenied)) {
+		return err
+	}
+	if opts.Readonly && (opts.AclPermission == types.PermissionWrite || opts.AclPermission == types.PermissionWriteAcp) {
+		return err
+	}
+
+	data, aclErr := be.GetObjectAcl(ctx, &s3.GetObjectAclInput{
+		Bucket: &opts.Bucket,
+		Key:    &opts.Object,
+	})
+	if aclErr != nil {
+		return err
+	}
+	objAcl, aclErr := ParseACL(data)
+	if aclErr != nil {
+		return err
+	}
+
+	if objAcl.Owner != "" && objAcl.Owner == opts.Acc.Access {
+		return nil
+	}
+	publicAccessBlock, aclErr := getPublicAccessBlock(ctx, be, opts.Bucket)
+	if aclErr != nil {
+		return err
+	}
+	if publicAccessBlock.IgnorePublicAcls {
+		objAcl = objAcl.withoutPublicGrants()
+	}
+	// the default object acl grants nothing beyond the owner
+	if objAcl.ACL == "" && len(objAcl.Grantees) == 0 {
+		return err
+	}
+	if verifyACL(objAcl, opts.Acc.Access, opts.AclPermission) == nil {
+		return nil
+	}
+
+	return err
+}
+
 func VerifyObjectCopyAccess(ctx context.Context, be backend.Backend, copySource string, opts AccessOptions) error {
 	if opts.IsRoot {
 		return nil
@@ -37,18 +37,35 @@ func (bp *BucketPolicy) Validate(bucket string, iam IAMService) error {
 	return nil
 }
 
-func (bp *BucketPolicy) isAllowed(principal string, action Action, resource string) bool {
+// An explicit deny overrides any allow statement matching the request.
+// Public statements are skipped if the bucket restricts public access.
+func (bp *BucketPolicy) isAllowed(acct Account, action Action, resource string, req RequestContext, restrictPublic bool) bool {
+	allowed := false
 	for _, statement := range bp.Statement {
-		if statement.findMatch(principal, action, resource) {
+		if restrictPublic && statement.isPublic() {
+			continue
+		}
+		if statement.findMatch(acct, action, resource, req) {
 			switch statement.Effect {
 			case BucketPolicyAccessTypeAllow:
-				return true
+				allowed = true
 			case BucketPolicyAccessTypeDeny:
 				return false
 			}
 		}
 	}
 
+	return allowed
+}
+
+// Checks if any of the statements grants public access
+func (bp *BucketPolicy) isPublic() bool {
+	for _, statement := range bp.Statement {
+		if statement.isPublic() {
+			return true
+		}
+	}
+
 	return false
 }
 
@@ -57,6 +74,7 @@ type BucketPolicyItem struct {
 	Principals Principals             `json:"Principal"`
 	Actions    Actions                `json:"Action"`
 	Resources  Resources              `json:"Resource"`
+	Conditions Conditions             `json:"Condition,omitempty"`
 }
 
 func (bpi *BucketPolicyItem) Validate(bucket string, iam IAMService) error {
@@ -69,6 +87,9 @@ func (bpi *BucketPolicyItem) Validate(bucket string, iam IAMService) error {
 	if err := bpi.Resources.Validate(bucket); err != nil {
 		return err
 	}
+	if err := bpi.Conditions.Validate(); err != nil {
+		return err
+	}
 
 	containsObjectAction := bpi.Resources.ContainsObjectPattern()
 	containsBucketAction := bpi.Resources.ContainsBucketPattern()
@@ -86,14 +107,28 @@ func (bpi *BucketPolicyItem) Validate(bucket string, iam IAMService) error {
 	return nil
 }
 
-func (bpi *BucketPolicyItem) findMatch(principal string, action Action, resource string) bool {
-	if bpi.Principals.Contains(principal) && bpi.Actions.FindMatch(action) && bpi.Resources.FindMatch(resource) {
+func (bpi *BucketPolicyItem) findMatch(acct Account, action Action, resource string, req RequestContext) bool {
+	if bpi.Principals.ContainsAccount(acct) && bpi.Actions.FindMatch(action) && bpi.Resources.FindMatch(resource) && bpi.Conditions.Match(req) {
 		return true
 	}
 
 	return false
 }
 
+// A statement allowing any principal is public, unless it is limited
+// to source ip addresses
+func (bpi *BucketPolicyItem) isPublic() bool {
+	if bpi.Effect != BucketPolicyAccessTypeAllow {
+		return false
+	}
+	if _, ok := bpi.Principals["*"]; !ok {
+		return false
+	}
+	_, limited := bpi.Conditions[ConditionIpAddress]
+
+	return !limited
+}
+
 func getMalformedPolicyError(err error) error {
 	return s3err.APIError{
 		Code:           "MalformedPolicy",
@@ -115,7 +150,7 @@ func ValidatePolicyDocument(policyBin []byte, bucket string, iam IAMService) err
 	return nil
 }
 
-func verifyBucketPolicy(policy []byte, access, bucket, object string, action Action) error {
+func verifyBucketPolicy(policy []byte, acct Account, bucket, object string, action Action, req RequestContext, restrictPublic bool) error {
 	// If bucket policy is not set
 	if policy == nil {
 		return nil
@@ -131,7 +166,7 @@ func verifyBucketPolicy(policy []byte, access, bucket, object string, action Act
 		resource += "/" + object
 	}
 
-	if !bucketPolicy.isAllowed(access, action, resource) {
+	if !bucketPolicy.isAllowed(acct, action, resource, req, restrictPublic) {
 		return s3err.GetAPIError(s3err.ErrAccessDenied)
 	}
 
@@ -32,6 +32,8 @@ const (
 	PutBucketPolicyAction                  Action = "s3:PutBucketPolicy"
 	GetBucketPolicyAction                  Action = "s3:GetBucketPolicy"
 	DeleteBucketPolicyAction               Action = "s3:DeleteBucketPolicy"
+	PutBucketNotificationAction            Action = "s3:PutBucketNotification"
+	GetBucketNotificationAction            Action = "s3:GetBucketNotification"
 	AbortMultipartUploadAction             Action = "s3:AbortMultipartUpload"
 	ListMultipartUploadPartsAction         Action = "s3:ListMultipartUploadParts"
 	ListBucketMultipartUploadsAction       Action = "s3:ListBucketMultipartUploads"
@@ -56,6 +58,11 @@ const (
 	GetObjectRetentionAction               Action = "s3:GetObjectRetention"
 	PutObjectRetentionAction               Action = "s3:PutObjectRetention"
 	BypassGovernanceRetentionAction        Action = "s3:BypassGovernanceRetention"
+	PutBucketPublicAccessBlockAction       Action = "s3:PutBucketPublicAccessBlock"
+	GetBucketPublicAccessBlockAction       Action = "s3:GetBucketPublicAccessBlock"
+	GetBucketLocationAction                Action = "s3:GetBucketLocation"
+	PutBucketLoggingAction                 Action = "s3:PutBucketLogging"
+	GetBucketLoggingAction                 Action = "s3:GetBucketLogging"
 	AllActions                             Action = "s3:*"
 )
 
@@ -69,6 +76,8 @@ var supportedActionList = map[Action]struct{}{
 	PutBucketPolicyAction:                  {},
 	GetBucketPolicyAction:                  {},
 	DeleteBucketPolicyAction:               {},
+	PutBucketNotificationAction:            {},
+	GetBucketNotificationAction:            {},
 	AbortMultipartUploadAction:             {},
 	ListMultipartUploadPartsAction:         {},
 	ListBucketMultipartUploadsAction:       {},
@@ -92,6 +101,11 @@ var supportedActionList = map[Action]struct{}{
 	GetObjectRetentionAction:               {},
 	PutObjectRetentionAction:               {},
 	BypassGovernanceRetentionAction:        {},
+	PutBucketPublicAccessBlockAction:       {},
+	GetBucketPublicAccessBlockAction:       {},
+	GetBucketLocationAction:                {},
+	PutBucketLoggingAction:                 {},
+	GetBucketLoggingAction:                 {},
 	AllActions:                             {},
 }
 
@@ -0,0 +1,257 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// RequestContext holds the request properties bucket policy conditions
+// are evaluated against
+type RequestContext struct {
+	SourceIP        net.IP
+	SecureTransport bool
+	// Prefix is only set for listing requests
+	Prefix *string
+}
+
+// requestContext returns the request context stored by the middlewares,
+// conditions depending on missing values won't match
+func requestContext(ctx context.Context) RequestContext {
+	req, _ := ctx.Value("requestContext").(RequestContext)
+	return req
+}
+
+type ConditionOperator string
+
+const (
+	ConditionIpAddress    ConditionOperator = "IpAddress"
+	ConditionNotIpAddress ConditionOperator = "NotIpAddress"
+	ConditionBool         ConditionOperator = "Bool"
+	ConditionStringEquals ConditionOperator = "StringEquals"
+	ConditionStringLike   ConditionOperator = "StringLike"
+)
+
+// Condition keys are case insensitive, they are stored in lower case
+const (
+	ConditionKeySourceIp        = "aws:sourceip"
+	ConditionKeySecureTransport = "aws:securetransport"
+	ConditionKeyPrefix          = "s3:prefix"
+)
+
+// supportedConditions maps the condition operators to the condition keys
+// they can be used with
+var supportedConditions = map[ConditionOperator]map[string]struct{}{
+	ConditionIpAddress:    {ConditionKeySourceIp: {}},
+	ConditionNotIpAddress: {ConditionKeySourceIp: {}},
+	ConditionBool:         {ConditionKeySecureTransport: {}},
+	ConditionStringEquals: {ConditionKeyPrefix: {}},
+	ConditionStringLike:   {ConditionKeyPrefix: {}},
+}
+
+type ConditionValues []string
+
+// Override UnmarshalJSON method to decode both list and single values
+// of strings or booleans
+func (cv *ConditionValues) UnmarshalJSON(data []byte) error {
+	var values []any
+	if err := json.Unmarshal(data, &values); err != nil {
+		var value any
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		values = []any{value}
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("condition values can't be empty")
+	}
+
+	*cv = make(ConditionValues, 0, len(values))
+	for _, v := range values {
+		switch val := v.(type) {
+		case string:
+			*cv = append(*cv, val)
+		case bool:
+			*cv = append(*cv, fmt.Sprint(val))
+		default:
+			return fmt.Errorf("invalid condition value: %v", v)
+		}
+	}
+
+	return nil
+}
+
+type Conditions map[ConditionOperator]map[string]ConditionValues
+
+// Override UnmarshalJSON method to lower case the condition keys
+func (c *Conditions) UnmarshalJSON(data []byte) error {
+	var conditions map[ConditionOperator]map[string]ConditionValues
+	if err := json.Unmarshal(data, &conditions); err != nil {
+		return err
+	}
+
+	*c = make(Conditions, len(conditions))
+	for op, keys := range conditions {
+		(*c)[op] = make(map[string]ConditionValues, len(keys))
+		for key, values := range keys {
+			(*c)[op][strings.ToLower(key)] = values
+		}
+	}
+
+	return nil
+}
+
+// Validates the condition operators, keys and values
+func (c Conditions) Validate() error {
+	for op, keys := range c {
+		supportedKeys, ok := supportedConditions[op]
+		if !ok {
+			return fmt.Errorf("unsupported condition operator: %v", op)
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("condition keys can't be empty")
+		}
+
+		for key, values := range keys {
+			if _, ok := supportedKeys[key]; !ok {
+				return fmt.Errorf("unsupported condition key '%v' for operator %v", key, op)
+			}
+
+			for _, value := range values {
+				switch op {
+				case ConditionIpAddress, ConditionNotIpAddress:
+					if _, err := parseCIDR(value); err != nil {
+						return fmt.Errorf("invalid ip address condition value: %v", value)
+					}
+				case ConditionBool:
+					if value != "true" && value != "false" {
+						return fmt.Errorf("invalid bool condition value: %v", value)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Checks if the request satisfies all the conditions. Any of the values
+// may match a condition key, but all the keys of all the operators have
+// to match.
+func (c Conditions) Match(req RequestContext) bool {
+	for op, keys := range c {
+		for key, values := range keys {
+			if !matchCondition(op, key, values, req) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func matchCondition(op ConditionOperator, key string, values ConditionValues, req RequestContext) bool {
+	switch op {
+	case ConditionIpAddress, ConditionNotIpAddress:
+		if req.SourceIP == nil {
+			// negated operators match missing keys
+			return op == ConditionNotIpAddress
+		}
+		found := false
+		for _, value := range values {
+			ipNet, err := parseCIDR(value)
+			if err == nil && ipNet.Contains(req.SourceIP) {
+				found = true
+				break
+			}
+		}
+		return found == (op == ConditionIpAddress)
+	case ConditionBool:
+		for _, value := range values {
+			if value == fmt.Sprint(req.SecureTransport) {
+				return true
+			}
+		}
+		return false
+	case ConditionStringEquals, ConditionStringLike:
+		if req.Prefix == nil {
+			return false
+		}
+		for _, value := range values {
+			if op == ConditionStringEquals && value == *req.Prefix {
+				return true
+			}
+			if op == ConditionStringLike && wildcardMatch(value, *req.Prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return false
+}
+
+// parseCIDR parses the CIDR block, single ip addresses are treated as
+// a block of one address
+func parseCIDR(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip address: %v", value)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(value)
+	return ipNet, err
+}
+
+// wildcardMatch matches the string against the pattern, where '*' matches
+// any sequence of characters and '?' any single character
+func wildcardMatch(pattern, str string) bool {
+	p, s := 0, 0
+	starIdx, matchIdx := -1, 0
+	for s < len(str) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == str[s]):
+			p++
+			s++
+		case p < len(pattern) && pattern[p] == '*':
+			starIdx = p
+			matchIdx = s
+			p++
+		case starIdx != -1:
+			p = starIdx + 1
+			matchIdx++
+			s = matchIdx
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
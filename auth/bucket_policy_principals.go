@@ -17,6 +17,7 @@ package auth
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 type Principals map[string]struct{}
@@ -51,11 +52,11 @@ func (p *Principals) UnmarshalJSON(data []byte) error {
 	return err
 }
 
-// Converts Principals map to a slice, by omitting "*"
+// Converts Principals map to a slice, by omitting "*" and the groups
 func (p Principals) ToSlice() []string {
 	principals := []string{}
 	for p := range p {
-		if p == "*" {
+		if p == "*" || strings.HasPrefix(p, groupPrincipalPrefix) {
 			continue
 		}
 		principals = append(principals, p)
@@ -82,6 +83,44 @@ func (p Principals) Validate(iam IAMService) error {
 		return fmt.Errorf("user accounts don't exist: %v", accs)
 	}
 
+	return p.validateGroups(iam)
+}
+
+// validateGroups checks the groups referenced as principals exist
+func (p Principals) validateGroups(iam IAMService) error {
+	var names []string
+	for principal := range p {
+		if name, ok := strings.CutPrefix(principal, groupPrincipalPrefix); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	gs, ok := GetGroupService(iam)
+	if !ok {
+		return fmt.Errorf("groups are not supported by the iam service")
+	}
+	groups, err := gs.ListGroups()
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]struct{}, len(groups))
+	for _, group := range groups {
+		existing[group.Name] = struct{}{}
+	}
+	var missing []string
+	for _, name := range names {
+		if _, ok := existing[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("groups don't exist: %v", missing)
+	}
+
 	return nil
 }
 
@@ -95,3 +134,14 @@ func (p Principals) Contains(userAccess string) bool {
 	_, found := p[userAccess]
 	return found
 }
+
+// ContainsAccount checks if the account or any of its groups is
+// one of the principals
+func (p Principals) ContainsAccount(acct Account) bool {
+	for _, principal := range acct.principals() {
+		if p.Contains(principal) {
+			return true
+		}
+	}
+	return false
+}
@@ -36,6 +36,17 @@ type Account struct {
 	UserID    int    `json:"userID"`
 	GroupID   int    `json:"groupID"`
 	ProjectID int    `json:"projectID"`
+	// Quota is the storage quota in bytes of the buckets owned by
+	// the account, 0 means unlimited
+	Quota int64 `json:"quota,omitempty"`
+	// Groups are the groups the account is a member of
+	Groups []string `json:"groups,omitempty"`
+}
+
+// IsAnonymous checks if the account is the anonymous principal
+// unsigned requests are authorized as
+func (a Account) IsAnonymous() bool {
+	return a.Access == ""
 }
 
 // IAMService is the interface for all IAM service implementations
@@ -49,7 +60,28 @@ type IAMService interface {
 	Shutdown() error
 }
 
-var ErrNoSuchUser = errors.New("user not found")
+var (
+	ErrNoSuchUser = errors.New("user not found")
+	ErrUserExists = errors.New("account already exists")
+)
+
+// findService returns the first of the IAM service and the services it
+// wraps implementing T
+func findService[T any](svc IAMService) (T, bool) {
+	for svc != nil {
+		if s, ok := svc.(T); ok {
+			return s, true
+		}
+		u, ok := svc.(interface{ Unwrap() IAMService })
+		if !ok {
+			break
+		}
+		svc = u.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
 
 type Opts struct {
 	Dir                string
@@ -61,6 +93,14 @@ type Opts struct {
 	LDAPAccessAtr      string
 	LDAPSecretAtr      string
 	LDAPRoleAtr        string
+	LDAPUserIDAtr      string
+	LDAPGroupIDAtr     string
+	LDAPGroupAtr       string
+	LDAPGroupRoles     string
+	LDAPStartTLS       bool
+	LDAPSkipVerify     bool
+	LDAPPoolSize       int
+	LDAPReadOnly       bool
 	S3Access           string
 	S3Secret           string
 	S3Region           string
@@ -68,9 +108,23 @@ type Opts struct {
 	S3Endpoint         string
 	S3DisableSSlVerfiy bool
 	S3Debug            bool
+	VaultEndpoint      string
+	VaultToken         string
+	VaultRoleID        string
+	VaultSecretID      string
+	VaultMount         string
+	VaultPrefix        string
+	SQLDriver          string
+	SQLDSN             string
+	OIDCIssuer         string
+	OIDCClientID       string
+	OIDCAccountClaim   string
+	OIDCRoleClaim      string
+	OIDCRoleMapping    string
 	CacheDisable       bool
 	CacheTTL           int
 	CachePrune         int
+	CacheMaxEntries    int
 }
 
 func New(o *Opts) (IAMService, error) {
@@ -82,30 +136,67 @@ func New(o *Opts) (IAMService, error) {
 		svc, err = NewInternal(o.Dir)
 		fmt.Printf("initializing internal IAM with %q\n", o.Dir)
 	case o.LDAPServerURL != "":
-		svc, err = NewLDAPService(o.LDAPServerURL, o.LDAPBindDN, o.LDAPPassword,
-			o.LDAPQueryBase, o.LDAPAccessAtr, o.LDAPSecretAtr, o.LDAPRoleAtr,
-			o.LDAPObjClasses)
+		svc, err = NewLDAPService(LDAPConfig{
+			URL:              o.LDAPServerURL,
+			BindDN:           o.LDAPBindDN,
+			Password:         o.LDAPPassword,
+			QueryBase:        o.LDAPQueryBase,
+			ObjClasses:       o.LDAPObjClasses,
+			AccessAtr:        o.LDAPAccessAtr,
+			SecretAtr:        o.LDAPSecretAtr,
+			RoleAtr:          o.LDAPRoleAtr,
+			UserIDAtr:        o.LDAPUserIDAtr,
+			GroupIDAtr:       o.LDAPGroupIDAtr,
+			GroupAtr:         o.LDAPGroupAtr,
+			GroupRoleMapping: o.LDAPGroupRoles,
+			StartTLS:         o.LDAPStartTLS,
+			TLSSkipVerify:    o.LDAPSkipVerify,
+			PoolSize:         o.LDAPPoolSize,
+			ReadOnly:         o.LDAPReadOnly,
+		})
 		fmt.Printf("initializing LDAP IAM with %q\n", o.LDAPServerURL)
 	case o.S3Endpoint != "":
 		svc, err = NewS3(o.S3Access, o.S3Secret, o.S3Region, o.S3Bucket,
 			o.S3Endpoint, o.S3DisableSSlVerfiy, o.S3Debug)
 		fmt.Printf("initializing S3 IAM with '%v/%v'\n",
 			o.S3Endpoint, o.S3Bucket)
+	case o.VaultEndpoint != "":
+		svc, err = NewVaultService(VaultConfig{
+			Endpoint:     o.VaultEndpoint,
+			Token:        o.VaultToken,
+			RoleID:       o.VaultRoleID,
+			RoleSecretID: o.VaultSecretID,
+			Mount:        o.VaultMount,
+			Prefix:       o.VaultPrefix,
+		})
+		fmt.Printf("initializing Vault IAM with %q\n", o.VaultEndpoint)
+	case o.SQLDSN != "":
+		svc, err = NewSQLService(o.SQLDriver, o.SQLDSN)
+		fmt.Printf("initializing %v IAM\n", o.SQLDriver)
 	default:
 		// if no iam options selected, default to the single user mode
 		fmt.Println("No IAM service configured, enabling single account mode")
-		return IAMServiceSingle{}, nil
+		svc = IAMServiceSingle{}
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	if o.CacheDisable {
-		return svc, nil
+	if _, single := svc.(IAMServiceSingle); !single && !o.CacheDisable {
+		svc = NewCache(svc,
+			time.Duration(o.CacheTTL)*time.Second,
+			time.Duration(o.CachePrune)*time.Second, o.CacheMaxEntries)
+	}
+
+	if o.OIDCIssuer != "" {
+		svc, err = NewOIDCService(svc, o.OIDCIssuer, o.OIDCClientID,
+			o.OIDCAccountClaim, o.OIDCRoleClaim, o.OIDCRoleMapping)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("initializing OIDC identity federation with %q\n", o.OIDCIssuer)
 	}
 
-	return NewCache(svc,
-		time.Duration(o.CacheTTL)*time.Second,
-		time.Duration(o.CachePrune)*time.Second), nil
+	return svc, nil
 }
@@ -18,6 +18,7 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,6 +35,16 @@ type IAMCache struct {
 }
 
 var _ IAMService = &IAMCache{}
+var _ GroupService = &IAMCache{}
+var _ QuotaService = &IAMCache{}
+
+// CacheStats are the IAM cache lookup counters
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
 
 type item struct {
 	value Account
@@ -42,13 +53,33 @@ type item struct {
 
 type icache struct {
 	sync.RWMutex
-	expire time.Duration
-	items  map[string]item
+	expire     time.Duration
+	maxEntries int
+	items      map[string]item
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
 }
 
 func (i *icache) set(k string, v Account) {
+	// we need a copy of account to be able to store beyond the
+	// lifetime of the request, otherwise Fiber will reuse and corrupt
+	// these entries
+	k = strings.Clone(k)
 	cpy := v
+	cpy.Access = strings.Clone(v.Access)
+	cpy.Secret = strings.Clone(v.Secret)
+	cpy.Role = Role(strings.Clone(string(v.Role)))
+	cpy.Groups = nil
+	for _, g := range v.Groups {
+		cpy.Groups = append(cpy.Groups, strings.Clone(g))
+	}
+
 	i.Lock()
+	if _, ok := i.items[k]; !ok && i.maxEntries > 0 && len(i.items) >= i.maxEntries {
+		i.evict()
+	}
 	i.items[k] = item{
 		exp:   time.Now().Add(i.expire),
 		value: cpy,
@@ -56,13 +87,29 @@ func (i *icache) set(k string, v Account) {
 	i.Unlock()
 }
 
+// evict removes the entry closest to expiration to make room for a
+// new one, the caller must hold the lock
+func (i *icache) evict() {
+	var key string
+	var exp time.Time
+	for k, v := range i.items {
+		if key == "" || v.exp.Before(exp) {
+			key, exp = k, v.exp
+		}
+	}
+	delete(i.items, key)
+	i.evictions.Add(1)
+}
+
 func (i *icache) get(k string) (Account, bool) {
 	i.RLock()
 	v, ok := i.items[k]
 	i.RUnlock()
 	if !ok || !v.exp.After(time.Now()) {
+		i.misses.Add(1)
 		return Account{}, false
 	}
+	i.hits.Add(1)
 	return v.value, true
 }
 
@@ -72,6 +119,12 @@ func (i *icache) Delete(k string) {
 	i.Unlock()
 }
 
+func (i *icache) clear() {
+	i.Lock()
+	i.items = make(map[string]item)
+	i.Unlock()
+}
+
 func (i *icache) gcCache(ctx context.Context, interval time.Duration) {
 	for {
 		if ctx.Err() != nil {
@@ -100,13 +153,15 @@ func (i *icache) gcCache(ctx context.Context, interval time.Duration) {
 
 // NewCache initializes an IAM cache for the provided service. The expireTime
 // is the duration a cache entry can be valid, and the cleanupInterval is
-// how often to scan cache and cleanup expired entries.
-func NewCache(service IAMService, expireTime, cleanupInterval time.Duration) *IAMCache {
+// how often to scan cache and cleanup expired entries. The maxEntries
+// limits the number of cached accounts, 0 means unlimited.
+func NewCache(service IAMService, expireTime, cleanupInterval time.Duration, maxEntries int) *IAMCache {
 	i := &IAMCache{
 		service: service,
 		iamcache: &icache{
-			items:  make(map[string]item),
-			expire: expireTime,
+			items:      make(map[string]item),
+			expire:     expireTime,
+			maxEntries: maxEntries,
 		},
 	}
 
@@ -124,16 +179,7 @@ func (c *IAMCache) CreateAccount(account Account) error {
 		return err
 	}
 
-	// we need a copy of account to be able to store beyond the
-	// lifetime of the request, otherwise Fiber will reuse and corrupt
-	// these entries
-	acct := Account{
-		Access: strings.Clone(account.Access),
-		Secret: strings.Clone(account.Secret),
-		Role:   Role(strings.Clone(string(account.Role))),
-	}
-
-	c.iamcache.set(acct.Access, acct)
+	c.iamcache.set(account.Access, account)
 	return nil
 }
 
@@ -172,6 +218,127 @@ func (c *IAMCache) ListUserAccounts() ([]Account, error) {
 	return c.service.ListUserAccounts()
 }
 
+// CreateGroup is a passthrough to the underlying group service, the
+// cached members are invalidated to pick up the group role
+func (c *IAMCache) CreateGroup(group Group) error {
+	gs, ok := GetGroupService(c.service)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := gs.CreateGroup(group); err != nil {
+		return err
+	}
+
+	for _, member := range group.Members {
+		c.iamcache.Delete(member)
+	}
+	return nil
+}
+
+// DeleteGroup is a passthrough to the underlying group service, the
+// cache is invalidated as the members of the group are not known
+func (c *IAMCache) DeleteGroup(name string) error {
+	gs, ok := GetGroupService(c.service)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := gs.DeleteGroup(name); err != nil {
+		return err
+	}
+
+	c.iamcache.clear()
+	return nil
+}
+
+// ListGroups is a passthrough to the underlying group service
+func (c *IAMCache) ListGroups() ([]Group, error) {
+	gs, ok := GetGroupService(c.service)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return gs.ListGroups()
+}
+
+// AddGroupMember is a passthrough to the underlying group service, the
+// cached member is invalidated to pick up the group role
+func (c *IAMCache) AddGroupMember(name, access string) error {
+	gs, ok := GetGroupService(c.service)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := gs.AddGroupMember(name, access); err != nil {
+		return err
+	}
+
+	c.iamcache.Delete(access)
+	return nil
+}
+
+// RemoveGroupMember is a passthrough to the underlying group service,
+// the cached member is invalidated to drop the group role
+func (c *IAMCache) RemoveGroupMember(name, access string) error {
+	gs, ok := GetGroupService(c.service)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := gs.RemoveGroupMember(name, access); err != nil {
+		return err
+	}
+
+	c.iamcache.Delete(access)
+	return nil
+}
+
+// SetAccountQuota is a passthrough to the underlying quota service, the
+// cached account is invalidated to pick up the new quota
+func (c *IAMCache) SetAccountQuota(access string, quota int64) error {
+	qs, ok := GetQuotaService(c.service)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := qs.SetAccountQuota(access, quota); err != nil {
+		return err
+	}
+
+	c.iamcache.Delete(access)
+	return nil
+}
+
+// Invalidate removes the account from the cache, the next lookup gets
+// it from the underlying IAM service
+func (c *IAMCache) Invalidate(access string) {
+	c.iamcache.Delete(access)
+}
+
+// InvalidateAll empties the cache
+func (c *IAMCache) InvalidateAll() {
+	c.iamcache.clear()
+}
+
+// Stats returns the cache lookup counters
+func (c *IAMCache) Stats() CacheStats {
+	c.iamcache.RLock()
+	entries := len(c.iamcache.items)
+	c.iamcache.RUnlock()
+
+	return CacheStats{
+		Hits:      c.iamcache.hits.Load(),
+		Misses:    c.iamcache.misses.Load(),
+		Evictions: c.iamcache.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+// Unwrap returns the cached IAM service
+func (c *IAMCache) Unwrap() IAMService {
+	return c.service
+}
+
+// GetCache returns the IAM cache wrapped by the IAM service, if any
+func GetCache(svc IAMService) (*IAMCache, bool) {
+	return findService[*IAMCache](svc)
+}
+
 // Shutdown graceful termination of service
 func (c *IAMCache) Shutdown() error {
 	c.cancel()
@@ -0,0 +1,108 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// groupPrincipalPrefix prefixes the group names referenced as bucket
+// policy principals, e.g. "group/developers"
+const groupPrincipalPrefix = "group/"
+
+var (
+	ErrNoSuchGroup = errors.New("group not found")
+	ErrGroupExists = errors.New("group already exists")
+)
+
+// Group is a set of accounts sharing a role. The members inherit the
+// group role when it is more privileged than their own.
+type Group struct {
+	Name    string   `json:"name"`
+	Role    Role     `json:"role"`
+	Members []string `json:"members"`
+}
+
+// GroupService is implemented by the IAM services able to manage groups
+type GroupService interface {
+	CreateGroup(group Group) error
+	DeleteGroup(name string) error
+	ListGroups() ([]Group, error)
+	AddGroupMember(name, access string) error
+	RemoveGroupMember(name, access string) error
+}
+
+// GetGroupService returns the group service of the IAM service, looking
+// through the IAM services wrapping others
+func GetGroupService(svc IAMService) (GroupService, bool) {
+	return findService[GroupService](svc)
+}
+
+// IsValidGroupName checks the group name can be referenced as a
+// bucket policy principal
+func IsValidGroupName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "/*")
+}
+
+// inheritRoles sets the groups of the account and its role to the most
+// privileged of its own and its groups roles
+func inheritRoles(acct Account, groups map[string]Group) Account {
+	acct.Groups = nil
+	for _, name := range sortedGroupNames(groups) {
+		group := groups[name]
+		for _, member := range group.Members {
+			if member != acct.Access {
+				continue
+			}
+			acct.Groups = append(acct.Groups, group.Name)
+			if rolePriority(group.Role) > rolePriority(acct.Role) {
+				acct.Role = group.Role
+			}
+			break
+		}
+	}
+
+	return acct
+}
+
+// principals returns the bucket policy principals matching the account
+func (a Account) principals() []string {
+	principals := []string{a.Access}
+	for _, group := range a.Groups {
+		principals = append(principals, groupPrincipalPrefix+group)
+	}
+	return principals
+}
+
+// requestAccount returns the account authenticated for the request, so
+// its groups are known, if it has the access key
+func requestAccount(ctx context.Context, access string) Account {
+	if acct, ok := ctx.Value("account").(Account); ok && acct.Access == access {
+		return acct
+	}
+	return Account{Access: access}
+}
+
+func sortedGroupNames(groups map[string]Group) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
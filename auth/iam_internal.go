@@ -41,9 +41,12 @@ type UpdateAcctFunc func([]byte) ([]byte, error)
 // iAMConfig stores all internal IAM accounts
 type iAMConfig struct {
 	AccessAccounts map[string]Account `json:"accessAccounts"`
+	Groups         map[string]Group   `json:"groups,omitempty"`
 }
 
 var _ IAMService = &IAMServiceInternal{}
+var _ GroupService = &IAMServiceInternal{}
+var _ QuotaService = &IAMServiceInternal{}
 
 // NewInternal creates a new instance for the Internal IAM service
 func NewInternal(dir string) (*IAMServiceInternal, error) {
@@ -70,8 +73,10 @@ func (s *IAMServiceInternal) CreateAccount(account Account) error {
 
 		_, ok := conf.AccessAccounts[account.Access]
 		if ok {
-			return nil, fmt.Errorf("account already exists")
+			return nil, ErrUserExists
 		}
+		// the group membership is stored with the groups
+		account.Groups = nil
 		conf.AccessAccounts[account.Access] = account
 
 		b, err := json.Marshal(conf)
@@ -96,7 +101,7 @@ func (s *IAMServiceInternal) GetUserAccount(access string) (Account, error) {
 		return Account{}, ErrNoSuchUser
 	}
 
-	return acct, nil
+	return inheritRoles(acct, conf.Groups), nil
 }
 
 // DeleteUserAccount deletes the specified user account. Does not check if
@@ -109,6 +114,10 @@ func (s *IAMServiceInternal) DeleteUserAccount(access string) error {
 		}
 
 		delete(conf.AccessAccounts, access)
+		for name, group := range conf.Groups {
+			group.Members = removeMember(group.Members, access)
+			conf.Groups[name] = group
+		}
 
 		b, err := json.Marshal(conf)
 		if err != nil {
@@ -134,19 +143,158 @@ func (s *IAMServiceInternal) ListUserAccounts() ([]Account, error) {
 
 	var accs []Account
 	for _, k := range keys {
-		accs = append(accs, Account{
+		accs = append(accs, inheritRoles(Account{
 			Access:    k,
 			Secret:    conf.AccessAccounts[k].Secret,
 			Role:      conf.AccessAccounts[k].Role,
 			UserID:    conf.AccessAccounts[k].UserID,
 			GroupID:   conf.AccessAccounts[k].GroupID,
 			ProjectID: conf.AccessAccounts[k].ProjectID,
-		})
+			Quota:     conf.AccessAccounts[k].Quota,
+		}, conf.Groups))
 	}
 
 	return accs, nil
 }
 
+// SetAccountQuota sets the storage quota of the account
+func (s *IAMServiceInternal) SetAccountQuota(access string, quota int64) error {
+	return s.storeIAM(func(data []byte) ([]byte, error) {
+		conf, err := parseIAM(data)
+		if err != nil {
+			return nil, fmt.Errorf("get iam data: %w", err)
+		}
+
+		acct, ok := conf.AccessAccounts[access]
+		if !ok {
+			return nil, ErrNoSuchUser
+		}
+		acct.Quota = quota
+		conf.AccessAccounts[access] = acct
+
+		b, err := json.Marshal(conf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize iam: %w", err)
+		}
+
+		return b, nil
+	})
+}
+
+// CreateGroup creates a new group. Returns ErrGroupExists if the group
+// already exists.
+func (s *IAMServiceInternal) CreateGroup(group Group) error {
+	return s.updateGroups(func(conf *iAMConfig) error {
+		if _, ok := conf.Groups[group.Name]; ok {
+			return ErrGroupExists
+		}
+		for _, member := range group.Members {
+			if _, ok := conf.AccessAccounts[member]; !ok {
+				return ErrNoSuchUser
+			}
+		}
+		if group.Members == nil {
+			group.Members = []string{}
+		}
+		conf.Groups[group.Name] = group
+		return nil
+	})
+}
+
+// DeleteGroup deletes the group, its members lose the group role
+func (s *IAMServiceInternal) DeleteGroup(name string) error {
+	return s.updateGroups(func(conf *iAMConfig) error {
+		if _, ok := conf.Groups[name]; !ok {
+			return ErrNoSuchGroup
+		}
+		delete(conf.Groups, name)
+		return nil
+	})
+}
+
+// ListGroups lists all the groups with their members
+func (s *IAMServiceInternal) ListGroups() ([]Group, error) {
+	conf, err := s.getIAM()
+	if err != nil {
+		return nil, fmt.Errorf("get iam data: %w", err)
+	}
+
+	groups := []Group{}
+	for _, name := range sortedGroupNames(conf.Groups) {
+		groups = append(groups, conf.Groups[name])
+	}
+
+	return groups, nil
+}
+
+// AddGroupMember adds the account to the group
+func (s *IAMServiceInternal) AddGroupMember(name, access string) error {
+	return s.updateGroups(func(conf *iAMConfig) error {
+		group, ok := conf.Groups[name]
+		if !ok {
+			return ErrNoSuchGroup
+		}
+		if _, ok := conf.AccessAccounts[access]; !ok {
+			return ErrNoSuchUser
+		}
+		for _, member := range group.Members {
+			if member == access {
+				return nil
+			}
+		}
+		group.Members = append(group.Members, access)
+		sort.Strings(group.Members)
+		conf.Groups[name] = group
+		return nil
+	})
+}
+
+// RemoveGroupMember removes the account from the group
+func (s *IAMServiceInternal) RemoveGroupMember(name, access string) error {
+	return s.updateGroups(func(conf *iAMConfig) error {
+		group, ok := conf.Groups[name]
+		if !ok {
+			return ErrNoSuchGroup
+		}
+		group.Members = removeMember(group.Members, access)
+		conf.Groups[name] = group
+		return nil
+	})
+}
+
+func (s *IAMServiceInternal) updateGroups(update func(*iAMConfig) error) error {
+	return s.storeIAM(func(data []byte) ([]byte, error) {
+		conf, err := parseIAM(data)
+		if err != nil {
+			return nil, fmt.Errorf("get iam data: %w", err)
+		}
+		if conf.Groups == nil {
+			conf.Groups = make(map[string]Group)
+		}
+
+		if err := update(&conf); err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(conf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize iam: %w", err)
+		}
+
+		return b, nil
+	})
+}
+
+func removeMember(members []string, access string) []string {
+	result := []string{}
+	for _, member := range members {
+		if member != access {
+			result = append(result, member)
+		}
+	}
+	return result
+}
+
 // Shutdown graceful termination of service
 func (s *IAMServiceInternal) Shutdown() error {
 	return nil
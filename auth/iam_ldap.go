@@ -15,54 +15,150 @@
 package auth
 
 import (
+	"crypto/tls"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/go-ldap/ldap/v3"
 )
 
+// LDAPConfig is the LDAP IAM service configuration
+type LDAPConfig struct {
+	URL        string
+	BindDN     string
+	Password   string
+	QueryBase  string
+	ObjClasses string
+	AccessAtr  string
+	SecretAtr  string
+	RoleAtr    string
+	// optional posix uid/gid attributes, e.g. uidNumber and gidNumber
+	UserIDAtr  string
+	GroupIDAtr string
+	// optional group membership attribute, e.g. memberOf, and the
+	// semicolon separated group dn to role mapping, for example
+	// "cn=admins,ou=groups,dc=example,dc=com=admin"
+	GroupAtr         string
+	GroupRoleMapping string
+	StartTLS         bool
+	TLSSkipVerify    bool
+	PoolSize         int
+	// ReadOnly disables the account creation and deletion, for
+	// directories managed outside of the gateway
+	ReadOnly bool
+}
+
 type LdapIAMService struct {
-	conn       *ldap.Conn
+	pool       *ldapPool
 	queryBase  string
 	objClasses []string
 	accessAtr  string
 	secretAtr  string
 	roleAtr    string
+	uidAtr     string
+	gidAtr     string
+	groupAtr   string
+	groupRoles map[string]Role
+	readOnly   bool
 }
 
 var _ IAMService = &LdapIAMService{}
 
-func NewLDAPService(url, bindDN, pass, queryBase, accAtr, secAtr, roleAtr, objClasses string) (IAMService, error) {
-	if url == "" || bindDN == "" || pass == "" || queryBase == "" || accAtr == "" || secAtr == "" || roleAtr == "" || objClasses == "" {
+func NewLDAPService(cfg LDAPConfig) (IAMService, error) {
+	if cfg.URL == "" || cfg.BindDN == "" || cfg.Password == "" || cfg.QueryBase == "" || cfg.AccessAtr == "" || cfg.SecretAtr == "" || cfg.ObjClasses == "" {
 		return nil, fmt.Errorf("required parameters list not fully provided")
 	}
-	conn, err := ldap.DialURL(url)
+	if cfg.RoleAtr == "" && cfg.GroupAtr == "" {
+		return nil, fmt.Errorf("either the role or the group attribute is required")
+	}
+
+	groupRoles, err := parseGroupRoleMapping(cfg.GroupRoleMapping)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+		return nil, err
 	}
 
-	err = conn.Bind(bindDN, pass)
+	pool := newLdapPool(cfg)
+	// check the connection and the credentials upfront
+	conn, err := pool.get()
 	if err != nil {
-		return nil, fmt.Errorf("failed to bind to LDAP server %w", err)
+		return nil, err
 	}
+	pool.put(conn)
+
 	return &LdapIAMService{
-		conn:       conn,
-		queryBase:  queryBase,
-		objClasses: strings.Split(objClasses, ","),
-		accessAtr:  accAtr,
-		secretAtr:  secAtr,
-		roleAtr:    roleAtr,
+		pool:       pool,
+		queryBase:  cfg.QueryBase,
+		objClasses: strings.Split(cfg.ObjClasses, ","),
+		accessAtr:  cfg.AccessAtr,
+		secretAtr:  cfg.SecretAtr,
+		roleAtr:    cfg.RoleAtr,
+		uidAtr:     cfg.UserIDAtr,
+		gidAtr:     cfg.GroupIDAtr,
+		groupAtr:   cfg.GroupAtr,
+		groupRoles: groupRoles,
+		readOnly:   cfg.ReadOnly,
 	}, nil
 }
 
+func parseGroupRoleMapping(mapping string) (map[string]Role, error) {
+	result := make(map[string]Role)
+	if mapping == "" {
+		return result, nil
+	}
+
+	for _, pair := range strings.Split(mapping, ";") {
+		pair = strings.TrimSpace(pair)
+		// group dns contain '=', the role is after the last one
+		idx := strings.LastIndex(pair, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid ldap group role mapping %q", pair)
+		}
+		role := Role(pair[idx+1:])
+		switch role {
+		case RoleAdmin, RoleUser, RoleUserPlus:
+		default:
+			return nil, fmt.Errorf("invalid role %q in ldap group role mapping", role)
+		}
+		result[normalizeDN(pair[:idx])] = role
+	}
+
+	return result, nil
+}
+
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return strings.ToLower(strings.Join(parts, ","))
+}
+
 func (ld *LdapIAMService) CreateAccount(account Account) error {
-	userEntry := ldap.NewAddRequest(fmt.Sprintf("%v=%v, %v", ld.accessAtr, account.Access, ld.queryBase), nil)
+	if ld.readOnly {
+		return ErrNotSupported
+	}
+
+	userEntry := ldap.NewAddRequest(ld.userDN(account.Access), nil)
 	userEntry.Attribute("objectClass", ld.objClasses)
 	userEntry.Attribute(ld.accessAtr, []string{account.Access})
 	userEntry.Attribute(ld.secretAtr, []string{account.Secret})
-	userEntry.Attribute(ld.roleAtr, []string{string(account.Role)})
+	if ld.roleAtr != "" {
+		userEntry.Attribute(ld.roleAtr, []string{string(account.Role)})
+	}
+	if ld.uidAtr != "" {
+		userEntry.Attribute(ld.uidAtr, []string{strconv.Itoa(account.UserID)})
+	}
+	if ld.gidAtr != "" {
+		userEntry.Attribute(ld.gidAtr, []string{strconv.Itoa(account.GroupID)})
+	}
 
-	err := ld.conn.Add(userEntry)
+	err := ld.pool.do(func(conn *ldap.Conn) error {
+		return conn.Add(userEntry)
+	})
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists) {
+		return ErrUserExists
+	}
 	if err != nil {
 		return fmt.Errorf("error adding an entry: %w", err)
 	}
@@ -78,33 +174,40 @@ func (ld *LdapIAMService) GetUserAccount(access string) (Account, error) {
 		0,
 		0,
 		false,
-		fmt.Sprintf("(%v=%v)", ld.accessAtr, access),
-		[]string{ld.accessAtr, ld.secretAtr, ld.roleAtr},
+		fmt.Sprintf("(%v=%v)", ld.accessAtr, ldap.EscapeFilter(access)),
+		ld.attributes(),
 		nil,
 	)
 
-	result, err := ld.conn.Search(searchRequest)
+	var result *ldap.SearchResult
+	err := ld.pool.do(func(conn *ldap.Conn) error {
+		var err error
+		result, err = conn.Search(searchRequest)
+		return err
+	})
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return Account{}, ErrNoSuchUser
+	}
 	if err != nil {
 		return Account{}, err
 	}
+	if len(result.Entries) == 0 {
+		return Account{}, ErrNoSuchUser
+	}
 
-	entry := result.Entries[0]
-	return Account{
-		Access: entry.GetAttributeValue(ld.accessAtr),
-		Secret: entry.GetAttributeValue(ld.secretAtr),
-		Role:   Role(entry.GetAttributeValue(ld.roleAtr)),
-	}, nil
+	return ld.account(result.Entries[0]), nil
 }
 
 func (ld *LdapIAMService) DeleteUserAccount(access string) error {
-	delReq := ldap.NewDelRequest(fmt.Sprintf("%v=%v, %v", ld.accessAtr, access, ld.queryBase), nil)
-
-	err := ld.conn.Del(delReq)
-	if err != nil {
-		return err
+	if ld.readOnly {
+		return ErrNotSupported
 	}
 
-	return nil
+	delReq := ldap.NewDelRequest(ld.userDN(access), nil)
+
+	return ld.pool.do(func(conn *ldap.Conn) error {
+		return conn.Del(delReq)
+	})
 }
 
 func (ld *LdapIAMService) ListUserAccounts() ([]Account, error) {
@@ -120,22 +223,23 @@ func (ld *LdapIAMService) ListUserAccounts() ([]Account, error) {
 		0,
 		false,
 		fmt.Sprintf("(&%v)", searchFilter),
-		[]string{ld.accessAtr, ld.secretAtr, ld.roleAtr},
+		ld.attributes(),
 		nil,
 	)
 
-	resp, err := ld.conn.Search(searchRequest)
+	var resp *ldap.SearchResult
+	err := ld.pool.do(func(conn *ldap.Conn) error {
+		var err error
+		resp, err = conn.Search(searchRequest)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	result := []Account{}
 	for _, el := range resp.Entries {
-		result = append(result, Account{
-			Access: el.GetAttributeValue(ld.accessAtr),
-			Secret: el.GetAttributeValue(ld.secretAtr),
-			Role:   Role(el.GetAttributeValue(ld.roleAtr)),
-		})
+		result = append(result, ld.account(el))
 	}
 
 	return result, nil
@@ -143,5 +247,150 @@ func (ld *LdapIAMService) ListUserAccounts() ([]Account, error) {
 
 // Shutdown graceful termination of service
 func (ld *LdapIAMService) Shutdown() error {
-	return ld.conn.Close()
+	return ld.pool.close()
+}
+
+func (ld *LdapIAMService) userDN(access string) string {
+	return fmt.Sprintf("%v=%v, %v", ld.accessAtr, ldap.EscapeDN(access), ld.queryBase)
+}
+
+func (ld *LdapIAMService) attributes() []string {
+	atrs := []string{ld.accessAtr, ld.secretAtr}
+	for _, atr := range []string{ld.roleAtr, ld.uidAtr, ld.gidAtr, ld.groupAtr} {
+		if atr != "" {
+			atrs = append(atrs, atr)
+		}
+	}
+	return atrs
+}
+
+// account builds the account from the directory entry, the group role
+// mapping takes precedence over the role attribute
+func (ld *LdapIAMService) account(entry *ldap.Entry) Account {
+	acct := Account{
+		Access: entry.GetAttributeValue(ld.accessAtr),
+		Secret: entry.GetAttributeValue(ld.secretAtr),
+	}
+	if ld.roleAtr != "" {
+		acct.Role = Role(entry.GetAttributeValue(ld.roleAtr))
+	}
+	if ld.groupAtr != "" {
+		var groupRole Role
+		for _, group := range entry.GetAttributeValues(ld.groupAtr) {
+			role, ok := ld.groupRoles[normalizeDN(group)]
+			if ok && rolePriority(role) > rolePriority(groupRole) {
+				groupRole = role
+			}
+		}
+		if groupRole != "" {
+			acct.Role = groupRole
+		}
+	}
+	if acct.Role == "" {
+		acct.Role = RoleUser
+	}
+	if ld.uidAtr != "" {
+		acct.UserID, _ = strconv.Atoi(entry.GetAttributeValue(ld.uidAtr))
+	}
+	if ld.gidAtr != "" {
+		acct.GroupID, _ = strconv.Atoi(entry.GetAttributeValue(ld.gidAtr))
+	}
+
+	return acct
+}
+
+// ldapPool is a pool of bound connections to the LDAP server
+type ldapPool struct {
+	cfg   LDAPConfig
+	conns chan *ldap.Conn
+}
+
+func newLdapPool(cfg LDAPConfig) *ldapPool {
+	size := cfg.PoolSize
+	if size <= 0 {
+		size = 1
+	}
+	return &ldapPool{
+		cfg:   cfg,
+		conns: make(chan *ldap.Conn, size),
+	}
+}
+
+func (p *ldapPool) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.cfg.TLSSkipVerify}
+
+	conn, err := ldap.DialURL(p.cfg.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+	}
+
+	if p.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start tls with LDAP server: %w", err)
+		}
+	}
+
+	err = conn.Bind(p.cfg.BindDN, p.cfg.Password)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to bind to LDAP server %w", err)
+	}
+
+	return conn, nil
+}
+
+func (p *ldapPool) get() (*ldap.Conn, error) {
+	select {
+	case conn := <-p.conns:
+		if !conn.IsClosing() {
+			return conn, nil
+		}
+		conn.Close()
+	default:
+	}
+
+	return p.dial()
+}
+
+func (p *ldapPool) put(conn *ldap.Conn) {
+	select {
+	case p.conns <- conn:
+	default:
+		// the pool is full
+		conn.Close()
+	}
+}
+
+// do runs fn with a pooled connection, the request is retried once on
+// a new connection if the pooled one was lost
+func (p *ldapPool) do(fn func(*ldap.Conn) error) error {
+	conn, err := p.get()
+	if err != nil {
+		return err
+	}
+
+	err = fn(conn)
+	if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+		conn.Close()
+		conn, err = p.dial()
+		if err != nil {
+			return err
+		}
+		err = fn(conn)
+	}
+
+	p.put(conn)
+	return err
+}
+
+func (p *ldapPool) close() error {
+	for {
+		select {
+		case conn := <-p.conns:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
 }
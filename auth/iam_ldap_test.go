@@ -0,0 +1,232 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestNewLDAPServiceConfig(t *testing.T) {
+	valid := LDAPConfig{
+		BindDN:     "cn=admin,dc=example,dc=com",
+		Password:   "secret",
+		QueryBase:  "ou=users,dc=example,dc=com",
+		ObjClasses: "top,person",
+		AccessAtr:  "uid",
+		SecretAtr:  "userPassword",
+		RoleAtr:    "description",
+	}
+
+	// nothing listens on the port of a closed listener
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid.URL = "ldap://" + ln.Addr().String()
+	ln.Close()
+
+	tests := []struct {
+		name   string
+		modify func(*LDAPConfig)
+	}{
+		{name: "missing-url", modify: func(c *LDAPConfig) { c.URL = "" }},
+		{name: "missing-bind-dn", modify: func(c *LDAPConfig) { c.BindDN = "" }},
+		{name: "missing-password", modify: func(c *LDAPConfig) { c.Password = "" }},
+		{name: "missing-query-base", modify: func(c *LDAPConfig) { c.QueryBase = "" }},
+		{name: "missing-object-classes", modify: func(c *LDAPConfig) { c.ObjClasses = "" }},
+		{name: "missing-access-attribute", modify: func(c *LDAPConfig) { c.AccessAtr = "" }},
+		{name: "missing-secret-attribute", modify: func(c *LDAPConfig) { c.SecretAtr = "" }},
+		{name: "missing-role-and-group-attribute", modify: func(c *LDAPConfig) { c.RoleAtr = "" }},
+		{name: "invalid-group-role-mapping", modify: func(c *LDAPConfig) {
+			c.GroupAtr = "memberOf"
+			c.GroupRoleMapping = "cn=admins,dc=example,dc=com=root"
+		}},
+		{name: "unreachable-server", modify: func(c *LDAPConfig) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			if _, err := NewLDAPService(cfg); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestParseGroupRoleMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping string
+		want    map[string]Role
+		wantErr bool
+	}{
+		{name: "empty", want: map[string]Role{}},
+		{
+			name:    "single",
+			mapping: "cn=admins,ou=groups,dc=example,dc=com=admin",
+			want:    map[string]Role{"cn=admins,ou=groups,dc=example,dc=com": RoleAdmin},
+		},
+		{
+			name:    "normalized-dns",
+			mapping: "CN=Admins, OU=Groups,DC=example,DC=com=admin; cn=devs,dc=example,dc=com=userplus",
+			want: map[string]Role{
+				"cn=admins,ou=groups,dc=example,dc=com": RoleAdmin,
+				"cn=devs,dc=example,dc=com":             RoleUserPlus,
+			},
+		},
+		{name: "missing-role", mapping: "cn=admins", wantErr: true},
+		{name: "missing-dn", mapping: "=admin", wantErr: true},
+		{name: "invalid-role", mapping: "cn=admins,dc=example,dc=com=superuser", wantErr: true},
+		{name: "invalid-second-pair", mapping: "cn=a,dc=com=user;nonsense", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGroupRoleMapping(tt.mapping)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLDAPAccountMapping(t *testing.T) {
+	groupRoles, err := parseGroupRoleMapping(
+		"cn=admins,ou=groups,dc=example,dc=com=admin;cn=devs,ou=groups,dc=example,dc=com=userplus")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ld := &LdapIAMService{
+		accessAtr:  "uid",
+		secretAtr:  "userPassword",
+		roleAtr:    "description",
+		uidAtr:     "uidNumber",
+		gidAtr:     "gidNumber",
+		groupAtr:   "memberOf",
+		groupRoles: groupRoles,
+	}
+
+	tests := []struct {
+		name  string
+		attrs map[string][]string
+		want  Account
+	}{
+		{
+			name: "role-attribute",
+			attrs: map[string][]string{
+				"uid":          {"user1"},
+				"userPassword": {"secret1"},
+				"description":  {"userplus"},
+				"uidNumber":    {"1001"},
+				"gidNumber":    {"2001"},
+			},
+			want: Account{Access: "user1", Secret: "secret1", Role: RoleUserPlus, UserID: 1001, GroupID: 2001},
+		},
+		{
+			name: "default-role",
+			attrs: map[string][]string{
+				"uid":          {"user2"},
+				"userPassword": {"secret2"},
+			},
+			want: Account{Access: "user2", Secret: "secret2", Role: RoleUser},
+		},
+		{
+			name: "group-role-overrides-attribute",
+			attrs: map[string][]string{
+				"uid":          {"user3"},
+				"userPassword": {"secret3"},
+				"description":  {"user"},
+				"memberOf":     {"CN=Devs, OU=Groups, DC=example, DC=com"},
+			},
+			want: Account{Access: "user3", Secret: "secret3", Role: RoleUserPlus},
+		},
+		{
+			name: "most-privileged-group-role",
+			attrs: map[string][]string{
+				"uid":          {"user4"},
+				"userPassword": {"secret4"},
+				"memberOf": {
+					"cn=devs,ou=groups,dc=example,dc=com",
+					"cn=admins,ou=groups,dc=example,dc=com",
+					"cn=other,ou=groups,dc=example,dc=com",
+				},
+			},
+			want: Account{Access: "user4", Secret: "secret4", Role: RoleAdmin},
+		},
+		{
+			name: "unmapped-groups",
+			attrs: map[string][]string{
+				"uid":          {"user5"},
+				"userPassword": {"secret5"},
+				"description":  {"admin"},
+				"memberOf":     {"cn=other,ou=groups,dc=example,dc=com"},
+			},
+			want: Account{Access: "user5", Secret: "secret5", Role: RoleAdmin},
+		},
+		{
+			name: "invalid-ids",
+			attrs: map[string][]string{
+				"uid":          {"user6"},
+				"userPassword": {"secret6"},
+				"uidNumber":    {"abc"},
+			},
+			want: Account{Access: "user6", Secret: "secret6", Role: RoleUser},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ld.account(ldap.NewEntry("uid=x,ou=users,dc=example,dc=com", tt.attrs))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLDAPAttributes(t *testing.T) {
+	ld := &LdapIAMService{accessAtr: "uid", secretAtr: "userPassword", groupAtr: "memberOf"}
+	want := []string{"uid", "userPassword", "memberOf"}
+	if got := ld.attributes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("attributes = %v, want %v", got, want)
+	}
+
+	ld = &LdapIAMService{accessAtr: "uid", secretAtr: "userPassword", queryBase: "ou=users,dc=example,dc=com"}
+	if got, want := ld.userDN("a,b=c"), `uid=a\,b=c, ou=users,dc=example,dc=com`; got != want {
+		t.Errorf("userDN = %v, want %v", got, want)
+	}
+}
+
+func TestLDAPReadOnly(t *testing.T) {
+	ld := &LdapIAMService{readOnly: true, pool: newLdapPool(LDAPConfig{})}
+
+	if err := ld.CreateAccount(Account{Access: "user1"}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("CreateAccount error = %v, want %v", err, ErrNotSupported)
+	}
+	if err := ld.DeleteUserAccount("user1"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("DeleteUserAccount error = %v, want %v", err, ErrNotSupported)
+	}
+}
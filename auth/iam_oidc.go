@@ -0,0 +1,494 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/versity/versitygw/s3err"
+)
+
+const (
+	// minimum and maximum lifetimes of the temporary credentials
+	minSessionDuration = 15 * time.Minute
+	maxSessionDuration = 12 * time.Hour
+
+	// minimum interval between the refreshes of the provider keys
+	jwksRefreshInterval = time.Minute
+)
+
+// Credentials are the temporary credentials minted for a web identity
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+	Subject         string
+}
+
+// WebIdentityService is implemented by the IAM services able to exchange
+// identity tokens of an external identity provider for temporary
+// gateway credentials
+type WebIdentityService interface {
+	AssumeRoleWithWebIdentity(token string, duration time.Duration) (Credentials, error)
+}
+
+// OIDCIAMService authenticates the users of an OpenID Connect provider.
+// The identity tokens issued by the provider are exchanged for temporary
+// credentials acting as the gateway account named by the account claim,
+// with the role mapped from the role claim. All the other accounts are
+// served by the underlying IAM service.
+type OIDCIAMService struct {
+	IAMService
+
+	issuer      string
+	clientID    string
+	accountClm  string
+	roleClm     string
+	roleMapping map[string]Role
+	client      *http.Client
+
+	keysMu      sync.RWMutex
+	jwksURI     string
+	keys        map[string]any
+	lastRefresh time.Time
+
+	sessionsMu sync.RWMutex
+	sessions   map[string]oidcSession
+}
+
+type oidcSession struct {
+	account Account
+	exp     time.Time
+}
+
+var _ IAMService = &OIDCIAMService{}
+var _ WebIdentityService = &OIDCIAMService{}
+
+// NewOIDCService creates the OIDC IAM service for the issuer. The role
+// mapping is a comma separated list of claim value to role pairs, for
+// example "admins=admin,developers=userplus".
+func NewOIDCService(svc IAMService, issuer, clientID, accountClaim, roleClaim, roleMapping string) (IAMService, error) {
+	if issuer == "" || clientID == "" {
+		return nil, fmt.Errorf("oidc issuer and client id are required")
+	}
+	if accountClaim == "" {
+		accountClaim = "preferred_username"
+	}
+
+	mapping, err := parseRoleMapping(roleMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &OIDCIAMService{
+		IAMService:  svc,
+		issuer:      strings.TrimSuffix(issuer, "/"),
+		clientID:    clientID,
+		accountClm:  accountClaim,
+		roleClm:     roleClaim,
+		roleMapping: mapping,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		keys:        make(map[string]any),
+		sessions:    make(map[string]oidcSession),
+	}
+
+	if err := o.refreshKeys(); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+func parseRoleMapping(mapping string) (map[string]Role, error) {
+	result := make(map[string]Role)
+	if mapping == "" {
+		return result, nil
+	}
+
+	for _, pair := range strings.Split(mapping, ",") {
+		value, role, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || value == "" {
+			return nil, fmt.Errorf("invalid oidc role mapping %q", pair)
+		}
+		switch Role(role) {
+		case RoleAdmin, RoleUser, RoleUserPlus:
+		default:
+			return nil, fmt.Errorf("invalid role %q in oidc role mapping", role)
+		}
+		result[value] = Role(role)
+	}
+
+	return result, nil
+}
+
+// GetUserAccount returns the account the temporary credentials act as,
+// or the account of the underlying IAM service
+func (o *OIDCIAMService) GetUserAccount(access string) (Account, error) {
+	o.sessionsMu.RLock()
+	session, ok := o.sessions[access]
+	o.sessionsMu.RUnlock()
+	if !ok {
+		return o.IAMService.GetUserAccount(access)
+	}
+
+	if !session.exp.After(time.Now()) {
+		o.sessionsMu.Lock()
+		delete(o.sessions, access)
+		o.sessionsMu.Unlock()
+		return Account{}, ErrNoSuchUser
+	}
+
+	return session.account, nil
+}
+
+// Unwrap returns the IAM service serving the non federated accounts
+func (o *OIDCIAMService) Unwrap() IAMService {
+	return o.IAMService
+}
+
+// AssumeRoleWithWebIdentity validates the identity token and mints
+// temporary credentials for the account it maps to
+func (o *OIDCIAMService) AssumeRoleWithWebIdentity(token string, duration time.Duration) (Credentials, error) {
+	claims, err := o.parseToken(token)
+	if err != nil {
+		return Credentials{}, s3err.GetAPIError(s3err.ErrInvalidIdentityToken)
+	}
+
+	account, err := o.mapAccount(claims)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	if duration == 0 {
+		duration = time.Hour
+	}
+	if duration < minSessionDuration || duration > maxSessionDuration {
+		return Credentials{}, s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
+	exp := time.Now().Add(duration)
+	// the credentials can't outlive the identity token
+	if tokenExp, err := claims.GetExpirationTime(); err == nil && tokenExp != nil && tokenExp.Before(exp) {
+		exp = tokenExp.Time
+	}
+
+	accessKey, err := randomString(8)
+	if err != nil {
+		return Credentials{}, err
+	}
+	accessKey = "ASIA" + strings.ToUpper(accessKey)
+	secret, err := randomString(20)
+	if err != nil {
+		return Credentials{}, err
+	}
+	sessionToken, err := randomString(32)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	account.Secret = secret
+
+	o.sessionsMu.Lock()
+	o.pruneSessions()
+	o.sessions[accessKey] = oidcSession{
+		account: account,
+		exp:     exp,
+	}
+	o.sessionsMu.Unlock()
+
+	sub, _ := claims.GetSubject()
+
+	return Credentials{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secret,
+		SessionToken:    sessionToken,
+		Expiration:      exp,
+		Subject:         sub,
+	}, nil
+}
+
+// pruneSessions removes the expired sessions, the caller must hold
+// the sessions lock
+func (o *OIDCIAMService) pruneSessions() {
+	now := time.Now()
+	for access, session := range o.sessions {
+		if !session.exp.After(now) {
+			delete(o.sessions, access)
+		}
+	}
+}
+
+// mapAccount maps the token claims to the gateway account. Existing
+// accounts keep their uid/gid/project, the role is overridden by the
+// role mapping when one of the role claim values matches.
+func (o *OIDCIAMService) mapAccount(claims jwt.MapClaims) (Account, error) {
+	name, ok := claims[o.accountClm].(string)
+	if !ok || name == "" {
+		return Account{}, s3err.GetAPIError(s3err.ErrInvalidIdentityToken)
+	}
+
+	account, err := o.IAMService.GetUserAccount(name)
+	if errors.Is(err, ErrNoSuchUser) || errors.Is(err, s3err.GetAPIError(s3err.ErrNotImplemented)) {
+		account = Account{Access: name, Role: RoleUser}
+	} else if err != nil {
+		return Account{}, err
+	}
+
+	if role, ok := o.mapRole(claims); ok {
+		account.Role = role
+	}
+
+	return account, nil
+}
+
+// mapRole returns the most privileged role mapped from the role claim
+func (o *OIDCIAMService) mapRole(claims jwt.MapClaims) (Role, bool) {
+	if o.roleClm == "" {
+		return "", false
+	}
+
+	var values []string
+	switch v := claims[o.roleClm].(type) {
+	case string:
+		values = append(values, v)
+	case []any:
+		for _, el := range v {
+			if str, ok := el.(string); ok {
+				values = append(values, str)
+			}
+		}
+	}
+
+	var result Role
+	for _, v := range values {
+		role, ok := o.roleMapping[v]
+		if !ok {
+			continue
+		}
+		if rolePriority(role) > rolePriority(result) {
+			result = role
+		}
+	}
+
+	return result, result != ""
+}
+
+func rolePriority(role Role) int {
+	switch role {
+	case RoleAdmin:
+		return 3
+	case RoleUserPlus:
+		return 2
+	case RoleUser:
+		return 1
+	}
+	return 0
+}
+
+// parseToken verifies the token signature with the provider keys and
+// validates the issuer, audience and expiration claims
+func (o *OIDCIAMService) parseToken(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, o.keyFunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(o.issuer),
+		jwt.WithAudience(o.clientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (o *OIDCIAMService) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if key, ok := o.getKey(kid); ok {
+		return key, nil
+	}
+
+	// the provider might have rotated its keys
+	if err := o.refreshKeys(); err != nil {
+		return nil, err
+	}
+	if key, ok := o.getKey(kid); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (o *OIDCIAMService) getKey(kid string) (any, bool) {
+	o.keysMu.RLock()
+	defer o.keysMu.RUnlock()
+
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, true
+		}
+	}
+	key, ok := o.keys[kid]
+	return key, ok
+}
+
+type oidcDiscovery struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// refreshKeys fetches the provider signing keys, the provider
+// configuration is discovered on the first call
+func (o *OIDCIAMService) refreshKeys() error {
+	o.keysMu.Lock()
+	defer o.keysMu.Unlock()
+
+	if time.Since(o.lastRefresh) < jwksRefreshInterval {
+		return nil
+	}
+
+	if o.jwksURI == "" {
+		var discovery oidcDiscovery
+		err := o.getJSON(o.issuer+"/.well-known/openid-configuration", &discovery)
+		if err != nil {
+			return fmt.Errorf("oidc discovery: %w", err)
+		}
+		if strings.TrimSuffix(discovery.Issuer, "/") != o.issuer {
+			return fmt.Errorf("oidc discovery: issuer mismatch %q", discovery.Issuer)
+		}
+		if discovery.JWKSURI == "" {
+			return fmt.Errorf("oidc discovery: missing jwks_uri")
+		}
+		o.jwksURI = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := o.getJSON(o.jwksURI, &jwks); err != nil {
+		return fmt.Errorf("oidc keys: %w", err)
+	}
+
+	keys := make(map[string]any)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// skip the key types that can't sign the tokens
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	o.keys = keys
+	o.lastRefresh = time.Now()
+	return nil
+}
+
+func (o *OIDCIAMService) getJSON(url string, v any) error {
+	resp, err := o.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %v: %v", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (jwk jsonWebKey) publicKey() (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,283 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+const (
+	SQLDriverPostgres = "postgres"
+	SQLDriverMySQL    = "mysql"
+
+	// number of accounts fetched per query when listing the accounts
+	sqlListPageSize = 1000
+)
+
+// sqlMigrations are the schema changes applied in order, the schema
+// version is the number of the applied migrations
+var sqlMigrations = []string{
+	`CREATE TABLE versitygw_accounts (
+		access VARCHAR(255) NOT NULL PRIMARY KEY,
+		secret VARCHAR(255) NOT NULL,
+		role VARCHAR(32) NOT NULL,
+		user_id INTEGER NOT NULL DEFAULT 0,
+		group_id INTEGER NOT NULL DEFAULT 0,
+		project_id INTEGER NOT NULL DEFAULT 0
+	)`,
+	`ALTER TABLE versitygw_accounts ADD COLUMN quota BIGINT NOT NULL DEFAULT 0`,
+}
+
+// SQLIAMService stores the accounts in a PostgreSQL or MySQL database,
+// allowing multiple gateways to share the same accounts
+type SQLIAMService struct {
+	db     *sql.DB
+	driver string
+}
+
+var _ IAMService = &SQLIAMService{}
+var _ QuotaService = &SQLIAMService{}
+
+// NewSQLService connects to the database and migrates its schema to the
+// latest version
+func NewSQLService(driver, dsn string) (IAMService, error) {
+	if driver != SQLDriverPostgres && driver != SQLDriverMySQL {
+		return nil, fmt.Errorf("unsupported sql driver %q", driver)
+	}
+	if dsn == "" {
+		return nil, fmt.Errorf("sql data source name should be specified")
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+
+	s := &SQLIAMService{db: db, driver: driver}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// migrate applies the missing schema migrations. The schema table is
+// locked while migrating so concurrently starting gateways don't apply
+// the same migration twice.
+func (s *SQLIAMService) migrate() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS versitygw_schema (version INTEGER NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("create schema table: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	lock := "LOCK TABLE versitygw_schema IN EXCLUSIVE MODE"
+	if s.driver == SQLDriverMySQL {
+		lock = "SELECT version FROM versitygw_schema FOR UPDATE"
+	}
+	if _, err := tx.Exec(lock); err != nil {
+		return fmt.Errorf("lock schema table: %w", err)
+	}
+
+	var version int
+	err = tx.QueryRow(`SELECT version FROM versitygw_schema`).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = tx.Exec(`INSERT INTO versitygw_schema (version) VALUES (0)`)
+	}
+	if err != nil {
+		return fmt.Errorf("get schema version: %w", err)
+	}
+
+	for ; version < len(sqlMigrations); version++ {
+		if _, err := tx.Exec(sqlMigrations[version]); err != nil {
+			return fmt.Errorf("migrate schema to version %v: %w", version+1, err)
+		}
+		_, err := tx.Exec(s.rebind(`UPDATE versitygw_schema SET version = ?`), version+1)
+		if err != nil {
+			return fmt.Errorf("update schema version: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// rebind replaces the '?' placeholders with the postgres numbered ones
+func (s *SQLIAMService) rebind(query string) string {
+	if s.driver != SQLDriverPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (s *SQLIAMService) CreateAccount(account Account) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow(s.rebind(`SELECT COUNT(*) FROM versitygw_accounts WHERE access = ?`),
+		account.Access).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("get account: %w", err)
+	}
+	if count != 0 {
+		return ErrUserExists
+	}
+
+	_, err = tx.Exec(s.rebind(`INSERT INTO versitygw_accounts
+		(access, secret, role, user_id, group_id, project_id, quota) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		account.Access, account.Secret, string(account.Role),
+		account.UserID, account.GroupID, account.ProjectID, account.Quota)
+	if err != nil {
+		return fmt.Errorf("insert account: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLIAMService) GetUserAccount(access string) (Account, error) {
+	var acct Account
+	err := s.db.QueryRow(s.rebind(`SELECT access, secret, role, user_id, group_id, project_id, quota
+		FROM versitygw_accounts WHERE access = ?`), access).
+		Scan(&acct.Access, &acct.Secret, &acct.Role, &acct.UserID, &acct.GroupID, &acct.ProjectID, &acct.Quota)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Account{}, ErrNoSuchUser
+	}
+	if err != nil {
+		return Account{}, fmt.Errorf("get account: %w", err)
+	}
+
+	return acct, nil
+}
+
+func (s *SQLIAMService) DeleteUserAccount(access string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(s.rebind(`DELETE FROM versitygw_accounts WHERE access = ?`), access)
+	if err != nil {
+		return fmt.Errorf("delete account: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListUserAccounts lists all the accounts sorted by access key, the
+// accounts are fetched a page at a time
+func (s *SQLIAMService) ListUserAccounts() ([]Account, error) {
+	accs := []Account{}
+	marker := ""
+	for {
+		page, next, err := s.ListUserAccountsPage(marker, sqlListPageSize)
+		if err != nil {
+			return nil, err
+		}
+		accs = append(accs, page...)
+		if next == "" {
+			return accs, nil
+		}
+		marker = next
+	}
+}
+
+// ListUserAccountsPage lists up to limit accounts with access keys
+// sorted after the marker. The returned marker is empty if there are no
+// more accounts.
+func (s *SQLIAMService) ListUserAccountsPage(marker string, limit int) ([]Account, string, error) {
+	rows, err := s.db.Query(s.rebind(`SELECT access, secret, role, user_id, group_id, project_id, quota
+		FROM versitygw_accounts WHERE access > ? ORDER BY access LIMIT ?`), marker, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accs := []Account{}
+	for rows.Next() {
+		var acct Account
+		err := rows.Scan(&acct.Access, &acct.Secret, &acct.Role, &acct.UserID, &acct.GroupID, &acct.ProjectID, &acct.Quota)
+		if err != nil {
+			return nil, "", fmt.Errorf("list accounts: %w", err)
+		}
+		accs = append(accs, acct)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("list accounts: %w", err)
+	}
+
+	if len(accs) > limit {
+		accs = accs[:limit]
+		return accs, accs[limit-1].Access, nil
+	}
+
+	return accs, "", nil
+}
+
+// SetAccountQuota sets the storage quota of the account
+func (s *SQLIAMService) SetAccountQuota(access string, quota int64) error {
+	res, err := s.db.Exec(s.rebind(`UPDATE versitygw_accounts SET quota = ? WHERE access = ?`),
+		quota, access)
+	if err != nil {
+		return fmt.Errorf("update account quota: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update account quota: %w", err)
+	}
+	if n == 0 {
+		// mysql doesn't count the rows updated to the same value
+		_, err := s.GetUserAccount(access)
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown graceful termination of service
+func (s *SQLIAMService) Shutdown() error {
+	return s.db.Close()
+}
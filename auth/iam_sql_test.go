@@ -0,0 +1,422 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSQL is a database/sql driver serving the statements of the sql IAM
+// service from memory. It records the statements and checks that they use
+// the placeholders of the driver dialect.
+type fakeSQL struct {
+	mu         sync.Mutex
+	dialect    string
+	statements []string
+	failOn     string
+	version    *int64
+	accounts   map[string][]driver.Value
+}
+
+func newFakeSQL(dialect string) *fakeSQL {
+	return &fakeSQL{dialect: dialect, accounts: make(map[string][]driver.Value)}
+}
+
+func (f *fakeSQL) Connect(context.Context) (driver.Conn, error) { return fakeSQLConn{f}, nil }
+func (f *fakeSQL) Driver() driver.Driver                        { return nil }
+
+type fakeSQLConn struct{ f *fakeSQL }
+
+func (c fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeSQLStmt{f: c.f, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+func (c fakeSQLConn) Close() error              { return nil }
+func (c fakeSQLConn) Begin() (driver.Tx, error) { return fakeSQLTx{}, nil }
+
+type fakeSQLTx struct{}
+
+func (fakeSQLTx) Commit() error   { return nil }
+func (fakeSQLTx) Rollback() error { return nil }
+
+type fakeSQLStmt struct {
+	f     *fakeSQL
+	query string
+}
+
+func (s fakeSQLStmt) Close() error  { return nil }
+func (s fakeSQLStmt) NumInput() int { return -1 }
+
+func (s fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	_, n, err := s.f.run(s.query, args)
+	return driver.RowsAffected(n), err
+}
+
+func (s fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, _, err := s.f.run(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+var accountColumns = []string{"access", "secret", "role", "user_id", "group_id", "project_id", "quota"}
+
+func (f *fakeSQL) run(query string, args []driver.Value) (*fakeSQLRows, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.statements = append(f.statements, query)
+	if f.failOn != "" && strings.Contains(query, f.failOn) {
+		return nil, 0, errors.New("injected failure")
+	}
+
+	placeholders := strings.Count(query, "?")
+	if f.dialect == SQLDriverPostgres {
+		if placeholders != 0 {
+			return nil, 0, fmt.Errorf("postgres query with ? placeholders: %v", query)
+		}
+		placeholders = strings.Count(query, "$")
+	} else if strings.Contains(query, "$") {
+		return nil, 0, fmt.Errorf("mysql query with $ placeholders: %v", query)
+	}
+	if placeholders != len(args) {
+		return nil, 0, fmt.Errorf("%v placeholders for %v args: %v", placeholders, len(args), query)
+	}
+
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE"), strings.HasPrefix(query, "ALTER TABLE"),
+		strings.HasPrefix(query, "LOCK TABLE"):
+		return &fakeSQLRows{}, 0, nil
+	case strings.HasPrefix(query, "SELECT version FROM versitygw_schema"):
+		rows := &fakeSQLRows{columns: []string{"version"}}
+		if f.version != nil {
+			rows.values = append(rows.values, []driver.Value{*f.version})
+		}
+		return rows, 0, nil
+	case strings.HasPrefix(query, "INSERT INTO versitygw_schema"):
+		var v int64
+		f.version = &v
+		return nil, 1, nil
+	case strings.HasPrefix(query, "UPDATE versitygw_schema SET version"):
+		v := args[0].(int64)
+		f.version = &v
+		return nil, 1, nil
+	case strings.HasPrefix(query, "SELECT COUNT(*) FROM versitygw_accounts WHERE access"):
+		var n int64
+		if _, ok := f.accounts[args[0].(string)]; ok {
+			n = 1
+		}
+		return &fakeSQLRows{columns: []string{"count"}, values: [][]driver.Value{{n}}}, 0, nil
+	case strings.HasPrefix(query, "INSERT INTO versitygw_accounts"):
+		f.accounts[args[0].(string)] = args
+		return nil, 1, nil
+	case strings.HasPrefix(query, "SELECT access, secret") && strings.Contains(query, "WHERE access > "):
+		var keys []string
+		for k := range f.accounts {
+			if k > args[0].(string) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		if limit := int(args[1].(int64)); len(keys) > limit {
+			keys = keys[:limit]
+		}
+		rows := &fakeSQLRows{columns: accountColumns}
+		for _, k := range keys {
+			rows.values = append(rows.values, f.accounts[k])
+		}
+		return rows, 0, nil
+	case strings.HasPrefix(query, "SELECT access, secret"):
+		rows := &fakeSQLRows{columns: accountColumns}
+		if acct, ok := f.accounts[args[0].(string)]; ok {
+			rows.values = append(rows.values, acct)
+		}
+		return rows, 0, nil
+	case strings.HasPrefix(query, "DELETE FROM versitygw_accounts"):
+		delete(f.accounts, args[0].(string))
+		return nil, 1, nil
+	case strings.HasPrefix(query, "UPDATE versitygw_accounts SET quota"):
+		acct, ok := f.accounts[args[1].(string)]
+		if !ok {
+			return nil, 0, nil
+		}
+		acct[6] = args[0]
+		return nil, 1, nil
+	}
+
+	return nil, 0, fmt.Errorf("unexpected query: %v", query)
+}
+
+type fakeSQLRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeSQLRows) Columns() []string { return r.columns }
+func (r *fakeSQLRows) Close() error      { return nil }
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func newFakeSQLService(t *testing.T, dialect string) (*SQLIAMService, *fakeSQL) {
+	t.Helper()
+	f := newFakeSQL(dialect)
+	s := &SQLIAMService{db: sql.OpenDB(f), driver: dialect}
+	t.Cleanup(func() { s.Shutdown() })
+	return s, f
+}
+
+func TestSQLRebind(t *testing.T) {
+	tests := []struct {
+		driver string
+		query  string
+		want   string
+	}{
+		{SQLDriverPostgres, `SELECT a FROM t WHERE b = ? AND c > ?`, `SELECT a FROM t WHERE b = $1 AND c > $2`},
+		{SQLDriverPostgres, `SELECT a FROM t`, `SELECT a FROM t`},
+		{SQLDriverMySQL, `SELECT a FROM t WHERE b = ? AND c > ?`, `SELECT a FROM t WHERE b = ? AND c > ?`},
+	}
+
+	for _, tt := range tests {
+		s := &SQLIAMService{driver: tt.driver}
+		if got := s.rebind(tt.query); got != tt.want {
+			t.Errorf("%v rebind = %v, want %v", tt.driver, got, tt.want)
+		}
+	}
+}
+
+func TestSQLMigrate(t *testing.T) {
+	for _, dialect := range []string{SQLDriverPostgres, SQLDriverMySQL} {
+		t.Run(dialect, func(t *testing.T) {
+			s, f := newFakeSQLService(t, dialect)
+
+			if err := s.migrate(); err != nil {
+				t.Fatal(err)
+			}
+			if f.version == nil || *f.version != int64(len(sqlMigrations)) {
+				t.Fatalf("schema version = %v, want %v", f.version, len(sqlMigrations))
+			}
+
+			lock := "LOCK TABLE versitygw_schema IN EXCLUSIVE MODE"
+			if dialect == SQLDriverMySQL {
+				lock = "SELECT version FROM versitygw_schema FOR UPDATE"
+			}
+			if f.statements[1] != lock {
+				t.Errorf("lock statement = %v, want %v", f.statements[1], lock)
+			}
+
+			// the migrations are applied in order, each followed by the
+			// schema version update
+			var applied []string
+			for _, stmt := range f.statements {
+				if strings.HasPrefix(stmt, "CREATE TABLE versitygw_accounts") ||
+					strings.HasPrefix(stmt, "ALTER TABLE") {
+					applied = append(applied, stmt)
+				}
+			}
+			var want []string
+			for _, m := range sqlMigrations {
+				want = append(want, strings.Join(strings.Fields(m), " "))
+			}
+			if !reflect.DeepEqual(applied, want) {
+				t.Errorf("applied migrations = %v, want %v", applied, want)
+			}
+
+			// an up to date schema is left alone
+			f.statements = nil
+			if err := s.migrate(); err != nil {
+				t.Fatal(err)
+			}
+			for _, stmt := range f.statements {
+				if strings.HasPrefix(stmt, "ALTER TABLE") || strings.HasPrefix(stmt, "CREATE TABLE versitygw_accounts") {
+					t.Errorf("migration applied again: %v", stmt)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLMigratePartial(t *testing.T) {
+	s, f := newFakeSQLService(t, SQLDriverPostgres)
+	v := int64(1)
+	f.version = &v
+
+	if err := s.migrate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range f.statements {
+		if strings.HasPrefix(stmt, "CREATE TABLE versitygw_accounts") {
+			t.Errorf("first migration applied again")
+		}
+	}
+	if *f.version != int64(len(sqlMigrations)) {
+		t.Errorf("schema version = %v, want %v", *f.version, len(sqlMigrations))
+	}
+
+	f.failOn = "ALTER TABLE"
+	v = 1
+	f.version = &v
+	if err := s.migrate(); err == nil || !strings.Contains(err.Error(), "migrate schema to version 2") {
+		t.Errorf("migrate error = %v, want failed version 2 migration", err)
+	}
+}
+
+func TestSQLIAMService(t *testing.T) {
+	for _, dialect := range []string{SQLDriverPostgres, SQLDriverMySQL} {
+		t.Run(dialect, func(t *testing.T) {
+			s, f := newFakeSQLService(t, dialect)
+
+			user1 := Account{Access: "user1", Secret: "secret1", Role: RoleUserPlus, UserID: 1001, GroupID: 2001, ProjectID: 3, Quota: 1 << 30}
+			if err := s.CreateAccount(user1); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.CreateAccount(user1); !errors.Is(err, ErrUserExists) {
+				t.Fatalf("create existing account error = %v, want %v", err, ErrUserExists)
+			}
+
+			got, err := s.GetUserAccount("user1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, user1) {
+				t.Errorf("get account = %+v, want %+v", got, user1)
+			}
+			if _, err := s.GetUserAccount("nobody"); !errors.Is(err, ErrNoSuchUser) {
+				t.Errorf("get missing account error = %v, want %v", err, ErrNoSuchUser)
+			}
+
+			if err := s.SetAccountQuota("user1", 42); err != nil {
+				t.Fatal(err)
+			}
+			got, _ = s.GetUserAccount("user1")
+			if got.Quota != 42 {
+				t.Errorf("quota = %v, want 42", got.Quota)
+			}
+			if err := s.SetAccountQuota("nobody", 42); !errors.Is(err, ErrNoSuchUser) {
+				t.Errorf("set missing account quota error = %v, want %v", err, ErrNoSuchUser)
+			}
+
+			if err := s.DeleteUserAccount("user1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.GetUserAccount("user1"); !errors.Is(err, ErrNoSuchUser) {
+				t.Errorf("get deleted account error = %v, want %v", err, ErrNoSuchUser)
+			}
+
+			f.failOn = "SELECT access, secret"
+			if _, err := s.GetUserAccount("user1"); err == nil || errors.Is(err, ErrNoSuchUser) {
+				t.Errorf("get account error = %v, want query failure", err)
+			}
+			f.failOn = "INSERT INTO versitygw_accounts"
+			if err := s.CreateAccount(user1); err == nil {
+				t.Errorf("expected create account error")
+			}
+		})
+	}
+}
+
+func TestSQLListUserAccounts(t *testing.T) {
+	s, _ := newFakeSQLService(t, SQLDriverPostgres)
+
+	var want []string
+	for i := 0; i < 7; i++ {
+		access := fmt.Sprintf("user%v", i)
+		want = append(want, access)
+		if err := s.CreateAccount(Account{Access: access, Role: RoleUser}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	marker := ""
+	pages := 0
+	for {
+		page, next, err := s.ListUserAccountsPage(marker, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, acct := range page {
+			got = append(got, acct.Access)
+		}
+		if next == "" {
+			break
+		}
+		if next != page[len(page)-1].Access {
+			t.Fatalf("marker = %v, want last access key %v", next, page[len(page)-1].Access)
+		}
+		marker = next
+	}
+	if pages != 3 {
+		t.Errorf("pages = %v, want 3", pages)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("paged accounts = %v, want %v", got, want)
+	}
+
+	accts, err := s.ListUserAccounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accts) != len(want) {
+		t.Errorf("listed %v accounts, want %v", len(accts), len(want))
+	}
+}
+
+func TestNewSQLServiceErrors(t *testing.T) {
+	// nothing listens on the port of a closed listener
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	tests := []struct {
+		name   string
+		driver string
+		dsn    string
+	}{
+		{name: "unsupported-driver", driver: "sqlite", dsn: "file.db"},
+		{name: "missing-dsn", driver: SQLDriverPostgres},
+		{name: "unreachable-postgres", driver: SQLDriverPostgres, dsn: "postgres://user:pass@" + addr + "/db?sslmode=disable&connect_timeout=2"},
+		{name: "unreachable-mysql", driver: SQLDriverMySQL, dsn: "user:pass@tcp(" + addr + ")/db?timeout=2s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSQLService(tt.driver, tt.dsn); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultVaultKVMount  = "secret"
+	defaultVaultKVPrefix = "versitygw/accounts"
+	vaultTokenHeader     = "X-Vault-Token"
+)
+
+var errVaultNotFound = errors.New("vault secret not found")
+
+// VaultConfig is the Vault IAM service configuration. Either the token
+// or the approle role id and secret id have to be provided.
+type VaultConfig struct {
+	Endpoint     string
+	Token        string
+	RoleID       string
+	RoleSecretID string
+	Mount        string
+	Prefix       string
+}
+
+// VaultIAMService stores the accounts in a HashiCorp Vault KV version 2
+// secrets engine, one secret per account. Vault keeps the secrets
+// encrypted at rest, and every account update is a new secret version.
+// Deleting an account deletes its latest version, so the previous
+// versions can still be recovered from Vault.
+type VaultIAMService struct {
+	endpoint string
+	mount    string
+	prefix   string
+	roleID   string
+	secretID string
+	client   *http.Client
+
+	tokenMu  sync.Mutex
+	token    string
+	tokenExp time.Time
+}
+
+var _ IAMService = &VaultIAMService{}
+
+func NewVaultService(cfg VaultConfig) (IAMService, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("vault endpoint should be specified")
+	}
+	if cfg.Token == "" && (cfg.RoleID == "" || cfg.RoleSecretID == "") {
+		return nil, fmt.Errorf("vault token or approle credentials should be specified")
+	}
+	if _, err := url.Parse(cfg.Endpoint); err != nil {
+		return nil, fmt.Errorf("parse vault endpoint: %w", err)
+	}
+	if cfg.Mount == "" {
+		cfg.Mount = defaultVaultKVMount
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultVaultKVPrefix
+	}
+
+	v := &VaultIAMService{
+		endpoint: strings.TrimSuffix(cfg.Endpoint, "/"),
+		mount:    strings.Trim(cfg.Mount, "/"),
+		prefix:   strings.Trim(cfg.Prefix, "/"),
+		roleID:   cfg.RoleID,
+		secretID: cfg.RoleSecretID,
+		token:    cfg.Token,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+
+	// check the vault credentials upfront
+	if _, err := v.getToken(); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+type vaultKVMetadata struct {
+	Data struct {
+		CurrentVersion int `json:"current_version"`
+	} `json:"data"`
+}
+
+type vaultKVSecret struct {
+	Data struct {
+		Data Account `json:"data"`
+	} `json:"data"`
+}
+
+func (v *VaultIAMService) CreateAccount(account Account) error {
+	_, err := v.GetUserAccount(account.Access)
+	if err == nil {
+		return ErrUserExists
+	}
+	if !errors.Is(err, ErrNoSuchUser) {
+		return err
+	}
+
+	// the write only succeeds if no other version was written meanwhile
+	var meta vaultKVMetadata
+	err = v.do(http.MethodGet, v.path("metadata", account.Access), nil, &meta)
+	if err != nil && !errors.Is(err, errVaultNotFound) {
+		return fmt.Errorf("get account metadata: %w", err)
+	}
+
+	err = v.do(http.MethodPost, v.path("data", account.Access), map[string]any{
+		"options": map[string]any{"cas": meta.Data.CurrentVersion},
+		"data":    account,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("store account: %w", err)
+	}
+
+	return nil
+}
+
+func (v *VaultIAMService) GetUserAccount(access string) (Account, error) {
+	var secret vaultKVSecret
+	err := v.do(http.MethodGet, v.path("data", access), nil, &secret)
+	if errors.Is(err, errVaultNotFound) {
+		return Account{}, ErrNoSuchUser
+	}
+	if err != nil {
+		return Account{}, fmt.Errorf("get account: %w", err)
+	}
+
+	return secret.Data.Data, nil
+}
+
+func (v *VaultIAMService) DeleteUserAccount(access string) error {
+	if _, err := v.GetUserAccount(access); err != nil {
+		return err
+	}
+
+	err := v.do(http.MethodDelete, v.path("data", access), nil, nil)
+	if err != nil {
+		return fmt.Errorf("delete account: %w", err)
+	}
+
+	return nil
+}
+
+type vaultKVList struct {
+	Data struct {
+		Keys []string `json:"keys"`
+	} `json:"data"`
+}
+
+func (v *VaultIAMService) ListUserAccounts() ([]Account, error) {
+	var list vaultKVList
+	err := v.do("LIST", v.path("metadata", ""), nil, &list)
+	if errors.Is(err, errVaultNotFound) {
+		return []Account{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list accounts: %w", err)
+	}
+
+	accs := []Account{}
+	for _, key := range list.Data.Keys {
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+		acct, err := v.GetUserAccount(key)
+		if errors.Is(err, ErrNoSuchUser) {
+			// the latest version of the account was deleted
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		accs = append(accs, acct)
+	}
+
+	return accs, nil
+}
+
+// Shutdown graceful termination of service
+func (v *VaultIAMService) Shutdown() error {
+	return nil
+}
+
+func (v *VaultIAMService) path(kind, access string) string {
+	p := fmt.Sprintf("%v/%v/%v", v.mount, kind, v.prefix)
+	if access != "" {
+		p += "/" + url.PathEscape(access)
+	}
+	return p
+}
+
+type vaultLoginResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+}
+
+// getToken returns the vault token, logging in with the approle
+// credentials when the token is missing or about to expire
+func (v *VaultIAMService) getToken() (string, error) {
+	v.tokenMu.Lock()
+	defer v.tokenMu.Unlock()
+
+	if v.roleID == "" {
+		return v.token, nil
+	}
+	if v.token != "" && time.Now().Add(time.Minute).Before(v.tokenExp) {
+		return v.token, nil
+	}
+
+	var resp vaultLoginResponse
+	err := v.request(http.MethodPost, "auth/approle/login", "", map[string]any{
+		"role_id":   v.roleID,
+		"secret_id": v.secretID,
+	}, &resp)
+	if err != nil {
+		return "", fmt.Errorf("vault approle login: %w", err)
+	}
+
+	v.token = resp.Auth.ClientToken
+	v.tokenExp = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second)
+	return v.token, nil
+}
+
+func (v *VaultIAMService) do(method, path string, body, out any) error {
+	token, err := v.getToken()
+	if err != nil {
+		return err
+	}
+
+	return v.request(method, path, token, body, out)
+}
+
+type vaultErrorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+func (v *VaultIAMService) request(method, path, token string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal vault request: %w", err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%v/v1/%v", v.endpoint, path), r)
+	if err != nil {
+		return fmt.Errorf("create vault request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set(vaultTokenHeader, token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send vault request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read vault response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errVaultNotFound
+	}
+	if resp.StatusCode >= 400 {
+		var verr vaultErrorResponse
+		_ = json.Unmarshal(data, &verr)
+		return fmt.Errorf("vault request failed (%v): %v",
+			resp.StatusCode, strings.Join(verr.Errors, ", "))
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parse vault response: %w", err)
+	}
+
+	return nil
+}
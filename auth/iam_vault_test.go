@@ -0,0 +1,309 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVault is a minimal Vault KV version 2 secrets engine with approle
+// login
+type fakeVault struct {
+	mu       sync.Mutex
+	token    string
+	roleID   string
+	secretID string
+	lease    int
+	logins   int
+	// secrets holds the versions of each secret, a nil version is a
+	// deleted one
+	secrets map[string][]map[string]any
+}
+
+func newFakeVault() *fakeVault {
+	return &fakeVault{
+		token:   "root-token",
+		lease:   3600,
+		secrets: make(map[string][]map[string]any),
+	}
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if path == "auth/approle/login" {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["role_id"] != f.roleID || req["secret_id"] != f.secretID {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []string{"invalid role or secret ID"}})
+			return
+		}
+		f.logins++
+		json.NewEncoder(w).Encode(map[string]any{"auth": map[string]any{
+			"client_token":   f.token,
+			"lease_duration": f.lease,
+		}})
+		return
+	}
+
+	if r.Header.Get(vaultTokenHeader) != f.token {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]any{"errors": []string{"permission denied"}})
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(path, "secret/data/"):
+		key := strings.TrimPrefix(path, "secret/data/")
+		versions := f.secrets[key]
+		switch r.Method {
+		case http.MethodGet:
+			if len(versions) == 0 || versions[len(versions)-1] == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": versions[len(versions)-1]}})
+		case http.MethodPost:
+			var req struct {
+				Options struct {
+					Cas *int `json:"cas"`
+				} `json:"options"`
+				Data map[string]any `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Options.Cas != nil && *req.Options.Cas != len(versions) {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]any{"errors": []string{"check-and-set parameter did not match the current version"}})
+				return
+			}
+			f.secrets[key] = append(versions, req.Data)
+		case http.MethodDelete:
+			if len(versions) != 0 {
+				f.secrets[key] = append(versions, nil)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case strings.HasPrefix(path, "secret/metadata/"):
+		key := strings.TrimPrefix(path, "secret/metadata/")
+		switch r.Method {
+		case http.MethodGet:
+			versions, ok := f.secrets[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"current_version": len(versions)}})
+		case "LIST":
+			var keys []string
+			for k := range f.secrets {
+				if name, ok := strings.CutPrefix(k, key+"/"); ok {
+					keys = append(keys, name)
+				}
+			}
+			if len(keys) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			sort.Strings(keys)
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"keys": keys}})
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestNewVaultServiceConfig(t *testing.T) {
+	fv := newFakeVault()
+	fv.roleID, fv.secretID = "role", "secret"
+	srv := httptest.NewServer(fv)
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		cfg     VaultConfig
+		wantErr bool
+	}{
+		{name: "missing-endpoint", cfg: VaultConfig{Token: "root-token"}, wantErr: true},
+		{name: "missing-credentials", cfg: VaultConfig{Endpoint: srv.URL}, wantErr: true},
+		{name: "missing-approle-secret", cfg: VaultConfig{Endpoint: srv.URL, RoleID: "role"}, wantErr: true},
+		{name: "token", cfg: VaultConfig{Endpoint: srv.URL, Token: "root-token"}},
+		{name: "approle", cfg: VaultConfig{Endpoint: srv.URL, RoleID: "role", RoleSecretID: "secret"}},
+		{name: "approle-login-failed", cfg: VaultConfig{Endpoint: srv.URL, RoleID: "role", RoleSecretID: "wrong"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVaultService(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultIAMService(t *testing.T) {
+	fv := newFakeVault()
+	srv := httptest.NewServer(fv)
+	defer srv.Close()
+
+	iam, err := NewVaultService(VaultConfig{Endpoint: srv.URL + "/", Token: "root-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accts, err := iam.ListUserAccounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accts) != 0 {
+		t.Fatalf("expected no accounts, got %v", accts)
+	}
+
+	user1 := Account{Access: "user1", Secret: "secret1", Role: RoleUserPlus, UserID: 1001, GroupID: 2001, ProjectID: 3}
+	user2 := Account{Access: "user2", Secret: "secret2", Role: RoleAdmin}
+
+	for _, acct := range []Account{user1, user2} {
+		if err := iam.CreateAccount(acct); err != nil {
+			t.Fatalf("create %v: %v", acct.Access, err)
+		}
+	}
+	if err := iam.CreateAccount(user1); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("create existing account error = %v, want %v", err, ErrUserExists)
+	}
+
+	// the accounts are stored under the prefix, with the account
+	// attributes as the secret data
+	data := fv.secrets["versitygw/accounts/user1"]
+	if len(data) != 1 || data[0]["access"] != "user1" || data[0]["role"] != "userplus" ||
+		data[0]["userID"] != float64(1001) {
+		t.Fatalf("unexpected secret data %v", data)
+	}
+
+	got, err := iam.GetUserAccount("user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, user1) {
+		t.Errorf("get account = %+v, want %+v", got, user1)
+	}
+
+	if _, err := iam.GetUserAccount("nobody"); !errors.Is(err, ErrNoSuchUser) {
+		t.Errorf("get missing account error = %v, want %v", err, ErrNoSuchUser)
+	}
+
+	accts, err = iam.ListUserAccounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accts) != 2 {
+		t.Fatalf("expected 2 accounts, got %v", accts)
+	}
+
+	if err := iam.DeleteUserAccount("user1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := iam.DeleteUserAccount("user1"); !errors.Is(err, ErrNoSuchUser) {
+		t.Errorf("delete missing account error = %v, want %v", err, ErrNoSuchUser)
+	}
+
+	// deleted accounts are skipped, and can be created again on top of
+	// the deleted version
+	accts, err = iam.ListUserAccounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accts) != 1 || accts[0].Access != "user2" {
+		t.Fatalf("expected only user2, got %v", accts)
+	}
+	if err := iam.CreateAccount(user1); err != nil {
+		t.Fatalf("recreate deleted account: %v", err)
+	}
+}
+
+func TestVaultIAMServiceErrors(t *testing.T) {
+	fv := newFakeVault()
+	srv := httptest.NewServer(fv)
+	defer srv.Close()
+
+	iam, err := NewVaultService(VaultConfig{Endpoint: srv.URL, Token: "root-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a revoked token fails the requests with the vault errors
+	fv.mu.Lock()
+	fv.token = "new-token"
+	fv.mu.Unlock()
+
+	_, err = iam.GetUserAccount("user1")
+	if err == nil || errors.Is(err, ErrNoSuchUser) || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("get account error = %v, want permission denied", err)
+	}
+	if err := iam.CreateAccount(Account{Access: "user1"}); err == nil {
+		t.Errorf("expected create account error")
+	}
+	if _, err := iam.ListUserAccounts(); err == nil {
+		t.Errorf("expected list accounts error")
+	}
+
+	// unreachable server
+	srv.Close()
+	if _, err := iam.GetUserAccount("user1"); err == nil {
+		t.Errorf("expected error from unreachable server")
+	}
+}
+
+func TestVaultApproleTokenRenewal(t *testing.T) {
+	fv := newFakeVault()
+	fv.roleID, fv.secretID = "role", "secret"
+	// tokens expiring within a minute are renewed before each request
+	fv.lease = 30
+	srv := httptest.NewServer(fv)
+	defer srv.Close()
+
+	iam, err := NewVaultService(VaultConfig{Endpoint: srv.URL, RoleID: "role", RoleSecretID: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := iam.GetUserAccount("user1"); !errors.Is(err, ErrNoSuchUser) {
+		t.Fatalf("get account error = %v, want %v", err, ErrNoSuchUser)
+	}
+	if fv.logins != 2 {
+		t.Errorf("expected 2 logins for short lived tokens, got %v", fv.logins)
+	}
+
+	fv.lease = 3600
+	iam, err = NewVaultService(VaultConfig{Endpoint: srv.URL, RoleID: "role", RoleSecretID: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		iam.GetUserAccount("user1")
+	}
+	if fv.logins != 3 {
+		t.Errorf("expected the token to be reused, got %v logins", fv.logins)
+	}
+}
@@ -33,6 +33,52 @@ type BucketLockConfig struct {
 	CreatedAt        *time.Time
 }
 
+// DefaultObjectRetention returns the retention the bucket default
+// retention rule applies to an object created at the given time, or nil
+// when object lock is disabled or there is no default retention
+func (c BucketLockConfig) DefaultObjectRetention(created time.Time) *types.ObjectLockRetention {
+	if !c.Enabled || c.DefaultRetention == nil || c.DefaultRetention.Mode == "" {
+		return nil
+	}
+
+	retainUntilDate := created
+	if c.DefaultRetention.Days != nil {
+		retainUntilDate = retainUntilDate.AddDate(0, 0, int(*c.DefaultRetention.Days))
+	}
+	if c.DefaultRetention.Years != nil {
+		retainUntilDate = retainUntilDate.AddDate(int(*c.DefaultRetention.Years), 0, 0)
+	}
+
+	return &types.ObjectLockRetention{
+		Mode:            types.ObjectLockRetentionMode(c.DefaultRetention.Mode),
+		RetainUntilDate: &retainUntilDate,
+	}
+}
+
+// MarshalDefaultRetention returns the encoded retention the default
+// retention rule of the encoded bucket lock configuration applies to an
+// object created at the given time, or nil when there is none
+func MarshalDefaultRetention(lockConfig []byte, created time.Time) ([]byte, error) {
+	if len(lockConfig) == 0 {
+		return nil, nil
+	}
+
+	var cfg BucketLockConfig
+	if err := json.Unmarshal(lockConfig, &cfg); err != nil {
+		return nil, fmt.Errorf("parse bucket lock config: %w", err)
+	}
+	retention := cfg.DefaultObjectRetention(created)
+	if retention == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(retention)
+	if err != nil {
+		return nil, fmt.Errorf("parse object lock retention: %w", err)
+	}
+	return data, nil
+}
+
 func ParseBucketLockConfigurationInput(input []byte) ([]byte, error) {
 	var lockConfig types.ObjectLockConfiguration
 	if err := xml.Unmarshal(input, &lockConfig); err != nil {
@@ -76,21 +122,31 @@ func ParseBucketLockConfigurationOutput(input []byte) (*types.ObjectLockConfigur
 	return result, nil
 }
 
+// ParseObjectLockRetentionInput validates the retention of a put object
+// retention request. An empty retention removes the object retention,
+// otherwise both a valid mode and a future retain until date are required.
 func ParseObjectLockRetentionInput(input []byte) ([]byte, error) {
 	var retention types.ObjectLockRetention
 	if err := xml.Unmarshal(input, &retention); err != nil {
-		return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+		return nil, s3err.GetAPIError(s3err.ErrMalformedXML)
 	}
 
-	if retention.RetainUntilDate == nil || retention.RetainUntilDate.Before(time.Now()) {
-		return nil, s3err.GetAPIError(s3err.ErrPastObjectLockRetainDate)
+	if retention.Mode == "" && retention.RetainUntilDate == nil {
+		return json.Marshal(retention)
 	}
+
 	switch retention.Mode {
 	case types.ObjectLockRetentionModeCompliance:
 	case types.ObjectLockRetentionModeGovernance:
 	default:
 		return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
 	}
+	if retention.RetainUntilDate == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
+	if retention.RetainUntilDate.Before(time.Now()) {
+		return nil, s3err.GetAPIError(s3err.ErrPastObjectLockRetainDate)
+	}
 
 	return json.Marshal(retention)
 }
@@ -120,7 +176,12 @@ func ParseObjectLegalHoldOutput(status *bool) *types.ObjectLockLegalHold {
 	}
 }
 
-func CheckObjectAccess(ctx context.Context, bucket, userAccess string, objects []string, isAdminOrRoot bool, be backend.Backend) error {
+// CheckObjectAccess verifies that none of the objects are protected by
+// object lock before they are deleted or overwritten. Unexpired COMPLIANCE
+// retention and legal holds can not be bypassed, while GOVERNANCE retention
+// is only bypassed when the request asks for it and the user is allowed to
+// bypass governance retention.
+func CheckObjectAccess(ctx context.Context, bucket, userAccess string, objects []string, bypass, isAdminOrRoot bool, be backend.Backend) error {
 	data, err := be.GetObjectLockConfiguration(ctx, bucket)
 	if err != nil {
 		if errors.Is(err, s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotFound)) {
@@ -139,13 +200,10 @@ func CheckObjectAccess(ctx context.Context, bucket, userAccess string, objects [
 		return nil
 	}
 
-	objExists := true
-
 	for _, obj := range objects {
 		var checkRetention bool = true
 		retentionData, err := be.GetObjectRetention(ctx, bucket, obj, "")
 		if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchKey)) {
-			objExists = false
 			continue
 		}
 		if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)) {
@@ -165,18 +223,9 @@ func CheckObjectAccess(ctx context.Context, bucket, userAccess string, objects [
 				if retention.RetainUntilDate.After(time.Now()) {
 					switch retention.Mode {
 					case types.ObjectLockRetentionModeGovernance:
-						if !isAdminOrRoot {
-							policy, err := be.GetBucketPolicy(ctx, bucket)
-							if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchBucketPolicy)) {
-								return s3err.GetAPIError(s3err.ErrObjectLocked)
-							}
-							if err != nil {
-								return err
-							}
-							err = verifyBucketPolicy(policy, userAccess, bucket, obj, BypassGovernanceRetentionAction)
-							if err != nil {
-								return s3err.GetAPIError(s3err.ErrObjectLocked)
-							}
+						err := checkGovernanceBypass(ctx, be, bucket, obj, userAccess, bypass, isAdminOrRoot)
+						if err != nil {
+							return err
 						}
 					case types.ObjectLockRetentionModeCompliance:
 						return s3err.GetAPIError(s3err.ErrObjectLocked)
@@ -193,38 +242,79 @@ func CheckObjectAccess(ctx context.Context, bucket, userAccess string, objects [
 			return err
 		}
 
-		if *status && !isAdminOrRoot {
+		if status != nil && *status {
 			return s3err.GetAPIError(s3err.ErrObjectLocked)
 		}
 	}
 
-	if bucketLockConfig.DefaultRetention != nil && bucketLockConfig.CreatedAt != nil && objExists {
-		expirationDate := *bucketLockConfig.CreatedAt
-		if bucketLockConfig.DefaultRetention.Days != nil {
-			expirationDate = expirationDate.AddDate(0, 0, int(*bucketLockConfig.DefaultRetention.Days))
-		}
-		if bucketLockConfig.DefaultRetention.Years != nil {
-			expirationDate = expirationDate.AddDate(int(*bucketLockConfig.DefaultRetention.Years), 0, 0)
-		}
+	return nil
+}
 
-		if expirationDate.After(time.Now()) {
-			switch bucketLockConfig.DefaultRetention.Mode {
-			case types.ObjectLockRetentionModeGovernance:
-				if !isAdminOrRoot {
-					policy, err := be.GetBucketPolicy(ctx, bucket)
-					if err != nil {
-						return err
-					}
-					err = verifyBucketPolicy(policy, userAccess, bucket, "", BypassGovernanceRetentionAction)
-					if err != nil {
-						return s3err.GetAPIError(s3err.ErrObjectLocked)
-					}
-				}
-			case types.ObjectLockRetentionModeCompliance:
-				return s3err.GetAPIError(s3err.ErrObjectLocked)
-			}
-		}
+// CheckRetentionUpdate verifies that a new object retention does not weaken
+// an unexpired retention of the object. Removing the retention, moving the
+// retain until date earlier, or changing COMPLIANCE to GOVERNANCE mode is
+// never allowed for COMPLIANCE retention, and requires bypassing governance
+// retention for GOVERNANCE retention.
+func CheckRetentionUpdate(ctx context.Context, be backend.Backend, bucket, object, versionId, userAccess string, retention []byte, bypass, isAdminOrRoot bool) error {
+	data, err := be.GetObjectRetention(ctx, bucket, object, versionId)
+	if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	current, err := ParseObjectLockRetentionOutput(data)
+	if err != nil {
+		return err
+	}
+	if current.Mode == "" || current.RetainUntilDate == nil ||
+		!current.RetainUntilDate.After(time.Now()) {
+		return nil
+	}
+
+	updated, err := ParseObjectLockRetentionOutput(retention)
+	if err != nil {
+		return err
 	}
 
+	weakened := updated.Mode == "" || updated.RetainUntilDate == nil ||
+		updated.RetainUntilDate.Before(*current.RetainUntilDate) ||
+		(current.Mode == types.ObjectLockRetentionModeCompliance &&
+			updated.Mode != types.ObjectLockRetentionModeCompliance)
+	if !weakened {
+		return nil
+	}
+
+	if current.Mode == types.ObjectLockRetentionModeCompliance {
+		return s3err.GetAPIError(s3err.ErrObjectLocked)
+	}
+	return checkGovernanceBypass(ctx, be, bucket, object, userAccess, bypass, isAdminOrRoot)
+}
+
+// checkGovernanceBypass allows GOVERNANCE retention to be bypassed when the
+// request sets the bypass governance retention header, and the user is
+// either root, an admin, or granted s3:BypassGovernanceRetention by the
+// bucket policy
+func checkGovernanceBypass(ctx context.Context, be backend.Backend, bucket, object, userAccess string, bypass, isAdminOrRoot bool) error {
+	if !bypass {
+		return s3err.GetAPIError(s3err.ErrObjectLocked)
+	}
+	if isAdminOrRoot {
+		return nil
+	}
+
+	policy, err := be.GetBucketPolicy(ctx, bucket)
+	if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchBucketPolicy)) {
+		return s3err.GetAPIError(s3err.ErrObjectLocked)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = verifyBucketPolicy(policy, requestAccount(ctx, userAccess), bucket, object, BypassGovernanceRetentionAction, requestContext(ctx), false)
+	if err != nil {
+		return s3err.GetAPIError(s3err.ErrObjectLocked)
+	}
 	return nil
 }
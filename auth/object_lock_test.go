@@ -0,0 +1,91 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestMarshalDefaultRetention(t *testing.T) {
+	created := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	days, years := int32(10), int32(2)
+
+	config := func(enabled bool, retention *types.DefaultRetention) []byte {
+		data, err := json.Marshal(BucketLockConfig{Enabled: enabled, DefaultRetention: retention})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		config  []byte
+		mode    types.ObjectLockRetentionMode
+		until   time.Time
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "no-config", wantNil: true},
+		{name: "lock-disabled", config: config(false, &types.DefaultRetention{Mode: types.ObjectLockRetentionModeGovernance, Days: &days}), wantNil: true},
+		{name: "no-default-retention", config: config(true, nil), wantNil: true},
+		{
+			name:   "days",
+			config: config(true, &types.DefaultRetention{Mode: types.ObjectLockRetentionModeGovernance, Days: &days}),
+			mode:   types.ObjectLockRetentionModeGovernance,
+			until:  created.AddDate(0, 0, 10),
+		},
+		{
+			name:   "years",
+			config: config(true, &types.DefaultRetention{Mode: types.ObjectLockRetentionModeCompliance, Years: &years}),
+			mode:   types.ObjectLockRetentionModeCompliance,
+			until:  created.AddDate(2, 0, 0),
+		},
+		{name: "invalid-config", config: []byte("{invalid"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := MarshalDefaultRetention(tt.config, created)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantNil {
+				if data != nil {
+					t.Fatalf("expected no retention, got %s", data)
+				}
+				return
+			}
+
+			var retention types.ObjectLockRetention
+			if err := json.Unmarshal(data, &retention); err != nil {
+				t.Fatal(err)
+			}
+			if retention.Mode != tt.mode {
+				t.Errorf("mode = %v, want %v", retention.Mode, tt.mode)
+			}
+			if retention.RetainUntilDate == nil || !retention.RetainUntilDate.Equal(tt.until) {
+				t.Errorf("retain until = %v, want %v", retention.RetainUntilDate, tt.until)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+type PublicAccessBlock struct {
+	BlockPublicAcls       bool
+	IgnorePublicAcls      bool
+	BlockPublicPolicy     bool
+	RestrictPublicBuckets bool
+}
+
+func ParsePublicAccessBlockInput(input []byte) ([]byte, error) {
+	var config types.PublicAccessBlockConfiguration
+	if err := xml.Unmarshal(input, &config); err != nil {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	return json.Marshal(PublicAccessBlock{
+		BlockPublicAcls:       getBool(config.BlockPublicAcls),
+		IgnorePublicAcls:      getBool(config.IgnorePublicAcls),
+		BlockPublicPolicy:     getBool(config.BlockPublicPolicy),
+		RestrictPublicBuckets: getBool(config.RestrictPublicBuckets),
+	})
+}
+
+func ParsePublicAccessBlockOutput(input []byte) (*types.PublicAccessBlockConfiguration, error) {
+	var config PublicAccessBlock
+	if err := json.Unmarshal(input, &config); err != nil {
+		return nil, fmt.Errorf("parse public access block: %w", err)
+	}
+
+	return &types.PublicAccessBlockConfiguration{
+		BlockPublicAcls:       &config.BlockPublicAcls,
+		IgnorePublicAcls:      &config.IgnorePublicAcls,
+		BlockPublicPolicy:     &config.BlockPublicPolicy,
+		RestrictPublicBuckets: &config.RestrictPublicBuckets,
+	}, nil
+}
+
+func getBool(b *bool) bool {
+	return b != nil && *b
+}
+
+// getPublicAccessBlock returns the bucket public access block, buckets
+// without one don't block anything
+func getPublicAccessBlock(ctx context.Context, be backend.Backend, bucket string) (PublicAccessBlock, error) {
+	data, err := be.GetPublicAccessBlock(ctx, bucket)
+	if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchPublicAccessBlockConfiguration)) ||
+		errors.Is(err, s3err.GetAPIError(s3err.ErrNotImplemented)) {
+		return PublicAccessBlock{}, nil
+	}
+	if err != nil {
+		return PublicAccessBlock{}, err
+	}
+
+	var config PublicAccessBlock
+	if err := json.Unmarshal(data, &config); err != nil {
+		return PublicAccessBlock{}, fmt.Errorf("parse public access block: %w", err)
+	}
+
+	return config, nil
+}
+
+// IsPublic checks if the acl grants access to everyone or to all
+// authenticated users
+func (acl ACL) IsPublic() bool {
+	switch acl.ACL {
+	case types.BucketCannedACLPublicRead,
+		types.BucketCannedACLPublicReadWrite,
+		types.BucketCannedACLAuthenticatedRead:
+		return true
+	}
+
+	for _, grt := range acl.Grantees {
+		if grt.Type == types.TypeGroup {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withoutPublicGrants returns the acl with the public grants removed
+func (acl ACL) withoutPublicGrants() ACL {
+	grantees := acl.Grantees
+	if acl.ACL != "" && len(grantees) == 0 {
+		grantees = ExpandCannedACL(acl.ACL, acl.Owner, acl.Owner)
+	}
+
+	result := ACL{Owner: acl.Owner, Grantees: []Grantee{}}
+	for _, grt := range grantees {
+		if grt.Type != types.TypeGroup {
+			result.Grantees = append(result.Grantees, grt)
+		}
+	}
+
+	return result
+}
+
+// VerifyPublicACL rejects acls granting public access to the bucket or
+// its objects when the bucket blocks public acls
+func VerifyPublicACL(ctx context.Context, be backend.Backend, bucket string, acl ACL) error {
+	if !acl.IsPublic() {
+		return nil
+	}
+
+	config, err := getPublicAccessBlock(ctx, be, bucket)
+	if err != nil {
+		return err
+	}
+	if config.BlockPublicAcls {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+
+	return nil
+}
+
+// VerifyPublicPolicy rejects public bucket policies when the bucket
+// blocks public policies
+func VerifyPublicPolicy(ctx context.Context, be backend.Backend, bucket string, policy []byte) error {
+	var bucketPolicy BucketPolicy
+	if err := json.Unmarshal(policy, &bucketPolicy); err != nil {
+		return getMalformedPolicyError(err)
+	}
+	if !bucketPolicy.isPublic() {
+		return nil
+	}
+
+	config, err := getPublicAccessBlock(ctx, be, bucket)
+	if err != nil {
+		return err
+	}
+	if config.BlockPublicPolicy {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+
+	return nil
+}
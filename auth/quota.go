@@ -0,0 +1,202 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+// QuotaService is implemented by the IAM services able to store the
+// account storage quotas
+type QuotaService interface {
+	SetAccountQuota(access string, quota int64) error
+}
+
+// GetQuotaService returns the quota service of the IAM service, looking
+// through the IAM services wrapping others
+func GetQuotaService(svc IAMService) (QuotaService, bool) {
+	return findService[QuotaService](svc)
+}
+
+// AccountUsage is the storage used by the buckets of an account
+type AccountUsage struct {
+	Access string `json:"access"`
+	Quota  int64  `json:"quota"`
+	Used   int64  `json:"used"`
+}
+
+// QuotaTracker enforces the account storage quotas. The usage of the
+// accounts is computed from the backend bucket usage, and updated with
+// the uploaded data sizes in between the periodic reconciliations. The
+// freed space is only accounted for by the reconciliations.
+type QuotaTracker struct {
+	be  backend.Backend
+	iam IAMService
+
+	mu    sync.Mutex
+	usage map[string]int64
+}
+
+func NewQuotaTracker(be backend.Backend, iam IAMService) *QuotaTracker {
+	return &QuotaTracker{
+		be:    be,
+		iam:   iam,
+		usage: make(map[string]int64),
+	}
+}
+
+// Check returns ErrQuotaExceeded if storing size more bytes in the
+// buckets of the owner would exceed the owner quota
+func (q *QuotaTracker) Check(ctx context.Context, owner string, size int64) error {
+	if q == nil {
+		return nil
+	}
+
+	acct, err := q.iam.GetUserAccount(owner)
+	if errors.Is(err, ErrNoSuchUser) {
+		// the root account has no quota
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if acct.Quota <= 0 {
+		return nil
+	}
+
+	used, err := q.getUsage(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if used+size > acct.Quota {
+		return s3err.GetAPIError(s3err.ErrQuotaExceeded)
+	}
+
+	return nil
+}
+
+// Add accounts for size more bytes stored in the buckets of the owner
+func (q *QuotaTracker) Add(owner string, size int64) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	if used, ok := q.usage[owner]; ok {
+		q.usage[owner] = used + size
+	}
+	q.mu.Unlock()
+}
+
+// GetAccountUsage returns the quota and the storage used by the account
+func (q *QuotaTracker) GetAccountUsage(ctx context.Context, access string) (AccountUsage, error) {
+	acct, err := q.iam.GetUserAccount(access)
+	if err != nil {
+		return AccountUsage{}, err
+	}
+
+	used, err := q.getUsage(ctx, access)
+	if err != nil {
+		return AccountUsage{}, err
+	}
+
+	return AccountUsage{
+		Access: access,
+		Quota:  acct.Quota,
+		Used:   used,
+	}, nil
+}
+
+func (q *QuotaTracker) getUsage(ctx context.Context, owner string) (int64, error) {
+	q.mu.Lock()
+	used, ok := q.usage[owner]
+	q.mu.Unlock()
+	if ok {
+		return used, nil
+	}
+
+	usage, err := q.computeUsage(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	q.mu.Lock()
+	q.usage = usage
+	q.mu.Unlock()
+
+	return usage[owner], nil
+}
+
+// computeUsage sums the usage of the buckets per owner
+func (q *QuotaTracker) computeUsage(ctx context.Context) (map[string]int64, error) {
+	buckets, err := q.be.ListBucketsAndOwners(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list buckets: %w", err)
+	}
+
+	usage := make(map[string]int64)
+	for _, bucket := range buckets {
+		bu, err := q.be.GetBucketUsage(ctx, bucket.Name)
+		if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)) {
+			// deleted meanwhile
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get bucket %v usage: %w", bucket.Name, err)
+		}
+		usage[bucket.Owner] += bu.Size + bu.MultipartSize
+	}
+
+	return usage, nil
+}
+
+// Reconcile recomputes the usage of all the accounts from the backend
+func (q *QuotaTracker) Reconcile(ctx context.Context) error {
+	usage, err := q.computeUsage(ctx)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	q.usage = usage
+	q.mu.Unlock()
+
+	return nil
+}
+
+// Run reconciles the usage at every interval until the context is canceled
+func (q *QuotaTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.Reconcile(ctx); err != nil {
+				log.Printf("reconcile account quota usage: %v", err)
+			}
+		}
+	}
+}
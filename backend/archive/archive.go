@@ -0,0 +1,689 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+const (
+	nullVersionId = "null"
+)
+
+// Archive is a read-only backend exposing tar and zip archive files as
+// buckets. The archive members are indexed when the backend is created,
+// and all modifications return NotImplemented.
+type Archive struct {
+	backend.BackendUnsupported
+
+	buckets map[string]*archiveIndex
+	acl     []byte
+	owner   string
+}
+
+var _ backend.Backend = &Archive{}
+
+type ArchiveOpts struct {
+	// Owner is the account owning the archive buckets
+	Owner string
+	// PublicRead grants anonymous read access to the archive buckets
+	PublicRead bool
+}
+
+// New indexes the archive files. Each archive is exposed as a bucket
+// named after the file without its extension, or as the name given in
+// the "bucket=path" form.
+func New(archives []string, opts ArchiveOpts) (*Archive, error) {
+	acl := auth.ACL{Owner: opts.Owner}
+	if opts.PublicRead {
+		acl.ACL = types.BucketCannedACLPublicRead
+		acl.Grantees = auth.ExpandCannedACL(acl.ACL, opts.Owner, opts.Owner)
+	}
+	aclJSON, err := json.Marshal(acl)
+	if err != nil {
+		return nil, fmt.Errorf("marshal acl: %w", err)
+	}
+
+	a := &Archive{
+		buckets: make(map[string]*archiveIndex),
+		acl:     aclJSON,
+		owner:   opts.Owner,
+	}
+
+	for _, arg := range archives {
+		bucket, archivePath, ok := strings.Cut(arg, "=")
+		if !ok {
+			archivePath = arg
+			bucket = strings.TrimSuffix(path.Base(archivePath), path.Ext(archivePath))
+			bucket = strings.TrimSuffix(bucket, ".tar")
+		}
+		if _, ok := a.buckets[bucket]; ok {
+			a.Shutdown()
+			return nil, fmt.Errorf("duplicate bucket name %q for %v", bucket, archivePath)
+		}
+
+		idx, err := openArchive(archivePath)
+		if err != nil {
+			a.Shutdown()
+			return nil, err
+		}
+		a.buckets[bucket] = idx
+	}
+
+	return a, nil
+}
+
+func (a *Archive) Shutdown() {
+	for _, idx := range a.buckets {
+		idx.f.Close()
+	}
+}
+
+func (a *Archive) String() string {
+	return "Archive Gateway"
+}
+
+func (a *Archive) getBucket(bucket string) (*archiveIndex, error) {
+	idx, ok := a.buckets[bucket]
+	if !ok {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	return idx, nil
+}
+
+func (a *Archive) getMember(bucket, object string) (*archiveIndex, *member, error) {
+	idx, err := a.getBucket(bucket)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, ok := idx.members[object]
+	if !ok {
+		return nil, nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	return idx, m, nil
+}
+
+func getString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (a *Archive) ListBuckets(_ context.Context, owner string, isAdmin bool) (s3response.ListAllMyBucketsResult, error) {
+	var buckets []s3response.ListAllMyBucketsEntry
+	if isAdmin || owner == a.owner {
+		for name, idx := range a.buckets {
+			buckets = append(buckets, s3response.ListAllMyBucketsEntry{
+				Name:         name,
+				CreationDate: idx.created,
+			})
+		}
+	}
+
+	sort.Sort(backend.ByBucketName(buckets))
+
+	return s3response.ListAllMyBucketsResult{
+		Buckets: s3response.ListAllMyBucketsList{
+			Bucket: buckets,
+		},
+		Owner: s3response.CanonicalUser{
+			ID: owner,
+		},
+	}, nil
+}
+
+func (a *Archive) HeadBucket(_ context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+
+	_, err := a.getBucket(*input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (a *Archive) GetBucketAcl(_ context.Context, input *s3.GetBucketAclInput) ([]byte, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+
+	_, err := a.getBucket(*input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.acl, nil
+}
+
+func (a *Archive) GetBucketVersioning(_ context.Context, bucket string) (s3response.GetBucketVersioningOutput, error) {
+	_, err := a.getBucket(bucket)
+	return s3response.GetBucketVersioningOutput{}, err
+}
+
+func (a *Archive) GetBucketPolicy(_ context.Context, bucket string) ([]byte, error) {
+	_, err := a.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s3err.GetAPIError(s3err.ErrNoSuchBucketPolicy)
+}
+
+func (a *Archive) GetBucketNotificationConfiguration(_ context.Context, bucket string) ([]byte, error) {
+	_, err := a.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return []byte{}, nil
+}
+
+func (a *Archive) GetBucketLogging(_ context.Context, bucket string) ([]byte, error) {
+	_, err := a.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return []byte{}, nil
+}
+
+func (a *Archive) GetPublicAccessBlock(_ context.Context, bucket string) ([]byte, error) {
+	_, err := a.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s3err.GetAPIError(s3err.ErrNoSuchPublicAccessBlockConfiguration)
+}
+
+func (a *Archive) GetBucketTagging(_ context.Context, bucket string) (map[string]string, error) {
+	_, err := a.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s3err.GetAPIError(s3err.ErrBucketTaggingNotFound)
+}
+
+func (a *Archive) GetObjectLockConfiguration(_ context.Context, bucket string) ([]byte, error) {
+	_, err := a.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotFound)
+}
+
+func (a *Archive) ListMultipartUploads(_ context.Context, mpu *s3.ListMultipartUploadsInput) (s3response.ListMultipartUploadsResult, error) {
+	if mpu.Bucket == nil {
+		return s3response.ListMultipartUploadsResult{}, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+
+	_, err := a.getBucket(*mpu.Bucket)
+	if err != nil {
+		return s3response.ListMultipartUploadsResult{}, err
+	}
+
+	maxUploads := 0
+	if mpu.MaxUploads != nil {
+		maxUploads = int(*mpu.MaxUploads)
+	}
+
+	// archives never have multipart uploads in progress
+	return s3response.ListMultipartUploadsResult{
+		Bucket:         *mpu.Bucket,
+		Delimiter:      getString(mpu.Delimiter),
+		KeyMarker:      getString(mpu.KeyMarker),
+		MaxUploads:     maxUploads,
+		Prefix:         getString(mpu.Prefix),
+		UploadIDMarker: getString(mpu.UploadIdMarker),
+		Uploads:        []s3response.Upload{},
+	}, nil
+}
+
+func (a *Archive) ListParts(_ context.Context, input *s3.ListPartsInput) (s3response.ListPartsResult, error) {
+	if input.Bucket == nil {
+		return s3response.ListPartsResult{}, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+
+	_, err := a.getBucket(*input.Bucket)
+	if err != nil {
+		return s3response.ListPartsResult{}, err
+	}
+
+	return s3response.ListPartsResult{}, s3err.GetAPIError(s3err.ErrNoSuchUpload)
+}
+
+func (a *Archive) HeadObject(_ context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	_, m, err := a.getMember(*input.Bucket, *input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = backend.EvaluatePreconditions(m.etag, m.modTime, input.IfMatch,
+		input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.PartNumber != nil && *input.PartNumber != 1 {
+		// archive members are always a single part
+		return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
+	}
+
+	size := m.size
+	etag := m.etag
+
+	return &s3.HeadObjectOutput{
+		ContentLength: &size,
+		ETag:          &etag,
+		LastModified:  backend.GetTimePtr(m.modTime),
+	}, nil
+}
+
+func (a *Archive) GetObject(_ context.Context, input *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	idx, m, err := a.getMember(*input.Bucket, *input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = backend.EvaluatePreconditions(m.etag, m.modTime, input.IfMatch,
+		input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+	if err != nil {
+		return nil, err
+	}
+
+	acceptRange := getString(input.Range)
+	if input.PartNumber != nil {
+		// archive members are always a single part
+		acceptRange, err = backend.PartRange(m.size, nil, *input.PartNumber)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	startOffset, length, err := backend.ParseRange(m.size, acceptRange)
+	if err != nil {
+		return nil, err
+	}
+
+	contentRange := backend.ContentRange(acceptRange, startOffset, length, m.size)
+
+	// archive members have no user metadata
+	err = backend.SetWriterMetadata(writer, nil, startOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	err = idx.read(m, writer, startOffset, length)
+	if err != nil {
+		return nil, fmt.Errorf("copy data: %w", err)
+	}
+
+	etag := m.etag
+
+	return &s3.GetObjectOutput{
+		AcceptRanges:  &acceptRange,
+		ContentLength: &length,
+		ETag:          &etag,
+		LastModified:  backend.GetTimePtr(m.modTime),
+		ContentRange:  &contentRange,
+	}, nil
+}
+
+func (a *Archive) GetObjectAcl(_ context.Context, input *s3.GetObjectAclInput) ([]byte, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	_, _, err := a.getMember(*input.Bucket, *input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	// members share the bucket acl
+	return a.acl, nil
+}
+
+func (a *Archive) GetObjectAttributes(_ context.Context, input *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error) {
+	if input.Bucket == nil {
+		return s3response.GetObjectAttributesResult{}, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return s3response.GetObjectAttributesResult{}, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	_, m, err := a.getMember(*input.Bucket, *input.Key)
+	if err != nil {
+		return s3response.GetObjectAttributesResult{}, err
+	}
+
+	size := m.size
+	etag := m.etag
+	storageClass := types.StorageClassStandard
+
+	return s3response.GetObjectAttributesResult{
+		ETag:         &etag,
+		LastModified: backend.GetTimePtr(m.modTime),
+		ObjectSize:   &size,
+		StorageClass: &storageClass,
+	}, nil
+}
+
+func (a *Archive) GetObjectTagging(_ context.Context, bucket, object string) (map[string]string, error) {
+	_, _, err := a.getMember(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s3err.GetAPIError(s3err.ErrBucketTaggingNotFound)
+}
+
+func (a *Archive) GetObjectRetention(_ context.Context, bucket, object, versionId string) ([]byte, error) {
+	_, _, err := a.getMember(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)
+}
+
+func (a *Archive) GetObjectLegalHold(_ context.Context, bucket, object, versionId string) (*bool, error) {
+	_, _, err := a.getMember(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)
+}
+
+func (a *Archive) ListObjects(_ context.Context, input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	bucket := *input.Bucket
+	prefix := getString(input.Prefix)
+	marker := getString(input.Marker)
+	delim := getString(input.Delimiter)
+	maxkeys := int32(0)
+	if input.MaxKeys != nil {
+		maxkeys = *input.MaxKeys
+	}
+
+	idx, err := a.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	results := a.list(idx, prefix, delim, marker, maxkeys, true)
+
+	return &s3.ListObjectsOutput{
+		CommonPrefixes: results.CommonPrefixes,
+		Contents:       results.Objects,
+		Delimiter:      &delim,
+		IsTruncated:    &results.Truncated,
+		Marker:         &marker,
+		MaxKeys:        &maxkeys,
+		Name:           &bucket,
+		NextMarker:     &results.NextMarker,
+		Prefix:         &prefix,
+	}, nil
+}
+
+func (a *Archive) ListObjectsV2(_ context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	bucket := *input.Bucket
+	prefix := getString(input.Prefix)
+	marker := getString(input.ContinuationToken)
+	if input.StartAfter != nil && *input.StartAfter > marker {
+		marker = *input.StartAfter
+	}
+	delim := getString(input.Delimiter)
+	maxkeys := int32(0)
+	if input.MaxKeys != nil {
+		maxkeys = *input.MaxKeys
+	}
+	fetchOwner := input.FetchOwner != nil && *input.FetchOwner
+
+	idx, err := a.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	results := a.list(idx, prefix, delim, marker, maxkeys, fetchOwner)
+	count := int32(len(results.Objects))
+
+	return &s3.ListObjectsV2Output{
+		CommonPrefixes:        results.CommonPrefixes,
+		Contents:              results.Objects,
+		Delimiter:             &delim,
+		IsTruncated:           &results.Truncated,
+		ContinuationToken:     input.ContinuationToken,
+		MaxKeys:               &maxkeys,
+		Name:                  &bucket,
+		NextContinuationToken: &results.NextMarker,
+		Prefix:                &prefix,
+		StartAfter:            input.StartAfter,
+		KeyCount:              &count,
+	}, nil
+}
+
+// ListObjectVersions lists the archive members, which only ever have a
+// single "null" version
+func (a *Archive) ListObjectVersions(_ context.Context, input *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
+	if input.Bucket == nil {
+		return s3response.ListVersionsResult{}, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	bucket := *input.Bucket
+	prefix := getString(input.Prefix)
+	keyMarker := getString(input.KeyMarker)
+	versionIdMarker := getString(input.VersionIdMarker)
+	delim := getString(input.Delimiter)
+	maxkeys := int32(0)
+	if input.MaxKeys != nil {
+		maxkeys = *input.MaxKeys
+	}
+
+	if versionIdMarker != "" && keyMarker == "" {
+		return s3response.ListVersionsResult{}, s3err.GetAPIError(s3err.ErrInvalidVersionIdMarker)
+	}
+
+	idx, err := a.getBucket(bucket)
+	if err != nil {
+		return s3response.ListVersionsResult{}, err
+	}
+
+	results := a.list(idx, prefix, delim, keyMarker, maxkeys, true)
+
+	versions := make([]types.ObjectVersion, 0, len(results.Objects))
+	for _, obj := range results.Objects {
+		versions = append(versions, types.ObjectVersion{
+			ETag:         obj.ETag,
+			IsLatest:     backend.GetBoolPtr(true),
+			Key:          obj.Key,
+			LastModified: obj.LastModified,
+			Owner:        obj.Owner,
+			Size:         obj.Size,
+			StorageClass: types.ObjectVersionStorageClassStandard,
+			VersionId:    backend.GetStringPtr(nullVersionId),
+		})
+	}
+
+	result := s3response.ListVersionsResult{
+		Name:            bucket,
+		Prefix:          prefix,
+		KeyMarker:       keyMarker,
+		VersionIdMarker: versionIdMarker,
+		Delimiter:       delim,
+		MaxKeys:         maxkeys,
+		IsTruncated:     results.Truncated,
+		Versions:        versions,
+		CommonPrefixes:  results.CommonPrefixes,
+	}
+	if results.Truncated {
+		result.NextKeyMarker = results.NextMarker
+		result.NextVersionIdMarker = nullVersionId
+	}
+
+	return result, nil
+}
+
+// list returns the archive members and common prefixes in key order
+// following the marker
+func (a *Archive) list(idx *archiveIndex, prefix, delim, marker string, max int32, fetchOwner bool) backend.WalkResults {
+	var results backend.WalkResults
+	if max == 0 {
+		return results
+	}
+
+	var owner *types.Owner
+	if fetchOwner {
+		owner = &types.Owner{
+			ID:          &a.owner,
+			DisplayName: &a.owner,
+		}
+	}
+
+	// the keys are sorted, so start at the first key past both the
+	// marker and the prefix
+	start := prefix
+	if marker >= start {
+		start = marker + "\x00"
+	}
+	i := sort.SearchStrings(idx.keys, start)
+
+	var last string
+	var count int32
+	for ; i < len(idx.keys); i++ {
+		key := idx.keys[i]
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+
+		if delim != "" {
+			if j := strings.Index(key[len(prefix):], delim); j != -1 {
+				cpref := key[:len(prefix)+j+len(delim)]
+				if cpref == last || cpref <= marker {
+					// already returned with the previous listing
+					// or an earlier key
+					continue
+				}
+				if count == max {
+					results.Truncated = true
+					break
+				}
+				results.CommonPrefixes = append(results.CommonPrefixes,
+					types.CommonPrefix{Prefix: backend.GetStringPtr(cpref)})
+				last = cpref
+				count++
+				continue
+			}
+		}
+
+		if count == max {
+			results.Truncated = true
+			break
+		}
+
+		m := idx.members[key]
+		size := m.size
+		etag := m.etag
+		results.Objects = append(results.Objects, types.Object{
+			ETag:         &etag,
+			Key:          backend.GetStringPtr(key),
+			LastModified: backend.GetTimePtr(m.modTime),
+			Size:         &size,
+			Owner:        owner,
+			StorageClass: types.ObjectStorageClassStandard,
+		})
+		last = key
+		count++
+	}
+
+	if results.Truncated {
+		results.NextMarker = last
+	}
+
+	return results
+}
+
+func (a *Archive) ListBucketsAndOwners(context.Context) ([]s3response.Bucket, error) {
+	buckets := make([]s3response.Bucket, 0, len(a.buckets))
+	for name := range a.buckets {
+		buckets = append(buckets, s3response.Bucket{
+			Name:  name,
+			Owner: a.owner,
+		})
+	}
+
+	sort.SliceStable(buckets, func(i, j int) bool {
+		return buckets[i].Name < buckets[j].Name
+	})
+
+	return buckets, nil
+}
+
+// GetBucketUsage returns the member count, size and last modification
+// time of the archive
+func (a *Archive) GetBucketUsage(_ context.Context, bucket string) (s3response.BucketUsage, error) {
+	usage := s3response.BucketUsage{Bucket: bucket}
+
+	idx, err := a.getBucket(bucket)
+	if err != nil {
+		return usage, err
+	}
+
+	usage.Objects = int64(len(idx.members))
+	for _, m := range idx.members {
+		usage.Size += m.size
+		if m.modTime.After(usage.LastModified) {
+			usage.LastModified = m.modTime
+		}
+	}
+
+	return usage, nil
+}
+
+func (a *Archive) GetBucketQuota(_ context.Context, bucket string) (s3response.BucketQuota, error) {
+	_, err := a.getBucket(bucket)
+	return s3response.BucketQuota{}, err
+}
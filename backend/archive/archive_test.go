@@ -0,0 +1,180 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+var testMembers = []struct {
+	name string
+	data string
+}{
+	{"a.txt", "hello"},
+	{"dir/b.txt", "0123456789"},
+	{"dir/sub/c.txt", "abcdefghijklmnopqrstuvwxyz"},
+	{"z.txt", ""},
+}
+
+func writeTar(t *testing.T, dir string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, m := range testMembers {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     m.name,
+			Mode:     0644,
+			Size:     int64(len(m.data)),
+			ModTime:  time.Unix(1700000000, 0),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(m.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(dir, "data.tar")
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func writeZip(t *testing.T, dir string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, m := range testMembers {
+		method := zip.Store
+		if i%2 == 1 {
+			method = zip.Deflate
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     m.name,
+			Method:   method,
+			Modified: time.Unix(1700000000, 0),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(m.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(dir, "data.zip")
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	be, err := New([]string{
+		writeTar(t, dir),
+		"zipped=" + writeZip(t, dir),
+	}, ArchiveOpts{Owner: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer be.Shutdown()
+
+	ctx := context.Background()
+	maxKeys := int32(1000)
+
+	for _, bucket := range []string{"data", "zipped"} {
+		t.Run(bucket, func(t *testing.T) {
+			out, err := be.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+				Bucket:    &bucket,
+				Delimiter: backend.GetStringPtr("/"),
+				MaxKeys:   &maxKeys,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.Contents) != 2 || *out.Contents[0].Key != "a.txt" || *out.Contents[1].Key != "z.txt" {
+				t.Errorf("unexpected objects %v", out.Contents)
+			}
+			if len(out.CommonPrefixes) != 1 || *out.CommonPrefixes[0].Prefix != "dir/" {
+				t.Errorf("unexpected common prefixes %v", out.CommonPrefixes)
+			}
+
+			for _, m := range testMembers {
+				var buf bytes.Buffer
+				_, err := be.GetObject(ctx, &s3.GetObjectInput{
+					Bucket: &bucket,
+					Key:    backend.GetStringPtr(m.name),
+				}, &buf)
+				if err != nil {
+					t.Fatalf("get %v: %v", m.name, err)
+				}
+				if buf.String() != m.data {
+					t.Errorf("get %v: expected %q, got %q", m.name, m.data, buf.String())
+				}
+			}
+
+			var buf bytes.Buffer
+			_, err = be.GetObject(ctx, &s3.GetObjectInput{
+				Bucket: &bucket,
+				Key:    backend.GetStringPtr("dir/sub/c.txt"),
+				Range:  backend.GetStringPtr("bytes=3-6"),
+			}, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != "defg" {
+				t.Errorf("range: expected %q, got %q", "defg", buf.String())
+			}
+
+			_, err = be.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: &bucket,
+				Key:    backend.GetStringPtr("missing"),
+			})
+			if !errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchKey)) {
+				t.Errorf("expected NoSuchKey, got %v", err)
+			}
+
+			err = be.DeleteObject(ctx, &s3.DeleteObjectInput{
+				Bucket: &bucket,
+				Key:    backend.GetStringPtr("a.txt"),
+			})
+			if !errors.Is(err, s3err.GetAPIError(s3err.ErrNotImplemented)) {
+				t.Errorf("expected NotImplemented, got %v", err)
+			}
+		})
+	}
+}
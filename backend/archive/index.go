@@ -0,0 +1,215 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// member is a regular file within an archive
+type member struct {
+	key     string
+	size    int64
+	modTime time.Time
+	etag    string
+	// offset is the start of the member data within the archive file,
+	// only valid for uncompressed members
+	offset int64
+	// zf is set for compressed zip members, which must be decompressed
+	// from the start when read
+	zf *zip.File
+}
+
+// archiveIndex is the index of the members of an archive file
+type archiveIndex struct {
+	path    string
+	f       *os.File
+	created time.Time
+	members map[string]*member
+	// keys are the sorted member keys used for listing
+	keys []string
+}
+
+var errCompressedTar = errors.New("compressed tar archives are not supported")
+
+// openArchive opens and indexes the tar or zip archive at path
+func openArchive(archivePath string) (*archiveIndex, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("stat archive: %w", err)
+	}
+
+	idx := &archiveIndex{
+		path:    archivePath,
+		f:       f,
+		created: fi.ModTime(),
+		members: make(map[string]*member),
+	}
+
+	switch strings.ToLower(path.Ext(archivePath)) {
+	case ".zip":
+		err = idx.indexZip(fi.Size())
+	case ".gz", ".tgz", ".bz2", ".xz", ".zst":
+		err = errCompressedTar
+	default:
+		err = idx.indexTar()
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("index %v: %w", archivePath, err)
+	}
+
+	idx.keys = make([]string, 0, len(idx.members))
+	for key := range idx.members {
+		idx.keys = append(idx.keys, key)
+	}
+	sort.Strings(idx.keys)
+
+	return idx, nil
+}
+
+// countingReader tracks the offset within the archive while the tar
+// reader consumes it. It is also a Seeker so the tar reader skips over
+// the member data instead of reading it.
+type countingReader struct {
+	r io.ReadSeeker
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := c.r.Seek(offset, whence)
+	if err == nil {
+		c.n = pos
+	}
+	return pos, err
+}
+
+func (idx *archiveIndex) indexTar() error {
+	cr := &countingReader{r: idx.f}
+	tr := tar.NewReader(cr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// the tar reader has consumed the headers of this member,
+		// so the data starts at the current offset
+		idx.add(&member{
+			key:     hdr.Name,
+			size:    hdr.Size,
+			modTime: hdr.ModTime,
+			offset:  cr.n,
+		})
+	}
+}
+
+func (idx *archiveIndex) indexZip(size int64) error {
+	zr, err := zip.NewReader(idx.f, size)
+	if err != nil {
+		return err
+	}
+
+	for _, zf := range zr.File {
+		if !zf.Mode().IsRegular() {
+			continue
+		}
+
+		m := &member{
+			key:     zf.Name,
+			size:    int64(zf.UncompressedSize64),
+			modTime: zf.Modified,
+		}
+		if zf.Method == zip.Store {
+			m.offset, err = zf.DataOffset()
+			if err != nil {
+				return fmt.Errorf("member %v: %w", zf.Name, err)
+			}
+		} else {
+			m.zf = zf
+		}
+		idx.add(m)
+	}
+
+	return nil
+}
+
+// add indexes the member under its object key, later members replace
+// earlier ones with the same name as when extracting the archive
+func (idx *archiveIndex) add(m *member) {
+	m.key = strings.TrimPrefix(path.Clean("/"+m.key), "/")
+	if m.key == "" {
+		return
+	}
+
+	// members are never modified, so the etag only has to identify
+	// the member data within this archive
+	sum := md5.Sum([]byte(fmt.Sprintf("%v:%v:%v:%v:%v",
+		idx.path, m.key, m.offset, m.size, m.modTime.UnixNano())))
+	m.etag = hex.EncodeToString(sum[:])
+
+	idx.members[m.key] = m
+}
+
+// read writes length bytes of the member data starting at offset
+func (idx *archiveIndex) read(m *member, w io.Writer, offset, length int64) error {
+	if m.zf == nil {
+		_, err := io.Copy(w, io.NewSectionReader(idx.f, m.offset+offset, length))
+		return err
+	}
+
+	rc, err := m.zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = io.CopyN(io.Discard, rc, offset)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, rc, length)
+	return err
+}
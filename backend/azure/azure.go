@@ -191,7 +191,7 @@ func (az *Azure) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput)
 }
 
 func (az *Azure) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, error) {
-	tags, err := parseTags(po.Tagging)
+	tags, err := backend.ParseObjectTags(getString(po.Tagging))
 	if err != nil {
 		return "", err
 	}
@@ -224,11 +224,31 @@ func (az *Azure) PutObject(ctx context.Context, po *s3.PutObjectInput) (string,
 		if err := az.PutObjectRetention(ctx, *po.Bucket, *po.Key, "", retParsed); err != nil {
 			return "", err
 		}
+	} else if err := az.setDefaultRetention(ctx, *po.Bucket, *po.Key); err != nil {
+		return "", err
 	}
 
 	return string(*uploadResp.ETag), nil
 }
 
+// setDefaultRetention applies the bucket default retention to a new object
+// created without a retention of its own
+func (az *Azure) setDefaultRetention(ctx context.Context, bucket, object string) error {
+	cfg, err := az.GetObjectLockConfiguration(ctx, bucket)
+	if errors.Is(err, s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotFound)) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	retention, err := auth.MarshalDefaultRetention(cfg, time.Now())
+	if err != nil || retention == nil {
+		return err
+	}
+	return az.PutObjectRetention(ctx, bucket, object, "", retention)
+}
+
 func (az *Azure) PutBucketTagging(ctx context.Context, bucket string, tags map[string]string) error {
 	client, err := az.getContainerClient(bucket)
 	if err != nil {
@@ -289,8 +309,11 @@ func (az *Azure) DeleteBucketTagging(ctx context.Context, bucket string) error {
 
 func (az *Azure) GetObject(ctx context.Context, input *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, error) {
 	var opts *azblob.DownloadStreamOptions
-	if *input.Range != "" {
-		offset, count, err := parseRange(*input.Range)
+	var offset int64
+	if input.Range != nil && *input.Range != "" {
+		var count int64
+		var err error
+		offset, count, err = parseRange(*input.Range)
 		if err != nil {
 			return nil, err
 		}
@@ -307,6 +330,11 @@ func (az *Azure) GetObject(ctx context.Context, input *s3.GetObjectInput, writer
 	}
 	defer blobDownloadResponse.Body.Close()
 
+	err = backend.SetWriterMetadata(writer, parseAzMetadata(blobDownloadResponse.Metadata), offset)
+	if err != nil {
+		return nil, err
+	}
+
 	_, err = io.Copy(writer, blobDownloadResponse.Body)
 	if err != nil {
 		return nil, fmt.Errorf("copy data: %w", err)
@@ -571,7 +599,7 @@ func (az *Azure) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3
 		return nil, s3err.GetAPIError(s3err.ErrInvalidCopyDest)
 	}
 
-	tags, err := parseTags(input.Tagging)
+	tags, err := backend.ParseObjectTags(getString(input.Tagging))
 	if err != nil {
 		return nil, err
 	}
@@ -711,19 +739,12 @@ func (az *Azure) ListParts(ctx context.Context, input *s3.ListPartsInput) (s3res
 		return s3response.ListPartsResult{}, parseMpError(err)
 	}
 	var partNumberMarker int
-	var nextPartNumberMarker int
-	var maxParts int32 = math.MaxInt32
-	var isTruncated bool
-
-	if *input.PartNumberMarker != "" {
+	if input.PartNumberMarker != nil && *input.PartNumberMarker != "" {
 		partNumberMarker, err = strconv.Atoi(*input.PartNumberMarker)
 		if err != nil {
 			return s3response.ListPartsResult{}, s3err.GetAPIError(s3err.ErrInvalidPartNumberMarker)
 		}
 	}
-	if input.MaxParts != nil {
-		maxParts = *input.MaxParts
-	}
 
 	parts := []s3response.Part{}
 	for _, el := range resp.UncommittedBlocks {
@@ -731,14 +752,9 @@ func (az *Azure) ListParts(ctx context.Context, input *s3.ListPartsInput) (s3res
 		if err != nil {
 			return s3response.ListPartsResult{}, err
 		}
-		if partNumberMarker != 0 && partNumberMarker < partNumber {
+		if partNumber <= partNumberMarker {
 			continue
 		}
-		if len(parts) >= int(maxParts) {
-			nextPartNumberMarker = partNumber
-			isTruncated = true
-			break
-		}
 		parts = append(parts, s3response.Part{
 			Size:         *el.Size,
 			ETag:         *el.Name,
@@ -746,14 +762,18 @@ func (az *Azure) ListParts(ctx context.Context, input *s3.ListPartsInput) (s3res
 			LastModified: time.Now().Format(backend.RFC3339TimeFormat),
 		})
 	}
+
+	parts, maxParts, nextPartNumberMarker, isTruncated := backend.PageParts(parts, input.MaxParts)
+
 	return s3response.ListPartsResult{
 		Bucket:               *input.Bucket,
 		Key:                  *input.Key,
 		Parts:                parts,
+		StorageClass:         string(types.StorageClassStandard),
 		NextPartNumberMarker: nextPartNumberMarker,
 		PartNumberMarker:     partNumberMarker,
 		IsTruncated:          isTruncated,
-		MaxParts:             int(maxParts),
+		MaxParts:             maxParts,
 	}, nil
 }
 
@@ -831,6 +851,14 @@ func (az *Azure) CompleteMultipartUpload(ctx context.Context, input *s3.Complete
 	if err != nil {
 		return nil, err
 	}
+	if input.MultipartUpload == nil {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+	err = backend.CheckCompletedParts(input.MultipartUpload.Parts)
+	if err != nil {
+		return nil, err
+	}
+
 	blockIds := []string{}
 	for _, el := range input.MultipartUpload.Parts {
 		blockIds = append(blockIds, *el.ETag)
@@ -840,6 +868,11 @@ func (az *Azure) CompleteMultipartUpload(ctx context.Context, input *s3.Complete
 		return nil, parseMpError(err)
 	}
 
+	err = az.setDefaultRetention(ctx, *input.Bucket, *input.Key)
+	if err != nil {
+		return nil, err
+	}
+
 	return &s3.CompleteMultipartUploadOutput{
 		Bucket: input.Bucket,
 		Key:    input.Key,
@@ -1265,24 +1298,6 @@ func parseAzMetadata(m map[string]*string) map[string]string {
 	return meta
 }
 
-func parseTags(tagstr *string) (map[string]string, error) {
-	tagsStr := getString(tagstr)
-	tags := make(map[string]string)
-
-	if tagsStr != "" {
-		tagParts := strings.Split(tagsStr, "&")
-		for _, prt := range tagParts {
-			p := strings.Split(prt, "=")
-			if len(p) != 2 {
-				return nil, s3err.GetAPIError(s3err.ErrInvalidTag)
-			}
-			tags[p[0]] = p[1]
-		}
-	}
-
-	return tags, nil
-}
-
 func parseAzTags(tagSet []*blob.Tags) map[string]string {
 	tags := map[string]string{}
 	for _, tag := range tagSet {
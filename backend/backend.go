@@ -39,10 +39,17 @@ type Backend interface {
 	PutBucketAcl(_ context.Context, bucket string, data []byte) error
 	DeleteBucket(context.Context, *s3.DeleteBucketInput) error
 	PutBucketVersioning(context.Context, *s3.PutBucketVersioningInput) error
-	GetBucketVersioning(_ context.Context, bucket string) (*s3.GetBucketVersioningOutput, error)
+	GetBucketVersioning(_ context.Context, bucket string) (s3response.GetBucketVersioningOutput, error)
 	PutBucketPolicy(_ context.Context, bucket string, policy []byte) error
 	GetBucketPolicy(_ context.Context, bucket string) ([]byte, error)
 	DeleteBucketPolicy(_ context.Context, bucket string) error
+	PutBucketNotificationConfiguration(_ context.Context, bucket string, config []byte) error
+	GetBucketNotificationConfiguration(_ context.Context, bucket string) ([]byte, error)
+	PutBucketLogging(_ context.Context, bucket string, config []byte) error
+	GetBucketLogging(_ context.Context, bucket string) ([]byte, error)
+	PutPublicAccessBlock(_ context.Context, bucket string, config []byte) error
+	GetPublicAccessBlock(_ context.Context, bucket string) ([]byte, error)
+	DeletePublicAccessBlock(_ context.Context, bucket string) error
 
 	// multipart operations
 	CreateMultipartUpload(context.Context, *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error)
@@ -57,15 +64,15 @@ type Backend interface {
 	PutObject(context.Context, *s3.PutObjectInput) (string, error)
 	HeadObject(context.Context, *s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
 	GetObject(context.Context, *s3.GetObjectInput, io.Writer) (*s3.GetObjectOutput, error)
-	GetObjectAcl(context.Context, *s3.GetObjectAclInput) (*s3.GetObjectAclOutput, error)
+	GetObjectAcl(context.Context, *s3.GetObjectAclInput) ([]byte, error)
 	GetObjectAttributes(context.Context, *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error)
 	CopyObject(context.Context, *s3.CopyObjectInput) (*s3.CopyObjectOutput, error)
 	ListObjects(context.Context, *s3.ListObjectsInput) (*s3.ListObjectsOutput, error)
 	ListObjectsV2(context.Context, *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)
 	DeleteObject(context.Context, *s3.DeleteObjectInput) error
 	DeleteObjects(context.Context, *s3.DeleteObjectsInput) (s3response.DeleteResult, error)
-	PutObjectAcl(context.Context, *s3.PutObjectAclInput) error
-	ListObjectVersions(context.Context, *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error)
+	PutObjectAcl(_ context.Context, bucket, object string, data []byte) error
+	ListObjectVersions(context.Context, *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error)
 
 	// special case object operations
 	RestoreObject(context.Context, *s3.RestoreObjectInput) error
@@ -91,7 +98,26 @@ type Backend interface {
 
 	// non AWS actions
 	ChangeBucketOwner(_ context.Context, bucket, newOwner string) error
+	ChownBucket(_ context.Context, bucket string, uid, gid int) error
 	ListBucketsAndOwners(context.Context) ([]s3response.Bucket, error)
+	GetBucketUsage(_ context.Context, bucket string) (s3response.BucketUsage, error)
+	PutBucketQuota(_ context.Context, bucket string, quota s3response.BucketQuota) error
+	GetBucketQuota(_ context.Context, bucket string) (s3response.BucketQuota, error)
+	GetScrubStatus(context.Context) (s3response.ScrubStatus, error)
+	ListTrash(_ context.Context, bucket string) ([]s3response.TrashEntry, error)
+	RestoreTrash(_ context.Context, bucket, id string) error
+	PurgeTrash(_ context.Context, bucket, id string) error
+}
+
+// UploadMetadataStorer is implemented by backends that can retrieve and
+// update the user metadata of a multipart upload while it is in progress.
+// The upload metadata is applied to the object when the upload completes.
+type UploadMetadataStorer interface {
+	// GetUploadMetadata returns the user metadata of the upload
+	GetUploadMetadata(_ context.Context, bucket, object, uploadID string) (map[string]string, error)
+	// SetUploadMetadata adds the metadata entries to the upload, entries
+	// with an empty value are removed from the upload metadata
+	SetUploadMetadata(_ context.Context, bucket, object, uploadID string, meta map[string]string) error
 }
 
 type BackendUnsupported struct{}
@@ -126,8 +152,8 @@ func (BackendUnsupported) DeleteBucket(context.Context, *s3.DeleteBucketInput) e
 func (BackendUnsupported) PutBucketVersioning(context.Context, *s3.PutBucketVersioningInput) error {
 	return s3err.GetAPIError(s3err.ErrNotImplemented)
 }
-func (BackendUnsupported) GetBucketVersioning(_ context.Context, bucket string) (*s3.GetBucketVersioningOutput, error) {
-	return nil, s3err.GetAPIError(s3err.ErrNotImplemented)
+func (BackendUnsupported) GetBucketVersioning(_ context.Context, bucket string) (s3response.GetBucketVersioningOutput, error) {
+	return s3response.GetBucketVersioningOutput{}, s3err.GetAPIError(s3err.ErrNotImplemented)
 }
 func (BackendUnsupported) PutBucketPolicy(_ context.Context, bucket string, policy []byte) error {
 	return s3err.GetAPIError(s3err.ErrNotImplemented)
@@ -138,6 +164,27 @@ func (BackendUnsupported) GetBucketPolicy(_ context.Context, bucket string) ([]b
 func (BackendUnsupported) DeleteBucketPolicy(_ context.Context, bucket string) error {
 	return s3err.GetAPIError(s3err.ErrNotImplemented)
 }
+func (BackendUnsupported) PutBucketNotificationConfiguration(_ context.Context, bucket string, config []byte) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) GetBucketNotificationConfiguration(_ context.Context, bucket string) ([]byte, error) {
+	return nil, s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) PutBucketLogging(_ context.Context, bucket string, config []byte) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) GetBucketLogging(_ context.Context, bucket string) ([]byte, error) {
+	return nil, s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) PutPublicAccessBlock(_ context.Context, bucket string, config []byte) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) GetPublicAccessBlock(_ context.Context, bucket string) ([]byte, error) {
+	return nil, s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) DeletePublicAccessBlock(_ context.Context, bucket string) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
 
 func (BackendUnsupported) CreateMultipartUpload(context.Context, *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
 	return nil, s3err.GetAPIError(s3err.ErrNotImplemented)
@@ -170,7 +217,7 @@ func (BackendUnsupported) HeadObject(context.Context, *s3.HeadObjectInput) (*s3.
 func (BackendUnsupported) GetObject(context.Context, *s3.GetObjectInput, io.Writer) (*s3.GetObjectOutput, error) {
 	return nil, s3err.GetAPIError(s3err.ErrNotImplemented)
 }
-func (BackendUnsupported) GetObjectAcl(context.Context, *s3.GetObjectAclInput) (*s3.GetObjectAclOutput, error) {
+func (BackendUnsupported) GetObjectAcl(context.Context, *s3.GetObjectAclInput) ([]byte, error) {
 	return nil, s3err.GetAPIError(s3err.ErrNotImplemented)
 }
 func (BackendUnsupported) GetObjectAttributes(context.Context, *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error) {
@@ -191,7 +238,7 @@ func (BackendUnsupported) DeleteObject(context.Context, *s3.DeleteObjectInput) e
 func (BackendUnsupported) DeleteObjects(context.Context, *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
 	return s3response.DeleteResult{}, s3err.GetAPIError(s3err.ErrNotImplemented)
 }
-func (BackendUnsupported) PutObjectAcl(context.Context, *s3.PutObjectAclInput) error {
+func (BackendUnsupported) PutObjectAcl(_ context.Context, bucket, object string, data []byte) error {
 	return s3err.GetAPIError(s3err.ErrNotImplemented)
 }
 
@@ -213,8 +260,8 @@ func (BackendUnsupported) SelectObjectContent(ctx context.Context, input *s3.Sel
 	}
 }
 
-func (BackendUnsupported) ListObjectVersions(context.Context, *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
-	return nil, s3err.GetAPIError(s3err.ErrNotImplemented)
+func (BackendUnsupported) ListObjectVersions(context.Context, *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
+	return s3response.ListVersionsResult{}, s3err.GetAPIError(s3err.ErrNotImplemented)
 }
 
 func (BackendUnsupported) GetBucketTagging(_ context.Context, bucket string) (map[string]string, error) {
@@ -259,6 +306,30 @@ func (BackendUnsupported) GetObjectLegalHold(_ context.Context, bucket, object,
 func (BackendUnsupported) ChangeBucketOwner(_ context.Context, bucket, newOwner string) error {
 	return s3err.GetAPIError(s3err.ErrNotImplemented)
 }
+func (BackendUnsupported) ChownBucket(_ context.Context, bucket string, uid, gid int) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
 func (BackendUnsupported) ListBucketsAndOwners(context.Context) ([]s3response.Bucket, error) {
 	return []s3response.Bucket{}, s3err.GetAPIError(s3err.ErrNotImplemented)
 }
+func (BackendUnsupported) GetBucketUsage(_ context.Context, bucket string) (s3response.BucketUsage, error) {
+	return s3response.BucketUsage{}, s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) PutBucketQuota(_ context.Context, bucket string, quota s3response.BucketQuota) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) GetBucketQuota(_ context.Context, bucket string) (s3response.BucketQuota, error) {
+	return s3response.BucketQuota{}, s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) GetScrubStatus(context.Context) (s3response.ScrubStatus, error) {
+	return s3response.ScrubStatus{}, s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) ListTrash(_ context.Context, bucket string) ([]s3response.TrashEntry, error) {
+	return []s3response.TrashEntry{}, s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) RestoreTrash(_ context.Context, bucket, id string) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+func (BackendUnsupported) PurgeTrash(_ context.Context, bucket, id string) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
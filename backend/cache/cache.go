@@ -0,0 +1,435 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3response"
+)
+
+// Cache is a backend wrapper keeping a copy of the object data read
+// through GetObject in a local directory, so that repeated reads are
+// served from fast local storage instead of the inner backend. All writes
+// go directly through to the inner backend and drop the cached copies of
+// the objects they modify. Cached objects are evicted in least recently
+// used order once the total cached size exceeds the limit.
+type Cache struct {
+	backend.Backend
+
+	dir        string
+	maxSize    int64
+	revalidate bool
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	fills   map[*fill]struct{}
+	size    int64
+}
+
+var _ backend.Backend = &Cache{}
+
+type CacheOpts struct {
+	// MaxSize is the maximum total size in bytes of the cached objects
+	MaxSize int64
+	// Revalidate checks the object etag with the inner backend before
+	// serving a cached copy, for inner backends that are also modified
+	// outside of this gateway
+	Revalidate bool
+}
+
+type entry struct {
+	key  string
+	path string
+	size int64
+	out  s3.GetObjectOutput
+}
+
+// fill tracks an object read from the inner backend while it is being
+// copied to the cache, a write to the object in the meantime marks it
+// stale so that it is not added to the cache
+type fill struct {
+	key   string
+	stale bool
+}
+
+// New wraps the backend with a read cache stored within dir. The cache
+// contents are kept in a private subdirectory that is removed on
+// shutdown.
+func New(be backend.Backend, dir string, opts CacheOpts) (*Cache, error) {
+	if opts.MaxSize <= 0 {
+		return nil, fmt.Errorf("invalid cache size %v", opts.MaxSize)
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+
+	cachedir, err := os.MkdirTemp(dir, "vgw-cache-")
+	if err != nil {
+		return nil, fmt.Errorf("create cache dir: %w", err)
+	}
+
+	return &Cache{
+		Backend:    be,
+		dir:        cachedir,
+		maxSize:    opts.MaxSize,
+		revalidate: opts.Revalidate,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+		fills:      make(map[*fill]struct{}),
+	}, nil
+}
+
+func (c *Cache) Shutdown() {
+	c.Backend.Shutdown()
+	os.RemoveAll(c.dir)
+}
+
+func cacheKey(bucket, object string) string {
+	return bucket + "/" + object
+}
+
+// invalidate drops the cached copy of the object, along with any copy
+// being filled
+func (c *Cache) invalidate(bucket, object string) {
+	key := cacheKey(bucket, object)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	for f := range c.fills {
+		if f.key == key {
+			f.stale = true
+		}
+	}
+}
+
+// invalidateBucket drops all of the cached objects of the bucket
+func (c *Cache) invalidateBucket(bucket string) {
+	prefix := cacheKey(bucket, "")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, el := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(el)
+		}
+	}
+	for f := range c.fills {
+		if strings.HasPrefix(f.key, prefix) {
+			f.stale = true
+		}
+	}
+}
+
+// remove must be called with the lock held. Readers that already opened
+// the cache file can keep reading from it after it is removed.
+func (c *Cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	delete(c.entries, e.key)
+	c.size -= e.size
+	os.Remove(e.path)
+}
+
+// insert adds the filled cache file, evicting the least recently used
+// objects to make room for it
+func (c *Cache) insert(f *fill, tmp string, size int64, out *s3.GetObjectOutput) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.fills, f)
+	if f.stale || size > c.maxSize {
+		os.Remove(tmp)
+		return
+	}
+
+	if el, ok := c.entries[f.key]; ok {
+		c.remove(el)
+	}
+	for c.size+size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+
+	e := &entry{
+		key:  f.key,
+		path: tmp,
+		size: size,
+		out:  *out,
+	}
+	e.out.Body = nil
+	e.out.AcceptRanges = nil
+	e.out.ContentRange = nil
+	e.out.Metadata = maps.Clone(out.Metadata)
+
+	c.entries[f.key] = c.lru.PushFront(e)
+	c.size += size
+}
+
+func (c *Cache) abandon(f *fill, tmp string) {
+	c.mu.Lock()
+	delete(c.fills, f)
+	c.mu.Unlock()
+	os.Remove(tmp)
+}
+
+// open returns the cached copy of the object, the caller must close the
+// returned file
+func (c *Cache) open(key string) (*os.File, *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, nil
+	}
+
+	e := el.Value.(*entry)
+	f, err := os.Open(e.path)
+	if err != nil {
+		c.remove(el)
+		return nil, nil
+	}
+	c.lru.MoveToFront(el)
+
+	return f, e
+}
+
+// cacheWriter copies the object data to the cache file, giving up on the
+// copy without failing the request if the cache file can not be written
+// or the object is larger than the cache
+type cacheWriter struct {
+	f      *os.File
+	n      int64
+	max    int64
+	failed bool
+}
+
+func (w *cacheWriter) Write(p []byte) (int, error) {
+	if w.failed {
+		return len(p), nil
+	}
+
+	w.n += int64(len(p))
+	if w.n > w.max {
+		w.failed = true
+		return len(p), nil
+	}
+
+	_, err := w.f.Write(p)
+	if err != nil {
+		w.failed = true
+	}
+	return len(p), nil
+}
+
+func (c *Cache) GetObject(ctx context.Context, input *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, error) {
+	// only the latest version of the whole object is cached
+	if input.Bucket == nil || input.Key == nil ||
+		(input.VersionId != nil && *input.VersionId != "") ||
+		input.PartNumber != nil {
+		return c.Backend.GetObject(ctx, input, writer)
+	}
+
+	key := cacheKey(*input.Bucket, *input.Key)
+
+	out, ok, err := c.getCached(ctx, key, input, writer)
+	if ok {
+		return out, err
+	}
+
+	acceptRange := ""
+	if input.Range != nil {
+		acceptRange = *input.Range
+	}
+	if acceptRange != "" {
+		// partial reads are not cached
+		return c.Backend.GetObject(ctx, input, writer)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "obj-")
+	if err != nil {
+		return c.Backend.GetObject(ctx, input, writer)
+	}
+	defer tmp.Close()
+
+	f := &fill{key: key}
+	c.mu.Lock()
+	c.fills[f] = struct{}{}
+	c.mu.Unlock()
+
+	cw := &cacheWriter{f: tmp, max: c.maxSize}
+	out, err = c.Backend.GetObject(ctx, input,
+		backend.ForwardWriterMetadata(io.MultiWriter(writer, cw), writer))
+	if err != nil || out == nil || cw.failed ||
+		out.ContentLength == nil || *out.ContentLength != cw.n ||
+		(out.ContentRange != nil && *out.ContentRange != "") {
+		c.abandon(f, tmp.Name())
+		return out, err
+	}
+
+	c.insert(f, tmp.Name(), cw.n, out)
+
+	return out, nil
+}
+
+// getCached serves the object from the cache, the returned ok is false
+// when the object is not cached
+func (c *Cache) getCached(ctx context.Context, key string, input *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, bool, error) {
+	f, e := c.open(key)
+	if f == nil {
+		return nil, false, nil
+	}
+	defer f.Close()
+
+	if c.revalidate {
+		head, err := c.Backend.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: input.Bucket,
+			Key:    input.Key,
+		})
+		if err != nil || head.ETag == nil || e.out.ETag == nil || *head.ETag != *e.out.ETag {
+			c.invalidate(*input.Bucket, *input.Key)
+			return nil, false, nil
+		}
+	}
+
+	var etag string
+	if e.out.ETag != nil {
+		etag = *e.out.ETag
+	}
+	if e.out.LastModified != nil {
+		err := backend.EvaluatePreconditions(etag, *e.out.LastModified,
+			input.IfMatch, input.IfNoneMatch, input.IfModifiedSince,
+			input.IfUnmodifiedSince)
+		if err != nil {
+			return nil, true, err
+		}
+	}
+
+	acceptRange := ""
+	if input.Range != nil {
+		acceptRange = *input.Range
+	}
+
+	startOffset, length, err := backend.ParseRange(e.size, acceptRange)
+	if err != nil {
+		return nil, true, err
+	}
+
+	err = backend.SetWriterMetadata(writer, e.out.Metadata, startOffset)
+	if err != nil {
+		return nil, true, err
+	}
+
+	_, err = io.Copy(writer, io.NewSectionReader(f, startOffset, length))
+	if err != nil {
+		return nil, true, fmt.Errorf("copy cached data: %w", err)
+	}
+
+	contentRange := backend.ContentRange(acceptRange, startOffset, length, e.size)
+
+	out := e.out
+	out.ContentLength = &length
+	out.AcceptRanges = &acceptRange
+	out.ContentRange = &contentRange
+	out.Metadata = maps.Clone(e.out.Metadata)
+
+	return &out, true, nil
+}
+
+func (c *Cache) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) error {
+	err := c.Backend.DeleteBucket(ctx, input)
+	if err == nil && input.Bucket != nil {
+		c.invalidateBucket(*input.Bucket)
+	}
+	return err
+}
+
+// The writes below drop the cached object whether or not they succeed,
+// a failed write may still have partly modified the object
+
+func (c *Cache) PutObject(ctx context.Context, input *s3.PutObjectInput) (string, error) {
+	defer c.invalidateInput(input.Bucket, input.Key)
+	return c.Backend.PutObject(ctx, input)
+}
+
+func (c *Cache) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	defer c.invalidateInput(input.Bucket, input.Key)
+	return c.Backend.CopyObject(ctx, input)
+}
+
+func (c *Cache) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	defer c.invalidateInput(input.Bucket, input.Key)
+	return c.Backend.CompleteMultipartUpload(ctx, input)
+}
+
+func (c *Cache) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) error {
+	defer c.invalidateInput(input.Bucket, input.Key)
+	return c.Backend.DeleteObject(ctx, input)
+}
+
+func (c *Cache) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
+	defer func() {
+		if input.Delete == nil {
+			return
+		}
+		for _, obj := range input.Delete.Objects {
+			c.invalidateInput(input.Bucket, obj.Key)
+		}
+	}()
+	return c.Backend.DeleteObjects(ctx, input)
+}
+
+func (c *Cache) PutObjectTagging(ctx context.Context, bucket, object string, tags map[string]string) error {
+	defer c.invalidate(bucket, object)
+	return c.Backend.PutObjectTagging(ctx, bucket, object, tags)
+}
+
+func (c *Cache) DeleteObjectTagging(ctx context.Context, bucket, object string) error {
+	defer c.invalidate(bucket, object)
+	return c.Backend.DeleteObjectTagging(ctx, bucket, object)
+}
+
+func (c *Cache) PutObjectRetention(ctx context.Context, bucket, object, versionId string, retention []byte) error {
+	defer c.invalidate(bucket, object)
+	return c.Backend.PutObjectRetention(ctx, bucket, object, versionId, retention)
+}
+
+func (c *Cache) PutObjectLegalHold(ctx context.Context, bucket, object, versionId string, status bool) error {
+	defer c.invalidate(bucket, object)
+	return c.Backend.PutObjectLegalHold(ctx, bucket, object, versionId, status)
+}
+
+func (c *Cache) invalidateInput(bucket, object *string) {
+	if bucket == nil || object == nil {
+		return
+	}
+	c.invalidate(*bucket, *object)
+}
@@ -0,0 +1,170 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/memstore"
+	"github.com/versity/versitygw/s3err"
+)
+
+// countingBackend counts the reads reaching the inner backend
+type countingBackend struct {
+	*memstore.MemStore
+	gets int
+}
+
+func (c *countingBackend) GetObject(ctx context.Context, input *s3.GetObjectInput, w io.Writer) (*s3.GetObjectOutput, error) {
+	c.gets++
+	return c.MemStore.GetObject(ctx, input, w)
+}
+
+func newTestCache(t *testing.T, maxSize int64) (*Cache, *countingBackend) {
+	t.Helper()
+	inner := &countingBackend{MemStore: memstore.New()}
+	c, err := New(inner, t.TempDir(), CacheOpts{MaxSize: maxSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Shutdown)
+
+	err = c.CreateBucket(context.Background(), &s3.CreateBucketInput{
+		Bucket: backend.GetStringPtr("bucket"),
+	}, []byte(`{"Owner":"owner"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, inner
+}
+
+func put(t *testing.T, c *Cache, key, data string) {
+	t.Helper()
+	_, err := c.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: backend.GetStringPtr("bucket"),
+		Key:    &key,
+		Body:   strings.NewReader(data),
+	})
+	if err != nil {
+		t.Fatalf("put %v: %v", key, err)
+	}
+}
+
+func get(t *testing.T, c *Cache, key, rng string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	input := &s3.GetObjectInput{
+		Bucket: backend.GetStringPtr("bucket"),
+		Key:    &key,
+	}
+	if rng != "" {
+		input.Range = &rng
+	}
+	out, err := c.GetObject(context.Background(), input, &buf)
+	if err != nil {
+		t.Fatalf("get %v: %v", key, err)
+	}
+	if *out.ContentLength != int64(buf.Len()) {
+		t.Errorf("get %v: content length %v, read %v", key, *out.ContentLength, buf.Len())
+	}
+	return buf.String()
+}
+
+func TestCache_Reads(t *testing.T) {
+	c, inner := newTestCache(t, 1024)
+	put(t, c, "obj", "hello world")
+
+	for i := 0; i < 3; i++ {
+		if data := get(t, c, "obj", ""); data != "hello world" {
+			t.Errorf("expected %q, got %q", "hello world", data)
+		}
+	}
+	if data := get(t, c, "obj", "bytes=6-10"); data != "world" {
+		t.Errorf("expected %q, got %q", "world", data)
+	}
+	if inner.gets != 1 {
+		t.Errorf("expected 1 inner read, got %v", inner.gets)
+	}
+
+	// overwrites and deletes are visible on the next read
+	put(t, c, "obj", "goodbye")
+	if data := get(t, c, "obj", ""); data != "goodbye" {
+		t.Errorf("expected %q, got %q", "goodbye", data)
+	}
+	if inner.gets != 2 {
+		t.Errorf("expected 2 inner reads, got %v", inner.gets)
+	}
+
+	err := c.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: backend.GetStringPtr("bucket"),
+		Key:    backend.GetStringPtr("obj"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: backend.GetStringPtr("bucket"),
+		Key:    backend.GetStringPtr("obj"),
+	}, io.Discard)
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchKey)) {
+		t.Errorf("expected NoSuchKey, got %v", err)
+	}
+}
+
+func TestCache_Eviction(t *testing.T) {
+	c, inner := newTestCache(t, 20)
+	put(t, c, "a", "0123456789")
+	put(t, c, "b", "0123456789")
+	put(t, c, "c", "0123456789")
+	put(t, c, "big", strings.Repeat("x", 21))
+
+	get(t, c, "a", "")
+	get(t, c, "b", "")
+	get(t, c, "a", "")
+	// evicts b, the least recently used
+	get(t, c, "c", "")
+	if c.size != 20 || len(c.entries) != 2 {
+		t.Errorf("expected 2 cached objects of 20 bytes, got %v of %v bytes",
+			len(c.entries), c.size)
+	}
+
+	inner.gets = 0
+	get(t, c, "a", "")
+	get(t, c, "c", "")
+	if inner.gets != 0 {
+		t.Errorf("expected cached reads, got %v inner reads", inner.gets)
+	}
+	get(t, c, "b", "")
+	if inner.gets != 1 {
+		t.Errorf("expected evicted object read, got %v inner reads", inner.gets)
+	}
+
+	// objects larger than the cache are never cached
+	inner.gets = 0
+	if data := get(t, c, "big", ""); len(data) != 21 {
+		t.Errorf("expected 21 bytes, got %v", len(data))
+	}
+	get(t, c, "big", "")
+	if inner.gets != 2 {
+		t.Errorf("expected uncached reads, got %v inner reads", inner.gets)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cephfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/xattr"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/meta"
+	"github.com/versity/versitygw/backend/posix"
+	"github.com/versity/versitygw/s3err"
+)
+
+type CephFSOpts struct {
+	ChownUID bool
+	ChownGID bool
+}
+
+// CephFS is the posix backend with cephfs data placement hints, and with
+// CopyObject data copied with copy_file_range. With the cephfs kernel
+// client "copyfrom" mount option, the copy is done by the OSDs without
+// the data passing through the gateway.
+type CephFS struct {
+	*posix.Posix
+
+	// poolMu serializes creating the pool temp dirs
+	poolMu sync.Mutex
+}
+
+var _ backend.Backend = &CephFS{}
+
+const (
+	metaTmpDir = ".sgwtmp"
+
+	// poolMetaKey is the user metadata key of the data pool hint, set
+	// with the x-amz-meta-ceph-pool header
+	poolMetaKey = "ceph-pool"
+	// poolLayoutKey is the directory layout xattr inherited by the
+	// ceph.file.layout.pool of new files
+	poolLayoutKey = "ceph.dir.layout.pool"
+)
+
+var poolName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func New(rootdir string, meta meta.MetadataStorer, opts CephFSOpts) (*CephFS, error) {
+	c := &CephFS{}
+
+	p, err := posix.New(rootdir, meta, posix.PosixOpts{
+		ChownUID:  opts.ChownUID,
+		ChownGID:  opts.ChownGID,
+		PutTmpDir: c.putTmpDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Posix = p
+	return c, nil
+}
+
+func (*CephFS) String() string {
+	return "CephFS Gateway"
+}
+
+// putTmpDir creates new objects with a data pool hint within a temp dir
+// with that data pool layout, so that the object file inherits the layout
+// when created
+func (c *CephFS) putTmpDir(po *s3.PutObjectInput) (string, error) {
+	var pool string
+	for k, v := range po.Metadata {
+		if strings.EqualFold(k, poolMetaKey) {
+			pool = v
+		}
+	}
+	if pool == "" {
+		return "", nil
+	}
+	if !poolName.MatchString(pool) {
+		return "", s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
+
+	dir := filepath.Join(*po.Bucket, metaTmpDir, "pool-"+pool)
+
+	c.poolMu.Lock()
+	defer c.poolMu.Unlock()
+
+	_, err := os.Stat(dir)
+	if err == nil {
+		return dir, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(dir), 0755)
+	if err != nil {
+		return "", fmt.Errorf("make temp dir: %w", err)
+	}
+	err = os.Mkdir(dir, 0755)
+	if errors.Is(err, fs.ErrExist) {
+		return dir, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("make pool temp dir: %w", err)
+	}
+
+	err = xattr.Set(dir, poolLayoutKey, []byte(pool))
+	if err != nil {
+		os.Remove(dir)
+		return "", fmt.Errorf("set data pool %q: %w", pool, err)
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package backend
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/s3err"
+)
+
+// Checksum is an object checksum and the algorithm that computed it.
+// The value is the base64 encoded digest as sent in the x-amz-checksum-*
+// headers.
+type Checksum struct {
+	Algorithm types.ChecksumAlgorithm `json:"algorithm"`
+	Value     string                  `json:"value"`
+}
+
+// NewChecksumHash returns the hash computing checksums of the algorithm
+func NewChecksumHash(algo types.ChecksumAlgorithm) (hash.Hash, error) {
+	switch algo {
+	case types.ChecksumAlgorithmCrc32:
+		return crc32.NewIEEE(), nil
+	case types.ChecksumAlgorithmCrc32c:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	case types.ChecksumAlgorithmSha1:
+		return sha1.New(), nil
+	case types.ChecksumAlgorithmSha256:
+		return sha256.New(), nil
+	default:
+		return nil, s3err.GetAPIError(s3err.ErrInvalidChecksumHeader)
+	}
+}
+
+// IsValidChecksum checks that the value is a base64 encoded digest of the
+// size produced by the algorithm
+func IsValidChecksum(algo types.ChecksumAlgorithm, value string) bool {
+	h, err := NewChecksumHash(algo)
+	if err != nil {
+		return false
+	}
+	b, err := base64.StdEncoding.DecodeString(value)
+	return err == nil && len(b) == h.Size()
+}
+
+// ChecksumFromFields returns the checksum set in the CRC32, CRC32C, SHA1
+// or SHA256 checksum fields of the s3 inputs and outputs, or nil when no
+// checksum is set
+func ChecksumFromFields(crc32sum, crc32csum, sha1sum, sha256sum *string) *Checksum {
+	for _, c := range []struct {
+		algo  types.ChecksumAlgorithm
+		value *string
+	}{
+		{types.ChecksumAlgorithmCrc32, crc32sum},
+		{types.ChecksumAlgorithmCrc32c, crc32csum},
+		{types.ChecksumAlgorithmSha1, sha1sum},
+		{types.ChecksumAlgorithmSha256, sha256sum},
+	} {
+		if c.value != nil && *c.value != "" {
+			return &Checksum{Algorithm: c.algo, Value: *c.value}
+		}
+	}
+	return nil
+}
+
+// Fields returns the checksum as the CRC32, CRC32C, SHA1 and SHA256
+// checksum fields of the s3 inputs and outputs, only the field of the
+// checksum algorithm is set
+func (c *Checksum) Fields() (crc32sum, crc32csum, sha1sum, sha256sum *string) {
+	if c == nil {
+		return nil, nil, nil, nil
+	}
+	value := c.Value
+	switch c.Algorithm {
+	case types.ChecksumAlgorithmCrc32:
+		return &value, nil, nil, nil
+	case types.ChecksumAlgorithmCrc32c:
+		return nil, &value, nil, nil
+	case types.ChecksumAlgorithmSha1:
+		return nil, nil, &value, nil
+	case types.ChecksumAlgorithmSha256:
+		return nil, nil, nil, &value
+	default:
+		return nil, nil, nil, nil
+	}
+}
+
+// ObjectChecksum returns the checksum as reported in the object
+// attributes, or nil for a nil checksum
+func (c *Checksum) ObjectChecksum() *types.Checksum {
+	if c == nil {
+		return nil
+	}
+	crc32sum, crc32csum, sha1sum, sha256sum := c.Fields()
+	return &types.Checksum{
+		ChecksumCRC32:  crc32sum,
+		ChecksumCRC32C: crc32csum,
+		ChecksumSHA1:   sha1sum,
+		ChecksumSHA256: sha256sum,
+	}
+}
+
+// CompositeChecksum returns the checksum of a multipart upload object
+// from the checksums of its parts in part order. Like the multipart etag,
+// this is the checksum of the concatenated part digests followed by the
+// number of parts, and not the checksum of the object data.
+func CompositeChecksum(algo types.ChecksumAlgorithm, parts []*Checksum) (*Checksum, error) {
+	h, err := NewChecksumHash(algo)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		if part == nil || part.Algorithm != algo {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
+		}
+		b, err := base64.StdEncoding.DecodeString(part.Value)
+		if err != nil {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
+		}
+		h.Write(b)
+	}
+
+	return &Checksum{
+		Algorithm: algo,
+		Value: fmt.Sprintf("%v-%v",
+			base64.StdEncoding.EncodeToString(h.Sum(nil)), len(parts)),
+	}, nil
+}
+
+// CheckPartChecksum verifies the checksum sent with a completed part of a
+// multipart upload, if any, against the checksum stored for the part
+func CheckPartChecksum(part types.CompletedPart, stored *Checksum) error {
+	sent := ChecksumFromFields(part.ChecksumCRC32, part.ChecksumCRC32C,
+		part.ChecksumSHA1, part.ChecksumSHA256)
+	if sent == nil {
+		return nil
+	}
+	if stored == nil || *sent != *stored {
+		return s3err.GetAPIError(s3err.ErrInvalidPart)
+	}
+	return nil
+}
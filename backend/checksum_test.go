@@ -0,0 +1,80 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package backend_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+func TestCompositeChecksum(t *testing.T) {
+	sum1 := sha256.Sum256([]byte("part1"))
+	sum2 := sha256.Sum256([]byte("part2"))
+	parts := []*backend.Checksum{
+		{Algorithm: types.ChecksumAlgorithmSha256, Value: base64.StdEncoding.EncodeToString(sum1[:])},
+		{Algorithm: types.ChecksumAlgorithmSha256, Value: base64.StdEncoding.EncodeToString(sum2[:])},
+	}
+
+	composite := sha256.Sum256(append(sum1[:], sum2[:]...))
+	want := base64.StdEncoding.EncodeToString(composite[:]) + "-2"
+
+	got, err := backend.CompositeChecksum(types.ChecksumAlgorithmSha256, parts)
+	if err != nil {
+		t.Fatalf("CompositeChecksum() unexpected error %v", err)
+	}
+	if got.Algorithm != types.ChecksumAlgorithmSha256 || got.Value != want {
+		t.Errorf("CompositeChecksum() = %v, want %v", *got, want)
+	}
+
+	_, err = backend.CompositeChecksum(types.ChecksumAlgorithmCrc32, parts)
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrInvalidPart)) {
+		t.Errorf("expected invalid part for mismatched algorithm, got %v", err)
+	}
+	_, err = backend.CompositeChecksum(types.ChecksumAlgorithmSha256,
+		[]*backend.Checksum{parts[0], nil})
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrInvalidPart)) {
+		t.Errorf("expected invalid part for missing part checksum, got %v", err)
+	}
+}
+
+func TestCheckPartChecksum(t *testing.T) {
+	stored := &backend.Checksum{Algorithm: types.ChecksumAlgorithmCrc32, Value: "AAAAAA=="}
+	tests := []struct {
+		name    string
+		part    types.CompletedPart
+		stored  *backend.Checksum
+		wantErr bool
+	}{
+		{name: "not-sent", part: types.CompletedPart{}, stored: stored},
+		{name: "match", part: types.CompletedPart{ChecksumCRC32: &stored.Value}, stored: stored},
+		{name: "mismatch", part: types.CompletedPart{ChecksumCRC32: backend.GetStringPtr("AQIDBA==")}, stored: stored, wantErr: true},
+		{name: "other-algorithm", part: types.CompletedPart{ChecksumCRC32C: &stored.Value}, stored: stored, wantErr: true},
+		{name: "none-stored", part: types.CompletedPart{ChecksumCRC32: &stored.Value}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := backend.CheckPartChecksum(tt.part, tt.stored)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckPartChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -17,11 +17,16 @@ package backend
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io/fs"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/versity/versitygw/s3err"
@@ -33,7 +38,47 @@ var (
 	RFC3339TimeFormat = "2006-01-02T15:04:05.999Z"
 )
 
-func IsValidBucketName(name string) bool { return true }
+var (
+	bucketNameRegexp   = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]+[a-z0-9]$`)
+	bucketNameIpRegexp = regexp.MustCompile(`^(?:[0-9]{1,3}\.){3}[0-9]{1,3}$`)
+
+	// reservedBucketPrefixes and reservedBucketSuffixes are the bucket
+	// name forms reserved by S3 for other uses
+	reservedBucketPrefixes = []string{"xn--", "sthree-", "amzn-s3-demo-"}
+	reservedBucketSuffixes = []string{"-s3alias", "--ol-s3", ".mrap", "--x-s3"}
+)
+
+// IsValidBucketName returns true for a name following the S3 bucket naming
+// rules. These names are also safe to use as a single path element, such
+// as a bucket directory.
+func IsValidBucketName(name string) bool {
+	if len(name) < 3 || len(name) > 63 {
+		return false
+	}
+	// only digits, lowercase letters, dots and hyphens, starting and
+	// ending with a digit or lowercase letter
+	if !bucketNameRegexp.MatchString(name) {
+		return false
+	}
+	// no adjacent dots, which also excludes path traversal
+	if strings.Contains(name, "..") {
+		return false
+	}
+	if bucketNameIpRegexp.MatchString(name) {
+		return false
+	}
+	for _, prefix := range reservedBucketPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	for _, suffix := range reservedBucketSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return true
+}
 
 type ByBucketName []s3response.ListAllMyBucketsEntry
 
@@ -55,50 +100,384 @@ func GetTimePtr(t time.Time) *time.Time {
 	return &t
 }
 
+func GetBoolPtr(b bool) *bool {
+	return &b
+}
+
 var (
 	errInvalidRange = s3err.GetAPIError(s3err.ErrInvalidRange)
 )
 
-// ParseRange parses input range header and returns startoffset, length, and
-// error. If no endoffset specified, then length is set to -1.
-func ParseRange(fi fs.FileInfo, acceptRange string) (int64, int64, error) {
+// ParseRange parses the Range header value of a GET request against the
+// object size and returns the start offset and length of the requested
+// bytes. An empty range selects the whole object. Following RFC 7233,
+// "bytes=-N" selects the final N bytes, an open-ended or last byte
+// position past the end of the object is clamped to the object size, and
+// a range that starts past the end of the object is unsatisfiable.
+func ParseRange(size int64, acceptRange string) (int64, int64, error) {
 	if acceptRange == "" {
-		return 0, fi.Size(), nil
+		return 0, size, nil
 	}
 
-	rangeKv := strings.Split(acceptRange, "=")
+	start, end, err := splitRange(acceptRange)
+	if err != nil {
+		return 0, 0, err
+	}
 
-	if len(rangeKv) < 2 {
-		return 0, 0, errInvalidRange
+	if start == "" {
+		suffixLength, err := strconv.ParseInt(end, 10, 64)
+		if err != nil || suffixLength <= 0 || size == 0 {
+			return 0, 0, errInvalidRange
+		}
+		if suffixLength > size {
+			suffixLength = size
+		}
+		return size - suffixLength, suffixLength, nil
 	}
 
-	bRange := strings.Split(rangeKv[1], "-")
-	if len(bRange) < 1 || len(bRange) > 2 {
+	startOffset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || startOffset < 0 {
 		return 0, 0, errInvalidRange
 	}
 
-	startOffset, err := strconv.ParseInt(bRange[0], 10, 64)
-	if err != nil {
+	endOffset := size - 1
+	if end != "" {
+		endOffset, err = strconv.ParseInt(end, 10, 64)
+		if err != nil || endOffset < startOffset {
+			return 0, 0, errInvalidRange
+		}
+	}
+
+	if startOffset >= size {
 		return 0, 0, errInvalidRange
 	}
+	if endOffset >= size {
+		endOffset = size - 1
+	}
 
-	endOffset := int64(-1)
-	if len(bRange) == 1 || bRange[1] == "" {
-		return startOffset, endOffset, nil
+	return startOffset, endOffset - startOffset + 1, nil
+}
+
+// ParseCopySourceRange parses the x-amz-copy-source-range header value
+// against the copy source size. Unlike ParseRange, the range must be of
+// the form "bytes=first-last" or "bytes=first-" and lie entirely within
+// the source object.
+func ParseCopySourceRange(size int64, copyRange string) (int64, int64, error) {
+	if copyRange == "" {
+		return 0, size, nil
 	}
 
-	endOffset, err = strconv.ParseInt(bRange[1], 10, 64)
+	start, end, err := splitRange(copyRange)
 	if err != nil {
+		return 0, 0, err
+	}
+
+	startOffset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || startOffset < 0 || startOffset >= size {
 		return 0, 0, errInvalidRange
 	}
 
-	if endOffset < startOffset {
+	if end == "" {
+		return startOffset, size - startOffset, nil
+	}
+
+	endOffset, err := strconv.ParseInt(end, 10, 64)
+	if err != nil || endOffset < startOffset || endOffset >= size {
 		return 0, 0, errInvalidRange
 	}
 
 	return startOffset, endOffset - startOffset + 1, nil
 }
 
+// ObjectMetadataWriter is implemented by GetObject writers that need the
+// object metadata to process the object data, such as decrypting server
+// side encrypted objects. Backends pass the metadata and the object offset
+// of the first byte written with SetWriterMetadata before writing any of
+// the object data.
+type ObjectMetadataWriter interface {
+	io.Writer
+	SetObjectMetadata(meta map[string]string, offset int64) error
+}
+
+// SetWriterMetadata passes the object metadata to w when w is an
+// ObjectMetadataWriter
+func SetWriterMetadata(w io.Writer, meta map[string]string, offset int64) error {
+	mw, ok := w.(ObjectMetadataWriter)
+	if !ok {
+		return nil
+	}
+	return mw.SetObjectMetadata(meta, offset)
+}
+
+type metadataForwarder struct {
+	io.Writer
+	mw ObjectMetadataWriter
+}
+
+func (f metadataForwarder) SetObjectMetadata(meta map[string]string, offset int64) error {
+	return f.mw.SetObjectMetadata(meta, offset)
+}
+
+// ForwardWriterMetadata returns dst, which wraps the GetObject writer w,
+// passing the object metadata on to w when w is an ObjectMetadataWriter
+func ForwardWriterMetadata(dst, w io.Writer) io.Writer {
+	mw, ok := w.(ObjectMetadataWriter)
+	if !ok {
+		return dst
+	}
+	return metadataForwarder{Writer: dst, mw: mw}
+}
+
+// splitRange splits a "bytes=first-last" range into its first and last
+// byte positions
+func splitRange(rng string) (string, string, error) {
+	unit, spec, found := strings.Cut(rng, "=")
+	if !found || unit != "bytes" {
+		return "", "", errInvalidRange
+	}
+
+	start, end, found := strings.Cut(spec, "-")
+	if !found || (start == "" && end == "") {
+		return "", "", errInvalidRange
+	}
+
+	return start, end, nil
+}
+
+// PartRange returns the range selecting part partNumber of an object of
+// the given size that was assembled from parts of partSizes. Objects not
+// created by a multipart upload have no part sizes and are a single part.
+// An empty range is returned when the part selects the whole empty object.
+func PartRange(size int64, partSizes []int64, partNumber int32) (string, error) {
+	if len(partSizes) == 0 {
+		partSizes = []int64{size}
+	}
+	if partNumber < 1 || int(partNumber) > len(partSizes) {
+		return "", s3err.GetAPIError(s3err.ErrInvalidPartNumberRange)
+	}
+
+	var offset int64
+	for _, s := range partSizes[:partNumber-1] {
+		offset += s
+	}
+
+	length := partSizes[partNumber-1]
+	if length == 0 {
+		if size == 0 {
+			return "", nil
+		}
+		return "", s3err.GetAPIError(s3err.ErrInvalidPartNumberRange)
+	}
+
+	return fmt.Sprintf("bytes=%v-%v", offset, offset+length-1), nil
+}
+
+// UnsatisfiableContentRange formats the Content-Range response header value
+// returned with a 416 response for a range that does not overlap the object
+func UnsatisfiableContentRange(size int64) string {
+	return fmt.Sprintf("bytes */%v", size)
+}
+
+// ContentRange formats the Content-Range response header value for the
+// selected bytes, or returns "" when no range was requested
+func ContentRange(acceptRange string, startOffset, length, size int64) string {
+	if acceptRange == "" {
+		return ""
+	}
+	return fmt.Sprintf("bytes %v-%v/%v", startOffset, startOffset+length-1, size)
+}
+
+// EvaluatePreconditions checks the conditional request headers against the
+// object etag and modification time. A failed If-Match or If-Unmodified-Since
+// returns PreconditionFailed, and a failed If-None-Match or If-Modified-Since
+// returns NotModified. Nil conditions are ignored.
+func EvaluatePreconditions(etag string, modTime time.Time, ifMatch, ifNoneMatch *string, ifModifiedSince, ifUnmodifiedSince *time.Time) error {
+	// http dates only have second precision
+	modTime = modTime.Truncate(time.Second)
+
+	if ifMatch != nil {
+		if !etagMatches(*ifMatch, etag) {
+			return s3err.GetAPIError(s3err.ErrPreconditionFailed)
+		}
+	} else if ifUnmodifiedSince != nil && modTime.After(*ifUnmodifiedSince) {
+		return s3err.GetAPIError(s3err.ErrPreconditionFailed)
+	}
+
+	if ifNoneMatch != nil {
+		if etagMatches(*ifNoneMatch, etag) {
+			return s3err.GetAPIError(s3err.ErrNotModified)
+		}
+	} else if ifModifiedSince != nil && !modTime.After(*ifModifiedSince) {
+		return s3err.GetAPIError(s3err.ErrNotModified)
+	}
+
+	return nil
+}
+
+// EvaluateCopySourcePreconditions checks the x-amz-copy-source conditional
+// headers against the copy source etag and modification time. Any failed
+// condition returns PreconditionFailed.
+func EvaluateCopySourcePreconditions(etag string, modTime time.Time, ifMatch, ifNoneMatch *string, ifModifiedSince, ifUnmodifiedSince *time.Time) error {
+	err := EvaluatePreconditions(etag, modTime, ifMatch, ifNoneMatch,
+		ifModifiedSince, ifUnmodifiedSince)
+	if errors.Is(err, s3err.GetAPIError(s3err.ErrNotModified)) {
+		return s3err.GetAPIError(s3err.ErrPreconditionFailed)
+	}
+	return err
+}
+
+// etagMatches checks the etag against a comma separated list of
+// (optionally quoted or weak) etags or the "*" wildcard
+func etagMatches(list, etag string) bool {
+	etag = trimEtag(etag)
+	for _, e := range strings.Split(list, ",") {
+		e = strings.TrimSpace(e)
+		if e == "*" || trimEtag(e) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func trimEtag(etag string) string {
+	return strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+}
+
+const (
+	// MinPartSize is the minimum size of every part of a completed
+	// multipart upload except the last
+	MinPartSize = 5 * 1024 * 1024
+	// MaxPartNumber is the largest part number of a multipart upload
+	MaxPartNumber = 10000
+	// DefaultMaxParts is the number of parts listed by ListParts when
+	// the request does not set max-parts
+	DefaultMaxParts = 1000
+)
+
+// PageParts sorts the parts following the part number marker and limits
+// them to maxParts entries, using DefaultMaxParts when maxParts is nil.
+// The next part number marker is only set when the listing is truncated.
+// A maxParts of 0 lists no parts, but still reports truncation when there
+// are any parts left to list.
+func PageParts(parts []s3response.Part, maxParts *int32) (page []s3response.Part, max int, next int, truncated bool) {
+	max = DefaultMaxParts
+	if maxParts != nil {
+		max = int(*maxParts)
+	}
+
+	sort.Slice(parts,
+		func(i int, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+
+	if len(parts) <= max {
+		return parts, max, 0, false
+	}
+
+	page = parts[:max]
+	if len(page) != 0 {
+		next = page[len(page)-1].PartNumber
+	}
+	return page, max, next, true
+}
+
+const (
+	// MaxObjectTags is the maximum number of tags on an object
+	MaxObjectTags = 10
+	// MaxTagKeyLength is the maximum number of characters in a tag key
+	MaxTagKeyLength = 128
+	// MaxTagValueLength is the maximum number of characters in a tag value
+	MaxTagValueLength = 256
+)
+
+var tagRegexp = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// ValidateTag checks a tag key and value for their length and for
+// characters outside of letters, numbers, spaces and _ . : / = + - @
+func ValidateTag(key, value string) error {
+	if key == "" || utf8.RuneCountInString(key) > MaxTagKeyLength ||
+		utf8.RuneCountInString(value) > MaxTagValueLength {
+		return s3err.GetAPIError(s3err.ErrInvalidTag)
+	}
+	if !tagRegexp.MatchString(key) || !tagRegexp.MatchString(value) {
+		return s3err.GetAPIError(s3err.ErrInvalidTag)
+	}
+	return nil
+}
+
+// ObjectTags validates an object tag set and returns it as a map of tag
+// keys to values, rejecting duplicate keys and more than MaxObjectTags tags
+func ObjectTags(tagSet []s3response.Tag) (map[string]string, error) {
+	if len(tagSet) > MaxObjectTags {
+		return nil, s3err.GetAPIError(s3err.ErrTooManyObjectTags)
+	}
+
+	tags := make(map[string]string, len(tagSet))
+	for _, tag := range tagSet {
+		err := ValidateTag(tag.Key, tag.Value)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tags[tag.Key]; ok {
+			return nil, s3err.GetAPIError(s3err.ErrDuplicateTagKey)
+		}
+		tags[tag.Key] = tag.Value
+	}
+	return tags, nil
+}
+
+// ParseObjectTags parses and validates the url encoded tag set sent in
+// the X-Amz-Tagging header of object uploads, the tag keys and values
+// are returned decoded
+func ParseObjectTags(tagging string) (map[string]string, error) {
+	if tagging == "" {
+		return nil, nil
+	}
+
+	var tagSet []s3response.Tag
+	for _, prt := range strings.Split(tagging, "&") {
+		k, v, ok := strings.Cut(prt, "=")
+		if !ok {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidTag)
+		}
+		key, err := url.QueryUnescape(k)
+		if err != nil {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidTag)
+		}
+		value, err := url.QueryUnescape(v)
+		if err != nil {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidTag)
+		}
+		tagSet = append(tagSet, s3response.Tag{Key: key, Value: value})
+	}
+	return ObjectTags(tagSet)
+}
+
+// CheckCompletedParts validates the part list of a complete multipart
+// upload request. The list can't be empty, each part needs an ETag and a
+// part number between 1 and MaxPartNumber, and the parts must be listed
+// in ascending part number order. The part sizes are checked against
+// MinPartSize by the backends as the parts are located.
+func CheckCompletedParts(parts []types.CompletedPart) error {
+	if len(parts) == 0 {
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	var prev int32
+	for _, part := range parts {
+		if part.PartNumber == nil || part.ETag == nil {
+			return s3err.GetAPIError(s3err.ErrInvalidPart)
+		}
+		pn := *part.PartNumber
+		if pn < 1 || pn > MaxPartNumber {
+			return s3err.GetAPIError(s3err.ErrInvalidPartNumber)
+		}
+		if pn <= prev {
+			return s3err.GetAPIError(s3err.ErrInvalidPartOrder)
+		}
+		prev = pn
+	}
+	return nil
+}
+
 func GetMultipartMD5(parts []types.CompletedPart) string {
 	var partsEtagBytes []byte
 	for _, part := range parts {
@@ -120,3 +499,50 @@ func md5String(data []byte) string {
 	sum := md5.Sum(data)
 	return hex.EncodeToString(sum[:])
 }
+
+// PipeObject streams the object data written by get into the reader
+// consumed by put, as when copying an object between backends
+func PipeObject(get func(io.Writer) error, put func(io.Reader) error) error {
+	pr, pw := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		err := get(pw)
+		pw.CloseWithError(err)
+		errc <- err
+	}()
+
+	err := put(pr)
+	pr.CloseWithError(io.ErrClosedPipe)
+	getErr := <-errc
+
+	// report the read failure rather than the write of the truncated data
+	if getErr != nil && !errors.Is(getErr, io.ErrClosedPipe) {
+		return getErr
+	}
+	return err
+}
+
+// ParseCopySource splits the copy source into the bucket, object and
+// optional version id
+func ParseCopySource(copySource *string) (string, string, string, error) {
+	if copySource == nil {
+		return "", "", "", s3err.GetAPIError(s3err.ErrInvalidCopySource)
+	}
+
+	src, query, _ := strings.Cut(strings.TrimPrefix(*copySource, "/"), "?")
+	bucket, object, ok := strings.Cut(src, "/")
+	if !ok {
+		return "", "", "", s3err.GetAPIError(s3err.ErrInvalidCopySource)
+	}
+
+	var versionId string
+	if query != "" {
+		vals, err := url.ParseQuery(query)
+		if err != nil {
+			return "", "", "", s3err.GetAPIError(s3err.ErrInvalidCopySource)
+		}
+		versionId = vals.Get("versionId")
+	}
+
+	return bucket, object, versionId, nil
+}
@@ -0,0 +1,355 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package backend_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+func TestEvaluatePreconditions(t *testing.T) {
+	etag := "0123456789abcdef0123456789abcdef"
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+	before := modTime.Add(-time.Hour)
+	after := modTime.Add(time.Hour)
+	same := modTime.Truncate(time.Second)
+
+	str := func(s string) *string { return &s }
+
+	precondFailed := s3err.GetAPIError(s3err.ErrPreconditionFailed)
+	notModified := s3err.GetAPIError(s3err.ErrNotModified)
+
+	tests := []struct {
+		name              string
+		ifMatch           *string
+		ifNoneMatch       *string
+		ifModifiedSince   *time.Time
+		ifUnmodifiedSince *time.Time
+		want              error
+	}{
+		{name: "no-conditions"},
+		{name: "if-match-quoted", ifMatch: str(`"` + etag + `"`)},
+		{name: "if-match-list", ifMatch: str(`"other", "` + etag + `"`)},
+		{name: "if-match-wildcard", ifMatch: str("*")},
+		{name: "if-match-fails", ifMatch: str(`"other"`), want: precondFailed},
+		{name: "if-none-match-matches", ifNoneMatch: str(`"` + etag + `"`), want: notModified},
+		{name: "if-none-match-weak", ifNoneMatch: str(`W/"` + etag + `"`), want: notModified},
+		{name: "if-none-match-other", ifNoneMatch: str(`"other"`)},
+		{name: "if-modified-since-before", ifModifiedSince: &before},
+		{name: "if-modified-since-same", ifModifiedSince: &same, want: notModified},
+		{name: "if-modified-since-after", ifModifiedSince: &after, want: notModified},
+		{name: "if-unmodified-since-before", ifUnmodifiedSince: &before, want: precondFailed},
+		{name: "if-unmodified-since-same", ifUnmodifiedSince: &same},
+		{
+			name:              "if-match-overrides-if-unmodified-since",
+			ifMatch:           str(etag),
+			ifUnmodifiedSince: &before,
+		},
+		{
+			name:            "if-none-match-overrides-if-modified-since",
+			ifNoneMatch:     str(`"other"`),
+			ifModifiedSince: &after,
+		},
+		{
+			name:        "precondition-failed-before-not-modified",
+			ifMatch:     str(`"other"`),
+			ifNoneMatch: str(etag),
+			want:        precondFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := backend.EvaluatePreconditions(etag, modTime, tt.ifMatch,
+				tt.ifNoneMatch, tt.ifModifiedSince, tt.ifUnmodifiedSince)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("EvaluatePreconditions() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateCopySourcePreconditions(t *testing.T) {
+	etag := "0123456789abcdef0123456789abcdef"
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	after := modTime.Add(time.Hour)
+	other := `"other"`
+
+	precondFailed := s3err.GetAPIError(s3err.ErrPreconditionFailed)
+
+	err := backend.EvaluateCopySourcePreconditions(etag, modTime, nil, &etag, nil, nil)
+	if !errors.Is(err, precondFailed) {
+		t.Errorf("if-none-match error = %v, want %v", err, precondFailed)
+	}
+
+	err = backend.EvaluateCopySourcePreconditions(etag, modTime, nil, nil, &after, nil)
+	if !errors.Is(err, precondFailed) {
+		t.Errorf("if-modified-since error = %v, want %v", err, precondFailed)
+	}
+
+	err = backend.EvaluateCopySourcePreconditions(etag, modTime, &other, nil, nil, nil)
+	if !errors.Is(err, precondFailed) {
+		t.Errorf("if-match error = %v, want %v", err, precondFailed)
+	}
+
+	err = backend.EvaluateCopySourcePreconditions(etag, modTime, &etag, &other, nil, nil)
+	if err != nil {
+		t.Errorf("matching conditions error = %v, want nil", err)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int64
+		rng        string
+		wantOffset int64
+		wantLength int64
+		wantErr    bool
+	}{
+		{name: "no-range", size: 10, rng: "", wantOffset: 0, wantLength: 10},
+		{name: "no-range-empty-object", size: 0, rng: "", wantOffset: 0, wantLength: 0},
+		{name: "closed-range", size: 10, rng: "bytes=2-5", wantOffset: 2, wantLength: 4},
+		{name: "single-byte", size: 10, rng: "bytes=9-9", wantOffset: 9, wantLength: 1},
+		{name: "open-ended", size: 10, rng: "bytes=5-", wantOffset: 5, wantLength: 5},
+		{name: "whole-object", size: 10, rng: "bytes=0-9", wantOffset: 0, wantLength: 10},
+		{name: "invalid-unit", size: 10, rng: "items=0-5", wantErr: true},
+		{name: "missing-separator", size: 10, rng: "bytes=5", wantErr: true},
+		{name: "invalid-start", size: 10, rng: "bytes=invalid-range", wantErr: true},
+		{name: "end-before-start", size: 10, rng: "bytes=5-2", wantErr: true},
+		{name: "start-past-end", size: 10, rng: "bytes=10-", wantErr: true},
+		{name: "end-past-end", size: 10, rng: "bytes=0-10", wantOffset: 0, wantLength: 10},
+		{name: "end-far-past-end", size: 10, rng: "bytes=4-999999999999", wantOffset: 4, wantLength: 6},
+		{name: "suffix", size: 10, rng: "bytes=-3", wantOffset: 7, wantLength: 3},
+		{name: "suffix-whole-object", size: 10, rng: "bytes=-10", wantOffset: 0, wantLength: 10},
+		{name: "suffix-past-start", size: 10, rng: "bytes=-20", wantOffset: 0, wantLength: 10},
+		{name: "suffix-zero", size: 10, rng: "bytes=-0", wantErr: true},
+		{name: "suffix-invalid", size: 10, rng: "bytes=-x", wantErr: true},
+		{name: "suffix-empty-object", size: 0, rng: "bytes=-5", wantErr: true},
+		{name: "missing-positions", size: 10, rng: "bytes=-", wantErr: true},
+		{name: "range-on-empty-object", size: 0, rng: "bytes=0-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, length, err := backend.ParseRange(tt.size, tt.rng)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if offset != tt.wantOffset || length != tt.wantLength {
+				t.Errorf("ParseRange() = %v, %v, want %v, %v",
+					offset, length, tt.wantOffset, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestPartRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int64
+		partSizes  []int64
+		partNumber int32
+		want       string
+		wantErr    bool
+	}{
+		{name: "first-part", size: 10, partSizes: []int64{4, 4, 2}, partNumber: 1, want: "bytes=0-3"},
+		{name: "middle-part", size: 10, partSizes: []int64{4, 4, 2}, partNumber: 2, want: "bytes=4-7"},
+		{name: "last-part", size: 10, partSizes: []int64{4, 4, 2}, partNumber: 3, want: "bytes=8-9"},
+		{name: "past-last-part", size: 10, partSizes: []int64{4, 4, 2}, partNumber: 4, wantErr: true},
+		{name: "zero-part", size: 10, partSizes: []int64{4, 4, 2}, partNumber: 0, wantErr: true},
+		{name: "single-part", size: 10, partNumber: 1, want: "bytes=0-9"},
+		{name: "single-part-past-end", size: 10, partNumber: 2, wantErr: true},
+		{name: "empty-object", size: 0, partNumber: 1, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng, err := backend.PartRange(tt.size, tt.partSizes, tt.partNumber)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PartRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if rng != tt.want {
+				t.Errorf("PartRange() = %q, want %q", rng, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCompletedParts(t *testing.T) {
+	etag := `"d41d8cd98f00b204e9800998ecf8427e"`
+	part := func(pn int32) types.CompletedPart {
+		return types.CompletedPart{PartNumber: &pn, ETag: &etag}
+	}
+	tests := []struct {
+		name  string
+		parts []types.CompletedPart
+		want  error
+	}{
+		{name: "ascending", parts: []types.CompletedPart{part(1), part(2), part(5)}},
+		{name: "max-part-number", parts: []types.CompletedPart{part(backend.MaxPartNumber)}},
+		{name: "no-parts", want: s3err.GetAPIError(s3err.ErrMalformedXML)},
+		{name: "empty-parts", parts: []types.CompletedPart{}, want: s3err.GetAPIError(s3err.ErrMalformedXML)},
+		{name: "missing-part-number", parts: []types.CompletedPart{{ETag: &etag}}, want: s3err.GetAPIError(s3err.ErrInvalidPart)},
+		{name: "missing-etag", parts: []types.CompletedPart{part(1), {PartNumber: part(2).PartNumber}}, want: s3err.GetAPIError(s3err.ErrInvalidPart)},
+		{name: "zero-part-number", parts: []types.CompletedPart{part(0)}, want: s3err.GetAPIError(s3err.ErrInvalidPartNumber)},
+		{name: "part-number-too-large", parts: []types.CompletedPart{part(backend.MaxPartNumber + 1)}, want: s3err.GetAPIError(s3err.ErrInvalidPartNumber)},
+		{name: "descending", parts: []types.CompletedPart{part(2), part(1)}, want: s3err.GetAPIError(s3err.ErrInvalidPartOrder)},
+		{name: "duplicate", parts: []types.CompletedPart{part(1), part(1)}, want: s3err.GetAPIError(s3err.ErrInvalidPartOrder)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := backend.CheckCompletedParts(tt.parts)
+			if tt.want == nil && err != nil {
+				t.Fatalf("CheckCompletedParts() unexpected error %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("CheckCompletedParts() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageParts(t *testing.T) {
+	parts := func(pns ...int) []s3response.Part {
+		var p []s3response.Part
+		for _, pn := range pns {
+			p = append(p, s3response.Part{PartNumber: pn})
+		}
+		return p
+	}
+	max := func(n int32) *int32 { return &n }
+	tests := []struct {
+		name          string
+		parts         []s3response.Part
+		maxParts      *int32
+		wantParts     []int
+		wantMax       int
+		wantNext      int
+		wantTruncated bool
+	}{
+		{name: "default-max", parts: parts(3, 1, 2), wantParts: []int{1, 2, 3}, wantMax: backend.DefaultMaxParts},
+		{name: "not-truncated", parts: parts(2, 1), maxParts: max(2), wantParts: []int{1, 2}, wantMax: 2},
+		{name: "truncated", parts: parts(4, 2, 3, 1), maxParts: max(2), wantParts: []int{1, 2}, wantMax: 2, wantNext: 2, wantTruncated: true},
+		{name: "zero-max", parts: parts(1), maxParts: max(0), wantTruncated: true},
+		{name: "zero-max-no-parts", maxParts: max(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, max, next, truncated := backend.PageParts(tt.parts, tt.maxParts)
+			var pns []int
+			for _, p := range page {
+				pns = append(pns, p.PartNumber)
+			}
+			if !reflect.DeepEqual(pns, tt.wantParts) {
+				t.Errorf("PageParts() parts = %v, want %v", pns, tt.wantParts)
+			}
+			if max != tt.wantMax {
+				t.Errorf("PageParts() max = %v, want %v", max, tt.wantMax)
+			}
+			if next != tt.wantNext {
+				t.Errorf("PageParts() next = %v, want %v", next, tt.wantNext)
+			}
+			if truncated != tt.wantTruncated {
+				t.Errorf("PageParts() truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestParseObjectTags(t *testing.T) {
+	var tooMany []string
+	for i := 0; i <= backend.MaxObjectTags; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("key%v=val", i))
+	}
+	tests := []struct {
+		name    string
+		tagging string
+		want    map[string]string
+		wantErr error
+	}{
+		{name: "empty"},
+		{name: "decoded", tagging: "my%20key=a%2Fb+c&k2=", want: map[string]string{"my key": "a/b c", "k2": ""}},
+		{name: "allowed-chars", tagging: "a_.:/=%2B-@=1", want: map[string]string{"a_.:/": "+-@=1"}},
+		{name: "unicode-length", tagging: "k=" + strings.Repeat("%C3%A9", backend.MaxTagValueLength), want: map[string]string{"k": strings.Repeat("é", backend.MaxTagValueLength)}},
+		{name: "missing-value", tagging: "key", wantErr: s3err.GetAPIError(s3err.ErrInvalidTag)},
+		{name: "empty-key", tagging: "=val", wantErr: s3err.GetAPIError(s3err.ErrInvalidTag)},
+		{name: "bad-escape", tagging: "key=%zz", wantErr: s3err.GetAPIError(s3err.ErrInvalidTag)},
+		{name: "invalid-char", tagging: "key=val%21", wantErr: s3err.GetAPIError(s3err.ErrInvalidTag)},
+		{name: "long-key", tagging: strings.Repeat("k", backend.MaxTagKeyLength+1) + "=val", wantErr: s3err.GetAPIError(s3err.ErrInvalidTag)},
+		{name: "duplicate-key", tagging: "key=1&key=2", wantErr: s3err.GetAPIError(s3err.ErrDuplicateTagKey)},
+		{name: "too-many", tagging: strings.Join(tooMany, "&"), wantErr: s3err.GetAPIError(s3err.ErrTooManyObjectTags)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := backend.ParseObjectTags(tt.tagging)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseObjectTags() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseObjectTags() unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseObjectTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCopySourceRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int64
+		rng        string
+		wantOffset int64
+		wantLength int64
+		wantErr    bool
+	}{
+		{name: "no-range", size: 10, rng: "", wantOffset: 0, wantLength: 10},
+		{name: "closed-range", size: 10, rng: "bytes=2-5", wantOffset: 2, wantLength: 4},
+		{name: "open-ended", size: 10, rng: "bytes=5-", wantOffset: 5, wantLength: 5},
+		{name: "invalid-start", size: 10, rng: "bytes=invalid-range", wantErr: true},
+		{name: "end-past-end", size: 10, rng: "bytes=0-10", wantErr: true},
+		{name: "start-past-end", size: 10, rng: "bytes=10-", wantErr: true},
+		{name: "suffix", size: 10, rng: "bytes=-3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, length, err := backend.ParseCopySourceRange(tt.size, tt.rng)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCopySourceRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if offset != tt.wantOffset || length != tt.wantLength {
+				t.Errorf("ParseCopySourceRange() = %v, %v, want %v, %v",
+					offset, length, tt.wantOffset, tt.wantLength)
+			}
+		})
+	}
+}
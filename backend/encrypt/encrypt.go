@@ -0,0 +1,311 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package encrypt
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/kms"
+	"github.com/versity/versitygw/s3err"
+)
+
+// Object metadata keys holding the wrapped data key of the object, and
+// the layout of the parts of objects completed from multipart uploads.
+// All metadata keys with the metaPrefix are removed from the metadata
+// returned to clients.
+const (
+	metaPrefix  = "versitygw-at-rest-"
+	metaKeyID   = metaPrefix + "key-id"
+	metaDataKey = metaPrefix + "data-key"
+	metaIV      = metaPrefix + "iv"
+	metaParts   = metaPrefix + "parts"
+)
+
+var metaKeys = map[string]string{
+	kms.MetaKeyID:   metaKeyID,
+	kms.MetaDataKey: metaDataKey,
+	kms.MetaIV:      metaIV,
+}
+
+// Encrypt is a backend wrapper encrypting all object data before it is
+// stored by the inner backend, independent of any server side encryption
+// requested by the clients. Each object is encrypted with its own data
+// key, wrapped by a kms master key and stored in the object metadata.
+// Objects stored before encryption was enabled are returned unmodified.
+//
+// The object data is encrypted with AES-256-CTR, so object sizes and
+// ranges are unchanged, but the object etags are computed by the inner
+// backend over the encrypted data.
+type Encrypt struct {
+	backend.Backend
+
+	kms   kms.Provider
+	keyID string
+}
+
+var _ backend.Backend = &Encrypt{}
+
+// New wraps the backend with at rest encryption using data keys wrapped
+// by the keyID master key, or the provider default key if keyID is empty
+func New(be backend.Backend, p kms.Provider, keyID string) (*Encrypt, error) {
+	if p == nil {
+		return nil, fmt.Errorf("at rest encryption requires a kms provider")
+	}
+	if keyID == "" {
+		keyID = p.DefaultKeyID()
+	}
+	if keyID == "" {
+		return nil, fmt.Errorf("no kms key id specified for at rest encryption")
+	}
+
+	return &Encrypt{
+		Backend: be,
+		kms:     p,
+		keyID:   keyID,
+	}, nil
+}
+
+// isEncrypted returns true if the object metadata holds a data key
+func isEncrypted(meta map[string]string) bool {
+	return meta[metaKeyID] != "" && meta[metaDataKey] != ""
+}
+
+// stripMetadata removes the data key and upload metadata
+func stripMetadata(meta map[string]string) {
+	for k := range meta {
+		if strings.HasPrefix(strings.ToLower(k), metaPrefix) {
+			delete(meta, k)
+		}
+	}
+}
+
+// userMetadata returns a copy of the request metadata without any data
+// key metadata supplied by the client
+func userMetadata(meta map[string]string) map[string]string {
+	m := maps.Clone(meta)
+	if m == nil {
+		m = make(map[string]string)
+	}
+	stripMetadata(m)
+	return m
+}
+
+// encryptReader returns a reader encrypting r with a new data key, and
+// adds the data key to meta
+func (e *Encrypt) encryptReader(ctx context.Context, r io.Reader, meta map[string]string) (io.Reader, error) {
+	er, kmeta, err := kms.EncryptObject(ctx, e.kms, e.keyID, r)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt object: %w", err)
+	}
+	for k, v := range kmeta {
+		meta[metaKeys[k]] = v
+	}
+	return er, nil
+}
+
+// newDataKey generates a new data key, and adds the wrapped data key
+// to meta
+func (e *Encrypt) newDataKey(ctx context.Context, meta map[string]string) error {
+	dk, err := e.kms.GenerateDataKey(ctx, e.keyID)
+	if err != nil {
+		return fmt.Errorf("generate data key: %w", err)
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return fmt.Errorf("generate iv: %w", err)
+	}
+
+	meta[metaKeyID] = e.keyID
+	meta[metaDataKey] = base64.StdEncoding.EncodeToString(dk.Ciphertext)
+	meta[metaIV] = base64.StdEncoding.EncodeToString(iv)
+	return nil
+}
+
+// dataKey returns the data key and iv unwrapped from meta
+func (e *Encrypt) dataKey(ctx context.Context, meta map[string]string) ([]byte, []byte, error) {
+	wrapped, err := base64.StdEncoding.DecodeString(meta[metaDataKey])
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode data key: %w", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(meta[metaIV])
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode iv: %w", err)
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, nil, fmt.Errorf("invalid iv length %v", len(iv))
+	}
+
+	key, err := e.kms.Decrypt(ctx, meta[metaKeyID], wrapped)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decrypt data key: %w", err)
+	}
+	return key, iv, nil
+}
+
+// decryptWriter returns a writer decrypting the object data written
+// to it starting at offset into the object
+func (e *Encrypt) decryptWriter(ctx context.Context, meta map[string]string, w io.Writer, offset int64) (io.Writer, error) {
+	if meta[metaParts] != "" {
+		return e.decryptPartsWriter(ctx, meta, w, offset)
+	}
+
+	kmeta := make(map[string]string, len(metaKeys))
+	for k, mk := range metaKeys {
+		kmeta[k] = meta[mk]
+	}
+	dw, err := kms.DecryptWriter(ctx, e.kms, kmeta, w, offset)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt object: %w", err)
+	}
+	return dw, nil
+}
+
+// copyKeyMetadata copies the data key and part layout metadata from src
+// to dst
+func copyKeyMetadata(dst, src map[string]string) {
+	for _, mk := range []string{metaKeyID, metaDataKey, metaIV, metaParts} {
+		if v, ok := src[mk]; ok {
+			dst[mk] = v
+		}
+	}
+}
+
+func (e *Encrypt) PutObject(ctx context.Context, input *s3.PutObjectInput) (string, error) {
+	meta := userMetadata(input.Metadata)
+
+	body := input.Body
+	if body == nil {
+		body = strings.NewReader("")
+	}
+	r, err := e.encryptReader(ctx, body, meta)
+	if err != nil {
+		return "", err
+	}
+
+	in := *input
+	in.Body = r
+	in.Metadata = meta
+
+	return e.Backend.PutObject(ctx, &in)
+}
+
+func (e *Encrypt) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	out, err := e.Backend.HeadObject(ctx, input)
+	if out != nil {
+		stripMetadata(out.Metadata)
+	}
+	return out, err
+}
+
+// maxGetAttempts bounds the retries of reads racing with object
+// overwrites
+const maxGetAttempts = 3
+
+func (e *Encrypt) GetObject(ctx context.Context, input *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, error) {
+	for attempt := 1; ; attempt++ {
+		head, err := e.Backend.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket:    input.Bucket,
+			Key:       input.Key,
+			VersionId: input.VersionId,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		w := writer
+		if isEncrypted(head.Metadata) {
+			var size int64
+			if head.ContentLength != nil {
+				size = *head.ContentLength
+			}
+			var acceptRange string
+			if input.Range != nil {
+				acceptRange = *input.Range
+			}
+
+			offset, _, err := backend.ParseRange(size, acceptRange)
+			if err != nil {
+				return nil, err
+			}
+			if input.PartNumber != nil {
+				offset = partStart(head.Metadata, *input.PartNumber)
+			}
+
+			w, err = e.decryptWriter(ctx, head.Metadata, writer, offset)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// the data must belong to the object version the data key was
+		// read from, retry if the object was replaced in between
+		in := *input
+		if in.IfMatch == nil {
+			in.IfMatch = head.ETag
+		}
+
+		out, err := e.Backend.GetObject(ctx, &in, w)
+		if input.IfMatch == nil && attempt < maxGetAttempts &&
+			errors.Is(err, s3err.GetAPIError(s3err.ErrPreconditionFailed)) {
+			continue
+		}
+		if out != nil {
+			stripMetadata(out.Metadata)
+		}
+		return out, err
+	}
+}
+
+func (e *Encrypt) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	srcBucket, srcObject, versionId, err := backend.ParseCopySource(input.CopySource)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := e.Backend.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:    &srcBucket,
+		Key:       &srcObject,
+		VersionId: &versionId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the data is copied as is, so the copy keeps the source data key
+	meta := userMetadata(input.Metadata)
+	copyKeyMetadata(meta, head.Metadata)
+
+	in := *input
+	in.Metadata = meta
+
+	return e.Backend.CopyObject(ctx, &in)
+}
+
+// SelectObjectContent is not supported since the inner backend can not
+// read the encrypted object data
+func (e *Encrypt) SelectObjectContent(ctx context.Context, input *s3.SelectObjectContentInput) func(w *bufio.Writer) {
+	return backend.BackendUnsupported{}.SelectObjectContent(ctx, input)
+}
@@ -0,0 +1,383 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/memstore"
+	"github.com/versity/versitygw/kms"
+)
+
+const testBucket = "bucket"
+
+func newTestEncrypt(t *testing.T) (*Encrypt, *memstore.MemStore) {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	p, err := kms.NewStatic(map[string][]byte{"key1": key}, "key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner := memstore.New()
+	e, err := New(inner, p, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = e.CreateBucket(context.Background(), &s3.CreateBucketInput{
+		Bucket: backend.GetStringPtr(testBucket),
+	}, []byte(`{"Owner":"owner"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e, inner
+}
+
+func get(t *testing.T, be backend.Backend, key, rng string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	input := &s3.GetObjectInput{
+		Bucket: backend.GetStringPtr(testBucket),
+		Key:    &key,
+	}
+	if rng != "" {
+		input.Range = &rng
+	}
+	_, err := be.GetObject(context.Background(), input, &buf)
+	if err != nil {
+		t.Fatalf("get %v: %v", key, err)
+	}
+	return buf.String()
+}
+
+func TestEncrypt_Objects(t *testing.T) {
+	ctx := context.Background()
+	e, inner := newTestEncrypt(t)
+
+	data := "the quick brown fox jumps over the lazy dog"
+	_, err := e.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:   backend.GetStringPtr(testBucket),
+		Key:      backend.GetStringPtr("obj"),
+		Body:     strings.NewReader(data),
+		Metadata: map[string]string{"color": "blue"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored := get(t, inner, "obj", ""); stored == data || len(stored) != len(data) {
+		t.Errorf("expected same length ciphertext stored, got %q", stored)
+	}
+	if got := get(t, e, "obj", ""); got != data {
+		t.Errorf("expected %q, got %q", data, got)
+	}
+	if got := get(t, e, "obj", "bytes=16-24"); got != data[16:25] {
+		t.Errorf("range: expected %q, got %q", data[16:25], got)
+	}
+
+	head, err := e.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: backend.GetStringPtr(testBucket),
+		Key:    backend.GetStringPtr("obj"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(head.Metadata) != 1 || head.Metadata["color"] != "blue" {
+		t.Errorf("unexpected metadata %v", head.Metadata)
+	}
+
+	_, err = e.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:              backend.GetStringPtr(testBucket),
+		Key:                 backend.GetStringPtr("copy"),
+		CopySource:          backend.GetStringPtr(testBucket + "/obj"),
+		ExpectedBucketOwner: backend.GetStringPtr("owner"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := get(t, e, "copy", ""); got != data {
+		t.Errorf("copy: expected %q, got %q", data, got)
+	}
+}
+
+func TestEncrypt_MultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	e, inner := newTestEncrypt(t)
+
+	src := strings.Repeat("0123456789", backend.MinPartSize/10+1)
+	_, err := e.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: backend.GetStringPtr(testBucket),
+		Key:    backend.GetStringPtr("src"),
+		Body:   strings.NewReader(src),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mpu, err := e.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: backend.GetStringPtr(testBucket),
+		Key:    backend.GetStringPtr("mp"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// upload the parts out of order, with the second part copied
+	parts := []string{strings.Repeat("abcd", backend.MinPartSize/4), "", "xy"}
+	etags := make([]string, 4)
+	for _, pn := range []int32{3, 1} {
+		etags[pn], err = e.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     backend.GetStringPtr(testBucket),
+			Key:        backend.GetStringPtr("mp"),
+			UploadId:   mpu.UploadId,
+			PartNumber: &pn,
+			Body:       strings.NewReader(parts[pn-1]),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pn := int32(2)
+	res, err := e.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+		Bucket:          backend.GetStringPtr(testBucket),
+		Key:             backend.GetStringPtr("mp"),
+		UploadId:        mpu.UploadId,
+		PartNumber:      &pn,
+		CopySource:      backend.GetStringPtr(testBucket + "/src"),
+		CopySourceRange: backend.GetStringPtr(fmt.Sprintf("bytes=2-%v", backend.MinPartSize+1)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	etags[2] = res.ETag
+
+	var completed []types.CompletedPart
+	for i := int32(1); i <= 3; i++ {
+		pn := i
+		completed = append(completed, types.CompletedPart{
+			ETag:       backend.GetStringPtr(etags[pn]),
+			PartNumber: &pn,
+		})
+	}
+	_, err = e.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          backend.GetStringPtr(testBucket),
+		Key:             backend.GetStringPtr("mp"),
+		UploadId:        mpu.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := parts[0] + src[2:backend.MinPartSize+2] + parts[2]
+	if got := get(t, e, "mp", ""); got != expected {
+		t.Errorf("expected %v bytes of object data, got %v bytes", len(expected), len(got))
+	}
+	if got := get(t, e, "mp", "bytes=3-8"); got != expected[3:9] {
+		t.Errorf("range: expected %q, got %q", expected[3:9], got)
+	}
+	if stored := get(t, inner, "mp", ""); stored == expected {
+		t.Errorf("expected ciphertext stored, got plaintext")
+	}
+
+	var buf bytes.Buffer
+	pn = 2
+	_, err = e.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:     backend.GetStringPtr(testBucket),
+		Key:        backend.GetStringPtr("mp"),
+		PartNumber: &pn,
+	}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != src[2:backend.MinPartSize+2] {
+		t.Errorf("part 2: expected %v bytes of part data, got %v bytes",
+			backend.MinPartSize, buf.Len())
+	}
+
+	// the data key and part layout are stored by the inner backend
+	// with the completed object
+	head, err := inner.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: backend.GetStringPtr(testBucket),
+		Key:    backend.GetStringPtr("mp"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(head.Metadata) {
+		t.Errorf("expected data key in object metadata, got %v", head.Metadata)
+	}
+	layout := fmt.Sprintf("1-2:%v,3:2", backend.MinPartSize)
+	if head.Metadata[metaParts] != layout {
+		t.Errorf("expected part layout %q, got %q", layout, head.Metadata[metaParts])
+	}
+}
+
+func TestEncrypt_MultipartUploadRestart(t *testing.T) {
+	ctx := context.Background()
+	e, inner := newTestEncrypt(t)
+
+	mpu, err := e.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:            backend.GetStringPtr(testBucket),
+		Key:               backend.GetStringPtr("mp"),
+		Metadata:          map[string]string{"color": "blue"},
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the upload is continued and completed by gateways sharing the
+	// inner backend, such as after a restart
+	parts := []string{strings.Repeat("abcd", backend.MinPartSize/4), "xyz"}
+	var completed []types.CompletedPart
+	var checksums []*backend.Checksum
+	for i, data := range parts {
+		gw, err := New(inner, e.kms, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pn := int32(i + 1)
+		etag, err := gw.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     backend.GetStringPtr(testBucket),
+			Key:        backend.GetStringPtr("mp"),
+			UploadId:   mpu.UploadId,
+			PartNumber: &pn,
+			Body:       strings.NewReader(data),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		h := sha256.Sum256([]byte(data))
+		checksum := &backend.Checksum{
+			Algorithm: types.ChecksumAlgorithmSha256,
+			Value:     base64.StdEncoding.EncodeToString(h[:]),
+		}
+		checksums = append(checksums, checksum)
+		completed = append(completed, types.CompletedPart{
+			ETag:           &etag,
+			PartNumber:     &pn,
+			ChecksumSHA256: &checksum.Value,
+		})
+	}
+
+	gw, err := New(inner, e.kms, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := gw.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          backend.GetStringPtr(testBucket),
+		Key:             backend.GetStringPtr("mp"),
+		UploadId:        mpu.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := backend.CompositeChecksum(types.ChecksumAlgorithmSha256, checksums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.ChecksumSHA256 == nil || *out.ChecksumSHA256 != expected.Value {
+		t.Errorf("expected checksum %v, got %v", expected.Value, out.ChecksumSHA256)
+	}
+
+	if got := get(t, e, "mp", ""); got != parts[0]+parts[1] {
+		t.Errorf("expected %v bytes of object data, got %v bytes",
+			len(parts[0]+parts[1]), len(got))
+	}
+	rng := fmt.Sprintf("bytes=%v-%v", backend.MinPartSize-2, backend.MinPartSize)
+	if got := get(t, e, "mp", rng); got != "cdx" {
+		t.Errorf("range across parts: expected %q, got %q", "cdx", got)
+	}
+
+	head, err := e.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: backend.GetStringPtr(testBucket),
+		Key:    backend.GetStringPtr("mp"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(head.Metadata) != 1 || head.Metadata["color"] != "blue" {
+		t.Errorf("unexpected metadata %v", head.Metadata)
+	}
+
+	// the upload checksum state is not kept with the object
+	head, err = inner.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: backend.GetStringPtr(testBucket),
+		Key:    backend.GetStringPtr("mp"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range head.Metadata {
+		if k == metaChecksumAlgorithm || strings.HasPrefix(k, metaPartChecksum) {
+			t.Errorf("unexpected object metadata %v", k)
+		}
+	}
+}
+
+func TestEncrypt_PartLayout(t *testing.T) {
+	tests := []struct {
+		parts  []int32
+		sizes  []int64
+		layout string
+	}{
+		{[]int32{1}, []int64{0}, "1:0"},
+		{[]int32{1, 2, 3}, []int64{5, 5, 2}, "1-2:5,3:2"},
+		{[]int32{1, 3, 4, 7}, []int64{5, 5, 5, 5}, "1:5,3-4:5,7:5"},
+		{[]int32{2, 3, 4}, []int64{5, 6, 6}, "2:5,3-4:6"},
+	}
+	for _, tt := range tests {
+		layout := encodeParts(tt.parts, tt.sizes)
+		if layout != tt.layout {
+			t.Errorf("encode %v %v: expected %q, got %q",
+				tt.parts, tt.sizes, tt.layout, layout)
+		}
+		parts, sizes, err := decodeParts(layout)
+		if err != nil {
+			t.Errorf("decode %q: %v", layout, err)
+			continue
+		}
+		if !reflect.DeepEqual(parts, tt.parts) || !reflect.DeepEqual(sizes, tt.sizes) {
+			t.Errorf("decode %q: expected %v %v, got %v %v",
+				layout, tt.parts, tt.sizes, parts, sizes)
+		}
+	}
+
+	for _, layout := range []string{"", "1", "1:x", "2-1:5", "1:-5"} {
+		if _, _, err := decodeParts(layout); err == nil {
+			t.Errorf("decode %q: expected error", layout)
+		}
+	}
+}
@@ -0,0 +1,630 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package encrypt
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/kms"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+// Multipart uploads are encrypted with the data key of the object they
+// complete, generated when the upload is created. The offset of a part
+// within the object is only known once the upload completes, so each part
+// is encrypted at its own keystream offset, and the order and sizes of the
+// completed parts are recorded with the object to decrypt it. The wrapped
+// data key, the checksum algorithm and the part checksums are kept in the
+// upload metadata, so that the upload can be continued by any gateway
+// sharing the inner backend. Multipart uploads are not supported for inner
+// backends that do not implement backend.UploadMetadataStorer. The part
+// checksums are computed here over the part data before it is encrypted.
+const (
+	metaChecksumAlgorithm = metaPrefix + "checksum-algorithm"
+	metaPartChecksum      = metaPrefix + "part-checksum-"
+)
+
+// upload is a multipart upload as stored in the upload metadata
+type upload struct {
+	meta map[string]string
+
+	// key and iv are nil for uploads created before at rest encryption
+	// was enabled, these are passed through to the inner backend
+	key []byte
+	iv  []byte
+
+	checksumAlgorithm types.ChecksumAlgorithm
+}
+
+// partSpacing separates the keystreams of the parts, it is larger than
+// the maximum part size
+const partSpacing = 1 << 36
+
+func partOffset(partNumber int32) int64 {
+	return int64(partNumber) * partSpacing
+}
+
+// metadataStorer returns the inner backend upload metadata storage,
+// which is required to encrypt multipart uploads
+func (e *Encrypt) metadataStorer() (backend.UploadMetadataStorer, error) {
+	s, ok := e.Backend.(backend.UploadMetadataStorer)
+	if !ok {
+		return nil, s3err.GetAPIError(s3err.ErrNotImplemented)
+	}
+	return s, nil
+}
+
+func (e *Encrypt) getUpload(ctx context.Context, bucket, object, uploadId *string) (*upload, error) {
+	if bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if object == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	if uploadId == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchUpload)
+	}
+
+	s, err := e.metadataStorer()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := s.GetUploadMetadata(ctx, *bucket, *object, *uploadId)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &upload{
+		meta:              meta,
+		checksumAlgorithm: types.ChecksumAlgorithm(meta[metaChecksumAlgorithm]),
+	}
+	if !isEncrypted(meta) {
+		return u, nil
+	}
+	u.key, u.iv, err = e.dataKey(ctx, meta)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (e *Encrypt) CreateMultipartUpload(ctx context.Context, input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	_, err := e.metadataStorer()
+	if err != nil {
+		return nil, err
+	}
+
+	in := *input
+	in.Metadata = userMetadata(input.Metadata)
+	err = e.newDataKey(ctx, in.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if input.ChecksumAlgorithm != "" {
+		in.Metadata[metaChecksumAlgorithm] = string(input.ChecksumAlgorithm)
+	}
+	// the backend would checksum the encrypted parts
+	in.ChecksumAlgorithm = ""
+
+	out, err := e.Backend.CreateMultipartUpload(ctx, &in)
+	if err != nil {
+		return out, err
+	}
+	out.ChecksumAlgorithm = input.ChecksumAlgorithm
+
+	return out, nil
+}
+
+// partReader returns a reader encrypting the part data read from r
+func (u *upload) partReader(partNumber int32, r io.Reader) (io.Reader, error) {
+	if u.key == nil {
+		return r, nil
+	}
+	stream, err := kms.NewStream(u.key, u.iv, partOffset(partNumber))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamReader{S: stream, R: r}, nil
+}
+
+func (e *Encrypt) UploadPart(ctx context.Context, input *s3.UploadPartInput) (string, error) {
+	u, err := e.getUpload(ctx, input.Bucket, input.Key, input.UploadId)
+	if err != nil {
+		return "", err
+	}
+	if u.key == nil {
+		return e.Backend.UploadPart(ctx, input)
+	}
+	if input.PartNumber == nil {
+		return "", s3err.GetAPIError(s3err.ErrInvalidPartNumber)
+	}
+
+	body := input.Body
+	if body == nil {
+		body = eofReader{}
+	}
+
+	sent := backend.ChecksumFromFields(input.ChecksumCRC32,
+		input.ChecksumCRC32C, input.ChecksumSHA1, input.ChecksumSHA256)
+	body, h, err := u.checksumReader(body, sent)
+	if err != nil {
+		return "", err
+	}
+	body, err = u.partReader(*input.PartNumber, body)
+	if err != nil {
+		return "", err
+	}
+
+	in := *input
+	in.Body = body
+	in.ChecksumCRC32 = nil
+	in.ChecksumCRC32C = nil
+	in.ChecksumSHA1 = nil
+	in.ChecksumSHA256 = nil
+
+	etag, err := e.Backend.UploadPart(ctx, &in)
+	if err != nil {
+		return etag, err
+	}
+	err = e.setPartChecksum(ctx, input.Bucket, input.Key, input.UploadId,
+		*input.PartNumber, h)
+	if err != nil {
+		return "", err
+	}
+	return etag, nil
+}
+
+// checksumReader returns a reader of r computing the checksum of the part
+// data when the upload has a checksum algorithm. A checksum sent with the
+// part must use the checksum algorithm of the upload.
+func (u *upload) checksumReader(r io.Reader, sent *backend.Checksum) (io.Reader, hash.Hash, error) {
+	if u.checksumAlgorithm == "" {
+		return r, nil, nil
+	}
+	if sent != nil && sent.Algorithm != u.checksumAlgorithm {
+		return nil, nil, s3err.GetAPIError(s3err.ErrChecksumAlgorithmMismatch)
+	}
+	h, err := backend.NewChecksumHash(u.checksumAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.TeeReader(r, h), h, nil
+}
+
+// setPartChecksum stores the checksum computed for the uploaded part in
+// the upload metadata
+func (e *Encrypt) setPartChecksum(ctx context.Context, bucket, object, uploadId *string, partNumber int32, h hash.Hash) error {
+	if h == nil {
+		return nil
+	}
+	s, err := e.metadataStorer()
+	if err != nil {
+		return err
+	}
+	err = s.SetUploadMetadata(ctx, *bucket, *object, *uploadId, map[string]string{
+		metaPartChecksum + strconv.Itoa(int(partNumber)): base64.StdEncoding.EncodeToString(h.Sum(nil)),
+	})
+	if err != nil {
+		return fmt.Errorf("store part checksum: %w", err)
+	}
+	return nil
+}
+
+// objectChecksum returns the composite checksum of the completed parts,
+// or nil for uploads without checksums
+func (u *upload) objectChecksum(parts []types.CompletedPart) (*backend.Checksum, error) {
+	if u.checksumAlgorithm == "" {
+		return nil, nil
+	}
+
+	checksums := make([]*backend.Checksum, 0, len(parts))
+	for _, p := range parts {
+		if p.PartNumber == nil {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
+		}
+		var checksum *backend.Checksum
+		value, ok := u.meta[metaPartChecksum+strconv.Itoa(int(*p.PartNumber))]
+		if ok {
+			checksum = &backend.Checksum{
+				Algorithm: u.checksumAlgorithm,
+				Value:     value,
+			}
+		}
+		err := backend.CheckPartChecksum(p, checksum)
+		if err != nil {
+			return nil, err
+		}
+		checksums = append(checksums, checksum)
+	}
+	return backend.CompositeChecksum(u.checksumAlgorithm, checksums)
+}
+
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+// UploadPartCopy reads the decrypted source data through the gateway,
+// since the source is encrypted with a different key than the part
+func (e *Encrypt) UploadPartCopy(ctx context.Context, input *s3.UploadPartCopyInput) (s3response.CopyObjectResult, error) {
+	u, err := e.getUpload(ctx, input.Bucket, input.Key, input.UploadId)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+	if input.PartNumber == nil {
+		return s3response.CopyObjectResult{}, s3err.GetAPIError(s3err.ErrInvalidPartNumber)
+	}
+
+	srcBucket, srcObject, versionId, err := backend.ParseCopySource(input.CopySource)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	head, err := e.Backend.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:    &srcBucket,
+		Key:       &srcObject,
+		VersionId: &versionId,
+	})
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	var etag string
+	if head.ETag != nil {
+		etag = *head.ETag
+	}
+	var modTime time.Time
+	if head.LastModified != nil {
+		modTime = *head.LastModified
+	}
+	err = backend.EvaluateCopySourcePreconditions(etag, modTime,
+		input.CopySourceIfMatch, input.CopySourceIfNoneMatch,
+		input.CopySourceIfModifiedSince, input.CopySourceIfUnmodifiedSince)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	var size int64
+	if head.ContentLength != nil {
+		size = *head.ContentLength
+	}
+	var copyRange string
+	if input.CopySourceRange != nil {
+		copyRange = *input.CopySourceRange
+	}
+	_, length, err := backend.ParseCopySourceRange(size, copyRange)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	var partEtag string
+	var h hash.Hash
+	err = backend.PipeObject(
+		func(w io.Writer) error {
+			_, err := e.GetObject(ctx, &s3.GetObjectInput{
+				Bucket:    &srcBucket,
+				Key:       &srcObject,
+				VersionId: &versionId,
+				Range:     input.CopySourceRange,
+				IfMatch:   head.ETag,
+			}, w)
+			return err
+		},
+		func(r io.Reader) error {
+			var err error
+			r, h, err = u.checksumReader(r, nil)
+			if err != nil {
+				return err
+			}
+			r, err = u.partReader(*input.PartNumber, r)
+			if err != nil {
+				return err
+			}
+			partEtag, err = e.Backend.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:        input.Bucket,
+				Key:           input.Key,
+				UploadId:      input.UploadId,
+				PartNumber:    input.PartNumber,
+				ContentLength: &length,
+				Body:          r,
+			})
+			return err
+		})
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+	err = e.setPartChecksum(ctx, input.Bucket, input.Key, input.UploadId,
+		*input.PartNumber, h)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	return s3response.CopyObjectResult{
+		ETag:         partEtag,
+		LastModified: time.Now(),
+	}, nil
+}
+
+func (e *Encrypt) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	u, err := e.getUpload(ctx, input.Bucket, input.Key, input.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	if u.key == nil {
+		return e.Backend.CompleteMultipartUpload(ctx, input)
+	}
+
+	parts, sizes, err := e.partSizes(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := u.objectChecksum(input.MultipartUpload.Parts)
+	if err != nil {
+		return nil, err
+	}
+
+	// the part layout is stored with the upload metadata so that the
+	// object is decryptable as soon as the backend completes it, the
+	// checksums of the parts are not kept with the object
+	update := map[string]string{
+		metaParts:             encodeParts(parts, sizes),
+		metaChecksumAlgorithm: "",
+	}
+	restore := make(map[string]string)
+	for k, v := range u.meta {
+		if strings.HasPrefix(k, metaPartChecksum) {
+			update[k] = ""
+			restore[k] = v
+		}
+	}
+	s, err := e.metadataStorer()
+	if err != nil {
+		return nil, err
+	}
+	err = s.SetUploadMetadata(ctx, *input.Bucket, *input.Key, *input.UploadId, update)
+	if err != nil {
+		return nil, fmt.Errorf("store part layout: %w", err)
+	}
+
+	// the part checksums were verified above, the backend has none
+	completed := make([]types.CompletedPart, 0, len(input.MultipartUpload.Parts))
+	for _, p := range input.MultipartUpload.Parts {
+		completed = append(completed, types.CompletedPart{
+			ETag:       p.ETag,
+			PartNumber: p.PartNumber,
+		})
+	}
+	in := *input
+	in.MultipartUpload = &types.CompletedMultipartUpload{Parts: completed}
+
+	out, err := e.Backend.CompleteMultipartUpload(ctx, &in)
+	if err != nil {
+		// keep the part checksums for a retry of the completion
+		if u.checksumAlgorithm != "" {
+			restore[metaChecksumAlgorithm] = string(u.checksumAlgorithm)
+		}
+		_ = s.SetUploadMetadata(ctx, *input.Bucket, *input.Key, *input.UploadId, restore)
+		return out, err
+	}
+
+	out.ChecksumCRC32, out.ChecksumCRC32C, out.ChecksumSHA1,
+		out.ChecksumSHA256 = checksum.Fields()
+
+	return out, nil
+}
+
+// partSizes returns the sizes of the completed parts in object order
+func (e *Encrypt) partSizes(ctx context.Context, input *s3.CompleteMultipartUploadInput) ([]int32, []int64, error) {
+	if input.MultipartUpload == nil {
+		return nil, nil, s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	uploaded := make(map[int32]int64)
+	var marker string
+	for {
+		res, err := e.Backend.ListParts(ctx, &s3.ListPartsInput{
+			Bucket:           input.Bucket,
+			Key:              input.Key,
+			UploadId:         input.UploadId,
+			PartNumberMarker: &marker,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range res.Parts {
+			uploaded[int32(p.PartNumber)] = p.Size
+		}
+		if !res.IsTruncated {
+			break
+		}
+		marker = strconv.Itoa(res.NextPartNumberMarker)
+	}
+
+	parts := make([]int32, 0, len(input.MultipartUpload.Parts))
+	sizes := make([]int64, 0, len(input.MultipartUpload.Parts))
+	for _, p := range input.MultipartUpload.Parts {
+		if p.PartNumber == nil {
+			return nil, nil, s3err.GetAPIError(s3err.ErrInvalidPart)
+		}
+		size, ok := uploaded[*p.PartNumber]
+		if !ok {
+			return nil, nil, s3err.GetAPIError(s3err.ErrInvalidPart)
+		}
+		parts = append(parts, *p.PartNumber)
+		sizes = append(sizes, size)
+	}
+
+	return parts, sizes, nil
+}
+
+// encodeParts returns the layout of the completed parts in object order.
+// Runs of consecutive part numbers with the same size are stored as
+// "first-last:size", other parts as "number:size".
+func encodeParts(parts []int32, sizes []int64) string {
+	var runs []string
+	for i := 0; i < len(parts); {
+		j := i
+		for j+1 < len(parts) && parts[j+1] == parts[j]+1 && sizes[j+1] == sizes[i] {
+			j++
+		}
+		if j == i {
+			runs = append(runs, fmt.Sprintf("%v:%v", parts[i], sizes[i]))
+		} else {
+			runs = append(runs, fmt.Sprintf("%v-%v:%v", parts[i], parts[j], sizes[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(runs, ",")
+}
+
+// decodeParts parses the part layout stored by encodeParts
+func decodeParts(layout string) ([]int32, []int64, error) {
+	var parts []int32
+	var sizes []int64
+	for _, run := range strings.Split(layout, ",") {
+		numbers, sizeStr, ok := strings.Cut(run, ":")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid part layout %q", run)
+		}
+		size, err := strconv.ParseInt(sizeStr, 10, 64)
+		if err != nil || size < 0 {
+			return nil, nil, fmt.Errorf("invalid part layout %q", run)
+		}
+		firstStr, lastStr, isRun := strings.Cut(numbers, "-")
+		if !isRun {
+			lastStr = firstStr
+		}
+		first, err := strconv.ParseInt(firstStr, 10, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid part layout %q", run)
+		}
+		last, err := strconv.ParseInt(lastStr, 10, 32)
+		if err != nil || last < first {
+			return nil, nil, fmt.Errorf("invalid part layout %q", run)
+		}
+		for pn := first; pn <= last; pn++ {
+			parts = append(parts, int32(pn))
+			sizes = append(sizes, size)
+		}
+	}
+	return parts, sizes, nil
+}
+
+// partStart returns the object offset of the part read by part number,
+// the backend part numbers are the positions of the parts in the object
+func partStart(meta map[string]string, partNumber int32) int64 {
+	if meta[metaParts] == "" {
+		return 0
+	}
+	_, sizes, err := decodeParts(meta[metaParts])
+	if err != nil || partNumber < 1 || int(partNumber) > len(sizes) {
+		return 0
+	}
+	var offset int64
+	for _, size := range sizes[:partNumber-1] {
+		offset += size
+	}
+	return offset
+}
+
+// decryptPartsWriter returns a writer decrypting the data of an object
+// completed from a multipart upload written to it starting at offset
+// into the object
+func (e *Encrypt) decryptPartsWriter(ctx context.Context, meta map[string]string, w io.Writer, offset int64) (io.Writer, error) {
+	parts, sizes, err := decodeParts(meta[metaParts])
+	if err != nil {
+		return nil, fmt.Errorf("decrypt object: %w", err)
+	}
+	key, iv, err := e.dataKey(ctx, meta)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt object: %w", err)
+	}
+
+	d := &partDecrypter{w: w, key: key, iv: iv, parts: parts, sizes: sizes}
+	for d.next < len(sizes) && offset >= sizes[d.next] {
+		offset -= sizes[d.next]
+		d.next++
+	}
+	d.skip = offset
+	return d, nil
+}
+
+// partDecrypter decrypts the parts of a completed object written to it,
+// restarting the keystream at each part boundary
+type partDecrypter struct {
+	w      io.Writer
+	key    []byte
+	iv     []byte
+	parts  []int32
+	sizes  []int64
+	next   int
+	skip   int64
+	left   int64
+	stream cipher.Stream
+	buf    []byte
+}
+
+func (d *partDecrypter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		for d.left == 0 {
+			if d.next == len(d.parts) {
+				return n, errors.New("object data exceeds the part sizes")
+			}
+			stream, err := kms.NewStream(d.key, d.iv, partOffset(d.parts[d.next])+d.skip)
+			if err != nil {
+				return n, err
+			}
+			d.stream = stream
+			d.left = d.sizes[d.next] - d.skip
+			d.skip = 0
+			d.next++
+		}
+
+		chunk := p
+		if int64(len(chunk)) > d.left {
+			chunk = chunk[:d.left]
+		}
+		if cap(d.buf) < len(chunk) {
+			d.buf = make([]byte, len(chunk))
+		}
+		buf := d.buf[:len(chunk)]
+		d.stream.XORKeyStream(buf, chunk)
+
+		_, err := d.w.Write(buf)
+		if err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		d.left -= int64(len(chunk))
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
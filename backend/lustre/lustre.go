@@ -0,0 +1,282 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package lustre
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/meta"
+	"github.com/versity/versitygw/backend/posix"
+	"github.com/versity/versitygw/s3err"
+)
+
+type LustreOpts struct {
+	ChownUID bool
+	ChownGID bool
+	HSMMode  bool
+}
+
+type Lustre struct {
+	*posix.Posix
+	rootfd *os.File
+
+	// hsmmode enables the following behavior:
+	// GET object:  if file released, return invalid object state
+	// HEAD object: if file released, set obj storage class to GLACIER
+	//              if file released and restoring, x-amz-restore: ongoing-request="true"
+	//              if file released and not restoring, x-amz-restore: ongoing-request="false"
+	// ListObjects: if file released, set obj storage class to GLACIER
+	// RestoreObject: request hsm restore of the released file
+	hsmmode bool
+
+	// stripeMu serializes creating the stripe count temp dirs
+	stripeMu sync.Mutex
+}
+
+var _ backend.Backend = &Lustre{}
+
+const (
+	metaTmpDir = ".sgwtmp"
+
+	// stripeMetaKey is the user metadata key of the stripe count hint,
+	// set with the x-amz-meta-lustre-stripe header
+	stripeMetaKey = "lustre-stripe"
+	// maxStripeCount is the lustre LOV_MAX_STRIPE_COUNT, a stripe
+	// count of -1 stripes over all OSTs
+	maxStripeCount = 2000
+)
+
+var (
+	restoreInProgress    = "ongoing-request=\"true\""
+	restoreNotInProgress = "ongoing-request=\"false\""
+)
+
+func New(rootdir string, meta meta.MetadataStorer, opts LustreOpts) (*Lustre, error) {
+	l := &Lustre{
+		hsmmode: opts.HSMMode,
+	}
+
+	p, err := posix.New(rootdir, meta, posix.PosixOpts{
+		ChownUID:  opts.ChownUID,
+		ChownGID:  opts.ChownGID,
+		PutTmpDir: l.putTmpDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(rootdir)
+	if err != nil {
+		p.Shutdown()
+		return nil, fmt.Errorf("open %v: %w", rootdir, err)
+	}
+
+	l.Posix = p
+	l.rootfd = f
+	return l, nil
+}
+
+func (l *Lustre) Shutdown() {
+	l.Posix.Shutdown()
+	l.rootfd.Close()
+}
+
+func (*Lustre) String() string {
+	return "Lustre Gateway"
+}
+
+// putTmpDir creates new objects with a stripe count hint within a temp dir
+// with a default layout of that stripe count, so that the object file
+// inherits the layout when created
+func (l *Lustre) putTmpDir(po *s3.PutObjectInput) (string, error) {
+	var hint string
+	for k, v := range po.Metadata {
+		if strings.EqualFold(k, stripeMetaKey) {
+			hint = v
+		}
+	}
+	if hint == "" {
+		return "", nil
+	}
+
+	count, err := strconv.Atoi(hint)
+	if err != nil || count == 0 || count < -1 || count > maxStripeCount {
+		return "", s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
+
+	name := fmt.Sprintf("stripe-%v", count)
+	if count == -1 {
+		name = "stripe-all"
+	}
+	dir := filepath.Join(*po.Bucket, metaTmpDir, name)
+
+	l.stripeMu.Lock()
+	defer l.stripeMu.Unlock()
+
+	_, err = os.Stat(dir)
+	if err == nil {
+		return dir, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(dir), 0755)
+	if err != nil {
+		return "", fmt.Errorf("make temp dir: %w", err)
+	}
+	err = os.Mkdir(dir, 0755)
+	if errors.Is(err, fs.ErrExist) {
+		return dir, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("make stripe temp dir: %w", err)
+	}
+
+	err = setDirStripeCount(dir, count)
+	if err != nil {
+		os.Remove(dir)
+		return "", fmt.Errorf("set stripe count: %w", err)
+	}
+
+	return dir, nil
+}
+
+func (l *Lustre) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	out, err := l.Posix.HeadObject(ctx, input)
+	if err != nil || !l.hsmmode {
+		return out, err
+	}
+
+	st, err := getHSMState(filepath.Join(*input.Bucket, *input.Key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get hsm state: %w", err)
+	}
+
+	if st.released {
+		out.StorageClass = types.StorageClassGlacier
+		out.Restore = &restoreNotInProgress
+		if st.restoring {
+			out.Restore = &restoreInProgress
+		}
+	}
+
+	return out, nil
+}
+
+func (l *Lustre) GetObject(ctx context.Context, input *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, error) {
+	if l.hsmmode && input.Bucket != nil && input.Key != nil {
+		// reading a released file would block on an implicit restore,
+		// so return an error until the object is restored
+		st, err := getHSMState(filepath.Join(*input.Bucket, *input.Key))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("get hsm state: %w", err)
+		}
+		if st.released {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidObjectState)
+		}
+	}
+
+	return l.Posix.GetObject(ctx, input, writer)
+}
+
+func (l *Lustre) ListObjects(ctx context.Context, input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	out, err := l.Posix.ListObjects(ctx, input)
+	if err != nil || !l.hsmmode {
+		return out, err
+	}
+
+	return out, l.setStorageClass(*input.Bucket, out.Contents)
+}
+
+func (l *Lustre) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	out, err := l.Posix.ListObjectsV2(ctx, input)
+	if err != nil || !l.hsmmode {
+		return out, err
+	}
+
+	return out, l.setStorageClass(*input.Bucket, out.Contents)
+}
+
+// setStorageClass sets the storage class of the listed released objects to
+// GLACIER
+func (l *Lustre) setStorageClass(bucket string, objs []types.Object) error {
+	for i, obj := range objs {
+		if obj.Key == nil || strings.HasSuffix(*obj.Key, "/") {
+			continue
+		}
+		st, err := getHSMState(filepath.Join(bucket, *obj.Key))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("get hsm state %v: %w", *obj.Key, err)
+		}
+		if st.released {
+			objs[i].StorageClass = types.ObjectStorageClassGlacier
+		}
+	}
+	return nil
+}
+
+// RestoreObject requests the hsm restore of a released file, and does
+// nothing if the file is not released or already restoring
+func (l *Lustre) RestoreObject(ctx context.Context, input *s3.RestoreObjectInput) error {
+	if !l.hsmmode {
+		return l.Posix.RestoreObject(ctx, input)
+	}
+
+	bucket := *input.Bucket
+	object := *input.Key
+
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	objPath := filepath.Join(bucket, object)
+	st, err := getHSMState(objPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	if err != nil {
+		return fmt.Errorf("get hsm state: %w", err)
+	}
+	if !st.released || st.restoring {
+		return nil
+	}
+
+	err = requestRestore(l.rootfd, objPath)
+	if err != nil {
+		return fmt.Errorf("request hsm restore: %w", err)
+	}
+
+	return nil
+}
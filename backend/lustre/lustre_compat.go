@@ -0,0 +1,159 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+
+package lustre
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// lustre_user.h ioctls and flags
+const (
+	// _IOR('f', 211, struct hsm_user_state)
+	iocHSMStateGet = 0x802066d3
+	// _IOR('f', 173, struct lu_fid)
+	iocPath2Fid = 0x801066ad
+	// _IOW('f', 217, struct hsm_user_request *)
+	iocHSMRequest = 0x400866d9
+	// _IOW('f', 154, long)
+	iocLovSetStripe = 0x4008669a
+
+	hsRelease    = 0x4
+	hsmaRestore  = 21
+	huaRestore   = 11
+	lovUserMagic = 0x0bd10bd0
+)
+
+// hsmUserState is struct hsm_user_state
+type hsmUserState struct {
+	States           uint32
+	ArchiveID        uint32
+	InProgressState  uint32
+	InProgressAction uint32
+	Offset           uint64
+	Length           uint64
+}
+
+// luFid is struct lu_fid
+type luFid struct {
+	Seq uint64
+	Oid uint32
+	Ver uint32
+}
+
+// lovUserMd is struct lov_user_md_v1
+type lovUserMd struct {
+	Magic        uint32
+	Pattern      uint32
+	ObjectID     uint64
+	ObjectSeq    uint64
+	StripeSize   uint32
+	StripeCount  uint16
+	StripeOffset uint16
+}
+
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+type hsmState struct {
+	released  bool
+	restoring bool
+}
+
+func getHSMState(path string) (hsmState, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return hsmState{}, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return hsmState{}, err
+	}
+	if !fi.Mode().IsRegular() {
+		return hsmState{}, nil
+	}
+
+	var hus hsmUserState
+	err = ioctl(f, iocHSMStateGet, unsafe.Pointer(&hus))
+	if err != nil {
+		return hsmState{}, err
+	}
+
+	return hsmState{
+		released:  hus.States&hsRelease != 0,
+		restoring: hus.InProgressAction == hsmaRestore,
+	}, nil
+}
+
+// requestRestore submits an hsm restore request of the whole file to the
+// coordinator, dir is any directory within the filesystem
+func requestRestore(dir *os.File, path string) error {
+	f, err := os.OpenFile(path, os.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return err
+	}
+	var fid luFid
+	err = ioctl(f, iocPath2Fid, unsafe.Pointer(&fid))
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("get fid: %w", err)
+	}
+
+	// struct hsm_user_request with a single struct hsm_user_item
+	// covering the whole file
+	req := make([]byte, 0, 56)
+	req = binary.NativeEndian.AppendUint32(req, huaRestore) // hr_action
+	req = binary.NativeEndian.AppendUint32(req, 0)          // hr_archive_id
+	req = binary.NativeEndian.AppendUint64(req, 0)          // hr_flags
+	req = binary.NativeEndian.AppendUint32(req, 1)          // hr_itemcount
+	req = binary.NativeEndian.AppendUint32(req, 0)          // hr_data_len
+	req = binary.NativeEndian.AppendUint64(req, fid.Seq)
+	req = binary.NativeEndian.AppendUint32(req, fid.Oid)
+	req = binary.NativeEndian.AppendUint32(req, fid.Ver)
+	req = binary.NativeEndian.AppendUint64(req, 0)          // offset
+	req = binary.NativeEndian.AppendUint64(req, ^uint64(0)) // length
+
+	return ioctl(dir, iocHSMRequest, unsafe.Pointer(&req[0]))
+}
+
+// setDirStripeCount sets the default layout stripe count of new files
+// created within the directory, -1 stripes over all OSTs
+func setDirStripeCount(dir string, count int) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	lum := lovUserMd{
+		Magic:        lovUserMagic,
+		StripeCount:  uint16(count),
+		StripeOffset: 0xffff,
+	}
+	return ioctl(f, iocLovSetStripe, unsafe.Pointer(&lum))
+}
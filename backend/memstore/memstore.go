@@ -0,0 +1,1697 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+const (
+	nullVersionId = "null"
+)
+
+// MemStore is a backend keeping all buckets, objects and multipart
+// uploads in memory. Nothing is persisted, so the contents are lost
+// when the gateway exits.
+type MemStore struct {
+	backend.BackendUnsupported
+
+	mu      sync.RWMutex
+	buckets map[string]*bucket
+}
+
+var _ backend.Backend = &MemStore{}
+
+type bucket struct {
+	created           time.Time
+	acl               []byte
+	policy            []byte
+	tags              map[string]string
+	lockConfig        []byte
+	notification      []byte
+	logging           []byte
+	publicAccessBlock []byte
+	versioning        types.BucketVersioningStatus
+	quota             s3response.BucketQuota
+	objects           map[string]*object
+	uploads           map[string]*upload
+}
+
+type object struct {
+	data            []byte
+	etag            string
+	modTime         time.Time
+	owner           string
+	acl             []byte
+	metadata        map[string]string
+	contentType     string
+	contentEncoding string
+	tags            map[string]string
+	retention       []byte
+	legalHold       *bool
+	// partSizes are the part sizes of objects created by a multipart
+	// upload
+	partSizes []int64
+	checksum  *backend.Checksum
+}
+
+type upload struct {
+	key             string
+	initiated       time.Time
+	initiator       string
+	metadata        map[string]string
+	contentType     string
+	contentEncoding string
+	acl             []byte
+	tags            map[string]string
+	retention       []byte
+	legalHold       *bool
+	parts           map[int32]*part
+	// checksumAlgorithm is the algorithm of the part checksums, or ""
+	// for uploads without checksums
+	checksumAlgorithm types.ChecksumAlgorithm
+}
+
+type part struct {
+	data     []byte
+	etag     string
+	modTime  time.Time
+	checksum *backend.Checksum
+}
+
+// New returns an empty in-memory backend
+func New() *MemStore {
+	return &MemStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (m *MemStore) Shutdown() {}
+
+func (m *MemStore) String() string {
+	return "Memory Gateway"
+}
+
+// getBucket returns the named bucket, the caller must hold the lock
+func (m *MemStore) getBucket(name string) (*bucket, error) {
+	b, ok := m.buckets[name]
+	if !ok {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	return b, nil
+}
+
+// getObject returns the named object, the caller must hold the lock
+func (m *MemStore) getObject(bucket, key string) (*bucket, *object, error) {
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj, ok := b.objects[key]
+	if !ok {
+		return b, nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	return b, obj, nil
+}
+
+// owner returns the bucket owner recorded in the bucket acl
+func (b *bucket) owner() string {
+	var acl auth.ACL
+	if err := json.Unmarshal(b.acl, &acl); err != nil {
+		return ""
+	}
+	return acl.Owner
+}
+
+// lockEnabled reports whether object lock is enabled for the bucket
+func (b *bucket) lockEnabled() (bool, error) {
+	if b.lockConfig == nil {
+		return false, nil
+	}
+
+	var cfg auth.BucketLockConfig
+	if err := json.Unmarshal(b.lockConfig, &cfg); err != nil {
+		return false, fmt.Errorf("parse bucket lock config: %w", err)
+	}
+	return cfg.Enabled, nil
+}
+
+// usage returns the total size and count of the bucket objects
+func (b *bucket) usage() (int64, int64) {
+	var size int64
+	for _, obj := range b.objects {
+		size += int64(len(obj.data))
+	}
+	return size, int64(len(b.objects))
+}
+
+// checkQuota returns ErrQuotaExceeded if storing an object of the given
+// size at key would exceed the bucket quota
+func (b *bucket) checkQuota(key string, size int64) error {
+	if b.quota.Size == 0 && b.quota.Objects == 0 {
+		return nil
+	}
+
+	usedSize, usedObjects := b.usage()
+	sizeDelta, objDelta := size, int64(1)
+	if old, ok := b.objects[key]; ok {
+		sizeDelta -= int64(len(old.data))
+		objDelta = 0
+	}
+
+	if (sizeDelta > 0 && b.quota.Size > 0 && usedSize+sizeDelta > b.quota.Size) ||
+		(objDelta > 0 && b.quota.Objects > 0 && usedObjects+objDelta > b.quota.Objects) {
+		return s3err.GetAPIError(s3err.ErrQuotaExceeded)
+	}
+	return nil
+}
+
+// ownerOf returns the account that created the object, or the bucket
+// owner for objects without a recorded owner
+func (b *bucket) ownerOf(obj *object) *types.Owner {
+	owner := obj.owner
+	if owner == "" {
+		owner = b.owner()
+	}
+	return &types.Owner{
+		ID:          &owner,
+		DisplayName: &owner,
+	}
+}
+
+func getAccount(ctx context.Context) auth.Account {
+	acct, ok := ctx.Value("account").(auth.Account)
+	if !ok {
+		acct = auth.Account{}
+	}
+	return acct
+}
+
+func getString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// copyMap returns a deep copy of m. The strings of the request are only
+// valid for the duration of the request, so anything kept in memory
+// must be cloned.
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[strings.Clone(k)] = strings.Clone(v)
+	}
+	return c
+}
+
+func compareUserMetadata(meta1, meta2 map[string]string) bool {
+	if len(meta1) != len(meta2) {
+		return false
+	}
+
+	for key, val := range meta1 {
+		if meta2[key] != val {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newObjectAcl returns the encoded acl requested with the upload of a new
+// object, or nil if the object keeps the default acl
+func newObjectAcl(b *bucket, owner string, canned types.ObjectCannedACL, grants auth.Grants) ([]byte, error) {
+	acl, ok := auth.NewObjectACL(owner, b.owner(), canned, grants)
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(acl)
+	if err != nil {
+		return nil, fmt.Errorf("marshal acl: %w", err)
+	}
+	return data, nil
+}
+
+// newRetention returns the encoded retention of the object lock settings
+// requested for a new object, or nil when no retention is requested
+func newRetention(mode types.ObjectLockMode, retainUntilDate *time.Time) ([]byte, error) {
+	if mode == "" {
+		return nil, nil
+	}
+	retention, err := json.Marshal(types.ObjectLockRetention{
+		Mode:            types.ObjectLockRetentionMode(mode),
+		RetainUntilDate: retainUntilDate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse object lock retention: %w", err)
+	}
+	return retention, nil
+}
+
+// newLegalHold returns the legal hold requested for a new object, the
+// bucket must have object lock enabled for a legal hold or retention
+// to be requested
+func (b *bucket) newLegalHold(status types.ObjectLockLegalHoldStatus, retention []byte) (*bool, error) {
+	var legalHold *bool
+	if status == types.ObjectLockLegalHoldStatusOn {
+		legalHold = backend.GetBoolPtr(true)
+	}
+	if retention != nil || legalHold != nil {
+		enabled, err := b.lockEnabled()
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidBucketObjectLockConfiguration)
+		}
+	}
+	return legalHold, nil
+}
+
+func (m *MemStore) ListBuckets(_ context.Context, owner string, isAdmin bool) (s3response.ListAllMyBucketsResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var buckets []s3response.ListAllMyBucketsEntry
+	for name, b := range m.buckets {
+		// return all the buckets for admin users
+		if !isAdmin && b.owner() != owner {
+			continue
+		}
+		buckets = append(buckets, s3response.ListAllMyBucketsEntry{
+			Name:         name,
+			CreationDate: b.created,
+		})
+	}
+
+	sort.Sort(backend.ByBucketName(buckets))
+
+	return s3response.ListAllMyBucketsResult{
+		Buckets: s3response.ListAllMyBucketsList{
+			Bucket: buckets,
+		},
+		Owner: s3response.CanonicalUser{
+			ID: owner,
+		},
+	}, nil
+}
+
+func (m *MemStore) HeadBucket(_ context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, err := m.getBucket(*input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (m *MemStore) CreateBucket(_ context.Context, input *s3.CreateBucketInput, acl []byte) error {
+	if input.Bucket == nil {
+		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.buckets[*input.Bucket]; ok {
+		return s3err.GetAPIError(s3err.ErrBucketAlreadyExists)
+	}
+
+	now := time.Now()
+	b := &bucket{
+		created: now,
+		acl:     bytes.Clone(acl),
+		objects: make(map[string]*object),
+		uploads: make(map[string]*upload),
+	}
+
+	if input.ObjectLockEnabledForBucket != nil && *input.ObjectLockEnabledForBucket {
+		defaultLock, err := json.Marshal(auth.BucketLockConfig{
+			Enabled:   true,
+			CreatedAt: &now,
+		})
+		if err != nil {
+			return fmt.Errorf("parse default bucket lock state: %w", err)
+		}
+		b.lockConfig = defaultLock
+	}
+
+	m.buckets[strings.Clone(*input.Bucket)] = b
+	return nil
+}
+
+func (m *MemStore) DeleteBucket(_ context.Context, input *s3.DeleteBucketInput) error {
+	if input.Bucket == nil {
+		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(*input.Bucket)
+	if err != nil {
+		return err
+	}
+	if len(b.objects) != 0 {
+		return s3err.GetAPIError(s3err.ErrBucketNotEmpty)
+	}
+
+	delete(m.buckets, *input.Bucket)
+	return nil
+}
+
+func (m *MemStore) PutBucketAcl(_ context.Context, bucket string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	b.acl = bytes.Clone(data)
+	return nil
+}
+
+func (m *MemStore) GetBucketAcl(_ context.Context, input *s3.GetBucketAclInput) ([]byte, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(*input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.acl, nil
+}
+
+// PutBucketVersioning stores the bucket versioning state. Older object
+// versions are not retained, so enabling versioning is not implemented
+// and only the suspended state is recorded for reporting back to clients.
+func (m *MemStore) PutBucketVersioning(_ context.Context, input *s3.PutBucketVersioningInput) error {
+	if input.Bucket == nil {
+		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.VersioningConfiguration == nil {
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(*input.Bucket)
+	if err != nil {
+		return err
+	}
+
+	status := input.VersioningConfiguration.Status
+	switch status {
+	case types.BucketVersioningStatusSuspended:
+	case types.BucketVersioningStatusEnabled:
+		return s3err.GetAPIError(s3err.ErrNotImplemented)
+	default:
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	b.versioning = status
+	return nil
+}
+
+func (m *MemStore) GetBucketVersioning(_ context.Context, bucket string) (s3response.GetBucketVersioningOutput, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return s3response.GetBucketVersioningOutput{}, err
+	}
+
+	if b.versioning == "" {
+		// versioning has never been configured for this bucket
+		return s3response.GetBucketVersioningOutput{}, nil
+	}
+
+	status := b.versioning
+	return s3response.GetBucketVersioningOutput{
+		Status: &status,
+	}, nil
+}
+
+func (m *MemStore) PutBucketPolicy(_ context.Context, bucket string, policy []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	b.policy = bytes.Clone(policy)
+	return nil
+}
+
+func (m *MemStore) GetBucketPolicy(_ context.Context, bucket string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if b.policy == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucketPolicy)
+	}
+
+	return b.policy, nil
+}
+
+func (m *MemStore) DeleteBucketPolicy(ctx context.Context, bucket string) error {
+	return m.PutBucketPolicy(ctx, bucket, nil)
+}
+
+func (m *MemStore) PutBucketNotificationConfiguration(_ context.Context, bucket string, config []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	b.notification = bytes.Clone(config)
+	return nil
+}
+
+func (m *MemStore) GetBucketNotificationConfiguration(_ context.Context, bucket string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if b.notification == nil {
+		return []byte{}, nil
+	}
+
+	return b.notification, nil
+}
+
+func (m *MemStore) PutBucketLogging(_ context.Context, bucket string, config []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	b.logging = bytes.Clone(config)
+	return nil
+}
+
+func (m *MemStore) GetBucketLogging(_ context.Context, bucket string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if b.logging == nil {
+		return []byte{}, nil
+	}
+
+	return b.logging, nil
+}
+
+func (m *MemStore) PutPublicAccessBlock(_ context.Context, bucket string, config []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	b.publicAccessBlock = bytes.Clone(config)
+	return nil
+}
+
+func (m *MemStore) GetPublicAccessBlock(_ context.Context, bucket string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if b.publicAccessBlock == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchPublicAccessBlockConfiguration)
+	}
+
+	return b.publicAccessBlock, nil
+}
+
+func (m *MemStore) DeletePublicAccessBlock(ctx context.Context, bucket string) error {
+	return m.PutPublicAccessBlock(ctx, bucket, nil)
+}
+
+func (m *MemStore) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, error) {
+	acct := getAccount(ctx)
+
+	if po.Bucket == nil {
+		return "", s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if po.Key == nil {
+		return "", s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	tags, err := backend.ParseObjectTags(getString(po.Tagging))
+	if err != nil {
+		return "", err
+	}
+
+	retention, err := newRetention(po.ObjectLockMode, po.ObjectLockRetainUntilDate)
+	if err != nil {
+		return "", err
+	}
+
+	// read the body before taking the lock so slow clients don't
+	// block other requests
+	var data []byte
+	if po.Body != nil {
+		data, err = io.ReadAll(po.Body)
+		if err != nil {
+			return "", fmt.Errorf("read object data: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(*po.Bucket)
+	if err != nil {
+		return "", err
+	}
+
+	legalHold, err := b.newLegalHold(po.ObjectLockLegalHoldStatus, retention)
+	if err != nil {
+		return "", err
+	}
+	if retention == nil {
+		retention, err = auth.MarshalDefaultRetention(b.lockConfig, time.Now())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	acl, err := newObjectAcl(b, acct.Access, po.ACL,
+		auth.Grants{
+			FullControl: po.GrantFullControl,
+			Read:        po.GrantRead,
+			ReadACP:     po.GrantReadACP,
+			WriteACP:    po.GrantWriteACP,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	err = b.checkQuota(*po.Key, int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+
+	sum := md5.Sum(data)
+	etag := hex.EncodeToString(sum[:])
+
+	b.objects[strings.Clone(*po.Key)] = &object{
+		data:            data,
+		etag:            etag,
+		modTime:         time.Now(),
+		owner:           strings.Clone(acct.Access),
+		acl:             acl,
+		metadata:        copyMap(po.Metadata),
+		contentType:     strings.Clone(getString(po.ContentType)),
+		contentEncoding: strings.Clone(getString(po.ContentEncoding)),
+		tags:            copyMap(tags),
+		retention:       retention,
+		legalHold:       legalHold,
+		checksum: backend.ChecksumFromFields(po.ChecksumCRC32,
+			po.ChecksumCRC32C, po.ChecksumSHA1, po.ChecksumSHA256),
+	}
+
+	return etag, nil
+}
+
+func (m *MemStore) HeadObject(_ context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(*input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.PartNumber != nil {
+		// report the part of an in progress multipart upload
+		// of the object
+		if upload := b.firstUpload(*input.Key); upload != nil {
+			p, ok := upload.parts[*input.PartNumber]
+			if !ok {
+				return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
+			}
+			etag := p.etag
+			size := int64(len(p.data))
+			partsCount := int32(len(upload.parts))
+
+			return &s3.HeadObjectOutput{
+				LastModified:  backend.GetTimePtr(p.modTime),
+				ETag:          &etag,
+				PartsCount:    &partsCount,
+				ContentLength: &size,
+			}, nil
+		}
+	}
+
+	obj, ok := b.objects[*input.Key]
+	if !ok {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	err = backend.EvaluatePreconditions(obj.etag, obj.modTime, input.IfMatch,
+		input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int64(len(obj.data))
+
+	checksum := obj.checksum
+	var partsCount *int32
+	if input.PartNumber != nil {
+		checksum = nil
+		rng, err := backend.PartRange(size, obj.partSizes, *input.PartNumber)
+		if err != nil {
+			return nil, err
+		}
+		_, size, err = backend.ParseRange(size, rng)
+		if err != nil {
+			return nil, err
+		}
+		if len(obj.partSizes) != 0 {
+			count := int32(len(obj.partSizes))
+			partsCount = &count
+		}
+	}
+
+	var objectLockLegalHoldStatus types.ObjectLockLegalHoldStatus
+	if obj.legalHold != nil {
+		if *obj.legalHold {
+			objectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOn
+		} else {
+			objectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOff
+		}
+	}
+
+	var objectLockMode types.ObjectLockMode
+	var objectLockRetainUntilDate *time.Time
+	if obj.retention != nil {
+		var config types.ObjectLockRetention
+		if err := json.Unmarshal(obj.retention, &config); err == nil {
+			objectLockMode = types.ObjectLockMode(config.Mode)
+			objectLockRetainUntilDate = config.RetainUntilDate
+		}
+	}
+
+	etag := obj.etag
+	contentType := obj.contentType
+	contentEncoding := obj.contentEncoding
+	checksumCRC32, checksumCRC32C, checksumSHA1, checksumSHA256 := checksum.Fields()
+
+	return &s3.HeadObjectOutput{
+		ContentLength:             &size,
+		ContentType:               &contentType,
+		ContentEncoding:           &contentEncoding,
+		ETag:                      &etag,
+		LastModified:              backend.GetTimePtr(obj.modTime),
+		Metadata:                  copyMap(obj.metadata),
+		PartsCount:                partsCount,
+		ChecksumCRC32:             checksumCRC32,
+		ChecksumCRC32C:            checksumCRC32C,
+		ChecksumSHA1:              checksumSHA1,
+		ChecksumSHA256:            checksumSHA256,
+		ObjectLockLegalHoldStatus: objectLockLegalHoldStatus,
+		ObjectLockMode:            objectLockMode,
+		ObjectLockRetainUntilDate: objectLockRetainUntilDate,
+	}, nil
+}
+
+func (m *MemStore) GetObject(_ context.Context, input *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	m.mu.RLock()
+	_, o, err := m.getObject(*input.Bucket, *input.Key)
+	if err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+	// the object data is replaced rather than modified on overwrite,
+	// so a copy of the object can be used without holding the lock
+	obj := *o
+	m.mu.RUnlock()
+
+	err = backend.EvaluatePreconditions(obj.etag, obj.modTime, input.IfMatch,
+		input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
+	if err != nil {
+		return nil, err
+	}
+
+	acceptRange := getString(input.Range)
+	objSize := int64(len(obj.data))
+
+	var partsCount *int32
+	if input.PartNumber != nil {
+		acceptRange, err = backend.PartRange(objSize, obj.partSizes, *input.PartNumber)
+		if err != nil {
+			return nil, err
+		}
+		if len(obj.partSizes) != 0 {
+			count := int32(len(obj.partSizes))
+			partsCount = &count
+		}
+	}
+
+	startOffset, length, err := backend.ParseRange(objSize, acceptRange)
+	if err != nil {
+		return nil, err
+	}
+
+	contentRange := backend.ContentRange(acceptRange, startOffset, length, objSize)
+
+	err = backend.SetWriterMetadata(writer, obj.metadata, startOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = writer.Write(obj.data[startOffset : startOffset+length])
+	if err != nil {
+		return nil, fmt.Errorf("copy data: %w", err)
+	}
+
+	var tagCount *int32
+	if obj.tags != nil {
+		tgCount := int32(len(obj.tags))
+		tagCount = &tgCount
+	}
+
+	etag := obj.etag
+	contentType := obj.contentType
+	contentEncoding := obj.contentEncoding
+
+	return &s3.GetObjectOutput{
+		AcceptRanges:    &acceptRange,
+		ContentLength:   &length,
+		ContentEncoding: &contentEncoding,
+		ContentType:     &contentType,
+		ETag:            &etag,
+		LastModified:    backend.GetTimePtr(obj.modTime),
+		Metadata:        copyMap(obj.metadata),
+		TagCount:        tagCount,
+		ContentRange:    &contentRange,
+		PartsCount:      partsCount,
+	}, nil
+}
+
+func (m *MemStore) GetObjectAttributes(_ context.Context, input *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error) {
+	if input.Bucket == nil {
+		return s3response.GetObjectAttributesResult{}, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return s3response.GetObjectAttributesResult{}, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, obj, err := m.getObject(*input.Bucket, *input.Key)
+	if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchKey)) {
+		// report the parts of an in progress multipart upload
+		// of the object
+		upload := b.firstUpload(*input.Key)
+		if upload == nil {
+			return s3response.GetObjectAttributesResult{}, err
+		}
+
+		objParts, err := pageObjectParts(upload.objectParts(),
+			input.PartNumberMarker, input.MaxParts)
+		if err != nil {
+			return s3response.GetObjectAttributesResult{}, err
+		}
+		return s3response.GetObjectAttributesResult{
+			ObjectParts: objParts,
+		}, nil
+	}
+	if err != nil {
+		return s3response.GetObjectAttributesResult{}, err
+	}
+
+	size := int64(len(obj.data))
+	etag := obj.etag
+	storageClass := types.StorageClassStandard
+	result := s3response.GetObjectAttributesResult{
+		ETag:         &etag,
+		Checksum:     obj.checksum.ObjectChecksum(),
+		LastModified: backend.GetTimePtr(obj.modTime),
+		ObjectSize:   &size,
+		StorageClass: &storageClass,
+	}
+
+	if len(obj.partSizes) != 0 {
+		result.ObjectParts, err = pageObjectParts(obj.objectParts(),
+			input.PartNumberMarker, input.MaxParts)
+		if err != nil {
+			return s3response.GetObjectAttributesResult{}, err
+		}
+	}
+
+	return result, nil
+}
+
+// objectParts returns the parts of an object created by a multipart upload
+func (o *object) objectParts() []types.ObjectPart {
+	parts := make([]types.ObjectPart, 0, len(o.partSizes))
+	for i, size := range o.partSizes {
+		pn := int32(i + 1)
+		size := size
+		parts = append(parts, types.ObjectPart{
+			Size:       &size,
+			PartNumber: &pn,
+		})
+	}
+	return parts
+}
+
+// pageObjectParts returns the parts following the part number marker,
+// limited to maxParts
+func pageObjectParts(parts []types.ObjectPart, partNumberMarker *string, maxParts *int32) (*s3response.ObjectParts, error) {
+	var marker int
+	if partNumberMarker != nil && *partNumberMarker != "" {
+		var err error
+		marker, err = strconv.Atoi(*partNumberMarker)
+		if err != nil {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidPartNumberMarker)
+		}
+	}
+	max := 0
+	if maxParts != nil {
+		max = int(*maxParts)
+	}
+
+	result := []types.ObjectPart{}
+	var truncated bool
+	for _, p := range parts {
+		if int(*p.PartNumber) <= marker {
+			continue
+		}
+		if max > 0 && len(result) == max {
+			truncated = true
+			break
+		}
+		result = append(result, p)
+	}
+
+	nextMarker := 0
+	if len(result) != 0 {
+		nextMarker = int(*result[len(result)-1].PartNumber)
+	}
+
+	return &s3response.ObjectParts{
+		IsTruncated:          truncated,
+		MaxParts:             max,
+		PartNumberMarker:     marker,
+		NextPartNumberMarker: nextMarker,
+		Parts:                result,
+	}, nil
+}
+
+func (m *MemStore) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidCopyDest)
+	}
+	if input.CopySource == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidCopySource)
+	}
+	if input.ExpectedBucketOwner == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
+	srcBucket, srcObject, ok := strings.Cut(*input.CopySource, "/")
+	if !ok {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidCopySource)
+	}
+	dstBucket := *input.Bucket
+	dstObject := *input.Key
+
+	m.mu.RLock()
+	_, o, err := m.getObject(srcBucket, srcObject)
+	if err == nil {
+		_, err = m.getBucket(dstBucket)
+	}
+	if err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+	src := *o
+	m.mu.RUnlock()
+
+	err = backend.EvaluateCopySourcePreconditions(src.etag, src.modTime,
+		input.CopySourceIfMatch, input.CopySourceIfNoneMatch,
+		input.CopySourceIfModifiedSince, input.CopySourceIfUnmodifiedSince)
+	if err != nil {
+		return nil, err
+	}
+
+	replaceMeta := input.MetadataDirective == types.MetadataDirectiveReplace
+	if srcBucket == dstBucket && srcObject == dstObject && !replaceMeta {
+		// copying an object onto itself is only allowed to
+		// replace its metadata
+		if compareUserMetadata(src.metadata, input.Metadata) {
+			return &s3.CopyObjectOutput{}, s3err.GetAPIError(s3err.ErrInvalidCopyDest)
+		}
+		replaceMeta = true
+	}
+
+	meta := src.metadata
+	contentType := &src.contentType
+	contentEncoding := &src.contentEncoding
+	if replaceMeta {
+		meta = input.Metadata
+		contentType = input.ContentType
+		contentEncoding = input.ContentEncoding
+	}
+
+	var tagging *string
+	if input.TaggingDirective == types.TaggingDirectiveReplace {
+		tagging = input.Tagging
+	}
+
+	etag, err := m.PutObject(ctx,
+		&s3.PutObjectInput{
+			Bucket:           &dstBucket,
+			Key:              &dstObject,
+			Body:             bytes.NewReader(src.data),
+			Metadata:         meta,
+			ContentType:      contentType,
+			ContentEncoding:  contentEncoding,
+			Tagging:          tagging,
+			ACL:              input.ACL,
+			GrantFullControl: input.GrantFullControl,
+			GrantRead:        input.GrantRead,
+			GrantReadACP:     input.GrantReadACP,
+			GrantWriteACP:    input.GrantWriteACP,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	if input.TaggingDirective != types.TaggingDirectiveReplace && len(src.tags) > 0 {
+		err = m.PutObjectTagging(ctx, dstBucket, dstObject, src.tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, dst, err := m.getObject(dstBucket, dstObject)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{
+			ETag:         &etag,
+			LastModified: backend.GetTimePtr(dst.modTime),
+		},
+	}, nil
+}
+
+func (m *MemStore) ListObjects(_ context.Context, input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	bucket := *input.Bucket
+	prefix := getString(input.Prefix)
+	marker := getString(input.Marker)
+	delim := getString(input.Delimiter)
+	maxkeys := int32(0)
+	if input.MaxKeys != nil {
+		maxkeys = *input.MaxKeys
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	results := b.list(prefix, delim, marker, maxkeys, true)
+
+	return &s3.ListObjectsOutput{
+		CommonPrefixes: results.CommonPrefixes,
+		Contents:       results.Objects,
+		Delimiter:      &delim,
+		IsTruncated:    &results.Truncated,
+		Marker:         &marker,
+		MaxKeys:        &maxkeys,
+		Name:           &bucket,
+		NextMarker:     &results.NextMarker,
+		Prefix:         &prefix,
+	}, nil
+}
+
+func (m *MemStore) ListObjectsV2(_ context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	bucket := *input.Bucket
+	prefix := getString(input.Prefix)
+	marker := getString(input.ContinuationToken)
+	if input.StartAfter != nil && *input.StartAfter > marker {
+		marker = *input.StartAfter
+	}
+	delim := getString(input.Delimiter)
+	maxkeys := int32(0)
+	if input.MaxKeys != nil {
+		maxkeys = *input.MaxKeys
+	}
+	fetchOwner := input.FetchOwner != nil && *input.FetchOwner
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	results := b.list(prefix, delim, marker, maxkeys, fetchOwner)
+	count := int32(len(results.Objects))
+
+	return &s3.ListObjectsV2Output{
+		CommonPrefixes:        results.CommonPrefixes,
+		Contents:              results.Objects,
+		Delimiter:             &delim,
+		IsTruncated:           &results.Truncated,
+		ContinuationToken:     input.ContinuationToken,
+		MaxKeys:               &maxkeys,
+		Name:                  &bucket,
+		NextContinuationToken: &results.NextMarker,
+		Prefix:                &prefix,
+		StartAfter:            input.StartAfter,
+		KeyCount:              &count,
+	}, nil
+}
+
+// ListObjectVersions lists the versions of the objects within a bucket.
+// Only the current version of each object is kept, so every object is
+// reported as a single "null" version and no delete markers are returned.
+func (m *MemStore) ListObjectVersions(_ context.Context, input *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
+	if input.Bucket == nil {
+		return s3response.ListVersionsResult{}, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	bucket := *input.Bucket
+	prefix := getString(input.Prefix)
+	keyMarker := getString(input.KeyMarker)
+	versionIdMarker := getString(input.VersionIdMarker)
+	delim := getString(input.Delimiter)
+	maxkeys := int32(0)
+	if input.MaxKeys != nil {
+		maxkeys = *input.MaxKeys
+	}
+
+	if versionIdMarker != "" && keyMarker == "" {
+		return s3response.ListVersionsResult{}, s3err.GetAPIError(s3err.ErrInvalidVersionIdMarker)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return s3response.ListVersionsResult{}, err
+	}
+
+	results := b.list(prefix, delim, keyMarker, maxkeys, true)
+
+	versions := make([]types.ObjectVersion, 0, len(results.Objects))
+	for _, obj := range results.Objects {
+		versions = append(versions, types.ObjectVersion{
+			ETag:         obj.ETag,
+			IsLatest:     backend.GetBoolPtr(true),
+			Key:          obj.Key,
+			LastModified: obj.LastModified,
+			Owner:        obj.Owner,
+			Size:         obj.Size,
+			StorageClass: types.ObjectVersionStorageClassStandard,
+			VersionId:    backend.GetStringPtr(nullVersionId),
+		})
+	}
+
+	result := s3response.ListVersionsResult{
+		Name:            bucket,
+		Prefix:          prefix,
+		KeyMarker:       keyMarker,
+		VersionIdMarker: versionIdMarker,
+		Delimiter:       delim,
+		MaxKeys:         maxkeys,
+		IsTruncated:     results.Truncated,
+		Versions:        versions,
+		CommonPrefixes:  results.CommonPrefixes,
+	}
+	if results.Truncated {
+		result.NextKeyMarker = results.NextMarker
+		result.NextVersionIdMarker = nullVersionId
+	}
+
+	return result, nil
+}
+
+// list returns the bucket objects and common prefixes in key order
+// following the marker, the caller must hold the lock
+func (b *bucket) list(prefix, delim, marker string, max int32, fetchOwner bool) backend.WalkResults {
+	var results backend.WalkResults
+	if max == 0 {
+		return results
+	}
+
+	keys := make([]string, 0, len(b.objects))
+	for key := range b.objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var last string
+	var count int32
+	for _, key := range keys {
+		if delim != "" {
+			if i := strings.Index(key[len(prefix):], delim); i != -1 {
+				cpref := key[:len(prefix)+i+len(delim)]
+				if cpref == last || cpref <= marker {
+					// already returned with the previous listing
+					// or an earlier key
+					continue
+				}
+				if count == max {
+					results.Truncated = true
+					break
+				}
+				results.CommonPrefixes = append(results.CommonPrefixes,
+					types.CommonPrefix{Prefix: backend.GetStringPtr(cpref)})
+				last = cpref
+				count++
+				continue
+			}
+		}
+
+		if count == max {
+			results.Truncated = true
+			break
+		}
+
+		obj := b.objects[key]
+		key := key
+		etag := obj.etag
+		size := int64(len(obj.data))
+		var owner *types.Owner
+		if fetchOwner {
+			owner = b.ownerOf(obj)
+		}
+		results.Objects = append(results.Objects, types.Object{
+			ETag:         &etag,
+			Key:          &key,
+			LastModified: backend.GetTimePtr(obj.modTime),
+			Size:         &size,
+			Owner:        owner,
+			StorageClass: types.ObjectStorageClassStandard,
+		})
+		last = key
+		count++
+	}
+
+	if results.Truncated {
+		results.NextMarker = last
+	}
+
+	return results
+}
+
+func (m *MemStore) DeleteObject(_ context.Context, input *s3.DeleteObjectInput) error {
+	if input.Bucket == nil {
+		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, _, err := m.getObject(*input.Bucket, *input.Key)
+	if err != nil {
+		return err
+	}
+
+	delete(b.objects, *input.Key)
+	return nil
+}
+
+func (m *MemStore) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
+	// delete object already checks bucket
+	delResult, errs := []types.DeletedObject{}, []types.Error{}
+	for _, obj := range input.Delete.Objects {
+		err := m.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: input.Bucket,
+			Key:    obj.Key,
+		})
+		if err == nil {
+			delResult = append(delResult, types.DeletedObject{Key: obj.Key})
+		} else {
+			serr, ok := err.(s3err.APIError)
+			if ok {
+				errs = append(errs, types.Error{
+					Key:     obj.Key,
+					Code:    &serr.Code,
+					Message: &serr.Description,
+				})
+			} else {
+				errs = append(errs, types.Error{
+					Key:     obj.Key,
+					Code:    backend.GetStringPtr("InternalError"),
+					Message: backend.GetStringPtr(err.Error()),
+				})
+			}
+		}
+	}
+
+	return s3response.DeleteResult{
+		Deleted: delResult,
+		Error:   errs,
+	}, nil
+}
+
+func (m *MemStore) PutObjectAcl(_ context.Context, bucket, object string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, obj, err := m.getObject(bucket, object)
+	if err != nil {
+		return err
+	}
+
+	obj.acl = bytes.Clone(data)
+	return nil
+}
+
+func (m *MemStore) GetObjectAcl(_ context.Context, input *s3.GetObjectAclInput) ([]byte, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, obj, err := m.getObject(*input.Bucket, *input.Key)
+	if err != nil {
+		return nil, err
+	}
+	if obj.acl != nil {
+		return obj.acl, nil
+	}
+
+	// objects without an acl of their own are private to their owner
+	data, err := json.Marshal(auth.ACL{Owner: *b.ownerOf(obj).ID})
+	if err != nil {
+		return nil, fmt.Errorf("marshal acl: %w", err)
+	}
+
+	return data, nil
+}
+
+func (m *MemStore) PutBucketTagging(_ context.Context, bucket string, tags map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	b.tags = copyMap(tags)
+	return nil
+}
+
+func (m *MemStore) GetBucketTagging(_ context.Context, bucket string) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if b.tags == nil {
+		return nil, s3err.GetAPIError(s3err.ErrBucketTaggingNotFound)
+	}
+
+	return copyMap(b.tags), nil
+}
+
+func (m *MemStore) DeleteBucketTagging(ctx context.Context, bucket string) error {
+	return m.PutBucketTagging(ctx, bucket, nil)
+}
+
+func (m *MemStore) GetObjectTagging(_ context.Context, bucket, object string) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, obj, err := m.getObject(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	if obj.tags == nil {
+		return nil, s3err.GetAPIError(s3err.ErrBucketTaggingNotFound)
+	}
+
+	return copyMap(obj.tags), nil
+}
+
+func (m *MemStore) PutObjectTagging(_ context.Context, bucket, object string, tags map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, obj, err := m.getObject(bucket, object)
+	if err != nil {
+		return err
+	}
+
+	obj.tags = copyMap(tags)
+	return nil
+}
+
+func (m *MemStore) DeleteObjectTagging(ctx context.Context, bucket, object string) error {
+	return m.PutObjectTagging(ctx, bucket, object, nil)
+}
+
+func (m *MemStore) PutObjectLockConfiguration(_ context.Context, bucket string, config []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	enabled, err := b.lockEnabled()
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotAllowed)
+	}
+
+	b.lockConfig = bytes.Clone(config)
+	return nil
+}
+
+func (m *MemStore) GetObjectLockConfiguration(_ context.Context, bucket string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if b.lockConfig == nil {
+		return nil, s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotFound)
+	}
+
+	return b.lockConfig, nil
+}
+
+// getLockedObject returns the object for updating its retention or legal
+// hold, which requires object lock to be enabled for the bucket. The
+// caller must hold the lock.
+func (m *MemStore) getLockedObject(bucket, object string) (*object, error) {
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := b.lockEnabled()
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketObjectLockConfiguration)
+	}
+
+	obj, ok := b.objects[object]
+	if !ok {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	return obj, nil
+}
+
+func (m *MemStore) PutObjectLegalHold(_ context.Context, bucket, object, versionId string, status bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	obj, err := m.getLockedObject(bucket, object)
+	if err != nil {
+		return err
+	}
+
+	obj.legalHold = &status
+	return nil
+}
+
+func (m *MemStore) GetObjectLegalHold(_ context.Context, bucket, object, versionId string) (*bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, obj, err := m.getObject(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	if obj.legalHold == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)
+	}
+
+	status := *obj.legalHold
+	return &status, nil
+}
+
+func (m *MemStore) PutObjectRetention(_ context.Context, bucket, object, versionId string, retention []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	obj, err := m.getLockedObject(bucket, object)
+	if err != nil {
+		return err
+	}
+
+	obj.retention = bytes.Clone(retention)
+	return nil
+}
+
+func (m *MemStore) GetObjectRetention(_ context.Context, bucket, object, versionId string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, obj, err := m.getObject(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	if obj.retention == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)
+	}
+
+	return obj.retention, nil
+}
+
+func (m *MemStore) ChangeBucketOwner(_ context.Context, bucket, newOwner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	var acl auth.ACL
+	err = json.Unmarshal(b.acl, &acl)
+	if err != nil {
+		return fmt.Errorf("unmarshal acl: %w", err)
+	}
+
+	acl.Owner = newOwner
+
+	newAcl, err := json.Marshal(acl)
+	if err != nil {
+		return fmt.Errorf("marshal acl: %w", err)
+	}
+
+	b.acl = newAcl
+	return nil
+}
+
+func (m *MemStore) ListBucketsAndOwners(context.Context) ([]s3response.Bucket, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	buckets := make([]s3response.Bucket, 0, len(m.buckets))
+	for name, b := range m.buckets {
+		buckets = append(buckets, s3response.Bucket{
+			Name:  name,
+			Owner: b.owner(),
+		})
+	}
+
+	sort.SliceStable(buckets, func(i, j int) bool {
+		return buckets[i].Name < buckets[j].Name
+	})
+
+	return buckets, nil
+}
+
+// GetBucketUsage returns the object count, size and last modification
+// time of the bucket objects, along with the size of the parts of the
+// incomplete multipart uploads
+func (m *MemStore) GetBucketUsage(_ context.Context, bucket string) (s3response.BucketUsage, error) {
+	usage := s3response.BucketUsage{Bucket: bucket}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return usage, err
+	}
+
+	usage.Size, usage.Objects = b.usage()
+	for _, obj := range b.objects {
+		if obj.modTime.After(usage.LastModified) {
+			usage.LastModified = obj.modTime
+		}
+	}
+	for _, upload := range b.uploads {
+		for _, part := range upload.parts {
+			usage.MultipartSize += int64(len(part.data))
+		}
+	}
+
+	return usage, nil
+}
+
+// PutBucketQuota sets the bucket quota, a zero quota removes it
+func (m *MemStore) PutBucketQuota(_ context.Context, bucket string, quota s3response.BucketQuota) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return err
+	}
+
+	b.quota = s3response.BucketQuota{Size: quota.Size, Objects: quota.Objects}
+	return nil
+}
+
+// GetBucketQuota returns the bucket quota along with the accounted usage
+func (m *MemStore) GetBucketQuota(_ context.Context, bucket string) (s3response.BucketQuota, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, err := m.getBucket(bucket)
+	if err != nil {
+		return s3response.BucketQuota{}, err
+	}
+	if b.quota.Size == 0 && b.quota.Objects == 0 {
+		return s3response.BucketQuota{}, nil
+	}
+
+	quota := b.quota
+	quota.UsedSize, quota.UsedObjects = b.usage()
+
+	return quota, nil
+}
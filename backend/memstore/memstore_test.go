@@ -0,0 +1,705 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+func newTestBucket(t *testing.T, m *MemStore, bucket string, lock bool) {
+	t.Helper()
+	err := m.CreateBucket(context.Background(), &s3.CreateBucketInput{
+		Bucket:                     &bucket,
+		ObjectLockEnabledForBucket: &lock,
+	}, []byte(`{"Owner":"owner"}`))
+	if err != nil {
+		t.Fatalf("create bucket: %v", err)
+	}
+}
+
+func putTestObject(t *testing.T, m *MemStore, bucket, key, data string) string {
+	t.Helper()
+	etag, err := m.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Body:   strings.NewReader(data),
+	})
+	if err != nil {
+		t.Fatalf("put object %v: %v", key, err)
+	}
+	return etag
+}
+
+func TestMemStore_Objects(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	bucket := "bucket"
+	newTestBucket(t, m, bucket, false)
+
+	err := m.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: &bucket}, nil)
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrBucketAlreadyExists)) {
+		t.Errorf("expected bucket already exists, got %v", err)
+	}
+
+	etag := putTestObject(t, m, bucket, "obj", "hello world")
+	if etag != "5eb63bbbe01eeed093cb22bb8f5acdc3" {
+		t.Errorf("unexpected etag %v", etag)
+	}
+
+	var buf bytes.Buffer
+	rng := "bytes=6-10"
+	out, err := m.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    backend.GetStringPtr("obj"),
+		Range:  &rng,
+	}, &buf)
+	if err != nil {
+		t.Fatalf("get object: %v", err)
+	}
+	if buf.String() != "world" || *out.ContentLength != 5 {
+		t.Errorf("unexpected range data %q", buf.String())
+	}
+
+	err = m.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: &bucket})
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrBucketNotEmpty)) {
+		t.Errorf("expected bucket not empty, got %v", err)
+	}
+
+	err = m.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: &bucket, Key: backend.GetStringPtr("obj")})
+	if err != nil {
+		t.Fatalf("delete object: %v", err)
+	}
+	_, err = m.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &bucket, Key: backend.GetStringPtr("obj")})
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchKey)) {
+		t.Errorf("expected no such key, got %v", err)
+	}
+}
+
+func TestMemStore_ListObjects(t *testing.T) {
+	m := New()
+	bucket := "bucket"
+	newTestBucket(t, m, bucket, false)
+
+	for _, key := range []string{"a", "b/1", "b/2", "c/1", "d"} {
+		putTestObject(t, m, bucket, key, key)
+	}
+
+	tests := []struct {
+		name      string
+		delim     string
+		marker    string
+		max       int32
+		keys      []string
+		prefixes  []string
+		truncated bool
+	}{
+		{name: "all", max: 1000, keys: []string{"a", "b/1", "b/2", "c/1", "d"}},
+		{name: "delimiter", delim: "/", max: 1000, keys: []string{"a", "d"}, prefixes: []string{"b/", "c/"}},
+		{name: "truncated", delim: "/", max: 2, keys: []string{"a"}, prefixes: []string{"b/"}, truncated: true},
+		{name: "marker", delim: "/", marker: "b/", max: 1000, keys: []string{"d"}, prefixes: []string{"c/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := m.ListObjects(context.Background(), &s3.ListObjectsInput{
+				Bucket:    &bucket,
+				Delimiter: &tt.delim,
+				Marker:    &tt.marker,
+				MaxKeys:   &tt.max,
+			})
+			if err != nil {
+				t.Fatalf("list objects: %v", err)
+			}
+
+			var keys, prefixes []string
+			for _, obj := range out.Contents {
+				keys = append(keys, *obj.Key)
+			}
+			for _, cp := range out.CommonPrefixes {
+				prefixes = append(prefixes, *cp.Prefix)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.keys, ",") {
+				t.Errorf("expected keys %v, got %v", tt.keys, keys)
+			}
+			if strings.Join(prefixes, ",") != strings.Join(tt.prefixes, ",") {
+				t.Errorf("expected prefixes %v, got %v", tt.prefixes, prefixes)
+			}
+			if *out.IsTruncated != tt.truncated {
+				t.Errorf("expected truncated %v, got %v", tt.truncated, *out.IsTruncated)
+			}
+		})
+	}
+}
+
+func TestMemStore_MultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	bucket, key := "bucket", "mp"
+	newTestBucket(t, m, bucket, false)
+
+	mpu, err := m.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		t.Fatalf("create multipart upload: %v", err)
+	}
+
+	partData := []string{
+		strings.Repeat("a", backend.MinPartSize),
+		strings.Repeat("b", backend.MinPartSize),
+		"cc",
+	}
+	var parts []types.CompletedPart
+	for i, data := range partData {
+		pn := int32(i + 1)
+		etag, err := m.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     &bucket,
+			Key:        &key,
+			UploadId:   mpu.UploadId,
+			PartNumber: &pn,
+			Body:       strings.NewReader(data),
+		})
+		if err != nil {
+			t.Fatalf("upload part %v: %v", pn, err)
+		}
+		parts = append(parts, types.CompletedPart{ETag: &etag, PartNumber: &pn})
+	}
+
+	_, err = m.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &bucket,
+		Key:             &key,
+		UploadId:        mpu.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		t.Fatalf("complete multipart upload: %v", err)
+	}
+
+	var buf bytes.Buffer
+	_, err = m.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key}, &buf)
+	if err != nil {
+		t.Fatalf("get object: %v", err)
+	}
+	if buf.String() != strings.Join(partData, "") {
+		t.Errorf("unexpected object data of length %v", buf.Len())
+	}
+
+	pn := int32(2)
+	buf.Reset()
+	out, err := m.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key, PartNumber: &pn}, &buf)
+	if err != nil {
+		t.Fatalf("get object part: %v", err)
+	}
+	if buf.String() != partData[1] {
+		t.Errorf("unexpected part data of length %v", buf.Len())
+	}
+	if out.PartsCount == nil || *out.PartsCount != 3 {
+		t.Errorf("unexpected parts count %v", out.PartsCount)
+	}
+	if *out.ContentRange != "bytes 5242880-10485759/10485762" {
+		t.Errorf("unexpected content range %q", *out.ContentRange)
+	}
+
+	pn = 4
+	_, err = m.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key, PartNumber: &pn}, io.Discard)
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrInvalidPartNumberRange)) {
+		t.Errorf("expected invalid part number, got %v", err)
+	}
+
+	_, err = m.ListParts(ctx, &s3.ListPartsInput{Bucket: &bucket, Key: &key, UploadId: mpu.UploadId})
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchUpload)) {
+		t.Errorf("expected no such upload, got %v", err)
+	}
+}
+
+func TestMemStore_MultipartPartConstraints(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	bucket, key := "bucket", "mp"
+	newTestBucket(t, m, bucket, false)
+
+	mpu, err := m.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		t.Fatalf("create multipart upload: %v", err)
+	}
+
+	// a small first part followed by a larger last part
+	var parts []types.CompletedPart
+	for i, data := range []string{"aaaa", strings.Repeat("b", backend.MinPartSize)} {
+		pn := int32(i + 1)
+		etag, err := m.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     &bucket,
+			Key:        &key,
+			UploadId:   mpu.UploadId,
+			PartNumber: &pn,
+			Body:       strings.NewReader(data),
+		})
+		if err != nil {
+			t.Fatalf("upload part %v: %v", pn, err)
+		}
+		parts = append(parts, types.CompletedPart{ETag: &etag, PartNumber: &pn})
+	}
+
+	tests := []struct {
+		name  string
+		parts []types.CompletedPart
+		want  error
+	}{
+		{"out-of-order", []types.CompletedPart{parts[1], parts[0]}, s3err.GetAPIError(s3err.ErrInvalidPartOrder)},
+		{"small-non-last-part", parts, s3err.GetAPIError(s3err.ErrEntityTooSmall)},
+		{"small-last-part", parts[:1], nil},
+	}
+	for _, tt := range tests {
+		_, err = m.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          &bucket,
+			Key:             &key,
+			UploadId:        mpu.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: tt.parts},
+		})
+		if tt.want == nil && err != nil {
+			t.Errorf("%v: unexpected error %v", tt.name, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%v: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestMemStore_MultipartObjectSettings(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	bucket, key := "bucket", "mp"
+	newTestBucket(t, m, bucket, true)
+
+	mpu, err := m.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:                    &bucket,
+		Key:                       &key,
+		ContentType:               backend.GetStringPtr("text/plain"),
+		Tagging:                   backend.GetStringPtr("k1=v1&k2=v2"),
+		ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOn,
+	})
+	if err != nil {
+		t.Fatalf("create multipart upload: %v", err)
+	}
+
+	pn := int32(1)
+	etag, err := m.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     &bucket,
+		Key:        &key,
+		UploadId:   mpu.UploadId,
+		PartNumber: &pn,
+		Body:       strings.NewReader("data"),
+	})
+	if err != nil {
+		t.Fatalf("upload part: %v", err)
+	}
+
+	_, err = m.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   &bucket,
+		Key:      &key,
+		UploadId: mpu.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: []types.CompletedPart{{ETag: &etag, PartNumber: &pn}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("complete multipart upload: %v", err)
+	}
+
+	head, err := m.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &bucket, Key: &key})
+	if err != nil {
+		t.Fatalf("head object: %v", err)
+	}
+	if getString(head.ContentType) != "text/plain" {
+		t.Errorf("unexpected content type %q", getString(head.ContentType))
+	}
+	if head.ObjectLockLegalHoldStatus != types.ObjectLockLegalHoldStatusOn {
+		t.Errorf("expected legal hold, got %q", head.ObjectLockLegalHoldStatus)
+	}
+
+	tags, err := m.GetObjectTagging(ctx, bucket, key)
+	if err != nil {
+		t.Fatalf("get object tagging: %v", err)
+	}
+	if len(tags) != 2 || tags["k1"] != "v1" || tags["k2"] != "v2" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	other := "other"
+	newTestBucket(t, m, other, false)
+	_, err = m.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:                    &other,
+		Key:                       &key,
+		ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOn,
+	})
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrInvalidBucketObjectLockConfiguration)) {
+		t.Errorf("expected invalid bucket object lock configuration, got %v", err)
+	}
+}
+
+func TestMemStore_MultipartChecksum(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	bucket, key := "bucket", "mp"
+	newTestBucket(t, m, bucket, false)
+
+	mpu, err := m.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:            &bucket,
+		Key:               &key,
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+	})
+	if err != nil {
+		t.Fatalf("create multipart upload: %v", err)
+	}
+
+	pn := int32(1)
+	_, err = m.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        &bucket,
+		Key:           &key,
+		UploadId:      mpu.UploadId,
+		PartNumber:    &pn,
+		Body:          strings.NewReader("data"),
+		ChecksumCRC32: backend.GetStringPtr("AAAAAA=="),
+	})
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrChecksumAlgorithmMismatch)) {
+		t.Errorf("expected checksum algorithm mismatch, got %v", err)
+	}
+
+	var parts []types.CompletedPart
+	var composite []byte
+	for i, data := range []string{strings.Repeat("a", backend.MinPartSize), "b"} {
+		pn := int32(i + 1)
+		etag, err := m.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     &bucket,
+			Key:        &key,
+			UploadId:   mpu.UploadId,
+			PartNumber: &pn,
+			Body:       strings.NewReader(data),
+		})
+		if err != nil {
+			t.Fatalf("upload part %v: %v", pn, err)
+		}
+		sum := sha256.Sum256([]byte(data))
+		composite = append(composite, sum[:]...)
+		parts = append(parts, types.CompletedPart{
+			ETag:           &etag,
+			PartNumber:     &pn,
+			ChecksumSHA256: backend.GetStringPtr(base64.StdEncoding.EncodeToString(sum[:])),
+		})
+	}
+	sum := sha256.Sum256(composite)
+	want := base64.StdEncoding.EncodeToString(sum[:]) + "-2"
+
+	out, err := m.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &bucket,
+		Key:             &key,
+		UploadId:        mpu.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		t.Fatalf("complete multipart upload: %v", err)
+	}
+	if out.ChecksumSHA256 == nil || *out.ChecksumSHA256 != want {
+		t.Errorf("expected complete checksum %v, got %v", want, out.ChecksumSHA256)
+	}
+
+	attrs, err := m.GetObjectAttributes(ctx, &s3.GetObjectAttributesInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		t.Fatalf("get object attributes: %v", err)
+	}
+	if attrs.Checksum == nil || attrs.Checksum.ChecksumSHA256 == nil ||
+		*attrs.Checksum.ChecksumSHA256 != want {
+		t.Errorf("expected attributes checksum %v, got %v", want, attrs.Checksum)
+	}
+}
+
+func TestMemStore_MultipartUploadInitiator(t *testing.T) {
+	m := New()
+	bucket, key := "bucket", "mp"
+	newTestBucket(t, m, bucket, false)
+
+	accountCtx := func(access string, role auth.Role) context.Context {
+		return context.WithValue(context.Background(), "account",
+			auth.Account{Access: access, Role: role})
+	}
+
+	mpu, err := m.CreateMultipartUpload(accountCtx("user1", auth.RoleUser),
+		&s3.CreateMultipartUploadInput{Bucket: &bucket, Key: &key})
+	if err != nil {
+		t.Fatalf("create multipart upload: %v", err)
+	}
+
+	lpr, err := m.ListParts(context.Background(), &s3.ListPartsInput{
+		Bucket: &bucket, Key: &key, UploadId: mpu.UploadId})
+	if err != nil {
+		t.Fatalf("list parts: %v", err)
+	}
+	if lpr.Initiator.ID != "user1" || lpr.Owner.ID != "user1" {
+		t.Errorf("unexpected initiator %v and owner %v", lpr.Initiator, lpr.Owner)
+	}
+
+	tests := []struct {
+		access  string
+		role    auth.Role
+		wantErr bool
+	}{
+		{"user2", auth.RoleUser, true},
+		{"user1", auth.RoleUser, false},
+		{"owner", auth.RoleUser, false},
+		{"admin", auth.RoleAdmin, false},
+	}
+	for i, tt := range tests {
+		pn := int32(i + 1)
+		_, err := m.UploadPart(accountCtx(tt.access, tt.role), &s3.UploadPartInput{
+			Bucket:     &bucket,
+			Key:        &key,
+			UploadId:   mpu.UploadId,
+			PartNumber: &pn,
+			Body:       strings.NewReader("data"),
+		})
+		if tt.wantErr != errors.Is(err, s3err.GetAPIError(s3err.ErrAccessDenied)) {
+			t.Errorf("upload part as %v: unexpected error %v", tt.access, err)
+		}
+	}
+
+	err = m.AbortMultipartUpload(accountCtx("user2", auth.RoleUser),
+		&s3.AbortMultipartUploadInput{Bucket: &bucket, Key: &key, UploadId: mpu.UploadId})
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrAccessDenied)) {
+		t.Errorf("expected access denied aborting as another user, got %v", err)
+	}
+	err = m.AbortMultipartUpload(accountCtx("user1", auth.RoleUser),
+		&s3.AbortMultipartUploadInput{Bucket: &bucket, Key: &key, UploadId: mpu.UploadId})
+	if err != nil {
+		t.Errorf("abort multipart upload: %v", err)
+	}
+}
+
+func TestMemStore_CopyObjectDirectives(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	bucket := "bucket"
+	newTestBucket(t, m, bucket, false)
+
+	_, err := m.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      &bucket,
+		Key:         backend.GetStringPtr("src"),
+		Body:        strings.NewReader("data"),
+		Metadata:    map[string]string{"color": "red"},
+		ContentType: backend.GetStringPtr("text/plain"),
+		Tagging:     backend.GetStringPtr("env=prod"),
+	})
+	if err != nil {
+		t.Fatalf("put object: %v", err)
+	}
+
+	copyObject := func(dst string, metaDirective types.MetadataDirective, tagDirective types.TaggingDirective) {
+		t.Helper()
+		_, err := m.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:              &bucket,
+			Key:                 &dst,
+			CopySource:          backend.GetStringPtr(bucket + "/src"),
+			ExpectedBucketOwner: backend.GetStringPtr("owner"),
+			MetadataDirective:   metaDirective,
+			Metadata:            map[string]string{"color": "blue"},
+			ContentType:         backend.GetStringPtr("text/html"),
+			TaggingDirective:    tagDirective,
+			Tagging:             backend.GetStringPtr("env=dev"),
+		})
+		if err != nil {
+			t.Fatalf("copy object %v: %v", dst, err)
+		}
+	}
+
+	tests := []struct {
+		dst           string
+		metaDirective types.MetadataDirective
+		tagDirective  types.TaggingDirective
+		color, ctype  string
+		env           string
+	}{
+		{"default", "", "", "red", "text/plain", "prod"},
+		{"copy", types.MetadataDirectiveCopy, types.TaggingDirectiveCopy, "red", "text/plain", "prod"},
+		{"replace", types.MetadataDirectiveReplace, types.TaggingDirectiveReplace, "blue", "text/html", "dev"},
+		{"mixed", types.MetadataDirectiveReplace, types.TaggingDirectiveCopy, "blue", "text/html", "prod"},
+	}
+	for _, tt := range tests {
+		copyObject(tt.dst, tt.metaDirective, tt.tagDirective)
+
+		out, err := m.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &bucket, Key: &tt.dst})
+		if err != nil {
+			t.Fatalf("head object %v: %v", tt.dst, err)
+		}
+		if out.Metadata["color"] != tt.color || *out.ContentType != tt.ctype {
+			t.Errorf("%v: unexpected metadata %v content type %v",
+				tt.dst, out.Metadata, *out.ContentType)
+		}
+
+		tags, err := m.GetObjectTagging(ctx, bucket, tt.dst)
+		if err != nil {
+			t.Fatalf("get tags %v: %v", tt.dst, err)
+		}
+		if tags["env"] != tt.env {
+			t.Errorf("%v: unexpected tags %v", tt.dst, tags)
+		}
+	}
+}
+
+func TestMemStore_ObjectLock(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	newTestBucket(t, m, "plain", false)
+	newTestBucket(t, m, "locked", true)
+	putTestObject(t, m, "plain", "obj", "data")
+	putTestObject(t, m, "locked", "obj", "data")
+
+	err := m.PutObjectLegalHold(ctx, "plain", "obj", "", true)
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrInvalidBucketObjectLockConfiguration)) {
+		t.Errorf("expected invalid bucket object lock configuration, got %v", err)
+	}
+
+	_, err = m.GetObjectLegalHold(ctx, "locked", "obj", "")
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)) {
+		t.Errorf("expected no such object lock configuration, got %v", err)
+	}
+
+	err = m.PutObjectLegalHold(ctx, "locked", "obj", "", true)
+	if err != nil {
+		t.Fatalf("put legal hold: %v", err)
+	}
+	status, err := m.GetObjectLegalHold(ctx, "locked", "obj", "")
+	if err != nil {
+		t.Fatalf("get legal hold: %v", err)
+	}
+	if !*status {
+		t.Errorf("expected legal hold on")
+	}
+}
+
+func TestMemStore_DefaultRetention(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	bucket := "locked"
+	newTestBucket(t, m, bucket, true)
+
+	cfg, err := auth.ParseBucketLockConfigurationInput([]byte(`<ObjectLockConfiguration>
+		<ObjectLockEnabled>Enabled</ObjectLockEnabled>
+		<Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>2</Days></DefaultRetention></Rule>
+	</ObjectLockConfiguration>`))
+	if err != nil {
+		t.Fatalf("parse lock config: %v", err)
+	}
+	err = m.PutObjectLockConfiguration(ctx, bucket, cfg)
+	if err != nil {
+		t.Fatalf("put lock config: %v", err)
+	}
+
+	retention := func(key string) types.ObjectLockRetention {
+		t.Helper()
+		data, err := m.GetObjectRetention(ctx, bucket, key, "")
+		if err != nil {
+			t.Fatalf("get retention %v: %v", key, err)
+		}
+		r, err := auth.ParseObjectLockRetentionOutput(data)
+		if err != nil {
+			t.Fatalf("parse retention %v: %v", key, err)
+		}
+		return *r
+	}
+
+	before := time.Now().AddDate(0, 0, 2)
+	putTestObject(t, m, bucket, "default", "data")
+
+	key := "mp"
+	mpu, err := m.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		t.Fatalf("create multipart upload: %v", err)
+	}
+	pn := int32(1)
+	etag, err := m.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     &bucket,
+		Key:        &key,
+		UploadId:   mpu.UploadId,
+		PartNumber: &pn,
+		Body:       strings.NewReader("data"),
+	})
+	if err != nil {
+		t.Fatalf("upload part: %v", err)
+	}
+	_, err = m.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   &bucket,
+		Key:      &key,
+		UploadId: mpu.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: []types.CompletedPart{{ETag: &etag, PartNumber: &pn}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("complete multipart upload: %v", err)
+	}
+	after := time.Now().AddDate(0, 0, 2)
+
+	for _, key := range []string{"default", "mp"} {
+		r := retention(key)
+		if r.Mode != types.ObjectLockRetentionModeGovernance {
+			t.Errorf("%v: expected GOVERNANCE retention, got %q", key, r.Mode)
+		}
+		if r.RetainUntilDate == nil || r.RetainUntilDate.Before(before) || r.RetainUntilDate.After(after) {
+			t.Errorf("%v: expected retain until date between %v and %v, got %v",
+				key, before, after, r.RetainUntilDate)
+		}
+	}
+
+	// an explicit retention takes precedence over the default
+	explicit := "explicit"
+	date := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	_, err = m.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:                    &bucket,
+		Key:                       &explicit,
+		Body:                      strings.NewReader("data"),
+		ObjectLockMode:            types.ObjectLockModeCompliance,
+		ObjectLockRetainUntilDate: &date,
+	})
+	if err != nil {
+		t.Fatalf("put object: %v", err)
+	}
+	r := retention(explicit)
+	if r.Mode != types.ObjectLockRetentionModeCompliance || r.RetainUntilDate == nil || !r.RetainUntilDate.Equal(date) {
+		t.Errorf("expected COMPLIANCE retention until %v, got %q until %v", date, r.Mode, r.RetainUntilDate)
+	}
+}
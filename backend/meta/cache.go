@@ -0,0 +1,193 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package meta
+
+import (
+	"container/list"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache is a metadata storer wrapper keeping the bucket attributes, such
+// as the bucket acl, policy and object lock config read for most
+// requests, in memory. Object attributes are passed through. Writes
+// through the cache invalidate the cached attribute, and entries are
+// evicted in least recently used order once the cache is full. Missing
+// attributes are cached as well, so that the common case of a bucket
+// without a policy does not hit the store each request.
+type Cache struct {
+	MetadataStorer
+
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[cacheKey]*list.Element
+	// gen is incremented by every invalidation so that a read racing
+	// with a write does not cache the value read before the write
+	gen uint64
+}
+
+var _ MetadataStorer = &Cache{}
+
+type cacheKey struct {
+	bucket    string
+	attribute string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	value   []byte
+	err     error
+	expires time.Time
+}
+
+// NewCache wraps the metadata storer with a cache of up to size bucket
+// attributes. A non zero ttl expires the entries after that time, for
+// stores also modified outside of this gateway.
+func NewCache(ms MetadataStorer, size int, ttl time.Duration) *Cache {
+	return &Cache{
+		MetadataStorer: ms,
+		size:           size,
+		ttl:            ttl,
+		lru:            list.New(),
+		entries:        make(map[cacheKey]*list.Element),
+	}
+}
+
+// RetrieveAttribute retrieves the value of a specific attribute for an object in a bucket.
+func (c *Cache) RetrieveAttribute(bucket, object, attribute string) ([]byte, error) {
+	if object != "" {
+		return c.MetadataStorer.RetrieveAttribute(bucket, object, attribute)
+	}
+
+	key := cacheKey{bucket: bucket, attribute: attribute}
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		if c.ttl == 0 || time.Now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			return e.value, e.err
+		}
+		c.remove(el)
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	value, err := c.MetadataStorer.RetrieveAttribute(bucket, object, attribute)
+	if err != nil && !errors.Is(err, ErrNoSuchKey) {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return value, err
+	}
+	if _, ok := c.entries[key]; ok {
+		return value, err
+	}
+	// the request strings may reference reused request buffers
+	key = cacheKey{
+		bucket:    strings.Clone(bucket),
+		attribute: strings.Clone(attribute),
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{
+		key:     key,
+		value:   value,
+		err:     err,
+		expires: time.Now().Add(c.ttl),
+	})
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+	return value, err
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
+
+// invalidate drops the cached bucket attribute, or all of the bucket
+// attributes when attribute is empty
+func (c *Cache) invalidate(bucket, attribute string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	if attribute != "" {
+		if el, ok := c.entries[cacheKey{bucket: bucket, attribute: attribute}]; ok {
+			c.remove(el)
+		}
+		return
+	}
+	for key, el := range c.entries {
+		if key.bucket == bucket {
+			c.remove(el)
+		}
+	}
+}
+
+// StoreAttribute stores the value of a specific attribute for an object in a bucket.
+func (c *Cache) StoreAttribute(bucket, object, attribute string, value []byte) error {
+	if object == "" {
+		defer c.invalidate(bucket, attribute)
+	}
+	return c.MetadataStorer.StoreAttribute(bucket, object, attribute, value)
+}
+
+// DeleteAttribute removes the value of a specific attribute for an object in a bucket.
+func (c *Cache) DeleteAttribute(bucket, object, attribute string) error {
+	if object == "" {
+		defer c.invalidate(bucket, attribute)
+	}
+	return c.MetadataStorer.DeleteAttribute(bucket, object, attribute)
+}
+
+// DeleteAttributes removes all attributes for an object or a bucket.
+func (c *Cache) DeleteAttributes(bucket, object string) error {
+	if object == "" {
+		defer c.invalidate(bucket, "")
+	}
+	return c.MetadataStorer.DeleteAttributes(bucket, object)
+}
+
+// CommitAttributes replaces all attributes for an object with attrs.
+func (c *Cache) CommitAttributes(bucket, object string, attrs map[string][]byte) error {
+	if object == "" {
+		defer c.invalidate(bucket, "")
+	}
+	return CommitAttributes(c.MetadataStorer, bucket, object, attrs)
+}
+
+// AttributesWithFile returns true if the wrapped store keeps the
+// attributes with the file
+func (c *Cache) AttributesWithFile() bool {
+	return AttributesWithFile(c.MetadataStorer)
+}
+
+// Close closes the wrapped metadata storer if it needs closing
+func (c *Cache) Close() error {
+	if cl, ok := c.MetadataStorer.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package meta
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// mapStore is an in memory metadata storer counting the retrieves
+type mapStore struct {
+	attrs     map[string][]byte
+	retrieves int
+}
+
+func (m *mapStore) RetrieveAttribute(bucket, object, attribute string) ([]byte, error) {
+	m.retrieves++
+	b, ok := m.attrs[bucket+"/"+object+":"+attribute]
+	if !ok {
+		return nil, ErrNoSuchKey
+	}
+	return b, nil
+}
+
+func (m *mapStore) StoreAttribute(bucket, object, attribute string, value []byte) error {
+	m.attrs[bucket+"/"+object+":"+attribute] = value
+	return nil
+}
+
+func (m *mapStore) DeleteAttribute(bucket, object, attribute string) error {
+	delete(m.attrs, bucket+"/"+object+":"+attribute)
+	return nil
+}
+
+func (m *mapStore) ListAttributes(bucket, object string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mapStore) DeleteAttributes(bucket, object string) error {
+	for k := range m.attrs {
+		delete(m.attrs, k)
+	}
+	return nil
+}
+
+func TestCache(t *testing.T) {
+	ms := &mapStore{attrs: map[string][]byte{}}
+	c := NewCache(ms, 2, 0)
+
+	get := func(bucket, object, attr, want string, retrieves int) {
+		t.Helper()
+		b, err := c.RetrieveAttribute(bucket, object, attr)
+		if want == "" {
+			if !errors.Is(err, ErrNoSuchKey) {
+				t.Fatalf("get %v %v got %q, %v, want no such key", bucket, attr, b, err)
+			}
+		} else if err != nil || string(b) != want {
+			t.Fatalf("get %v %v got %q, %v, want %q", bucket, attr, b, err, want)
+		}
+		if ms.retrieves != retrieves {
+			t.Fatalf("get %v %v store retrieves %v, want %v", bucket, attr, ms.retrieves, retrieves)
+		}
+	}
+
+	c.StoreAttribute("b1", "", "acl", []byte("acl1"))
+	get("b1", "", "acl", "acl1", 1)
+	get("b1", "", "acl", "acl1", 1)
+
+	// missing attributes are cached too
+	get("b1", "", "policy", "", 2)
+	get("b1", "", "policy", "", 2)
+
+	// writes invalidate the entry
+	c.StoreAttribute("b1", "", "policy", []byte("p"))
+	get("b1", "", "policy", "p", 3)
+	get("b1", "", "policy", "p", 3)
+	c.DeleteAttribute("b1", "", "policy")
+	get("b1", "", "policy", "", 4)
+
+	// object attributes are not cached
+	c.StoreAttribute("b1", "obj", "etag", []byte("e"))
+	get("b1", "obj", "etag", "e", 5)
+	get("b1", "obj", "etag", "e", 6)
+
+	// least recently used entry is evicted
+	get("b1", "", "acl", "acl1", 6)
+	get("b2", "", "acl", "", 7)
+	get("b1", "", "acl", "acl1", 7)
+	get("b1", "", "policy", "", 8)
+	get("b2", "", "acl", "", 9)
+
+	// removing the bucket drops all its entries
+	c.DeleteAttributes("b1", "")
+	get("b1", "", "acl", "", 10)
+
+	c = NewCache(ms, 10, time.Millisecond)
+	ms.retrieves = 0
+	c.StoreAttribute("b1", "", "acl", []byte("acl1"))
+	get("b1", "", "acl", "acl1", 1)
+	time.Sleep(2 * time.Millisecond)
+	get("b1", "", "acl", "acl1", 2)
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package meta
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DBMeta is a metadata storer that keeps the bucket and object attributes
+// in an embedded bbolt database instead of on the files themselves. Each
+// gateway bucket has a database bucket with keys of the form
+// "<object>\x00<attribute>", so all attributes for an object are adjacent
+// and can be listed or removed with a prefix scan. Bucket attributes use
+// the empty object name.
+//
+// Concurrent updates are coalesced into shared write transactions, which
+// avoids the per attribute syscall cost of xattrs for high create rates
+// and removes the filesystem limits on attribute value sizes.
+//
+// The database can only be opened by one gateway at a time.
+type DBMeta struct {
+	db *bolt.DB
+}
+
+var _ MetadataStorer = &DBMeta{}
+
+// NewDBMeta opens or creates the metadata database at path
+func NewDBMeta(path string) (*DBMeta, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{
+		// fail instead of blocking when another gateway has the
+		// database open
+		Timeout: time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open metadata db %v: %w", path, err)
+	}
+
+	return &DBMeta{db: db}, nil
+}
+
+// Close closes the metadata database
+func (d *DBMeta) Close() error {
+	return d.db.Close()
+}
+
+// objectKey returns the key prefix for all attributes of the object
+func objectKey(object string) []byte {
+	if object != "" {
+		// "a/" and "a" refer to the same directory
+		object = filepath.Clean(object)
+	}
+	return append([]byte(object), 0)
+}
+
+func attrKey(object, attribute string) []byte {
+	return append(objectKey(object), attribute...)
+}
+
+// exists checks the bucket or object itself so that missing entries
+// return the same not exist errors as the xattr store
+func exists(bucket, object string) error {
+	_, err := os.Stat(filepath.Join(bucket, object))
+	return err
+}
+
+// RetrieveAttribute looks up the object attribute key in the db bucket
+// named after the bucket
+func (d *DBMeta) RetrieveAttribute(bucket, object, attribute string) ([]byte, error) {
+	var value []byte
+	err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return ErrNoSuchKey
+		}
+		v := b.Get(attrKey(object, attribute))
+		if v == nil {
+			return ErrNoSuchKey
+		}
+		// values are only valid for the life of the transaction
+		value = bytes.Clone(v)
+		return nil
+	})
+	if errors.Is(err, ErrNoSuchKey) {
+		if err := exists(bucket, object); err != nil {
+			return nil, err
+		}
+	}
+	return value, err
+}
+
+// StoreAttribute puts the object attribute key in the db bucket, creating
+// the db bucket on first use. Concurrent updates are batched into a
+// single db transaction.
+func (d *DBMeta) StoreAttribute(bucket, object, attribute string, value []byte) error {
+	if err := exists(bucket, object); err != nil {
+		return err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+
+	return d.db.Batch(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return fmt.Errorf("create db bucket: %w", err)
+		}
+		return b.Put(attrKey(object, attribute), value)
+	})
+}
+
+// DeleteAttribute removes the object attribute key from the db bucket
+func (d *DBMeta) DeleteAttribute(bucket, object, attribute string) error {
+	err := d.db.Batch(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return ErrNoSuchKey
+		}
+		key := attrKey(object, attribute)
+		if b.Get(key) == nil {
+			return ErrNoSuchKey
+		}
+		return b.Delete(key)
+	})
+	if errors.Is(err, ErrNoSuchKey) {
+		if err := exists(bucket, object); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// ListAttributes returns the attribute names stored under the object key
+// prefix in the db bucket
+func (d *DBMeta) ListAttributes(bucket, object string) ([]string, error) {
+	attributes := []string{}
+	err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		prefix := objectKey(object)
+		c := b.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			attributes = append(attributes, string(k[len(prefix):]))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(attributes) == 0 {
+		if err := exists(bucket, object); err != nil {
+			return nil, err
+		}
+	}
+	return attributes, nil
+}
+
+// CommitAttributes replaces all attributes for an object with attrs in
+// a single transaction.
+func (d *DBMeta) CommitAttributes(bucket, object string, attrs map[string][]byte) error {
+	if err := exists(bucket, object); err != nil {
+		return err
+	}
+
+	return d.db.Batch(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return fmt.Errorf("create db bucket: %w", err)
+		}
+
+		prefix := objectKey(object)
+		c := b.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+
+		for attribute, value := range attrs {
+			if value == nil {
+				value = []byte{}
+			}
+			err := b.Put(attrKey(object, attribute), value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteAttributes removes the attributes for the bucket or object. For
+// a bucket or a directory the attributes of everything below it are
+// removed as well, so this should only be called once the directory
+// itself has been removed.
+func (d *DBMeta) DeleteAttributes(bucket, object string) error {
+	return d.db.Batch(func(tx *bolt.Tx) error {
+		if object == "" {
+			err := tx.DeleteBucket([]byte(bucket))
+			if errors.Is(err, bolt.ErrBucketNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+
+		object = filepath.Clean(object)
+		for _, prefix := range [][]byte{objectKey(object), []byte(object + "/")} {
+			c := b.Cursor()
+			// deleting at the cursor moves it to the next key, so
+			// keep seeking to the prefix until no keys remain
+			for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
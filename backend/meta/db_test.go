@@ -0,0 +1,137 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package meta
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDBMeta(t *testing.T) {
+	dir := chdirTemp(t)
+	for _, d := range []string{"bucket/a/b", "bucket/ab", "other"} {
+		if err := os.MkdirAll(filepath.FromSlash(d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := NewDBMeta(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// the database allows only one opener
+	if _, err := NewDBMeta(filepath.Join(dir, "meta.db")); err == nil {
+		t.Fatalf("expected second open to fail")
+	}
+
+	store := func(bucket, object string, attrs ...string) {
+		t.Helper()
+		for _, attr := range attrs {
+			if err := d.StoreAttribute(bucket, object, attr, []byte(object+attr)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	list := func(bucket, object string, want ...string) {
+		t.Helper()
+		attrs, err := d.ListAttributes(bucket, object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want == nil {
+			want = []string{}
+		}
+		if !reflect.DeepEqual(attrs, want) {
+			t.Fatalf("list %v/%v got %v, want %v", bucket, object, attrs, want)
+		}
+	}
+
+	store("bucket", "", "acl", "policy")
+	store("bucket", "a/", "etag")
+	store("bucket", "a/b", "etag", "user.x")
+	store("bucket", "ab", "etag")
+	store("other", "", "acl")
+
+	list("bucket", "", "acl", "policy")
+	list("bucket", "a", "etag")
+	list("bucket", "a/b", "etag", "user.x")
+
+	b, err := d.RetrieveAttribute("bucket", "a/b", "user.x")
+	if err != nil || string(b) != "a/buser.x" {
+		t.Fatalf("retrieve got %q, %v", b, err)
+	}
+	_, err = d.RetrieveAttribute("bucket", "a/b", "missing")
+	if !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("retrieve missing attribute got %v, want %v", err, ErrNoSuchKey)
+	}
+	_, err = d.RetrieveAttribute("bucket", "nosuchobj", "etag")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("retrieve for missing object got %v, want not exist", err)
+	}
+	err = d.StoreAttribute("bucket", "nosuchobj", "etag", nil)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("store for missing object got %v, want not exist", err)
+	}
+
+	if err := d.DeleteAttribute("bucket", "a/b", "user.x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DeleteAttribute("bucket", "a/b", "user.x"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("delete missing attribute got %v, want %v", err, ErrNoSuchKey)
+	}
+	list("bucket", "a/b", "etag")
+
+	// removing a directory removes everything below it, but not
+	// objects sharing the name prefix
+	if err := d.DeleteAttributes("bucket", "a/"); err != nil {
+		t.Fatal(err)
+	}
+	list("bucket", "a")
+	list("bucket", "a/b")
+	list("bucket", "ab", "etag")
+	list("bucket", "", "acl", "policy")
+
+	// committing replaces all the object attributes
+	store("bucket", "ab", "user.x")
+	err = d.CommitAttributes("bucket", "ab", map[string][]byte{
+		"etag":   []byte("new"),
+		"user.y": nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list("bucket", "ab", "etag", "user.y")
+	b, err = d.RetrieveAttribute("bucket", "ab", "etag")
+	if err != nil || string(b) != "new" {
+		t.Fatalf("retrieve committed etag got %q, %v", b, err)
+	}
+	err = d.CommitAttributes("bucket", "nosuchobj", map[string][]byte{"etag": nil})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("commit for missing object got %v, want not exist", err)
+	}
+
+	if err := d.DeleteAttributes("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	list("bucket", "")
+	list("bucket", "ab")
+	list("other", "", "acl")
+}
@@ -38,3 +38,46 @@ type MetadataStorer interface {
 	// Returns an error if the operation fails.
 	DeleteAttributes(bucket, object string) error
 }
+
+// AttributeCommitter is implemented by the metadata stores that can
+// replace all the attributes of an object in one update, so that
+// readers and a crash see either the previous or the new attributes.
+type AttributeCommitter interface {
+	// CommitAttributes replaces all attributes for an object with attrs.
+	CommitAttributes(bucket, object string, attrs map[string][]byte) error
+}
+
+// FileAttributer is implemented by the metadata stores keeping the
+// attributes with the file itself, such that attributes stored on a
+// temp file are published along with the file when it is renamed.
+type FileAttributer interface {
+	AttributesWithFile() bool
+}
+
+// CommitAttributes replaces all attributes for an object with attrs, as
+// one update for the stores implementing AttributeCommitter and
+// otherwise by removing the previous attributes before storing attrs.
+func CommitAttributes(m MetadataStorer, bucket, object string, attrs map[string][]byte) error {
+	if c, ok := m.(AttributeCommitter); ok {
+		return c.CommitAttributes(bucket, object, attrs)
+	}
+
+	err := m.DeleteAttributes(bucket, object)
+	if err != nil {
+		return err
+	}
+	for attribute, value := range attrs {
+		err := m.StoreAttribute(bucket, object, attribute, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AttributesWithFile returns true if the store keeps the attributes
+// with the file, see FileAttributer
+func AttributesWithFile(m MetadataStorer) bool {
+	f, ok := m.(FileAttributer)
+	return ok && f.AttributesWithFile()
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package meta
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// StoreFactory creates a metadata store for the posix gateway root
+// directory. The path is the store location from the gateway options,
+// empty to use the store default.
+type StoreFactory func(gwroot, path string) (MetadataStorer, error)
+
+var (
+	storesMu sync.RWMutex
+	stores   = make(map[string]StoreFactory)
+)
+
+// Register makes a metadata store available by name to the gateway
+// "--metadata" option. This is meant to be called from the init function
+// of the package implementing the store, so that adding a blank import of
+// the package to the gateway build is all that is needed to use a site
+// specific store. Register panics if the name is already registered or
+// the factory is nil.
+func Register(name string, factory StoreFactory) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if factory == nil {
+		panic("meta: register nil store factory for " + name)
+	}
+	if _, ok := stores[name]; ok {
+		panic("meta: store registered twice: " + name)
+	}
+	stores[name] = factory
+}
+
+// Stores returns the sorted names of the registered metadata stores
+func Stores() []string {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+
+	names := make([]string, 0, len(stores))
+	for name := range stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open creates the named metadata store for the gateway root directory,
+// the xattr store is used when name is empty
+func Open(name, gwroot, path string) (MetadataStorer, error) {
+	if name == "" {
+		name = "xattr"
+	}
+
+	storesMu.RLock()
+	factory, ok := stores[name]
+	storesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown metadata store %q, available: %v",
+			name, Stores())
+	}
+
+	return factory(gwroot, path)
+}
+
+func init() {
+	Register("xattr", func(gwroot, _ string) (MetadataStorer, error) {
+		err := XattrMeta{}.Test(gwroot)
+		if err != nil {
+			return nil, fmt.Errorf("posix xattr check: %v", err)
+		}
+		return XattrMeta{}, nil
+	})
+	Register("sidecar", func(gwroot, path string) (MetadataStorer, error) {
+		if path == "" {
+			path = filepath.Join(gwroot, ".sgwmeta")
+		}
+		sc, err := NewSideCar(path)
+		if err != nil {
+			return nil, err
+		}
+		err = sc.Test()
+		if err != nil {
+			return nil, fmt.Errorf("posix sidecar check: %v", err)
+		}
+		return sc, nil
+	})
+	Register("db", func(gwroot, path string) (MetadataStorer, error) {
+		if path == "" {
+			path = filepath.Join(gwroot, ".sgwmeta.db")
+		}
+		return NewDBMeta(path)
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package meta
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	var gotRoot, gotPath string
+	Register("test", func(gwroot, path string) (MetadataStorer, error) {
+		gotRoot, gotPath = gwroot, path
+		return &mapStore{attrs: map[string][]byte{}}, nil
+	})
+
+	ms, err := Open("test", "/gwroot", "/store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ms.(*mapStore); !ok {
+		t.Fatalf("open returned %T", ms)
+	}
+	if gotRoot != "/gwroot" || gotPath != "/store" {
+		t.Fatalf("factory got %q %q", gotRoot, gotPath)
+	}
+
+	if want := []string{"db", "sidecar", "test", "xattr"}; !reflect.DeepEqual(Stores(), want) {
+		t.Fatalf("stores got %v, want %v", Stores(), want)
+	}
+
+	_, err = Open("nosuchstore", "/gwroot", "")
+	if err == nil || !strings.Contains(err.Error(), "unknown metadata store") {
+		t.Fatalf("open unknown store got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected duplicate register to panic")
+		}
+	}()
+	Register("xattr", func(string, string) (MetadataStorer, error) { return nil, nil })
+}
@@ -0,0 +1,297 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	// sidecarAttrDir is the directory within each level of the sidecar
+	// tree that holds the attribute files for the entries at that level.
+	// A directory component with this name can not be used in object
+	// names when the sidecar store is in use.
+	sidecarAttrDir = ".sgwattrs"
+	sidecarTmpPfx  = ".sgwtmp-"
+
+	// number of locks used to serialize read-modify-write updates
+	sidecarLocks = 64
+)
+
+// SideCar is a metadata storer that keeps the attributes for each bucket
+// and object in a JSON file within a separate directory tree. This is
+// meant for filesystems that do not support extended attributes, such
+// as many NFS exports. The sidecar tree mirrors the bucket directory
+// layout:
+//
+//	<dir>/.sgwattrs/<bucket>              bucket attributes
+//	<dir>/<bucket>/a/b/.sgwattrs/<object> attributes for object a/b/<object>
+//
+// Updates are written to a temp file and renamed over the existing
+// attribute file so that readers never see a partially written file.
+type SideCar struct {
+	dir   string
+	locks [sidecarLocks]sync.Mutex
+}
+
+var _ MetadataStorer = &SideCar{}
+
+// NewSideCar returns a sidecar metadata storer rooted at dir. The
+// directory is created if it does not already exist.
+func NewSideCar(dir string) (*SideCar, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("create sidecar dir: %w", err)
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("sidecar dir path: %w", err)
+	}
+
+	return &SideCar{dir: abs}, nil
+}
+
+// attrPath returns the path of the attribute file for the bucket or object
+func (s *SideCar) attrPath(bucket, object string) string {
+	if object == "" {
+		return filepath.Join(s.dir, sidecarAttrDir, bucket)
+	}
+	object = filepath.Clean(object)
+	return filepath.Join(s.dir, bucket, filepath.Dir(object),
+		sidecarAttrDir, filepath.Base(object))
+}
+
+func (s *SideCar) lock(bucket, object string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(filepath.Join(bucket, filepath.Clean(object))))
+	mu := &s.locks[h.Sum32()%sidecarLocks]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// load reads the attributes for the bucket or object. If there is no
+// attribute file, the bucket or object itself is checked so that
+// missing entries return the same not exist errors as the xattr store.
+func (s *SideCar) load(bucket, object string) (map[string][]byte, error) {
+	b, err := os.ReadFile(s.attrPath(bucket, object))
+	if errors.Is(err, fs.ErrNotExist) {
+		_, err := os.Stat(filepath.Join(bucket, object))
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read attributes: %w", err)
+	}
+
+	attrs := make(map[string][]byte)
+	err = json.Unmarshal(b, &attrs)
+	if err != nil {
+		return nil, fmt.Errorf("parse attributes %v: %w",
+			s.attrPath(bucket, object), err)
+	}
+	return attrs, nil
+}
+
+// save atomically replaces the attribute file for the bucket or object
+func (s *SideCar) save(bucket, object string, attrs map[string][]byte) error {
+	if len(attrs) == 0 {
+		return s.remove(bucket, object)
+	}
+
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		return fmt.Errorf("encode attributes: %w", err)
+	}
+
+	path := s.attrPath(bucket, object)
+	dir := filepath.Dir(path)
+
+	var f *os.File
+	for i := 0; i < 3; i++ {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return fmt.Errorf("create attribute dir: %w", err)
+		}
+		// an empty parent might be pruned by a concurrent delete
+		// between the mkdir and create, so retry on ENOENT
+		f, err = os.CreateTemp(dir, sidecarTmpPfx)
+		if !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("create attribute temp file: %w", err)
+	}
+
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	cerr := f.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("write attributes: %w", err)
+	}
+
+	return nil
+}
+
+// remove deletes the attribute file for the bucket or object and any
+// sidecar directories left empty up to the bucket level
+func (s *SideCar) remove(bucket, object string) error {
+	path := s.attrPath(bucket, object)
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove attributes: %w", err)
+	}
+
+	if object == "" {
+		return nil
+	}
+
+	stop := filepath.Join(s.dir, bucket)
+	for dir := filepath.Dir(path); dir != stop && dir != s.dir; dir = filepath.Dir(dir) {
+		// fails if the directory is not empty
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// RetrieveAttribute returns the attribute value from the sidecar attribute
+// file of the bucket or object
+func (s *SideCar) RetrieveAttribute(bucket, object, attribute string) ([]byte, error) {
+	attrs, err := s.load(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := attrs[attribute]
+	if !ok {
+		return nil, ErrNoSuchKey
+	}
+	return b, nil
+}
+
+// StoreAttribute adds or replaces the attribute in the sidecar attribute
+// file, rewriting the whole file while holding the object lock
+func (s *SideCar) StoreAttribute(bucket, object, attribute string, value []byte) error {
+	unlock := s.lock(bucket, object)
+	defer unlock()
+
+	attrs, err := s.load(bucket, object)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	attrs[attribute] = value
+	return s.save(bucket, object, attrs)
+}
+
+// DeleteAttribute removes the attribute from the sidecar attribute file
+func (s *SideCar) DeleteAttribute(bucket, object, attribute string) error {
+	unlock := s.lock(bucket, object)
+	defer unlock()
+
+	attrs, err := s.load(bucket, object)
+	if err != nil {
+		return err
+	}
+	if _, ok := attrs[attribute]; !ok {
+		return ErrNoSuchKey
+	}
+	delete(attrs, attribute)
+	return s.save(bucket, object, attrs)
+}
+
+// ListAttributes returns the sorted attribute names from the sidecar
+// attribute file
+func (s *SideCar) ListAttributes(bucket, object string) ([]string, error) {
+	attrs, err := s.load(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	attributes := make([]string, 0, len(attrs))
+	for attr := range attrs {
+		attributes = append(attributes, attr)
+	}
+	sort.Strings(attributes)
+	return attributes, nil
+}
+
+// CommitAttributes replaces all attributes for an object with attrs,
+// with a single rename of the attribute file.
+func (s *SideCar) CommitAttributes(bucket, object string, attrs map[string][]byte) error {
+	unlock := s.lock(bucket, object)
+	defer unlock()
+
+	if _, err := os.Stat(filepath.Join(bucket, object)); err != nil {
+		return err
+	}
+	return s.save(bucket, object, attrs)
+}
+
+// DeleteAttributes removes the attributes for the bucket or object. For
+// a bucket or a directory the attributes of everything below it are
+// removed as well, so this should only be called once the directory
+// itself has been removed.
+func (s *SideCar) DeleteAttributes(bucket, object string) error {
+	unlock := s.lock(bucket, object)
+	defer unlock()
+
+	if object == "" {
+		err := os.RemoveAll(filepath.Join(s.dir, bucket))
+		if err != nil {
+			return fmt.Errorf("remove bucket attributes: %w", err)
+		}
+		return s.remove(bucket, "")
+	}
+
+	err := os.RemoveAll(filepath.Join(s.dir, bucket, filepath.Clean(object)))
+	if err != nil {
+		return fmt.Errorf("remove attributes: %w", err)
+	}
+	return s.remove(bucket, object)
+}
+
+// Test checks that the sidecar directory is writable
+func (s *SideCar) Test() error {
+	f, err := os.CreateTemp(s.dir, sidecarTmpPfx)
+	if err != nil {
+		return fmt.Errorf("sidecar dir not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package meta
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// chdirTemp changes to a new temp directory for the duration of the test
+// since the stores resolve bucket paths relative to the working directory
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestSideCar(t *testing.T) {
+	dir := chdirTemp(t)
+	if err := os.MkdirAll(filepath.Join("bucket", "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("bucket", "a", "b", "obj"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSideCar(filepath.Join(dir, ".sgwmeta"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Test(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.StoreAttribute("bucket", "", "acl", []byte("owner")); err != nil {
+		t.Fatal(err)
+	}
+	for _, attr := range []string{"etag", "content-type", "user.x"} {
+		if err := s.StoreAttribute("bucket", "a/b/obj", attr, []byte(attr)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.StoreAttribute("bucket", "a/", "etag", []byte("dir")); err != nil {
+		t.Fatal(err)
+	}
+
+	attrs, err := s.ListAttributes("bucket", "a/b/obj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"content-type", "etag", "user.x"}; !reflect.DeepEqual(attrs, want) {
+		t.Fatalf("list attributes got %v, want %v", attrs, want)
+	}
+
+	b, err := s.RetrieveAttribute("bucket", "a/b/obj", "etag")
+	if err != nil || string(b) != "etag" {
+		t.Fatalf("retrieve etag got %q, %v", b, err)
+	}
+	b, err = s.RetrieveAttribute("bucket", "a", "etag")
+	if err != nil || string(b) != "dir" {
+		t.Fatalf("retrieve dir etag got %q, %v", b, err)
+	}
+
+	_, err = s.RetrieveAttribute("bucket", "a/b/obj", "missing")
+	if !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("retrieve missing attribute got %v, want %v", err, ErrNoSuchKey)
+	}
+	_, err = s.RetrieveAttribute("bucket", "nosuchobj", "etag")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("retrieve for missing object got %v, want not exist", err)
+	}
+	err = s.StoreAttribute("bucket", "nosuchobj", "etag", nil)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("store for missing object got %v, want not exist", err)
+	}
+
+	if err := s.DeleteAttribute("bucket", "a/b/obj", "user.x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteAttribute("bucket", "a/b/obj", "user.x"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("delete missing attribute got %v, want %v", err, ErrNoSuchKey)
+	}
+
+	// deleting the object attributes prunes the empty sidecar dirs
+	// but leaves the parent directory object attributes
+	if err := s.DeleteAttributes("bucket", "a/b/obj"); err != nil {
+		t.Fatal(err)
+	}
+	attrs, err = s.ListAttributes("bucket", "a/b/obj")
+	if err != nil || len(attrs) != 0 {
+		t.Fatalf("list deleted attributes got %v, %v", attrs, err)
+	}
+	_, err = os.Stat(filepath.Join(dir, ".sgwmeta", "bucket", "a"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("sidecar dir not pruned: %v", err)
+	}
+	b, err = s.RetrieveAttribute("bucket", "a/", "etag")
+	if err != nil || string(b) != "dir" {
+		t.Fatalf("retrieve dir etag after delete got %q, %v", b, err)
+	}
+
+	// committing replaces all the object attributes
+	if err := s.StoreAttribute("bucket", "a/b/obj", "user.x", nil); err != nil {
+		t.Fatal(err)
+	}
+	err = s.CommitAttributes("bucket", "a/b/obj", map[string][]byte{
+		"etag":   []byte("new"),
+		"user.y": nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs, err = s.ListAttributes("bucket", "a/b/obj")
+	if want := []string{"etag", "user.y"}; err != nil || !reflect.DeepEqual(attrs, want) {
+		t.Fatalf("list committed attributes got %v, %v, want %v", attrs, err, want)
+	}
+	err = s.CommitAttributes("bucket", "nosuchobj", map[string][]byte{"etag": nil})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("commit for missing object got %v, want not exist", err)
+	}
+	if err := s.CommitAttributes("bucket", "a/b/obj", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteAttributes("bucket", ""); err != nil {
+		t.Fatal(err)
+	}
+	ents, err := os.ReadDir(filepath.Join(dir, ".sgwmeta"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ent := range ents {
+		if ent.Name() == "bucket" {
+			t.Fatalf("bucket sidecar tree not removed")
+		}
+	}
+	_, err = s.RetrieveAttribute("bucket", "", "acl")
+	if !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("retrieve deleted bucket attribute got %v, want %v", err, ErrNoSuchKey)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package meta
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const (
+	// spillDir holds attribute values too large for the filesystem
+	// xattr limits, within the bucket gateway temp dir so that the
+	// spilled values are removed along with the bucket
+	spillDir = ".sgwtmp/spill"
+)
+
+var (
+	// spillPrefix marks an xattr value holding the bucket relative
+	// path of the spilled value. Attribute values are either http
+	// header values or json, neither of which can contain a NUL.
+	spillPrefix = []byte("\x00sgwspill:")
+)
+
+// isTooLarge returns true for the errors returned when an xattr value
+// exceeds the size supported by the filesystem
+func isTooLarge(err error) bool {
+	return errors.Is(err, syscall.E2BIG) || errors.Is(err, syscall.ENOSPC) ||
+		errors.Is(err, syscall.ERANGE)
+}
+
+// spillObjDir returns the bucket relative directory holding the spilled
+// values for the object, or the bucket when object is empty
+func spillObjDir(object string) string {
+	if object != "" {
+		object = filepath.Clean(object)
+	}
+	sum := sha256.Sum256([]byte(object))
+	return filepath.Join(spillDir, fmt.Sprintf("%x", sum))
+}
+
+// spillAttribute writes the value to the object spill file for the
+// attribute and returns the pointer to store in the xattr
+func spillAttribute(bucket, object, attribute string, value []byte) ([]byte, error) {
+	rel := filepath.Join(spillObjDir(object), attribute)
+	dir := filepath.Join(bucket, filepath.Dir(rel))
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("create spill dir: %w", err)
+	}
+
+	f, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("create spill file: %w", err)
+	}
+	_, err = f.Write(value)
+	cerr := f.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(bucket, rel))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("write spill file: %w", err)
+	}
+
+	return append(bytes.Clone(spillPrefix), rel...), nil
+}
+
+// spilledPath returns the path of the spilled value if the xattr value
+// is a spill pointer
+func spilledPath(bucket string, value []byte) (string, bool) {
+	if !bytes.HasPrefix(value, spillPrefix) {
+		return "", false
+	}
+	return filepath.Join(bucket, string(value[len(spillPrefix):])), true
+}
+
+// removeSpilled removes all spilled values for the object
+func removeSpilled(bucket, object string) error {
+	err := os.RemoveAll(filepath.Join(bucket, spillObjDir(object)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove spilled attributes: %w", err)
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ package meta
 import (
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"syscall"
@@ -33,35 +34,90 @@ var (
 	ErrNoSuchKey = errors.New("no such key")
 )
 
-type XattrMeta struct{}
+// XattrMeta stores the attributes as extended attributes of the bucket
+// directories and object files.
+type XattrMeta struct {
+	// Prefix is the xattr namespace and prefix prepended to the
+	// attribute names, "user." when empty. A prefix such as "user.sgw."
+	// keeps the gateway attributes apart from the user xattrs set by
+	// other tools, and a "trusted." prefix hides them from unprivileged
+	// users of a shared filesystem.
+	Prefix string
+}
+
+func (x XattrMeta) prefix() string {
+	if x.Prefix == "" {
+		return xattrPrefix
+	}
+	return x.Prefix
+}
 
 // RetrieveAttribute retrieves the value of a specific attribute for an object in a bucket.
 func (x XattrMeta) RetrieveAttribute(bucket, object, attribute string) ([]byte, error) {
-	b, err := xattr.Get(filepath.Join(bucket, object), xattrPrefix+attribute)
+	b, err := xattr.Get(filepath.Join(bucket, object), x.prefix()+attribute)
 	if errors.Is(err, xattr.ENOATTR) {
 		return nil, ErrNoSuchKey
 	}
-	return b, err
+	if err != nil {
+		return nil, err
+	}
+	if path, ok := spilledPath(bucket, b); ok {
+		b, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read spilled attribute: %w", err)
+		}
+	}
+	return b, nil
 }
 
 // StoreAttribute stores the value of a specific attribute for an object in a bucket.
+// Values exceeding the filesystem xattr size limit are spilled to a file
+// within the bucket, with the xattr holding the path to the spill file.
 func (x XattrMeta) StoreAttribute(bucket, object, attribute string, value []byte) error {
-	return xattr.Set(filepath.Join(bucket, object), xattrPrefix+attribute, value)
+	path := filepath.Join(bucket, object)
+	err := xattr.Set(path, x.prefix()+attribute, value)
+	if err == nil || !isTooLarge(err) {
+		return err
+	}
+
+	ptr, serr := spillAttribute(bucket, object, attribute, value)
+	if serr != nil {
+		return fmt.Errorf("%w: %v", err, serr)
+	}
+	return xattr.Set(path, x.prefix()+attribute, ptr)
 }
 
 // DeleteAttribute removes the value of a specific attribute for an object in a bucket.
 func (x XattrMeta) DeleteAttribute(bucket, object, attribute string) error {
-	err := xattr.Remove(filepath.Join(bucket, object), xattrPrefix+attribute)
+	path := filepath.Join(bucket, object)
+	b, err := xattr.Get(path, x.prefix()+attribute)
+	if err == nil {
+		if spilled, ok := spilledPath(bucket, b); ok {
+			os.Remove(spilled)
+		}
+	}
+
+	err = xattr.Remove(path, x.prefix()+attribute)
 	if errors.Is(err, xattr.ENOATTR) {
 		return ErrNoSuchKey
 	}
 	return err
 }
 
-// DeleteAttributes is not implemented for xattr since xattrs
-// are automatically removed when the file is deleted.
+// DeleteAttributes removes any spilled attribute values for an object.
+// The xattrs themselves are automatically removed when the file is
+// deleted, and spilled bucket attributes are removed with the bucket.
 func (x XattrMeta) DeleteAttributes(bucket, object string) error {
-	return nil
+	if object == "" {
+		return nil
+	}
+	return removeSpilled(bucket, object)
+}
+
+// AttributesWithFile returns true as the xattrs are part of the file
+// inode and follow renames of the file
+func (x XattrMeta) AttributesWithFile() bool {
+	return true
 }
 
 // ListAttributes lists all attributes for an object in a bucket.
@@ -71,19 +127,16 @@ func (x XattrMeta) ListAttributes(bucket, object string) ([]string, error) {
 		return nil, err
 	}
 	attributes := make([]string, 0, len(attrs))
+	prefix := x.prefix()
 	for _, attr := range attrs {
-		if !isUserAttr(attr) {
+		if !strings.HasPrefix(attr, prefix) {
 			continue
 		}
-		attributes = append(attributes, strings.TrimPrefix(attr, xattrPrefix))
+		attributes = append(attributes, strings.TrimPrefix(attr, prefix))
 	}
 	return attributes, nil
 }
 
-func isUserAttr(attr string) bool {
-	return strings.HasPrefix(attr, xattrPrefix)
-}
-
 // Test is a helper function to test if xattrs are supported.
 func (x XattrMeta) Test(path string) error {
 	// check for platform support
@@ -92,10 +145,19 @@ func (x XattrMeta) Test(path string) error {
 	}
 
 	// check if the filesystem supports xattrs
-	_, err := xattr.Get(path, "user.test")
+	_, err := xattr.Get(path, x.prefix()+"test")
 	if errors.Is(err, syscall.ENOTSUP) {
 		return fmt.Errorf("xattrs are not supported on this filesystem")
 	}
 
+	if !strings.HasPrefix(x.prefix(), xattrPrefix) {
+		// other namespaces, such as trusted, need privileges to set
+		err = xattr.Set(path, x.prefix()+"test", nil)
+		if err != nil {
+			return fmt.Errorf("set %v xattrs: %w", x.prefix(), err)
+		}
+		xattr.Remove(path, x.prefix()+"test")
+	}
+
 	return nil
 }
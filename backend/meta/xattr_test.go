@@ -0,0 +1,124 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package meta
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/xattr"
+)
+
+func TestXattrSpill(t *testing.T) {
+	dir := chdirTemp(t)
+	x := XattrMeta{}
+	if err := x.Test(dir); err != nil {
+		t.Skip(err)
+	}
+	if err := os.Mkdir("bucket", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("bucket", "obj"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// larger than the 64k linux xattr value limit
+	large := bytes.Repeat([]byte("policy"), 20000)
+	for _, object := range []string{"", "obj"} {
+		if err := x.StoreAttribute("bucket", object, "policy", large); err != nil {
+			t.Fatalf("store large attribute: %v", err)
+		}
+		b, err := x.RetrieveAttribute("bucket", object, "policy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, large) {
+			t.Fatalf("spilled attribute mismatch, got %v bytes", len(b))
+		}
+	}
+
+	spilled := filepath.Join("bucket", spillObjDir("obj"), "policy")
+	if _, err := os.Stat(spilled); err != nil {
+		t.Fatalf("spill file: %v", err)
+	}
+
+	// small values are still stored inline
+	if err := x.StoreAttribute("bucket", "obj", "etag", []byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join("bucket", spillObjDir("obj"), "etag")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("small attribute spilled: %v", err)
+	}
+
+	if err := x.DeleteAttribute("bucket", "obj", "policy"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(spilled); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("spill file not removed on attribute delete: %v", err)
+	}
+
+	if err := x.StoreAttribute("bucket", "obj", "policy", large); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.DeleteAttributes("bucket", "obj"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join("bucket", spillObjDir("obj"))); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("spill dir not removed on object delete: %v", err)
+	}
+
+	// bucket attributes remain
+	b, err := x.RetrieveAttribute("bucket", "", "policy")
+	if err != nil || !bytes.Equal(b, large) {
+		t.Fatalf("bucket spilled attribute got %v bytes, %v", len(b), err)
+	}
+}
+
+func TestXattrPrefix(t *testing.T) {
+	dir := chdirTemp(t)
+	x := XattrMeta{Prefix: "user.sgw."}
+	if err := x.Test(dir); err != nil {
+		t.Skip(err)
+	}
+	if err := os.Mkdir("bucket", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// attributes set by other tools are ignored
+	if err := xattr.Set("bucket", "user.other", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.StoreAttribute("bucket", "", "acl", []byte("acl")); err != nil {
+		t.Fatal(err)
+	}
+
+	attrs, err := x.ListAttributes("bucket", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs) != 1 || attrs[0] != "acl" {
+		t.Fatalf("list attributes got %v, want [acl]", attrs)
+	}
+	if _, err := xattr.Get("bucket", "user.sgw.acl"); err != nil {
+		t.Fatalf("prefixed xattr: %v", err)
+	}
+	if _, err := (XattrMeta{}).RetrieveAttribute("bucket", "", "acl"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("default prefix retrieve got %v, want %v", err, ErrNoSuchKey)
+	}
+}
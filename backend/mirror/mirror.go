@@ -0,0 +1,564 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mirror
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+// Mirror is a backend writing every modification to both a primary and a
+// secondary backend. Requests are answered by the primary, a failed
+// secondary write is logged and left for Repair to fix. Reads fail over
+// to the secondary when the primary fails with an error other than an
+// S3 error response, such as when its filesystem is unavailable.
+//
+// Version ids are assigned independently by each backend, so requests
+// for a specific object version only modify the primary.
+type Mirror struct {
+	primary   backend.Backend
+	secondary backend.Backend
+
+	mu      sync.Mutex
+	uploads map[string]*upload
+}
+
+var _ backend.Backend = &Mirror{}
+
+func New(primary, secondary backend.Backend) *Mirror {
+	return &Mirror{
+		primary:   primary,
+		secondary: secondary,
+		uploads:   make(map[string]*upload),
+	}
+}
+
+func (m *Mirror) String() string {
+	return "Mirror Gateway"
+}
+
+func (m *Mirror) Shutdown() {
+	m.primary.Shutdown()
+	m.secondary.Shutdown()
+}
+
+// secondaryFailed logs a failed secondary write
+func secondaryFailed(op string, err error) {
+	if err != nil {
+		log.Printf("mirror: secondary %v: %v", op, err)
+	}
+}
+
+// write applies the modification to the primary, and then to the
+// secondary if the primary succeeded
+func (m *Mirror) write(op string, fn func(backend.Backend) error) error {
+	err := fn(m.primary)
+	if err != nil {
+		return err
+	}
+	secondaryFailed(op, fn(m.secondary))
+	return nil
+}
+
+// failover returns true if the primary error is not an S3 error
+// response, and the request should be retried on the secondary
+func failover(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr s3err.APIError
+	return !errors.As(err, &apiErr) && !errors.Is(err, context.Canceled)
+}
+
+// read returns the primary result, or the secondary result if the
+// primary failed over
+func read[T any](m *Mirror, fn func(backend.Backend) (T, error)) (T, error) {
+	res, err := fn(m.primary)
+	if failover(err) {
+		log.Printf("mirror: primary failed, reading from secondary: %v", err)
+		return fn(m.secondary)
+	}
+	return res, err
+}
+
+// versioned returns true for requests naming a specific object version
+func versioned(versionId *string) bool {
+	return versionId != nil && *versionId != "" && *versionId != "null"
+}
+
+// detachWriter passes the data to the secondary until the secondary stops
+// reading it, without failing the primary
+type detachWriter struct {
+	w      io.Writer
+	failed bool
+}
+
+func (d *detachWriter) Write(p []byte) (int, error) {
+	if !d.failed {
+		if _, err := d.w.Write(p); err != nil {
+			d.failed = true
+		}
+	}
+	return len(p), nil
+}
+
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+// dualWrite streams the request body to the primary and secondary writes
+// at the same time, and returns the primary and secondary errors. The
+// secondary is only sent the data read by the primary, and fails if the
+// primary fails.
+func dualWrite(body io.Reader, primary, secondary func(io.Reader) error) (error, error) {
+	if body == nil {
+		body = eofReader{}
+	}
+
+	pr, pw := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		err := secondary(pr)
+		pr.CloseWithError(io.ErrClosedPipe)
+		errc <- err
+	}()
+
+	perr := primary(io.TeeReader(body, &detachWriter{w: pw}))
+	if perr != nil {
+		pw.CloseWithError(perr)
+	} else {
+		pw.Close()
+	}
+
+	return perr, <-errc
+}
+
+func (m *Mirror) ListBuckets(ctx context.Context, owner string, isAdmin bool) (s3response.ListAllMyBucketsResult, error) {
+	return read(m, func(be backend.Backend) (s3response.ListAllMyBucketsResult, error) {
+		return be.ListBuckets(ctx, owner, isAdmin)
+	})
+}
+
+func (m *Mirror) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	return read(m, func(be backend.Backend) (*s3.HeadBucketOutput, error) {
+		return be.HeadBucket(ctx, input)
+	})
+}
+
+func (m *Mirror) GetBucketAcl(ctx context.Context, input *s3.GetBucketAclInput) ([]byte, error) {
+	return read(m, func(be backend.Backend) ([]byte, error) {
+		return be.GetBucketAcl(ctx, input)
+	})
+}
+
+func (m *Mirror) CreateBucket(ctx context.Context, input *s3.CreateBucketInput, defaultACL []byte) error {
+	return m.write("create bucket", func(be backend.Backend) error {
+		return be.CreateBucket(ctx, input, defaultACL)
+	})
+}
+
+func (m *Mirror) PutBucketAcl(ctx context.Context, bucket string, data []byte) error {
+	return m.write("put bucket acl", func(be backend.Backend) error {
+		return be.PutBucketAcl(ctx, bucket, data)
+	})
+}
+
+func (m *Mirror) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) error {
+	return m.write("delete bucket", func(be backend.Backend) error {
+		return be.DeleteBucket(ctx, input)
+	})
+}
+
+func (m *Mirror) PutBucketVersioning(ctx context.Context, input *s3.PutBucketVersioningInput) error {
+	return m.write("put bucket versioning", func(be backend.Backend) error {
+		return be.PutBucketVersioning(ctx, input)
+	})
+}
+
+func (m *Mirror) GetBucketVersioning(ctx context.Context, bucket string) (s3response.GetBucketVersioningOutput, error) {
+	return read(m, func(be backend.Backend) (s3response.GetBucketVersioningOutput, error) {
+		return be.GetBucketVersioning(ctx, bucket)
+	})
+}
+
+func (m *Mirror) PutBucketPolicy(ctx context.Context, bucket string, policy []byte) error {
+	return m.write("put bucket policy", func(be backend.Backend) error {
+		return be.PutBucketPolicy(ctx, bucket, policy)
+	})
+}
+
+func (m *Mirror) GetBucketPolicy(ctx context.Context, bucket string) ([]byte, error) {
+	return read(m, func(be backend.Backend) ([]byte, error) {
+		return be.GetBucketPolicy(ctx, bucket)
+	})
+}
+
+func (m *Mirror) DeleteBucketPolicy(ctx context.Context, bucket string) error {
+	return m.write("delete bucket policy", func(be backend.Backend) error {
+		return be.DeleteBucketPolicy(ctx, bucket)
+	})
+}
+
+func (m *Mirror) PutBucketNotificationConfiguration(ctx context.Context, bucket string, config []byte) error {
+	return m.write("put bucket notification", func(be backend.Backend) error {
+		return be.PutBucketNotificationConfiguration(ctx, bucket, config)
+	})
+}
+
+func (m *Mirror) GetBucketNotificationConfiguration(ctx context.Context, bucket string) ([]byte, error) {
+	return read(m, func(be backend.Backend) ([]byte, error) {
+		return be.GetBucketNotificationConfiguration(ctx, bucket)
+	})
+}
+
+func (m *Mirror) PutBucketLogging(ctx context.Context, bucket string, config []byte) error {
+	return m.write("put bucket logging", func(be backend.Backend) error {
+		return be.PutBucketLogging(ctx, bucket, config)
+	})
+}
+
+func (m *Mirror) GetBucketLogging(ctx context.Context, bucket string) ([]byte, error) {
+	return read(m, func(be backend.Backend) ([]byte, error) {
+		return be.GetBucketLogging(ctx, bucket)
+	})
+}
+
+func (m *Mirror) PutPublicAccessBlock(ctx context.Context, bucket string, config []byte) error {
+	return m.write("put public access block", func(be backend.Backend) error {
+		return be.PutPublicAccessBlock(ctx, bucket, config)
+	})
+}
+
+func (m *Mirror) GetPublicAccessBlock(ctx context.Context, bucket string) ([]byte, error) {
+	return read(m, func(be backend.Backend) ([]byte, error) {
+		return be.GetPublicAccessBlock(ctx, bucket)
+	})
+}
+
+func (m *Mirror) DeletePublicAccessBlock(ctx context.Context, bucket string) error {
+	return m.write("delete public access block", func(be backend.Backend) error {
+		return be.DeletePublicAccessBlock(ctx, bucket)
+	})
+}
+
+func (m *Mirror) PutObject(ctx context.Context, input *s3.PutObjectInput) (string, error) {
+	var etag string
+	perr, serr := dualWrite(input.Body,
+		func(r io.Reader) error {
+			in := *input
+			in.Body = r
+			var err error
+			etag, err = m.primary.PutObject(ctx, &in)
+			return err
+		},
+		func(r io.Reader) error {
+			in := *input
+			in.Body = r
+			_, err := m.secondary.PutObject(ctx, &in)
+			return err
+		})
+	if perr != nil {
+		return "", perr
+	}
+	secondaryFailed("put object", serr)
+	return etag, nil
+}
+
+func (m *Mirror) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	return read(m, func(be backend.Backend) (*s3.HeadObjectOutput, error) {
+		return be.HeadObject(ctx, input)
+	})
+}
+
+// countWriter counts the bytes written to the client
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (m *Mirror) GetObject(ctx context.Context, input *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, error) {
+	cw := &countWriter{w: writer}
+	out, err := m.primary.GetObject(ctx, input,
+		backend.ForwardWriterMetadata(cw, writer))
+	// the read can only fail over before any data was sent
+	if failover(err) && cw.n == 0 {
+		log.Printf("mirror: primary failed, reading from secondary: %v", err)
+		return m.secondary.GetObject(ctx, input, writer)
+	}
+	return out, err
+}
+
+func (m *Mirror) GetObjectAcl(ctx context.Context, input *s3.GetObjectAclInput) ([]byte, error) {
+	return read(m, func(be backend.Backend) ([]byte, error) {
+		return be.GetObjectAcl(ctx, input)
+	})
+}
+
+func (m *Mirror) GetObjectAttributes(ctx context.Context, input *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error) {
+	return read(m, func(be backend.Backend) (s3response.GetObjectAttributesResult, error) {
+		return be.GetObjectAttributes(ctx, input)
+	})
+}
+
+func (m *Mirror) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	out, err := m.primary.CopyObject(ctx, input)
+	if err != nil {
+		return out, err
+	}
+
+	_, _, versionId, err := backend.ParseCopySource(input.CopySource)
+	if err == nil && !versioned(&versionId) {
+		_, err = m.secondary.CopyObject(ctx, input)
+		secondaryFailed("copy object", err)
+	}
+	return out, nil
+}
+
+func (m *Mirror) ListObjects(ctx context.Context, input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	return read(m, func(be backend.Backend) (*s3.ListObjectsOutput, error) {
+		return be.ListObjects(ctx, input)
+	})
+}
+
+func (m *Mirror) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	return read(m, func(be backend.Backend) (*s3.ListObjectsV2Output, error) {
+		return be.ListObjectsV2(ctx, input)
+	})
+}
+
+func (m *Mirror) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) error {
+	err := m.primary.DeleteObject(ctx, input)
+	if err != nil || versioned(input.VersionId) {
+		return err
+	}
+	secondaryFailed("delete object", m.secondary.DeleteObject(ctx, input))
+	return nil
+}
+
+func (m *Mirror) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
+	res, err := m.primary.DeleteObjects(ctx, input)
+	if err != nil || input.Delete == nil {
+		return res, err
+	}
+
+	var objects []types.ObjectIdentifier
+	for _, obj := range input.Delete.Objects {
+		if !versioned(obj.VersionId) {
+			objects = append(objects, obj)
+		}
+	}
+	if len(objects) == 0 {
+		return res, nil
+	}
+
+	in := *input
+	in.Delete = &types.Delete{
+		Objects: objects,
+		Quiet:   input.Delete.Quiet,
+	}
+	sres, err := m.secondary.DeleteObjects(ctx, &in)
+	secondaryFailed("delete objects", err)
+	// errors already returned by the primary are not logged
+	failed := make(map[string]bool)
+	for _, e := range res.Error {
+		if e.Key != nil {
+			failed[*e.Key] = true
+		}
+	}
+	for _, e := range sres.Error {
+		if e.Key != nil && e.Message != nil && !failed[*e.Key] {
+			secondaryFailed("delete object "+*e.Key, errors.New(*e.Message))
+		}
+	}
+
+	return res, nil
+}
+
+func (m *Mirror) PutObjectAcl(ctx context.Context, bucket, object string, data []byte) error {
+	return m.write("put object acl", func(be backend.Backend) error {
+		return be.PutObjectAcl(ctx, bucket, object, data)
+	})
+}
+
+func (m *Mirror) ListObjectVersions(ctx context.Context, input *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
+	return read(m, func(be backend.Backend) (s3response.ListVersionsResult, error) {
+		return be.ListObjectVersions(ctx, input)
+	})
+}
+
+func (m *Mirror) RestoreObject(ctx context.Context, input *s3.RestoreObjectInput) error {
+	return m.write("restore object", func(be backend.Backend) error {
+		return be.RestoreObject(ctx, input)
+	})
+}
+
+func (m *Mirror) SelectObjectContent(ctx context.Context, input *s3.SelectObjectContentInput) func(w *bufio.Writer) {
+	return m.primary.SelectObjectContent(ctx, input)
+}
+
+func (m *Mirror) GetBucketTagging(ctx context.Context, bucket string) (map[string]string, error) {
+	return read(m, func(be backend.Backend) (map[string]string, error) {
+		return be.GetBucketTagging(ctx, bucket)
+	})
+}
+
+func (m *Mirror) PutBucketTagging(ctx context.Context, bucket string, tags map[string]string) error {
+	return m.write("put bucket tagging", func(be backend.Backend) error {
+		return be.PutBucketTagging(ctx, bucket, tags)
+	})
+}
+
+func (m *Mirror) DeleteBucketTagging(ctx context.Context, bucket string) error {
+	return m.write("delete bucket tagging", func(be backend.Backend) error {
+		return be.DeleteBucketTagging(ctx, bucket)
+	})
+}
+
+func (m *Mirror) GetObjectTagging(ctx context.Context, bucket, object string) (map[string]string, error) {
+	return read(m, func(be backend.Backend) (map[string]string, error) {
+		return be.GetObjectTagging(ctx, bucket, object)
+	})
+}
+
+func (m *Mirror) PutObjectTagging(ctx context.Context, bucket, object string, tags map[string]string) error {
+	return m.write("put object tagging", func(be backend.Backend) error {
+		return be.PutObjectTagging(ctx, bucket, object, tags)
+	})
+}
+
+func (m *Mirror) DeleteObjectTagging(ctx context.Context, bucket, object string) error {
+	return m.write("delete object tagging", func(be backend.Backend) error {
+		return be.DeleteObjectTagging(ctx, bucket, object)
+	})
+}
+
+func (m *Mirror) PutObjectLockConfiguration(ctx context.Context, bucket string, config []byte) error {
+	return m.write("put object lock configuration", func(be backend.Backend) error {
+		return be.PutObjectLockConfiguration(ctx, bucket, config)
+	})
+}
+
+func (m *Mirror) GetObjectLockConfiguration(ctx context.Context, bucket string) ([]byte, error) {
+	return read(m, func(be backend.Backend) ([]byte, error) {
+		return be.GetObjectLockConfiguration(ctx, bucket)
+	})
+}
+
+func (m *Mirror) PutObjectRetention(ctx context.Context, bucket, object, versionId string, retention []byte) error {
+	err := m.primary.PutObjectRetention(ctx, bucket, object, versionId, retention)
+	if err != nil || versioned(&versionId) {
+		return err
+	}
+	secondaryFailed("put object retention",
+		m.secondary.PutObjectRetention(ctx, bucket, object, versionId, retention))
+	return nil
+}
+
+func (m *Mirror) GetObjectRetention(ctx context.Context, bucket, object, versionId string) ([]byte, error) {
+	return read(m, func(be backend.Backend) ([]byte, error) {
+		return be.GetObjectRetention(ctx, bucket, object, versionId)
+	})
+}
+
+func (m *Mirror) PutObjectLegalHold(ctx context.Context, bucket, object, versionId string, status bool) error {
+	err := m.primary.PutObjectLegalHold(ctx, bucket, object, versionId, status)
+	if err != nil || versioned(&versionId) {
+		return err
+	}
+	secondaryFailed("put object legal hold",
+		m.secondary.PutObjectLegalHold(ctx, bucket, object, versionId, status))
+	return nil
+}
+
+func (m *Mirror) GetObjectLegalHold(ctx context.Context, bucket, object, versionId string) (*bool, error) {
+	return read(m, func(be backend.Backend) (*bool, error) {
+		return be.GetObjectLegalHold(ctx, bucket, object, versionId)
+	})
+}
+
+func (m *Mirror) ChangeBucketOwner(ctx context.Context, bucket, newOwner string) error {
+	return m.write("change bucket owner", func(be backend.Backend) error {
+		return be.ChangeBucketOwner(ctx, bucket, newOwner)
+	})
+}
+
+func (m *Mirror) ChownBucket(ctx context.Context, bucket string, uid, gid int) error {
+	return m.write("chown bucket", func(be backend.Backend) error {
+		return be.ChownBucket(ctx, bucket, uid, gid)
+	})
+}
+
+func (m *Mirror) ListBucketsAndOwners(ctx context.Context) ([]s3response.Bucket, error) {
+	return read(m, func(be backend.Backend) ([]s3response.Bucket, error) {
+		return be.ListBucketsAndOwners(ctx)
+	})
+}
+
+func (m *Mirror) GetBucketUsage(ctx context.Context, bucket string) (s3response.BucketUsage, error) {
+	return read(m, func(be backend.Backend) (s3response.BucketUsage, error) {
+		return be.GetBucketUsage(ctx, bucket)
+	})
+}
+
+func (m *Mirror) PutBucketQuota(ctx context.Context, bucket string, quota s3response.BucketQuota) error {
+	return m.write("put bucket quota", func(be backend.Backend) error {
+		return be.PutBucketQuota(ctx, bucket, quota)
+	})
+}
+
+func (m *Mirror) GetBucketQuota(ctx context.Context, bucket string) (s3response.BucketQuota, error) {
+	return read(m, func(be backend.Backend) (s3response.BucketQuota, error) {
+		return be.GetBucketQuota(ctx, bucket)
+	})
+}
+
+// The trash entry ids are assigned independently by each backend, so
+// the trash of the mirrored backends is not managed through the mirror
+
+func (m *Mirror) ListTrash(_ context.Context, bucket string) ([]s3response.TrashEntry, error) {
+	return nil, s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+
+func (m *Mirror) RestoreTrash(_ context.Context, bucket, id string) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+
+func (m *Mirror) PurgeTrash(_ context.Context, bucket, id string) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+
+func (m *Mirror) GetScrubStatus(ctx context.Context) (s3response.ScrubStatus, error) {
+	return read(m, func(be backend.Backend) (s3response.ScrubStatus, error) {
+		return be.GetScrubStatus(ctx)
+	})
+}
@@ -0,0 +1,250 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/memstore"
+	"github.com/versity/versitygw/s3err"
+)
+
+// downBackend fails all reads as an unavailable filesystem would
+type downBackend struct {
+	backend.Backend
+	down bool
+}
+
+var errDown = errors.New("input/output error")
+
+func (d *downBackend) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	if d.down {
+		return nil, errDown
+	}
+	return d.Backend.HeadObject(ctx, input)
+}
+
+func (d *downBackend) GetObject(ctx context.Context, input *s3.GetObjectInput, w io.Writer) (*s3.GetObjectOutput, error) {
+	if d.down {
+		return nil, errDown
+	}
+	return d.Backend.GetObject(ctx, input, w)
+}
+
+func putObject(t *testing.T, be backend.Backend, bucket, key, data string) {
+	t.Helper()
+	size := int64(len(data))
+	_, err := be.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:        &bucket,
+		Key:           &key,
+		Body:          strings.NewReader(data),
+		ContentLength: &size,
+	})
+	if err != nil {
+		t.Fatalf("put %v: %v", key, err)
+	}
+}
+
+func getObject(be backend.Backend, bucket, key string) (string, error) {
+	var buf bytes.Buffer
+	_, err := be.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	}, &buf)
+	return buf.String(), err
+}
+
+func newTestMirror(t *testing.T) (*Mirror, *downBackend, backend.Backend) {
+	t.Helper()
+	primary := &downBackend{Backend: memstore.New()}
+	secondary := memstore.New()
+	m := New(primary, secondary)
+
+	err := m.CreateBucket(context.Background(), &s3.CreateBucketInput{
+		Bucket: backend.GetStringPtr("bucket"),
+	}, []byte(`{"Owner":"owner"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, primary, secondary
+}
+
+func TestMirrorWrite(t *testing.T) {
+	ctx := context.Background()
+	m, primary, secondary := newTestMirror(t)
+
+	putObject(t, m, "bucket", "obj", "mirrored data")
+	for _, be := range []backend.Backend{primary, secondary} {
+		data, err := getObject(be, "bucket", "obj")
+		if err != nil || data != "mirrored data" {
+			t.Errorf("%v: got %q, %v", be, data, err)
+		}
+	}
+
+	err := m.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: backend.GetStringPtr("bucket"),
+		Key:    backend.GetStringPtr("obj"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = getObject(secondary, "bucket", "obj")
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchKey)) {
+		t.Errorf("expected object deleted from secondary, got %v", err)
+	}
+}
+
+func TestMirrorMultipart(t *testing.T) {
+	ctx := context.Background()
+	m, _, secondary := newTestMirror(t)
+
+	bucket, key := "bucket", "mp"
+	out, err := m.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := []string{
+		strings.Repeat("a", backend.MinPartSize),
+		strings.Repeat("b", backend.MinPartSize),
+		"cc",
+	}
+	var completed []types.CompletedPart
+	for i, data := range parts {
+		pn := int32(i + 1)
+		size := int64(len(data))
+		etag, err := m.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        &bucket,
+			Key:           &key,
+			UploadId:      out.UploadId,
+			PartNumber:    &pn,
+			Body:          strings.NewReader(data),
+			ContentLength: &size,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		completed = append(completed, types.CompletedPart{
+			ETag:       &etag,
+			PartNumber: &pn,
+		})
+	}
+
+	_, err = m.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &bucket,
+		Key:             &key,
+		UploadId:        out.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := getObject(secondary, bucket, key)
+	if err != nil || data != strings.Join(parts, "") {
+		t.Errorf("secondary got %v bytes, %v", len(data), err)
+	}
+}
+
+func TestMirrorFailover(t *testing.T) {
+	m, primary, _ := newTestMirror(t)
+
+	putObject(t, m, "bucket", "obj", "data")
+
+	primary.down = true
+	data, err := getObject(m, "bucket", "obj")
+	if err != nil || data != "data" {
+		t.Errorf("expected read from secondary, got %q, %v", data, err)
+	}
+
+	// S3 errors are returned without failover
+	primary.down = false
+	_, err = getObject(m, "bucket", "missing")
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchKey)) {
+		t.Errorf("expected NoSuchKey, got %v", err)
+	}
+}
+
+func TestMirrorRepair(t *testing.T) {
+	ctx := context.Background()
+	m, primary, secondary := newTestMirror(t)
+
+	putObject(t, m, "bucket", "same", "same")
+	// writes missed by the secondary
+	putObject(t, primary, "bucket", "missing", "missing")
+	putObject(t, primary, "bucket", "changed", "new data")
+	putObject(t, secondary, "bucket", "changed", "old data")
+	putObject(t, secondary, "bucket", "extra", "extra")
+	err := primary.CreateBucket(ctx, &s3.CreateBucketInput{
+		Bucket: backend.GetStringPtr("newbucket"),
+	}, []byte(`{"Owner":"owner"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	putObject(t, primary, "newbucket", "obj", "obj")
+
+	stats, err := m.Repair(ctx, RepairOpts{DryRun: true, Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Copied != 3 || stats.Deleted != 1 {
+		t.Errorf("dry run: expected 3 copied and 1 deleted, got %+v", stats)
+	}
+	if _, err := getObject(secondary, "bucket", "missing"); err == nil {
+		t.Errorf("dry run copied an object")
+	}
+
+	stats, err = m.Repair(ctx, RepairOpts{Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Copied != 3 || stats.Deleted != 1 || stats.Failed != 0 {
+		t.Errorf("expected 3 copied and 1 deleted, got %+v", stats)
+	}
+
+	for _, obj := range []struct{ bucket, key, data string }{
+		{"bucket", "same", "same"},
+		{"bucket", "missing", "missing"},
+		{"bucket", "changed", "new data"},
+		{"newbucket", "obj", "obj"},
+	} {
+		data, err := getObject(secondary, obj.bucket, obj.key)
+		if err != nil || data != obj.data {
+			t.Errorf("%v/%v: got %q, %v", obj.bucket, obj.key, data, err)
+		}
+	}
+	if _, err := getObject(secondary, "bucket", "extra"); err == nil {
+		t.Errorf("expected extra object deleted")
+	}
+
+	stats, err = m.Repair(ctx, RepairOpts{Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Copied != 0 || stats.Deleted != 0 {
+		t.Errorf("expected nothing to repair, got %+v", stats)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3response"
+)
+
+// upload tracks the secondary multipart upload mirroring a primary
+// upload. The client only sees the primary upload id and part etags.
+type upload struct {
+	id    string
+	etags map[int32]string
+}
+
+func (m *Mirror) getUpload(uploadId *string) *upload {
+	if uploadId == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.uploads[*uploadId]
+}
+
+func (m *Mirror) setPartEtag(up *upload, partNumber *int32, etag string) {
+	if partNumber == nil {
+		return
+	}
+	m.mu.Lock()
+	up.etags[*partNumber] = etag
+	m.mu.Unlock()
+}
+
+func (m *Mirror) removeUpload(uploadId *string) *upload {
+	if uploadId == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	up := m.uploads[*uploadId]
+	delete(m.uploads, *uploadId)
+	return up
+}
+
+func (m *Mirror) CreateMultipartUpload(ctx context.Context, input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	out, err := m.primary.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	sout, err := m.secondary.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		secondaryFailed("create multipart upload", err)
+		return out, nil
+	}
+
+	m.mu.Lock()
+	m.uploads[*out.UploadId] = &upload{
+		id:    *sout.UploadId,
+		etags: make(map[int32]string),
+	}
+	m.mu.Unlock()
+
+	return out, nil
+}
+
+func (m *Mirror) UploadPart(ctx context.Context, input *s3.UploadPartInput) (string, error) {
+	up := m.getUpload(input.UploadId)
+	if up == nil {
+		return m.primary.UploadPart(ctx, input)
+	}
+
+	var etag, setag string
+	perr, serr := dualWrite(input.Body,
+		func(r io.Reader) error {
+			in := *input
+			in.Body = r
+			var err error
+			etag, err = m.primary.UploadPart(ctx, &in)
+			return err
+		},
+		func(r io.Reader) error {
+			in := *input
+			in.Body = r
+			in.UploadId = &up.id
+			var err error
+			setag, err = m.secondary.UploadPart(ctx, &in)
+			return err
+		})
+	if perr != nil {
+		return "", perr
+	}
+	if serr != nil {
+		secondaryFailed("upload part", serr)
+		return etag, nil
+	}
+
+	m.setPartEtag(up, input.PartNumber, setag)
+	return etag, nil
+}
+
+func (m *Mirror) UploadPartCopy(ctx context.Context, input *s3.UploadPartCopyInput) (s3response.CopyObjectResult, error) {
+	res, err := m.primary.UploadPartCopy(ctx, input)
+	if err != nil {
+		return res, err
+	}
+
+	up := m.getUpload(input.UploadId)
+	if up == nil {
+		return res, nil
+	}
+	_, _, versionId, err := backend.ParseCopySource(input.CopySource)
+	if err != nil || versioned(&versionId) {
+		return res, nil
+	}
+
+	in := *input
+	in.UploadId = &up.id
+	sres, err := m.secondary.UploadPartCopy(ctx, &in)
+	if err != nil {
+		secondaryFailed("upload part copy", err)
+		return res, nil
+	}
+
+	m.setPartEtag(up, input.PartNumber, sres.ETag)
+	return res, nil
+}
+
+func (m *Mirror) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	out, err := m.primary.CompleteMultipartUpload(ctx, input)
+	if err != nil {
+		return out, err
+	}
+
+	up := m.removeUpload(input.UploadId)
+	if up == nil {
+		secondaryFailed("complete multipart upload",
+			fmt.Errorf("no secondary upload for %v/%v", *input.Bucket, *input.Key))
+		return out, nil
+	}
+
+	in := *input
+	in.UploadId = &up.id
+	if input.MultipartUpload != nil {
+		parts := make([]types.CompletedPart, 0, len(input.MultipartUpload.Parts))
+		for _, p := range input.MultipartUpload.Parts {
+			if p.PartNumber == nil {
+				continue
+			}
+			etag, ok := up.etags[*p.PartNumber]
+			if !ok {
+				secondaryFailed("complete multipart upload",
+					fmt.Errorf("missing part %v of %v/%v", *p.PartNumber, *input.Bucket, *input.Key))
+				m.secondary.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+					Bucket:   input.Bucket,
+					Key:      input.Key,
+					UploadId: &up.id,
+				})
+				return out, nil
+			}
+			p.ETag = &etag
+			parts = append(parts, p)
+		}
+		in.MultipartUpload = &types.CompletedMultipartUpload{Parts: parts}
+	}
+
+	_, err = m.secondary.CompleteMultipartUpload(ctx, &in)
+	secondaryFailed("complete multipart upload", err)
+	return out, nil
+}
+
+func (m *Mirror) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) error {
+	err := m.primary.AbortMultipartUpload(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	up := m.removeUpload(input.UploadId)
+	if up == nil {
+		return nil
+	}
+
+	in := *input
+	in.UploadId = &up.id
+	secondaryFailed("abort multipart upload", m.secondary.AbortMultipartUpload(ctx, &in))
+	return nil
+}
+
+// ListMultipartUploads and ListParts only list the primary, the secondary
+// upload ids are not known to the client.
+
+func (m *Mirror) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (s3response.ListMultipartUploadsResult, error) {
+	return m.primary.ListMultipartUploads(ctx, input)
+}
+
+func (m *Mirror) ListParts(ctx context.Context, input *s3.ListPartsInput) (s3response.ListPartsResult, error) {
+	return m.primary.ListParts(ctx, input)
+}
@@ -0,0 +1,342 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+// RepairOpts controls a mirror repair
+type RepairOpts struct {
+	// Bucket limits the repair to a single bucket, all buckets
+	// are repaired if empty
+	Bucket string
+	// Delete removes secondary objects missing from the primary
+	Delete bool
+	// DryRun reports the changes without making them
+	DryRun bool
+	// Report is called for every bucket and object changed
+	Report func(action, bucket, object string)
+}
+
+// RepairStats summarizes a mirror repair
+type RepairStats struct {
+	Buckets int
+	Objects int
+	Copied  int
+	Deleted int
+	Failed  int
+}
+
+// Repair brings the secondary back in sync with the primary after
+// failed secondary writes. The primary is the source of truth: missing
+// buckets are created, and missing or differing objects are copied to
+// the secondary. Objects are compared by size and etag. Only the current
+// object versions are repaired.
+func (m *Mirror) Repair(ctx context.Context, opts RepairOpts) (RepairStats, error) {
+	var stats RepairStats
+
+	report := opts.Report
+	if report == nil {
+		report = func(string, string, string) {}
+	}
+
+	buckets, err := m.primary.ListBucketsAndOwners(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("list primary buckets: %w", err)
+	}
+
+	found := false
+	for _, b := range buckets {
+		if opts.Bucket != "" && b.Name != opts.Bucket {
+			continue
+		}
+		found = true
+		stats.Buckets++
+
+		err := m.repairBucket(ctx, b.Name, opts, report, &stats)
+		if err != nil {
+			return stats, fmt.Errorf("repair bucket %v: %w", b.Name, err)
+		}
+	}
+
+	if opts.Bucket != "" && !found {
+		return stats, fmt.Errorf("bucket %v not found on primary", opts.Bucket)
+	}
+
+	return stats, nil
+}
+
+func (m *Mirror) repairBucket(ctx context.Context, bucket string, opts RepairOpts, report func(string, string, string), stats *RepairStats) error {
+	_, err := m.secondary.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: &bucket})
+	if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)) {
+		report("create", bucket, "")
+		if opts.DryRun {
+			// nothing to compare against
+			return m.walk(ctx, m.primary, bucket, func(obj types.Object) error {
+				stats.Objects++
+				stats.Copied++
+				report("copy", bucket, *obj.Key)
+				return nil
+			})
+		}
+		err = m.createBucket(ctx, bucket)
+	}
+	if err != nil {
+		return err
+	}
+
+	prim := &lister{be: m.primary, bucket: bucket}
+	sec := &lister{be: m.secondary, bucket: bucket}
+	for {
+		p, err := prim.peek(ctx)
+		if err != nil {
+			return err
+		}
+		s, err := sec.peek(ctx)
+		if err != nil {
+			return err
+		}
+		if p == nil && s == nil {
+			return nil
+		}
+
+		switch {
+		case p != nil && (s == nil || *p.Key < *s.Key):
+			// missing from secondary
+			stats.Objects++
+			m.repairObject(ctx, bucket, *p.Key, opts, report, stats)
+			prim.pop()
+		case s != nil && (p == nil || *s.Key < *p.Key):
+			// missing from primary
+			sec.pop()
+			if !opts.Delete {
+				continue
+			}
+			report("delete", bucket, *s.Key)
+			if opts.DryRun {
+				stats.Deleted++
+				continue
+			}
+			err := m.secondary.DeleteObject(ctx, &s3.DeleteObjectInput{
+				Bucket: &bucket,
+				Key:    s.Key,
+			})
+			if err != nil {
+				report(fmt.Sprintf("failed: %v", err), bucket, *s.Key)
+				stats.Failed++
+				continue
+			}
+			stats.Deleted++
+		default:
+			stats.Objects++
+			if !same(p, s) {
+				m.repairObject(ctx, bucket, *p.Key, opts, report, stats)
+			}
+			prim.pop()
+			sec.pop()
+		}
+	}
+}
+
+func (m *Mirror) createBucket(ctx context.Context, bucket string) error {
+	acl, err := m.primary.GetBucketAcl(ctx, &s3.GetBucketAclInput{Bucket: &bucket})
+	if err != nil {
+		return err
+	}
+
+	lockEnabled := false
+	_, err = m.primary.GetObjectLockConfiguration(ctx, bucket)
+	if err == nil {
+		lockEnabled = true
+	}
+
+	err = m.secondary.CreateBucket(ctx, &s3.CreateBucketInput{
+		Bucket:                     &bucket,
+		ObjectLockEnabledForBucket: &lockEnabled,
+	}, acl)
+	if err != nil {
+		return fmt.Errorf("create secondary bucket: %w", err)
+	}
+
+	policy, err := m.primary.GetBucketPolicy(ctx, bucket)
+	if err == nil && len(policy) > 0 {
+		err = m.secondary.PutBucketPolicy(ctx, bucket, policy)
+		if err != nil {
+			return fmt.Errorf("put secondary bucket policy: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// same compares the listed objects. Multipart etags depend on the part
+// sizes the object was uploaded with, and a repaired copy is written
+// with a single put, so only the sizes of multipart objects are compared.
+func same(p, s *types.Object) bool {
+	if p.Size == nil || s.Size == nil || *p.Size != *s.Size {
+		return false
+	}
+	if p.ETag == nil || s.ETag == nil {
+		return false
+	}
+	if strings.Contains(*p.ETag, "-") {
+		return true
+	}
+	return *p.ETag == *s.ETag
+}
+
+func (m *Mirror) repairObject(ctx context.Context, bucket, object string, opts RepairOpts, report func(string, string, string), stats *RepairStats) {
+	report("copy", bucket, object)
+	if opts.DryRun {
+		stats.Copied++
+		return
+	}
+
+	err := m.copyObject(ctx, bucket, object)
+	if err != nil {
+		report(fmt.Sprintf("failed: %v", err), bucket, object)
+		stats.Failed++
+		return
+	}
+	stats.Copied++
+}
+
+// copyObject copies the primary object data, metadata, tags and acl to
+// the secondary
+func (m *Mirror) copyObject(ctx context.Context, bucket, object string) error {
+	head, err := m.primary.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &bucket,
+		Key:    &object,
+	})
+	if err != nil {
+		return err
+	}
+
+	// pin the read to the object that was just looked up, in case it
+	// changes during the copy
+	err = backend.PipeObject(
+		func(w io.Writer) error {
+			_, err := m.primary.GetObject(ctx, &s3.GetObjectInput{
+				Bucket:  &bucket,
+				Key:     &object,
+				IfMatch: head.ETag,
+			}, w)
+			return err
+		},
+		func(r io.Reader) error {
+			_, err := m.secondary.PutObject(ctx, &s3.PutObjectInput{
+				Bucket:             &bucket,
+				Key:                &object,
+				Body:               r,
+				ContentLength:      head.ContentLength,
+				ContentType:        head.ContentType,
+				ContentEncoding:    head.ContentEncoding,
+				ContentDisposition: head.ContentDisposition,
+				ContentLanguage:    head.ContentLanguage,
+				CacheControl:       head.CacheControl,
+				Expires:            head.Expires,
+				Metadata:           head.Metadata,
+			})
+			return err
+		})
+	if err != nil {
+		return err
+	}
+
+	tags, err := m.primary.GetObjectTagging(ctx, bucket, object)
+	if err == nil && len(tags) > 0 {
+		err = m.secondary.PutObjectTagging(ctx, bucket, object, tags)
+		if err != nil {
+			return fmt.Errorf("put tags: %w", err)
+		}
+	}
+
+	acl, err := m.primary.GetObjectAcl(ctx, &s3.GetObjectAclInput{
+		Bucket: &bucket,
+		Key:    &object,
+	})
+	if err == nil && len(acl) > 0 {
+		err = m.secondary.PutObjectAcl(ctx, bucket, object, acl)
+		if err != nil && !errors.Is(err, s3err.GetAPIError(s3err.ErrNotImplemented)) {
+			return fmt.Errorf("put acl: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// walk calls fn for every object in the bucket
+func (m *Mirror) walk(ctx context.Context, be backend.Backend, bucket string, fn func(types.Object) error) error {
+	l := &lister{be: be, bucket: bucket}
+	for {
+		obj, err := l.peek(ctx)
+		if err != nil || obj == nil {
+			return err
+		}
+		err = fn(*obj)
+		if err != nil {
+			return err
+		}
+		l.pop()
+	}
+}
+
+// lister pages through a bucket listing in key order
+type lister struct {
+	be     backend.Backend
+	bucket string
+	token  *string
+	objs   []types.Object
+	done   bool
+}
+
+// peek returns the next object in the listing, or nil at the end
+func (l *lister) peek(ctx context.Context) (*types.Object, error) {
+	for len(l.objs) == 0 {
+		if l.done {
+			return nil, nil
+		}
+		maxKeys := int32(1000)
+		out, err := l.be.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            &l.bucket,
+			ContinuationToken: l.token,
+			MaxKeys:           &maxKeys,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list objects: %w", err)
+		}
+		l.objs = out.Contents
+		if out.IsTruncated == nil || !*out.IsTruncated || out.NextContinuationToken == nil {
+			l.done = true
+		} else {
+			l.token = out.NextContinuationToken
+		}
+	}
+	return &l.objs[0], nil
+}
+
+func (l *lister) pop() {
+	l.objs = l.objs[1:]
+}
@@ -16,7 +16,6 @@ import (
 )
 
 var (
-	// TODO: make this configurable
 	defaultDirPerm fs.FileMode = 0755
 )
 
@@ -33,6 +32,14 @@ var (
 // Any directoy created will be set to provided uid/gid ownership
 // if doChown is true.
 func MkdirAll(path string, uid, gid int, doChown bool) error {
+	return MkdirAllPerm(path, defaultDirPerm, false, uid, gid, doChown)
+}
+
+// MkdirAllPerm is MkdirAll creating the directories with the permission
+// bits perm (before umask). The setgid and sticky bits of perm are set
+// on the new directories regardless of the parent directory. With exact,
+// the new directories are set to perm regardless of the process umask.
+func MkdirAllPerm(path string, perm fs.FileMode, exact bool, uid, gid int, doChown bool) error {
 	// Fast path: if we can tell whether path is a directory or file, stop with success or error.
 	dir, err := os.Stat(path)
 	if err == nil {
@@ -55,14 +62,14 @@ func MkdirAll(path string, uid, gid int, doChown bool) error {
 
 	if j > 1 {
 		// Create parent.
-		err = MkdirAll(path[:j-1], uid, gid, doChown)
+		err = MkdirAllPerm(path[:j-1], perm, exact, uid, gid, doChown)
 		if err != nil {
 			return err
 		}
 	}
 
 	// Parent now exists; invoke Mkdir and use its result.
-	err = os.Mkdir(path, defaultDirPerm)
+	err = os.Mkdir(path, perm)
 	if err != nil {
 		// Handle arguments like "foo/." by
 		// double-checking that directory doesn't exist.
@@ -78,5 +85,21 @@ func MkdirAll(path string, uid, gid int, doChown bool) error {
 			return err
 		}
 	}
+	if exact {
+		err = os.Chmod(path, perm)
+		if err != nil {
+			return err
+		}
+	} else if special := perm & (fs.ModeSetgid | fs.ModeSticky); special != 0 {
+		// mkdir only inherits setgid from the parent directory
+		dir, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		err = os.Chmod(path, dir.Mode().Perm()|special)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
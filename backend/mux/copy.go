@@ -0,0 +1,236 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mux
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+// CopyObject copies within the member backend when both buckets are
+// routed to it, otherwise the data is streamed between the members
+func (m *Mux) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	dstBe, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	srcBucket, srcObject, versionId, err := backend.ParseCopySource(input.CopySource)
+	if err != nil {
+		return nil, err
+	}
+	srcBe, err := m.route(srcBucket)
+	if err != nil {
+		return nil, err
+	}
+	if srcBe == dstBe {
+		return dstBe.CopyObject(ctx, input)
+	}
+
+	head, err := srcHead(ctx, srcBe, srcBucket, srcObject, versionId,
+		input.CopySourceIfMatch, input.CopySourceIfNoneMatch,
+		input.CopySourceIfModifiedSince, input.CopySourceIfUnmodifiedSince)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := head.Metadata
+	contentType := head.ContentType
+	contentEncoding := head.ContentEncoding
+	if input.MetadataDirective == types.MetadataDirectiveReplace {
+		meta = input.Metadata
+		contentType = input.ContentType
+		contentEncoding = input.ContentEncoding
+	}
+
+	storageClass := head.StorageClass
+	if input.StorageClass != "" {
+		storageClass = input.StorageClass
+	}
+
+	// the source tags are read before the put in case the member
+	// backends share the storage
+	var tagging *string
+	var tags map[string]string
+	if input.TaggingDirective == types.TaggingDirectiveReplace {
+		tagging = input.Tagging
+	} else {
+		tags, err = srcBe.GetObjectTagging(ctx, srcBucket, srcObject)
+		if err != nil && !errors.Is(err, s3err.GetAPIError(s3err.ErrBucketTaggingNotFound)) {
+			return nil, err
+		}
+	}
+
+	var etag string
+	err = backend.PipeObject(
+		func(w io.Writer) error {
+			_, err := srcBe.GetObject(ctx, &s3.GetObjectInput{
+				Bucket:    &srcBucket,
+				Key:       &srcObject,
+				VersionId: &versionId,
+				IfMatch:   head.ETag,
+			}, w)
+			return err
+		},
+		func(r io.Reader) error {
+			var err error
+			etag, err = dstBe.PutObject(ctx, &s3.PutObjectInput{
+				Bucket:                    input.Bucket,
+				Key:                       input.Key,
+				Body:                      r,
+				ContentLength:             head.ContentLength,
+				ContentType:               contentType,
+				ContentEncoding:           contentEncoding,
+				Metadata:                  meta,
+				Tagging:                   tagging,
+				StorageClass:              storageClass,
+				ACL:                       input.ACL,
+				GrantFullControl:          input.GrantFullControl,
+				GrantRead:                 input.GrantRead,
+				GrantReadACP:              input.GrantReadACP,
+				GrantWriteACP:             input.GrantWriteACP,
+				ObjectLockMode:            input.ObjectLockMode,
+				ObjectLockRetainUntilDate: input.ObjectLockRetainUntilDate,
+				ObjectLockLegalHoldStatus: input.ObjectLockLegalHoldStatus,
+			})
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) > 0 {
+		err = dstBe.PutObjectTagging(ctx, *input.Bucket, *input.Key, tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{
+			ETag:         &etag,
+			LastModified: backend.GetTimePtr(time.Now()),
+		},
+	}, nil
+}
+
+// UploadPartCopy copies within the member backend when both buckets are
+// routed to it, otherwise the data is streamed between the members
+func (m *Mux) UploadPartCopy(ctx context.Context, input *s3.UploadPartCopyInput) (s3response.CopyObjectResult, error) {
+	dstBe, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+	srcBucket, srcObject, versionId, err := backend.ParseCopySource(input.CopySource)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+	srcBe, err := m.route(srcBucket)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+	if srcBe == dstBe {
+		return dstBe.UploadPartCopy(ctx, input)
+	}
+
+	head, err := srcHead(ctx, srcBe, srcBucket, srcObject, versionId,
+		input.CopySourceIfMatch, input.CopySourceIfNoneMatch,
+		input.CopySourceIfModifiedSince, input.CopySourceIfUnmodifiedSince)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	var size int64
+	if head.ContentLength != nil {
+		size = *head.ContentLength
+	}
+	var copyRange string
+	if input.CopySourceRange != nil {
+		copyRange = *input.CopySourceRange
+	}
+	_, length, err := backend.ParseCopySourceRange(size, copyRange)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	var etag string
+	err = backend.PipeObject(
+		func(w io.Writer) error {
+			_, err := srcBe.GetObject(ctx, &s3.GetObjectInput{
+				Bucket:    &srcBucket,
+				Key:       &srcObject,
+				VersionId: &versionId,
+				Range:     input.CopySourceRange,
+				IfMatch:   head.ETag,
+			}, w)
+			return err
+		},
+		func(r io.Reader) error {
+			var err error
+			etag, err = dstBe.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:        input.Bucket,
+				Key:           input.Key,
+				UploadId:      input.UploadId,
+				PartNumber:    input.PartNumber,
+				ContentLength: &length,
+				Body:          r,
+			})
+			return err
+		})
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	return s3response.CopyObjectResult{
+		ETag:         etag,
+		LastModified: time.Now(),
+	}, nil
+}
+
+// srcHead returns the copy source object after checking the copy source
+// preconditions
+func srcHead(ctx context.Context, be backend.Backend, bucket, object, versionId string, ifMatch, ifNoneMatch *string, ifModifiedSince, ifUnmodifiedSince *time.Time) (*s3.HeadObjectOutput, error) {
+	head, err := be.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:    &bucket,
+		Key:       &object,
+		VersionId: &versionId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var etag string
+	if head.ETag != nil {
+		etag = *head.ETag
+	}
+	var modTime time.Time
+	if head.LastModified != nil {
+		modTime = *head.LastModified
+	}
+	err = backend.EvaluateCopySourcePreconditions(etag, modTime,
+		ifMatch, ifNoneMatch, ifModifiedSince, ifUnmodifiedSince)
+	if err != nil {
+		return nil, err
+	}
+
+	return head, nil
+}
@@ -0,0 +1,644 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mux
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+// Route maps the bucket names matching the pattern to a backend. The
+// pattern uses the path.Match syntax, for example "archive-*".
+type Route struct {
+	Pattern string
+	Backend backend.Backend
+}
+
+// Mux is a backend routing each bucket to one of several member backends
+// by the bucket name. The routes are checked in order and the first
+// matching route owns the bucket, buckets matching no route do not
+// exist. Listing the buckets merges the buckets of all members.
+type Mux struct {
+	routes  []Route
+	members []backend.Backend
+}
+
+var _ backend.Backend = &Mux{}
+
+func New(routes []Route) (*Mux, error) {
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no bucket routes specified")
+	}
+
+	m := &Mux{}
+	for _, r := range routes {
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid bucket pattern %q: %w", r.Pattern, err)
+		}
+		if r.Backend == nil {
+			return nil, fmt.Errorf("no backend for bucket pattern %q", r.Pattern)
+		}
+		m.routes = append(m.routes, r)
+
+		member := false
+		for _, be := range m.members {
+			if be == r.Backend {
+				member = true
+				break
+			}
+		}
+		if !member {
+			m.members = append(m.members, r.Backend)
+		}
+	}
+
+	return m, nil
+}
+
+// route returns the backend owning the bucket
+func (m *Mux) route(bucket string) (backend.Backend, error) {
+	for _, r := range m.routes {
+		if ok, _ := path.Match(r.Pattern, bucket); ok {
+			return r.Backend, nil
+		}
+	}
+	return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+}
+
+func (m *Mux) routeInput(bucket *string) (backend.Backend, error) {
+	if bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	return m.route(*bucket)
+}
+
+func (m *Mux) String() string {
+	return "Multiplexing Gateway"
+}
+
+func (m *Mux) Shutdown() {
+	for _, be := range m.members {
+		be.Shutdown()
+	}
+}
+
+// ListBuckets merges the buckets of all members, leaving out the
+// buckets routed to another member
+func (m *Mux) ListBuckets(ctx context.Context, owner string, isAdmin bool) (s3response.ListAllMyBucketsResult, error) {
+	var buckets []s3response.ListAllMyBucketsEntry
+	for _, be := range m.members {
+		res, err := be.ListBuckets(ctx, owner, isAdmin)
+		if err != nil {
+			return s3response.ListAllMyBucketsResult{}, err
+		}
+		for _, b := range res.Buckets.Bucket {
+			if rbe, err := m.route(b.Name); err == nil && rbe == be {
+				buckets = append(buckets, b)
+			}
+		}
+	}
+
+	sort.Sort(backend.ByBucketName(buckets))
+
+	return s3response.ListAllMyBucketsResult{
+		Buckets: s3response.ListAllMyBucketsList{
+			Bucket: buckets,
+		},
+		Owner: s3response.CanonicalUser{
+			ID: owner,
+		},
+	}, nil
+}
+
+func (m *Mux) ListBucketsAndOwners(ctx context.Context) ([]s3response.Bucket, error) {
+	var buckets []s3response.Bucket
+	for _, be := range m.members {
+		res, err := be.ListBucketsAndOwners(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range res {
+			if rbe, err := m.route(b.Name); err == nil && rbe == be {
+				buckets = append(buckets, b)
+			}
+		}
+	}
+
+	sort.SliceStable(buckets, func(i, j int) bool {
+		return buckets[i].Name < buckets[j].Name
+	})
+
+	return buckets, nil
+}
+
+func (m *Mux) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.HeadBucket(ctx, input)
+}
+
+func (m *Mux) GetBucketAcl(ctx context.Context, input *s3.GetBucketAclInput) ([]byte, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetBucketAcl(ctx, input)
+}
+
+func (m *Mux) CreateBucket(ctx context.Context, input *s3.CreateBucketInput, defaultACL []byte) error {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		// buckets can only be created for the configured routes
+		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	return be.CreateBucket(ctx, input, defaultACL)
+}
+
+func (m *Mux) PutBucketAcl(ctx context.Context, bucket string, data []byte) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutBucketAcl(ctx, bucket, data)
+}
+
+func (m *Mux) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) error {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return err
+	}
+	return be.DeleteBucket(ctx, input)
+}
+
+func (m *Mux) PutBucketVersioning(ctx context.Context, input *s3.PutBucketVersioningInput) error {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutBucketVersioning(ctx, input)
+}
+
+func (m *Mux) GetBucketVersioning(ctx context.Context, bucket string) (s3response.GetBucketVersioningOutput, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return s3response.GetBucketVersioningOutput{}, err
+	}
+	return be.GetBucketVersioning(ctx, bucket)
+}
+
+func (m *Mux) PutBucketPolicy(ctx context.Context, bucket string, policy []byte) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutBucketPolicy(ctx, bucket, policy)
+}
+
+func (m *Mux) GetBucketPolicy(ctx context.Context, bucket string) ([]byte, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetBucketPolicy(ctx, bucket)
+}
+
+func (m *Mux) DeleteBucketPolicy(ctx context.Context, bucket string) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.DeleteBucketPolicy(ctx, bucket)
+}
+
+func (m *Mux) PutBucketNotificationConfiguration(ctx context.Context, bucket string, config []byte) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutBucketNotificationConfiguration(ctx, bucket, config)
+}
+
+func (m *Mux) GetBucketNotificationConfiguration(ctx context.Context, bucket string) ([]byte, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetBucketNotificationConfiguration(ctx, bucket)
+}
+
+func (m *Mux) PutBucketLogging(ctx context.Context, bucket string, config []byte) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutBucketLogging(ctx, bucket, config)
+}
+
+func (m *Mux) GetBucketLogging(ctx context.Context, bucket string) ([]byte, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetBucketLogging(ctx, bucket)
+}
+
+func (m *Mux) PutPublicAccessBlock(ctx context.Context, bucket string, config []byte) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutPublicAccessBlock(ctx, bucket, config)
+}
+
+func (m *Mux) GetPublicAccessBlock(ctx context.Context, bucket string) ([]byte, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetPublicAccessBlock(ctx, bucket)
+}
+
+func (m *Mux) DeletePublicAccessBlock(ctx context.Context, bucket string) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.DeletePublicAccessBlock(ctx, bucket)
+}
+
+func (m *Mux) CreateMultipartUpload(ctx context.Context, input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.CreateMultipartUpload(ctx, input)
+}
+
+func (m *Mux) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.CompleteMultipartUpload(ctx, input)
+}
+
+func (m *Mux) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) error {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return err
+	}
+	return be.AbortMultipartUpload(ctx, input)
+}
+
+func (m *Mux) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (s3response.ListMultipartUploadsResult, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return s3response.ListMultipartUploadsResult{}, err
+	}
+	return be.ListMultipartUploads(ctx, input)
+}
+
+func (m *Mux) ListParts(ctx context.Context, input *s3.ListPartsInput) (s3response.ListPartsResult, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return s3response.ListPartsResult{}, err
+	}
+	return be.ListParts(ctx, input)
+}
+
+func (m *Mux) UploadPart(ctx context.Context, input *s3.UploadPartInput) (string, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return "", err
+	}
+	return be.UploadPart(ctx, input)
+}
+
+func (m *Mux) PutObject(ctx context.Context, input *s3.PutObjectInput) (string, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return "", err
+	}
+	return be.PutObject(ctx, input)
+}
+
+func (m *Mux) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.HeadObject(ctx, input)
+}
+
+func (m *Mux) GetObject(ctx context.Context, input *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetObject(ctx, input, writer)
+}
+
+func (m *Mux) GetObjectAcl(ctx context.Context, input *s3.GetObjectAclInput) ([]byte, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetObjectAcl(ctx, input)
+}
+
+func (m *Mux) GetObjectAttributes(ctx context.Context, input *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return s3response.GetObjectAttributesResult{}, err
+	}
+	return be.GetObjectAttributes(ctx, input)
+}
+
+func (m *Mux) ListObjects(ctx context.Context, input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.ListObjects(ctx, input)
+}
+
+func (m *Mux) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.ListObjectsV2(ctx, input)
+}
+
+func (m *Mux) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) error {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return err
+	}
+	return be.DeleteObject(ctx, input)
+}
+
+func (m *Mux) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return s3response.DeleteResult{}, err
+	}
+	return be.DeleteObjects(ctx, input)
+}
+
+func (m *Mux) PutObjectAcl(ctx context.Context, bucket, object string, data []byte) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutObjectAcl(ctx, bucket, object, data)
+}
+
+func (m *Mux) ListObjectVersions(ctx context.Context, input *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return s3response.ListVersionsResult{}, err
+	}
+	return be.ListObjectVersions(ctx, input)
+}
+
+func (m *Mux) RestoreObject(ctx context.Context, input *s3.RestoreObjectInput) error {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return err
+	}
+	return be.RestoreObject(ctx, input)
+}
+
+func (m *Mux) SelectObjectContent(ctx context.Context, input *s3.SelectObjectContentInput) func(w *bufio.Writer) {
+	be, err := m.routeInput(input.Bucket)
+	if err != nil {
+		return backend.BackendUnsupported{}.SelectObjectContent(ctx, input)
+	}
+	return be.SelectObjectContent(ctx, input)
+}
+
+func (m *Mux) GetBucketTagging(ctx context.Context, bucket string) (map[string]string, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetBucketTagging(ctx, bucket)
+}
+
+func (m *Mux) PutBucketTagging(ctx context.Context, bucket string, tags map[string]string) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutBucketTagging(ctx, bucket, tags)
+}
+
+func (m *Mux) DeleteBucketTagging(ctx context.Context, bucket string) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.DeleteBucketTagging(ctx, bucket)
+}
+
+func (m *Mux) GetObjectTagging(ctx context.Context, bucket, object string) (map[string]string, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetObjectTagging(ctx, bucket, object)
+}
+
+func (m *Mux) PutObjectTagging(ctx context.Context, bucket, object string, tags map[string]string) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutObjectTagging(ctx, bucket, object, tags)
+}
+
+func (m *Mux) DeleteObjectTagging(ctx context.Context, bucket, object string) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.DeleteObjectTagging(ctx, bucket, object)
+}
+
+func (m *Mux) PutObjectLockConfiguration(ctx context.Context, bucket string, config []byte) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutObjectLockConfiguration(ctx, bucket, config)
+}
+
+func (m *Mux) GetObjectLockConfiguration(ctx context.Context, bucket string) ([]byte, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetObjectLockConfiguration(ctx, bucket)
+}
+
+func (m *Mux) PutObjectRetention(ctx context.Context, bucket, object, versionId string, retention []byte) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutObjectRetention(ctx, bucket, object, versionId, retention)
+}
+
+func (m *Mux) GetObjectRetention(ctx context.Context, bucket, object, versionId string) ([]byte, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetObjectRetention(ctx, bucket, object, versionId)
+}
+
+func (m *Mux) PutObjectLegalHold(ctx context.Context, bucket, object, versionId string, status bool) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutObjectLegalHold(ctx, bucket, object, versionId, status)
+}
+
+func (m *Mux) GetObjectLegalHold(ctx context.Context, bucket, object, versionId string) (*bool, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.GetObjectLegalHold(ctx, bucket, object, versionId)
+}
+
+func (m *Mux) ChangeBucketOwner(ctx context.Context, bucket, newOwner string) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.ChangeBucketOwner(ctx, bucket, newOwner)
+}
+
+func (m *Mux) ChownBucket(ctx context.Context, bucket string, uid, gid int) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.ChownBucket(ctx, bucket, uid, gid)
+}
+
+func (m *Mux) GetBucketUsage(ctx context.Context, bucket string) (s3response.BucketUsage, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return s3response.BucketUsage{}, err
+	}
+	return be.GetBucketUsage(ctx, bucket)
+}
+
+func (m *Mux) PutBucketQuota(ctx context.Context, bucket string, quota s3response.BucketQuota) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PutBucketQuota(ctx, bucket, quota)
+}
+
+func (m *Mux) GetBucketQuota(ctx context.Context, bucket string) (s3response.BucketQuota, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return s3response.BucketQuota{}, err
+	}
+	return be.GetBucketQuota(ctx, bucket)
+}
+
+func (m *Mux) ListTrash(ctx context.Context, bucket string) ([]s3response.TrashEntry, error) {
+	be, err := m.route(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return be.ListTrash(ctx, bucket)
+}
+
+func (m *Mux) RestoreTrash(ctx context.Context, bucket, id string) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.RestoreTrash(ctx, bucket, id)
+}
+
+func (m *Mux) PurgeTrash(ctx context.Context, bucket, id string) error {
+	be, err := m.route(bucket)
+	if err != nil {
+		return err
+	}
+	return be.PurgeTrash(ctx, bucket, id)
+}
+
+// GetScrubStatus combines the scrub status of the members running a
+// scrubber, the mismatches are ordered by detection time
+func (m *Mux) GetScrubStatus(ctx context.Context) (s3response.ScrubStatus, error) {
+	var status s3response.ScrubStatus
+	found := false
+	for _, be := range m.members {
+		st, err := be.GetScrubStatus(ctx)
+		if errors.Is(err, s3err.GetAPIError(s3err.ErrNotImplemented)) {
+			continue
+		}
+		if err != nil {
+			return s3response.ScrubStatus{}, err
+		}
+		found = true
+
+		status.Running = status.Running || st.Running
+		status.Passes += st.Passes
+		if st.LastStart.After(status.LastStart) {
+			status.LastStart = st.LastStart
+		}
+		if st.LastFinish.After(status.LastFinish) {
+			status.LastFinish = st.LastFinish
+		}
+		status.Objects += st.Objects
+		status.Bytes += st.Bytes
+		status.Skipped += st.Skipped
+		status.Mismatched += st.Mismatched
+		status.Quarantined += st.Quarantined
+		status.Mismatches = append(status.Mismatches, st.Mismatches...)
+	}
+	if !found {
+		return s3response.ScrubStatus{}, s3err.GetAPIError(s3err.ErrNotImplemented)
+	}
+
+	sort.SliceStable(status.Mismatches, func(i, j int) bool {
+		return status.Mismatches[i].Detected.Before(status.Mismatches[j].Detected)
+	})
+
+	return status, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mux
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/memstore"
+	"github.com/versity/versitygw/s3err"
+)
+
+func TestMux(t *testing.T) {
+	ctx := context.Background()
+	archive := memstore.New()
+	fast := memstore.New()
+
+	m, err := New([]Route{
+		{Pattern: "archive-*", Backend: archive},
+		{Pattern: "data*", Backend: fast},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bucket := range []string{"archive-2023", "data", "datasets"} {
+		err := m.CreateBucket(ctx, &s3.CreateBucketInput{
+			Bucket: backend.GetStringPtr(bucket),
+		}, []byte(`{"Owner":"owner"}`))
+		if err != nil {
+			t.Fatalf("create %v: %v", bucket, err)
+		}
+	}
+
+	err = m.CreateBucket(ctx, &s3.CreateBucketInput{
+		Bucket: backend.GetStringPtr("other"),
+	}, []byte(`{"Owner":"owner"}`))
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrInvalidBucketName)) {
+		t.Errorf("expected InvalidBucketName for unrouted bucket, got %v", err)
+	}
+
+	_, err = archive.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: backend.GetStringPtr("archive-2023"),
+	})
+	if err != nil {
+		t.Errorf("expected bucket in the archive backend: %v", err)
+	}
+
+	// a bucket created directly in a member is hidden unless routed to it
+	err = archive.CreateBucket(ctx, &s3.CreateBucketInput{
+		Bucket: backend.GetStringPtr("data-hidden"),
+	}, []byte(`{"Owner":"owner"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := m.ListBuckets(ctx, "owner", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, b := range res.Buckets.Bucket {
+		names = append(names, b.Name)
+	}
+	if strings.Join(names, ",") != "archive-2023,data,datasets" {
+		t.Errorf("unexpected buckets %v", names)
+	}
+
+	_, err = m.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:   backend.GetStringPtr("data"),
+		Key:      backend.GetStringPtr("obj"),
+		Body:     strings.NewReader("hello world"),
+		Metadata: map[string]string{"color": "blue"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:              backend.GetStringPtr("archive-2023"),
+		Key:                 backend.GetStringPtr("copy"),
+		CopySource:          backend.GetStringPtr("data/obj"),
+		ExpectedBucketOwner: backend.GetStringPtr("owner"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	out, err := archive.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: backend.GetStringPtr("archive-2023"),
+		Key:    backend.GetStringPtr("copy"),
+	}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "hello world" || out.Metadata["color"] != "blue" {
+		t.Errorf("unexpected copy %q with metadata %v", buf.String(), out.Metadata)
+	}
+}
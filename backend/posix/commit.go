@@ -0,0 +1,82 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/versity/versitygw/backend/meta"
+)
+
+// errObjectAttrs is wrapped by the errors committing the attributes of a
+// new object, as opposed to the errors placing the object data
+var errObjectAttrs = errors.New("commit object attributes")
+
+// linkObject places the temp file f in the namespace for the object,
+// replacing the previous object and all of its attributes with attrs.
+//
+// The metadata stores keeping the attributes with the file get attrs set
+// on the temp file before it is renamed over the object, so the object
+// is only visible once complete. The other stores commit attrs once the
+// new file is in place, replacing the previous attributes in one update
+// when supported, and the new file is removed if that fails.
+func (p *Posix) linkObject(f *tmpfile, bucket, object string, size int64, attrs map[string][]byte) error {
+	var committed bool
+	if meta.AttributesWithFile(p.meta) {
+		f.commit = func(tempname string) error {
+			tmpobj, ok := strings.CutPrefix(tempname, bucket+string(filepath.Separator))
+			if !ok {
+				// temp dir outside of the bucket, the attributes
+				// are stored once the object is in place
+				return nil
+			}
+			for k, v := range attrs {
+				err := p.meta.StoreAttribute(bucket, tmpobj, k, v)
+				if err != nil {
+					return fmt.Errorf("%w: set %v attr: %w", errObjectAttrs, k, err)
+				}
+			}
+			committed = true
+			return nil
+		}
+	}
+
+	err := p.linkWithBucketQuota(bucket, object, size, f.link)
+	if err != nil {
+		return err
+	}
+
+	if committed {
+		// only the spilled attribute values of the previous object
+		// remain to be removed
+		err := p.meta.DeleteAttributes(bucket, object)
+		if err != nil {
+			return fmt.Errorf("remove previous object attributes: %w", err)
+		}
+		return nil
+	}
+
+	err = meta.CommitAttributes(p.meta, bucket, object, attrs)
+	if err != nil {
+		// an object without its attributes is removed
+		os.Remove(filepath.Join(bucket, object))
+		return fmt.Errorf("%w: %w", errObjectAttrs, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+)
+
+// ContentTypeDetect selects how the Content-Type of an object put
+// without one is filled in
+type ContentTypeDetect string
+
+const (
+	// DetectNone leaves the Content-Type of the object empty
+	DetectNone ContentTypeDetect = ""
+	// DetectExtension uses the type registered for the key extension
+	DetectExtension ContentTypeDetect = "extension"
+	// DetectContent uses the type registered for the key extension, and
+	// sniffs the object data when the extension is unknown
+	DetectContent ContentTypeDetect = "content"
+)
+
+// sniffLen is the most data http.DetectContentType considers
+const sniffLen = 512
+
+// ParseContentTypeDetect parses "none", "extension" or "content"
+func ParseContentTypeDetect(s string) (ContentTypeDetect, error) {
+	switch s {
+	case "", "none":
+		return DetectNone, nil
+	case string(DetectExtension), string(DetectContent):
+		return ContentTypeDetect(s), nil
+	default:
+		return DetectNone, fmt.Errorf("invalid content type detection %q", s)
+	}
+}
+
+// detectContentType returns the Content-Type for an object put without
+// one, or an empty string when the type is not known. The object data is
+// read from r when sniffing the content.
+func (p *Posix) detectContentType(object string, r io.ReaderAt) string {
+	if p.detect == DetectNone {
+		return ""
+	}
+
+	typ := mime.TypeByExtension(path.Ext(object))
+	if typ != "" || p.detect != DetectContent {
+		return typ
+	}
+
+	buf := make([]byte, sniffLen)
+	n, _ := r.ReadAt(buf, 0)
+	if n == 0 {
+		return ""
+	}
+	return http.DetectContentType(buf[:n])
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Durability selects what is flushed to stable storage before a new
+// object is acknowledged
+type Durability string
+
+const (
+	// DurabilityNone leaves the flushing to the filesystem
+	DurabilityNone Durability = ""
+	// DurabilityData flushes the object data and attributes, a crash
+	// may still lose the rename of a recent object
+	DurabilityData Durability = "data"
+	// DurabilityDir flushes the object data and attributes, and the
+	// parent directory of the object so the new object survives a crash
+	DurabilityDir Durability = "data+dir"
+)
+
+// ParseDurability parses "none", "data" or "data+dir"
+func ParseDurability(s string) (Durability, error) {
+	switch s {
+	case "", "none":
+		return DurabilityNone, nil
+	case string(DurabilityData), string(DurabilityDir):
+		return Durability(s), nil
+	default:
+		return DurabilityNone, fmt.Errorf("invalid durability %q", s)
+	}
+}
+
+// syncFile flushes the temp file of a new object if required by d
+func syncFile(f *os.File, d Durability) error {
+	if d == DurabilityNone {
+		return nil
+	}
+	err := f.Sync()
+	if err != nil {
+		return fmt.Errorf("sync tmpfile: %w", err)
+	}
+	return nil
+}
+
+// syncParent flushes the parent directory of a new object if required
+// by d, so that the directory entry of the object is persisted
+func syncParent(path string, d Durability) error {
+	// directories can not be flushed on windows
+	if d != DurabilityDir || runtime.GOOS == "windows" {
+		return nil
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("open parent dir: %w", err)
+	}
+	defer dir.Close()
+
+	err = dir.Sync()
+	if err != nil {
+		return fmt.Errorf("sync parent dir: %w", err)
+	}
+	return nil
+}
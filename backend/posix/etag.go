@@ -0,0 +1,169 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/versity/versitygw/backend/meta"
+)
+
+// EtagSource selects the etag of the files created outside of the
+// gateway, which have no stored etag
+type EtagSource string
+
+const (
+	// EtagNone returns an empty etag
+	EtagNone EtagSource = ""
+	// EtagStat returns a stat etag derived from the inode, modification
+	// time and size of the file, which changes whenever the file does
+	EtagStat EtagSource = "stat"
+	// EtagMD5 stores the md5 of the file data as its etag, computed in
+	// the background on first access. The stat etag is returned until
+	// the md5 is stored.
+	EtagMD5 EtagSource = "md5"
+)
+
+const (
+	// etagWorkers is the number of background md5 computations
+	etagWorkers = 4
+	// etagQueueLen is the most files waiting for an md5, files accessed
+	// while the queue is full are queued again on a later access
+	etagQueueLen = 1024
+)
+
+// ParseEtagSource parses "none", "stat" or "md5"
+func ParseEtagSource(s string) (EtagSource, error) {
+	switch s {
+	case "", "none":
+		return EtagNone, nil
+	case string(EtagStat), string(EtagMD5):
+		return EtagSource(s), nil
+	default:
+		return EtagNone, fmt.Errorf("invalid etag source %q", s)
+	}
+}
+
+// etagJob is a file queued for the background md5
+type etagJob struct {
+	bucket string
+	object string
+}
+
+// objectEtag returns the stored etag of the object, or the etag of the
+// EtagSource for a file without one
+func (p *Posix) objectEtag(bucket, object string, fi fs.FileInfo) string {
+	b, err := p.meta.RetrieveAttribute(bucket, object, etagkey)
+	if err == nil {
+		return string(b)
+	}
+	if !errors.Is(err, meta.ErrNoSuchKey) {
+		return ""
+	}
+	return p.missingEtag(bucket, object, fi)
+}
+
+// missingEtag returns the etag of a file without a stored etag
+func (p *Posix) missingEtag(bucket, object string, fi fs.FileInfo) string {
+	if p.etagSource == EtagNone || !fi.Mode().IsRegular() {
+		return ""
+	}
+	if p.etagSource == EtagMD5 {
+		p.queueEtag(bucket, object)
+	}
+	return statEtag(fi)
+}
+
+// statEtag hashes the file identity, modification time and size. The
+// "-0" suffix keeps clients from validating the data against it as an
+// md5, as for the etag of a multipart upload.
+func statEtag(fi fs.FileInfo) string {
+	var b [24]byte
+	binary.LittleEndian.PutUint64(b[0:], fileID(fi))
+	binary.LittleEndian.PutUint64(b[8:], uint64(fi.ModTime().UnixNano()))
+	binary.LittleEndian.PutUint64(b[16:], uint64(fi.Size()))
+	sum := md5.Sum(b[:])
+	return hex.EncodeToString(sum[:]) + "-0"
+}
+
+// queueEtag queues the file for the background md5 unless it is
+// already queued
+func (p *Posix) queueEtag(bucket, object string) {
+	job := etagJob{bucket: bucket, object: object}
+	if _, loaded := p.etagPending.LoadOrStore(job, struct{}{}); loaded {
+		return
+	}
+	select {
+	case p.etagQueue <- job:
+	default:
+		p.etagPending.Delete(job)
+	}
+}
+
+// runEtagWorker stores the md5 etags of the queued files until done is
+// closed
+func (p *Posix) runEtagWorker() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case job := <-p.etagQueue:
+			p.storeMD5Etag(job.bucket, job.object)
+			p.etagPending.Delete(job)
+		}
+	}
+}
+
+// storeMD5Etag stores the md5 of the file data as its etag, unless the
+// file changed or an etag was stored while hashing
+func (p *Posix) storeMD5Etag(bucket, object string) {
+	f, err := os.Open(filepath.Join(bucket, object))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	before, err := f.Stat()
+	if err != nil || !before.Mode().IsRegular() {
+		return
+	}
+
+	hash := md5.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return
+	}
+
+	after, err := os.Stat(filepath.Join(bucket, object))
+	if err != nil || !os.SameFile(before, after) ||
+		!after.ModTime().Equal(before.ModTime()) || after.Size() != before.Size() {
+		return
+	}
+	_, err = p.meta.RetrieveAttribute(bucket, object, etagkey)
+	if !errors.Is(err, meta.ErrNoSuchKey) {
+		return
+	}
+
+	p.meta.StoreAttribute(bucket, object, etagkey,
+		[]byte(hex.EncodeToString(hash.Sum(nil))))
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// GCStats are the garbage collector counters since startup
+type GCStats struct {
+	Runs           uint64 `json:"runs"`
+	TmpFiles       uint64 `json:"tmpFiles"`
+	Uploads        uint64 `json:"uploads"`
+	ReclaimedBytes uint64 `json:"reclaimedBytes"`
+}
+
+type gcCounters struct {
+	runs      atomic.Uint64
+	tmpFiles  atomic.Uint64
+	uploads   atomic.Uint64
+	reclaimed atomic.Uint64
+}
+
+// GCStats returns the garbage collector counters
+func (p *Posix) GCStats() GCStats {
+	return GCStats{
+		Runs:           p.gc.runs.Load(),
+		TmpFiles:       p.gc.tmpFiles.Load(),
+		Uploads:        p.gc.uploads.Load(),
+		ReclaimedBytes: p.gc.reclaimed.Load(),
+	}
+}
+
+// runGC periodically collects the garbage until Shutdown
+func (p *Posix) runGC(interval, tmpAge, uploadAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.collectGarbage(tmpAge, uploadAge)
+		}
+	}
+}
+
+// collectGarbage removes the temp files not modified for tmpAge, which
+// were left behind by interrupted uploads, and the multipart uploads
+// not modified for uploadAge. A zero age disables the removal.
+func (p *Posix) collectGarbage(tmpAge, uploadAge time.Duration) {
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		log.Printf("gc: readdir buckets: %v", err)
+		return
+	}
+
+	now := time.Now()
+	var files, uploads, reclaimed uint64
+	for _, entry := range entries {
+		if !p.isBucketDir(entry) || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		tmpdir := filepath.Join(entry.Name(), metaTmpDir)
+		mpdir := filepath.Join(entry.Name(), metaTmpMultipartDir)
+
+		if tmpAge > 0 {
+			n, size := removeStaleFiles(tmpdir, now.Add(-tmpAge))
+			files += n
+			reclaimed += size
+
+			objdirs, _ := os.ReadDir(mpdir)
+			for _, objdir := range objdirs {
+				n, size := removeStaleFiles(filepath.Join(mpdir, objdir.Name()), now.Add(-tmpAge))
+				files += n
+				reclaimed += size
+			}
+		}
+
+		if uploadAge > 0 {
+			n, size := p.removeStaleUploads(entry.Name(), now.Add(-uploadAge))
+			uploads += n
+			reclaimed += size
+		}
+	}
+
+	p.gc.runs.Add(1)
+	p.gc.tmpFiles.Add(files)
+	p.gc.uploads.Add(uploads)
+	p.gc.reclaimed.Add(reclaimed)
+
+	if files > 0 || uploads > 0 {
+		log.Printf("gc: removed %v temp files and %v multipart uploads, reclaimed %v bytes",
+			files, uploads, reclaimed)
+	}
+}
+
+// removeStaleFiles removes the regular files in dir modified before
+// cutoff, returning the number of files and bytes removed
+func removeStaleFiles(dir string, cutoff time.Time) (uint64, uint64) {
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, 0
+	}
+
+	var n, size uint64
+	for _, ent := range ents {
+		if !ent.Type().IsRegular() {
+			continue
+		}
+		fi, err := ent.Info()
+		if err != nil || fi.ModTime().After(cutoff) {
+			continue
+		}
+		if os.Remove(filepath.Join(dir, ent.Name())) == nil {
+			n++
+			size += uint64(fi.Size())
+		}
+	}
+
+	return n, size
+}
+
+// removeStaleUploads removes the bucket multipart upload directories
+// modified before cutoff, along with the object directories left empty
+func (p *Posix) removeStaleUploads(bucket string, cutoff time.Time) (uint64, uint64) {
+	mpdir := filepath.Join(bucket, metaTmpMultipartDir)
+	objdirs, err := os.ReadDir(mpdir)
+	if err != nil {
+		return 0, 0
+	}
+
+	var n, size uint64
+	for _, objdir := range objdirs {
+		if !objdir.IsDir() {
+			continue
+		}
+		objpath := filepath.Join(mpdir, objdir.Name())
+		ents, err := os.ReadDir(objpath)
+		if err != nil {
+			continue
+		}
+		for _, ent := range ents {
+			if !ent.IsDir() {
+				continue
+			}
+			fi, err := ent.Info()
+			if err != nil || fi.ModTime().After(cutoff) {
+				continue
+			}
+			upath := filepath.Join(objpath, ent.Name())
+			usize := dirSize(upath)
+			if os.RemoveAll(upath) == nil {
+				p.meta.DeleteAttributes(bucket, filepath.Join(metaTmpMultipartDir, objdir.Name(), ent.Name()))
+				n++
+				size += usize
+			}
+		}
+		// fails if other uploads or temp files remain
+		if os.Remove(objpath) == nil {
+			p.meta.DeleteAttributes(bucket, filepath.Join(metaTmpMultipartDir, objdir.Name()))
+		}
+	}
+
+	return n, size
+}
+
+func dirSize(dir string) uint64 {
+	var size uint64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err == nil {
+			size += uint64(fi.Size())
+		}
+		return nil
+	})
+	return size
+}
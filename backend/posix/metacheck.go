@@ -0,0 +1,271 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend/meta"
+)
+
+const (
+	// quarantinePrefix is prepended to the attribute name of values
+	// that fail to parse when repairing metadata, so that they no
+	// longer break requests but are kept for inspection
+	quarantinePrefix = "quarantine."
+)
+
+// MetaCheckOpts configures a metadata check
+type MetaCheckOpts struct {
+	// Bucket limits the check to a single bucket
+	Bucket string
+	// Repair fixes the problems found where possible
+	Repair bool
+	// Report is called for each problem found, object is empty for
+	// bucket problems
+	Report func(problem, bucket, object string, repaired bool)
+}
+
+// MetaCheckStats are the totals of a metadata check
+type MetaCheckStats struct {
+	Buckets  int
+	Objects  int
+	Problems int
+	Repaired int
+}
+
+type metaChecker struct {
+	p     *Posix
+	opts  MetaCheckOpts
+	stats MetaCheckStats
+}
+
+// CheckMeta validates the bucket and object metadata: multipart uploads
+// without a valid object name mapping, objects missing etags, and acl,
+// policy, tagging, lock and retention attributes that fail to parse.
+// With opts.Repair, missing etags are recalculated, unparsable attributes
+// are quarantined and uploads that can no longer be listed, completed or
+// aborted are removed.
+func (p *Posix) CheckMeta(ctx context.Context, opts MetaCheckOpts) (MetaCheckStats, error) {
+	c := &metaChecker{p: p, opts: opts}
+
+	var buckets []string
+	if opts.Bucket != "" {
+		buckets = append(buckets, opts.Bucket)
+	} else {
+		entries, err := os.ReadDir(".")
+		if err != nil {
+			return c.stats, fmt.Errorf("readdir buckets: %w", err)
+		}
+		for _, entry := range entries {
+			if p.isBucketDir(entry) && !strings.HasPrefix(entry.Name(), ".") {
+				buckets = append(buckets, entry.Name())
+			}
+		}
+	}
+
+	for _, bucket := range buckets {
+		if err := ctx.Err(); err != nil {
+			return c.stats, err
+		}
+		err := c.checkBucket(ctx, bucket)
+		if err != nil {
+			return c.stats, fmt.Errorf("check bucket %v: %w", bucket, err)
+		}
+	}
+
+	return c.stats, nil
+}
+
+func (c *metaChecker) report(problem, bucket, object string, repaired bool) {
+	c.stats.Problems++
+	if repaired {
+		c.stats.Repaired++
+	}
+	if c.opts.Report != nil {
+		c.opts.Report(problem, bucket, object, repaired)
+	}
+}
+
+func (c *metaChecker) checkBucket(ctx context.Context, bucket string) error {
+	_, err := os.Stat(bucket)
+	if err != nil {
+		return err
+	}
+	c.stats.Buckets++
+
+	_, err = c.p.meta.RetrieveAttribute(bucket, "", aclkey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		c.report("missing acl", bucket, "", false)
+	}
+	c.checkJSON(bucket, "", aclkey, &auth.ACL{})
+	c.checkJSON(bucket, "", policykey, &json.RawMessage{})
+	c.checkJSON(bucket, "", tagHdr, &map[string]string{})
+	c.checkJSON(bucket, "", bucketLockKey, &auth.BucketLockConfig{})
+
+	err = c.checkUploads(bucket)
+	if err != nil {
+		return err
+	}
+
+	return walkBucket(bucket, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == bucket {
+			return nil
+		}
+		object := strings.TrimPrefix(path, bucket+string(os.PathSeparator))
+		if d.IsDir() {
+			if d.Name() == metaTmpDir || d.Name() == metaTrashDir {
+				return fs.SkipDir
+			}
+			// only directories explicitly put as objects have an etag
+			_, err := c.p.meta.RetrieveAttribute(bucket, object, etagkey)
+			if err != nil {
+				return nil
+			}
+			object += "/"
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+
+		c.stats.Objects++
+		c.checkObject(bucket, object)
+		return nil
+	})
+}
+
+func (c *metaChecker) checkObject(bucket, object string) {
+	_, err := c.p.meta.RetrieveAttribute(bucket, object, etagkey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		repaired := false
+		if c.opts.Repair {
+			repaired = c.repairEtag(bucket, object) == nil
+		}
+		c.report("missing etag", bucket, object, repaired)
+	}
+
+	c.checkJSON(bucket, object, aclkey, &auth.ACL{})
+	c.checkJSON(bucket, object, tagHdr, &map[string]string{})
+	c.checkJSON(bucket, object, objectRetentionKey, &types.ObjectLockRetention{})
+
+	b, err := c.p.meta.RetrieveAttribute(bucket, object, objectLegalHoldKey)
+	if err == nil && len(b) != 1 {
+		c.report("invalid "+objectLegalHoldKey, bucket, object,
+			c.quarantine(bucket, object, objectLegalHoldKey, b))
+	}
+}
+
+// repairEtag sets the etag to the md5 of the object data, which matches
+// the etag for objects not created with a multipart upload
+func (c *metaChecker) repairEtag(bucket, object string) error {
+	f, err := os.Open(filepath.Join(bucket, object))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := md5.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+	return c.p.meta.StoreAttribute(bucket, object, etagkey,
+		[]byte(hex.EncodeToString(hash.Sum(nil))))
+}
+
+// checkJSON reports the attribute when it is set but can not be parsed
+// into v
+func (c *metaChecker) checkJSON(bucket, object, attribute string, v any) {
+	b, err := c.p.meta.RetrieveAttribute(bucket, object, attribute)
+	if err != nil {
+		return
+	}
+	if json.Unmarshal(b, v) == nil {
+		return
+	}
+	c.report("invalid "+attribute, bucket, object,
+		c.quarantine(bucket, object, attribute, b))
+}
+
+// quarantine moves the attribute value to the quarantine attribute when
+// repairing, returning true if the value was moved
+func (c *metaChecker) quarantine(bucket, object, attribute string, value []byte) bool {
+	if !c.opts.Repair {
+		return false
+	}
+	err := c.p.meta.StoreAttribute(bucket, object, quarantinePrefix+attribute, value)
+	if err != nil {
+		return false
+	}
+	return c.p.meta.DeleteAttribute(bucket, object, attribute) == nil
+}
+
+// checkUploads reports the multipart upload directories that do not map
+// back to their object name. These can not be listed, completed or
+// aborted, so are removed when repairing.
+func (c *metaChecker) checkUploads(bucket string) error {
+	objdirs, err := os.ReadDir(filepath.Join(bucket, metaTmpMultipartDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("readdir multipart uploads: %w", err)
+	}
+
+	for _, objdir := range objdirs {
+		if !objdir.IsDir() {
+			continue
+		}
+		mpobjdir := filepath.Join(metaTmpMultipartDir, objdir.Name())
+		b, err := c.p.meta.RetrieveAttribute(bucket, mpobjdir, onameAttr)
+		if err == nil && fmt.Sprintf("%x", sha256.Sum256(b)) == objdir.Name() {
+			continue
+		}
+
+		problem := "dangling upload"
+		if err != nil {
+			problem = "upload missing object name"
+		}
+
+		repaired := false
+		if c.opts.Repair {
+			repaired = os.RemoveAll(filepath.Join(bucket, mpobjdir)) == nil
+			if repaired {
+				c.p.meta.DeleteAttributes(bucket, mpobjdir)
+			}
+		}
+		c.report(problem, bucket, mpobjdir, repaired)
+	}
+
+	return nil
+}
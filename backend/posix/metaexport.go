@@ -0,0 +1,208 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	metaArchiveVersion = 1
+)
+
+// metaArchiveHeader is the first record of a metadata archive
+type metaArchiveHeader struct {
+	Version int    `json:"version"`
+	Bucket  string `json:"bucket"`
+}
+
+// metaArchiveEntry holds all attributes of an object, or of the bucket
+// when Object is empty
+type metaArchiveEntry struct {
+	Object string            `json:"object"`
+	Attrs  map[string][]byte `json:"attrs"`
+}
+
+// MetaArchiveStats are the totals of a metadata export or import
+type MetaArchiveStats struct {
+	Objects    int
+	Attributes int
+	// Skipped counts the imported objects not found in the bucket
+	Skipped int
+}
+
+// ExportMeta writes the bucket and object metadata of bucket to w as a
+// gzip compressed stream of json records. Together with a copy of the
+// object data, such as with rsync or tape, the archive allows the bucket
+// to be restored onto another gateway root with ImportMeta, including
+// one using a different metadata store. Incomplete multipart uploads are
+// not exported.
+func (p *Posix) ExportMeta(ctx context.Context, bucket string, w io.Writer) (MetaArchiveStats, error) {
+	var stats MetaArchiveStats
+
+	_, err := os.Stat(bucket)
+	if err != nil {
+		return stats, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	err = enc.Encode(metaArchiveHeader{
+		Version: metaArchiveVersion,
+		Bucket:  bucket,
+	})
+	if err != nil {
+		return stats, fmt.Errorf("write header: %w", err)
+	}
+
+	export := func(object string) error {
+		attrs, err := p.meta.ListAttributes(bucket, object)
+		if err != nil {
+			return fmt.Errorf("list attributes %v/%v: %w", bucket, object, err)
+		}
+		entry := metaArchiveEntry{
+			Object: object,
+			Attrs:  make(map[string][]byte, len(attrs)),
+		}
+		for _, attr := range attrs {
+			b, err := p.meta.RetrieveAttribute(bucket, object, attr)
+			if err != nil {
+				return fmt.Errorf("get attribute %v/%v %v: %w", bucket, object, attr, err)
+			}
+			entry.Attrs[attr] = b
+		}
+		if object != "" && len(entry.Attrs) == 0 {
+			return nil
+		}
+
+		err = enc.Encode(entry)
+		if err != nil {
+			return fmt.Errorf("write entry: %w", err)
+		}
+		stats.Objects++
+		stats.Attributes += len(entry.Attrs)
+		return nil
+	}
+
+	err = export("")
+	if err != nil {
+		return stats, err
+	}
+
+	err = walkBucket(bucket, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == bucket {
+			return nil
+		}
+		if d.IsDir() && (d.Name() == metaTmpDir || d.Name() == metaTrashDir) {
+			return fs.SkipDir
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		object := strings.TrimPrefix(path, bucket+string(os.PathSeparator))
+		if d.IsDir() {
+			object += "/"
+		}
+		return export(object)
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, zw.Close()
+}
+
+// ImportMeta restores the metadata written by ExportMeta onto bucket, or
+// onto the bucket the archive was exported from when bucket is empty.
+// The bucket directory and object data must already be in place, the
+// objects not found are skipped.
+func (p *Posix) ImportMeta(ctx context.Context, bucket string, r io.Reader) (MetaArchiveStats, error) {
+	var stats MetaArchiveStats
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return stats, fmt.Errorf("open archive: %w", err)
+	}
+	defer zr.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	var hdr metaArchiveHeader
+	err = dec.Decode(&hdr)
+	if err != nil {
+		return stats, fmt.Errorf("read header: %w", err)
+	}
+	if hdr.Version != metaArchiveVersion {
+		return stats, fmt.Errorf("unsupported archive version %v", hdr.Version)
+	}
+	if bucket == "" {
+		bucket = hdr.Bucket
+	}
+
+	_, err = os.Stat(bucket)
+	if err != nil {
+		return stats, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		var entry metaArchiveEntry
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return stats, nil
+		}
+		if err != nil {
+			return stats, fmt.Errorf("read entry: %w", err)
+		}
+
+		_, err = os.Stat(filepath.Join(bucket, entry.Object))
+		if errors.Is(err, fs.ErrNotExist) {
+			stats.Skipped++
+			continue
+		}
+		if err != nil {
+			return stats, fmt.Errorf("stat object: %w", err)
+		}
+
+		for attr, value := range entry.Attrs {
+			err := p.meta.StoreAttribute(bucket, entry.Object, attr, value)
+			if err != nil {
+				return stats, fmt.Errorf("set attribute %v/%v %v: %w",
+					bucket, entry.Object, attr, err)
+			}
+		}
+		stats.Objects++
+		stats.Attributes += len(entry.Attrs)
+	}
+}
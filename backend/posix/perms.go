@@ -0,0 +1,178 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+
+	"github.com/versity/versitygw/backend/meta"
+	"github.com/versity/versitygw/s3err"
+)
+
+const (
+	defaultDirPerm  fs.FileMode = 0755
+	defaultFilePerm fs.FileMode = 0644
+
+	// permsKey is the bucket attribute of the BucketPerms
+	permsKey = "permissions"
+)
+
+// BucketPerms are the permissions of the directories and files created
+// within a bucket, the gateway defaults are used for the zero values
+type BucketPerms struct {
+	DirPerm  fs.FileMode
+	FilePerm fs.FileMode
+}
+
+// bucketPermsAttr is the stored BucketPerms, with the permissions as
+// octal strings
+type bucketPermsAttr struct {
+	DirPerm  string `json:"dirPerm,omitempty"`
+	FilePerm string `json:"filePerm,omitempty"`
+}
+
+// ParsePerm parses an octal permission such as 0644 or 2775, including
+// the setuid, setgid and sticky bits
+func ParsePerm(s string) (fs.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m&^07777 != 0 {
+		return 0, fmt.Errorf("invalid permission %q", s)
+	}
+
+	perm := fs.FileMode(m & 0777)
+	if m&04000 != 0 {
+		perm |= fs.ModeSetuid
+	}
+	if m&02000 != 0 {
+		perm |= fs.ModeSetgid
+	}
+	if m&01000 != 0 {
+		perm |= fs.ModeSticky
+	}
+	return perm, nil
+}
+
+// FormatPerm formats the permission in the octal form of ParsePerm
+func FormatPerm(perm fs.FileMode) string {
+	m := uint32(perm.Perm())
+	if perm&fs.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if perm&fs.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if perm&fs.ModeSticky != 0 {
+		m |= 01000
+	}
+	return fmt.Sprintf("%04o", m)
+}
+
+// SetBucketPerms sets the permissions of the directories and files
+// created within the bucket from now on, existing objects keep their
+// permissions
+func (p *Posix) SetBucketPerms(bucket string, perms BucketPerms) error {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	if perms == (BucketPerms{}) {
+		err := p.meta.DeleteAttribute(bucket, "", permsKey)
+		if err != nil && !errors.Is(err, meta.ErrNoSuchKey) {
+			return fmt.Errorf("remove permissions: %w", err)
+		}
+		return nil
+	}
+
+	var attr bucketPermsAttr
+	if perms.DirPerm != 0 {
+		attr.DirPerm = FormatPerm(perms.DirPerm)
+	}
+	if perms.FilePerm != 0 {
+		attr.FilePerm = FormatPerm(perms.FilePerm)
+	}
+	b, err := json.Marshal(attr)
+	if err != nil {
+		return fmt.Errorf("marshal permissions: %w", err)
+	}
+
+	err = p.meta.StoreAttribute(bucket, "", permsKey, b)
+	if err != nil {
+		return fmt.Errorf("set permissions: %w", err)
+	}
+	return nil
+}
+
+// GetBucketPerms returns the permissions set for the bucket, the zero
+// values are the ones using the gateway defaults
+func (p *Posix) GetBucketPerms(bucket string) (BucketPerms, error) {
+	b, err := p.meta.RetrieveAttribute(bucket, "", permsKey)
+	if errors.Is(err, fs.ErrNotExist) {
+		return BucketPerms{}, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		return BucketPerms{}, nil
+	}
+	if err != nil {
+		return BucketPerms{}, fmt.Errorf("get permissions: %w", err)
+	}
+
+	var attr bucketPermsAttr
+	err = json.Unmarshal(b, &attr)
+	if err != nil {
+		return BucketPerms{}, fmt.Errorf("parse permissions: %w", err)
+	}
+
+	var perms BucketPerms
+	if attr.DirPerm != "" {
+		perms.DirPerm, err = ParsePerm(attr.DirPerm)
+		if err != nil {
+			return BucketPerms{}, err
+		}
+	}
+	if attr.FilePerm != "" {
+		perms.FilePerm, err = ParsePerm(attr.FilePerm)
+		if err != nil {
+			return BucketPerms{}, err
+		}
+	}
+	return perms, nil
+}
+
+// objectPerms returns the directory and file permissions for new objects
+// in the bucket. A bucket without valid permissions uses the defaults.
+func (p *Posix) objectPerms(bucket string) (fs.FileMode, fs.FileMode) {
+	dirPerm, filePerm := p.dirPerm, p.filePerm
+
+	perms, err := p.GetBucketPerms(bucket)
+	if err == nil && perms.DirPerm != 0 {
+		dirPerm = perms.DirPerm
+	}
+	if err == nil && perms.FilePerm != 0 {
+		filePerm = perms.FilePerm
+	}
+	if p.setgid {
+		dirPerm |= fs.ModeSetgid
+	}
+	return dirPerm, filePerm
+}
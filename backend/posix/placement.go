@@ -0,0 +1,178 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Placement places the new buckets with a name matching Pattern, in the
+// path.Match syntax, in Dir instead of the gateway root directory, such
+// as "fast-*" buckets on an NVMe filesystem. The placed buckets are
+// linked into the root directory so that clients see a single namespace.
+type Placement struct {
+	Pattern string
+	Dir     string
+}
+
+// ParsePlacement parses a "pattern=dir" bucket placement
+func ParsePlacement(s string) (Placement, error) {
+	pattern, dir, ok := strings.Cut(s, "=")
+	if !ok || pattern == "" || dir == "" {
+		return Placement{}, fmt.Errorf("invalid placement %q, expected pattern=dir", s)
+	}
+	return Placement{Pattern: pattern, Dir: dir}, nil
+}
+
+type placementTarget struct {
+	pattern string
+	dir     string
+	// fd is held open to keep the target filesystem from being
+	// unmounted while the gateway is using it
+	fd *os.File
+}
+
+// openPlacements opens the placement target directories, relative dirs
+// are resolved from the current directory
+func openPlacements(placements []Placement) ([]placementTarget, error) {
+	var targets []placementTarget
+	for _, pl := range placements {
+		_, err := path.Match(pl.Pattern, "")
+		if err != nil {
+			closePlacements(targets)
+			return nil, fmt.Errorf("placement pattern %q: %w", pl.Pattern, err)
+		}
+
+		dir, err := filepath.Abs(pl.Dir)
+		if err != nil {
+			closePlacements(targets)
+			return nil, fmt.Errorf("placement dir %v: %w", pl.Dir, err)
+		}
+		f, err := os.Open(dir)
+		if err != nil {
+			closePlacements(targets)
+			return nil, fmt.Errorf("open placement dir: %w", err)
+		}
+		fi, err := f.Stat()
+		if err == nil && !fi.IsDir() {
+			err = fmt.Errorf("not a directory")
+		}
+		if err != nil {
+			f.Close()
+			closePlacements(targets)
+			return nil, fmt.Errorf("placement dir %v: %w", dir, err)
+		}
+
+		targets = append(targets, placementTarget{
+			pattern: pl.Pattern,
+			dir:     dir,
+			fd:      f,
+		})
+	}
+
+	return targets, nil
+}
+
+func closePlacements(targets []placementTarget) {
+	for _, t := range targets {
+		t.fd.Close()
+	}
+}
+
+// bucketPlacement returns the directory to create the bucket in, the
+// first matching placement wins
+func (p *Posix) bucketPlacement(bucket string) (string, bool) {
+	for _, t := range p.placements {
+		if ok, _ := path.Match(t.pattern, bucket); ok {
+			return t.dir, true
+		}
+	}
+	return "", false
+}
+
+// mkdirBucket creates the bucket directory, or the directory in the
+// placement dir along with its link when the bucket matches a placement
+func (p *Posix) mkdirBucket(bucket string, perm fs.FileMode) error {
+	dir, ok := p.bucketPlacement(bucket)
+	if !ok {
+		return os.Mkdir(bucket, perm)
+	}
+
+	// the link is created first to reserve the bucket name
+	target := filepath.Join(dir, bucket)
+	err := os.Symlink(target, bucket)
+	if err != nil {
+		return err
+	}
+	err = os.Mkdir(target, perm)
+	if err != nil {
+		os.Remove(bucket)
+		if os.IsExist(err) {
+			return fmt.Errorf("placement dir %v already exists", target)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// placedBucketDir returns the directory of a bucket placed outside of
+// the gateway root, the bucket is then a link to it in the root
+func (p *Posix) placedBucketDir(bucket string) (string, bool) {
+	if len(p.placements) == 0 {
+		return "", false
+	}
+
+	target, err := os.Readlink(bucket)
+	if err != nil {
+		return "", false
+	}
+	for _, t := range p.placements {
+		if filepath.Dir(target) == t.dir {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+// isBucketDir returns true for the gateway root entries that may be
+// buckets, including the links to the placed buckets
+func (p *Posix) isBucketDir(entry fs.DirEntry) bool {
+	if entry.IsDir() {
+		return true
+	}
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+	_, ok := p.placedBucketDir(entry.Name())
+	return ok
+}
+
+// walkBucket walks the bucket directory as filepath.WalkDir, following
+// the link of a placed bucket
+func walkBucket(bucket string, fn fs.WalkDirFunc) error {
+	root := bucket + string(filepath.Separator)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if path == root {
+			path = bucket
+		}
+		return fn(path, d, err)
+	})
+}
@@ -18,17 +18,21 @@ import (
 	"context"
 	"crypto/md5"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -60,56 +64,275 @@ type Posix struct {
 	// used to determine if chowning is needed
 	euid int
 	egid int
+
+	// putTmpDir chooses the put object temp dir, see PosixOpts
+	putTmpDir func(*s3.PutObjectInput) (string, error)
+	// partMover moves the completed upload parts, see PosixOpts
+	partMover PartMover
+	// directio is the O_DIRECT transfer size threshold, see PosixOpts
+	directio int64
+
+	// dirPerm/filePerm are the default permissions of new directories
+	// and objects, setgid keeps the group of setgid directories
+	dirPerm  fs.FileMode
+	filePerm fs.FileMode
+	setgid   bool
+	// exactPerms applies the permissions regardless of the umask
+	exactPerms bool
+
+	// exclude are the glob patterns of files hidden from the clients,
+	// skipdirs adds the gateway temp dir for the listings
+	exclude  []string
+	skipdirs []string
+
+	// detect fills in the missing object content types, see PosixOpts
+	detect ContentTypeDetect
+
+	// durability flushes the new objects before they are acknowledged,
+	// see PosixOpts
+	durability Durability
+
+	// etagSource is the etag of the files without one, see PosixOpts.
+	// The md5 etags are computed from etagQueue, etagPending tracks the
+	// queued files.
+	etagSource  EtagSource
+	etagQueue   chan etagJob
+	etagPending sync.Map
+
+	// placements are the bucket placement target dirs, see PosixOpts
+	placements []placementTarget
+
+	// trashRetention moves the deleted objects into the bucket trash
+	// for this long, see PosixOpts
+	trashRetention time.Duration
+
+	// scrub verifies the object data in the background, nil when the
+	// scrubber is disabled
+	scrub *scrubber
+
+	// gc counts the garbage collected, the collector runs until
+	// done is closed
+	gc   gcCounters
+	done chan struct{}
+
+	// quotaMu serializes the bucket quota usage updates
+	quotaMu sync.Mutex
+
+	// keyLocks serializes the object data and metadata updates of
+	// concurrent writers to the same object
+	keyLocks keyLocks
 }
 
 var _ backend.Backend = &Posix{}
 
 const (
-	metaTmpDir          = ".sgwtmp"
-	metaTmpMultipartDir = metaTmpDir + "/multipart"
-	onameAttr           = "objname"
-	tagHdr              = "X-Amz-Tagging"
-	metaHdr             = "X-Amz-Meta"
-	contentTypeHdr      = "content-type"
-	contentEncHdr       = "content-encoding"
-	emptyMD5            = "d41d8cd98f00b204e9800998ecf8427e"
-	aclkey              = "acl"
-	etagkey             = "etag"
-	policykey           = "policy"
-	bucketLockKey       = "bucket-lock"
-	objectRetentionKey  = "object-retention"
-	objectLegalHoldKey  = "object-legal-hold"
+	metaTmpDir           = ".sgwtmp"
+	metaTmpMultipartDir  = metaTmpDir + "/multipart"
+	onameAttr            = "objname"
+	tagHdr               = "X-Amz-Tagging"
+	metaHdr              = "X-Amz-Meta"
+	contentTypeHdr       = "content-type"
+	contentEncHdr        = "content-encoding"
+	cacheControlHdr      = "cache-control"
+	contentDispHdr       = "content-disposition"
+	contentLangHdr       = "content-language"
+	expiresHdr           = "expires"
+	emptyMD5             = "d41d8cd98f00b204e9800998ecf8427e"
+	aclkey               = "acl"
+	etagkey              = "etag"
+	policykey            = "policy"
+	bucketLockKey        = "bucket-lock"
+	objectRetentionKey   = "object-retention"
+	objectLegalHoldKey   = "object-legal-hold"
+	nullVersionId        = "null"
+	versioningKey        = "versioning"
+	notificationKey      = "notification"
+	loggingKey           = "logging"
+	ownerkey             = "owner"
+	partSizesKey         = "part-sizes"
+	checksumKey          = "checksum"
+	checksumAlgorithmKey = "checksum-algorithm"
+	publicAccessBlockKey = "public-access-block"
 )
 
 type PosixOpts struct {
 	ChownUID bool
 	ChownGID bool
+	// GCInterval enables the periodic removal of the temp files not
+	// modified for GCTmpAge and of the multipart uploads not modified
+	// for GCUploadAge, a zero age disables the respective removal
+	GCInterval  time.Duration
+	GCTmpAge    time.Duration
+	GCUploadAge time.Duration
+	// PutTmpDir returns the directory to create the temp file of a put
+	// object in, for filesystems where new files inherit their layout
+	// from the parent directory. The bucket temp dir is used when nil or
+	// when an empty dir is returned.
+	PutTmpDir func(*s3.PutObjectInput) (string, error)
+	// PartMover appends the parts of a completed multipart upload to the
+	// object without copying the part data, for filesystems that can move
+	// file extents. The part data is copied when nil.
+	PartMover PartMover
+	// DirectIOThreshold enables O_DIRECT for the object data reads and
+	// writes of at least this many bytes, so that large transfers do not
+	// evict the page cache used by other workloads. Transfers use the page
+	// cache when zero, or when the filesystem does not support O_DIRECT.
+	DirectIOThreshold int64
+	// DirPerm and FilePerm are the permission bits (before umask) of the
+	// new directories and object files, 0755 and 0644 when zero. Buckets
+	// can override these with SetBucketPerms.
+	DirPerm  fs.FileMode
+	FilePerm fs.FileMode
+	// SetGID creates the directories with the setgid bit, and new objects
+	// and directories keep the group inherited from their parent directory
+	// instead of being changed to the account GID, so that the group of a
+	// directory shared with NFS users applies to the whole tree.
+	SetGID bool
+	// ExactPerms sets the new directories and objects to exactly the
+	// DirPerm and FilePerm (or bucket) permissions regardless of the
+	// process umask, so that other posix consumers of the files get
+	// predictable permissions.
+	ExactPerms bool
+	// Exclude are glob patterns of file and directory names, such as
+	// ".snapshot/", ".nfs*" or "lost+found", that are hidden from the
+	// listings and are not found by object reads. A pattern ending in "/"
+	// only matches directories.
+	Exclude []string
+	// ContentTypeDetect fills in the Content-Type of the objects put
+	// without one from the key extension or the object data, so that
+	// browsers downloading the objects get a usable type.
+	ContentTypeDetect ContentTypeDetect
+	// Durability flushes the data, and optionally the parent directory,
+	// of the objects written by PutObject and CompleteMultipartUpload
+	// before they are acknowledged, trading throughput for the objects
+	// surviving a crash. The filesystem does the flushing when not set.
+	Durability Durability
+	// EtagSource is the etag of the files created outside of the gateway,
+	// so that listings and conditional requests work for data ingested
+	// directly into the filesystem. The etag is empty when not set.
+	EtagSource EtagSource
+	// ScrubInterval enables the background scrubber, which verifies the
+	// data of the objects with an md5 etag to detect bit rot. A scrub
+	// pass starts this long after the previous one finished. ScrubRate
+	// limits the scrub reads in bytes per second, unlimited when zero,
+	// and ScrubQuarantine moves the mismatching objects out of the
+	// bucket into the bucket ".sgwtmp/quarantine" directory.
+	ScrubInterval   time.Duration
+	ScrubRate       int64
+	ScrubQuarantine bool
+	// TrashRetention enables the bucket trash, DeleteObject moves the
+	// objects along with their metadata into the bucket ".sgwtrash"
+	// directory where they can be restored from until purged after
+	// this retention. The trash of a deleted bucket is removed with it.
+	TrashRetention time.Duration
+	// Placements create the new buckets matching a placement in the
+	// placement directory instead of the root directory, such as on a
+	// faster or an archive filesystem. The placed bucket directory is
+	// linked into the root directory.
+	Placements []Placement
+}
+
+// PartMover moves the data of the completed multipart upload parts to
+// the object instead of copying it
+type PartMover interface {
+	// CanMove returns true when the parts with the given sizes, in
+	// object order, can be moved
+	CanMove(partSizes []int64) bool
+	// MovePart appends the data of part to the end of dst
+	MovePart(dst, part *os.File) error
 }
 
 func New(rootdir string, meta meta.MetadataStorer, opts PosixOpts) (*Posix, error) {
-	err := os.Chdir(rootdir)
+	for _, pattern := range opts.Exclude {
+		_, err := path.Match(strings.TrimSuffix(pattern, "/"), "")
+		if err != nil {
+			return nil, fmt.Errorf("exclude pattern %q: %w", pattern, err)
+		}
+	}
+
+	// the relative placement dirs are resolved before the chdir
+	placements, err := openPlacements(opts.Placements)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chdir(rootdir)
 	if err != nil {
+		closePlacements(placements)
 		return nil, fmt.Errorf("chdir %v: %w", rootdir, err)
 	}
 
 	f, err := os.Open(rootdir)
 	if err != nil {
+		closePlacements(placements)
 		return nil, fmt.Errorf("open %v: %w", rootdir, err)
 	}
 
-	return &Posix{
-		meta:     meta,
-		rootfd:   f,
-		rootdir:  rootdir,
-		euid:     os.Geteuid(),
-		egid:     os.Getegid(),
-		chownuid: opts.ChownUID,
-		chowngid: opts.ChownGID,
-	}, nil
+	p := &Posix{
+		meta:       meta,
+		rootfd:     f,
+		rootdir:    rootdir,
+		euid:       os.Geteuid(),
+		egid:       os.Getegid(),
+		chownuid:   opts.ChownUID,
+		chowngid:   opts.ChownGID,
+		putTmpDir:  opts.PutTmpDir,
+		partMover:  opts.PartMover,
+		directio:   opts.DirectIOThreshold,
+		dirPerm:    opts.DirPerm,
+		filePerm:   opts.FilePerm,
+		setgid:     opts.SetGID,
+		exactPerms: opts.ExactPerms,
+		exclude:    opts.Exclude,
+		skipdirs:   append([]string{metaTmpDir, metaTrashDir}, opts.Exclude...),
+		detect:     opts.ContentTypeDetect,
+		durability: opts.Durability,
+		etagSource: opts.EtagSource,
+		done:       make(chan struct{}),
+
+		placements:     placements,
+		trashRetention: opts.TrashRetention,
+	}
+	if p.dirPerm == 0 {
+		p.dirPerm = defaultDirPerm
+	}
+	if p.filePerm == 0 {
+		p.filePerm = defaultFilePerm
+	}
+
+	if opts.GCInterval > 0 {
+		go p.runGC(opts.GCInterval, opts.GCTmpAge, opts.GCUploadAge)
+	}
+
+	if p.etagSource == EtagMD5 {
+		p.etagQueue = make(chan etagJob, etagQueueLen)
+		for i := 0; i < etagWorkers; i++ {
+			go p.runEtagWorker()
+		}
+	}
+
+	if p.trashRetention > 0 {
+		go p.runTrashPurge()
+	}
+
+	if opts.ScrubInterval > 0 {
+		p.scrub = &scrubber{
+			rate:       opts.ScrubRate,
+			quarantine: opts.ScrubQuarantine,
+		}
+		go p.runScrub(opts.ScrubInterval)
+	}
+
+	return p, nil
 }
 
 func (p *Posix) Shutdown() {
+	close(p.done)
 	p.rootfd.Close()
+	closePlacements(p.placements)
+	if c, ok := p.meta.(io.Closer); ok {
+		c.Close()
+	}
 }
 
 func (p *Posix) String() string {
@@ -125,10 +348,18 @@ func (p *Posix) ListBuckets(_ context.Context, owner string, isAdmin bool) (s3re
 
 	var buckets []s3response.ListAllMyBucketsEntry
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !p.isBucketDir(entry) {
 			// buckets must be a directory
 			continue
 		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			// not a valid bucket name, these are reserved
+			// for gateway internal data such as sidecar metadata
+			continue
+		}
+		if backend.IsExcluded(entry.Name(), true, p.exclude) {
+			continue
+		}
 
 		fi, err := entry.Info()
 		if err != nil {
@@ -192,13 +423,10 @@ func (p *Posix) HeadBucket(_ context.Context, input *s3.HeadBucketInput) (*s3.He
 	return &s3.HeadBucketOutput{}, nil
 }
 
-var (
-	// TODO: make this configurable
-	defaultDirPerm fs.FileMode = 0755
-)
-
 func (p *Posix) CreateBucket(ctx context.Context, input *s3.CreateBucketInput, acl []byte) error {
-	if input.Bucket == nil {
+	// the bucket name is a directory name within the gateway root, so
+	// reject names that could resolve outside of it
+	if input.Bucket == nil || !backend.IsValidBucketName(*input.Bucket) {
 		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
 	}
 
@@ -211,13 +439,30 @@ func (p *Posix) CreateBucket(ctx context.Context, input *s3.CreateBucketInput, a
 
 	bucket := *input.Bucket
 
-	err := os.Mkdir(bucket, defaultDirPerm)
+	dirPerm, _ := p.objectPerms(bucket)
+	err := p.mkdirBucket(bucket, dirPerm)
 	if err != nil && os.IsExist(err) {
 		return s3err.GetAPIError(s3err.ErrBucketAlreadyExists)
 	}
 	if err != nil {
 		return fmt.Errorf("mkdir bucket: %w", err)
 	}
+	if p.exactPerms {
+		err = os.Chmod(bucket, dirPerm)
+		if err != nil {
+			return fmt.Errorf("chmod bucket: %w", err)
+		}
+	} else if dirPerm&fs.ModeSetgid != 0 {
+		// mkdir only inherits setgid from the parent directory
+		fi, err := os.Stat(bucket)
+		if err != nil {
+			return fmt.Errorf("stat bucket: %w", err)
+		}
+		err = os.Chmod(bucket, fi.Mode().Perm()|fs.ModeSetgid)
+		if err != nil {
+			return fmt.Errorf("chmod bucket: %w", err)
+		}
+	}
 
 	if doChown {
 		err := os.Chown(bucket, uid, gid)
@@ -263,22 +508,42 @@ func (p *Posix) DeleteBucket(_ context.Context, input *s3.DeleteBucketInput) err
 		return fmt.Errorf("readdir bucket: %w", err)
 	}
 
-	if len(names) == 1 && names[0].Name() == metaTmpDir {
-		// if .sgwtmp is only item in directory
-		// then clean this up before trying to remove the bucket
-		err = os.RemoveAll(filepath.Join(*input.Bucket, metaTmpDir))
-		if err != nil && !errors.Is(err, fs.ErrNotExist) {
-			return fmt.Errorf("remove temp dir: %w", err)
+	// if .sgwtmp and .sgwtrash are the only items in directory
+	// then clean these up before trying to remove the bucket
+	onlyMeta := true
+	for _, name := range names {
+		if name.Name() != metaTmpDir && name.Name() != metaTrashDir {
+			onlyMeta = false
+		}
+	}
+	if onlyMeta {
+		for _, name := range names {
+			err = os.RemoveAll(filepath.Join(*input.Bucket, name.Name()))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("remove %v dir: %w", name.Name(), err)
+			}
 		}
 	}
 
-	err = os.Remove(*input.Bucket)
+	// a placed bucket dir is removed along with its link
+	dir, placed := p.placedBucketDir(*input.Bucket)
+	if !placed {
+		dir = *input.Bucket
+	}
+
+	err = os.Remove(dir)
 	if err != nil && err.(*os.PathError).Err == syscall.ENOTEMPTY {
 		return s3err.GetAPIError(s3err.ErrBucketNotEmpty)
 	}
 	if err != nil {
 		return fmt.Errorf("remove bucket: %w", err)
 	}
+	if placed {
+		err = os.Remove(*input.Bucket)
+		if err != nil {
+			return fmt.Errorf("remove bucket link: %w", err)
+		}
+	}
 
 	err = p.meta.DeleteAttributes(*input.Bucket, "")
 	if err != nil {
@@ -288,7 +553,12 @@ func (p *Posix) DeleteBucket(_ context.Context, input *s3.DeleteBucketInput) err
 	return nil
 }
 
-func (p *Posix) CreateMultipartUpload(_ context.Context, mpu *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+func (p *Posix) CreateMultipartUpload(ctx context.Context, mpu *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	acct, ok := ctx.Value("account").(auth.Account)
+	if !ok {
+		acct = auth.Account{}
+	}
+
 	if mpu.Bucket == nil {
 		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
 	}
@@ -313,6 +583,34 @@ func (p *Posix) CreateMultipartUpload(_ context.Context, mpu *s3.CreateMultipart
 		return nil, s3err.GetAPIError(s3err.ErrDirectoryObjectContainsData)
 	}
 
+	// the content headers, tags and object lock settings are applied
+	// to the object on completion
+	uploadAttrs, err := p.objectLockAttrs(bucket, mpu.ObjectLockMode,
+		mpu.ObjectLockRetainUntilDate, mpu.ObjectLockLegalHoldStatus)
+	if err != nil {
+		return nil, err
+	}
+	if uploadAttrs == nil {
+		uploadAttrs = make(map[string][]byte)
+	}
+	tags, err := backend.ParseObjectTags(getString(mpu.Tagging))
+	if err != nil {
+		return nil, err
+	}
+	if tags != nil {
+		b, err := json.Marshal(tags)
+		if err != nil {
+			return nil, fmt.Errorf("marshal tags: %w", err)
+		}
+		uploadAttrs[tagHdr] = b
+	}
+	if getString(mpu.ContentType) != "" {
+		uploadAttrs[contentTypeHdr] = []byte(*mpu.ContentType)
+	}
+	if getString(mpu.ContentEncoding) != "" {
+		uploadAttrs[contentEncHdr] = []byte(*mpu.ContentEncoding)
+	}
+
 	// generate random uuid for upload id
 	uploadID := uuid.New().String()
 	// hash object name for multipart container
@@ -340,10 +638,23 @@ func (p *Posix) CreateMultipartUpload(_ context.Context, mpu *s3.CreateMultipart
 		return nil, fmt.Errorf("set name attr for upload: %w", err)
 	}
 
+	// record the initiator, only the initiator and the bucket owner
+	// may access the upload
+	if acct.Access != "" {
+		err = p.meta.StoreAttribute(bucket, filepath.Join(objdir, uploadID),
+			ownerkey, []byte(acct.Access))
+		if err != nil {
+			// cleanup object if returning error
+			os.RemoveAll(filepath.Join(tmppath, uploadID))
+			os.Remove(tmppath)
+			return nil, fmt.Errorf("set initiator attr: %w", err)
+		}
+	}
+
 	// set user attrs
 	for k, v := range mpu.Metadata {
 		err := p.meta.StoreAttribute(bucket, filepath.Join(objdir, uploadID),
-			k, []byte(v))
+			fmt.Sprintf("%v.%v", metaHdr, k), []byte(v))
 		if err != nil {
 			// cleanup object if returning error
 			os.RemoveAll(filepath.Join(tmppath, uploadID))
@@ -352,10 +663,67 @@ func (p *Posix) CreateMultipartUpload(_ context.Context, mpu *s3.CreateMultipart
 		}
 	}
 
+	err = p.storeObjectHeaders(bucket, filepath.Join(objdir, uploadID),
+		objectHeaders{
+			cacheControl:       mpu.CacheControl,
+			contentDisposition: mpu.ContentDisposition,
+			contentLanguage:    mpu.ContentLanguage,
+			expires:            mpu.Expires,
+		})
+	if err != nil {
+		// cleanup object if returning error
+		os.RemoveAll(filepath.Join(tmppath, uploadID))
+		os.Remove(tmppath)
+		return nil, err
+	}
+
+	// the requested acl is applied to the object on completion
+	acl, err := p.newObjectAcl(bucket, acct.Access, mpu.ACL,
+		auth.Grants{
+			FullControl: mpu.GrantFullControl,
+			Read:        mpu.GrantRead,
+			ReadACP:     mpu.GrantReadACP,
+			WriteACP:    mpu.GrantWriteACP,
+		})
+	if err == nil && acl != nil {
+		err = p.meta.StoreAttribute(bucket, filepath.Join(objdir, uploadID),
+			aclkey, acl)
+	}
+	if err != nil {
+		// cleanup object if returning error
+		os.RemoveAll(filepath.Join(tmppath, uploadID))
+		os.Remove(tmppath)
+		return nil, fmt.Errorf("set acl for upload: %w", err)
+	}
+
+	for k, v := range uploadAttrs {
+		err = p.meta.StoreAttribute(bucket, filepath.Join(objdir, uploadID), k, v)
+		if err != nil {
+			// cleanup object if returning error
+			os.RemoveAll(filepath.Join(tmppath, uploadID))
+			os.Remove(tmppath)
+			return nil, fmt.Errorf("set %v attr for upload: %w", k, err)
+		}
+	}
+
+	// the parts are checksummed with the requested algorithm to
+	// derive the checksum of the object on completion
+	if mpu.ChecksumAlgorithm != "" {
+		err = p.meta.StoreAttribute(bucket, filepath.Join(objdir, uploadID),
+			checksumAlgorithmKey, []byte(mpu.ChecksumAlgorithm))
+		if err != nil {
+			// cleanup object if returning error
+			os.RemoveAll(filepath.Join(tmppath, uploadID))
+			os.Remove(tmppath)
+			return nil, fmt.Errorf("set checksum algorithm for upload: %w", err)
+		}
+	}
+
 	return &s3.CreateMultipartUploadOutput{
-		Bucket:   &bucket,
-		Key:      &object,
-		UploadId: &uploadID,
+		Bucket:            &bucket,
+		Key:               &object,
+		UploadId:          &uploadID,
+		ChecksumAlgorithm: mpu.ChecksumAlgorithm,
 	}, nil
 }
 
@@ -370,7 +738,7 @@ func (p *Posix) getChownIDs(acct auth.Account) (int, int, bool) {
 		uid = acct.UserID
 		needsChown = true
 	}
-	if p.chowngid && acct.GroupID != p.egid {
+	if p.chowngid && !p.setgid && acct.GroupID != p.egid {
 		gid = acct.GroupID
 		needsChown = true
 	}
@@ -417,10 +785,21 @@ func (p *Posix) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteM
 
 	objdir := filepath.Join(metaTmpMultipartDir, fmt.Sprintf("%x", sum))
 
+	err = p.checkUploadAccess(ctx, bucket, filepath.Join(objdir, uploadID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = backend.CheckCompletedParts(parts)
+	if err != nil {
+		return nil, err
+	}
+
 	// check all parts ok
 	last := len(parts) - 1
-	partsize := int64(0)
 	var totalsize int64
+	partSizes := make([]int64, 0, len(parts))
+	partChecksums := make([]*backend.Checksum, 0, len(parts))
 	for i, part := range parts {
 		partObjPath := filepath.Join(objdir, uploadID, fmt.Sprintf("%v", *part.PartNumber))
 		fullPartPath := filepath.Join(bucket, partObjPath)
@@ -429,13 +808,11 @@ func (p *Posix) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteM
 			return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
 		}
 
-		if i == 0 {
-			partsize = fi.Size()
-		}
 		totalsize += fi.Size()
-		// all parts except the last need to be the same size
-		if i < last && partsize != fi.Size() {
-			return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
+		partSizes = append(partSizes, fi.Size())
+		// all parts except the last need to meet the minimum part size
+		if i < last && fi.Size() < backend.MinPartSize {
+			return nil, s3err.GetAPIError(s3err.ErrEntityTooSmall)
 		}
 
 		b, err := p.meta.RetrieveAttribute(bucket, partObjPath, etagkey)
@@ -446,12 +823,36 @@ func (p *Posix) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteM
 		if etag != *parts[i].ETag {
 			return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
 		}
+
+		partChecksum := p.objectChecksum(bucket, partObjPath)
+		err = backend.CheckPartChecksum(part, partChecksum)
+		if err != nil {
+			return nil, err
+		}
+		partChecksums = append(partChecksums, partChecksum)
+	}
+
+	var checksum *backend.Checksum
+	algo := p.uploadChecksumAlgorithm(bucket, filepath.Join(objdir, uploadID))
+	if algo != "" {
+		checksum, err = backend.CompositeChecksum(algo, partChecksums)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the moved parts only add extents to the file, so the temp file
+	// is not preallocated for them
+	move := p.partMover != nil && p.partMover.CanMove(partSizes)
+	allocsize := totalsize
+	if move {
+		allocsize = 0
 	}
 
 	f, err := p.openTmpFile(filepath.Join(bucket, metaTmpDir), bucket, object,
-		totalsize, acct)
+		allocsize, acct)
 	if err != nil {
-		if errors.Is(err, syscall.EDQUOT) {
+		if isNoSpace(err) {
 			return nil, s3err.GetAPIError(s3err.ErrQuotaExceeded)
 		}
 		return nil, fmt.Errorf("open temp file: %w", err)
@@ -465,6 +866,14 @@ func (p *Posix) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteM
 		if err != nil {
 			return nil, fmt.Errorf("open part %v: %v", *part.PartNumber, err)
 		}
+		if move {
+			err = p.partMover.MovePart(f.f, pf)
+			pf.Close()
+			if err != nil {
+				return nil, fmt.Errorf("move part %v: %w", *part.PartNumber, err)
+			}
+			continue
+		}
 		_, err = io.Copy(f, pf)
 		pf.Close()
 		if err != nil {
@@ -475,56 +884,185 @@ func (p *Posix) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteM
 		}
 	}
 
+	// the user metadata is stored with the upload when it is created
 	userMetaData := make(map[string]string)
 	upiddir := filepath.Join(objdir, uploadID)
-	p.loadUserMetaData(bucket, objdir, userMetaData)
+	p.loadUserMetaData(bucket, upiddir, userMetaData)
 
 	objname := filepath.Join(bucket, object)
 	dir := filepath.Dir(objname)
 	if dir != "" {
 		uid, gid, doChown := p.getChownIDs(acct)
-		err = backend.MkdirAll(dir, uid, gid, doChown)
+		dirPerm, _ := p.objectPerms(bucket)
+		err = backend.MkdirAllPerm(dir, dirPerm, p.exactPerms, uid, gid, doChown)
 		if err != nil {
 			return nil, err
 		}
 	}
-	err = f.link()
-	if err != nil {
-		return nil, fmt.Errorf("link object in namespace: %w", err)
-	}
 
+	attrs := make(map[string][]byte)
 	for k, v := range userMetaData {
-		err = p.meta.StoreAttribute(bucket, object, k, []byte(v))
-		if err != nil {
-			// cleanup object if returning error
-			os.Remove(objname)
-			return nil, fmt.Errorf("set user attr %q: %w", k, err)
+		if strings.EqualFold(k, expiresHdr) {
+			// applied with the object headers below
+			continue
 		}
+		attrs[fmt.Sprintf("%v.%v", metaHdr, k)] = []byte(v)
 	}
 
 	// Calculate s3 compatible md5sum for complete multipart.
 	s3MD5 := backend.GetMultipartMD5(parts)
+	attrs[etagkey] = []byte(s3MD5)
 
-	err = p.meta.StoreAttribute(bucket, object, etagkey, []byte(s3MD5))
+	// the part sizes locate the parts for reads by part number
+	b, err := json.Marshal(partSizes)
 	if err != nil {
-		// cleanup object if returning error
-		os.Remove(objname)
-		return nil, fmt.Errorf("set etag attr: %w", err)
+		return nil, fmt.Errorf("marshal part sizes: %w", err)
+	}
+	attrs[partSizesKey] = b
+
+	if checksum != nil {
+		b, err := json.Marshal(checksum)
+		if err != nil {
+			return nil, fmt.Errorf("marshal checksum: %w", err)
+		}
+		attrs[checksumKey] = b
+	}
+
+	if acct.Access != "" {
+		attrs[ownerkey] = []byte(acct.Access)
+	}
+
+	acl, err := p.meta.RetrieveAttribute(bucket, upiddir, aclkey)
+	if err == nil {
+		attrs[aclkey] = acl
+	}
+
+	for _, k := range uploadObjectKeys {
+		v, err := p.meta.RetrieveAttribute(bucket, upiddir, k)
+		if err != nil {
+			continue
+		}
+		attrs[k] = v
+	}
+	err = p.setDefaultRetention(bucket, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	// the object and its attributes are replaced under the key lock
+	unlock := p.keyLocks.lock(bucket, object)
+	defer unlock()
+
+	err = p.linkObject(f, bucket, object, totalsize, attrs)
+	if errors.Is(err, s3err.GetAPIError(s3err.ErrQuotaExceeded)) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("link object in namespace: %w", err)
 	}
 
 	// cleanup tmp dirs
-	os.RemoveAll(upiddir)
+	os.RemoveAll(filepath.Join(bucket, upiddir))
+	p.meta.DeleteAttributes(bucket, upiddir)
 	// use Remove for objdir in case there are still other uploads
 	// for same object name outstanding, this will fail if there are
-	os.Remove(filepath.Join(bucket, objdir))
+	if os.Remove(filepath.Join(bucket, objdir)) == nil {
+		p.meta.DeleteAttributes(bucket, objdir)
+	}
 
+	checksumCRC32, checksumCRC32C, checksumSHA1, checksumSHA256 := checksum.Fields()
 	return &s3.CompleteMultipartUploadOutput{
-		Bucket: &bucket,
-		ETag:   &s3MD5,
-		Key:    &object,
+		Bucket:         &bucket,
+		ETag:           &s3MD5,
+		Key:            &object,
+		ChecksumCRC32:  checksumCRC32,
+		ChecksumCRC32C: checksumCRC32C,
+		ChecksumSHA1:   checksumSHA1,
+		ChecksumSHA256: checksumSHA256,
 	}, nil
 }
 
+// uploadChecksumAlgorithm returns the checksum algorithm requested when
+// the upload was created, or "" for uploads without checksums
+func (p *Posix) uploadChecksumAlgorithm(bucket, upiddir string) types.ChecksumAlgorithm {
+	b, err := p.meta.RetrieveAttribute(bucket, upiddir, checksumAlgorithmKey)
+	if err != nil {
+		return ""
+	}
+	return types.ChecksumAlgorithm(b)
+}
+
+// partChecksumHash returns the hash computing the checksum of the parts
+// of the upload, or nil for uploads without checksums. A checksum sent
+// with the part must use the checksum algorithm of the upload.
+func (p *Posix) partChecksumHash(bucket, upiddir string, sent *backend.Checksum) (types.ChecksumAlgorithm, hash.Hash, error) {
+	algo := p.uploadChecksumAlgorithm(bucket, upiddir)
+	if algo == "" {
+		return "", nil, nil
+	}
+	if sent != nil && sent.Algorithm != algo {
+		return "", nil, s3err.GetAPIError(s3err.ErrChecksumAlgorithmMismatch)
+	}
+	h, err := backend.NewChecksumHash(algo)
+	if err != nil {
+		return "", nil, err
+	}
+	return algo, h, nil
+}
+
+// storePartChecksum stores the checksum computed for the part
+func (p *Posix) storePartChecksum(bucket, partPath string, algo types.ChecksumAlgorithm, h hash.Hash) error {
+	b, err := json.Marshal(backend.Checksum{
+		Algorithm: algo,
+		Value:     base64.StdEncoding.EncodeToString(h.Sum(nil)),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal part checksum: %w", err)
+	}
+	err = p.meta.StoreAttribute(bucket, partPath, checksumKey, b)
+	if err != nil {
+		return fmt.Errorf("set part checksum attr: %w", err)
+	}
+	return nil
+}
+
+// uploadInitiator returns the account that created the upload, or ""
+// for uploads without a recorded initiator
+func (p *Posix) uploadInitiator(bucket, upiddir string) string {
+	b, err := p.meta.RetrieveAttribute(bucket, upiddir, ownerkey)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// uploadOwner returns the initiator of the upload as the owner reported
+// in the upload listings, uploads without a recorded initiator are
+// reported as owned by the bucket owner
+func (p *Posix) uploadOwner(bucket, upiddir string, bucketOwner s3response.Owner) s3response.Owner {
+	initiator := p.uploadInitiator(bucket, upiddir)
+	if initiator == "" {
+		return bucketOwner
+	}
+	return s3response.Owner{ID: initiator, DisplayName: initiator}
+}
+
+// checkUploadAccess verifies that the account of the request may access
+// the upload
+func (p *Posix) checkUploadAccess(ctx context.Context, bucket, upiddir string) error {
+	initiator := p.uploadInitiator(bucket, upiddir)
+	if initiator == "" {
+		return nil
+	}
+
+	bucketOwner, err := p.getBucketOwner(bucket)
+	if err != nil {
+		return err
+	}
+
+	return auth.VerifyUploadAccess(ctx, initiator, getString(bucketOwner.ID))
+}
+
 func (p *Posix) checkUploadIDExists(bucket, object, uploadID string) ([32]byte, error) {
 	sum := sha256.Sum256([]byte(object))
 	objdir := filepath.Join(bucket, metaTmpMultipartDir, fmt.Sprintf("%x", sum))
@@ -573,22 +1111,93 @@ func (p *Posix) loadUserMetaData(bucket, object string, m map[string]string) (st
 		m[strings.TrimPrefix(e, fmt.Sprintf("%v.", metaHdr))] = string(b)
 	}
 
-	var contentType, contentEncoding string
 	b, _ := p.meta.RetrieveAttribute(bucket, object, contentTypeHdr)
-	contentType = string(b)
-	if contentType != "" {
-		m[contentTypeHdr] = contentType
-	}
+	contentType := string(b)
 
 	b, _ = p.meta.RetrieveAttribute(bucket, object, contentEncHdr)
-	contentEncoding = string(b)
-	if contentEncoding != "" {
-		m[contentEncHdr] = contentEncoding
-	}
+	contentEncoding := string(b)
 
 	return contentType, contentEncoding
 }
 
+// objectHeaders are the standard http headers stored with an object
+// and returned as is on get and head object
+type objectHeaders struct {
+	cacheControl       *string
+	contentDisposition *string
+	contentLanguage    *string
+	expires            *time.Time
+}
+
+// objectHeaderKeys are the attributes holding the objectHeaders
+var objectHeaderKeys = []string{
+	cacheControlHdr,
+	contentDispHdr,
+	contentLangHdr,
+	expiresHdr,
+}
+
+// uploadObjectKeys are the attributes stored with a multipart upload
+// when it is created that are applied to the object on completion
+var uploadObjectKeys = append([]string{
+	contentTypeHdr,
+	contentEncHdr,
+	tagHdr,
+	objectLegalHoldKey,
+	objectRetentionKey,
+}, objectHeaderKeys...)
+
+// objectHeaderAttrs returns the attributes holding the set objectHeaders
+func objectHeaderAttrs(h objectHeaders) map[string][]byte {
+	attrs := make(map[string][]byte)
+	set := func(key string, v *string) {
+		if getString(v) != "" {
+			attrs[key] = []byte(*v)
+		}
+	}
+
+	set(cacheControlHdr, h.cacheControl)
+	set(contentDispHdr, h.contentDisposition)
+	set(contentLangHdr, h.contentLanguage)
+	if h.expires != nil {
+		attrs[expiresHdr] = []byte(h.expires.UTC().Format(time.RFC3339))
+	}
+	return attrs
+}
+
+func (p *Posix) storeObjectHeaders(bucket, object string, h objectHeaders) error {
+	for k, v := range objectHeaderAttrs(h) {
+		err := p.meta.StoreAttribute(bucket, object, k, v)
+		if err != nil {
+			return fmt.Errorf("set %v attr: %w", k, err)
+		}
+	}
+	return nil
+}
+
+func (p *Posix) loadObjectHeaders(bucket, object string) objectHeaders {
+	var h objectHeaders
+	load := func(key string) *string {
+		b, err := p.meta.RetrieveAttribute(bucket, object, key)
+		if err != nil || len(b) == 0 {
+			return nil
+		}
+		v := string(b)
+		return &v
+	}
+
+	h.cacheControl = load(cacheControlHdr)
+	h.contentDisposition = load(contentDispHdr)
+	h.contentLanguage = load(contentLangHdr)
+	if exp := load(expiresHdr); exp != nil {
+		t, err := time.Parse(time.RFC3339, *exp)
+		if err == nil {
+			h.expires = &t
+		}
+	}
+	return h
+}
+
 func compareUserMetadata(meta1, meta2 map[string]string) bool {
 	if len(meta1) != len(meta2) {
 		return false
@@ -613,7 +1222,54 @@ func isValidMeta(val string) bool {
 	return false
 }
 
-func (p *Posix) AbortMultipartUpload(_ context.Context, mpu *s3.AbortMultipartUploadInput) error {
+var _ backend.UploadMetadataStorer = &Posix{}
+
+// GetUploadMetadata returns the user metadata stored with the upload
+func (p *Posix) GetUploadMetadata(_ context.Context, bucket, object, uploadID string) (map[string]string, error) {
+	sum, err := p.checkUploadIDExists(bucket, object, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	upiddir := filepath.Join(metaTmpMultipartDir, fmt.Sprintf("%x", sum), uploadID)
+
+	m := make(map[string]string)
+	p.loadUserMetaData(bucket, upiddir, m)
+	for k := range m {
+		if strings.EqualFold(k, expiresHdr) {
+			// stored with the object headers, not user metadata
+			delete(m, k)
+		}
+	}
+	return m, nil
+}
+
+// SetUploadMetadata updates the user metadata stored with the upload,
+// which is applied to the object on completion
+func (p *Posix) SetUploadMetadata(_ context.Context, bucket, object, uploadID string, m map[string]string) error {
+	sum, err := p.checkUploadIDExists(bucket, object, uploadID)
+	if err != nil {
+		return err
+	}
+	upiddir := filepath.Join(metaTmpMultipartDir, fmt.Sprintf("%x", sum), uploadID)
+
+	for k, v := range m {
+		attr := fmt.Sprintf("%v.%v", metaHdr, k)
+		if v == "" {
+			err := p.meta.DeleteAttribute(bucket, upiddir, attr)
+			if err != nil && !errors.Is(err, meta.ErrNoSuchKey) {
+				return fmt.Errorf("remove user attr %q: %w", k, err)
+			}
+			continue
+		}
+		err := p.meta.StoreAttribute(bucket, upiddir, attr, []byte(v))
+		if err != nil {
+			return fmt.Errorf("set user attr %q: %w", k, err)
+		}
+	}
+	return nil
+}
+
+func (p *Posix) AbortMultipartUpload(ctx context.Context, mpu *s3.AbortMultipartUploadInput) error {
 	if mpu.Bucket == nil {
 		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
 	}
@@ -644,11 +1300,23 @@ func (p *Posix) AbortMultipartUpload(_ context.Context, mpu *s3.AbortMultipartUp
 		return s3err.GetAPIError(s3err.ErrNoSuchUpload)
 	}
 
+	mpobjdir := filepath.Join(metaTmpMultipartDir, fmt.Sprintf("%x", sum))
+	err = p.checkUploadAccess(ctx, bucket, filepath.Join(mpobjdir, uploadID))
+	if err != nil {
+		return err
+	}
+
 	err = os.RemoveAll(filepath.Join(objdir, uploadID))
 	if err != nil {
 		return fmt.Errorf("remove multipart upload container: %w", err)
 	}
-	os.Remove(objdir)
+	err = p.meta.DeleteAttributes(bucket, filepath.Join(mpobjdir, uploadID))
+	if err != nil {
+		return fmt.Errorf("remove multipart upload attributes: %w", err)
+	}
+	if os.Remove(objdir) == nil {
+		p.meta.DeleteAttributes(bucket, mpobjdir)
+	}
 
 	return nil
 }
@@ -678,9 +1346,18 @@ func (p *Posix) ListMultipartUploads(_ context.Context, mpu *s3.ListMultipartUpl
 		return lmu, fmt.Errorf("stat bucket: %w", err)
 	}
 
-	// ignore readdir error and use the empty list returned
-	objs, _ := os.ReadDir(filepath.Join(bucket, metaTmpMultipartDir))
-
+	bucketOwner, err := p.getBucketOwner(bucket)
+	if err != nil {
+		return lmu, err
+	}
+	owner := s3response.Owner{
+		ID:          getString(bucketOwner.ID),
+		DisplayName: getString(bucketOwner.DisplayName),
+	}
+
+	// ignore readdir error and use the empty list returned
+	objs, _ := os.ReadDir(filepath.Join(bucket, metaTmpMultipartDir))
+
 	var uploads []s3response.Upload
 	var resultUpds []s3response.Upload
 
@@ -718,10 +1395,6 @@ func (p *Posix) ListMultipartUploads(_ context.Context, mpu *s3.ListMultipartUpl
 				continue
 			}
 
-			// userMetaData := make(map[string]string)
-			// upiddir := filepath.Join(bucket, metaTmpMultipartDir, obj.Name(), upid.Name())
-			// loadUserMetaData(upiddir, userMetaData)
-
 			fi, err := upid.Info()
 			if err != nil {
 				return lmu, fmt.Errorf("stat %q: %w", upid.Name(), err)
@@ -734,9 +1407,13 @@ func (p *Posix) ListMultipartUploads(_ context.Context, mpu *s3.ListMultipartUpl
 			if keyMarkerInd == -1 && objectName == keyMarker {
 				keyMarkerInd = len(uploads)
 			}
+			initiator := p.uploadOwner(bucket,
+				filepath.Join(metaTmpMultipartDir, obj.Name(), uploadID), owner)
 			uploads = append(uploads, s3response.Upload{
 				Key:       objectName,
 				UploadID:  uploadID,
+				Initiator: s3response.Initiator(initiator),
+				Owner:     initiator,
 				Initiated: fi.ModTime().Format(backend.RFC3339TimeFormat),
 			})
 		}
@@ -818,11 +1495,6 @@ func (p *Posix) ListParts(_ context.Context, input *s3.ListPartsInput) (s3respon
 	if input.PartNumberMarker != nil {
 		stringMarker = *input.PartNumberMarker
 	}
-	maxParts := 0
-	if input.MaxParts != nil {
-		maxParts = int(*input.MaxParts)
-	}
-
 	var partNumberMarker int
 	if stringMarker != "" {
 		var err error
@@ -858,17 +1530,19 @@ func (p *Posix) ListParts(_ context.Context, input *s3.ListPartsInput) (s3respon
 
 	var parts []s3response.Part
 	for _, e := range ents {
-		pn, _ := strconv.Atoi(e.Name())
-		if pn <= partNumberMarker {
+		pn, err := strconv.Atoi(e.Name())
+		if err != nil || pn <= partNumberMarker {
 			continue
 		}
 
+		// the part etag is stored once the part data is in place, so
+		// skip any part still being uploaded
 		partPath := filepath.Join(objdir, uploadID, e.Name())
 		b, err := p.meta.RetrieveAttribute(bucket, partPath, etagkey)
-		etag := string(b)
 		if err != nil {
-			etag = ""
+			continue
 		}
+		etag := string(b)
 
 		fi, err := os.Lstat(filepath.Join(bucket, partPath))
 		if err != nil {
@@ -883,27 +1557,24 @@ func (p *Posix) ListParts(_ context.Context, input *s3.ListPartsInput) (s3respon
 		})
 	}
 
-	sort.Slice(parts,
-		func(i int, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
-
-	oldLen := len(parts)
-	if maxParts > 0 && len(parts) > maxParts {
-		parts = parts[:maxParts]
-	}
-	newLen := len(parts)
+	parts, maxParts, nextpart, truncated := backend.PageParts(parts, input.MaxParts)
 
-	nextpart := 0
-	if len(parts) != 0 {
-		nextpart = parts[len(parts)-1].PartNumber
+	bucketOwner, err := p.getBucketOwner(bucket)
+	if err != nil {
+		return lpr, err
 	}
-
-	userMetaData := make(map[string]string)
-	upiddir := filepath.Join(objdir, uploadID)
-	p.loadUserMetaData(bucket, upiddir, userMetaData)
+	initiator := p.uploadOwner(bucket, filepath.Join(objdir, uploadID),
+		s3response.Owner{
+			ID:          getString(bucketOwner.ID),
+			DisplayName: getString(bucketOwner.DisplayName),
+		})
 
 	return s3response.ListPartsResult{
 		Bucket:               bucket,
-		IsTruncated:          oldLen != newLen,
+		Initiator:            s3response.Initiator(initiator),
+		Owner:                initiator,
+		StorageClass:         string(types.StorageClassStandard),
+		IsTruncated:          truncated,
 		Key:                  object,
 		MaxParts:             maxParts,
 		NextPartNumberMarker: nextpart,
@@ -955,19 +1626,36 @@ func (p *Posix) UploadPart(ctx context.Context, input *s3.UploadPartInput) (stri
 		return "", fmt.Errorf("stat uploadid: %w", err)
 	}
 
+	err = p.checkUploadAccess(ctx, bucket, filepath.Join(objdir, uploadID))
+	if err != nil {
+		return "", err
+	}
+
+	algo, chash, err := p.partChecksumHash(bucket, filepath.Join(objdir, uploadID),
+		backend.ChecksumFromFields(input.ChecksumCRC32, input.ChecksumCRC32C,
+			input.ChecksumSHA1, input.ChecksumSHA256))
+	if err != nil {
+		return "", err
+	}
+
 	partPath := filepath.Join(objdir, uploadID, fmt.Sprintf("%v", *part))
 
 	f, err := p.openTmpFile(filepath.Join(bucket, objdir),
 		bucket, partPath, length, acct)
 	if err != nil {
-		if errors.Is(err, syscall.EDQUOT) {
+		if isNoSpace(err) {
 			return "", s3err.GetAPIError(s3err.ErrQuotaExceeded)
 		}
 		return "", fmt.Errorf("open temp file: %w", err)
 	}
+	defer f.cleanup()
 
 	hash := md5.New()
-	tr := io.TeeReader(r, hash)
+	var hw io.Writer = hash
+	if chash != nil {
+		hw = io.MultiWriter(hash, chash)
+	}
+	tr := io.TeeReader(r, hw)
 	_, err = io.Copy(f, tr)
 	if err != nil {
 		if errors.Is(err, syscall.EDQUOT) {
@@ -981,8 +1669,6 @@ func (p *Posix) UploadPart(ctx context.Context, input *s3.UploadPartInput) (stri
 		return "", fmt.Errorf("link object in namespace: %w", err)
 	}
 
-	f.cleanup()
-
 	dataSum := hash.Sum(nil)
 	etag := hex.EncodeToString(dataSum)
 	err = p.meta.StoreAttribute(bucket, partPath, etagkey, []byte(etag))
@@ -990,6 +1676,13 @@ func (p *Posix) UploadPart(ctx context.Context, input *s3.UploadPartInput) (stri
 		return "", fmt.Errorf("set etag attr: %w", err)
 	}
 
+	if chash != nil {
+		err = p.storePartChecksum(bucket, partPath, algo, chash)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	return etag, nil
 }
 
@@ -1025,6 +1718,11 @@ func (p *Posix) UploadPartCopy(ctx context.Context, upi *s3.UploadPartCopyInput)
 		return s3response.CopyObjectResult{}, fmt.Errorf("stat uploadid: %w", err)
 	}
 
+	err = p.checkUploadAccess(ctx, *upi.Bucket, filepath.Join(objdir, *upi.UploadId))
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
 	partPath := filepath.Join(objdir, *upi.UploadId, fmt.Sprintf("%v", *upi.PartNumber))
 
 	substrs := strings.SplitN(*upi.CopySource, "/", 2)
@@ -1043,6 +1741,10 @@ func (p *Posix) UploadPartCopy(ctx context.Context, upi *s3.UploadPartCopyInput)
 		return s3response.CopyObjectResult{}, fmt.Errorf("stat bucket: %w", err)
 	}
 
+	if p.isExcluded(srcObject) {
+		return s3response.CopyObjectResult{}, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
 	objPath := filepath.Join(srcBucket, srcObject)
 	fi, err := os.Stat(objPath)
 	if errors.Is(err, fs.ErrNotExist) {
@@ -1052,23 +1754,29 @@ func (p *Posix) UploadPartCopy(ctx context.Context, upi *s3.UploadPartCopyInput)
 		return s3response.CopyObjectResult{}, fmt.Errorf("stat object: %w", err)
 	}
 
-	startOffset, length, err := backend.ParseRange(fi, *upi.CopySourceRange)
+	srcEtag := p.objectEtag(srcBucket, srcObject, fi)
+
+	err = backend.EvaluateCopySourcePreconditions(srcEtag, fi.ModTime(),
+		upi.CopySourceIfMatch, upi.CopySourceIfNoneMatch,
+		upi.CopySourceIfModifiedSince, upi.CopySourceIfUnmodifiedSince)
 	if err != nil {
 		return s3response.CopyObjectResult{}, err
 	}
 
-	if length == -1 {
-		length = fi.Size() - startOffset + 1
+	var copyRange string
+	if upi.CopySourceRange != nil {
+		copyRange = *upi.CopySourceRange
 	}
 
-	if startOffset+length > fi.Size()+1 {
-		return s3response.CopyObjectResult{}, s3err.GetAPIError(s3err.ErrInvalidRange)
+	startOffset, length, err := backend.ParseCopySourceRange(fi.Size(), copyRange)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
 	}
 
 	f, err := p.openTmpFile(filepath.Join(*upi.Bucket, objdir),
 		*upi.Bucket, partPath, length, acct)
 	if err != nil {
-		if errors.Is(err, syscall.EDQUOT) {
+		if isNoSpace(err) {
 			return s3response.CopyObjectResult{}, s3err.GetAPIError(s3err.ErrQuotaExceeded)
 		}
 		return s3response.CopyObjectResult{}, fmt.Errorf("open temp file: %w", err)
@@ -1084,13 +1792,35 @@ func (p *Posix) UploadPartCopy(ctx context.Context, upi *s3.UploadPartCopyInput)
 	}
 	defer srcf.Close()
 
-	rdr := io.NewSectionReader(srcf, startOffset, length)
-	hash := md5.New()
-	tr := io.TeeReader(rdr, hash)
+	// a copy of the whole source keeps a known md5 etag, otherwise the
+	// etag of the part is the md5 of the copied range
+	var etag string
+	if startOffset == 0 && length == fi.Size() && isMD5Etag(srcEtag) {
+		etag = srcEtag
+	} else {
+		hash := md5.New()
+		err = hashSection(hash, srcf, startOffset, length)
+		if err != nil {
+			return s3response.CopyObjectResult{}, fmt.Errorf("hash part data: %w", err)
+		}
+		etag = hex.EncodeToString(hash.Sum(nil))
+	}
 
-	_, err = io.Copy(f, tr)
+	algo, chash, err := p.partChecksumHash(*upi.Bucket,
+		filepath.Join(objdir, *upi.UploadId), nil)
 	if err != nil {
-		if errors.Is(err, syscall.EDQUOT) {
+		return s3response.CopyObjectResult{}, err
+	}
+	if chash != nil {
+		err = hashSection(chash, srcf, startOffset, length)
+		if err != nil {
+			return s3response.CopyObjectResult{}, fmt.Errorf("checksum part data: %w", err)
+		}
+	}
+
+	err = f.copyFrom(srcf, startOffset, length)
+	if err != nil {
+		if isNoSpace(err) {
 			return s3response.CopyObjectResult{}, s3err.GetAPIError(s3err.ErrQuotaExceeded)
 		}
 		return s3response.CopyObjectResult{}, fmt.Errorf("copy part data: %w", err)
@@ -1101,13 +1831,18 @@ func (p *Posix) UploadPartCopy(ctx context.Context, upi *s3.UploadPartCopyInput)
 		return s3response.CopyObjectResult{}, fmt.Errorf("link object in namespace: %w", err)
 	}
 
-	dataSum := hash.Sum(nil)
-	etag := hex.EncodeToString(dataSum)
 	err = p.meta.StoreAttribute(*upi.Bucket, partPath, etagkey, []byte(etag))
 	if err != nil {
 		return s3response.CopyObjectResult{}, fmt.Errorf("set etag attr: %w", err)
 	}
 
+	if chash != nil {
+		err = p.storePartChecksum(*upi.Bucket, partPath, algo, chash)
+		if err != nil {
+			return s3response.CopyObjectResult{}, err
+		}
+	}
+
 	fi, err = os.Stat(filepath.Join(*upi.Bucket, partPath))
 	if err != nil {
 		return s3response.CopyObjectResult{}, fmt.Errorf("stat part path: %w", err)
@@ -1132,8 +1867,6 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 		return "", s3err.GetAPIError(s3err.ErrNoSuchKey)
 	}
 
-	tagsStr := getString(po.Tagging)
-	tags := make(map[string]string)
 	_, err := os.Stat(*po.Bucket)
 	if errors.Is(err, fs.ErrNotExist) {
 		return "", s3err.GetAPIError(s3err.ErrNoSuchBucket)
@@ -1142,18 +1875,9 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 		return "", fmt.Errorf("stat bucket: %w", err)
 	}
 
-	if tagsStr != "" {
-		tagParts := strings.Split(tagsStr, "&")
-		for _, prt := range tagParts {
-			p := strings.Split(prt, "=")
-			if len(p) != 2 {
-				return "", s3err.GetAPIError(s3err.ErrInvalidTag)
-			}
-			if len(p[0]) > 128 || len(p[1]) > 256 {
-				return "", s3err.GetAPIError(s3err.ErrInvalidTag)
-			}
-			tags[p[0]] = p[1]
-		}
+	tags, err := backend.ParseObjectTags(getString(po.Tagging))
+	if err != nil {
+		return "", err
 	}
 
 	name := filepath.Join(*po.Bucket, *po.Key)
@@ -1173,7 +1897,11 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 			return "", s3err.GetAPIError(s3err.ErrDirectoryObjectContainsData)
 		}
 
-		err = backend.MkdirAll(name, uid, gid, doChown)
+		unlock := p.keyLocks.lock(*po.Bucket, *po.Key)
+		defer unlock()
+
+		dirPerm, _ := p.objectPerms(*po.Bucket)
+		err = backend.MkdirAllPerm(name, dirPerm, p.exactPerms, uid, gid, doChown)
 		if err != nil {
 			if errors.Is(err, syscall.EDQUOT) {
 				return "", s3err.GetAPIError(s3err.ErrQuotaExceeded)
@@ -1195,6 +1923,22 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 			return "", fmt.Errorf("set etag attr: %w", err)
 		}
 
+		err = p.storeObjectOwner(*po.Bucket, *po.Key, acct)
+		if err != nil {
+			return "", err
+		}
+
+		err = p.storeNewObjectAcl(*po.Bucket, *po.Key, acct.Access, po.ACL,
+			auth.Grants{
+				FullControl: po.GrantFullControl,
+				Read:        po.GrantRead,
+				ReadACP:     po.GrantReadACP,
+				WriteACP:    po.GrantWriteACP,
+			})
+		if err != nil {
+			return "", err
+		}
+
 		return emptyMD5, nil
 	}
 
@@ -1204,10 +1948,20 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 		return "", s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
 	}
 
-	f, err := p.openTmpFile(filepath.Join(*po.Bucket, metaTmpDir),
-		*po.Bucket, *po.Key, contentLength, acct)
+	tmpdir := filepath.Join(*po.Bucket, metaTmpDir)
+	if p.putTmpDir != nil {
+		dir, err := p.putTmpDir(po)
+		if err != nil {
+			return "", err
+		}
+		if dir != "" {
+			tmpdir = dir
+		}
+	}
+
+	f, err := p.openTmpFile(tmpdir, *po.Bucket, *po.Key, contentLength, acct)
 	if err != nil {
-		if errors.Is(err, syscall.EDQUOT) {
+		if isNoSpace(err) {
 			return "", s3err.GetAPIError(s3err.ErrQuotaExceeded)
 		}
 		return "", fmt.Errorf("open temp file: %w", err)
@@ -1215,8 +1969,19 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 	defer f.cleanup()
 
 	hash := md5.New()
-	rdr := io.TeeReader(po.Body, hash)
-	_, err = io.Copy(f, rdr)
+	var copyEtag string
+	if src, ok := po.Body.(*copySource); ok {
+		copyEtag = src.etag
+		if copyEtag == "" {
+			err = hashSection(hash, src.File, 0, contentLength)
+		}
+		if err == nil {
+			err = f.copyFrom(src.File, 0, contentLength)
+		}
+	} else {
+		rdr := io.TeeReader(po.Body, hash)
+		_, err = io.Copy(f, rdr)
+	}
 	if err != nil {
 		if errors.Is(err, syscall.EDQUOT) {
 			return "", s3err.GetAPIError(s3err.ErrQuotaExceeded)
@@ -1225,65 +1990,196 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 	}
 	dir := filepath.Dir(name)
 	if dir != "" {
-		err = backend.MkdirAll(dir, uid, gid, doChown)
+		err = backend.MkdirAllPerm(dir, f.dirPerm, p.exactPerms, uid, gid, doChown)
 		if err != nil {
 			return "", s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
 		}
 	}
 
-	err = f.link()
-	if err != nil {
-		return "", s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
+	attrs := make(map[string][]byte)
+	for k, v := range po.Metadata {
+		attrs[fmt.Sprintf("%v.%v", metaHdr, k)] = []byte(v)
 	}
 
-	for k, v := range po.Metadata {
-		err := p.meta.StoreAttribute(*po.Bucket, *po.Key,
-			fmt.Sprintf("%v.%v", metaHdr, k), []byte(v))
-		if err != nil {
-			return "", fmt.Errorf("set user attr %q: %w", k, err)
-		}
+	contentType := getString(po.ContentType)
+	if contentType == "" {
+		contentType = p.detectContentType(*po.Key, f)
+	}
+	if contentType != "" {
+		attrs[contentTypeHdr] = []byte(contentType)
+	}
+
+	if getString(po.ContentEncoding) != "" {
+		attrs[contentEncHdr] = []byte(*po.ContentEncoding)
 	}
 
-	// Set object tagging
-	if tagsStr != "" {
-		err := p.PutObjectTagging(ctx, *po.Bucket, *po.Key, tags)
+	for k, v := range objectHeaderAttrs(objectHeaders{
+		cacheControl:       po.CacheControl,
+		contentDisposition: po.ContentDisposition,
+		contentLanguage:    po.ContentLanguage,
+		expires:            po.Expires,
+	}) {
+		attrs[k] = v
+	}
+
+	if tags != nil {
+		b, err := json.Marshal(tags)
 		if err != nil {
-			return "", err
+			return "", fmt.Errorf("marshal tags: %w", err)
 		}
+		attrs[tagHdr] = b
 	}
 
-	// Set object legal hold
-	if po.ObjectLockLegalHoldStatus == types.ObjectLockLegalHoldStatusOn {
-		if err := p.PutObjectLegalHold(ctx, *po.Bucket, *po.Key, "", true); err != nil {
-			return "", err
-		}
+	lockAttrs, err := p.objectLockAttrs(*po.Bucket, po.ObjectLockMode,
+		po.ObjectLockRetainUntilDate, po.ObjectLockLegalHoldStatus)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range lockAttrs {
+		attrs[k] = v
+	}
+	err = p.setDefaultRetention(*po.Bucket, attrs)
+	if err != nil {
+		return "", err
 	}
 
-	// Set object retention
-	if po.ObjectLockMode != "" {
-		retention := types.ObjectLockRetention{
-			Mode:            types.ObjectLockRetentionMode(po.ObjectLockMode),
-			RetainUntilDate: po.ObjectLockRetainUntilDate,
-		}
-		retParsed, err := json.Marshal(retention)
+	// the checksum was verified against the data as it was read
+	if checksum := backend.ChecksumFromFields(po.ChecksumCRC32,
+		po.ChecksumCRC32C, po.ChecksumSHA1, po.ChecksumSHA256); checksum != nil {
+		b, err := json.Marshal(checksum)
 		if err != nil {
-			return "", fmt.Errorf("parse object lock retention: %w", err)
-		}
-		if err := p.PutObjectRetention(ctx, *po.Bucket, *po.Key, "", retParsed); err != nil {
-			return "", err
+			return "", fmt.Errorf("marshal checksum: %w", err)
 		}
+		attrs[checksumKey] = b
 	}
 
-	dataSum := hash.Sum(nil)
-	etag := hex.EncodeToString(dataSum[:])
-	err = p.meta.StoreAttribute(*po.Bucket, *po.Key, etagkey, []byte(etag))
+	etag := copyEtag
+	if etag == "" {
+		dataSum := hash.Sum(nil)
+		etag = hex.EncodeToString(dataSum[:])
+	}
+	attrs[etagkey] = []byte(etag)
+
+	if acct.Access != "" {
+		attrs[ownerkey] = []byte(acct.Access)
+	}
+
+	acl, err := p.newObjectAcl(*po.Bucket, acct.Access, po.ACL,
+		auth.Grants{
+			FullControl: po.GrantFullControl,
+			Read:        po.GrantRead,
+			ReadACP:     po.GrantReadACP,
+			WriteACP:    po.GrantWriteACP,
+		})
 	if err != nil {
-		return "", fmt.Errorf("set etag attr: %w", err)
+		return "", err
+	}
+	if acl != nil {
+		attrs[aclkey] = acl
+	}
+
+	// the object and its attributes are replaced under the key lock
+	unlock := p.keyLocks.lock(*po.Bucket, *po.Key)
+	defer unlock()
+
+	err = p.linkObject(f, *po.Bucket, *po.Key, contentLength, attrs)
+	if errors.Is(err, s3err.GetAPIError(s3err.ErrQuotaExceeded)) ||
+		errors.Is(err, errObjectAttrs) {
+		return "", err
+	}
+	if err != nil {
+		return "", s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
 	}
 
 	return etag, nil
 }
 
+// newObjectAcl returns the encoded acl requested with the upload of a new
+// object, or nil if the object keeps the default acl
+func (p *Posix) newObjectAcl(bucket, owner string, canned types.ObjectCannedACL, grants auth.Grants) ([]byte, error) {
+	bucketOwner, err := p.getBucketOwner(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	acl, ok := auth.NewObjectACL(owner, getString(bucketOwner.ID), canned, grants)
+	if !ok {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(acl)
+	if err != nil {
+		return nil, fmt.Errorf("marshal acl: %w", err)
+	}
+	return b, nil
+}
+
+// storeNewObjectAcl records the acl requested with the upload of a new object
+func (p *Posix) storeNewObjectAcl(bucket, object, owner string, canned types.ObjectCannedACL, grants auth.Grants) error {
+	b, err := p.newObjectAcl(bucket, owner, canned, grants)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return nil
+	}
+
+	err = p.meta.StoreAttribute(bucket, object, aclkey, b)
+	if err != nil {
+		return fmt.Errorf("set acl: %w", err)
+	}
+	return nil
+}
+
+// storeObjectOwner records the account that created the object
+func (p *Posix) storeObjectOwner(bucket, object string, acct auth.Account) error {
+	if acct.Access == "" {
+		return nil
+	}
+
+	err := p.meta.StoreAttribute(bucket, object, ownerkey, []byte(acct.Access))
+	if err != nil {
+		return fmt.Errorf("set owner attr: %w", err)
+	}
+	return nil
+}
+
+// getBucketOwner returns the bucket owner recorded in the bucket acl
+func (p *Posix) getBucketOwner(bucket string) (*types.Owner, error) {
+	b, err := p.meta.RetrieveAttribute(bucket, "", aclkey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		return &types.Owner{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get acl: %w", err)
+	}
+
+	acl, err := auth.ParseACL(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Owner{
+		ID:          &acl.Owner,
+		DisplayName: &acl.Owner,
+	}, nil
+}
+
+// getObjectOwner returns the account that created the object, or the
+// bucket owner for objects without a recorded owner
+func (p *Posix) getObjectOwner(bucket, object string, bucketOwner *types.Owner) *types.Owner {
+	b, err := p.meta.RetrieveAttribute(bucket, object, ownerkey)
+	if err != nil || len(b) == 0 {
+		return bucketOwner
+	}
+
+	owner := string(b)
+	return &types.Owner{
+		ID:          &owner,
+		DisplayName: &owner,
+	}
+}
+
 func (p *Posix) DeleteObject(_ context.Context, input *s3.DeleteObjectInput) error {
 	if input.Bucket == nil {
 		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
@@ -1303,12 +2199,37 @@ func (p *Posix) DeleteObject(_ context.Context, input *s3.DeleteObjectInput) err
 		return fmt.Errorf("stat bucket: %w", err)
 	}
 
-	err = os.Remove(filepath.Join(bucket, object))
+	unlock := p.keyLocks.lock(bucket, object)
+	defer unlock()
+
+	fi, err := os.Lstat(filepath.Join(bucket, object))
 	if errors.Is(err, fs.ErrNotExist) {
 		return s3err.GetAPIError(s3err.ErrNoSuchKey)
 	}
 	if err != nil {
-		return fmt.Errorf("delete object: %w", err)
+		return fmt.Errorf("stat object: %w", err)
+	}
+
+	if p.trashRetention > 0 && fi.Mode().IsRegular() {
+		err = p.trashObject(bucket, object)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = os.Remove(filepath.Join(bucket, object))
+		if errors.Is(err, fs.ErrNotExist) {
+			return s3err.GetAPIError(s3err.ErrNoSuchKey)
+		}
+		if err != nil {
+			return fmt.Errorf("delete object: %w", err)
+		}
+	}
+
+	if fi.Mode().IsRegular() {
+		err = p.releaseBucketQuota(bucket, fi.Size())
+		if err != nil {
+			return err
+		}
 	}
 
 	err = p.meta.DeleteAttributes(bucket, object)
@@ -1329,7 +2250,7 @@ func (p *Posix) removeParents(bucket, object string) error {
 	for {
 		parent := filepath.Dir(objPath)
 
-		if parent == string(filepath.Separator) {
+		if parent == "." || parent == string(filepath.Separator) {
 			// stop removing parents if we hit the bucket directory.
 			break
 		}
@@ -1352,15 +2273,39 @@ func (p *Posix) removeParents(bucket, object string) error {
 	return nil
 }
 
+// deleteObjectsWorkers is the number of objects of a DeleteObjects
+// request deleted concurrently
+const deleteObjectsWorkers = 16
+
 func (p *Posix) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
 	// delete object already checks bucket
+	objs := input.Delete.Objects
+	results := make([]error, len(objs))
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < min(deleteObjectsWorkers, len(objs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = p.DeleteObject(ctx, &s3.DeleteObjectInput{
+					Bucket: input.Bucket,
+					Key:    objs[i].Key,
+				})
+			}
+		}()
+	}
+	for i := range objs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	// the results are reported in the order of the request
 	delResult, errs := []types.DeletedObject{}, []types.Error{}
-	for _, obj := range input.Delete.Objects {
-		//TODO: Make the delete operation concurrent
-		err := p.DeleteObject(ctx, &s3.DeleteObjectInput{
-			Bucket: input.Bucket,
-			Key:    obj.Key,
-		})
+	for i, obj := range objs {
+		err := results[i]
 		if err == nil {
 			delResult = append(delResult, types.DeletedObject{Key: obj.Key})
 		} else {
@@ -1394,9 +2339,6 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 	if input.Key == nil {
 		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
 	}
-	if input.Range == nil {
-		return nil, s3err.GetAPIError(s3err.ErrInvalidRange)
-	}
 
 	bucket := *input.Bucket
 	_, err := os.Stat(bucket)
@@ -1408,6 +2350,10 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 	}
 
 	object := *input.Key
+	if p.isExcluded(object) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
 	objPath := filepath.Join(bucket, object)
 	fi, err := os.Stat(objPath)
 	if errors.Is(err, fs.ErrNotExist) {
@@ -1417,43 +2363,50 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 		return nil, fmt.Errorf("stat object: %w", err)
 	}
 
-	acceptRange := *input.Range
-	startOffset, length, err := backend.ParseRange(fi, acceptRange)
+	etag := p.objectEtag(bucket, object, fi)
+
+	err = backend.EvaluatePreconditions(etag, fi.ModTime(), input.IfMatch,
+		input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
 	if err != nil {
 		return nil, err
 	}
 
+	var acceptRange string
+	if input.Range != nil {
+		acceptRange = *input.Range
+	}
+
 	objSize := fi.Size()
 	if fi.IsDir() {
 		// directory objects are always 0 len
 		objSize = 0
-		length = 0
 	}
 
-	if length == -1 {
-		length = objSize - startOffset + 1
+	var partsCount *int32
+	if input.PartNumber != nil {
+		partSizes := p.objectPartSizes(bucket, object, objSize)
+		acceptRange, err = backend.PartRange(objSize, partSizes, *input.PartNumber)
+		if err != nil {
+			return nil, err
+		}
+		if len(partSizes) != 0 {
+			count := int32(len(partSizes))
+			partsCount = &count
+		}
 	}
 
-	if startOffset+length > objSize+1 {
-		return nil, s3err.GetAPIError(s3err.ErrInvalidRange)
+	startOffset, length, err := backend.ParseRange(objSize, acceptRange)
+	if err != nil {
+		return nil, err
 	}
 
-	var contentRange string
-	if acceptRange != "" {
-		contentRange = fmt.Sprintf("bytes %v-%v/%v",
-			startOffset, startOffset+length-1, objSize)
-	}
+	contentRange := backend.ContentRange(acceptRange, startOffset, length, objSize)
 
 	if fi.IsDir() {
 		userMetaData := make(map[string]string)
 
 		contentType, contentEncoding := p.loadUserMetaData(bucket, object, userMetaData)
-
-		b, err := p.meta.RetrieveAttribute(bucket, object, etagkey)
-		etag := string(b)
-		if err != nil {
-			etag = ""
-		}
+		hdrs := p.loadObjectHeaders(bucket, object)
 
 		var tagCount *int32
 		tags, err := p.getAttrTags(bucket, object)
@@ -1466,15 +2419,20 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 		}
 
 		return &s3.GetObjectOutput{
-			AcceptRanges:    &acceptRange,
-			ContentLength:   &length,
-			ContentEncoding: &contentEncoding,
-			ContentType:     &contentType,
-			ETag:            &etag,
-			LastModified:    backend.GetTimePtr(fi.ModTime()),
-			Metadata:        userMetaData,
-			TagCount:        tagCount,
-			ContentRange:    &contentRange,
+			AcceptRanges:       &acceptRange,
+			ContentLength:      &length,
+			ContentEncoding:    &contentEncoding,
+			ContentType:        &contentType,
+			CacheControl:       hdrs.cacheControl,
+			ContentDisposition: hdrs.contentDisposition,
+			ContentLanguage:    hdrs.contentLanguage,
+			Expires:            hdrs.expires,
+			ETag:               &etag,
+			LastModified:       backend.GetTimePtr(fi.ModTime()),
+			Metadata:           userMetaData,
+			TagCount:           tagCount,
+			ContentRange:       &contentRange,
+			PartsCount:         partsCount,
 		}, nil
 	}
 
@@ -1487,22 +2445,22 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 	}
 	defer f.Close()
 
-	rdr := io.NewSectionReader(f, startOffset, length)
-	_, err = io.Copy(writer, rdr)
-	if err != nil {
-		return nil, fmt.Errorf("copy data: %w", err)
-	}
-
 	userMetaData := make(map[string]string)
 
 	contentType, contentEncoding := p.loadUserMetaData(bucket, object, userMetaData)
 
-	b, err := p.meta.RetrieveAttribute(bucket, object, etagkey)
-	etag := string(b)
+	err = backend.SetWriterMetadata(writer, userMetaData, startOffset)
 	if err != nil {
-		etag = ""
+		return nil, err
+	}
+
+	_, err = io.Copy(writer, p.objectReader(f, startOffset, length))
+	if err != nil {
+		return nil, fmt.Errorf("copy data: %w", err)
 	}
 
+	hdrs := p.loadObjectHeaders(bucket, object)
+
 	var tagCount *int32
 	tags, err := p.getAttrTags(bucket, object)
 	if err != nil && !errors.Is(err, s3err.GetAPIError(s3err.ErrBucketTaggingNotFound)) {
@@ -1514,66 +2472,81 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 	}
 
 	return &s3.GetObjectOutput{
-		AcceptRanges:    &acceptRange,
-		ContentLength:   &length,
-		ContentEncoding: &contentEncoding,
-		ContentType:     &contentType,
-		ETag:            &etag,
-		LastModified:    backend.GetTimePtr(fi.ModTime()),
-		Metadata:        userMetaData,
-		TagCount:        tagCount,
-		ContentRange:    &contentRange,
+		AcceptRanges:       &acceptRange,
+		ContentLength:      &length,
+		ContentEncoding:    &contentEncoding,
+		ContentType:        &contentType,
+		CacheControl:       hdrs.cacheControl,
+		ContentDisposition: hdrs.contentDisposition,
+		ContentLanguage:    hdrs.contentLanguage,
+		Expires:            hdrs.expires,
+		ETag:               &etag,
+		LastModified:       backend.GetTimePtr(fi.ModTime()),
+		Metadata:           userMetaData,
+		TagCount:           tagCount,
+		ContentRange:       &contentRange,
+		PartsCount:         partsCount,
 	}, nil
 }
 
-func (p *Posix) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
-	if input.Bucket == nil {
-		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
-	}
-	if input.Key == nil {
-		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+// objectPartSizes returns the sizes of the parts of an object completed
+// by a multipart upload, or nil for a single part object. Part sizes that
+// no longer add up to the object size are ignored.
+func (p *Posix) objectPartSizes(bucket, object string, size int64) []int64 {
+	b, err := p.meta.RetrieveAttribute(bucket, object, partSizesKey)
+	if err != nil {
+		return nil
 	}
-	bucket := *input.Bucket
-	object := *input.Key
 
-	if input.PartNumber != nil {
-		uploadId, sum, err := p.retrieveUploadId(bucket, object)
-		if err != nil {
-			return nil, err
-		}
+	var partSizes []int64
+	if err := json.Unmarshal(b, &partSizes); err != nil {
+		return nil
+	}
 
-		ents, err := os.ReadDir(filepath.Join(bucket, metaTmpMultipartDir, fmt.Sprintf("%x", sum), uploadId))
-		if errors.Is(err, fs.ErrNotExist) {
-			return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
-		}
-		if err != nil {
-			return nil, fmt.Errorf("read parts: %w", err)
-		}
+	var total int64
+	for _, s := range partSizes {
+		total += s
+	}
+	if total != size {
+		return nil
+	}
 
-		partPath := filepath.Join(metaTmpMultipartDir, fmt.Sprintf("%x", sum), uploadId, fmt.Sprintf("%v", *input.PartNumber))
+	return partSizes
+}
 
-		part, err := os.Stat(filepath.Join(bucket, partPath))
-		if errors.Is(err, fs.ErrNotExist) {
-			return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
-		}
-		if err != nil {
-			return nil, fmt.Errorf("stat part: %w", err)
-		}
+// objectChecksum returns the checksum stored with an object, or nil when
+// the object has no checksum
+func (p *Posix) objectChecksum(bucket, object string) *backend.Checksum {
+	b, err := p.meta.RetrieveAttribute(bucket, object, checksumKey)
+	if err != nil {
+		return nil
+	}
 
-		b, err := p.meta.RetrieveAttribute(bucket, partPath, etagkey)
-		etag := string(b)
-		if err != nil {
-			etag = ""
-		}
-		partsCount := int32(len(ents))
-		size := part.Size()
+	var checksum backend.Checksum
+	if err := json.Unmarshal(b, &checksum); err != nil {
+		return nil
+	}
 
-		return &s3.HeadObjectOutput{
-			LastModified:  backend.GetTimePtr(part.ModTime()),
-			ETag:          &etag,
-			PartsCount:    &partsCount,
-			ContentLength: &size,
-		}, nil
+	return &checksum
+}
+
+func (p *Posix) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	bucket := *input.Bucket
+	object := *input.Key
+
+	if input.PartNumber != nil {
+		// report the part of an in progress multipart upload
+		// of the object
+		uploadId, sum, err := p.retrieveUploadId(bucket, object)
+		if err == nil {
+			return p.headUploadPart(bucket, uploadId, sum, *input.PartNumber)
+		}
 	}
 
 	_, err := os.Stat(bucket)
@@ -1584,6 +2557,10 @@ func (p *Posix) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.
 		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
+	if p.isExcluded(object) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
 	objPath := filepath.Join(bucket, object)
 	fi, err := os.Stat(objPath)
 	if errors.Is(err, fs.ErrNotExist) {
@@ -1595,15 +2572,38 @@ func (p *Posix) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.
 
 	userMetaData := make(map[string]string)
 	contentType, contentEncoding := p.loadUserMetaData(bucket, object, userMetaData)
+	hdrs := p.loadObjectHeaders(bucket, object)
 
-	b, err := p.meta.RetrieveAttribute(bucket, object, etagkey)
-	etag := string(b)
+	etag := p.objectEtag(bucket, object, fi)
+
+	err = backend.EvaluatePreconditions(etag, fi.ModTime(), input.IfMatch,
+		input.IfNoneMatch, input.IfModifiedSince, input.IfUnmodifiedSince)
 	if err != nil {
-		etag = ""
+		return nil, err
 	}
 
 	size := fi.Size()
 
+	var checksum *backend.Checksum
+	var partsCount *int32
+	if input.PartNumber == nil {
+		checksum = p.objectChecksum(bucket, object)
+	} else {
+		partSizes := p.objectPartSizes(bucket, object, size)
+		rng, err := backend.PartRange(size, partSizes, *input.PartNumber)
+		if err != nil {
+			return nil, err
+		}
+		_, size, err = backend.ParseRange(size, rng)
+		if err != nil {
+			return nil, err
+		}
+		if len(partSizes) != 0 {
+			count := int32(len(partSizes))
+			partsCount = &count
+		}
+	}
+
 	var objectLockLegalHoldStatus types.ObjectLockLegalHoldStatus
 	status, err := p.GetObjectLegalHold(ctx, bucket, object, "")
 	if err == nil {
@@ -1625,21 +2625,67 @@ func (p *Posix) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.
 		}
 	}
 
-	//TODO: the method must handle multipart upload case
+	checksumCRC32, checksumCRC32C, checksumSHA1, checksumSHA256 := checksum.Fields()
 
 	return &s3.HeadObjectOutput{
 		ContentLength:             &size,
 		ContentType:               &contentType,
 		ContentEncoding:           &contentEncoding,
+		CacheControl:              hdrs.cacheControl,
+		ContentDisposition:        hdrs.contentDisposition,
+		ContentLanguage:           hdrs.contentLanguage,
+		Expires:                   hdrs.expires,
 		ETag:                      &etag,
 		LastModified:              backend.GetTimePtr(fi.ModTime()),
 		Metadata:                  userMetaData,
+		PartsCount:                partsCount,
+		ChecksumCRC32:             checksumCRC32,
+		ChecksumCRC32C:            checksumCRC32C,
+		ChecksumSHA1:              checksumSHA1,
+		ChecksumSHA256:            checksumSHA256,
 		ObjectLockLegalHoldStatus: objectLockLegalHoldStatus,
 		ObjectLockMode:            objectLockMode,
 		ObjectLockRetainUntilDate: objectLockRetainUntilDate,
 	}, nil
 }
 
+// headUploadPart reports part partNumber of the in progress multipart
+// upload uploadId
+func (p *Posix) headUploadPart(bucket, uploadId string, sum [32]byte, partNumber int32) (*s3.HeadObjectOutput, error) {
+	ents, err := os.ReadDir(filepath.Join(bucket, metaTmpMultipartDir, fmt.Sprintf("%x", sum), uploadId))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read parts: %w", err)
+	}
+
+	partPath := filepath.Join(metaTmpMultipartDir, fmt.Sprintf("%x", sum), uploadId, fmt.Sprintf("%v", partNumber))
+
+	part, err := os.Stat(filepath.Join(bucket, partPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat part: %w", err)
+	}
+
+	b, err := p.meta.RetrieveAttribute(bucket, partPath, etagkey)
+	etag := string(b)
+	if err != nil {
+		etag = ""
+	}
+	partsCount := int32(len(ents))
+	size := part.Size()
+
+	return &s3.HeadObjectOutput{
+		LastModified:  backend.GetTimePtr(part.ModTime()),
+		ETag:          &etag,
+		PartsCount:    &partsCount,
+		ContentLength: &size,
+	}, nil
+}
+
 func (p *Posix) GetObjectAttributes(ctx context.Context, input *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error) {
 	data, err := p.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: input.Bucket,
@@ -1647,7 +2693,10 @@ func (p *Posix) GetObjectAttributes(ctx context.Context, input *s3.GetObjectAttr
 	})
 	if err == nil {
 		return s3response.GetObjectAttributesResult{
-			ETag:         data.ETag,
+			ETag: data.ETag,
+			Checksum: backend.ChecksumFromFields(data.ChecksumCRC32,
+				data.ChecksumCRC32C, data.ChecksumSHA1,
+				data.ChecksumSHA256).ObjectChecksum(),
 			LastModified: data.LastModified,
 			ObjectSize:   data.ContentLength,
 			StorageClass: &data.StorageClass,
@@ -1735,6 +2784,10 @@ func (p *Posix) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.
 		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
+	if p.isExcluded(srcObject) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
 	objPath := filepath.Join(srcBucket, srcObject)
 	f, err := os.Open(objPath)
 	if errors.Is(err, fs.ErrNotExist) {
@@ -1750,44 +2803,103 @@ func (p *Posix) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.
 		return nil, fmt.Errorf("stat object: %w", err)
 	}
 
+	srcEtag := p.objectEtag(srcBucket, srcObject, fInfo)
+
+	err = backend.EvaluateCopySourcePreconditions(srcEtag, fInfo.ModTime(),
+		input.CopySourceIfMatch, input.CopySourceIfNoneMatch,
+		input.CopySourceIfModifiedSince, input.CopySourceIfUnmodifiedSince)
+	if err != nil {
+		return nil, err
+	}
+
 	meta := make(map[string]string)
-	p.loadUserMetaData(srcBucket, srcObject, meta)
+	contentType, contentEncoding := p.loadUserMetaData(srcBucket, srcObject, meta)
+	hdrs := p.loadObjectHeaders(srcBucket, srcObject)
 
+	replaceMeta := input.MetadataDirective == types.MetadataDirectiveReplace
 	dstObjdPath := filepath.Join(dstBucket, dstObject)
-	if dstObjdPath == objPath {
+	if dstObjdPath == objPath && !replaceMeta {
+		// copying an object onto itself is only allowed to
+		// replace its metadata
 		if compareUserMetadata(meta, input.Metadata) {
 			return &s3.CopyObjectOutput{}, s3err.GetAPIError(s3err.ErrInvalidCopyDest)
-		} else {
-			for key := range meta {
-				err := p.meta.DeleteAttribute(dstBucket, dstObject, key)
-				if err != nil {
-					return nil, fmt.Errorf("delete user metadata: %w", err)
-				}
-			}
-			for k, v := range input.Metadata {
-				err := p.meta.StoreAttribute(dstBucket, dstObject,
-					fmt.Sprintf("%v.%v", metaHdr, k), []byte(v))
-				if err != nil {
-					return nil, fmt.Errorf("set user attr %q: %w", k, err)
-				}
-			}
 		}
+		replaceMeta = true
+	}
+	if replaceMeta {
+		meta = input.Metadata
+		contentType = getString(input.ContentType)
+		contentEncoding = getString(input.ContentEncoding)
+		hdrs = objectHeaders{
+			cacheControl:       input.CacheControl,
+			contentDisposition: input.ContentDisposition,
+			contentLanguage:    input.ContentLanguage,
+			expires:            input.Expires,
+		}
+	}
+
+	// the source tags are read before the put in case the copy
+	// replaces the source object
+	var tagging *string
+	var tags map[string]string
+	if input.TaggingDirective == types.TaggingDirectiveReplace {
+		tagging = input.Tagging
+	} else {
+		tags, err = p.getAttrTags(srcBucket, srcObject)
+		if err != nil && !errors.Is(err, s3err.GetAPIError(s3err.ErrBucketTaggingNotFound)) {
+			return nil, err
+		}
+	}
+
+	lockMode, retainUntilDate, legalHold, err := p.copyObjectLock(ctx, input,
+		srcBucket, srcObject)
+	if err != nil {
+		return nil, err
 	}
 
 	contentLength := fInfo.Size()
 
+	// the copy is a single part object, so the etag of a multipart
+	// source is recomputed from the data
+	body := &copySource{File: f}
+	if isMD5Etag(srcEtag) {
+		body.etag = srcEtag
+	}
+
 	etag, err := p.PutObject(ctx,
 		&s3.PutObjectInput{
-			Bucket:        &dstBucket,
-			Key:           &dstObject,
-			Body:          f,
-			ContentLength: &contentLength,
-			Metadata:      meta,
+			Bucket:                    &dstBucket,
+			Key:                       &dstObject,
+			Body:                      body,
+			ContentLength:             &contentLength,
+			Metadata:                  meta,
+			ContentType:               &contentType,
+			ContentEncoding:           &contentEncoding,
+			CacheControl:              hdrs.cacheControl,
+			ContentDisposition:        hdrs.contentDisposition,
+			ContentLanguage:           hdrs.contentLanguage,
+			Expires:                   hdrs.expires,
+			Tagging:                   tagging,
+			ObjectLockMode:            lockMode,
+			ObjectLockRetainUntilDate: retainUntilDate,
+			ObjectLockLegalHoldStatus: legalHold,
+			ACL:                       input.ACL,
+			GrantFullControl:          input.GrantFullControl,
+			GrantRead:                 input.GrantRead,
+			GrantReadACP:              input.GrantReadACP,
+			GrantWriteACP:             input.GrantWriteACP,
 		})
 	if err != nil {
 		return nil, err
 	}
 
+	if len(tags) > 0 {
+		err = p.PutObjectTagging(ctx, dstBucket, dstObject, tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	fi, err := os.Stat(dstObjdPath)
 	if err != nil {
 		return nil, fmt.Errorf("stat dst object: %w", err)
@@ -1801,6 +2913,127 @@ func (p *Posix) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.
 	}, nil
 }
 
+// copyObjectLock returns the object lock settings of a copied object. The
+// settings of the source are kept unless the request sets them, and are
+// only carried over into buckets with object lock enabled.
+func (p *Posix) copyObjectLock(ctx context.Context, input *s3.CopyObjectInput, srcBucket, srcObject string) (types.ObjectLockMode, *time.Time, types.ObjectLockLegalHoldStatus, error) {
+	mode := input.ObjectLockMode
+	retainUntilDate := input.ObjectLockRetainUntilDate
+	legalHold := input.ObjectLockLegalHoldStatus
+	if mode != "" && legalHold != "" {
+		return mode, retainUntilDate, legalHold, nil
+	}
+
+	enabled, err := p.isBucketLockEnabled(*input.Bucket)
+	if err != nil || !enabled {
+		return mode, retainUntilDate, legalHold, err
+	}
+
+	if mode == "" {
+		b, err := p.GetObjectRetention(ctx, srcBucket, srcObject, "")
+		if err == nil {
+			var retention types.ObjectLockRetention
+			err = json.Unmarshal(b, &retention)
+			if err == nil && retention.RetainUntilDate != nil &&
+				retention.RetainUntilDate.After(time.Now()) {
+				mode = types.ObjectLockMode(retention.Mode)
+				retainUntilDate = retention.RetainUntilDate
+			}
+		}
+	}
+	if legalHold == "" {
+		status, err := p.GetObjectLegalHold(ctx, srcBucket, srcObject, "")
+		if err == nil && *status {
+			legalHold = types.ObjectLockLegalHoldStatusOn
+		}
+	}
+
+	return mode, retainUntilDate, legalHold, nil
+}
+
+// isBucketLockEnabled returns true if object lock is enabled for bucket
+// objectLockAttrs returns the legal hold and retention attributes of the
+// object lock settings requested for a new object, the bucket must have
+// object lock enabled for any settings to be requested
+func (p *Posix) objectLockAttrs(bucket string, mode types.ObjectLockMode, retainUntilDate *time.Time, legalHold types.ObjectLockLegalHoldStatus) (map[string][]byte, error) {
+	if legalHold != types.ObjectLockLegalHoldStatusOn && mode == "" {
+		return nil, nil
+	}
+
+	enabled, err := p.isBucketLockEnabled(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketObjectLockConfiguration)
+	}
+
+	attrs := make(map[string][]byte)
+	if legalHold == types.ObjectLockLegalHoldStatusOn {
+		attrs[objectLegalHoldKey] = []byte{1}
+	}
+	if mode != "" {
+		retention, err := json.Marshal(types.ObjectLockRetention{
+			Mode:            types.ObjectLockRetentionMode(mode),
+			RetainUntilDate: retainUntilDate,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("parse object lock retention: %w", err)
+		}
+		attrs[objectRetentionKey] = retention
+	}
+	return attrs, nil
+}
+
+// bucketLockConfig returns the bucket object lock configuration, which is
+// disabled when the bucket has none
+func (p *Posix) bucketLockConfig(bucket string) (auth.BucketLockConfig, error) {
+	var bucketLockConfig auth.BucketLockConfig
+	cfg, err := p.meta.RetrieveAttribute(bucket, "", bucketLockKey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		return bucketLockConfig, nil
+	}
+	if err != nil {
+		return bucketLockConfig, fmt.Errorf("get object lock config: %w", err)
+	}
+
+	if err := json.Unmarshal(cfg, &bucketLockConfig); err != nil {
+		return bucketLockConfig, fmt.Errorf("parse bucket lock config: %w", err)
+	}
+	return bucketLockConfig, nil
+}
+
+func (p *Posix) isBucketLockEnabled(bucket string) (bool, error) {
+	bucketLockConfig, err := p.bucketLockConfig(bucket)
+	if err != nil {
+		return false, err
+	}
+	return bucketLockConfig.Enabled, nil
+}
+
+// setDefaultRetention adds the bucket default retention to the attributes
+// of a new object created without a retention of its own
+func (p *Posix) setDefaultRetention(bucket string, attrs map[string][]byte) error {
+	if _, ok := attrs[objectRetentionKey]; ok {
+		return nil
+	}
+
+	cfg, err := p.meta.RetrieveAttribute(bucket, "", bucketLockKey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get object lock config: %w", err)
+	}
+
+	retention, err := auth.MarshalDefaultRetention(cfg, time.Now())
+	if err != nil || retention == nil {
+		return err
+	}
+	attrs[objectRetentionKey] = retention
+	return nil
+}
+
 func (p *Posix) ListObjects(_ context.Context, input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
 	if input.Bucket == nil {
 		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
@@ -1831,9 +3064,14 @@ func (p *Posix) ListObjects(_ context.Context, input *s3.ListObjectsInput) (*s3.
 		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
+	owner, err := p.getBucketOwner(bucket)
+	if err != nil {
+		return nil, err
+	}
+
 	fileSystem := os.DirFS(bucket)
 	results, err := backend.Walk(fileSystem, prefix, delim, marker, maxkeys,
-		p.fileToObj(bucket), []string{metaTmpDir})
+		p.fileToObj(bucket, owner), p.skipdirs)
 	if err != nil {
 		return nil, fmt.Errorf("walk %v: %w", bucket, err)
 	}
@@ -1851,7 +3089,9 @@ func (p *Posix) ListObjects(_ context.Context, input *s3.ListObjectsInput) (*s3.
 	}, nil
 }
 
-func (p *Posix) fileToObj(bucket string) backend.GetObjFunc {
+// fileToObj returns the GetObjFunc used to fill out listing results. The
+// object owner is only resolved when a bucket owner is provided.
+func (p *Posix) fileToObj(bucket string, bucketOwner *types.Owner) backend.GetObjFunc {
 	return func(path string, d fs.DirEntry) (types.Object, error) {
 		if d.IsDir() {
 			// directory object only happens if directory empty
@@ -1875,10 +3115,16 @@ func (p *Posix) fileToObj(bucket string) backend.GetObjFunc {
 
 			key := path + "/"
 
+			var owner *types.Owner
+			if bucketOwner != nil {
+				owner = p.getObjectOwner(bucket, path, bucketOwner)
+			}
+
 			return types.Object{
 				ETag:         &etag,
 				Key:          &key,
 				LastModified: backend.GetTimePtr(fi.ModTime()),
+				Owner:        owner,
 			}, nil
 		}
 
@@ -1890,8 +3136,7 @@ func (p *Posix) fileToObj(bucket string) backend.GetObjFunc {
 		if err != nil && !errors.Is(err, meta.ErrNoSuchKey) {
 			return types.Object{}, fmt.Errorf("get etag: %w", err)
 		}
-		// note: meta.ErrNoSuchKey will return etagBytes = []byte{}
-		// so this will just set etag to "" if its not already set
+		noEtag := errors.Is(err, meta.ErrNoSuchKey)
 
 		etag := string(etagBytes)
 
@@ -1902,18 +3147,139 @@ func (p *Posix) fileToObj(bucket string) backend.GetObjFunc {
 		if err != nil {
 			return types.Object{}, fmt.Errorf("get fileinfo: %w", err)
 		}
+		if noEtag {
+			etag = p.missingEtag(bucket, path, fi)
+		}
 
 		size := fi.Size()
 
+		var owner *types.Owner
+		if bucketOwner != nil {
+			owner = p.getObjectOwner(bucket, path, bucketOwner)
+		}
+
 		return types.Object{
 			ETag:         &etag,
 			Key:          &path,
 			LastModified: backend.GetTimePtr(fi.ModTime()),
 			Size:         &size,
+			Owner:        owner,
 		}, nil
 	}
 }
 
+// copySource is the put object body of an object copy, the source data
+// is copied within the filesystem and keeps the source etag when known
+type copySource struct {
+	*os.File
+	etag string
+}
+
+// copyFrom copies size bytes of src at offset off to the temp file. The
+// data extents are shared with a reflink on filesystems supporting it,
+// such as xfs and btrfs. Otherwise the file ReadFrom uses copy_file_range
+// where supported, and falls back to a read and write copy.
+func (tmp *tmpfile) copyFrom(src *os.File, off, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	if tmp.clone(src, off, size) == nil {
+		return nil
+	}
+
+	_, err := src.Seek(off, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("seek source: %w", err)
+	}
+	n, err := tmp.f.ReadFrom(io.LimitReader(src, size))
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("copied %v of %v bytes", n, size)
+	}
+	return nil
+}
+
+// hashSection reads size bytes of f at offset off into the hash, for the
+// etag of copied data that is not already known
+func hashSection(hash io.Writer, f *os.File, off, size int64) error {
+	n, err := io.Copy(hash, io.NewSectionReader(f, off, size))
+	if err != nil {
+		return fmt.Errorf("read source: %w", err)
+	}
+	if n != size {
+		return fmt.Errorf("read %v of %v bytes", n, size)
+	}
+	return nil
+}
+
+// isExcluded returns true for the objects hidden by the exclude patterns
+func (p *Posix) isExcluded(object string) bool {
+	return backend.IsExcluded(object, strings.HasSuffix(object, "/"), p.exclude)
+}
+
+// useDirectIO returns true when a transfer of size bytes is large enough
+// for O_DIRECT
+func (p *Posix) useDirectIO(size int64) bool {
+	return p.directio > 0 && size >= p.directio
+}
+
+// objectReader returns the reader of length bytes of the object data at
+// offset off. The holes of sparse files are skipped rather than read,
+// and large reads use O_DIRECT where supported.
+func (p *Posix) objectReader(f *os.File, off, length int64) io.Reader {
+	rdr, err := newSparseReader(f, off, length)
+	if err == nil {
+		return rdr
+	}
+
+	if p.useDirectIO(length) {
+		rdr, err := newDirectReader(f, off, length)
+		if err == nil {
+			return rdr
+		}
+	}
+	return io.NewSectionReader(f, off, length)
+}
+
+// isMD5Etag returns true for an etag that is the md5 of the object data,
+// which excludes the etags of completed multipart uploads
+func isMD5Etag(etag string) bool {
+	b, err := hex.DecodeString(etag)
+	return err == nil && len(b) == md5.Size
+}
+
+// isNoSpace returns true for the errors of a write exceeding the
+// available filesystem space or quota
+func isNoSpace(err error) bool {
+	return errors.Is(err, syscall.EDQUOT) || errors.Is(err, syscall.ENOSPC)
+}
+
+// renameObject atomically replaces the object with the temp file. Only a
+// directory can replace another directory, so an empty directory in the
+// way of the object is removed first.
+func renameObject(tempname, objPath string) error {
+	err := os.Rename(tempname, objPath)
+	if err == nil {
+		return nil
+	}
+
+	fi, serr := os.Lstat(objPath)
+	if serr != nil || !fi.IsDir() {
+		return fmt.Errorf("rename tmpfile: %w", err)
+	}
+	err = os.Remove(objPath)
+	if err != nil {
+		return fmt.Errorf("remove stale path: %w", err)
+	}
+	err = os.Rename(tempname, objPath)
+	if err != nil {
+		return fmt.Errorf("rename tmpfile: %w", err)
+	}
+	return nil
+}
+
 func (p *Posix) ListObjectsV2(_ context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
 	if input.Bucket == nil {
 		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
@@ -1952,9 +3318,17 @@ func (p *Posix) ListObjectsV2(_ context.Context, input *s3.ListObjectsV2Input) (
 		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
+	var owner *types.Owner
+	if input.FetchOwner != nil && *input.FetchOwner {
+		owner, err = p.getBucketOwner(bucket)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	fileSystem := os.DirFS(bucket)
 	results, err := backend.Walk(fileSystem, prefix, delim, marker, maxkeys,
-		p.fileToObj(bucket), []string{metaTmpDir})
+		p.fileToObj(bucket, owner), p.skipdirs)
 	if err != nil {
 		return nil, fmt.Errorf("walk %v: %w", bucket, err)
 	}
@@ -1975,7 +3349,135 @@ func (p *Posix) ListObjectsV2(_ context.Context, input *s3.ListObjectsV2Input) (
 	}, nil
 }
 
-func (p *Posix) PutBucketAcl(_ context.Context, bucket string, data []byte) error {
+// ListObjectVersions lists the versions of the objects within a bucket.
+// The posix backend only keeps the current version of each object, so
+// every object is reported as a single "null" version and no delete
+// markers are returned.
+func (p *Posix) ListObjectVersions(_ context.Context, input *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
+	if input.Bucket == nil {
+		return s3response.ListVersionsResult{}, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	bucket := *input.Bucket
+	prefix := ""
+	if input.Prefix != nil {
+		prefix = *input.Prefix
+	}
+	keyMarker := ""
+	if input.KeyMarker != nil {
+		keyMarker = *input.KeyMarker
+	}
+	versionIdMarker := ""
+	if input.VersionIdMarker != nil {
+		versionIdMarker = *input.VersionIdMarker
+	}
+	delim := ""
+	if input.Delimiter != nil {
+		delim = *input.Delimiter
+	}
+	maxkeys := int32(0)
+	if input.MaxKeys != nil {
+		maxkeys = *input.MaxKeys
+	}
+
+	if versionIdMarker != "" && keyMarker == "" {
+		return s3response.ListVersionsResult{}, s3err.GetAPIError(s3err.ErrInvalidVersionIdMarker)
+	}
+
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3response.ListVersionsResult{}, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return s3response.ListVersionsResult{}, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	// Every key has exactly one (null) version, so the key marker alone
+	// determines where the listing resumes: the version at the key
+	// marker is always the last one for that key.
+	owner, err := p.getBucketOwner(bucket)
+	if err != nil {
+		return s3response.ListVersionsResult{}, err
+	}
+
+	fileSystem := os.DirFS(bucket)
+	results, err := backend.Walk(fileSystem, prefix, delim, keyMarker, maxkeys,
+		p.fileToObj(bucket, owner), p.skipdirs)
+	if err != nil {
+		return s3response.ListVersionsResult{}, fmt.Errorf("walk %v: %w", bucket, err)
+	}
+
+	versions := make([]types.ObjectVersion, 0, len(results.Objects))
+	for _, obj := range results.Objects {
+		versions = append(versions, types.ObjectVersion{
+			ETag:         obj.ETag,
+			IsLatest:     backend.GetBoolPtr(true),
+			Key:          obj.Key,
+			LastModified: obj.LastModified,
+			Owner:        obj.Owner,
+			Size:         obj.Size,
+			StorageClass: types.ObjectVersionStorageClassStandard,
+			VersionId:    backend.GetStringPtr(nullVersionId),
+		})
+	}
+
+	result := s3response.ListVersionsResult{
+		Name:            bucket,
+		Prefix:          prefix,
+		KeyMarker:       keyMarker,
+		VersionIdMarker: versionIdMarker,
+		Delimiter:       delim,
+		MaxKeys:         maxkeys,
+		IsTruncated:     results.Truncated,
+		Versions:        versions,
+		CommonPrefixes:  results.CommonPrefixes,
+	}
+	if results.Truncated {
+		result.NextKeyMarker = results.NextMarker
+		result.NextVersionIdMarker = nullVersionId
+	}
+
+	return result, nil
+}
+
+func (p *Posix) PutBucketAcl(_ context.Context, bucket string, data []byte) error {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	if err := p.meta.StoreAttribute(bucket, "", aclkey, data); err != nil {
+		return fmt.Errorf("set acl: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Posix) GetBucketAcl(_ context.Context, input *s3.GetBucketAclInput) ([]byte, error) {
+	if input.Bucket == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	_, err := os.Stat(*input.Bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	b, err := p.meta.RetrieveAttribute(*input.Bucket, "", aclkey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		return []byte{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get acl: %w", err)
+	}
+	return b, nil
+}
+
+func (p *Posix) PutObjectAcl(_ context.Context, bucket, object string, data []byte) error {
 	_, err := os.Stat(bucket)
 	if errors.Is(err, fs.ErrNotExist) {
 		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
@@ -1984,18 +3486,29 @@ func (p *Posix) PutBucketAcl(_ context.Context, bucket string, data []byte) erro
 		return fmt.Errorf("stat bucket: %w", err)
 	}
 
-	if err := p.meta.StoreAttribute(bucket, "", aclkey, data); err != nil {
+	err = p.meta.StoreAttribute(bucket, object, aclkey, data)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	if err != nil {
 		return fmt.Errorf("set acl: %w", err)
 	}
 
 	return nil
 }
 
-func (p *Posix) GetBucketAcl(_ context.Context, input *s3.GetBucketAclInput) ([]byte, error) {
+func (p *Posix) GetObjectAcl(_ context.Context, input *s3.GetObjectAclInput) ([]byte, error) {
 	if input.Bucket == nil {
 		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
 	}
-	_, err := os.Stat(*input.Bucket)
+	if input.Key == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	bucket := *input.Bucket
+	object := *input.Key
+
+	_, err := os.Stat(bucket)
 	if errors.Is(err, fs.ErrNotExist) {
 		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
 	}
@@ -2003,13 +3516,39 @@ func (p *Posix) GetBucketAcl(_ context.Context, input *s3.GetBucketAclInput) ([]
 		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
-	b, err := p.meta.RetrieveAttribute(*input.Bucket, "", aclkey)
-	if errors.Is(err, meta.ErrNoSuchKey) {
-		return []byte{}, nil
+	_, err = os.Stat(filepath.Join(bucket, object))
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
 	}
 	if err != nil {
+		return nil, fmt.Errorf("stat object: %w", err)
+	}
+
+	b, err := p.meta.RetrieveAttribute(bucket, object, aclkey)
+	if err == nil {
+		return b, nil
+	}
+	if !errors.Is(err, meta.ErrNoSuchKey) {
 		return nil, fmt.Errorf("get acl: %w", err)
 	}
+
+	// objects without an acl of their own are private to their owner
+	bucketOwner, err := p.getBucketOwner(bucket)
+	if err != nil {
+		return nil, err
+	}
+	owner := p.getObjectOwner(bucket, object, bucketOwner)
+
+	acl := auth.ACL{}
+	if owner.ID != nil {
+		acl.Owner = *owner.ID
+	}
+
+	b, err = json.Marshal(acl)
+	if err != nil {
+		return nil, fmt.Errorf("marshal acl: %w", err)
+	}
+
 	return b, nil
 }
 
@@ -2141,6 +3680,68 @@ func (p *Posix) DeleteObjectTagging(ctx context.Context, bucket, object string)
 	return p.PutObjectTagging(ctx, bucket, object, nil)
 }
 
+// PutBucketVersioning stores the bucket versioning state. The posix
+// backend does not retain older object versions, so enabling versioning
+// is not implemented and only the suspended state is recorded for
+// reporting back to clients.
+func (p *Posix) PutBucketVersioning(_ context.Context, input *s3.PutBucketVersioningInput) error {
+	if input.Bucket == nil {
+		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.VersioningConfiguration == nil {
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+	bucket := *input.Bucket
+
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	status := input.VersioningConfiguration.Status
+	switch status {
+	case types.BucketVersioningStatusSuspended:
+	case types.BucketVersioningStatusEnabled:
+		return s3err.GetAPIError(s3err.ErrNotImplemented)
+	default:
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	err = p.meta.StoreAttribute(bucket, "", versioningKey, []byte(status))
+	if err != nil {
+		return fmt.Errorf("set versioning: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Posix) GetBucketVersioning(_ context.Context, bucket string) (s3response.GetBucketVersioningOutput, error) {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3response.GetBucketVersioningOutput{}, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return s3response.GetBucketVersioningOutput{}, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	b, err := p.meta.RetrieveAttribute(bucket, "", versioningKey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		// versioning has never been configured for this bucket
+		return s3response.GetBucketVersioningOutput{}, nil
+	}
+	if err != nil {
+		return s3response.GetBucketVersioningOutput{}, fmt.Errorf("get versioning: %w", err)
+	}
+
+	status := types.BucketVersioningStatus(b)
+	return s3response.GetBucketVersioningOutput{
+		Status: &status,
+	}, nil
+}
+
 func (p *Posix) PutBucketPolicy(ctx context.Context, bucket string, policy []byte) error {
 	_, err := os.Stat(bucket)
 	if errors.Is(err, fs.ErrNotExist) {
@@ -2198,6 +3799,148 @@ func (p *Posix) DeleteBucketPolicy(ctx context.Context, bucket string) error {
 	return p.PutBucketPolicy(ctx, bucket, nil)
 }
 
+func (p *Posix) PutPublicAccessBlock(_ context.Context, bucket string, config []byte) error {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	if config == nil {
+		err := p.meta.DeleteAttribute(bucket, "", publicAccessBlockKey)
+		if err != nil && !errors.Is(err, meta.ErrNoSuchKey) {
+			return fmt.Errorf("remove public access block: %w", err)
+		}
+
+		return nil
+	}
+
+	err = p.meta.StoreAttribute(bucket, "", publicAccessBlockKey, config)
+	if err != nil {
+		return fmt.Errorf("set public access block: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Posix) GetPublicAccessBlock(_ context.Context, bucket string) ([]byte, error) {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	config, err := p.meta.RetrieveAttribute(bucket, "", publicAccessBlockKey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchPublicAccessBlockConfiguration)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get public access block: %w", err)
+	}
+
+	return config, nil
+}
+
+func (p *Posix) DeletePublicAccessBlock(ctx context.Context, bucket string) error {
+	return p.PutPublicAccessBlock(ctx, bucket, nil)
+}
+
+func (p *Posix) PutBucketNotificationConfiguration(_ context.Context, bucket string, config []byte) error {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	if config == nil {
+		err := p.meta.DeleteAttribute(bucket, "", notificationKey)
+		if err != nil && !errors.Is(err, meta.ErrNoSuchKey) {
+			return fmt.Errorf("remove notification configuration: %w", err)
+		}
+
+		return nil
+	}
+
+	err = p.meta.StoreAttribute(bucket, "", notificationKey, config)
+	if err != nil {
+		return fmt.Errorf("set notification configuration: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Posix) GetBucketNotificationConfiguration(_ context.Context, bucket string) ([]byte, error) {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	config, err := p.meta.RetrieveAttribute(bucket, "", notificationKey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		return []byte{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get notification configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+func (p *Posix) PutBucketLogging(_ context.Context, bucket string, config []byte) error {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	if config == nil {
+		err := p.meta.DeleteAttribute(bucket, "", loggingKey)
+		if err != nil && !errors.Is(err, meta.ErrNoSuchKey) {
+			return fmt.Errorf("remove bucket logging: %w", err)
+		}
+
+		return nil
+	}
+
+	err = p.meta.StoreAttribute(bucket, "", loggingKey, config)
+	if err != nil {
+		return fmt.Errorf("set bucket logging: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Posix) GetBucketLogging(_ context.Context, bucket string) ([]byte, error) {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	config, err := p.meta.RetrieveAttribute(bucket, "", loggingKey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		return []byte{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get bucket logging: %w", err)
+	}
+
+	return config, nil
+}
+
 func (p *Posix) PutObjectLockConfiguration(_ context.Context, bucket string, config []byte) error {
 	_, err := os.Stat(bucket)
 	if errors.Is(err, fs.ErrNotExist) {
@@ -2415,6 +4158,54 @@ func (p *Posix) ChangeBucketOwner(ctx context.Context, bucket, newOwner string)
 	return nil
 }
 
+// ChownBucket recursively changes the ownership of the bucket directory
+// tree to uid and/or gid, as enabled by the chown uid/gid options
+func (p *Posix) ChownBucket(_ context.Context, bucket string, uid, gid int) error {
+	if !p.chownuid && !p.chowngid {
+		return s3err.GetAPIError(s3err.ErrNotImplemented)
+	}
+	// -1 leaves the id unchanged
+	if !p.chownuid {
+		uid = -1
+	}
+	if !p.chowngid {
+		gid = -1
+	}
+
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	err = walkBucket(bucket, func(path string, _ fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			// removed while walking
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if path == bucket {
+			// follows the link of a placed bucket
+			err = os.Chown(path, uid, gid)
+		} else {
+			err = os.Lchown(path, uid, gid)
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("chown bucket: %w", err)
+	}
+
+	return nil
+}
+
 func (p *Posix) ListBucketsAndOwners(ctx context.Context) (buckets []s3response.Bucket, err error) {
 	entries, err := os.ReadDir(".")
 	if err != nil {
@@ -2422,7 +4213,8 @@ func (p *Posix) ListBucketsAndOwners(ctx context.Context) (buckets []s3response.
 	}
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !p.isBucketDir(entry) || strings.HasPrefix(entry.Name(), ".") ||
+			backend.IsExcluded(entry.Name(), true, p.exclude) {
 			continue
 		}
 
@@ -2455,6 +4247,85 @@ func (p *Posix) ListBucketsAndOwners(ctx context.Context) (buckets []s3response.
 	return buckets, nil
 }
 
+// GetBucketUsage returns the object count, size and last modification
+// time of the bucket objects, along with the size of the parts of the
+// incomplete multipart uploads
+func (p *Posix) GetBucketUsage(_ context.Context, bucket string) (s3response.BucketUsage, error) {
+	usage := s3response.BucketUsage{Bucket: bucket}
+
+	_, err := os.Stat(bucket)
+	if err != nil && os.IsNotExist(err) {
+		return usage, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return usage, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	tmpdir := filepath.Join(bucket, metaTmpDir)
+	mpdir := filepath.Join(bucket, metaTmpMultipartDir)
+	trashdir := filepath.Join(bucket, metaTrashDir)
+
+	err = walkBucket(bucket, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			// removed while walking
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path == trashdir {
+			return filepath.SkipDir
+		}
+		if d.IsDir() && path == tmpdir {
+			err := filepath.WalkDir(mpdir, func(path string, d fs.DirEntry, err error) error {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if !d.Type().IsRegular() {
+					return nil
+				}
+				fi, err := d.Info()
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				usage.MultipartSize += fi.Size()
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		usage.Objects++
+		usage.Size += fi.Size()
+		if fi.ModTime().After(usage.LastModified) {
+			usage.LastModified = fi.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return usage, fmt.Errorf("walk bucket: %w", err)
+	}
+
+	return usage, nil
+}
+
 func getString(str *string) string {
 	if str == nil {
 		return ""
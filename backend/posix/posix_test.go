@@ -0,0 +1,344 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/meta"
+	"github.com/versity/versitygw/s3err"
+)
+
+// newTestPosix returns a posix backend rooted in a temp dir with the
+// metadata kept in a db outside of the gateway root
+func newTestPosix(t *testing.T) *Posix {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms, err := meta.NewDBMeta(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := New(t.TempDir(), ms, PosixOpts{})
+	if err != nil {
+		ms.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		p.Shutdown()
+		os.Chdir(wd)
+	})
+	return p
+}
+
+func newTestBucket(t *testing.T, p *Posix, bucket string, lock bool) {
+	t.Helper()
+	err := p.CreateBucket(context.Background(), &s3.CreateBucketInput{
+		Bucket:                     &bucket,
+		ObjectLockEnabledForBucket: &lock,
+	}, []byte(`{"Owner":"owner"}`))
+	if err != nil {
+		t.Fatalf("create bucket: %v", err)
+	}
+}
+
+func putTestObject(t *testing.T, p *Posix, bucket, key, data string) {
+	t.Helper()
+	size := int64(len(data))
+	_, err := p.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:        &bucket,
+		Key:           &key,
+		ContentLength: &size,
+		Body:          strings.NewReader(data),
+	})
+	if err != nil {
+		t.Fatalf("put object %v: %v", key, err)
+	}
+}
+
+func TestPosix_BucketVersioning(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPosix(t)
+	bucket := "bucket"
+	newTestBucket(t, p, bucket, false)
+
+	out, err := p.GetBucketVersioning(ctx, bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Status != nil {
+		t.Fatalf("unconfigured versioning status %v", *out.Status)
+	}
+
+	put := func(status types.BucketVersioningStatus) error {
+		return p.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+			Bucket: &bucket,
+			VersioningConfiguration: &types.VersioningConfiguration{
+				Status: status,
+			},
+		})
+	}
+
+	// older versions are not retained, so enabling must not succeed
+	err = put(types.BucketVersioningStatusEnabled)
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrNotImplemented)) {
+		t.Fatalf("enable versioning: expected NotImplemented, got %v", err)
+	}
+	out, err = p.GetBucketVersioning(ctx, bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Status != nil {
+		t.Fatalf("rejected versioning status stored as %v", *out.Status)
+	}
+
+	err = put(types.BucketVersioningStatusSuspended)
+	if err != nil {
+		t.Fatalf("suspend versioning: %v", err)
+	}
+	out, err = p.GetBucketVersioning(ctx, bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Status == nil || *out.Status != types.BucketVersioningStatusSuspended {
+		t.Fatalf("versioning status %v, expected Suspended", out.Status)
+	}
+
+	err = put("Bogus")
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrMalformedXML)) {
+		t.Fatalf("invalid status: expected MalformedXML, got %v", err)
+	}
+}
+
+func TestPosix_DeleteObjects(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPosix(t)
+	bucket := "bucket"
+	newTestBucket(t, p, bucket, false)
+
+	// enough keys to keep all the workers busy, every third one is
+	// missing and fails with NoSuchKey
+	var objs []types.ObjectIdentifier
+	var wantDeleted, wantErrors []string
+	for i := 0; i < 10*deleteObjectsWorkers; i++ {
+		key := fmt.Sprintf("dir%v/obj%03d", i%7, i)
+		if i%3 == 0 {
+			wantErrors = append(wantErrors, key)
+		} else {
+			putTestObject(t, p, bucket, key, key)
+			wantDeleted = append(wantDeleted, key)
+		}
+		objs = append(objs, types.ObjectIdentifier{Key: &key})
+	}
+
+	res, err := p.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: &bucket,
+		Delete: &types.Delete{Objects: objs},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the results are reported in the request order
+	var deleted, failed []string
+	for _, d := range res.Deleted {
+		deleted = append(deleted, *d.Key)
+	}
+	for _, e := range res.Error {
+		if *e.Code != "NoSuchKey" {
+			t.Errorf("%v: unexpected error code %v", *e.Key, *e.Code)
+		}
+		failed = append(failed, *e.Key)
+	}
+	if strings.Join(deleted, ",") != strings.Join(wantDeleted, ",") {
+		t.Errorf("deleted %v, expected %v", deleted, wantDeleted)
+	}
+	if strings.Join(failed, ",") != strings.Join(wantErrors, ",") {
+		t.Errorf("errors %v, expected %v", failed, wantErrors)
+	}
+
+	for _, key := range wantDeleted {
+		if _, err := os.Stat(filepath.Join(bucket, key)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%v: object not removed: %v", key, err)
+		}
+	}
+}
+
+func TestPosix_MultipartObjectSettings(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPosix(t)
+	bucket, key := "bucket", "dir/mp"
+	newTestBucket(t, p, bucket, true)
+
+	retainUntil := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	mpu, err := p.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:                    &bucket,
+		Key:                       &key,
+		ContentType:               backend.GetStringPtr("text/plain"),
+		ContentEncoding:           backend.GetStringPtr("gzip"),
+		Tagging:                   backend.GetStringPtr("k1=v1&k2=v2"),
+		Metadata:                  map[string]string{"color": "blue", "size": "xl"},
+		ObjectLockMode:            types.ObjectLockModeGovernance,
+		ObjectLockRetainUntilDate: &retainUntil,
+		ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOn,
+	})
+	if err != nil {
+		t.Fatalf("create multipart upload: %v", err)
+	}
+
+	pn := int32(1)
+	size := int64(4)
+	etag, err := p.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        &bucket,
+		Key:           &key,
+		UploadId:      mpu.UploadId,
+		PartNumber:    &pn,
+		ContentLength: &size,
+		Body:          strings.NewReader("data"),
+	})
+	if err != nil {
+		t.Fatalf("upload part: %v", err)
+	}
+
+	_, err = p.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   &bucket,
+		Key:      &key,
+		UploadId: mpu.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: []types.CompletedPart{{ETag: &etag, PartNumber: &pn}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("complete multipart upload: %v", err)
+	}
+
+	head, err := p.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &bucket, Key: &key})
+	if err != nil {
+		t.Fatalf("head object: %v", err)
+	}
+	if getString(head.ContentType) != "text/plain" {
+		t.Errorf("unexpected content type %q", getString(head.ContentType))
+	}
+	if getString(head.ContentEncoding) != "gzip" {
+		t.Errorf("unexpected content encoding %q", getString(head.ContentEncoding))
+	}
+	if !reflect.DeepEqual(head.Metadata, map[string]string{"color": "blue", "size": "xl"}) {
+		t.Errorf("unexpected user metadata %v", head.Metadata)
+	}
+	if head.ObjectLockLegalHoldStatus != types.ObjectLockLegalHoldStatusOn {
+		t.Errorf("expected legal hold, got %q", head.ObjectLockLegalHoldStatus)
+	}
+	if head.ObjectLockMode != types.ObjectLockModeGovernance {
+		t.Errorf("expected governance retention, got %q", head.ObjectLockMode)
+	}
+	if head.ObjectLockRetainUntilDate == nil || !head.ObjectLockRetainUntilDate.Equal(retainUntil) {
+		t.Errorf("unexpected retain until date %v", head.ObjectLockRetainUntilDate)
+	}
+
+	tags, err := p.GetObjectTagging(ctx, bucket, key)
+	if err != nil {
+		t.Fatalf("get object tagging: %v", err)
+	}
+	if len(tags) != 2 || tags["k1"] != "v1" || tags["k2"] != "v2" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+}
+
+func TestPosix_UploadMetadata(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPosix(t)
+	bucket, key := "bucket", "mp"
+	newTestBucket(t, p, bucket, false)
+
+	mpu, err := p.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:   &bucket,
+		Key:      &key,
+		Expires:  backend.GetTimePtr(time.Now().Add(time.Hour)),
+		Metadata: map[string]string{"color": "blue", "size": "xl"},
+	})
+	if err != nil {
+		t.Fatalf("create multipart upload: %v", err)
+	}
+
+	err = p.SetUploadMetadata(ctx, bucket, key, *mpu.UploadId,
+		map[string]string{"size": "", "shape": "round", "missing": ""})
+	if err != nil {
+		t.Fatalf("set upload metadata: %v", err)
+	}
+
+	expected := map[string]string{"color": "blue", "shape": "round"}
+	meta, err := p.GetUploadMetadata(ctx, bucket, key, *mpu.UploadId)
+	if err != nil {
+		t.Fatalf("get upload metadata: %v", err)
+	}
+	if !reflect.DeepEqual(meta, expected) {
+		t.Errorf("expected upload metadata %v, got %v", expected, meta)
+	}
+
+	_, err = p.GetUploadMetadata(ctx, bucket, key, "invalid")
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchUpload)) {
+		t.Errorf("expected %v, got %v", s3err.ErrNoSuchUpload, err)
+	}
+
+	pn := int32(1)
+	size := int64(4)
+	etag, err := p.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        &bucket,
+		Key:           &key,
+		UploadId:      mpu.UploadId,
+		PartNumber:    &pn,
+		ContentLength: &size,
+		Body:          strings.NewReader("data"),
+	})
+	if err != nil {
+		t.Fatalf("upload part: %v", err)
+	}
+
+	_, err = p.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   &bucket,
+		Key:      &key,
+		UploadId: mpu.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: []types.CompletedPart{{ETag: &etag, PartNumber: &pn}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("complete multipart upload: %v", err)
+	}
+
+	head, err := p.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &bucket, Key: &key})
+	if err != nil {
+		t.Fatalf("head object: %v", err)
+	}
+	_, removed := head.Metadata["size"]
+	if head.Metadata["color"] != "blue" || head.Metadata["shape"] != "round" || removed {
+		t.Errorf("expected object metadata %v, got %v", expected, head.Metadata)
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/versity/versitygw/backend/meta"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+const (
+	bucketQuotaKey = "bucket-quota"
+	bucketUsageKey = "bucket-usage"
+)
+
+// bucketUsage is the usage accounted against the bucket quota
+type bucketUsage struct {
+	Size    int64 `json:"size"`
+	Objects int64 `json:"objects"`
+}
+
+// PutBucketQuota sets the bucket quota, a zero quota removes it. The
+// bucket usage is recomputed and then accounted incrementally while
+// the bucket has a quota.
+func (p *Posix) PutBucketQuota(ctx context.Context, bucket string, quota s3response.BucketQuota) error {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	p.quotaMu.Lock()
+	defer p.quotaMu.Unlock()
+
+	if quota.Size == 0 && quota.Objects == 0 {
+		err := p.meta.DeleteAttribute(bucket, "", bucketQuotaKey)
+		if err != nil && !errors.Is(err, meta.ErrNoSuchKey) {
+			return fmt.Errorf("delete bucket quota: %w", err)
+		}
+		err = p.meta.DeleteAttribute(bucket, "", bucketUsageKey)
+		if err != nil && !errors.Is(err, meta.ErrNoSuchKey) {
+			return fmt.Errorf("delete bucket usage: %w", err)
+		}
+		return nil
+	}
+
+	usage, err := p.GetBucketUsage(ctx, bucket)
+	if err != nil {
+		return err
+	}
+
+	err = p.storeBucketUsage(bucket, bucketUsage{Size: usage.Size, Objects: usage.Objects})
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(s3response.BucketQuota{Size: quota.Size, Objects: quota.Objects})
+	if err != nil {
+		return fmt.Errorf("marshal bucket quota: %w", err)
+	}
+
+	err = p.meta.StoreAttribute(bucket, "", bucketQuotaKey, b)
+	if err != nil {
+		return fmt.Errorf("set bucket quota: %w", err)
+	}
+
+	return nil
+}
+
+// GetBucketQuota returns the bucket quota along with the accounted usage
+func (p *Posix) GetBucketQuota(_ context.Context, bucket string) (s3response.BucketQuota, error) {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3response.BucketQuota{}, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return s3response.BucketQuota{}, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	p.quotaMu.Lock()
+	defer p.quotaMu.Unlock()
+
+	quota, ok, err := p.getBucketQuota(bucket)
+	if err != nil || !ok {
+		return s3response.BucketQuota{}, err
+	}
+
+	usage, err := p.getBucketUsage(bucket)
+	if err != nil {
+		return s3response.BucketQuota{}, err
+	}
+
+	quota.UsedSize = usage.Size
+	quota.UsedObjects = usage.Objects
+
+	return quota, nil
+}
+
+func (p *Posix) getBucketQuota(bucket string) (s3response.BucketQuota, bool, error) {
+	var quota s3response.BucketQuota
+
+	b, err := p.meta.RetrieveAttribute(bucket, "", bucketQuotaKey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		return quota, false, nil
+	}
+	if err != nil {
+		return quota, false, fmt.Errorf("get bucket quota: %w", err)
+	}
+
+	err = json.Unmarshal(b, &quota)
+	if err != nil {
+		return quota, false, fmt.Errorf("unmarshal bucket quota: %w", err)
+	}
+
+	return quota, true, nil
+}
+
+func (p *Posix) getBucketUsage(bucket string) (bucketUsage, error) {
+	var usage bucketUsage
+
+	b, err := p.meta.RetrieveAttribute(bucket, "", bucketUsageKey)
+	if errors.Is(err, meta.ErrNoSuchKey) {
+		return usage, nil
+	}
+	if err != nil {
+		return usage, fmt.Errorf("get bucket usage: %w", err)
+	}
+
+	err = json.Unmarshal(b, &usage)
+	if err != nil {
+		return usage, fmt.Errorf("unmarshal bucket usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+func (p *Posix) storeBucketUsage(bucket string, usage bucketUsage) error {
+	b, err := json.Marshal(usage)
+	if err != nil {
+		return fmt.Errorf("marshal bucket usage: %w", err)
+	}
+
+	err = p.meta.StoreAttribute(bucket, "", bucketUsageKey, b)
+	if err != nil {
+		return fmt.Errorf("set bucket usage: %w", err)
+	}
+
+	return nil
+}
+
+// linkWithBucketQuota calls link to place the object of the given size
+// in the namespace, if the bucket has a quota it is first checked to
+// have room for the object, replacing the existing one, and the usage
+// is updated once linked
+func (p *Posix) linkWithBucketQuota(bucket, object string, size int64, link func() error) error {
+	p.quotaMu.Lock()
+	defer p.quotaMu.Unlock()
+
+	quota, ok, err := p.getBucketQuota(bucket)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return link()
+	}
+
+	usage, err := p.getBucketUsage(bucket)
+	if err != nil {
+		return err
+	}
+
+	sizeDelta, objDelta := size, int64(1)
+	fi, err := os.Lstat(filepath.Join(bucket, object))
+	if err == nil && fi.Mode().IsRegular() {
+		sizeDelta -= fi.Size()
+		objDelta = 0
+	}
+
+	if (sizeDelta > 0 && quota.Size > 0 && usage.Size+sizeDelta > quota.Size) ||
+		(objDelta > 0 && quota.Objects > 0 && usage.Objects+objDelta > quota.Objects) {
+		return s3err.GetAPIError(s3err.ErrQuotaExceeded)
+	}
+
+	err = link()
+	if err != nil {
+		return err
+	}
+
+	usage.Size += sizeDelta
+	usage.Objects += objDelta
+
+	return p.storeBucketUsage(bucket, usage)
+}
+
+// releaseBucketQuota accounts the removal of an object of the given size
+// if the bucket has a quota
+func (p *Posix) releaseBucketQuota(bucket string, size int64) error {
+	p.quotaMu.Lock()
+	defer p.quotaMu.Unlock()
+
+	_, ok, err := p.getBucketQuota(bucket)
+	if err != nil || !ok {
+		return err
+	}
+
+	usage, err := p.getBucketUsage(bucket)
+	if err != nil {
+		return err
+	}
+
+	usage.Size = max(usage.Size-size, 0)
+	usage.Objects = max(usage.Objects-1, 0)
+
+	return p.storeBucketUsage(bucket, usage)
+}
@@ -0,0 +1,323 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+const (
+	// scrubQuarantineDir is where the objects failing the scrub are
+	// moved to, within the bucket temp dir to hide them from listings
+	scrubQuarantineDir = metaTmpDir + "/quarantine"
+	// scrubMismatchesKept is the number of recent mismatches reported
+	scrubMismatchesKept = 100
+)
+
+var errScrubStopped = errors.New("scrub stopped")
+
+// scrubber tracks the background verification of the object data
+// against the stored md5 etags
+type scrubber struct {
+	rate       int64
+	quarantine bool
+
+	mu     sync.Mutex
+	status s3response.ScrubStatus
+}
+
+// GetScrubStatus returns the scrubber progress, not implemented when
+// the scrubber is disabled
+func (p *Posix) GetScrubStatus(context.Context) (s3response.ScrubStatus, error) {
+	if p.scrub == nil {
+		return s3response.ScrubStatus{}, s3err.GetAPIError(s3err.ErrNotImplemented)
+	}
+
+	p.scrub.mu.Lock()
+	defer p.scrub.mu.Unlock()
+
+	status := p.scrub.status
+	status.Mismatches = slices.Clone(status.Mismatches)
+	return status, nil
+}
+
+// runScrub starts a scrub pass interval after the previous one finished
+// until Shutdown
+func (p *Posix) runScrub(interval time.Duration) {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-timer.C:
+			p.scrubBuckets()
+			timer.Reset(interval)
+		}
+	}
+}
+
+// scrubBuckets verifies the data of all the bucket objects with an md5
+// etag, the objects without one, such as the multipart uploads, are
+// skipped
+func (p *Posix) scrubBuckets() {
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		log.Printf("scrub: readdir buckets: %v", err)
+		return
+	}
+
+	p.scrub.mu.Lock()
+	p.scrub.status.Running = true
+	p.scrub.status.LastStart = time.Now()
+	p.scrub.mu.Unlock()
+
+	t := &scrubThrottle{rate: p.scrub.rate, start: time.Now(), done: p.done}
+	for _, entry := range entries {
+		if !p.isBucketDir(entry) || strings.HasPrefix(entry.Name(), ".") ||
+			backend.IsExcluded(entry.Name(), true, p.exclude) {
+			continue
+		}
+		err := p.scrubBucket(entry.Name(), t)
+		if errors.Is(err, errScrubStopped) {
+			break
+		}
+		if err != nil {
+			log.Printf("scrub: bucket %v: %v", entry.Name(), err)
+		}
+	}
+
+	p.scrub.mu.Lock()
+	p.scrub.status.Running = false
+	p.scrub.status.Passes++
+	p.scrub.status.LastFinish = time.Now()
+	p.scrub.mu.Unlock()
+}
+
+func (p *Posix) scrubBucket(bucket string, t *scrubThrottle) error {
+	return walkBucket(bucket, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if path == bucket {
+			return nil
+		}
+
+		object, err := filepath.Rel(bucket, path)
+		if err != nil {
+			return err
+		}
+		object = filepath.ToSlash(object)
+
+		if d.IsDir() {
+			if object == metaTmpDir || object == metaTrashDir || backend.IsExcluded(object+"/", true, p.exclude) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || backend.IsExcluded(object, false, p.exclude) {
+			return nil
+		}
+
+		return p.scrubObject(bucket, object, t)
+	})
+}
+
+// scrubObject compares the object data md5 to the object etag, the
+// objects modified while being read are skipped until the next pass
+func (p *Posix) scrubObject(bucket, object string, t *scrubThrottle) error {
+	b, err := p.meta.RetrieveAttribute(bucket, object, etagkey)
+	etag := string(b)
+	if err != nil || !isMD5Etag(etag) {
+		p.scrubCount(0, 1)
+		return nil
+	}
+
+	objPath := filepath.Join(bucket, object)
+	f, err := os.Open(objPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	before, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+
+	hash := md5.New()
+	n, err := io.Copy(hash, &scrubReader{r: f, t: t})
+	if errors.Is(err, errScrubStopped) {
+		return err
+	}
+	if err != nil {
+		log.Printf("scrub: read %v/%v: %v", bucket, object, err)
+		return nil
+	}
+
+	after, err := os.Stat(objPath)
+	if err != nil || !os.SameFile(before, after) ||
+		!after.ModTime().Equal(before.ModTime()) || after.Size() != before.Size() {
+		p.scrubCount(0, 1)
+		return nil
+	}
+	b, err = p.meta.RetrieveAttribute(bucket, object, etagkey)
+	if err != nil || string(b) != etag {
+		p.scrubCount(0, 1)
+		return nil
+	}
+
+	p.scrubCount(n, 0)
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if sum == etag {
+		return nil
+	}
+
+	log.Printf("scrub: %v/%v data md5 %v does not match etag %v", bucket, object, sum, etag)
+
+	mismatch := s3response.ScrubMismatch{
+		Bucket:   bucket,
+		Key:      object,
+		ETag:     etag,
+		Computed: sum,
+		Detected: time.Now(),
+	}
+	if p.scrub.quarantine {
+		err := p.quarantineObject(bucket, object, after.Size())
+		if err != nil {
+			log.Printf("scrub: quarantine %v/%v: %v", bucket, object, err)
+		} else {
+			mismatch.Quarantined = true
+		}
+	}
+
+	p.scrub.mu.Lock()
+	p.scrub.status.Mismatched++
+	if mismatch.Quarantined {
+		p.scrub.status.Quarantined++
+	}
+	p.scrub.status.Mismatches = append(p.scrub.status.Mismatches, mismatch)
+	if len(p.scrub.status.Mismatches) > scrubMismatchesKept {
+		p.scrub.status.Mismatches = slices.Delete(p.scrub.status.Mismatches, 0,
+			len(p.scrub.status.Mismatches)-scrubMismatchesKept)
+	}
+	p.scrub.mu.Unlock()
+
+	return nil
+}
+
+func (p *Posix) scrubCount(bytes int64, skipped int64) {
+	p.scrub.mu.Lock()
+	defer p.scrub.mu.Unlock()
+
+	if skipped > 0 {
+		p.scrub.status.Skipped += skipped
+		return
+	}
+	p.scrub.status.Objects++
+	p.scrub.status.Bytes += bytes
+}
+
+// quarantineObject moves the object out of the bucket namespace into
+// the bucket quarantine dir, keeping the object path
+func (p *Posix) quarantineObject(bucket, object string, size int64) error {
+	dst := filepath.Join(bucket, scrubQuarantineDir, object)
+	err := os.MkdirAll(filepath.Dir(dst), p.dirPerm)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(filepath.Join(bucket, object), dst)
+	if err != nil {
+		return err
+	}
+
+	p.meta.DeleteAttributes(bucket, object)
+	p.removeParents(bucket, object)
+	return p.releaseBucketQuota(bucket, size)
+}
+
+// scrubThrottle limits the scrub reads of a pass to rate bytes per
+// second, zero is unlimited
+type scrubThrottle struct {
+	rate  int64
+	start time.Time
+	n     int64
+	done  <-chan struct{}
+}
+
+func (t *scrubThrottle) wait(n int) error {
+	t.n += int64(n)
+	if t.rate <= 0 {
+		select {
+		case <-t.done:
+			return errScrubStopped
+		default:
+			return nil
+		}
+	}
+
+	due := t.start.Add(time.Duration(float64(t.n) / float64(t.rate) * float64(time.Second)))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-t.done:
+		return errScrubStopped
+	case <-timer.C:
+		return nil
+	}
+}
+
+type scrubReader struct {
+	r io.Reader
+	t *scrubThrottle
+}
+
+func (s *scrubReader) Read(b []byte) (int, error) {
+	if s.t.rate > 0 && int64(len(b)) > s.t.rate {
+		b = b[:s.t.rate]
+	}
+	n, err := s.r.Read(b)
+	if werr := s.t.wait(n); werr != nil {
+		return n, werr
+	}
+	return n, err
+}
@@ -0,0 +1,339 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+const (
+	// metaTrashDir is the bucket dir holding the objects deleted while
+	// the trash is enabled. Each object is kept at its key within an
+	// entry dir named by the deletion time and a random suffix.
+	metaTrashDir = ".sgwtrash"
+	// trashPurgeInterval is the longest interval between the removals
+	// of the expired trash entries
+	trashPurgeInterval = time.Hour
+)
+
+// newTrashID returns a trash entry id for an object deleted at t
+func newTrashID(t time.Time) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%016x-%s", t.UnixNano(), hex.EncodeToString(b))
+}
+
+// parseTrashID returns the deletion time of a trash entry id
+func parseTrashID(id string) (time.Time, bool) {
+	ts, suffix, ok := strings.Cut(id, "-")
+	if !ok || len(ts) != 16 || len(suffix) != 8 {
+		return time.Time{}, false
+	}
+	if _, err := hex.DecodeString(suffix); err != nil {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseUint(ts, 16, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(n)), true
+}
+
+// moveObject renames the object src to dst within the bucket, along
+// with its metadata for the stores not keeping it with the file
+func (p *Posix) moveObject(bucket, src, dst string) error {
+	attrs := make(map[string][]byte)
+	names, _ := p.meta.ListAttributes(bucket, src)
+	for _, name := range names {
+		b, err := p.meta.RetrieveAttribute(bucket, src, name)
+		if err == nil {
+			attrs[name] = b
+		}
+	}
+
+	err := os.Rename(filepath.Join(bucket, src), filepath.Join(bucket, dst))
+	if err != nil {
+		return err
+	}
+
+	for name, b := range attrs {
+		err := p.meta.StoreAttribute(bucket, dst, name, b)
+		if err != nil {
+			return fmt.Errorf("store %v: %w", name, err)
+		}
+	}
+
+	return p.meta.DeleteAttributes(bucket, src)
+}
+
+// trashObject moves a deleted object into the bucket trash
+func (p *Posix) trashObject(bucket, object string) error {
+	dst := filepath.Join(metaTrashDir, newTrashID(time.Now()), object)
+	err := os.MkdirAll(filepath.Join(bucket, filepath.Dir(dst)), p.dirPerm)
+	if err != nil {
+		return fmt.Errorf("create trash dir: %w", err)
+	}
+
+	err = p.moveObject(bucket, object, dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	if err != nil {
+		return fmt.Errorf("move object to trash: %w", err)
+	}
+
+	return nil
+}
+
+// trashEntry returns the key and file info of the object in the trash
+// entry dir
+func (p *Posix) trashEntry(bucket, id string) (string, fs.FileInfo, error) {
+	if _, ok := parseTrashID(id); !ok {
+		return "", nil, s3err.GetAPIError(s3err.ErrNoSuchTrashEntry)
+	}
+
+	entrydir := filepath.Join(bucket, metaTrashDir, id)
+	var key string
+	var fi fs.FileInfo
+	err := filepath.WalkDir(entrydir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(entrydir, path)
+		if err != nil {
+			return err
+		}
+		fi, err = d.Info()
+		if err != nil {
+			return err
+		}
+		key = filepath.ToSlash(rel)
+		return fs.SkipAll
+	})
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && fi == nil) {
+		return "", nil, s3err.GetAPIError(s3err.ErrNoSuchTrashEntry)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("walk trash entry: %w", err)
+	}
+
+	return key, fi, nil
+}
+
+func (p *Posix) checkTrash(bucket string) error {
+	if p.trashRetention == 0 {
+		return s3err.GetAPIError(s3err.ErrNotImplemented)
+	}
+
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	return nil
+}
+
+// ListTrash returns the trash entries of the bucket ordered by deletion
+// time, not implemented when the trash is disabled
+func (p *Posix) ListTrash(_ context.Context, bucket string) ([]s3response.TrashEntry, error) {
+	err := p.checkTrash(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	// the entry names sort by deletion time
+	ents, err := os.ReadDir(filepath.Join(bucket, metaTrashDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("readdir trash: %w", err)
+	}
+
+	entries := []s3response.TrashEntry{}
+	for _, ent := range ents {
+		deleted, ok := parseTrashID(ent.Name())
+		if !ok {
+			continue
+		}
+		key, fi, err := p.trashEntry(bucket, ent.Name())
+		if err != nil {
+			continue
+		}
+		entries = append(entries, s3response.TrashEntry{
+			Bucket:  bucket,
+			Key:     key,
+			ID:      ent.Name(),
+			Size:    fi.Size(),
+			Deleted: deleted,
+			Expires: deleted.Add(p.trashRetention),
+		})
+	}
+
+	return entries, nil
+}
+
+// RestoreTrash moves the object of a trash entry back to its key, the
+// restore fails if an object was put at the key since the deletion
+func (p *Posix) RestoreTrash(_ context.Context, bucket, id string) error {
+	err := p.checkTrash(bucket)
+	if err != nil {
+		return err
+	}
+
+	key, fi, err := p.trashEntry(bucket, id)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(filepath.Join(bucket, key))
+	dirPerm, _ := p.objectPerms(bucket)
+	err = backend.MkdirAllPerm(dir, dirPerm, p.exactPerms, 0, 0, false)
+	if err != nil {
+		return fmt.Errorf("create object parent dir: %w", err)
+	}
+
+	unlock := p.keyLocks.lock(bucket, key)
+	defer unlock()
+
+	src := filepath.Join(metaTrashDir, id, key)
+	err = p.linkWithBucketQuota(bucket, key, fi.Size(), func() error {
+		_, err := os.Lstat(filepath.Join(bucket, key))
+		if err == nil {
+			return s3err.GetAPIError(s3err.ErrTrashRestoreConflict)
+		}
+		return p.moveObject(bucket, src, key)
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(filepath.Join(bucket, metaTrashDir, id))
+}
+
+// PurgeTrash removes a trash entry, or all the bucket trash entries
+// when id is empty
+func (p *Posix) PurgeTrash(_ context.Context, bucket, id string) error {
+	err := p.checkTrash(bucket)
+	if err != nil {
+		return err
+	}
+
+	if id != "" {
+		return p.purgeTrashEntry(bucket, id)
+	}
+
+	ents, err := os.ReadDir(filepath.Join(bucket, metaTrashDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("readdir trash: %w", err)
+	}
+	for _, ent := range ents {
+		err := p.purgeTrashEntry(bucket, ent.Name())
+		if err != nil && !errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchTrashEntry)) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Posix) purgeTrashEntry(bucket, id string) error {
+	if _, ok := parseTrashID(id); !ok {
+		return s3err.GetAPIError(s3err.ErrNoSuchTrashEntry)
+	}
+	entrydir := filepath.Join(bucket, metaTrashDir, id)
+	_, err := os.Stat(entrydir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchTrashEntry)
+	}
+
+	// entries left without an object by an interrupted move are
+	// removed as well
+	key, _, err := p.trashEntry(bucket, id)
+	if err == nil {
+		p.meta.DeleteAttributes(bucket, filepath.Join(metaTrashDir, id, key))
+	}
+
+	err = os.RemoveAll(entrydir)
+	if err != nil {
+		return fmt.Errorf("remove trash entry: %w", err)
+	}
+
+	return nil
+}
+
+// runTrashPurge periodically removes the trash entries older than the
+// retention until Shutdown
+func (p *Posix) runTrashPurge() {
+	ticker := time.NewTicker(min(p.trashRetention, trashPurgeInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.purgeExpiredTrash()
+		}
+	}
+}
+
+func (p *Posix) purgeExpiredTrash() {
+	buckets, err := os.ReadDir(".")
+	if err != nil {
+		log.Printf("trash: readdir buckets: %v", err)
+		return
+	}
+
+	cutoff := time.Now().Add(-p.trashRetention)
+	var purged int
+	for _, bucket := range buckets {
+		if !p.isBucketDir(bucket) || strings.HasPrefix(bucket.Name(), ".") {
+			continue
+		}
+		ents, _ := os.ReadDir(filepath.Join(bucket.Name(), metaTrashDir))
+		for _, ent := range ents {
+			deleted, ok := parseTrashID(ent.Name())
+			if !ok || deleted.After(cutoff) {
+				continue
+			}
+			if p.purgeTrashEntry(bucket.Name(), ent.Name()) == nil {
+				purged++
+			}
+		}
+	}
+
+	if purged > 0 {
+		log.Printf("trash: purged %v expired entries", purged)
+	}
+}
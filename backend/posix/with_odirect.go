@@ -0,0 +1,208 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+// +build linux
+
+package posix
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// directAlign is the buffer, offset and length alignment of O_DIRECT
+	// transfers, which covers the logical block size of common devices
+	directAlign = 4096
+	// directBufSize is the size of the aligned transfer buffer
+	directBufSize = 1 << 20
+)
+
+// alignedBuffer returns a buffer of size bytes starting at a directAlign
+// aligned address
+func alignedBuffer(size int) []byte {
+	b := make([]byte, size+directAlign)
+	off := int(uintptr(unsafe.Pointer(&b[0])) & (directAlign - 1))
+	if off != 0 {
+		off = directAlign - off
+	}
+	return b[off : off+size]
+}
+
+// setDirect sets or clears O_DIRECT on the open file
+func setDirect(f *os.File, direct bool) error {
+	flags, err := unix.FcntlInt(f.Fd(), unix.F_GETFL, 0)
+	if err != nil {
+		return err
+	}
+	if direct {
+		flags |= unix.O_DIRECT
+	} else {
+		flags &^= unix.O_DIRECT
+	}
+	_, err = unix.FcntlInt(f.Fd(), unix.F_SETFL, flags)
+	return err
+}
+
+// directWriter writes a file sequentially from the start with O_DIRECT.
+// The data is gathered in an aligned buffer, and the unaligned tail is
+// written without O_DIRECT by flush.
+type directWriter struct {
+	f   *os.File
+	buf []byte
+	n   int
+	// buffered is set when the filesystem rejected the O_DIRECT writes
+	buffered bool
+}
+
+func newDirectWriter(f *os.File) (*directWriter, error) {
+	err := setDirect(f, true)
+	if err != nil {
+		return nil, fmt.Errorf("set O_DIRECT: %w", err)
+	}
+	return &directWriter{f: f, buf: alignedBuffer(directBufSize)}, nil
+}
+
+func (w *directWriter) Write(b []byte) (int, error) {
+	var written int
+	for len(b) > 0 {
+		c := copy(w.buf[w.n:], b)
+		w.n += c
+		written += c
+		b = b[c:]
+		if w.n == len(w.buf) {
+			err := w.writeBuf(w.n)
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// writeBuf writes the first n bytes of the buffer, and keeps the
+// remaining buffered bytes for the next write
+func (w *directWriter) writeBuf(n int) error {
+	_, err := w.f.Write(w.buf[:n])
+	if errors.Is(err, unix.EINVAL) && !w.buffered {
+		// not all filesystems support O_DIRECT, which is only
+		// reported once data is written
+		err = setDirect(w.f, false)
+		if err != nil {
+			return fmt.Errorf("clear O_DIRECT: %w", err)
+		}
+		w.buffered = true
+		_, err = w.f.Write(w.buf[:n])
+	}
+	if err != nil {
+		return err
+	}
+	w.n = copy(w.buf, w.buf[n:w.n])
+	return nil
+}
+
+// flush writes the buffered data, and leaves the file without O_DIRECT
+func (w *directWriter) flush() error {
+	aligned := w.n &^ (directAlign - 1)
+	if aligned > 0 {
+		err := w.writeBuf(aligned)
+		if err != nil {
+			return err
+		}
+	}
+	if !w.buffered {
+		err := setDirect(w.f, false)
+		if err != nil {
+			return fmt.Errorf("clear O_DIRECT: %w", err)
+		}
+		w.buffered = true
+	}
+	return w.writeBuf(w.n)
+}
+
+// directReader reads a range of a file with O_DIRECT, using aligned reads
+// that cover the range
+type directReader struct {
+	f    *os.File
+	off  int64
+	end  int64
+	buf  []byte
+	data []byte
+	// buffered is set when the filesystem rejected the O_DIRECT reads
+	buffered bool
+}
+
+func newDirectReader(f *os.File, off, length int64) (io.Reader, error) {
+	err := setDirect(f, true)
+	if err != nil {
+		return nil, fmt.Errorf("set O_DIRECT: %w", err)
+	}
+	return &directReader{
+		f:   f,
+		off: off,
+		end: off + length,
+		buf: alignedBuffer(directBufSize),
+	}, nil
+}
+
+func (r *directReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		if r.off >= r.end {
+			return 0, io.EOF
+		}
+		err := r.fill()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	r.off += int64(n)
+	return n, nil
+}
+
+// fill reads the aligned block range starting at or before the current
+// offset into the buffer
+func (r *directReader) fill() error {
+	start := r.off &^ (directAlign - 1)
+	n, err := unix.Pread(int(r.f.Fd()), r.buf, start)
+	if errors.Is(err, unix.EINVAL) && !r.buffered {
+		err = setDirect(r.f, false)
+		if err != nil {
+			return fmt.Errorf("clear O_DIRECT: %w", err)
+		}
+		r.buffered = true
+		n, err = unix.Pread(int(r.f.Fd()), r.buf, start)
+	}
+	if err != nil {
+		return err
+	}
+
+	skip := int(r.off - start)
+	if n <= skip {
+		return io.ErrUnexpectedEOF
+	}
+	r.data = r.buf[skip:n]
+	if rem := r.end - r.off; int64(len(r.data)) > rem {
+		r.data = r.data[:rem]
+	}
+	return nil
+}
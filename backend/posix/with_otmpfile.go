@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -36,6 +37,7 @@ const procfddir = "/proc/self/fd"
 
 type tmpfile struct {
 	f          *os.File
+	dir        string
 	bucket     string
 	objname    string
 	isOTmp     bool
@@ -43,15 +45,24 @@ type tmpfile struct {
 	needsChown bool
 	uid        int
 	gid        int
+	dirPerm    fs.FileMode
+	filePerm   fs.FileMode
+	exactPerms bool
+	// setgid gives the object the group of a setgid parent directory
+	setgid bool
+	// directio writes the data with O_DIRECT through dw
+	directio bool
+	dw       *directWriter
+	// commit is called with the temp file path before it is renamed
+	// over the object, a failure leaves the object unchanged
+	commit func(tempname string) error
+	// durability flushes the object before it is acknowledged
+	durability Durability
 }
 
-var (
-	// TODO: make this configurable
-	defaultFilePerm uint32 = 0644
-)
-
 func (p *Posix) openTmpFile(dir, bucket, obj string, size int64, acct auth.Account) (*tmpfile, error) {
 	uid, gid, doChown := p.getChownIDs(acct)
+	dirPerm, filePerm := p.objectPerms(bucket)
 
 	// O_TMPFILE allows for a file handle to an unnamed file in the filesystem.
 	// This can help reduce contention within the namespace (parent directories),
@@ -59,10 +70,11 @@ func (p *Posix) openTmpFile(dir, bucket, obj string, size int64, acct auth.Accou
 	// file descriptor into the namespace.
 	// Not all filesystems support this, so fallback to CreateTemp for when
 	// this is not supported.
-	fd, err := unix.Open(dir, unix.O_RDWR|unix.O_TMPFILE|unix.O_CLOEXEC, defaultFilePerm)
+	fd, err := unix.Open(dir, unix.O_RDWR|unix.O_TMPFILE|unix.O_CLOEXEC,
+		uint32(filePerm.Perm()))
 	if err != nil {
 		// O_TMPFILE not supported, try fallback
-		err = backend.MkdirAll(dir, uid, gid, doChown)
+		err = backend.MkdirAllPerm(dir, dirPerm, p.exactPerms, uid, gid, doChown)
 		if err != nil {
 			return nil, fmt.Errorf("make temp dir: %w", err)
 		}
@@ -79,10 +91,19 @@ func (p *Posix) openTmpFile(dir, bucket, obj string, size int64, acct auth.Accou
 			needsChown: doChown,
 			uid:        uid,
 			gid:        gid,
+			dirPerm:    dirPerm,
+			filePerm:   filePerm,
+			exactPerms: p.exactPerms,
+			setgid:     p.setgid,
+			directio:   p.useDirectIO(size),
+			durability: p.durability,
 		}
-		// falloc is best effort, its fine if this fails
 		if size > 0 {
-			tmp.falloc()
+			err := tmp.preallocate()
+			if err != nil {
+				tmp.cleanup()
+				return nil, err
+			}
 		}
 
 		if doChown {
@@ -101,6 +122,7 @@ func (p *Posix) openTmpFile(dir, bucket, obj string, size int64, acct auth.Accou
 
 	tmp := &tmpfile{
 		f:          f,
+		dir:        dir,
 		bucket:     bucket,
 		objname:    obj,
 		isOTmp:     true,
@@ -108,11 +130,20 @@ func (p *Posix) openTmpFile(dir, bucket, obj string, size int64, acct auth.Accou
 		needsChown: doChown,
 		uid:        uid,
 		gid:        gid,
+		dirPerm:    dirPerm,
+		filePerm:   filePerm,
+		exactPerms: p.exactPerms,
+		setgid:     p.setgid,
+		directio:   p.useDirectIO(size),
+		durability: p.durability,
 	}
 
-	// falloc is best effort, its fine if this fails
 	if size > 0 {
-		tmp.falloc()
+		err := tmp.preallocate()
+		if err != nil {
+			tmp.cleanup()
+			return nil, err
+		}
 	}
 
 	if doChown {
@@ -128,7 +159,33 @@ func (p *Posix) openTmpFile(dir, bucket, obj string, size int64, acct auth.Accou
 func (tmp *tmpfile) falloc() error {
 	err := syscall.Fallocate(int(tmp.f.Fd()), 0, 0, tmp.size)
 	if err != nil {
-		return fmt.Errorf("fallocate: %v", err)
+		return fmt.Errorf("fallocate: %w", err)
+	}
+	return nil
+}
+
+// clone shares the extents of size bytes of src at offset off with the
+// temp file, on filesystems supporting reflinks
+func (tmp *tmpfile) clone(src *os.File, off, size int64) error {
+	err := unix.IoctlFileCloneRange(int(tmp.f.Fd()), &unix.FileCloneRange{
+		Src_fd:     int64(src.Fd()),
+		Src_offset: uint64(off),
+		Src_length: uint64(size),
+	})
+	if err != nil {
+		return fmt.Errorf("clone range: %w", err)
+	}
+	return nil
+}
+
+// preallocate allocates the full object size up front, to limit the
+// fragmentation of large objects and to fail an upload without enough
+// space or quota before any data is transferred. Allocation is otherwise
+// best effort, as not all filesystems support it.
+func (tmp *tmpfile) preallocate() error {
+	err := tmp.falloc()
+	if isNoSpace(err) {
+		return err
 	}
 	return nil
 }
@@ -140,19 +197,37 @@ func (tmp *tmpfile) link() error {
 	// temp file into place for the object. This ensures the object semantics
 	// of last upload completed wins and is not some combination of writes
 	// from simultaneous uploads.
-	objPath := filepath.Join(tmp.bucket, tmp.objname)
-	err := os.Remove(objPath)
-	if err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return fmt.Errorf("remove stale path: %w", err)
+	if tmp.dw != nil {
+		err := tmp.dw.flush()
+		if err != nil {
+			return fmt.Errorf("write tmpfile: %w", err)
+		}
 	}
 
+	objPath := filepath.Join(tmp.bucket, tmp.objname)
 	dir := filepath.Dir(objPath)
 
-	err = backend.MkdirAll(dir, tmp.uid, tmp.gid, tmp.needsChown)
+	err := backend.MkdirAllPerm(dir, tmp.dirPerm, tmp.exactPerms,
+		tmp.uid, tmp.gid, tmp.needsChown)
 	if err != nil {
 		return fmt.Errorf("make parent dir: %w", err)
 	}
 
+	if tmp.setgid {
+		err = tmp.inheritGroup(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	if tmp.exactPerms {
+		// the unnamed file was created with the umask applied
+		err = tmp.f.Chmod(tmp.filePerm)
+		if err != nil {
+			return fmt.Errorf("set tmpfile mode: %w", err)
+		}
+	}
+
 	if !tmp.isOTmp {
 		// O_TMPFILE not suported, use fallback
 		return tmp.fallbackLink()
@@ -164,24 +239,69 @@ func (tmp *tmpfile) link() error {
 	}
 	defer procdir.Close()
 
-	dirf, err := os.Open(dir)
+	// linkat can not replace an existing object, so the unnamed file is
+	// linked into the temp dir it was created in, and then renamed over
+	// the object. The object is always either the previous or the new
+	// version, and a crash in between leaves a temp file for the gc.
+	var tempname string
+	for i := 0; i < 10; i++ {
+		tempname = filepath.Join(tmp.dir, fmt.Sprintf("%x.%v",
+			sha256.Sum256([]byte(tmp.objname)), rand.Uint32()))
+		err = unix.Linkat(int(procdir.Fd()), filepath.Base(tmp.f.Name()),
+			unix.AT_FDCWD, tempname, unix.AT_SYMLINK_FOLLOW)
+		if !errors.Is(err, unix.EEXIST) {
+			break
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("open parent dir: %w", err)
+		return fmt.Errorf("link tmpfile (%q in %q): %w",
+			filepath.Base(tmp.f.Name()), tmp.dir, err)
+	}
+
+	if tmp.commit != nil {
+		err = tmp.commit(tempname)
+		if err != nil {
+			os.Remove(tempname)
+			return err
+		}
 	}
-	defer dirf.Close()
 
-	err = unix.Linkat(int(procdir.Fd()), filepath.Base(tmp.f.Name()),
-		int(dirf.Fd()), filepath.Base(objPath), unix.AT_SYMLINK_FOLLOW)
+	err = syncFile(tmp.f, tmp.durability)
 	if err != nil {
-		return fmt.Errorf("link tmpfile (%q in %q): %w",
-			filepath.Dir(objPath), filepath.Base(tmp.f.Name()), err)
+		os.Remove(tempname)
+		return err
 	}
 
 	err = tmp.f.Close()
 	if err != nil {
+		os.Remove(tempname)
 		return fmt.Errorf("close tmpfile: %w", err)
 	}
 
+	err = renameObject(tempname, objPath)
+	if err != nil {
+		os.Remove(tempname)
+		return err
+	}
+
+	return syncParent(objPath, tmp.durability)
+}
+
+// inheritGroup sets the group of the temp file to the group of dir when
+// dir is setgid, as for a file created within dir
+func (tmp *tmpfile) inheritGroup(dir string) error {
+	var st unix.Stat_t
+	err := unix.Stat(dir, &st)
+	if err != nil {
+		return fmt.Errorf("stat parent dir: %w", err)
+	}
+	if st.Mode&unix.S_ISGID == 0 {
+		return nil
+	}
+	err = tmp.f.Chown(-1, int(st.Gid))
+	if err != nil {
+		return fmt.Errorf("set tmpfile group: %w", err)
+	}
 	return nil
 }
 
@@ -192,20 +312,32 @@ func (tmp *tmpfile) fallbackLink() error {
 	defer os.Remove(tempname)
 
 	// reset default file mode because CreateTemp uses 0600
-	tmp.f.Chmod(fs.FileMode(defaultFilePerm))
+	tmp.f.Chmod(tmp.filePerm)
 
-	err := tmp.f.Close()
+	if tmp.commit != nil {
+		err := tmp.commit(tempname)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := syncFile(tmp.f, tmp.durability)
+	if err != nil {
+		return err
+	}
+
+	err = tmp.f.Close()
 	if err != nil {
 		return fmt.Errorf("close tmpfile: %w", err)
 	}
 
 	objPath := filepath.Join(tmp.bucket, tmp.objname)
-	err = os.Rename(tempname, objPath)
+	err = renameObject(tempname, objPath)
 	if err != nil {
-		return fmt.Errorf("rename tmpfile: %w", err)
+		return err
 	}
 
-	return nil
+	return syncParent(objPath, tmp.durability)
 }
 
 func (tmp *tmpfile) Write(b []byte) (int, error) {
@@ -213,11 +345,52 @@ func (tmp *tmpfile) Write(b []byte) (int, error) {
 		return 0, fmt.Errorf("write exceeds content length %v", tmp.size)
 	}
 
-	n, err := tmp.f.Write(b)
+	if tmp.directio && tmp.dw == nil {
+		dw, err := newDirectWriter(tmp.f)
+		if err != nil {
+			// O_DIRECT is best effort, write through the page cache
+			tmp.directio = false
+		} else {
+			tmp.dw = dw
+		}
+	}
+
+	var n int
+	var err error
+	if tmp.dw != nil {
+		n, err = tmp.dw.Write(b)
+	} else {
+		n, err = tmp.f.Write(b)
+	}
 	tmp.size -= int64(n)
 	return n, err
 }
 
+// ReadAt reads back the data written to the temp file
+func (tmp *tmpfile) ReadAt(b []byte, off int64) (int, error) {
+	if tmp.dw != nil {
+		err := tmp.dw.flush()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return tmp.f.ReadAt(b, off)
+}
+
 func (tmp *tmpfile) cleanup() {
 	tmp.f.Close()
+	if !tmp.isOTmp {
+		// the named temp file is left behind if the upload failed before
+		// being linked into the namespace
+		os.Remove(tmp.f.Name())
+	}
+}
+
+// fileID returns the inode number of the file
+func fileID(fi fs.FileInfo) uint64 {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return st.Ino
 }
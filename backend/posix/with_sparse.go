@@ -0,0 +1,126 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux
+// +build linux
+
+package posix
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// sparseReader reads a range of a sparse file, locating the data extents
+// with SEEK_DATA/SEEK_HOLE so the holes are returned as zeros without
+// reading them from the file
+type sparseReader struct {
+	f   *os.File
+	off int64
+	end int64
+	// holeEnd and dataEnd are the ends of the hole or the data extent
+	// at the current offset, only one is past the offset at a time
+	holeEnd int64
+	dataEnd int64
+}
+
+// newSparseReader returns a reader for length bytes at offset off of the
+// file, or an error if the file has no holes
+func newSparseReader(f *os.File, off, length int64) (io.Reader, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || st.Blocks*512 >= fi.Size() {
+		return nil, errors.ErrUnsupported
+	}
+
+	return &sparseReader{
+		f:   f,
+		off: off,
+		end: off + length,
+	}, nil
+}
+
+func (r *sparseReader) Read(p []byte) (int, error) {
+	if r.off >= r.end {
+		return 0, io.EOF
+	}
+	if r.off >= r.holeEnd && r.off >= r.dataEnd {
+		err := r.seek()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if rem := r.end - r.off; int64(len(p)) > rem {
+		p = p[:rem]
+	}
+
+	if r.off < r.holeEnd {
+		if rem := r.holeEnd - r.off; int64(len(p)) > rem {
+			p = p[:rem]
+		}
+		clear(p)
+		r.off += int64(len(p))
+		return len(p), nil
+	}
+
+	if rem := r.dataEnd - r.off; int64(len(p)) > rem {
+		p = p[:rem]
+	}
+	n, err := r.f.ReadAt(p, r.off)
+	r.off += int64(n)
+	if errors.Is(err, io.EOF) {
+		// the file was truncated while being read
+		if n > 0 {
+			return n, nil
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// seek locates the hole or data extent at the current offset
+func (r *sparseReader) seek() error {
+	data, err := r.f.Seek(r.off, unix.SEEK_DATA)
+	if errors.Is(err, unix.ENXIO) {
+		// no more data, the rest of the range is a hole
+		r.holeEnd = r.end
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if data > r.off {
+		r.holeEnd = data
+		return nil
+	}
+
+	hole, err := r.f.Seek(r.off, unix.SEEK_HOLE)
+	if err != nil {
+		return err
+	}
+	if hole <= r.off {
+		// the file changed in between, read the rest of the range
+		hole = r.end
+	}
+	r.dataEnd = hole
+	return nil
+}
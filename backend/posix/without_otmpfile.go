@@ -30,18 +30,26 @@ import (
 )
 
 type tmpfile struct {
-	f       *os.File
-	bucket  string
-	objname string
-	size    int64
+	f        *os.File
+	bucket   string
+	objname  string
+	size     int64
+	dirPerm  fs.FileMode
+	filePerm fs.FileMode
+	// commit is called with the temp file path before it is renamed
+	// over the object, a failure leaves the object unchanged
+	commit func(tempname string) error
+	// durability flushes the object before it is acknowledged
+	durability Durability
 }
 
 func (p *Posix) openTmpFile(dir, bucket, obj string, size int64, acct auth.Account) (*tmpfile, error) {
 	uid, gid, doChown := p.getChownIDs(acct)
+	dirPerm, filePerm := p.objectPerms(bucket)
 
 	// Create a temp file for upload while in progress (see link comments below).
 	var err error
-	err = backend.MkdirAll(dir, uid, gid, doChown)
+	err = backend.MkdirAllPerm(dir, dirPerm, p.exactPerms, uid, gid, doChown)
 	if err != nil {
 		return nil, fmt.Errorf("make temp dir: %w", err)
 	}
@@ -58,14 +66,10 @@ func (p *Posix) openTmpFile(dir, bucket, obj string, size int64, acct auth.Accou
 		}
 	}
 
-	return &tmpfile{f: f, bucket: bucket, objname: obj, size: size}, nil
+	return &tmpfile{f: f, bucket: bucket, objname: obj, size: size,
+		dirPerm: dirPerm, filePerm: filePerm, durability: p.durability}, nil
 }
 
-var (
-	// TODO: make this configurable
-	defaultFilePerm fs.FileMode = 0644
-)
-
 func (tmp *tmpfile) link() error {
 	tempname := tmp.f.Name()
 	// cleanup in case anything goes wrong, if rename succeeds then
@@ -78,25 +82,33 @@ func (tmp *tmpfile) link() error {
 	// the object. This ensures the object semantics of last upload completed
 	// wins and is not some combination of writes from simultaneous uploads.
 	objPath := filepath.Join(tmp.bucket, tmp.objname)
-	err := os.Remove(objPath)
-	if err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return fmt.Errorf("remove stale path: %w", err)
-	}
 
 	// reset default file mode because CreateTemp uses 0600
-	tmp.f.Chmod(defaultFilePerm)
+	tmp.f.Chmod(tmp.filePerm)
+
+	if tmp.commit != nil {
+		err := tmp.commit(tempname)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := syncFile(tmp.f, tmp.durability)
+	if err != nil {
+		return err
+	}
 
 	err = tmp.f.Close()
 	if err != nil {
 		return fmt.Errorf("close tmpfile: %w", err)
 	}
 
-	err = os.Rename(tempname, objPath)
+	err = renameObject(tempname, objPath)
 	if err != nil {
-		return fmt.Errorf("rename tmpfile: %w", err)
+		return err
 	}
 
-	return nil
+	return syncParent(objPath, tmp.durability)
 }
 
 func (tmp *tmpfile) Write(b []byte) (int, error) {
@@ -109,6 +121,25 @@ func (tmp *tmpfile) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// clone is not supported without the linux reflink ioctl
+func (tmp *tmpfile) clone(*os.File, int64, int64) error {
+	return errors.ErrUnsupported
+}
+
+// ReadAt reads back the data written to the temp file
+func (tmp *tmpfile) ReadAt(b []byte, off int64) (int, error) {
+	return tmp.f.ReadAt(b, off)
+}
+
 func (tmp *tmpfile) cleanup() {
 	tmp.f.Close()
+	// the temp file is left behind if the upload failed before
+	// being renamed into place
+	os.Remove(tmp.f.Name())
+}
+
+// fileID returns 0 as the file identity is not portable, the stat etag
+// then only depends on the size and modification time
+func fileID(fs.FileInfo) uint64 {
+	return 0
 }
@@ -0,0 +1,30 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build !linux
+// +build !linux
+
+package posix
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// newSparseReader is not supported without linux SEEK_DATA/SEEK_HOLE,
+// the holes are read from the file
+func newSparseReader(*os.File, int64, int64) (io.Reader, error) {
+	return nil, errors.ErrUnsupported
+}
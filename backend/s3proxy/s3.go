@@ -25,6 +25,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -288,6 +289,12 @@ func (s *S3Proxy) GetObject(ctx context.Context, input *s3.GetObjectInput, w io.
 	}
 	defer output.Body.Close()
 
+	err = backend.SetWriterMetadata(w, output.Metadata,
+		contentRangeOffset(output.ContentRange))
+	if err != nil {
+		return nil, err
+	}
+
 	_, err = io.Copy(w, output.Body)
 	if err != nil {
 		return nil, err
@@ -296,6 +303,24 @@ func (s *S3Proxy) GetObject(ctx context.Context, input *s3.GetObjectInput, w io.
 	return output, nil
 }
 
+// contentRangeOffset returns the object offset of the first byte of a
+// ranged GetObject response
+func contentRangeOffset(contentRange *string) int64 {
+	if contentRange == nil {
+		return 0
+	}
+	rng, ok := strings.CutPrefix(*contentRange, "bytes ")
+	if !ok {
+		return 0
+	}
+	start, _, _ := strings.Cut(rng, "-")
+	offset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return offset
+}
+
 func (s *S3Proxy) GetObjectAttributes(ctx context.Context, input *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error) {
 	out, err := s.client.GetObjectAttributes(ctx, input)
 
@@ -0,0 +1,299 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux && amd64
+
+package scoutfs
+
+import (
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/versity/scoutfs-go"
+)
+
+// listBufSize is the size of the getdents buffer, large enough for a few
+// thousand entries per call
+const listBufSize = 256 * 1024
+
+// listFS is the fs.FS the bucket objects are listed from. The directories
+// are read with large getdents batches that return the entry types along
+// with the names, so that the entries are not stat'ed to be classified.
+// The entries listed as objects read the stat and, in glacier mode, the
+// scoutfs stat_more from a single open of the file rather than looking up
+// the full path once for each.
+type listFS struct {
+	root     string
+	statMore bool
+}
+
+func newListFS(root string, statMore bool) fs.FS {
+	return listFS{root: root, statMore: statMore}
+}
+
+func (l listFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	dir := filepath.Join(l.root, filepath.FromSlash(name))
+	fd, err := unix.Open(dir, unix.O_RDONLY|unix.O_CLOEXEC|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &listDir{
+		fsys: l,
+		fd:   fd,
+		name: name,
+		path: dir,
+	}, nil
+}
+
+// listDir is an open directory of the listFS
+type listDir struct {
+	fsys listFS
+	fd   int
+	name string
+	path string
+
+	// buf holds the getdents results with the entries from bufp to
+	// nbuf not returned yet
+	buf  []byte
+	bufp int
+	nbuf int
+	eof  bool
+}
+
+func (d *listDir) Stat() (fs.FileInfo, error) {
+	var st unix.Stat_t
+	err := unix.Fstat(d.fd, &st)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: d.name, Err: err}
+	}
+	return newListInfo(path.Base(d.name), &st, nil), nil
+}
+
+func (d *listDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: unix.EISDIR}
+}
+
+func (d *listDir) Close() error {
+	return unix.Close(d.fd)
+}
+
+// ReadDir returns the next n directory entries, or all of the remaining
+// ones when n <= 0, in directory order
+func (d *listDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.buf == nil {
+		d.buf = make([]byte, listBufSize)
+	}
+
+	var ents []fs.DirEntry
+	for n <= 0 || len(ents) < n {
+		if d.bufp >= d.nbuf {
+			if d.eof {
+				break
+			}
+			nb, err := unix.Getdents(d.fd, d.buf)
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil {
+				return ents, &fs.PathError{Op: "readdirent", Path: d.name, Err: err}
+			}
+			if nb == 0 {
+				d.eof = true
+				break
+			}
+			d.bufp = 0
+			d.nbuf = nb
+		}
+
+		// linux_dirent64: ino, off, reclen, type, name
+		rec := d.buf[d.bufp:d.nbuf]
+		reclen := int(binary.LittleEndian.Uint16(rec[16:18]))
+		typ := rec[18]
+		name := rec[19:reclen]
+		for i, c := range name {
+			if c == 0 {
+				name = name[:i]
+				break
+			}
+		}
+		d.bufp += reclen
+
+		if string(name) == "." || string(name) == ".." {
+			continue
+		}
+
+		e, err := d.newEntry(string(name), typ)
+		if err != nil {
+			// removed since the directory was read
+			continue
+		}
+		ents = append(ents, e)
+	}
+
+	if n > 0 && len(ents) == 0 {
+		return nil, io.EOF
+	}
+	return ents, nil
+}
+
+func (d *listDir) newEntry(name string, typ uint8) (*listEntry, error) {
+	e := &listEntry{
+		fsys: d.fsys,
+		path: filepath.Join(d.path, name),
+		name: name,
+	}
+
+	switch typ {
+	case unix.DT_DIR:
+		e.typ = fs.ModeDir
+	case unix.DT_REG:
+	case unix.DT_UNKNOWN:
+		// not all filesystems return the entry types
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		e.typ = info.Mode().Type()
+	default:
+		e.typ = fileTypes[typ]
+	}
+
+	return e, nil
+}
+
+var fileTypes = map[uint8]fs.FileMode{
+	unix.DT_LNK:  fs.ModeSymlink,
+	unix.DT_FIFO: fs.ModeNamedPipe,
+	unix.DT_SOCK: fs.ModeSocket,
+	unix.DT_CHR:  fs.ModeDevice | fs.ModeCharDevice,
+	unix.DT_BLK:  fs.ModeDevice,
+}
+
+// listEntry is a directory entry of the listFS, the entry info is only
+// read when needed and then kept for the life of the entry
+type listEntry struct {
+	fsys listFS
+	path string
+	name string
+	typ  fs.FileMode
+	info fs.FileInfo
+}
+
+func (e *listEntry) Name() string      { return e.name }
+func (e *listEntry) IsDir() bool       { return e.typ.IsDir() }
+func (e *listEntry) Type() fs.FileMode { return e.typ }
+
+func (e *listEntry) Info() (fs.FileInfo, error) {
+	if e.info != nil {
+		return e.info, nil
+	}
+
+	if !e.fsys.statMore || !e.typ.IsRegular() {
+		var st unix.Stat_t
+		err := unix.Lstat(e.path, &st)
+		if err != nil {
+			return nil, &fs.PathError{Op: "lstat", Path: e.path, Err: err}
+		}
+		e.info = newListInfo(e.name, &st, nil)
+		return e.info, nil
+	}
+
+	f, err := os.OpenFile(e.path, os.O_RDONLY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var st unix.Stat_t
+	err = unix.Fstat(int(f.Fd()), &st)
+	if err != nil {
+		return nil, &fs.PathError{Op: "fstat", Path: e.path, Err: err}
+	}
+	sm, err := scoutfs.FStatMore(f)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat more", Path: e.path, Err: err}
+	}
+
+	e.info = newListInfo(e.name, &st, &stat{
+		Meta_seq:       sm.Meta_seq,
+		Data_seq:       sm.Data_seq,
+		Data_version:   sm.Data_version,
+		Online_blocks:  sm.Online_blocks,
+		Offline_blocks: sm.Offline_blocks,
+		Crtime_sec:     sm.Crtime_sec,
+		Crtime_nsec:    sm.Crtime_nsec,
+	})
+	return e.info, nil
+}
+
+// listInfo is the fs.FileInfo of the listFS entries, Sys returns the
+// scoutfs stat_more of the file when it was read
+type listInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	more    *stat
+}
+
+func newListInfo(name string, st *unix.Stat_t, more *stat) *listInfo {
+	mode := fs.FileMode(st.Mode & 0777)
+	switch st.Mode & unix.S_IFMT {
+	case unix.S_IFDIR:
+		mode |= fs.ModeDir
+	case unix.S_IFLNK:
+		mode |= fs.ModeSymlink
+	case unix.S_IFIFO:
+		mode |= fs.ModeNamedPipe
+	case unix.S_IFSOCK:
+		mode |= fs.ModeSocket
+	case unix.S_IFCHR:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	case unix.S_IFBLK:
+		mode |= fs.ModeDevice
+	}
+
+	return &listInfo{
+		name:    name,
+		size:    st.Size,
+		mode:    mode,
+		modTime: time.Unix(st.Mtim.Sec, st.Mtim.Nsec),
+		more:    more,
+	}
+}
+
+func (i *listInfo) Name() string       { return i.name }
+func (i *listInfo) Size() int64        { return i.size }
+func (i *listInfo) Mode() fs.FileMode  { return i.mode }
+func (i *listInfo) ModTime() time.Time { return i.modTime }
+func (i *listInfo) IsDir() bool        { return i.mode.IsDir() }
+
+func (i *listInfo) Sys() any {
+	if i.more == nil {
+		return nil
+	}
+	return i.more
+}
@@ -0,0 +1,253 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package scoutfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/xattr"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+// With project quotas each bucket directory is assigned a scoutfs project
+// id, inherited by all new files and directories within the bucket. Every
+// object is tagged with a scoutfs xattr total of its size, named by the
+// quotaTotlID and the project id, so that the filesystem maintains the
+// bucket usage and enforces the bucket quota rules itself. Objects are
+// tagged once placed, so a single object can exceed the quota, after
+// which writes to the bucket fail with EDQUOT.
+const (
+	// quotaTotlID is the first id of the object xattr totals, the
+	// second is unused and the third is the bucket project id
+	quotaTotlID = 0x766777
+)
+
+func totlXattr(project uint64) string {
+	return fmt.Sprintf("scoutfs.totl.versitygw.%d.0.%d", quotaTotlID, project)
+}
+
+// CreateBucket assigns the new bucket a project id when project quotas are
+// enabled
+func (s *ScoutFS) CreateBucket(ctx context.Context, input *s3.CreateBucketInput, acl []byte) error {
+	err := s.Posix.CreateBucket(ctx, input, acl)
+	if err != nil || !s.projectquota {
+		return err
+	}
+
+	_, err = setBucketProject(*input.Bucket)
+	if err != nil {
+		return fmt.Errorf("set bucket project id: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ScoutFS) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, error) {
+	etag, err := s.Posix.PutObject(ctx, po)
+	if err != nil || !s.projectquota {
+		return etag, err
+	}
+
+	return etag, s.accountObject(*po.Bucket, *po.Key)
+}
+
+func (s *ScoutFS) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	out, err := s.Posix.CopyObject(ctx, input)
+	if err != nil || !s.projectquota {
+		return out, err
+	}
+
+	return out, s.accountObject(*input.Bucket, *input.Key)
+}
+
+// accountObject tags the object with its size for the bucket project
+// totals, the object is removed if it can't be accounted
+func (s *ScoutFS) accountObject(bucket, object string) error {
+	project, err := getProjectID(bucket)
+	if err != nil {
+		return fmt.Errorf("get bucket project id: %w", err)
+	}
+	if project == 0 {
+		return nil
+	}
+
+	objPath := filepath.Join(bucket, object)
+	err = setObjectTotl(objPath, project)
+	if err != nil {
+		// cleanup object if returning error
+		os.Remove(objPath)
+		return fmt.Errorf("set object quota total: %w", err)
+	}
+
+	return nil
+}
+
+func setObjectTotl(objPath string, project uint64) error {
+	fi, err := os.Lstat(objPath)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+
+	return xattr.Set(objPath, totlXattr(project),
+		[]byte(strconv.FormatInt(fi.Size(), 10)))
+}
+
+// PutBucketQuota sets the bucket project quota rules, a zero quota removes
+// them. A bucket created before project quotas were enabled is assigned a
+// project id, and its existing files and directories are updated.
+func (s *ScoutFS) PutBucketQuota(ctx context.Context, bucket string, quota s3response.BucketQuota) error {
+	if !s.projectquota {
+		return s.Posix.PutBucketQuota(ctx, bucket, quota)
+	}
+
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	s.quotaMu.Lock()
+	defer s.quotaMu.Unlock()
+
+	project, err := getProjectID(bucket)
+	if err != nil {
+		return fmt.Errorf("get bucket project id: %w", err)
+	}
+	if project == 0 {
+		if quota.Size == 0 && quota.Objects == 0 {
+			return nil
+		}
+		project, err = s.assignBucketProject(bucket)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = setProjectQuota(s.rootfd, project, uint64(quota.Size), uint64(quota.Objects))
+	if err != nil {
+		return fmt.Errorf("set project quota rules: %w", err)
+	}
+
+	return nil
+}
+
+// assignBucketProject sets the project id of an existing bucket and all of
+// its files and directories, and accounts the existing objects
+func (s *ScoutFS) assignBucketProject(bucket string) (uint64, error) {
+	project, err := setBucketProject(bucket)
+	if err != nil {
+		return 0, fmt.Errorf("set bucket project id: %w", err)
+	}
+
+	tmpdir := filepath.Join(bucket, metaTmpDir)
+	err = filepath.WalkDir(bucket, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			// removed while walking
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if path == bucket {
+			return nil
+		}
+
+		err = setProjectID(path, project)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("set project id %v: %w", path, err)
+		}
+
+		// parts and temp files are not objects
+		if path == tmpdir || !d.Type().IsRegular() ||
+			strings.HasPrefix(path, tmpdir+string(os.PathSeparator)) {
+			return nil
+		}
+
+		err = setObjectTotl(path, project)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("set object quota total %v: %w", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return project, nil
+}
+
+// GetBucketQuota returns the bucket project quota rule limits, along with
+// the bucket usage totals maintained by the filesystem
+func (s *ScoutFS) GetBucketQuota(ctx context.Context, bucket string) (s3response.BucketQuota, error) {
+	if !s.projectquota {
+		return s.Posix.GetBucketQuota(ctx, bucket)
+	}
+
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3response.BucketQuota{}, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return s3response.BucketQuota{}, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	project, err := getProjectID(bucket)
+	if err != nil {
+		return s3response.BucketQuota{}, fmt.Errorf("get bucket project id: %w", err)
+	}
+	if project == 0 {
+		return s3response.BucketQuota{}, nil
+	}
+
+	size, objects, err := getProjectQuota(s.rootfd, project)
+	if err != nil {
+		return s3response.BucketQuota{}, fmt.Errorf("get project quota rules: %w", err)
+	}
+	if size == 0 && objects == 0 {
+		return s3response.BucketQuota{}, nil
+	}
+
+	usedSize, usedObjects, err := getProjectUsage(s.rootfd, project)
+	if err != nil {
+		return s3response.BucketQuota{}, fmt.Errorf("get project usage: %w", err)
+	}
+
+	return s3response.BucketQuota{
+		Size:        int64(size),
+		Objects:     int64(objects),
+		UsedSize:    int64(usedSize),
+		UsedObjects: int64(usedObjects),
+	}, nil
+}
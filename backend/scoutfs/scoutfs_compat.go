@@ -17,27 +17,20 @@
 package scoutfs
 
 import (
-	"errors"
 	"fmt"
-	"io/fs"
 	"os"
-	"path/filepath"
-	"strconv"
 	"syscall"
 
-	"golang.org/x/sys/unix"
-
 	"github.com/versity/scoutfs-go"
-	"github.com/versity/versitygw/auth"
-	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/backend/meta"
 	"github.com/versity/versitygw/backend/posix"
 )
 
-func New(rootdir string, opts ScoutfsOpts) (*ScoutFS, error) {
-	p, err := posix.New(rootdir, meta.XattrMeta{}, posix.PosixOpts{
-		ChownUID: opts.ChownUID,
-		ChownGID: opts.ChownGID,
+func New(rootdir string, meta meta.MetadataStorer, opts ScoutfsOpts) (*ScoutFS, error) {
+	p, err := posix.New(rootdir, meta, posix.PosixOpts{
+		ChownUID:  opts.ChownUID,
+		ChownGID:  opts.ChownGID,
+		PartMover: moveBlocks{},
 	})
 	if err != nil {
 		return nil, err
@@ -49,158 +42,182 @@ func New(rootdir string, opts ScoutfsOpts) (*ScoutFS, error) {
 	}
 
 	return &ScoutFS{
-		Posix:    p,
-		rootfd:   f,
-		rootdir:  rootdir,
-		chownuid: opts.ChownUID,
-		chowngid: opts.ChownGID,
+		Posix:        p,
+		meta:         meta,
+		rootfd:       f,
+		rootdir:      rootdir,
+		glaciermode:  opts.GlacierMode,
+		projectquota: opts.ProjectQuota,
 	}, nil
 }
 
-const procfddir = "/proc/self/fd"
-
-type tmpfile struct {
-	f          *os.File
-	bucket     string
-	objname    string
-	size       int64
-	needsChown bool
-	uid        int
-	gid        int
+// moveBlocks moves the completed upload parts with the scoutfs move
+// blocks ioctl, which moves the data extent references from the part to
+// the end of the object without reading and writing the data
+type moveBlocks struct{}
+
+// CanMove returns true when all parts but the last are multiples of 4k,
+// which is required for the moved extents
+func (moveBlocks) CanMove(partSizes []int64) bool {
+	for i, size := range partSizes {
+		if i < len(partSizes)-1 && size%4096 != 0 {
+			return false
+		}
+	}
+	return true
 }
 
-var (
-	// TODO: make this configurable
-	defaultFilePerm uint32 = 0644
-)
-
-func (s *ScoutFS) openTmpFile(dir, bucket, obj string, size int64, acct auth.Account) (*tmpfile, error) {
-	uid, gid, doChown := s.getChownIDs(acct)
+func (moveBlocks) MovePart(dst, part *os.File) error {
+	return scoutfs.MoveData(part, dst)
+}
 
-	// O_TMPFILE allows for a file handle to an unnamed file in the filesystem.
-	// This can help reduce contention within the namespace (parent directories),
-	// etc. And will auto cleanup the inode on close if we never link this
-	// file descriptor into the namespace.
-	fd, err := unix.Open(dir, unix.O_RDWR|unix.O_TMPFILE|unix.O_CLOEXEC, defaultFilePerm)
+func statMore(path string) (stat, error) {
+	st, err := scoutfs.StatMore(path)
 	if err != nil {
-		return nil, err
+		return stat{}, err
 	}
+	var s stat
 
-	// for O_TMPFILE, filename is /proc/self/fd/<fd> to be used
-	// later to link file into namespace
-	f := os.NewFile(uintptr(fd), filepath.Join(procfddir, strconv.Itoa(fd)))
-
-	tmp := &tmpfile{
-		f:          f,
-		bucket:     bucket,
-		objname:    obj,
-		size:       size,
-		needsChown: doChown,
-		uid:        uid,
-		gid:        gid,
-	}
+	s.Meta_seq = st.Meta_seq
+	s.Data_seq = st.Data_seq
+	s.Data_version = st.Data_version
+	s.Online_blocks = st.Online_blocks
+	s.Offline_blocks = st.Offline_blocks
+	s.Crtime_sec = st.Crtime_sec
+	s.Crtime_nsec = st.Crtime_nsec
 
-	// falloc is best effort, its fine if this fails
-	if size > 0 {
-		tmp.falloc()
-	}
+	return s, nil
+}
 
-	if doChown {
-		err := f.Chown(uid, gid)
-		if err != nil {
-			return nil, fmt.Errorf("set temp file ownership: %w", err)
-		}
+func getProjectID(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
 	}
+	defer f.Close()
 
-	return tmp, nil
+	return scoutfs.GetProjectID(f)
 }
 
-func (tmp *tmpfile) falloc() error {
-	err := syscall.Fallocate(int(tmp.f.Fd()), 0, 0, tmp.size)
+func setProjectID(path string, project uint64) error {
+	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("fallocate: %v", err)
+		return err
 	}
-	return nil
+	defer f.Close()
+
+	return scoutfs.SetProjectID(f, project)
 }
 
-func (tmp *tmpfile) link() error {
-	// We use Linkat/Rename as the atomic operation for object puts. The
-	// upload is written to a temp (or unnamed/O_TMPFILE) file to not conflict
-	// with any other simultaneous uploads. The final operation is to move the
-	// temp file into place for the object. This ensures the object semantics
-	// of last upload completed wins and is not some combination of writes
-	// from simultaneous uploads.
-	objPath := filepath.Join(tmp.bucket, tmp.objname)
-	err := os.Remove(objPath)
-	if err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return fmt.Errorf("remove stale path: %w", err)
+// setBucketProject assigns the bucket directory inode number as the
+// bucket project id, unless the bucket already has a project id
+func setBucketProject(bucket string) (uint64, error) {
+	f, err := os.Open(bucket)
+	if err != nil {
+		return 0, err
 	}
+	defer f.Close()
 
-	dir := filepath.Dir(objPath)
+	project, err := scoutfs.GetProjectID(f)
+	if err != nil || project != 0 {
+		return project, err
+	}
 
-	err = backend.MkdirAll(dir, tmp.uid, tmp.gid, tmp.needsChown)
+	fi, err := f.Stat()
 	if err != nil {
-		return fmt.Errorf("make parent dir: %w", err)
+		return 0, err
 	}
+	project = fi.Sys().(*syscall.Stat_t).Ino
+
+	return project, scoutfs.SetProjectID(f, project)
+}
 
-	procdir, err := os.Open(procfddir)
+// isProjectRule returns true for the bucket project quota rules
+func isProjectRule(r scoutfs.QuotaRule, project uint64) bool {
+	return r.QuotaValue == [3]uint64{quotaTotlID, 0, project} &&
+		scoutfs.QuotaType(r.QuotaSource[2]).String() == "project"
+}
+
+func getProjectRules(f *os.File, project uint64) ([]scoutfs.QuotaRule, error) {
+	q, err := scoutfs.GetQuotaRules(f, 256)
 	if err != nil {
-		return fmt.Errorf("open proc dir: %w", err)
+		return nil, err
 	}
-	defer procdir.Close()
 
-	dirf, err := os.Open(dir)
-	if err != nil {
-		return fmt.Errorf("open parent dir: %w", err)
+	var rules []scoutfs.QuotaRule
+	for {
+		batch, err := q.Next()
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			return rules, nil
+		}
+		for _, r := range batch {
+			if isProjectRule(r, project) {
+				rules = append(rules, r)
+			}
+		}
 	}
-	defer dirf.Close()
+}
 
-	err = unix.Linkat(int(procdir.Fd()), filepath.Base(tmp.f.Name()),
-		int(dirf.Fd()), filepath.Base(objPath), unix.AT_SYMLINK_FOLLOW)
+// getProjectQuota returns the size and object count limits of the bucket
+// project quota rules
+func getProjectQuota(f *os.File, project uint64) (uint64, uint64, error) {
+	rules, err := getProjectRules(f, project)
 	if err != nil {
-		return fmt.Errorf("link tmpfile: %w", err)
+		return 0, 0, err
 	}
 
-	err = tmp.f.Close()
-	if err != nil {
-		return fmt.Errorf("close tmpfile: %w", err)
+	var size, objects uint64
+	for _, r := range rules {
+		switch r.Op {
+		case scoutfs.QuotaData:
+			size = r.Limit
+		case scoutfs.QuotaInode:
+			objects = r.Limit
+		}
 	}
 
-	return nil
+	return size, objects, nil
 }
 
-func (tmp *tmpfile) Write(b []byte) (int, error) {
-	if int64(len(b)) > tmp.size {
-		return 0, fmt.Errorf("write exceeds content length %v", tmp.size)
+// setProjectQuota replaces the bucket project quota rules, zero limits
+// have no rule
+func setProjectQuota(f *os.File, project, size, objects uint64) error {
+	rules, err := getProjectRules(f, project)
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		err := scoutfs.QuotaDelete(f, r)
+		if err != nil {
+			return fmt.Errorf("delete rule %v: %w", r, err)
+		}
 	}
 
-	n, err := tmp.f.Write(b)
-	tmp.size -= int64(n)
-	return n, err
-}
-
-func (tmp *tmpfile) cleanup() {
-	tmp.f.Close()
-}
+	if size > 0 {
+		err := scoutfs.QuotaAddDataProject(f, quotaTotlID, 0, project, size, 0)
+		if err != nil {
+			return fmt.Errorf("add size rule: %w", err)
+		}
+	}
+	if objects > 0 {
+		err := scoutfs.QuotaAddInodeProject(f, quotaTotlID, 0, project, objects, 0)
+		if err != nil {
+			return fmt.Errorf("add object count rule: %w", err)
+		}
+	}
 
-func moveData(from *os.File, to *os.File) error {
-	return scoutfs.MoveData(from, to)
+	return nil
 }
 
-func statMore(path string) (stat, error) {
-	st, err := scoutfs.StatMore(path)
+// getProjectUsage returns the size and count of the bucket project
+// object totals
+func getProjectUsage(f *os.File, project uint64) (uint64, uint64, error) {
+	totl, err := scoutfs.ReadXattrTotals(f, quotaTotlID, 0, project)
 	if err != nil {
-		return stat{}, err
+		return 0, 0, err
 	}
-	var s stat
-
-	s.Meta_seq = st.Meta_seq
-	s.Data_seq = st.Data_seq
-	s.Data_version = st.Data_version
-	s.Online_blocks = st.Online_blocks
-	s.Offline_blocks = st.Offline_blocks
-	s.Crtime_sec = st.Crtime_sec
-	s.Crtime_nsec = st.Crtime_nsec
-
-	return s, nil
+	return totl.Total, totl.Count, nil
 }
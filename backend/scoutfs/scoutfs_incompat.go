@@ -19,47 +19,48 @@ package scoutfs
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 
-	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend/meta"
 )
 
-func New(rootdir string, opts ScoutfsOpts) (*ScoutFS, error) {
+func New(rootdir string, _ meta.MetadataStorer, opts ScoutfsOpts) (*ScoutFS, error) {
 	return nil, fmt.Errorf("scoutfs only available on linux")
 }
 
-type tmpfile struct {
-	f *os.File
-}
-
 var (
 	errNotSupported = errors.New("not supported")
 )
 
-func (s *ScoutFS) openTmpFile(_, _, _ string, _ int64, _ auth.Account) (*tmpfile, error) {
-	// make these look used for static check
-	_ = s.chownuid
-	_ = s.chowngid
-	_ = s.euid
-	_ = s.egid
-	return nil, errNotSupported
+func newListFS(root string, _ bool) fs.FS {
+	return os.DirFS(root)
+}
+
+func statMore(_ string) (stat, error) {
+	return stat{}, errNotSupported
 }
 
-func (tmp *tmpfile) link() error {
+func getProjectID(_ string) (uint64, error) {
+	return 0, errNotSupported
+}
+
+func setProjectID(_ string, _ uint64) error {
 	return errNotSupported
 }
 
-func (tmp *tmpfile) Write(b []byte) (int, error) {
+func setBucketProject(_ string) (uint64, error) {
 	return 0, errNotSupported
 }
 
-func (tmp *tmpfile) cleanup() {
+func getProjectQuota(_ *os.File, _ uint64) (uint64, uint64, error) {
+	return 0, 0, errNotSupported
 }
 
-func moveData(_, _ *os.File) error {
+func setProjectQuota(_ *os.File, _, _, _ uint64) error {
 	return errNotSupported
 }
 
-func statMore(_ string) (stat, error) {
-	return stat{}, errNotSupported
+func getProjectUsage(_ *os.File, _ uint64) (uint64, uint64, error) {
+	return 0, 0, errNotSupported
 }
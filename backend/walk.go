@@ -15,11 +15,12 @@
 package backend
 
 import (
+	"container/heap"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
-	"os"
-	"sort"
+	"path"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -32,189 +33,451 @@ type WalkResults struct {
 	NextMarker     string
 }
 
+// WalkEntry is a listing entry, either an object or a common prefix
+type WalkEntry struct {
+	Object       *types.Object
+	CommonPrefix *string
+}
+
+// WalkFunc is called with each listing entry in key order
+type WalkFunc func(WalkEntry) error
+
 type GetObjFunc func(path string, d fs.DirEntry) (types.Object, error)
 
 var ErrSkipObj = errors.New("skip this object")
 
+// errWalkDone stops the walk once the listing is complete
+var errWalkDone = errors.New("walk done")
+
 // Walk walks the supplied fs.FS and returns results compatible with list
-// objects responses
+// objects responses. The files and directories with names matching any of
+// the skipdirs glob patterns are left out of the results, see
+// IsExcluded.
 func Walk(fileSystem fs.FS, prefix, delimiter, marker string, max int32, getObj GetObjFunc, skipdirs []string) (WalkResults, error) {
-	cpmap := make(map[string]struct{})
-	var objects []types.Object
+	results := WalkResults{
+		CommonPrefixes: []types.CommonPrefix{},
+		Objects:        []types.Object{},
+	}
 
-	var pastMarker bool
-	if marker == "" {
-		pastMarker = true
+	truncated, next, err := WalkStream(fileSystem, prefix, delimiter, marker,
+		max, getObj, skipdirs, func(e WalkEntry) error {
+			if e.Object != nil {
+				results.Objects = append(results.Objects, *e.Object)
+				return nil
+			}
+			results.CommonPrefixes = append(results.CommonPrefixes,
+				types.CommonPrefix{Prefix: e.CommonPrefix})
+			return nil
+		})
+	if err != nil {
+		return WalkResults{}, err
 	}
 
-	pastMax := max == 0
-	var newMarker string
-	var truncated bool
+	results.Truncated = truncated
+	results.NextMarker = next
+	return results, nil
+}
 
-	err := fs.WalkDir(fileSystem, ".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		// Ignore the root directory
-		if path == "." {
-			return nil
-		}
-		if contains(d.Name(), skipdirs) {
-			return fs.SkipDir
-		}
+// WalkStream walks the supplied fs.FS as Walk, but calls fn with each
+// entry in key order as it is found instead of collecting the results.
+// The directories are read in pages of at most max entries past the
+// marker, so the memory used does not depend on the directory sizes.
+// The listing is truncated if there are entries left after the max
+// entries, nextMarker is then the last key or common prefix listed.
+func WalkStream(fileSystem fs.FS, prefix, delimiter, marker string, max int32, getObj GetObjFunc, skipdirs []string, fn WalkFunc) (truncated bool, nextMarker string, err error) {
+	if max <= 0 {
+		return false, "", nil
+	}
 
-		if pastMax {
-			if len(objects) != 0 {
-				newMarker = *objects[len(objects)-1].Key
-				truncated = true
-			}
-			return fs.SkipAll
-		}
+	w := &walker{
+		fsys:      fileSystem,
+		prefix:    prefix,
+		delimiter: delimiter,
+		marker:    marker,
+		max:       int(max),
+		getObj:    getObj,
+		skipdirs:  skipdirs,
+		fn:        fn,
+	}
 
-		if d.IsDir() {
-			// If prefix is defined and the directory does not match prefix,
-			// do not descend into the directory because nothing will
-			// match this prefix. Make sure to append the / at the end of
-			// directories since this is implied as a directory path name.
-			// If path is a prefix of prefix, then path could still be
-			// building to match. So only skip if path isn't a prefix of prefix
-			// and prefix isn't a prefix of path.
-			if prefix != "" &&
-				!strings.HasPrefix(path+string(os.PathSeparator), prefix) &&
-				!strings.HasPrefix(prefix, path+string(os.PathSeparator)) {
-				return fs.SkipDir
-			}
+	err = w.walkDir(".")
+	if err != nil && err != errWalkDone {
+		return false, "", err
+	}
+	if !w.truncated {
+		return false, "", nil
+	}
+	return true, w.last, nil
+}
 
-			// TODO: can we do better here rather than a second readdir
-			// per directory?
-			ents, err := fs.ReadDir(fileSystem, path)
-			if err != nil {
-				return fmt.Errorf("readdir %q: %w", path, err)
-			}
-			if len(ents) == 0 {
-				dirobj, err := getObj(path, d)
-				if err == ErrSkipObj {
-					return nil
-				}
-				if err != nil {
-					return fmt.Errorf("directory to object %q: %w", path, err)
-				}
-				objects = append(objects, dirobj)
-			}
+// walker lists a directory tree in key order, see WalkStream
+type walker struct {
+	fsys      fs.FS
+	prefix    string
+	delimiter string
+	marker    string
+	max       int
+	getObj    GetObjFunc
+	skipdirs  []string
+	fn        WalkFunc
 
-			return nil
+	// count is the number of entries listed, last the last key or
+	// common prefix listed, and lastCP the last common prefix
+	count     int
+	last      string
+	lastCP    string
+	truncated bool
+}
+
+// dirEntry is a directory entry with its key order within the directory,
+// which is the name followed by "/" for directories
+type dirEntry struct {
+	fs.DirEntry
+	sortKey string
+}
+
+func newDirEntry(d fs.DirEntry) dirEntry {
+	if d.IsDir() {
+		return dirEntry{DirEntry: d, sortKey: d.Name() + "/"}
+	}
+	return dirEntry{DirEntry: d, sortKey: d.Name()}
+}
+
+func joinPath(dir, name string) string {
+	if dir == "." {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// walkDir lists the entries of dir in key order. The directory is read
+// in pages of the entries following the last one listed, a page holds
+// one more entry than the listing max so that a single page is enough
+// unless some of the entries are not listed.
+func (w *walker) walkDir(dir string) error {
+	var after string
+	for {
+		ents, more, err := w.readDirPage(dir, after, w.max+1)
+		if err != nil {
+			return fmt.Errorf("readdir %q: %w", dir, err)
 		}
 
-		if !pastMarker {
-			if path == marker {
-				pastMarker = true
-				return nil
-			}
-			if path < marker {
-				return nil
+		for _, d := range ents {
+			err := w.visit(joinPath(dir, d.Name()), d)
+			if err != nil {
+				return err
 			}
+			after = d.sortKey
 		}
 
-		// If object doesn't have prefix, don't include in results.
-		if prefix != "" && !strings.HasPrefix(path, prefix) {
+		if !more {
 			return nil
 		}
+	}
+}
 
-		if delimiter == "" {
-			// If no delimiter specified, then all files with matching
-			// prefix are included in results
-			obj, err := getObj(path, d)
-			if err == ErrSkipObj {
-				return nil
-			}
-			if err != nil {
-				return fmt.Errorf("file to object %q: %w", path, err)
-			}
-			objects = append(objects, obj)
+// readDirPage returns the first size entries of dir in key order after
+// the entry with sort key after, more is set if there are entries left
+func (w *walker) readDirPage(dir, after string, size int) (page []dirEntry, more bool, err error) {
+	h := &entryHeap{}
+	add := func(d fs.DirEntry) {
+		e := newDirEntry(d)
+		if after != "" && e.sortKey <= after {
+			return
+		}
+		if w.skipEntry(joinPath(dir, d.Name()), d) {
+			return
+		}
+		if h.Len() < size {
+			heap.Push(h, e)
+			return
+		}
+		more = true
+		if e.sortKey < (*h)[0].sortKey {
+			(*h)[0] = e
+			heap.Fix(h, 0)
+		}
+	}
 
-			if max > 0 && (len(objects)+len(cpmap)) == int(max) {
-				pastMax = true
-			}
+	f, err := w.fsys.Open(dir)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
 
-			return nil
+	rdf, ok := f.(fs.ReadDirFile)
+	if !ok {
+		ents, err := fs.ReadDir(w.fsys, dir)
+		if err != nil {
+			return nil, false, err
 		}
-
-		// Since delimiter is specified, we only want results that
-		// do not contain the delimiter beyond the prefix.  If the
-		// delimiter exists past the prefix, then the substring
-		// between the prefix and delimiter is part of common prefixes.
-		//
-		// For example:
-		// prefix = A/
-		// delimiter = /
-		// and objects:
-		// A/file
-		// A/B/file
-		// B/C
-		// would return:
-		// objects: A/file
-		// common prefix: A/B/
-		//
-		// Note: No objects are included past the common prefix since
-		// these are all rolled up into the common prefix.
-		// Note: The delimiter can be anything, so we have to operate on
-		// the full path without any assumptions on posix directory hierarchy
-		// here.  Usually the delimiter will be "/", but thats not required.
-		suffix := strings.TrimPrefix(path, prefix)
-		before, _, found := strings.Cut(suffix, delimiter)
-		if !found {
-			obj, err := getObj(path, d)
-			if err == ErrSkipObj {
-				return nil
+		for _, d := range ents {
+			add(d)
+		}
+	} else {
+		for {
+			ents, err := rdf.ReadDir(readDirBatch)
+			for _, d := range ents {
+				add(d)
 			}
-			if err != nil {
-				return fmt.Errorf("file to object %q: %w", path, err)
+			if err == io.EOF {
+				break
 			}
-			objects = append(objects, obj)
-			if (len(objects) + len(cpmap)) == int(max) {
-				pastMax = true
+			if err != nil {
+				return nil, false, err
 			}
-			return nil
 		}
+	}
+
+	page = make([]dirEntry, h.Len())
+	for i := len(page) - 1; i >= 0; i-- {
+		page[i] = heap.Pop(h).(dirEntry)
+	}
+	return page, more, nil
+}
 
-		// Common prefixes are a set, so should not have duplicates.
-		// These are abstractly a "directory", so need to include the
-		// delimiter at the end.
-		cpmap[prefix+before+delimiter] = struct{}{}
-		if (len(objects) + len(cpmap)) == int(max) {
-			pastMax = true
+// readDirBatch is the number of entries read at a time from a directory
+const readDirBatch = 1024
+
+// skipEntry returns true for the directory entries that can not add
+// anything to the listing, without reading the directories
+func (w *walker) skipEntry(path string, d fs.DirEntry) bool {
+	if matchesName(d.Name(), d.IsDir(), w.skipdirs) {
+		return true
+	}
+
+	if !d.IsDir() {
+		return path <= w.marker ||
+			!strings.HasPrefix(path, w.prefix)
+	}
+
+	// If prefix is defined and the directory does not match prefix,
+	// do not descend into the directory because nothing will
+	// match this prefix. Make sure to append the / at the end of
+	// directories since this is implied as a directory path name.
+	// If path is a prefix of prefix, then path could still be
+	// building to match. So only skip if path isn't a prefix of prefix
+	// and prefix isn't a prefix of path.
+	dirpath := path + "/"
+	if w.prefix != "" &&
+		!strings.HasPrefix(dirpath, w.prefix) &&
+		!strings.HasPrefix(w.prefix, dirpath) {
+		return true
+	}
+
+	// all of the keys below the directory sort before the marker
+	return dirpath < w.marker && !strings.HasPrefix(w.marker, dirpath)
+}
+
+// visit lists the object or descends into the directory at path
+func (w *walker) visit(path string, d fs.DirEntry) error {
+	if !d.IsDir() {
+		return w.visitKey(path, d)
+	}
+
+	empty, err := isEmptyDir(w.fsys, path)
+	if err != nil {
+		return fmt.Errorf("readdir %q: %w", path, err)
+	}
+	if !empty {
+		if cp, ok := w.rollUp(path + "/"); ok {
+			return w.emitCommonPrefix(cp)
 		}
+		return w.walkDir(path)
+	}
 
+	// an empty directory may be a directory object
+	dirpath := path + "/"
+	if dirpath <= w.marker || !strings.HasPrefix(dirpath, w.prefix) {
 		return nil
-	})
+	}
+	obj, err := w.getObj(path, d)
+	if err == ErrSkipObj {
+		return nil
+	}
 	if err != nil {
-		return WalkResults{}, err
+		return fmt.Errorf("directory to object %q: %w", path, err)
 	}
 
-	var commonPrefixStrings []string
-	for k := range cpmap {
-		commonPrefixStrings = append(commonPrefixStrings, k)
+	// the directory object is listed as itself rather than as the
+	// common prefix of only itself
+	if cp, ok := w.commonPrefix(dirpath); ok && cp != dirpath {
+		return w.emitCommonPrefix(cp)
 	}
-	sort.Strings(commonPrefixStrings)
-	commonPrefixes := make([]types.CommonPrefix, 0, len(commonPrefixStrings))
-	for _, cp := range commonPrefixStrings {
-		pfx := cp
-		commonPrefixes = append(commonPrefixes, types.CommonPrefix{
-			Prefix: &pfx,
-		})
+	return w.emit(WalkEntry{Object: &obj}, dirpath)
+}
+
+// rollUp returns the common prefix that all of the keys below the
+// directory dirpath are rolled up into, if any, so the directory can be
+// listed without descending into it. The directories holding the marker
+// are walked to find the keys past the marker. A directory holding only
+// excluded files is then listed as a common prefix as well.
+func (w *walker) rollUp(dirpath string) (string, bool) {
+	// the first delimiter past the prefix within the directory path is
+	// the first one of every key below the directory
+	cp, ok := w.commonPrefix(dirpath)
+	if !ok {
+		return "", false
 	}
+	if cp != w.marker && strings.HasPrefix(w.marker, cp) {
+		return "", false
+	}
+	return cp, true
+}
 
-	return WalkResults{
-		CommonPrefixes: commonPrefixes,
-		Objects:        objects,
-		Truncated:      truncated,
-		NextMarker:     newMarker,
-	}, nil
+// commonPrefix returns the common prefix key is rolled up into when a
+// delimiter is specified.
+//
+// Since delimiter is specified, we only want results that
+// do not contain the delimiter beyond the prefix.  If the
+// delimiter exists past the prefix, then the substring
+// between the prefix and delimiter is part of common prefixes.
+//
+// For example:
+// prefix = A/
+// delimiter = /
+// and objects:
+// A/file
+// A/B/file
+// B/C
+// would return:
+// objects: A/file
+// common prefix: A/B/
+//
+// Note: No objects are included past the common prefix since
+// these are all rolled up into the common prefix.
+// Note: The delimiter can be anything, including multiple characters
+// such as "::", so we have to operate on the full path without any
+// assumptions on posix directory hierarchy here.  Usually the delimiter
+// will be "/", but thats not required.
+func (w *walker) commonPrefix(key string) (string, bool) {
+	if w.delimiter == "" || !strings.HasPrefix(key, w.prefix) {
+		return "", false
+	}
+
+	suffix := strings.TrimPrefix(key, w.prefix)
+	before, _, found := strings.Cut(suffix, w.delimiter)
+	if !found {
+		return "", false
+	}
+	return w.prefix + before + w.delimiter, true
 }
 
-func contains(a string, strs []string) bool {
-	for _, s := range strs {
-		if s == a {
+// visitKey lists the file at path as an object, or as the common prefix
+// it is rolled up into
+func (w *walker) visitKey(path string, d fs.DirEntry) error {
+	if cp, ok := w.commonPrefix(path); ok {
+		return w.emitCommonPrefix(cp)
+	}
+
+	obj, err := w.getObj(path, d)
+	if err == ErrSkipObj {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("file to object %q: %w", path, err)
+	}
+	return w.emit(WalkEntry{Object: &obj}, path)
+}
+
+// emitCommonPrefix lists the common prefix once. The keys are listed in
+// order, so the keys rolled up into the same common prefix are listed
+// one after the other.
+func (w *walker) emitCommonPrefix(cp string) error {
+	// a common prefix marker was listed by the previous page
+	if cp == w.lastCP || cp == w.marker {
+		return nil
+	}
+	err := w.emit(WalkEntry{CommonPrefix: &cp}, cp)
+	if err != nil {
+		return err
+	}
+	w.lastCP = cp
+	return nil
+}
+
+// emit lists the entry with the given key, or ends the walk as truncated
+// when the listing is already full
+func (w *walker) emit(e WalkEntry, key string) error {
+	if w.count == w.max {
+		w.truncated = true
+		return errWalkDone
+	}
+
+	err := w.fn(e)
+	if err != nil {
+		return err
+	}
+	w.count++
+	w.last = key
+	return nil
+}
+
+// entryHeap is a max heap of directory entries by key order
+type entryHeap []dirEntry
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return h[i].sortKey > h[j].sortKey }
+func (h entryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *entryHeap) Push(x any)        { *h = append(*h, x.(dirEntry)) }
+func (h *entryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	*h = old[:n-1]
+	return e
+}
+
+// isEmptyDir reads at most a single entry of the directory, so that
+// directories with many entries are not read in full a second time
+func isEmptyDir(fileSystem fs.FS, path string) (bool, error) {
+	f, err := fileSystem.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok {
+		ents, err := fs.ReadDir(fileSystem, path)
+		return len(ents) == 0, err
+	}
+
+	_, err = dir.ReadDir(1)
+	if err == io.EOF {
+		return true, nil
+	}
+	return false, err
+}
+
+// IsExcluded returns true when any element of the slash separated object
+// path matches one of the glob patterns, such as ".nfs*" or "lost+found".
+// A pattern ending in "/" only matches directories, which are all but the
+// last element of the path, or the last one too when isDir is set.
+func IsExcluded(object string, isDir bool, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+
+	elems := strings.Split(strings.Trim(object, "/"), "/")
+	for i, name := range elems {
+		if matchesName(name, isDir || i < len(elems)-1, patterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesName returns true when the file or directory name matches one
+// of the glob patterns
+func matchesName(name string, isDir bool, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern, dirOnly := strings.CutSuffix(pattern, "/")
+		if dirOnly && !isDir {
+			continue
+		}
+		ok, err := path.Match(pattern, name)
+		if err == nil && ok {
 			return true
 		}
 	}
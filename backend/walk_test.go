@@ -19,6 +19,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io/fs"
+	"strings"
 	"testing"
 	"testing/fstest"
 
@@ -105,6 +106,23 @@ func TestWalk(t *testing.T) {
 			},
 			getobj: getObj,
 		},
+		{
+			// test case empty dir object
+			fsys: fstest.MapFS{
+				"empty":         {Mode: fs.ModeDir},
+				"nonempty":      {Mode: fs.ModeDir},
+				"nonempty/file": {},
+			},
+			expected: backend.WalkResults{
+				CommonPrefixes: []types.CommonPrefix{{
+					Prefix: backend.GetStringPtr("nonempty/"),
+				}},
+				Objects: []types.Object{{
+					Key: backend.GetStringPtr("empty"),
+				}},
+			},
+			getobj: getObj,
+		},
 	}
 
 	for _, tt := range tests {
@@ -117,6 +135,179 @@ func TestWalk(t *testing.T) {
 	}
 }
 
+func TestWalkSkip(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/.nfs0001":          {},
+		"a/file":              {},
+		"a/file2":             {},
+		".snapshot/daily/obj": {},
+		"lost+found/x":        {},
+		"top":                 {},
+		"snapfile.snapshot":   {},
+	}
+
+	res, err := backend.Walk(fsys, "", "", "", 1000, getObj,
+		[]string{".nfs*", ".snapshot/", "lost+found"})
+	if err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+
+	var keys []string
+	for _, obj := range res.Objects {
+		keys = append(keys, *obj.Key)
+	}
+	want := []string{"a/file", "a/file2", "snapfile.snapshot", "top"}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected objects, got %v wanted %v", keys, want)
+	}
+}
+
+func TestWalkPages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a-b":      {},
+		"a/x":      {},
+		"a/y":      {},
+		"a0":       {},
+		"b/c/d":    {},
+		"b/c/e":    {},
+		"b/f":      {},
+		"c":        {},
+		"d/e/f/g":  {},
+		"d/e/f/g2": {},
+		"e::f::g":  {},
+		"e::h":     {},
+		"e::i/j":   {},
+		"e:k":      {},
+	}
+
+	tests := []struct {
+		prefix    string
+		delimiter string
+		want      []string
+	}{
+		{"", "", []string{"a-b", "a/x", "a/y", "a0", "b/c/d", "b/c/e",
+			"b/f", "c", "d/e/f/g", "d/e/f/g2", "e::f::g", "e::h", "e::i/j",
+			"e:k"}},
+		{"", "/", []string{"a-b", "a/", "a0", "b/", "c", "d/", "e::f::g",
+			"e::h", "e::i/", "e:k"}},
+		{"b/", "/", []string{"b/c/", "b/f"}},
+		{"d/e", "/", []string{"d/e/"}},
+		{"a", "", []string{"a-b", "a/x", "a/y", "a0"}},
+		{"", "::", []string{"a-b", "a/x", "a/y", "a0", "b/c/d", "b/c/e",
+			"b/f", "c", "d/e/f/g", "d/e/f/g2", "e::", "e:k"}},
+		{"e::", "::", []string{"e::f::", "e::h", "e::i/j"}},
+		{"", "/e/", []string{"a-b", "a/x", "a/y", "a0", "b/c/d", "b/c/e",
+			"b/f", "c", "d/e/", "e::f::g", "e::h", "e::i/j", "e:k"}},
+	}
+
+	for _, tt := range tests {
+		for _, max := range []int32{1, 2, 3, 1000} {
+			var keys []string
+			marker := ""
+			for pages := 0; ; pages++ {
+				if pages > len(fsys) {
+					t.Fatalf("prefix %q delimiter %q max %v: listing does not end",
+						tt.prefix, tt.delimiter, max)
+				}
+
+				var n int32
+				truncated, next, err := backend.WalkStream(fsys, tt.prefix,
+					tt.delimiter, marker, max, getObj, nil,
+					func(e backend.WalkEntry) error {
+						n++
+						if e.Object != nil {
+							keys = append(keys, *e.Object.Key)
+						} else {
+							keys = append(keys, *e.CommonPrefix)
+						}
+						return nil
+					})
+				if err != nil {
+					t.Fatalf("walk: %v", err)
+				}
+				if n > max {
+					t.Fatalf("listed %v entries, max %v", n, max)
+				}
+				if !truncated {
+					break
+				}
+				marker = next
+			}
+
+			if strings.Join(keys, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("prefix %q delimiter %q max %v: got %v wanted %v",
+					tt.prefix, tt.delimiter, max, keys, tt.want)
+			}
+		}
+	}
+}
+
+// openCounter counts the directories opened
+type openCounter struct {
+	fs.FS
+	opened map[string]int
+}
+
+func (o openCounter) Open(name string) (fs.File, error) {
+	o.opened[name]++
+	return o.FS.Open(name)
+}
+
+func TestWalkPrune(t *testing.T) {
+	fsys := openCounter{
+		FS: fstest.MapFS{
+			"a/b/c/d": {},
+			"a/b/e":   {},
+			"a/f":     {},
+			"g":       {},
+		},
+		opened: make(map[string]int),
+	}
+
+	res, err := backend.Walk(fsys, "a/", "/", "", 1000, getObj, nil)
+	if err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	compareResults(res, backend.WalkResults{
+		CommonPrefixes: []types.CommonPrefix{{
+			Prefix: backend.GetStringPtr("a/b/"),
+		}},
+		Objects: []types.Object{{
+			Key: backend.GetStringPtr("a/f"),
+		}},
+	}, t)
+
+	// the common prefix directory is only checked for entries
+	if fsys.opened["a/b"] != 1 || fsys.opened["a/b/c"] != 0 {
+		t.Errorf("walked into the common prefix, opened %v", fsys.opened)
+	}
+}
+
+func TestIsExcluded(t *testing.T) {
+	patterns := []string{".nfs*", ".snapshot/", "lost+found"}
+	tests := []struct {
+		object string
+		isDir  bool
+		want   bool
+	}{
+		{"dir/.nfs0001", false, true},
+		{".snapshot/daily/obj", false, true},
+		{"dir/.snapshot", false, false},
+		{"dir/.snapshot", true, true},
+		{"dir/.snapshot/", true, true},
+		{"lost+found", false, true},
+		{"found/file", false, false},
+	}
+
+	for _, tt := range tests {
+		got := backend.IsExcluded(tt.object, tt.isDir, patterns)
+		if got != tt.want {
+			t.Errorf("IsExcluded(%q, %v) = %v, want %v",
+				tt.object, tt.isDir, got, tt.want)
+		}
+	}
+}
+
 func compareResults(got, wanted backend.WalkResults, t *testing.T) {
 	if !compareCommonPrefix(got.CommonPrefixes, wanted.CommonPrefixes) {
 		t.Errorf("unexpected common prefix, got %v wanted %v",
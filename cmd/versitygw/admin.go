@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"text/tabwriter"
 	"time"
@@ -31,6 +32,7 @@ import (
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/urfave/cli/v2"
 	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3api/controllers"
 	"github.com/versity/versitygw/s3response"
 )
 
@@ -39,6 +41,11 @@ var (
 	adminSecret   string
 	adminEndpoint string
 	allowInsecure bool
+	// adminToken and the client certificate authenticate with an admin
+	// listener configured with a bearer token or mutual TLS
+	adminToken      string
+	adminClientCert string
+	adminClientKey  string
 )
 
 func adminCommand() *cli.Command {
@@ -106,8 +113,9 @@ func adminCommand() *cli.Command {
 				Action: listUsers,
 			},
 			{
-				Name:  "change-bucket-owner",
-				Usage: "Changes the bucket owner",
+				Name:    "change-bucket-owner",
+				Aliases: []string{"change-owner"},
+				Usage:   "Changes the bucket owner",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:     "bucket",
@@ -121,6 +129,10 @@ func adminCommand() *cli.Command {
 						Required: true,
 						Aliases:  []string{"o"},
 					},
+					&cli.BoolFlag{
+						Name:  "chown",
+						Usage: "chown the bucket files to the new owner uid/gid, if the gateway chowns files",
+					},
 				},
 				Action: changeBucketOwner,
 			},
@@ -129,6 +141,89 @@ func adminCommand() *cli.Command {
 				Usage:  "Lists all the gateway buckets and owners.",
 				Action: listBuckets,
 			},
+			{
+				Name:  "bucket-usage",
+				Usage: "Reports the storage usage of the gateway buckets",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "bucket",
+						Usage:   "report only the usage of this bucket",
+						Aliases: []string{"b"},
+					},
+				},
+				Action: bucketUsage,
+			},
+			{
+				Name:  "usage",
+				Usage: "Reports the storage quota and usage of an account",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "access",
+						Usage:    "the account access key id",
+						Required: true,
+						Aliases:  []string{"a"},
+					},
+				},
+				Action: accountUsage,
+			},
+			{
+				Name:   "ip-filter-stats",
+				Usage:  "Reports the number of requests rejected by the ip filter of each gateway listener",
+				Action: ipFilterStats,
+			},
+			{
+				Name:   "scrub-status",
+				Usage:  "Reports the progress and the detected mismatches of the object data scrubber",
+				Action: scrubStatus,
+			},
+			{
+				Name:  "list-trash",
+				Usage: "Lists the deleted objects in the bucket trash",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "bucket",
+						Usage:    "the bucket name",
+						Required: true,
+						Aliases:  []string{"b"},
+					},
+				},
+				Action: listTrash,
+			},
+			{
+				Name:  "restore-trash",
+				Usage: "Restores a deleted object from the bucket trash",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "bucket",
+						Usage:    "the bucket name",
+						Required: true,
+						Aliases:  []string{"b"},
+					},
+					&cli.StringFlag{
+						Name:     "id",
+						Usage:    "the trash entry id, as listed by list-trash",
+						Required: true,
+					},
+				},
+				Action: restoreTrash,
+			},
+			{
+				Name:  "purge-trash",
+				Usage: "Permanently removes deleted objects from the bucket trash",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "bucket",
+						Usage:    "the bucket name",
+						Required: true,
+						Aliases:  []string{"b"},
+					},
+					&cli.StringFlag{
+						Name:  "id",
+						Usage: "the trash entry id to remove, all the bucket trash entries when not set",
+					},
+				},
+				Action: purgeTrash,
+			},
 		},
 		Flags: []cli.Flag{
 			// TODO: create a configuration file for this
@@ -137,7 +232,6 @@ func adminCommand() *cli.Command {
 				Usage:       "admin access key id",
 				EnvVars:     []string{"ADMIN_ACCESS_KEY_ID", "ADMIN_ACCESS_KEY"},
 				Aliases:     []string{"a"},
-				Required:    true,
 				Destination: &adminAccess,
 			},
 			&cli.StringFlag{
@@ -145,9 +239,26 @@ func adminCommand() *cli.Command {
 				Usage:       "admin secret access key",
 				EnvVars:     []string{"ADMIN_SECRET_ACCESS_KEY", "ADMIN_SECRET_KEY"},
 				Aliases:     []string{"s"},
-				Required:    true,
 				Destination: &adminSecret,
 			},
+			&cli.StringFlag{
+				Name:        "token",
+				Usage:       "admin bearer token, used instead of the access and secret keys",
+				EnvVars:     []string{"ADMIN_TOKEN"},
+				Destination: &adminToken,
+			},
+			&cli.StringFlag{
+				Name:        "client-cert",
+				Usage:       "TLS client cert file for an admin endpoint requiring mutual TLS",
+				EnvVars:     []string{"ADMIN_CLIENT_CERT"},
+				Destination: &adminClientCert,
+			},
+			&cli.StringFlag{
+				Name:        "client-key",
+				Usage:       "TLS client key file for an admin endpoint requiring mutual TLS",
+				EnvVars:     []string{"ADMIN_CLIENT_KEY"},
+				Destination: &adminClientKey,
+			},
 			&cli.StringFlag{
 				Name:        "endpoint-url",
 				Usage:       "admin apis endpoint url",
@@ -167,16 +278,72 @@ func adminCommand() *cli.Command {
 	}
 }
 
-func initHTTPClient() *http.Client {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: allowInsecure},
+func initHTTPClient() (*http.Client, error) {
+	cfg := &tls.Config{InsecureSkipVerify: allowInsecure}
+	if adminClientCert != "" || adminClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(adminClientCert, adminClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
 	}
-	return &http.Client{Transport: tr}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}, nil
+}
+
+// adminRequest sends the admin api request, authenticated with the
+// bearer token if set or signed with the admin credentials otherwise,
+// and returns the response body
+func adminRequest(path string, query url.Values, payload []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/%v", adminEndpoint, path), bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send the request: %w", err)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	switch {
+	case adminToken != "":
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	case adminAccess != "" && adminSecret != "":
+		hashedPayload := sha256.Sum256(payload)
+		hexPayload := hex.EncodeToString(hashedPayload[:])
+
+		req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+
+		signer := v4.NewSigner()
+		err := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign the request: %w", err)
+		}
+	case adminClientCert == "":
+		return nil, fmt.Errorf("either the admin access and secret keys, a token or a client certificate are required")
+	}
+
+	client, err := initHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send the request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, parseAdminError(body)
+	}
+
+	return body, nil
 }
 
 func createUser(ctx *cli.Context) error {
 	access, secret, role := ctx.String("access"), ctx.String("secret"), ctx.String("role")
-	userID, groupID, projectID := ctx.Int("user-id"), ctx.Int("group-id"), ctx.Int("projectID")
+	userID, groupID, projectID := ctx.Int("user-id"), ctx.Int("group-id"), ctx.Int("project-id")
 	if access == "" || secret == "" {
 		return fmt.Errorf("invalid input parameters for the new user")
 	}
@@ -198,40 +365,11 @@ func createUser(ctx *cli.Context) error {
 		return fmt.Errorf("failed to parse user data: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/create-user", adminEndpoint), bytes.NewBuffer(accJson))
-	if err != nil {
-		return fmt.Errorf("failed to send the request: %w", err)
-	}
-
-	signer := v4.NewSigner()
-
-	hashedPayload := sha256.Sum256(accJson)
-	hexPayload := hex.EncodeToString(hashedPayload[:])
-
-	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
-
-	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
-	if signErr != nil {
-		return fmt.Errorf("failed to sign the request: %w", err)
-	}
-
-	client := initHTTPClient()
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send the request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := adminRequest("create-user", nil, accJson)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("%s", body)
-	}
-
 	fmt.Printf("%s\n", body)
 
 	return nil
@@ -243,80 +381,22 @@ func deleteUser(ctx *cli.Context) error {
 		return fmt.Errorf("invalid input parameter for the new user")
 	}
 
-	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/delete-user?access=%v", adminEndpoint, access), nil)
-	if err != nil {
-		return fmt.Errorf("failed to send the request: %w", err)
-	}
-
-	signer := v4.NewSigner()
-
-	hashedPayload := sha256.Sum256([]byte{})
-	hexPayload := hex.EncodeToString(hashedPayload[:])
-
-	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
-
-	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
-	if signErr != nil {
-		return fmt.Errorf("failed to sign the request: %w", err)
-	}
-
-	client := initHTTPClient()
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send the request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := adminRequest("delete-user", url.Values{"access": {access}}, nil)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("%s", body)
-	}
-
 	fmt.Printf("%s\n", body)
 
 	return nil
 }
 
 func listUsers(ctx *cli.Context) error {
-	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/list-users", adminEndpoint), nil)
-	if err != nil {
-		return fmt.Errorf("failed to send the request: %w", err)
-	}
-
-	signer := v4.NewSigner()
-
-	hashedPayload := sha256.Sum256([]byte{})
-	hexPayload := hex.EncodeToString(hashedPayload[:])
-
-	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
-
-	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
-	if signErr != nil {
-		return fmt.Errorf("failed to sign the request: %w", err)
-	}
-
-	client := initHTTPClient()
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send the request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := adminRequest("list-users", nil, nil)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("%s", body)
-	}
-
 	var accs []auth.Account
 	if err := json.Unmarshal(body, &accs); err != nil {
 		return err
@@ -336,6 +416,18 @@ const (
 	flags    uint = 0   // formatting control flags
 )
 
+// parseAdminError returns the error described by the admin api error
+// response body
+func parseAdminError(body []byte) error {
+	var resp controllers.AdminErrorResponse
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error.Code == "" {
+		return fmt.Errorf("%s", body)
+	}
+
+	return fmt.Errorf("%v: %v (request id: %v)",
+		resp.Error.Code, resp.Error.Message, resp.Error.RequestID)
+}
+
 func printAcctTable(accs []auth.Account) {
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
@@ -350,98 +442,237 @@ func printAcctTable(accs []auth.Account) {
 
 func changeBucketOwner(ctx *cli.Context) error {
 	bucket, owner := ctx.String("bucket"), ctx.String("owner")
-	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/change-bucket-owner/?bucket=%v&owner=%v", adminEndpoint, bucket, owner), nil)
+	query := url.Values{"bucket": {bucket}, "owner": {owner}}
+	if ctx.Bool("chown") {
+		query.Set("chown", "true")
+	}
+
+	body, err := adminRequest("change-bucket-owner", query, nil)
 	if err != nil {
-		return fmt.Errorf("failed to send the request: %w", err)
+		return err
 	}
 
-	signer := v4.NewSigner()
+	fmt.Println(string(body))
 
-	hashedPayload := sha256.Sum256([]byte{})
-	hexPayload := hex.EncodeToString(hashedPayload[:])
+	return nil
+}
 
-	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+func printBuckets(buckets []s3response.Bucket) {
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+	fmt.Fprintln(w, "Bucket\tOwner")
+	fmt.Fprintln(w, "-------\t----")
+	for _, acc := range buckets {
+		fmt.Fprintf(w, "%v\t%v\n", acc.Name, acc.Owner)
+	}
+	fmt.Fprintln(w)
+	w.Flush()
+}
 
-	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
-	if signErr != nil {
-		return fmt.Errorf("failed to sign the request: %w", err)
+func listBuckets(ctx *cli.Context) error {
+	body, err := adminRequest("list-buckets", nil, nil)
+	if err != nil {
+		return err
 	}
 
-	client := initHTTPClient()
+	var buckets []s3response.Bucket
+	if err := json.Unmarshal(body, &buckets); err != nil {
+		return err
+	}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send the request: %w", err)
+	printBuckets(buckets)
+
+	return nil
+}
+
+func bucketUsage(ctx *cli.Context) error {
+	query := url.Values{}
+	if bucket := ctx.String("bucket"); bucket != "" {
+		query.Set("bucket", bucket)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := adminRequest("bucket-usage", query, nil)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("%s", body)
+	var usage []s3response.BucketUsage
+	if err := json.Unmarshal(body, &usage); err != nil {
+		return err
 	}
 
-	fmt.Println(string(body))
+	printBucketUsage(usage)
 
 	return nil
 }
 
-func printBuckets(buckets []s3response.Bucket) {
+func printBucketUsage(usage []s3response.BucketUsage) {
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
-	fmt.Fprintln(w, "Bucket\tOwner")
-	fmt.Fprintln(w, "-------\t----")
-	for _, acc := range buckets {
-		fmt.Fprintf(w, "%v\t%v\n", acc.Name, acc.Owner)
+	fmt.Fprintln(w, "Bucket\tObjects\tSize\tMultipartSize\tLastModified")
+	fmt.Fprintln(w, "------\t-------\t----\t-------------\t------------")
+	for _, u := range usage {
+		lastModified := "-"
+		if !u.LastModified.IsZero() {
+			lastModified = u.LastModified.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", u.Bucket, u.Objects, u.Size, u.MultipartSize, lastModified)
 	}
 	fmt.Fprintln(w)
 	w.Flush()
 }
 
-func listBuckets(ctx *cli.Context) error {
-	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/list-buckets", adminEndpoint), nil)
+func ipFilterStats(ctx *cli.Context) error {
+	body, err := adminRequest("ip-filter-stats", url.Values{}, nil)
 	if err != nil {
-		return fmt.Errorf("failed to send the request: %w", err)
+		return err
 	}
 
-	signer := v4.NewSigner()
+	var stats []controllers.IPFilterListenerStats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return err
+	}
 
-	hashedPayload := sha256.Sum256([]byte{})
-	hexPayload := hex.EncodeToString(hashedPayload[:])
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+	fmt.Fprintln(w, "Listener\tDenied")
+	fmt.Fprintln(w, "--------\t------")
+	for _, l := range stats {
+		fmt.Fprintf(w, "%v\t%v\n", l.Listener, l.Denied)
+	}
+	fmt.Fprintln(w)
+	w.Flush()
 
-	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+	return nil
+}
 
-	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
-	if signErr != nil {
-		return fmt.Errorf("failed to sign the request: %w", err)
+func scrubStatus(ctx *cli.Context) error {
+	body, err := adminRequest("scrub-status", url.Values{}, nil)
+	if err != nil {
+		return err
 	}
 
-	client := initHTTPClient()
+	var status s3response.ScrubStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return err
+	}
 
-	resp, err := client.Do(req)
+	printScrubStatus(status)
+
+	return nil
+}
+
+func printScrubStatus(status s3response.ScrubStatus) {
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+	fmt.Fprintf(w, "Running:\t%v\n", status.Running)
+	fmt.Fprintf(w, "Passes:\t%v\n", status.Passes)
+	fmt.Fprintf(w, "LastStart:\t%v\n", formatTime(status.LastStart))
+	fmt.Fprintf(w, "LastFinish:\t%v\n", formatTime(status.LastFinish))
+	fmt.Fprintf(w, "Objects:\t%v\n", status.Objects)
+	fmt.Fprintf(w, "Bytes:\t%v\n", status.Bytes)
+	fmt.Fprintf(w, "Skipped:\t%v\n", status.Skipped)
+	fmt.Fprintf(w, "Mismatched:\t%v\n", status.Mismatched)
+	fmt.Fprintf(w, "Quarantined:\t%v\n", status.Quarantined)
+	fmt.Fprintln(w)
+	w.Flush()
+
+	if len(status.Mismatches) == 0 {
+		return
+	}
+
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+	fmt.Fprintln(w, "Bucket\tKey\tETag\tComputed\tDetected\tQuarantined")
+	fmt.Fprintln(w, "------\t---\t----\t--------\t--------\t-----------")
+	for _, m := range status.Mismatches {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", m.Bucket, m.Key, m.ETag, m.Computed, formatTime(m.Detected), m.Quarantined)
+	}
+	fmt.Fprintln(w)
+	w.Flush()
+}
+
+func listTrash(ctx *cli.Context) error {
+	body, err := adminRequest("list-trash", url.Values{"bucket": {ctx.String("bucket")}}, nil)
 	if err != nil {
-		return fmt.Errorf("failed to send the request: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	var entries []s3response.TrashEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return err
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+	fmt.Fprintln(w, "ID\tKey\tSize\tDeleted\tExpires")
+	fmt.Fprintln(w, "--\t---\t----\t-------\t-------")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", e.ID, e.Key, e.Size,
+			e.Deleted.Format(time.RFC3339), e.Expires.Format(time.RFC3339))
+	}
+	fmt.Fprintln(w)
+	w.Flush()
+
+	return nil
+}
+
+func restoreTrash(ctx *cli.Context) error {
+	query := url.Values{"bucket": {ctx.String("bucket")}, "id": {ctx.String("id")}}
+	body, err := adminRequest("restore-trash", query, nil)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("%s", body)
+	fmt.Println(string(body))
+
+	return nil
+}
+
+func purgeTrash(ctx *cli.Context) error {
+	query := url.Values{"bucket": {ctx.String("bucket")}}
+	if id := ctx.String("id"); id != "" {
+		query.Set("id", id)
 	}
 
-	var buckets []s3response.Bucket
-	if err := json.Unmarshal(body, &buckets); err != nil {
+	body, err := adminRequest("purge-trash", query, nil)
+	if err != nil {
 		return err
 	}
 
-	printBuckets(buckets)
+	fmt.Println(string(body))
+
+	return nil
+}
+
+func accountUsage(ctx *cli.Context) error {
+	body, err := adminRequest("get-account-usage", url.Values{"access": {ctx.String("access")}}, nil)
+	if err != nil {
+		return err
+	}
+
+	var usage auth.AccountUsage
+	if err := json.Unmarshal(body, &usage); err != nil {
+		return err
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+	fmt.Fprintln(w, "Account\tQuota\tUsed")
+	fmt.Fprintln(w, "-------\t-----\t----")
+	quota := "-"
+	if usage.Quota > 0 {
+		quota = fmt.Sprint(usage.Quota)
+	}
+	fmt.Fprintf(w, "%v\t%v\t%v\n", usage.Access, quota, usage.Used)
+	fmt.Fprintln(w)
+	w.Flush()
 
 	return nil
 }
@@ -0,0 +1,66 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"github.com/versity/versitygw/backend/archive"
+)
+
+var (
+	archivePublicRead bool
+)
+
+func archiveCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "archive",
+		Usage: "read-only tar and zip archive backend",
+		Description: `Serves the members of one or more tar or zip archive files as objects
+in read-only buckets, without unpacking the archives. Each archive is
+exposed as a bucket named after the file without its extension, or
+the bucket name can be given explicitly as bucket=path, for example:
+versitygw archive datasets=/data/published-2024.tar /data/images.zip
+Compressed tar files are not supported since they can not be read at
+random offsets, zip members may be stored or deflated. All requests
+that would modify a bucket or object return NotImplemented.`,
+		Action: runArchive,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:        "public-read",
+				Usage:       "allow anonymous read access to the archive buckets",
+				EnvVars:     []string{"VGW_ARCHIVE_PUBLIC_READ"},
+				Destination: &archivePublicRead,
+			},
+		},
+	}
+}
+
+func runArchive(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("no archive files provided for operation")
+	}
+
+	be, err := archive.New(ctx.Args().Slice(), archive.ArchiveOpts{
+		Owner:      rootUserAccess,
+		PublicRead: archivePublicRead,
+	})
+	if err != nil {
+		return fmt.Errorf("init archive: %v", err)
+	}
+
+	return runGateway(ctx.Context, be)
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/archive"
+	"github.com/versity/versitygw/backend/cephfs"
+	"github.com/versity/versitygw/backend/lustre"
+	"github.com/versity/versitygw/backend/memstore"
+	"github.com/versity/versitygw/backend/meta"
+	"github.com/versity/versitygw/backend/posix"
+	"github.com/versity/versitygw/backend/s3proxy"
+	"github.com/versity/versitygw/backend/scoutfs"
+)
+
+// backendConfig configures a backend within the mux and mirror config
+// files
+type backendConfig struct {
+	// Type is one of posix, scoutfs, lustre, cephfs, s3, mem or archive
+	Type string `json:"type"`
+
+	// posix, scoutfs, lustre and cephfs settings, the metadata
+	// settings select the object metadata store as for the posix
+	// backend command
+	Path         string `json:"path"`
+	ChownUID     bool   `json:"chuid"`
+	ChownGID     bool   `json:"chgid"`
+	Glacier      bool   `json:"glacier"`
+	ProjectQuota bool   `json:"projectQuota"`
+	HSM          bool   `json:"hsm"`
+	Metadata     string `json:"metadata"`
+	MetadataPath string `json:"metadataPath"`
+	XattrPrefix  string `json:"xattrPrefix"`
+
+	// s3 settings
+	Access          string `json:"access"`
+	Secret          string `json:"secret"`
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	DisableChecksum bool   `json:"disableChecksum"`
+	SslSkipVerify   bool   `json:"sslSkipVerify"`
+
+	// archive settings
+	Archives   []string `json:"archives"`
+	PublicRead bool     `json:"publicRead"`
+}
+
+// isFsBackend returns true for the backends operating within the gateway
+// working directory, only one of which can run in a gateway
+func isFsBackend(cfg backendConfig) bool {
+	return cfg.Type == "posix" || cfg.Type == "scoutfs" ||
+		cfg.Type == "lustre" || cfg.Type == "cephfs"
+}
+
+func newConfigBackend(cfg backendConfig) (backend.Backend, error) {
+	var ms meta.MetadataStorer
+	if isFsBackend(cfg) {
+		var err error
+		ms, err = openMetaStore(cfg.Metadata, cfg.Path, cfg.MetadataPath, cfg.XattrPrefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch cfg.Type {
+	case "posix":
+		return posix.New(cfg.Path, ms, posix.PosixOpts{
+			ChownUID: cfg.ChownUID,
+			ChownGID: cfg.ChownGID,
+		})
+	case "scoutfs":
+		return scoutfs.New(cfg.Path, ms, scoutfs.ScoutfsOpts{
+			GlacierMode:  cfg.Glacier,
+			ProjectQuota: cfg.ProjectQuota,
+			ChownUID:     cfg.ChownUID,
+			ChownGID:     cfg.ChownGID,
+		})
+	case "lustre":
+		return lustre.New(cfg.Path, ms, lustre.LustreOpts{
+			ChownUID: cfg.ChownUID,
+			ChownGID: cfg.ChownGID,
+			HSMMode:  cfg.HSM,
+		})
+	case "cephfs":
+		return cephfs.New(cfg.Path, ms, cephfs.CephFSOpts{
+			ChownUID: cfg.ChownUID,
+			ChownGID: cfg.ChownGID,
+		})
+	case "s3":
+		return s3proxy.New(cfg.Access, cfg.Secret, cfg.Endpoint, cfg.Region,
+			cfg.DisableChecksum, cfg.SslSkipVerify, false)
+	case "mem":
+		return memstore.New(), nil
+	case "archive":
+		return archive.New(cfg.Archives, archive.ArchiveOpts{
+			Owner:      rootUserAccess,
+			PublicRead: cfg.PublicRead,
+		})
+	default:
+		return nil, fmt.Errorf("unknown backend type %q", cfg.Type)
+	}
+}
+
+// openMetaStore opens the posix metadata store, using the xattr prefix
+// for the xattr store when set
+func openMetaStore(kind, gwroot, path, xattrPrefix string) (meta.MetadataStorer, error) {
+	if xattrPrefix == "" || (kind != "" && kind != "xattr") {
+		return meta.Open(kind, gwroot, path)
+	}
+
+	if !strings.HasSuffix(xattrPrefix, ".") ||
+		(!strings.HasPrefix(xattrPrefix, "user.") && !strings.HasPrefix(xattrPrefix, "trusted.")) {
+		return nil, fmt.Errorf("xattr prefix %q must be within the user or trusted namespace and end with \".\"",
+			xattrPrefix)
+	}
+
+	x := meta.XattrMeta{Prefix: xattrPrefix}
+	err := x.Test(gwroot)
+	if err != nil {
+		return nil, fmt.Errorf("posix xattr check: %v", err)
+	}
+	return x, nil
+}
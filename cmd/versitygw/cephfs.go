@@ -0,0 +1,77 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"github.com/versity/versitygw/backend/cephfs"
+)
+
+func cephfsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "cephfs",
+		Usage: "cephfs filesystem storage backend",
+		Description: `Support for CephFS.
+The top level directory for the gateway must be provided. All sub directories
+of the top level directory are treated as buckets, and all files/directories
+below the "bucket directory" are treated as the objects, the same as for the
+posix backend.
+
+CopyObject copies the object data with copy_file_range, which the cephfs
+kernel client offloads to the OSDs when mounted with the "copyfrom" option.
+The data pool of a new object can be requested with the
+x-amz-meta-ceph-pool header, the pool must be a data pool of the
+filesystem.`,
+		Action: runCephfs,
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:        "chuid",
+				Usage:       "chown newly created files and directories to client account UID",
+				EnvVars:     []string{"VGW_CHOWN_UID"},
+				Destination: &chownuid,
+			},
+			&cli.BoolFlag{
+				Name:        "chgid",
+				Usage:       "chown newly created files and directories to client account GID",
+				EnvVars:     []string{"VGW_CHOWN_GID"},
+				Destination: &chowngid,
+			},
+		}, metadataFlags()...),
+	}
+}
+
+func runCephfs(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("no directory provided for operation")
+	}
+
+	gwroot := ctx.Args().Get(0)
+	ms, err := openMetaStore(metaStore, gwroot, metaPath, xattrPrefix)
+	if err != nil {
+		return err
+	}
+
+	be, err := cephfs.New(gwroot, ms, cephfs.CephFSOpts{
+		ChownUID: chownuid,
+		ChownGID: chowngid,
+	})
+	if err != nil {
+		return fmt.Errorf("init cephfs: %v", err)
+	}
+
+	return runGateway(ctx.Context, be)
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"github.com/versity/versitygw/backend/lustre"
+)
+
+var (
+	hsm bool
+)
+
+func lustreCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "lustre",
+		Usage: "lustre filesystem storage backend",
+		Description: `Support for Lustre.
+The top level directory for the gateway must be provided. All sub directories
+of the top level directory are treated as buckets, and all files/directories
+below the "bucket directory" are treated as the objects, the same as for the
+posix backend.
+
+With --hsm, files released by Lustre HSM are reported as GLACIER storage
+class objects that must be restored with RestoreObject before they can be
+read. The stripe count of a new object can be requested with the
+x-amz-meta-lustre-stripe header, -1 stripes over all OSTs.`,
+		Action: runLustre,
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:        "hsm",
+				Usage:       "enable hsm released file handling",
+				EnvVars:     []string{"VGW_LUSTRE_HSM"},
+				Destination: &hsm,
+			},
+			&cli.BoolFlag{
+				Name:        "chuid",
+				Usage:       "chown newly created files and directories to client account UID",
+				EnvVars:     []string{"VGW_CHOWN_UID"},
+				Destination: &chownuid,
+			},
+			&cli.BoolFlag{
+				Name:        "chgid",
+				Usage:       "chown newly created files and directories to client account GID",
+				EnvVars:     []string{"VGW_CHOWN_GID"},
+				Destination: &chowngid,
+			},
+		}, metadataFlags()...),
+	}
+}
+
+func runLustre(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("no directory provided for operation")
+	}
+
+	gwroot := ctx.Args().Get(0)
+	ms, err := openMetaStore(metaStore, gwroot, metaPath, xattrPrefix)
+	if err != nil {
+		return err
+	}
+
+	be, err := lustre.New(gwroot, ms, lustre.LustreOpts{
+		ChownUID: chownuid,
+		ChownGID: chowngid,
+		HSMMode:  hsm,
+	})
+	if err != nil {
+		return fmt.Errorf("init lustre: %v", err)
+	}
+
+	return runGateway(ctx.Context, be)
+}
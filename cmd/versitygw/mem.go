@@ -0,0 +1,36 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+	"github.com/versity/versitygw/backend/memstore"
+)
+
+func memCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "mem",
+		Usage: "in-memory storage backend",
+		Description: `Buckets, objects and multipart uploads are kept in memory and are
+lost when the gateway exits. Intended for testing and ephemeral
+demo environments, the total object data is limited by the
+available memory.`,
+		Action: runMem,
+	}
+}
+
+func runMem(ctx *cli.Context) error {
+	return runGateway(ctx.Context, memstore.New())
+}
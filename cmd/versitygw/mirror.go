@@ -0,0 +1,123 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"github.com/versity/versitygw/backend/mirror"
+)
+
+// mirrorConfig is the mirroring backend config file
+type mirrorConfig struct {
+	Primary   backendConfig `json:"primary"`
+	Secondary backendConfig `json:"secondary"`
+}
+
+func mirrorCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "mirror",
+		Usage: "mirror all writes to a primary and a secondary backend",
+		Description: `Writes every modification to both the primary and secondary backend
+of a JSON config file. Requests are answered by the primary, and a
+failed secondary write is logged but does not fail the request. Reads
+fail over to the secondary when the primary fails with an error other
+than an S3 error response. For example:
+{
+  "primary": {"type": "posix", "path": "/mnt/fs1/gw"},
+  "secondary": {"type": "s3", "endpoint": "http://fs2-gateway:7070", "access": "key", "secret": "secret", "region": "us-east-1"}
+}
+The backend types are the same as for the mux command. At most one
+filesystem backend can be configured, so mirroring across two
+filesystems uses a second gateway serving the secondary filesystem.
+Use "versitygw utils mirror-repair" to resync the secondary after
+failed secondary writes.`,
+		Action: runMirror,
+	}
+}
+
+func runMirror(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("no config file provided for operation")
+	}
+
+	be, err := newMirror(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	return runGateway(ctx.Context, be)
+}
+
+func newMirror(path string) (*mirror.Mirror, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read mirror config: %w", err)
+	}
+
+	var cfg mirrorConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("parse mirror config: %w", err)
+	}
+
+	if isFsBackend(cfg.Primary) && isFsBackend(cfg.Secondary) {
+		return nil, fmt.Errorf("mirror: only one filesystem backend is supported")
+	}
+
+	primary, err := newConfigBackend(cfg.Primary)
+	if err != nil {
+		return nil, fmt.Errorf("init mirror primary: %w", err)
+	}
+	secondary, err := newConfigBackend(cfg.Secondary)
+	if err != nil {
+		primary.Shutdown()
+		return nil, fmt.Errorf("init mirror secondary: %w", err)
+	}
+
+	return mirror.New(primary, secondary), nil
+}
+
+func mirrorRepair(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("no config file provided for operation")
+	}
+
+	m, err := newMirror(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	defer m.Shutdown()
+
+	stats, err := m.Repair(ctx.Context, mirror.RepairOpts{
+		Bucket: ctx.String("bucket"),
+		Delete: ctx.Bool("delete"),
+		DryRun: ctx.Bool("dry-run"),
+		Report: func(action, bucket, object string) {
+			fmt.Printf("%v %v/%v\n", action, bucket, object)
+		},
+	})
+	fmt.Printf("buckets: %v, objects: %v, copied: %v, deleted: %v, failed: %v\n",
+		stats.Buckets, stats.Objects, stats.Copied, stats.Deleted, stats.Failed)
+	if err != nil {
+		return err
+	}
+	if stats.Failed > 0 {
+		return fmt.Errorf("%v objects failed to repair", stats.Failed)
+	}
+	return nil
+}
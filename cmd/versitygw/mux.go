@@ -0,0 +1,125 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/mux"
+)
+
+// muxConfig is the routing backend config file
+type muxConfig struct {
+	Backends map[string]backendConfig `json:"backends"`
+	Routes   []muxRouteConfig         `json:"routes"`
+}
+
+type muxRouteConfig struct {
+	Bucket  string `json:"bucket"`
+	Backend string `json:"backend"`
+}
+
+func muxCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "mux",
+		Usage: "route buckets to multiple storage backends",
+		Description: `Routes each bucket to one of several backends by matching the bucket
+name against the routes of a JSON config file, in order. Buckets
+matching no route do not exist, and listing the buckets merges the
+buckets of all the backends. For example:
+{
+  "backends": {
+    "archive": {"type": "scoutfs", "path": "/mnt/scoutfs/gw", "glacier": true},
+    "cloud": {"type": "s3", "endpoint": "https://s3.example.com", "access": "key", "secret": "secret", "region": "us-east-1"}
+  },
+  "routes": [
+    {"bucket": "archive-*", "backend": "archive"},
+    {"bucket": "*", "backend": "cloud"}
+  ]
+}
+The backend types are posix, scoutfs, lustre, cephfs, s3, mem and
+archive. At most one filesystem backend can be configured, since these
+operate within the gateway working directory. Objects copied between
+buckets of different backends are streamed through the gateway.`,
+		Action: runMux,
+	}
+}
+
+func runMux(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("no config file provided for operation")
+	}
+
+	b, err := os.ReadFile(ctx.Args().Get(0))
+	if err != nil {
+		return fmt.Errorf("read mux config: %w", err)
+	}
+
+	var cfg muxConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("parse mux config: %w", err)
+	}
+
+	backends := make(map[string]backend.Backend)
+	shutdown := func() {
+		for _, be := range backends {
+			be.Shutdown()
+		}
+	}
+
+	var fsBackend string
+	for name, bcfg := range cfg.Backends {
+		if isFsBackend(bcfg) {
+			if fsBackend != "" {
+				shutdown()
+				return fmt.Errorf("mux backends %q and %q: only one filesystem backend is supported",
+					fsBackend, name)
+			}
+			fsBackend = name
+		}
+
+		be, err := newConfigBackend(bcfg)
+		if err != nil {
+			shutdown()
+			return fmt.Errorf("init mux backend %q: %w", name, err)
+		}
+		backends[name] = be
+	}
+
+	routes := make([]mux.Route, 0, len(cfg.Routes))
+	for _, r := range cfg.Routes {
+		be, ok := backends[r.Backend]
+		if !ok {
+			shutdown()
+			return fmt.Errorf("mux route %q: unknown backend %q", r.Bucket, r.Backend)
+		}
+		routes = append(routes, mux.Route{
+			Pattern: r.Bucket,
+			Backend: be,
+		})
+	}
+
+	be, err := mux.New(routes)
+	if err != nil {
+		shutdown()
+		return fmt.Errorf("init mux: %w", err)
+	}
+
+	return runGateway(ctx.Context, be)
+}
@@ -16,6 +16,10 @@ package main
 
 import (
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"github.com/versity/versitygw/backend/meta"
@@ -24,13 +28,37 @@ import (
 
 var (
 	chownuid, chowngid bool
+	gcInterval         int
+	gcTmpAge           int
+	gcUploadAge        int
+	metaStore          string
+	metaPath           string
+	xattrPrefix        string
+	metaCacheSize      int
+	metaCacheTTL       int
+	directIOThreshold  int64
+	dirPerm, filePerm  string
+	dirMode, objMode   string
+	dirSetgid          bool
+	excludePatterns    cli.StringSlice
+	detectContentType  string
+	durability         string
+	etagSource         string
+	scrubInterval      int
+	scrubRate          int64
+	scrubQuarantine    bool
+	trashRetention     int
+	placements         cli.StringSlice
 )
 
 func posixCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "posix",
 		Usage: "posix filesystem storage backend",
-		Description: `Any posix filesystem that supports extended attributes. The top level
+		Description: `Any posix filesystem that supports extended attributes, or any posix
+filesystem with "--metadata sidecar" to keep the object metadata in
+JSON files in a separate directory tree, or "--metadata db" to keep it
+in an embedded database. The top level
 directory for the gateway must be provided. All sub directories of the
 top level directory are treated as buckets, and all files/directories
 below the "bucket directory" are treated as the objects. The object
@@ -41,7 +69,7 @@ bucket: mybucket
 object: a/b/c/myobject
 will be translated into the file /mnt/fs/gwroot/mybucket/a/b/c/myobject`,
 		Action: runPosix,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:        "chuid",
 				Usage:       "chown newly created files and directories to client account UID",
@@ -54,6 +82,158 @@ will be translated into the file /mnt/fs/gwroot/mybucket/a/b/c/myobject`,
 				EnvVars:     []string{"VGW_CHOWN_GID"},
 				Destination: &chowngid,
 			},
+			&cli.IntFlag{
+				Name:        "gc-interval",
+				Usage:       "run the garbage collector of orphaned temp files and multipart uploads at this interval (seconds)",
+				EnvVars:     []string{"VGW_GC_INTERVAL"},
+				Destination: &gcInterval,
+			},
+			&cli.IntFlag{
+				Name:        "gc-tmp-age",
+				Usage:       "age after which unmodified temp files are removed by the garbage collector (seconds)",
+				EnvVars:     []string{"VGW_GC_TMP_AGE"},
+				Value:       86400,
+				Destination: &gcTmpAge,
+			},
+			&cli.IntFlag{
+				Name:        "gc-multipart-age",
+				Usage:       "age after which unmodified multipart uploads are removed by the garbage collector, 0 to keep them (seconds)",
+				EnvVars:     []string{"VGW_GC_MULTIPART_AGE"},
+				Destination: &gcUploadAge,
+			},
+			&cli.IntFlag{
+				Name:        "metadata-cache",
+				Usage:       "number of bucket attributes such as acls and policies to cache in memory, 0 to disable",
+				EnvVars:     []string{"VGW_METADATA_CACHE"},
+				Destination: &metaCacheSize,
+			},
+			&cli.IntFlag{
+				Name:        "metadata-cache-ttl",
+				Usage:       "expire cached bucket attributes after this time when the buckets are also modified outside of this gateway, 0 to never expire (seconds)",
+				EnvVars:     []string{"VGW_METADATA_CACHE_TTL"},
+				Destination: &metaCacheTTL,
+			},
+			&cli.Int64Flag{
+				Name:        "direct-io-threshold",
+				Usage:       "read and write object data of at least this size with O_DIRECT to bypass the page cache, 0 to disable (bytes)",
+				EnvVars:     []string{"VGW_DIRECT_IO_THRESHOLD"},
+				Destination: &directIOThreshold,
+			},
+			&cli.StringFlag{
+				Name:        "dir-perm",
+				Usage:       "octal permissions of new bucket and object directories, before the umask",
+				EnvVars:     []string{"VGW_DIR_PERM"},
+				Value:       "0755",
+				Destination: &dirPerm,
+			},
+			&cli.StringFlag{
+				Name:        "file-perm",
+				Usage:       "octal permissions of new object files, before the umask",
+				EnvVars:     []string{"VGW_FILE_PERM"},
+				Value:       "0644",
+				Destination: &filePerm,
+			},
+			&cli.StringFlag{
+				Name:        "dir-mode",
+				Usage:       "octal mode set on new bucket and object directories regardless of the umask, instead of --dir-perm (with either mode option, the permissions not given as a mode also ignore the umask)",
+				EnvVars:     []string{"VGW_DIR_MODE"},
+				Destination: &dirMode,
+			},
+			&cli.StringFlag{
+				Name:        "object-mode",
+				Usage:       "octal mode set on new object files regardless of the umask, instead of --file-perm (with either mode option, the permissions not given as a mode also ignore the umask)",
+				EnvVars:     []string{"VGW_OBJECT_MODE"},
+				Destination: &objMode,
+			},
+			&cli.BoolFlag{
+				Name:        "dir-setgid",
+				Usage:       "create directories setgid, and keep the group of the parent directory for new objects instead of the --chgid account GID",
+				EnvVars:     []string{"VGW_DIR_SETGID"},
+				Destination: &dirSetgid,
+			},
+			&cli.StringSliceFlag{
+				Name:        "exclude",
+				Usage:       "glob pattern of file or directory names hidden from listings and object reads, such as .nfs* or .snapshot/ (a trailing / matches only directories), may be repeated",
+				EnvVars:     []string{"VGW_EXCLUDE"},
+				Destination: &excludePatterns,
+			},
+			&cli.StringFlag{
+				Name:        "detect-content-type",
+				Usage:       "fill in the Content-Type of objects put without one: none, extension (from the key extension) or content (from the key extension, else from the object data)",
+				EnvVars:     []string{"VGW_DETECT_CONTENT_TYPE"},
+				Value:       "none",
+				Destination: &detectContentType,
+			},
+			&cli.StringFlag{
+				Name:        "durability",
+				Usage:       "flush new objects to stable storage before acknowledging the put or multipart completion: none, data (the object data and attributes) or data+dir (the data and the parent directory entry)",
+				EnvVars:     []string{"VGW_DURABILITY"},
+				Value:       "none",
+				Destination: &durability,
+			},
+			&cli.StringFlag{
+				Name:        "etag-source",
+				Usage:       "etag of files created outside of the gateway: none, stat (from the inode, mtime and size) or md5 (computed in the background and stored, stat until then)",
+				EnvVars:     []string{"VGW_ETAG_SOURCE"},
+				Value:       "none",
+				Destination: &etagSource,
+			},
+			&cli.IntFlag{
+				Name:        "scrub-interval",
+				Usage:       "verify the object data against the stored md5 etags in the background, starting a pass this long after the previous one finished (seconds)",
+				EnvVars:     []string{"VGW_SCRUB_INTERVAL"},
+				Destination: &scrubInterval,
+			},
+			&cli.Int64Flag{
+				Name:        "scrub-rate",
+				Usage:       "limit the scrub reads to this many bytes per second, 0 for unlimited",
+				EnvVars:     []string{"VGW_SCRUB_RATE"},
+				Destination: &scrubRate,
+			},
+			&cli.BoolFlag{
+				Name:        "scrub-quarantine",
+				Usage:       "move the objects failing the scrub into the bucket .sgwtmp/quarantine directory",
+				EnvVars:     []string{"VGW_SCRUB_QUARANTINE"},
+				Destination: &scrubQuarantine,
+			},
+			&cli.IntFlag{
+				Name:        "trash-retention",
+				Usage:       "move deleted objects into the bucket trash, restorable with the admin api until purged after this retention (seconds)",
+				EnvVars:     []string{"VGW_TRASH_RETENTION"},
+				Destination: &trashRetention,
+			},
+			&cli.StringSliceFlag{
+				Name:        "placement",
+				Usage:       "create new buckets matching a glob pattern in another directory, such as fast-*=/mnt/nvme, linked into the gateway root, may be repeated (first match wins)",
+				EnvVars:     []string{"VGW_PLACEMENT"},
+				Destination: &placements,
+			},
+		}, metadataFlags()...),
+	}
+}
+
+// metadataFlags select the object metadata store of the posix based
+// backends
+func metadataFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "metadata",
+			Usage:       "object metadata store, xattr, sidecar for filesystems without extended attribute support, db for an embedded database, or the name of another registered store",
+			EnvVars:     []string{"VGW_METADATA"},
+			Value:       "xattr",
+			Destination: &metaStore,
+		},
+		&cli.StringFlag{
+			Name:        "metadata-path",
+			Usage:       "location of the metadata store, defaults to <gwroot>/.sgwmeta for sidecar and <gwroot>/.sgwmeta.db for db",
+			EnvVars:     []string{"VGW_METADATA_PATH"},
+			Destination: &metaPath,
+		},
+		&cli.StringFlag{
+			Name:        "xattr-prefix",
+			Usage:       "xattr namespace and prefix of the xattr metadata store attributes, such as user.sgw. or trusted.sgw. (default user.)",
+			EnvVars:     []string{"VGW_XATTR_PREFIX"},
+			Destination: &xattrPrefix,
 		},
 	}
 }
@@ -64,14 +244,86 @@ func runPosix(ctx *cli.Context) error {
 	}
 
 	gwroot := (ctx.Args().Get(0))
-	err := meta.XattrMeta{}.Test(gwroot)
+	dperm, err := posix.ParsePerm(dirPerm)
 	if err != nil {
-		return fmt.Errorf("posix xattr check: %v", err)
+		return fmt.Errorf("dir-perm: %w", err)
+	}
+	fperm, err := posix.ParsePerm(filePerm)
+	if err != nil {
+		return fmt.Errorf("file-perm: %w", err)
+	}
+	// an explicit mode applies the permissions regardless of the umask
+	exactPerms := dirMode != "" || objMode != ""
+	if dirMode != "" {
+		if ctx.IsSet("dir-perm") {
+			return fmt.Errorf("dir-mode and dir-perm are mutually exclusive")
+		}
+		dperm, err = posix.ParsePerm(dirMode)
+		if err != nil {
+			return fmt.Errorf("dir-mode: %w", err)
+		}
+	}
+	if objMode != "" {
+		if ctx.IsSet("file-perm") {
+			return fmt.Errorf("object-mode and file-perm are mutually exclusive")
+		}
+		fperm, err = posix.ParsePerm(objMode)
+		if err != nil {
+			return fmt.Errorf("object-mode: %w", err)
+		}
+	}
+
+	detect, err := posix.ParseContentTypeDetect(detectContentType)
+	if err != nil {
+		return fmt.Errorf("detect-content-type: %w", err)
+	}
+	durable, err := posix.ParseDurability(durability)
+	if err != nil {
+		return fmt.Errorf("durability: %w", err)
+	}
+	etagSrc, err := posix.ParseEtagSource(etagSource)
+	if err != nil {
+		return fmt.Errorf("etag-source: %w", err)
+	}
+
+	var bucketPlacements []posix.Placement
+	for _, s := range placements.Value() {
+		pl, err := posix.ParsePlacement(s)
+		if err != nil {
+			return fmt.Errorf("placement: %w", err)
+		}
+		bucketPlacements = append(bucketPlacements, pl)
 	}
 
-	be, err := posix.New(gwroot, meta.XattrMeta{}, posix.PosixOpts{
-		ChownUID: chownuid,
-		ChownGID: chowngid,
+	ms, err := openMetaStore(metaStore, gwroot, metaPath, xattrPrefix)
+	if err != nil {
+		return err
+	}
+	if metaCacheSize > 0 {
+		ms = meta.NewCache(ms, metaCacheSize,
+			time.Duration(metaCacheTTL)*time.Second)
+	}
+
+	be, err := posix.New(gwroot, ms, posix.PosixOpts{
+		ChownUID:          chownuid,
+		ChownGID:          chowngid,
+		GCInterval:        time.Duration(gcInterval) * time.Second,
+		GCTmpAge:          time.Duration(gcTmpAge) * time.Second,
+		GCUploadAge:       time.Duration(gcUploadAge) * time.Second,
+		DirectIOThreshold: directIOThreshold,
+		DirPerm:           dperm,
+		FilePerm:          fperm,
+		SetGID:            dirSetgid,
+		ExactPerms:        exactPerms,
+		Exclude:           excludePatterns.Value(),
+		ContentTypeDetect: detect,
+		Durability:        durable,
+		EtagSource:        etagSrc,
+		ScrubInterval:     time.Duration(scrubInterval) * time.Second,
+		ScrubRate:         scrubRate,
+		ScrubQuarantine:   scrubQuarantine,
+		TrashRetention:    time.Duration(trashRetention) * time.Second,
+		Placements:        bucketPlacements,
 	})
 	if err != nil {
 		return fmt.Errorf("init posix: %v", err)
@@ -79,3 +331,164 @@ func runPosix(ctx *cli.Context) error {
 
 	return runGateway(ctx.Context, be)
 }
+
+// openPosixRoot opens the gateway root of the metadata utils with the
+// metadata store selected by the metaStoreFlags
+func openPosixRoot(ctx *cli.Context) (*posix.Posix, error) {
+	if ctx.NArg() == 0 {
+		return nil, fmt.Errorf("no directory provided for operation")
+	}
+
+	gwroot := ctx.Args().Get(0)
+	ms, err := openMetaStore(ctx.String("metadata"), gwroot,
+		ctx.String("metadata-path"), ctx.String("xattr-prefix"))
+	if err != nil {
+		return nil, err
+	}
+
+	be, err := posix.New(gwroot, ms, posix.PosixOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("init posix: %v", err)
+	}
+	return be, nil
+}
+
+func metaCheck(ctx *cli.Context) error {
+	be, err := openPosixRoot(ctx)
+	if err != nil {
+		return err
+	}
+	defer be.Shutdown()
+
+	stats, err := be.CheckMeta(ctx.Context, posix.MetaCheckOpts{
+		Bucket: ctx.String("bucket"),
+		Repair: ctx.Bool("repair"),
+		Report: func(problem, bucket, object string, repaired bool) {
+			if repaired {
+				problem += " (repaired)"
+			}
+			fmt.Printf("%v: %v\n", filepath.Join(bucket, object), problem)
+		},
+	})
+	fmt.Printf("buckets: %v, objects: %v, problems: %v, repaired: %v\n",
+		stats.Buckets, stats.Objects, stats.Problems, stats.Repaired)
+	if err != nil {
+		return err
+	}
+	if stats.Problems > stats.Repaired {
+		return fmt.Errorf("%v metadata problems remain", stats.Problems-stats.Repaired)
+	}
+	return nil
+}
+
+func metaExport(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("gateway root and archive required")
+	}
+	// resolve before posix changes to the gateway root
+	path, err := filepath.Abs(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	be, err := openPosixRoot(ctx)
+	if err != nil {
+		return err
+	}
+	defer be.Shutdown()
+
+	w := os.Stdout
+	if ctx.Args().Get(1) != "-" {
+		w, err = os.Create(path)
+		if err != nil {
+			return fmt.Errorf("create archive: %w", err)
+		}
+	}
+
+	stats, err := be.ExportMeta(ctx.Context, ctx.String("bucket"), w)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "objects: %v, attributes: %v\n",
+		stats.Objects, stats.Attributes)
+	return nil
+}
+
+func metaImport(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("gateway root and archive required")
+	}
+	path, err := filepath.Abs(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	be, err := openPosixRoot(ctx)
+	if err != nil {
+		return err
+	}
+	defer be.Shutdown()
+
+	r := os.Stdin
+	if ctx.Args().Get(1) != "-" {
+		r, err = os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open archive: %w", err)
+		}
+		defer r.Close()
+	}
+
+	stats, err := be.ImportMeta(ctx.Context, ctx.String("bucket"), r)
+	fmt.Fprintf(os.Stderr, "objects: %v, attributes: %v, skipped: %v\n",
+		stats.Objects, stats.Attributes, stats.Skipped)
+	return err
+}
+
+func bucketPerms(ctx *cli.Context) error {
+	be, err := openPosixRoot(ctx)
+	if err != nil {
+		return err
+	}
+	defer be.Shutdown()
+
+	bucket := ctx.String("bucket")
+	if ctx.Bool("reset") {
+		return be.SetBucketPerms(bucket, posix.BucketPerms{})
+	}
+
+	perms, err := be.GetBucketPerms(bucket)
+	if err != nil {
+		return err
+	}
+	if !ctx.IsSet("dir-perm") && !ctx.IsSet("file-perm") {
+		fmt.Printf("dir-perm: %v, file-perm: %v\n",
+			formatBucketPerm(perms.DirPerm), formatBucketPerm(perms.FilePerm))
+		return nil
+	}
+
+	if ctx.IsSet("dir-perm") {
+		perms.DirPerm, err = posix.ParsePerm(ctx.String("dir-perm"))
+		if err != nil {
+			return fmt.Errorf("dir-perm: %w", err)
+		}
+	}
+	if ctx.IsSet("file-perm") {
+		perms.FilePerm, err = posix.ParsePerm(ctx.String("file-perm"))
+		if err != nil {
+			return fmt.Errorf("file-perm: %w", err)
+		}
+	}
+	return be.SetBucketPerms(bucket, perms)
+}
+
+// formatBucketPerm formats a bucket permission, where zero is the
+// gateway default
+func formatBucketPerm(perm fs.FileMode) string {
+	if perm == 0 {
+		return "default"
+	}
+	return posix.FormatPerm(perm)
+}
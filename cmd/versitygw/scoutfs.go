@@ -22,7 +22,8 @@ import (
 )
 
 var (
-	glacier bool
+	glacier      bool
+	projectQuota bool
 )
 
 func scoutfsCommand() *cli.Command {
@@ -41,9 +42,12 @@ object: a/b/c/myobject
 will be translated into the file /mnt/fs/gwroot/mybucket/a/b/c/myobject
 
 ScoutFS contains optimizations for multipart uploads using extent
-move interfaces as well as support for tiered filesystems.`,
+move interfaces as well as support for tiered filesystems. With
+--project-quota each bucket is assigned a scoutfs project id, and bucket
+quotas set with the admin api are enforced by filesystem project quota
+rules.`,
 		Action: runScoutfs,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:        "glacier",
 				Usage:       "enable glacier emulation mode",
@@ -51,6 +55,12 @@ move interfaces as well as support for tiered filesystems.`,
 				EnvVars:     []string{"VGW_SCOUTFS_GLACIER"},
 				Destination: &glacier,
 			},
+			&cli.BoolFlag{
+				Name:        "project-quota",
+				Usage:       "enforce bucket quotas with scoutfs project quotas",
+				EnvVars:     []string{"VGW_SCOUTFS_PROJECT_QUOTA"},
+				Destination: &projectQuota,
+			},
 			&cli.BoolFlag{
 				Name:        "chuid",
 				Usage:       "chown newly created files and directories to client account UID",
@@ -63,7 +73,7 @@ move interfaces as well as support for tiered filesystems.`,
 				EnvVars:     []string{"VGW_CHOWN_GID"},
 				Destination: &chowngid,
 			},
-		},
+		}, metadataFlags()...),
 	}
 }
 
@@ -72,12 +82,19 @@ func runScoutfs(ctx *cli.Context) error {
 		return fmt.Errorf("no directory provided for operation")
 	}
 
+	gwroot := ctx.Args().Get(0)
+	ms, err := openMetaStore(metaStore, gwroot, metaPath, xattrPrefix)
+	if err != nil {
+		return err
+	}
+
 	var opts scoutfs.ScoutfsOpts
 	opts.GlacierMode = glacier
+	opts.ProjectQuota = projectQuota
 	opts.ChownUID = chownuid
 	opts.ChownGID = chowngid
 
-	be, err := scoutfs.New(ctx.Args().Get(0), opts)
+	be, err := scoutfs.New(gwroot, ms, opts)
 	if err != nil {
 		return fmt.Errorf("init scoutfs: %v", err)
 	}
@@ -42,6 +42,131 @@ func utilsCommand() *cli.Command {
 					},
 				},
 			},
+			{
+				Name:      "mirror-repair",
+				Usage:     "Copy the objects missing or differing on a mirror secondary from the primary.",
+				ArgsUsage: "<mirror config.json>",
+				Action:    mirrorRepair,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "bucket",
+						Usage: "only repair this bucket",
+					},
+					&cli.BoolFlag{
+						Name:  "delete",
+						Usage: "delete secondary objects missing from the primary",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "report the changes without making them",
+					},
+				},
+			},
+			{
+				Name:  "meta-check",
+				Usage: "Check the posix bucket and object metadata for problems.",
+				Description: `Walks the buckets of a posix gateway root validating the metadata:
+multipart uploads that no longer map to their object name, objects
+missing etags, and acl, policy, tagging, object lock and retention
+attributes that fail to parse. With --repair, missing etags are
+recalculated from the object data, unparsable attributes are moved to
+"quarantine.<attribute>" and the unreachable uploads are removed.
+Objects being written while the check runs may be reported as missing
+etags, so repair is best run with the gateway stopped.`,
+				ArgsUsage: "<gateway root>",
+				Action:    metaCheck,
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "bucket",
+						Usage: "only check this bucket",
+					},
+					&cli.BoolFlag{
+						Name:  "repair",
+						Usage: "repair the problems found where possible",
+					},
+				}, metaStoreFlags()...),
+			},
+			{
+				Name:  "meta-export",
+				Usage: "Export the posix bucket and object metadata of a bucket to an archive.",
+				Description: `Writes the acls, policies, tags, object lock settings, etags and all
+other metadata of a posix gateway bucket to a compressed archive, "-"
+writes to stdout. After copying the bucket data with rsync or tape, the
+archive can be restored with meta-import onto another gateway root.`,
+				ArgsUsage: "<gateway root> <archive>",
+				Action:    metaExport,
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "bucket",
+						Usage:    "bucket to export",
+						Required: true,
+					},
+				}, metaStoreFlags()...),
+			},
+			{
+				Name:  "meta-import",
+				Usage: "Import the bucket and object metadata of a meta-export archive.",
+				Description: `Restores the metadata from a meta-export archive, "-" reads from stdin,
+onto the bucket within the posix gateway root. The bucket and object
+data must already be in place, the objects not found are skipped.`,
+				ArgsUsage: "<gateway root> <archive>",
+				Action:    metaImport,
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "bucket",
+						Usage: "import to this bucket instead of the exported bucket name",
+					},
+				}, metaStoreFlags()...),
+			},
+			{
+				Name:  "bucket-perms",
+				Usage: "Show or set the permissions of new directories and objects in a posix bucket.",
+				Description: `Sets the octal permissions of the directories and object files created
+within a bucket from now on, overriding the --dir-perm and --file-perm
+gateway defaults. Existing objects keep their permissions. Without
+permissions, the current bucket permissions are shown.`,
+				ArgsUsage: "<gateway root>",
+				Action:    bucketPerms,
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "bucket",
+						Usage:    "bucket to show or set the permissions of",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "dir-perm",
+						Usage: "octal permissions of new directories, such as 2775",
+					},
+					&cli.StringFlag{
+						Name:  "file-perm",
+						Usage: "octal permissions of new object files, such as 0664",
+					},
+					&cli.BoolFlag{
+						Name:  "reset",
+						Usage: "use the gateway default permissions for the bucket",
+					},
+				}, metaStoreFlags()...),
+			},
+		},
+	}
+}
+
+// metaStoreFlags select the metadata store of the gateway root for
+// the metadata utils
+func metaStoreFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "metadata",
+			Usage: "object metadata store of the gateway, xattr, sidecar, db or another registered store",
+			Value: "xattr",
+		},
+		&cli.StringFlag{
+			Name:  "metadata-path",
+			Usage: "location of the metadata store",
+		},
+		&cli.StringFlag{
+			Name:  "xattr-prefix",
+			Usage: "xattr prefix of the gateway xattr metadata store",
 		},
 	}
 }
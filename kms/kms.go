@@ -0,0 +1,86 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Provider wraps and unwraps per-object data keys with master keys held
+// by a key management service. Object data is never sent to the provider,
+// only the data keys used to encrypt it.
+type Provider interface {
+	// GenerateDataKey creates a new random data key and returns it both
+	// in plaintext and wrapped by the master key identified by keyID.
+	GenerateDataKey(ctx context.Context, keyID string) (DataKey, error)
+	// Decrypt unwraps a data key that was wrapped by the master key
+	// identified by keyID.
+	Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error)
+	// DefaultKeyID is the master key used when a request does not
+	// specify one.
+	DefaultKeyID() string
+}
+
+// DataKey is a per-object data encryption key
+type DataKey struct {
+	KeyID      string
+	Plaintext  []byte
+	Ciphertext []byte
+}
+
+const (
+	// data keys are AES-256 keys
+	dataKeySize = 32
+
+	ProviderStatic = "static"
+	ProviderVault  = "vault"
+)
+
+var (
+	// ErrKeyNotFound is returned when the requested master key does not
+	// exist within the provider
+	ErrKeyNotFound = errors.New("kms key not found")
+)
+
+type Config struct {
+	// Provider is one of "static" or "vault", empty disables kms
+	Provider     string
+	DefaultKeyID string
+
+	// static key provider settings
+	StaticKeyFile string
+
+	// vault transit provider settings
+	VaultEndpoint string
+	VaultToken    string
+	VaultMount    string
+}
+
+// New initializes the configured kms provider, or returns nil if no
+// provider is configured
+func New(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case ProviderStatic:
+		return NewStaticFromFile(cfg.StaticKeyFile, cfg.DefaultKeyID)
+	case ProviderVault:
+		return NewVaultTransit(cfg.VaultEndpoint, cfg.VaultToken, cfg.VaultMount, cfg.DefaultKeyID)
+	default:
+		return nil, fmt.Errorf("unknown kms provider %q", cfg.Provider)
+	}
+}
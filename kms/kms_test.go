@@ -0,0 +1,107 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func newTestStatic(t *testing.T) *Static {
+	t.Helper()
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStatic(map[string][]byte{"key1": key}, "key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestStaticDataKey(t *testing.T) {
+	s := newTestStatic(t)
+	ctx := context.Background()
+
+	dk, err := s.GenerateDataKey(ctx, "key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dk.Plaintext) != dataKeySize {
+		t.Fatalf("data key length = %v, want %v", len(dk.Plaintext), dataKeySize)
+	}
+
+	key, err := s.Decrypt(ctx, "key1", dk.Ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, dk.Plaintext) {
+		t.Errorf("unwrapped data key does not match")
+	}
+
+	_, err = s.GenerateDataKey(ctx, "nokey")
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("GenerateDataKey() error = %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestEncryptObject(t *testing.T) {
+	s := newTestStatic(t)
+	ctx := context.Background()
+
+	data := make([]byte, 1000)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	r, meta, err := EncryptObject(ctx, s, "key1", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(meta) {
+		t.Fatalf("expected encryption metadata")
+	}
+	ciphertext, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(ciphertext, data) {
+		t.Fatalf("data was not encrypted")
+	}
+
+	for _, offset := range []int64{0, 1, 15, 16, 17, 500, 999} {
+		var out bytes.Buffer
+		w, err := DecryptWriter(ctx, s, meta, &out, offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(ciphertext[offset:]); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out.Bytes(), data[offset:]) {
+			t.Errorf("decrypted data at offset %v does not match", offset)
+		}
+	}
+
+	StripMetadata(meta)
+	if len(meta) != 0 {
+		t.Errorf("metadata not stripped: %v", meta)
+	}
+}
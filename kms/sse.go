@@ -0,0 +1,158 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// Object metadata keys used to store the wrapped data key alongside the
+// encrypted object. These are stored as regular user metadata, and must
+// be stripped before metadata is returned to clients.
+const (
+	MetaKeyID   = "versitygw-sse-kms-key-id"
+	MetaDataKey = "versitygw-sse-kms-data-key"
+	MetaIV      = "versitygw-sse-kms-iv"
+)
+
+var metaKeys = []string{MetaKeyID, MetaDataKey, MetaIV}
+
+// EncryptObject generates a new data key wrapped by master key keyID and
+// returns a reader producing the encrypted object data along with the
+// metadata that needs to be stored with the object to decrypt it.
+// Object data is encrypted with AES-256-CTR so the ciphertext is the same
+// length as the plaintext and ranges can be decrypted independently.
+func EncryptObject(ctx context.Context, p Provider, keyID string, r io.Reader) (io.Reader, map[string]string, error) {
+	dk, err := p.GenerateDataKey(ctx, keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, nil, fmt.Errorf("generate iv: %w", err)
+	}
+
+	stream, err := NewStream(dk.Plaintext, iv, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta := map[string]string{
+		MetaKeyID:   keyID,
+		MetaDataKey: base64.StdEncoding.EncodeToString(dk.Ciphertext),
+		MetaIV:      base64.StdEncoding.EncodeToString(iv),
+	}
+
+	return cipher.StreamReader{S: stream, R: r}, meta, nil
+}
+
+// DecryptWriter returns a writer that decrypts object data written to it
+// into w. The offset is the position within the object of the first byte
+// written, which allows decrypting range requests.
+func DecryptWriter(ctx context.Context, p Provider, meta map[string]string, w io.Writer, offset int64) (io.Writer, error) {
+	wrapped, err := base64.StdEncoding.DecodeString(meta[MetaDataKey])
+	if err != nil {
+		return nil, fmt.Errorf("decode data key: %w", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(meta[MetaIV])
+	if err != nil {
+		return nil, fmt.Errorf("decode iv: %w", err)
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid iv length %v", len(iv))
+	}
+
+	key, err := p.Decrypt(ctx, meta[MetaKeyID], wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := NewStream(key, iv, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.StreamWriter{S: stream, W: w}, nil
+}
+
+// IsEncrypted returns true if the object metadata describes a kms
+// encrypted object
+func IsEncrypted(meta map[string]string) bool {
+	return meta[MetaKeyID] != "" && meta[MetaDataKey] != ""
+}
+
+// KeyID returns the master key id used to encrypt the object
+func KeyID(meta map[string]string) string {
+	return meta[MetaKeyID]
+}
+
+// StripMetadata removes the kms metadata keys from user metadata
+func StripMetadata(meta map[string]string) {
+	for k := range meta {
+		for _, mk := range metaKeys {
+			if strings.EqualFold(k, mk) {
+				delete(meta, k)
+			}
+		}
+	}
+}
+
+// CopyMetadata copies the kms metadata keys from src to dst
+func CopyMetadata(dst, src map[string]string) {
+	for _, mk := range metaKeys {
+		if v, ok := src[mk]; ok {
+			dst[mk] = v
+		}
+	}
+}
+
+// NewStream returns an AES-CTR keystream positioned at offset bytes
+// into the object
+func NewStream(key, iv []byte, offset int64) (cipher.Stream, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+
+	// advance the counter by the number of whole blocks before offset
+	ctr := new(big.Int).SetBytes(iv)
+	ctr.Add(ctr, big.NewInt(offset/aes.BlockSize))
+	counter := make([]byte, aes.BlockSize)
+	b := ctr.Bytes()
+	if len(b) > aes.BlockSize {
+		// counter wraps around at 2^128
+		b = b[len(b)-aes.BlockSize:]
+	}
+	copy(counter[aes.BlockSize-len(b):], b)
+
+	stream := cipher.NewCTR(block, counter)
+
+	// discard the keystream up to offset within the block
+	if skip := offset % aes.BlockSize; skip != 0 {
+		discard := make([]byte, skip)
+		stream.XORKeyStream(discard, discard)
+	}
+
+	return stream, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Static is a kms provider using a fixed set of locally stored master keys.
+// Data keys are wrapped with AES-256-GCM.
+type Static struct {
+	keys       map[string][]byte
+	defaultKey string
+}
+
+var _ Provider = &Static{}
+
+// NewStatic creates a static key provider from a map of key id to
+// 32 byte master keys
+func NewStatic(keys map[string][]byte, defaultKey string) (*Static, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no static kms keys specified")
+	}
+	for id, key := range keys {
+		if len(key) != dataKeySize {
+			return nil, fmt.Errorf("kms key %q: invalid key length %v, expected %v",
+				id, len(key), dataKeySize)
+		}
+	}
+	if defaultKey != "" {
+		if _, ok := keys[defaultKey]; !ok {
+			return nil, fmt.Errorf("default kms key %q not found", defaultKey)
+		}
+	}
+
+	return &Static{keys: keys, defaultKey: defaultKey}, nil
+}
+
+// NewStaticFromFile loads master keys from a JSON file mapping key ids to
+// base64 encoded 32 byte keys, for example:
+//
+//	{"key1": "4bWx6XHp6vN6a4jWJ3cVd+Tlm2m8Jk0xZ+oN0K1ZC4A="}
+func NewStaticFromFile(path, defaultKey string) (*Static, error) {
+	if path == "" {
+		return nil, fmt.Errorf("static kms key file should be specified")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read kms key file: %w", err)
+	}
+
+	var encoded map[string]string
+	if err := json.Unmarshal(b, &encoded); err != nil {
+		return nil, fmt.Errorf("parse kms key file: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(encoded))
+	for id, v := range encoded {
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("decode kms key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	return NewStatic(keys, defaultKey)
+}
+
+func (s *Static) DefaultKeyID() string {
+	return s.defaultKey
+}
+
+func (s *Static) GenerateDataKey(_ context.Context, keyID string) (DataKey, error) {
+	gcm, err := s.cipher(keyID)
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	plaintext := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return DataKey{}, fmt.Errorf("generate data key: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return DataKey{}, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	return DataKey{
+		KeyID:      keyID,
+		Plaintext:  plaintext,
+		Ciphertext: gcm.Seal(nonce, nonce, plaintext, []byte(keyID)),
+	}, nil
+}
+
+func (s *Static) Decrypt(_ context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	gcm, err := s.cipher(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid data key ciphertext")
+	}
+
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func (s *Static) cipher(keyID string) (cipher.AEAD, error) {
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
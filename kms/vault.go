@@ -0,0 +1,173 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultVaultMount = "transit"
+	vaultTokenHeader  = "X-Vault-Token"
+)
+
+// VaultTransit is a kms provider backed by the HashiCorp Vault transit
+// secrets engine. Master keys never leave Vault, data keys are generated
+// and unwrapped by the transit datakey and decrypt endpoints.
+type VaultTransit struct {
+	endpoint   string
+	token      string
+	mount      string
+	defaultKey string
+	client     *http.Client
+}
+
+var _ Provider = &VaultTransit{}
+
+// NewVaultTransit creates a Vault transit kms provider. The mount is the
+// path the transit engine is enabled at, and defaults to "transit".
+func NewVaultTransit(endpoint, token, mount, defaultKey string) (*VaultTransit, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("vault endpoint should be specified")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("vault token should be specified")
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("parse vault endpoint: %w", err)
+	}
+	if mount == "" {
+		mount = defaultVaultMount
+	}
+
+	return &VaultTransit{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		token:      token,
+		mount:      strings.Trim(mount, "/"),
+		defaultKey: defaultKey,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}, nil
+}
+
+func (v *VaultTransit) DefaultKeyID() string {
+	return v.defaultKey
+}
+
+type vaultDataKeyResponse struct {
+	Data struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	} `json:"data"`
+}
+
+func (v *VaultTransit) GenerateDataKey(ctx context.Context, keyID string) (DataKey, error) {
+	var resp vaultDataKeyResponse
+	err := v.do(ctx, fmt.Sprintf("datakey/plaintext/%v", url.PathEscape(keyID)),
+		map[string]any{"bits": dataKeySize * 8}, &resp)
+	if err != nil {
+		return DataKey{}, err
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return DataKey{}, fmt.Errorf("decode data key: %w", err)
+	}
+
+	return DataKey{
+		KeyID:      keyID,
+		Plaintext:  plaintext,
+		Ciphertext: []byte(resp.Data.Ciphertext),
+	}, nil
+}
+
+type vaultDecryptResponse struct {
+	Data struct {
+		Plaintext string `json:"plaintext"`
+	} `json:"data"`
+}
+
+func (v *VaultTransit) Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	var resp vaultDecryptResponse
+	err := v.do(ctx, fmt.Sprintf("decrypt/%v", url.PathEscape(keyID)),
+		map[string]any{"ciphertext": string(ciphertext)}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("decode data key: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+type vaultErrorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+func (v *VaultTransit) do(ctx context.Context, path string, body any, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal vault request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%v/v1/%v/%v", v.endpoint, v.mount, path), bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("create vault request: %w", err)
+	}
+	req.Header.Set(vaultTokenHeader, v.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send vault request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read vault response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		var verr vaultErrorResponse
+		_ = json.Unmarshal(data, &verr)
+		msg := strings.Join(verr.Errors, ", ")
+		if resp.StatusCode == http.StatusNotFound ||
+			strings.Contains(msg, "key not found") {
+			return ErrKeyNotFound
+		}
+		return fmt.Errorf("vault request failed (%v): %v", resp.StatusCode, msg)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parse vault response: %w", err)
+	}
+
+	return nil
+}
@@ -19,12 +19,13 @@ import (
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/s3api/controllers"
+	"github.com/versity/versitygw/s3log"
 )
 
 type S3AdminRouter struct{}
 
-func (ar *S3AdminRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMService) {
-	controller := controllers.NewAdminController(iam, be)
+func (ar *S3AdminRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMService, al s3log.AdminAuditLogger, m *controllers.Maintenance, fs *controllers.IPFilterStats) {
+	controller := controllers.NewAdminController(iam, be, al, m, fs)
 
 	// CreateUser admin api
 	app.Patch("/create-user", controller.CreateUser)
@@ -40,4 +41,64 @@ func (ar *S3AdminRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMSe
 
 	// ListBucketsAndOwners admin api
 	app.Patch("/list-buckets", controller.ListBuckets)
+
+	// CreateGroup admin api
+	app.Patch("/create-group", controller.CreateGroup)
+
+	// DeleteGroup admin api
+	app.Patch("/delete-group", controller.DeleteGroup)
+
+	// ListGroups admin api
+	app.Patch("/list-groups", controller.ListGroups)
+
+	// AddGroupMember admin api
+	app.Patch("/add-group-member", controller.AddGroupMember)
+
+	// RemoveGroupMember admin api
+	app.Patch("/remove-group-member", controller.RemoveGroupMember)
+
+	// SetAccountQuota admin api
+	app.Patch("/set-account-quota", controller.SetAccountQuota)
+
+	// GetAccountUsage admin api
+	app.Patch("/get-account-usage", controller.GetAccountUsage)
+
+	// InvalidateIAMCache admin api
+	app.Patch("/invalidate-iam-cache", controller.InvalidateIAMCache)
+
+	// SetBucketQuota admin api
+	app.Patch("/set-bucket-quota", controller.SetBucketQuota)
+
+	// GetBucketQuota admin api
+	app.Patch("/get-bucket-quota", controller.GetBucketQuota)
+
+	// GetBucketUsage admin api
+	app.Patch("/bucket-usage", controller.GetBucketUsage)
+
+	// ListMultipartUploads admin api
+	app.Patch("/list-multipart-uploads", controller.ListMultipartUploads)
+
+	// AbortMultipartUploads admin api
+	app.Patch("/abort-multipart-uploads", controller.AbortMultipartUploads)
+
+	// SetMaintenance admin api
+	app.Patch("/maintenance", controller.SetMaintenance)
+
+	// GetIAMCacheStats admin api
+	app.Patch("/iam-cache-stats", controller.GetIAMCacheStats)
+
+	// GetIPFilterStats admin api
+	app.Patch("/ip-filter-stats", controller.GetIPFilterStats)
+
+	// GetScrubStatus admin api
+	app.Patch("/scrub-status", controller.GetScrubStatus)
+
+	// ListTrash admin api
+	app.Patch("/list-trash", controller.ListTrash)
+
+	// RestoreTrash admin api
+	app.Patch("/restore-trash", controller.RestoreTrash)
+
+	// PurgeTrash admin api
+	app.Patch("/purge-trash", controller.PurgeTrash)
 }
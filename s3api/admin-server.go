@@ -16,12 +16,16 @@ package s3api
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3api/controllers"
 	"github.com/versity/versitygw/s3api/middlewares"
+	"github.com/versity/versitygw/s3api/utils"
+	"github.com/versity/versitygw/s3log"
 )
 
 type S3AdminServer struct {
@@ -29,7 +33,16 @@ type S3AdminServer struct {
 	backend backend.Backend
 	router  *S3AdminRouter
 	port    string
-	cert    *tls.Certificate
+	cert    *utils.CertStorage
+	audit   s3log.AdminAuditLogger
+	// token and clientCAs replace the SigV4 authentication with
+	// bearer token and/or tls client certificate authentication
+	token     string
+	clientCAs *x509.CertPool
+	// maintenance is the maintenance mode of the S3 server
+	maintenance *controllers.Maintenance
+	// ipFilterStats are the ip filter counters of the S3 server
+	ipFilterStats *controllers.IPFilterStats
 }
 
 func NewAdminServer(app *fiber.App, be backend.Backend, root middlewares.RootUserConfig, port, region string, iam auth.IAMService, opts ...AdminOpt) *S3AdminServer {
@@ -49,23 +62,64 @@ func NewAdminServer(app *fiber.App, be backend.Backend, root middlewares.RootUse
 	app.Use(middlewares.DecodeURL(nil))
 
 	// Authentication middlewares
-	app.Use(middlewares.VerifyV4Signature(root, iam, nil, region, false))
+	if server.token != "" || server.clientCAs != nil {
+		app.Use(middlewares.VerifyAdminToken(server.token, server.clientCAs != nil))
+	} else {
+		app.Use(middlewares.VerifyV4Signature(root, iam, nil, region, false, false))
+	}
 	app.Use(middlewares.VerifyMD5Body(nil))
 
-	server.router.Init(app, be, iam)
+	server.router.Init(app, be, iam, server.audit, server.maintenance, server.ipFilterStats)
 
 	return server
 }
 
 type AdminOpt func(s *S3AdminServer)
 
-func WithAdminSrvTLS(cert tls.Certificate) AdminOpt {
-	return func(s *S3AdminServer) { s.cert = &cert }
+func WithAdminSrvTLS(cs *utils.CertStorage) AdminOpt {
+	return func(s *S3AdminServer) { s.cert = cs }
+}
+
+// WithAdminSrvAuditLog sets the admin audit logger
+func WithAdminSrvAuditLog(l s3log.AdminAuditLogger) AdminOpt {
+	return func(s *S3AdminServer) { s.audit = l }
+}
+
+// WithAdminSrvMaintenance lets the admin api toggle the maintenance
+// mode of the S3 server
+func WithAdminSrvMaintenance(m *controllers.Maintenance) AdminOpt {
+	return func(s *S3AdminServer) { s.maintenance = m }
+}
+
+// WithAdminSrvIPFilterStats lets the admin api report the requests
+// rejected by the ip filters of the S3 server
+func WithAdminSrvIPFilterStats(fs *controllers.IPFilterStats) AdminOpt {
+	return func(s *S3AdminServer) { s.ipFilterStats = fs }
+}
+
+// WithAdminSrvToken authenticates the admin requests with the bearer
+// token instead of SigV4
+func WithAdminSrvToken(token string) AdminOpt {
+	return func(s *S3AdminServer) { s.token = token }
+}
+
+// WithAdminSrvClientCAs requires the admin clients to present a tls
+// certificate signed by one of the CAs, it requires WithAdminSrvTLS
+func WithAdminSrvClientCAs(pool *x509.CertPool) AdminOpt {
+	return func(s *S3AdminServer) { s.clientCAs = pool }
 }
 
 func (sa *S3AdminServer) Serve() (err error) {
 	if sa.cert != nil {
-		return sa.app.ListenTLSWithCertificate(sa.port, *sa.cert)
+		config := &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: sa.cert.GetCertificate,
+		}
+		if sa.clientCAs != nil {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+			config.ClientCAs = sa.clientCAs
+		}
+		return listenTLS(sa.app, sa.port, config)
 	}
 	return sa.app.Listen(sa.port)
 }
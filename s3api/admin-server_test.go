@@ -0,0 +1,75 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3api/middlewares"
+)
+
+func TestNewAdminServer_Token(t *testing.T) {
+	app := fiber.New()
+	NewAdminServer(app, backend.BackendUnsupported{}, middlewares.RootUserConfig{},
+		":7071", "us-east-1", &auth.IAMServiceInternal{}, WithAdminSrvToken("secret-token"))
+
+	tests := []struct {
+		name       string
+		auth       string
+		statusCode int
+	}{
+		{
+			name:       "Admin-token-missing",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "Admin-token-invalid",
+			auth:       "Bearer wrong-token",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "Admin-token-sigv4",
+			auth: "AWS4-HMAC-SHA256 Credential=access/20240101/us-east-1/s3/aws4_request, " +
+				"SignedHeaders=host, Signature=abc",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			// authenticated, the backend does not support the operation
+			name:       "Admin-token-success",
+			auth:       "Bearer secret-token",
+			statusCode: http.StatusNotImplemented,
+		},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPatch, "/list-buckets", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("%v: statusCode = %v, wantStatusCode = %v", tt.name, resp.StatusCode, tt.statusCode)
+		}
+	}
+}
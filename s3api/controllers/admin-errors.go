@@ -0,0 +1,116 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3err"
+)
+
+// AdminErrorVersion is the version of the admin api error envelope
+const AdminErrorVersion = "1"
+
+// AdminError is an admin api error, Code is a stable identifier
+// clients can branch on
+type AdminError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RequestID  string `json:"requestId"`
+}
+
+func (e AdminError) Error() string {
+	return e.Message
+}
+
+// AdminErrorResponse is the admin api error response body
+type AdminErrorResponse struct {
+	Version string     `json:"version"`
+	Error   AdminError `json:"error"`
+}
+
+var errAdminAccessDenied = AdminError{
+	StatusCode: http.StatusForbidden,
+	Code:       "AccessDenied",
+	Message:    "access denied: only admin users have access to this resource",
+}
+
+func adminInvalidArgument(format string, a ...any) AdminError {
+	return AdminError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "InvalidArgument",
+		Message:    fmt.Sprintf(format, a...),
+	}
+}
+
+func adminNotImplemented(format string, a ...any) AdminError {
+	return AdminError{
+		StatusCode: http.StatusNotImplemented,
+		Code:       "NotImplemented",
+		Message:    fmt.Sprintf(format, a...),
+	}
+}
+
+// toAdminError maps err to the admin api error
+func toAdminError(err error) AdminError {
+	var aerr AdminError
+	if errors.As(err, &aerr) {
+		return aerr
+	}
+
+	var apierr s3err.APIError
+	if errors.As(err, &apierr) {
+		return AdminError{
+			StatusCode: apierr.HTTPStatusCode,
+			Code:       apierr.Code,
+			Message:    apierr.Description,
+		}
+	}
+
+	switch {
+	case errors.Is(err, auth.ErrNoSuchUser):
+		return AdminError{StatusCode: http.StatusNotFound, Code: "NoSuchUser", Message: err.Error()}
+	case errors.Is(err, auth.ErrNoSuchGroup):
+		return AdminError{StatusCode: http.StatusNotFound, Code: "NoSuchGroup", Message: err.Error()}
+	case errors.Is(err, auth.ErrUserExists):
+		return AdminError{StatusCode: http.StatusConflict, Code: "UserAlreadyExists", Message: err.Error()}
+	case errors.Is(err, auth.ErrGroupExists):
+		return AdminError{StatusCode: http.StatusConflict, Code: "GroupAlreadyExists", Message: err.Error()}
+	case errors.Is(err, auth.ErrNotSupported):
+		return AdminError{StatusCode: http.StatusNotImplemented, Code: "NotImplemented", Message: err.Error()}
+	}
+
+	return AdminError{
+		StatusCode: http.StatusInternalServerError,
+		Code:       "InternalError",
+		Message:    err.Error(),
+	}
+}
+
+// sendAdminError sends the error envelope for err
+func sendAdminError(ctx *fiber.Ctx, err error) error {
+	aerr := toAdminError(err)
+	aerr.RequestID, _ = ctx.Locals("requestId").(string)
+
+	return ctx.Status(aerr.StatusCode).JSON(AdminErrorResponse{
+		Version: AdminErrorVersion,
+		Error:   aerr,
+	})
+}
@@ -15,36 +15,123 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+	"github.com/versity/versitygw/s3response"
 )
 
 type AdminController struct {
-	iam auth.IAMService
-	be  backend.Backend
+	iam           auth.IAMService
+	be            backend.Backend
+	logger        s3log.AdminAuditLogger
+	maintenance   *Maintenance
+	ipFilterStats *IPFilterStats
 }
 
-func NewAdminController(iam auth.IAMService, be backend.Backend) AdminController {
-	return AdminController{iam: iam, be: be}
+func NewAdminController(iam auth.IAMService, be backend.Backend, l s3log.AdminAuditLogger, m *Maintenance, fs *IPFilterStats) AdminController {
+	return AdminController{iam: iam, be: be, logger: l, maintenance: m, ipFilterStats: fs}
 }
 
-func (c AdminController) CreateUser(ctx *fiber.Ctx) error {
+// Maintenance is the gateway maintenance mode, while enabled the
+// mutating S3 requests are rejected and the reads still served
+type Maintenance struct {
+	enabled atomic.Bool
+}
+
+// MaintenanceStatus is the maintenance mode admin api response
+type MaintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+func (m *Maintenance) Set(enabled bool) {
+	m.enabled.Store(enabled)
+}
+
+// IPFilterStats counts the requests rejected by the ip filters of the
+// gateway listeners
+type IPFilterStats struct {
+	mu        sync.Mutex
+	listeners []*ListenerStats
+}
+
+// ListenerStats are the ip filter counters of a listener
+type ListenerStats struct {
+	addr   string
+	denied atomic.Uint64
+}
+
+// IPFilterListenerStats is the ip filter stats admin api response entry
+type IPFilterListenerStats struct {
+	Listener string `json:"listener"`
+	Denied   uint64 `json:"denied"`
+}
+
+// Listener adds the counters of the listener address
+func (s *IPFilterStats) Listener(addr string) *ListenerStats {
+	l := &ListenerStats{addr: addr}
+	s.mu.Lock()
+	s.listeners = append(s.listeners, l)
+	s.mu.Unlock()
+	return l
+}
+
+// Stats returns the counters of the listeners in the order they were added
+func (s *IPFilterStats) Stats() []IPFilterListenerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make([]IPFilterListenerStats, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		stats = append(stats, IPFilterListenerStats{
+			Listener: l.addr,
+			Denied:   l.denied.Load(),
+		})
+	}
+	return stats
+}
+
+// Deny counts a request rejected by the ip filter
+func (l *ListenerStats) Deny() {
+	l.denied.Add(1)
+}
+
+func (c AdminController) CreateUser(ctx *fiber.Ctx) (err error) {
+	var usr auth.Account
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "CreateUser",
+			Target: s3log.AdminAuditTarget{User: usr.Access, Role: string(usr.Role)},
+		})
+	}()
+
 	acct := ctx.Locals("account").(auth.Account)
 	if acct.Role != "admin" {
-		return fmt.Errorf("access denied: only admin users have access to this resource")
+		return errAdminAccessDenied
 	}
-	var usr auth.Account
-	err := json.Unmarshal(ctx.Body(), &usr)
+	err = json.Unmarshal(ctx.Body(), &usr)
 	if err != nil {
-		return fmt.Errorf("failed to parse request body: %w", err)
+		return adminInvalidArgument("failed to parse request body: %v", err)
 	}
 
 	if usr.Role != auth.RoleAdmin && usr.Role != auth.RoleUser && usr.Role != auth.RoleUserPlus {
-		return fmt.Errorf("invalid parameters: user role have to be one of the following: 'user', 'admin', 'userplus'")
+		return adminInvalidArgument("invalid parameters: user role have to be one of the following: 'user', 'admin', 'userplus'")
 	}
 
 	err = c.iam.CreateAccount(usr)
@@ -55,14 +142,21 @@ func (c AdminController) CreateUser(ctx *fiber.Ctx) error {
 	return ctx.SendString("The user has been created successfully")
 }
 
-func (c AdminController) DeleteUser(ctx *fiber.Ctx) error {
+func (c AdminController) DeleteUser(ctx *fiber.Ctx) (err error) {
 	access := ctx.Query("access")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "DeleteUser",
+			Target: s3log.AdminAuditTarget{User: access},
+		})
+	}()
+
 	acct := ctx.Locals("account").(auth.Account)
 	if acct.Role != "admin" {
-		return fmt.Errorf("access denied: only admin users have access to this resource")
+		return errAdminAccessDenied
 	}
 
-	err := c.iam.DeleteUserAccount(access)
+	err = c.iam.DeleteUserAccount(access)
 	if err != nil {
 		return err
 	}
@@ -70,10 +164,14 @@ func (c AdminController) DeleteUser(ctx *fiber.Ctx) error {
 	return ctx.SendString("The user has been deleted successfully")
 }
 
-func (c AdminController) ListUsers(ctx *fiber.Ctx) error {
+func (c AdminController) ListUsers(ctx *fiber.Ctx) (err error) {
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{Action: "ListUsers"})
+	}()
+
 	acct := ctx.Locals("account").(auth.Account)
 	if acct.Role != "admin" {
-		return fmt.Errorf("access denied: only admin users have access to this resource")
+		return errAdminAccessDenied
 	}
 	accs, err := c.iam.ListUserAccounts()
 	if err != nil {
@@ -83,20 +181,40 @@ func (c AdminController) ListUsers(ctx *fiber.Ctx) error {
 	return ctx.JSON(accs)
 }
 
-func (c AdminController) ChangeBucketOwner(ctx *fiber.Ctx) error {
+func (c AdminController) ChangeBucketOwner(ctx *fiber.Ctx) (err error) {
+	owner := ctx.Query("owner")
+	bucket := ctx.Query("bucket")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "ChangeBucketOwner",
+			Target: s3log.AdminAuditTarget{Bucket: bucket, Owner: owner},
+		})
+	}()
+
 	acct := ctx.Locals("account").(auth.Account)
 	if acct.Role != "admin" {
-		return fmt.Errorf("access denied: only admin users have access to this resource")
+		return errAdminAccessDenied
 	}
-	owner := ctx.Query("owner")
-	bucket := ctx.Query("bucket")
 
 	accs, err := auth.CheckIfAccountsExist([]string{owner}, c.iam)
 	if err != nil {
 		return err
 	}
 	if len(accs) > 0 {
-		return fmt.Errorf("user specified as the new bucket owner does not exist")
+		return fmt.Errorf("user specified as the new bucket owner does not exist: %w", auth.ErrNoSuchUser)
+	}
+
+	// the files are chowned first to not leave the bucket owned by an
+	// account unable to access them
+	if ctx.QueryBool("chown") {
+		newOwner, err := c.iam.GetUserAccount(owner)
+		if err != nil {
+			return err
+		}
+		err = c.be.ChownBucket(ctx.Context(), bucket, newOwner.UserID, newOwner.GroupID)
+		if err != nil {
+			return err
+		}
 	}
 
 	err = c.be.ChangeBucketOwner(ctx.Context(), bucket, owner)
@@ -107,10 +225,14 @@ func (c AdminController) ChangeBucketOwner(ctx *fiber.Ctx) error {
 	return ctx.Status(201).SendString("Bucket owner has been updated successfully")
 }
 
-func (c AdminController) ListBuckets(ctx *fiber.Ctx) error {
+func (c AdminController) ListBuckets(ctx *fiber.Ctx) (err error) {
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{Action: "ListBuckets"})
+	}()
+
 	acct := ctx.Locals("account").(auth.Account)
 	if acct.Role != "admin" {
-		return fmt.Errorf("access denied: only admin users have access to this resource")
+		return errAdminAccessDenied
 	}
 
 	buckets, err := c.be.ListBucketsAndOwners(ctx.Context())
@@ -120,3 +242,663 @@ func (c AdminController) ListBuckets(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(buckets)
 }
+
+func (c AdminController) CreateGroup(ctx *fiber.Ctx) (err error) {
+	var group auth.Group
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "CreateGroup",
+			Target: s3log.AdminAuditTarget{Group: group.Name, Role: string(group.Role)},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+	err = json.Unmarshal(ctx.Body(), &group)
+	if err != nil {
+		return adminInvalidArgument("failed to parse request body: %v", err)
+	}
+
+	if !auth.IsValidGroupName(group.Name) {
+		return adminInvalidArgument("invalid parameters: group name can't be empty or contain '/' or '*'")
+	}
+	if group.Role != auth.RoleAdmin && group.Role != auth.RoleUser && group.Role != auth.RoleUserPlus {
+		return adminInvalidArgument("invalid parameters: group role have to be one of the following: 'user', 'admin', 'userplus'")
+	}
+
+	gs, ok := auth.GetGroupService(c.iam)
+	if !ok {
+		return adminNotImplemented("groups are not supported by the iam service")
+	}
+	err = gs.CreateGroup(group)
+	if err != nil {
+		return fmt.Errorf("failed to create group: %w", err)
+	}
+
+	return ctx.SendString("The group has been created successfully")
+}
+
+func (c AdminController) DeleteGroup(ctx *fiber.Ctx) (err error) {
+	name := ctx.Query("group")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "DeleteGroup",
+			Target: s3log.AdminAuditTarget{Group: name},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	gs, ok := auth.GetGroupService(c.iam)
+	if !ok {
+		return adminNotImplemented("groups are not supported by the iam service")
+	}
+	err = gs.DeleteGroup(name)
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The group has been deleted successfully")
+}
+
+func (c AdminController) ListGroups(ctx *fiber.Ctx) (err error) {
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{Action: "ListGroups"})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	gs, ok := auth.GetGroupService(c.iam)
+	if !ok {
+		return adminNotImplemented("groups are not supported by the iam service")
+	}
+	groups, err := gs.ListGroups()
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(groups)
+}
+
+func (c AdminController) AddGroupMember(ctx *fiber.Ctx) (err error) {
+	name := ctx.Query("group")
+	access := ctx.Query("access")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "AddGroupMember",
+			Target: s3log.AdminAuditTarget{Group: name, User: access},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	gs, ok := auth.GetGroupService(c.iam)
+	if !ok {
+		return adminNotImplemented("groups are not supported by the iam service")
+	}
+	err = gs.AddGroupMember(name, access)
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The user has been added to the group successfully")
+}
+
+func (c AdminController) RemoveGroupMember(ctx *fiber.Ctx) (err error) {
+	name := ctx.Query("group")
+	access := ctx.Query("access")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "RemoveGroupMember",
+			Target: s3log.AdminAuditTarget{Group: name, User: access},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	gs, ok := auth.GetGroupService(c.iam)
+	if !ok {
+		return adminNotImplemented("groups are not supported by the iam service")
+	}
+	err = gs.RemoveGroupMember(name, access)
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The user has been removed from the group successfully")
+}
+
+func (c AdminController) SetAccountQuota(ctx *fiber.Ctx) (err error) {
+	access := ctx.Query("access")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "SetAccountQuota",
+			Target: s3log.AdminAuditTarget{User: access},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	quota, err := strconv.ParseInt(ctx.Query("quota"), 10, 64)
+	if err != nil || quota < 0 {
+		return adminInvalidArgument("invalid parameters: quota has to be a non negative number of bytes")
+	}
+
+	qs, ok := auth.GetQuotaService(c.iam)
+	if !ok {
+		return adminNotImplemented("quotas are not supported by the iam service")
+	}
+	err = qs.SetAccountQuota(access, quota)
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The user quota has been updated successfully")
+}
+
+func (c AdminController) SetBucketQuota(ctx *fiber.Ctx) (err error) {
+	bucket := ctx.Query("bucket")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "SetBucketQuota",
+			Target: s3log.AdminAuditTarget{Bucket: bucket},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	// a missing or zero limit is unlimited
+	var quota s3response.BucketQuota
+	if size := ctx.Query("size"); size != "" {
+		quota.Size, err = strconv.ParseInt(size, 10, 64)
+		if err != nil || quota.Size < 0 {
+			return adminInvalidArgument("invalid parameters: size has to be a non negative number of bytes")
+		}
+	}
+	if objects := ctx.Query("objects"); objects != "" {
+		quota.Objects, err = strconv.ParseInt(objects, 10, 64)
+		if err != nil || quota.Objects < 0 {
+			return adminInvalidArgument("invalid parameters: objects has to be a non negative number")
+		}
+	}
+
+	err = c.be.PutBucketQuota(ctx.Context(), bucket, quota)
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The bucket quota has been updated successfully")
+}
+
+func (c AdminController) GetBucketQuota(ctx *fiber.Ctx) (err error) {
+	bucket := ctx.Query("bucket")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "GetBucketQuota",
+			Target: s3log.AdminAuditTarget{Bucket: bucket},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	quota, err := c.be.GetBucketQuota(ctx.Context(), bucket)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(quota)
+}
+
+func (c AdminController) GetAccountUsage(ctx *fiber.Ctx) (err error) {
+	access := ctx.Query("access")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "GetAccountUsage",
+			Target: s3log.AdminAuditTarget{User: access},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	usage, err := auth.NewQuotaTracker(c.be, c.iam).GetAccountUsage(ctx.Context(), access)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(usage)
+}
+
+func (c AdminController) GetBucketUsage(ctx *fiber.Ctx) (err error) {
+	bucket := ctx.Query("bucket")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "GetBucketUsage",
+			Target: s3log.AdminAuditTarget{Bucket: bucket},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	var buckets []string
+	if bucket != "" {
+		buckets = append(buckets, bucket)
+	} else {
+		all, err := c.be.ListBucketsAndOwners(ctx.Context())
+		if err != nil {
+			return err
+		}
+		for _, b := range all {
+			buckets = append(buckets, b.Name)
+		}
+	}
+
+	usage := []s3response.BucketUsage{}
+	for _, b := range buckets {
+		bu, err := c.be.GetBucketUsage(ctx.Context(), b)
+		if bucket == "" && errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)) {
+			// deleted meanwhile
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		usage = append(usage, bu)
+	}
+
+	return ctx.JSON(usage)
+}
+
+// parseOlderThan parses the older-than query duration, which defaults
+// to zero to match all the uploads
+func parseOlderThan(ctx *fiber.Ctx) (time.Duration, error) {
+	olderThan := ctx.Query("older-than")
+	if olderThan == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(olderThan)
+	if err != nil || d < 0 {
+		return 0, adminInvalidArgument("invalid older-than duration: %q", olderThan)
+	}
+	return d, nil
+}
+
+// staleUploads returns the multipart uploads of all the buckets
+// initiated more than olderThan ago
+func (c AdminController) staleUploads(ctx context.Context, olderThan time.Duration) ([]s3response.MultipartUploadInfo, error) {
+	buckets, err := c.be.ListBucketsAndOwners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	uploads := []s3response.MultipartUploadInfo{}
+	for _, bucket := range buckets {
+		var keyMarker, uploadIDMarker string
+		for {
+			res, err := c.be.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
+				Bucket:         &bucket.Name,
+				KeyMarker:      &keyMarker,
+				UploadIdMarker: &uploadIDMarker,
+			})
+			if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)) {
+				// deleted meanwhile
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("list bucket %v multipart uploads: %w", bucket.Name, err)
+			}
+
+			for _, upload := range res.Uploads {
+				initiated, err := time.Parse(backend.RFC3339TimeFormat, upload.Initiated)
+				if err != nil {
+					return nil, fmt.Errorf("parse upload %v initiated time: %w", upload.UploadID, err)
+				}
+				age := now.Sub(initiated)
+				if age < olderThan {
+					continue
+				}
+
+				size, err := c.uploadSize(ctx, bucket.Name, upload.Key, upload.UploadID)
+				if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchUpload)) {
+					// completed or aborted meanwhile
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+
+				uploads = append(uploads, s3response.MultipartUploadInfo{
+					Bucket:    bucket.Name,
+					Key:       upload.Key,
+					UploadID:  upload.UploadID,
+					Owner:     bucket.Owner,
+					Initiated: initiated,
+					Age:       int64(age.Seconds()),
+					Size:      size,
+				})
+			}
+
+			if !res.IsTruncated {
+				break
+			}
+			keyMarker, uploadIDMarker = res.NextKeyMarker, res.NextUploadIDMarker
+		}
+	}
+
+	return uploads, nil
+}
+
+// uploadSize sums the size of the multipart upload parts
+func (c AdminController) uploadSize(ctx context.Context, bucket, key, uploadID string) (int64, error) {
+	var size int64
+	var marker string
+	for {
+		res, err := c.be.ListParts(ctx, &s3.ListPartsInput{
+			Bucket:           &bucket,
+			Key:              &key,
+			UploadId:         &uploadID,
+			PartNumberMarker: &marker,
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, part := range res.Parts {
+			size += part.Size
+		}
+		if !res.IsTruncated {
+			return size, nil
+		}
+		marker = strconv.Itoa(res.NextPartNumberMarker)
+	}
+}
+
+func (c AdminController) ListMultipartUploads(ctx *fiber.Ctx) (err error) {
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{Action: "ListMultipartUploads"})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	olderThan, err := parseOlderThan(ctx)
+	if err != nil {
+		return err
+	}
+
+	uploads, err := c.staleUploads(ctx.Context(), olderThan)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(uploads)
+}
+
+func (c AdminController) AbortMultipartUploads(ctx *fiber.Ctx) (err error) {
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{Action: "AbortMultipartUploads"})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	if ctx.Query("older-than") == "" {
+		return adminInvalidArgument("missing older-than duration")
+	}
+	olderThan, err := parseOlderThan(ctx)
+	if err != nil {
+		return err
+	}
+
+	uploads, err := c.staleUploads(ctx.Context(), olderThan)
+	if err != nil {
+		return err
+	}
+
+	aborted := []s3response.MultipartUploadInfo{}
+	for _, upload := range uploads {
+		err := c.be.AbortMultipartUpload(ctx.Context(), &s3.AbortMultipartUploadInput{
+			Bucket:   &upload.Bucket,
+			Key:      &upload.Key,
+			UploadId: &upload.UploadID,
+		})
+		if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchUpload)) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("abort upload %v: %w", upload.UploadID, err)
+		}
+		aborted = append(aborted, upload)
+	}
+
+	return ctx.JSON(aborted)
+}
+
+// SetMaintenance enables or disables the maintenance mode as set by
+// the enabled query, the current mode is returned without it
+func (c AdminController) SetMaintenance(ctx *fiber.Ctx) (err error) {
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{Action: "SetMaintenance"})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	if c.maintenance == nil {
+		return adminNotImplemented("maintenance mode is not supported by the gateway")
+	}
+
+	if enabled := ctx.Query("enabled"); enabled != "" {
+		e, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return adminInvalidArgument("invalid parameters: enabled has to be true or false")
+		}
+		c.maintenance.Set(e)
+	}
+
+	return ctx.JSON(MaintenanceStatus{Enabled: c.maintenance.Enabled()})
+}
+
+func (c AdminController) InvalidateIAMCache(ctx *fiber.Ctx) (err error) {
+	access := ctx.Query("access")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "InvalidateIAMCache",
+			Target: s3log.AdminAuditTarget{User: access},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	cache, ok := auth.GetCache(c.iam)
+	if !ok {
+		return adminNotImplemented("iam cache is not enabled")
+	}
+
+	// without an access key the whole cache is invalidated
+	if access == "" {
+		cache.InvalidateAll()
+	} else {
+		cache.Invalidate(access)
+	}
+
+	return ctx.SendString("The iam cache has been invalidated successfully")
+}
+
+func (c AdminController) GetIAMCacheStats(ctx *fiber.Ctx) (err error) {
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{Action: "GetIAMCacheStats"})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	cache, ok := auth.GetCache(c.iam)
+	if !ok {
+		return adminNotImplemented("iam cache is not enabled")
+	}
+
+	return ctx.JSON(cache.Stats())
+}
+
+func (c AdminController) GetIPFilterStats(ctx *fiber.Ctx) (err error) {
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{Action: "GetIPFilterStats"})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	if c.ipFilterStats == nil {
+		return adminNotImplemented("ip filter is not enabled")
+	}
+
+	return ctx.JSON(c.ipFilterStats.Stats())
+}
+
+func (c AdminController) GetScrubStatus(ctx *fiber.Ctx) (err error) {
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{Action: "GetScrubStatus"})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	status, err := c.be.GetScrubStatus(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(status)
+}
+
+func (c AdminController) ListTrash(ctx *fiber.Ctx) (err error) {
+	bucket := ctx.Query("bucket")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "ListTrash",
+			Target: s3log.AdminAuditTarget{Bucket: bucket},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	entries, err := c.be.ListTrash(ctx.Context(), bucket)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(entries)
+}
+
+func (c AdminController) RestoreTrash(ctx *fiber.Ctx) (err error) {
+	bucket, id := ctx.Query("bucket"), ctx.Query("id")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "RestoreTrash",
+			Target: s3log.AdminAuditTarget{Bucket: bucket},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	if id == "" {
+		return adminInvalidArgument("invalid parameters: the trash entry id is required")
+	}
+
+	err = c.be.RestoreTrash(ctx.Context(), bucket, id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The object has been restored successfully")
+}
+
+func (c AdminController) PurgeTrash(ctx *fiber.Ctx) (err error) {
+	bucket, id := ctx.Query("bucket"), ctx.Query("id")
+	defer func() {
+		err = c.finish(ctx, err, s3log.AdminAuditMeta{
+			Action: "PurgeTrash",
+			Target: s3log.AdminAuditTarget{Bucket: bucket},
+		})
+	}()
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role != "admin" {
+		return errAdminAccessDenied
+	}
+
+	// without an id the whole bucket trash is purged
+	err = c.be.PurgeTrash(ctx.Context(), bucket, id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The trash has been purged successfully")
+}
+
+// finish records the admin operation result in the admin audit log
+// and sends the error response of the failed operations, the audit
+// entry and the error response share the request id
+func (c AdminController) finish(ctx *fiber.Ctx, err error, meta s3log.AdminAuditMeta) error {
+	ctx.Locals("requestId", uuid.NewString())
+
+	if c.logger != nil {
+		c.logger.Log(ctx, err, meta)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	return sendAdminError(ctx, err)
+}
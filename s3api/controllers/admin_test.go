@@ -21,10 +21,19 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gofiber/fiber/v2"
 	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
 	"github.com/versity/versitygw/s3response"
 )
 
@@ -94,7 +103,7 @@ func TestAdminController_CreateUser(t *testing.T) {
 				req: httptest.NewRequest(http.MethodPatch, "/create-user", bytes.NewBuffer(user)),
 			},
 			wantErr:    false,
-			statusCode: 500,
+			statusCode: 400,
 		},
 		{
 			name: "Admin-create-user-invalid-requester-role",
@@ -103,7 +112,7 @@ func TestAdminController_CreateUser(t *testing.T) {
 				req: httptest.NewRequest(http.MethodPatch, "/create-user", nil),
 			},
 			wantErr:    false,
-			statusCode: 500,
+			statusCode: 403,
 		},
 	}
 	for _, tt := range tests {
@@ -173,7 +182,7 @@ func TestAdminController_DeleteUser(t *testing.T) {
 				req: httptest.NewRequest(http.MethodPatch, "/delete-user?access=test", nil),
 			},
 			wantErr:    false,
-			statusCode: 500,
+			statusCode: 403,
 		},
 	}
 	for _, tt := range tests {
@@ -251,7 +260,7 @@ func TestAdminController_ListUsers(t *testing.T) {
 				req: httptest.NewRequest(http.MethodPatch, "/list-users", nil),
 			},
 			wantErr:    false,
-			statusCode: 500,
+			statusCode: 403,
 		},
 		{
 			name: "Admin-list-users-iam-error",
@@ -294,6 +303,12 @@ func TestAdminController_ChangeBucketOwner(t *testing.T) {
 			ChangeBucketOwnerFunc: func(contextMoqParam context.Context, bucket, newOwner string) error {
 				return nil
 			},
+			ChownBucketFunc: func(contextMoqParam context.Context, bucket string, uid, gid int) error {
+				if bucket == "not-chowned" {
+					return s3err.GetAPIError(s3err.ErrNotImplemented)
+				}
+				return nil
+			},
 		},
 		iam: &IAMServiceMock{
 			GetUserAccountFunc: func(access string) (auth.Account, error) {
@@ -368,7 +383,7 @@ func TestAdminController_ChangeBucketOwner(t *testing.T) {
 				req: httptest.NewRequest(http.MethodPatch, "/change-bucket-owner", nil),
 			},
 			wantErr:    false,
-			statusCode: 500,
+			statusCode: 403,
 		},
 		{
 			name: "Change-bucket-owner-check-account-server-error",
@@ -386,7 +401,7 @@ func TestAdminController_ChangeBucketOwner(t *testing.T) {
 				req: httptest.NewRequest(http.MethodPatch, "/change-bucket-owner", nil),
 			},
 			wantErr:    false,
-			statusCode: 500,
+			statusCode: 404,
 		},
 		{
 			name: "Change-bucket-owner-success",
@@ -397,6 +412,24 @@ func TestAdminController_ChangeBucketOwner(t *testing.T) {
 			wantErr:    false,
 			statusCode: 201,
 		},
+		{
+			name: "Change-bucket-owner-chown-not-enabled",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/change-bucket-owner?bucket=not-chowned&owner=owner&chown=true", nil),
+			},
+			wantErr:    false,
+			statusCode: 501,
+		},
+		{
+			name: "Change-bucket-owner-chown-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/change-bucket-owner?bucket=bucket&owner=owner&chown=true", nil),
+			},
+			wantErr:    false,
+			statusCode: 201,
+		},
 	}
 	for _, tt := range tests {
 		resp, err := tt.app.Test(tt.args.req)
@@ -455,7 +488,7 @@ func TestAdminController_ListBuckets(t *testing.T) {
 				req: httptest.NewRequest(http.MethodPatch, "/list-buckets", nil),
 			},
 			wantErr:    false,
-			statusCode: 500,
+			statusCode: 403,
 		},
 		{
 			name: "List-buckets-success",
@@ -479,3 +512,842 @@ func TestAdminController_ListBuckets(t *testing.T) {
 		}
 	}
 }
+
+func TestAdminController_GetBucketUsage(t *testing.T) {
+	type args struct {
+		req *http.Request
+	}
+	adminController := AdminController{
+		be: &BackendMock{
+			ListBucketsAndOwnersFunc: func(contextMoqParam context.Context) ([]s3response.Bucket, error) {
+				return []s3response.Bucket{{Name: "bucket1", Owner: "user1"}, {Name: "deleted", Owner: "user1"}}, nil
+			},
+			GetBucketUsageFunc: func(contextMoqParam context.Context, bucket string) (s3response.BucketUsage, error) {
+				if bucket != "bucket1" {
+					return s3response.BucketUsage{}, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+				}
+				return s3response.BucketUsage{Bucket: bucket, Objects: 2, Size: 10}, nil
+			},
+		},
+	}
+
+	app := fiber.New()
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	app.Patch("/bucket-usage", adminController.GetBucketUsage)
+
+	appRoleErr := fiber.New()
+
+	appRoleErr.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "user1", Secret: "secret", Role: "user"})
+		return ctx.Next()
+	})
+
+	appRoleErr.Patch("/bucket-usage", adminController.GetBucketUsage)
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		args       args
+		wantErr    bool
+		statusCode int
+	}{
+		{
+			name: "Get-bucket-usage-incorrect-role",
+			app:  appRoleErr,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/bucket-usage", nil),
+			},
+			wantErr:    false,
+			statusCode: 403,
+		},
+		{
+			name: "Get-bucket-usage-non-existing-bucket",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/bucket-usage?bucket=deleted", nil),
+			},
+			wantErr:    false,
+			statusCode: 404,
+		},
+		{
+			name: "Get-bucket-usage-all-buckets",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/bucket-usage", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Get-bucket-usage-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/bucket-usage?bucket=bucket1", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		resp, err := tt.app.Test(tt.args.req)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AdminController.GetBucketUsage() %v error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("AdminController.GetBucketUsage() %v statusCode = %v, wantStatusCode = %v", tt.name, resp.StatusCode, tt.statusCode)
+		}
+	}
+}
+
+func TestAdminController_BucketQuota(t *testing.T) {
+	type args struct {
+		req *http.Request
+	}
+	adminController := AdminController{
+		be: &BackendMock{
+			PutBucketQuotaFunc: func(contextMoqParam context.Context, bucket string, quota s3response.BucketQuota) error {
+				if bucket != "bucket" {
+					return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+				}
+				return nil
+			},
+			GetBucketQuotaFunc: func(contextMoqParam context.Context, bucket string) (s3response.BucketQuota, error) {
+				return s3response.BucketQuota{Size: 10, UsedSize: 5}, nil
+			},
+		},
+	}
+
+	app := fiber.New()
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	app.Patch("/set-bucket-quota", adminController.SetBucketQuota)
+	app.Patch("/get-bucket-quota", adminController.GetBucketQuota)
+
+	appRoleErr := fiber.New()
+
+	appRoleErr.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "user1", Secret: "secret", Role: "user"})
+		return ctx.Next()
+	})
+
+	appRoleErr.Patch("/set-bucket-quota", adminController.SetBucketQuota)
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		args       args
+		wantErr    bool
+		statusCode int
+	}{
+		{
+			name: "Set-bucket-quota-incorrect-role",
+			app:  appRoleErr,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/set-bucket-quota?bucket=bucket&size=10", nil),
+			},
+			wantErr:    false,
+			statusCode: 403,
+		},
+		{
+			name: "Set-bucket-quota-invalid-size",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/set-bucket-quota?bucket=bucket&size=-1", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Set-bucket-quota-invalid-objects",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/set-bucket-quota?bucket=bucket&objects=many", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Set-bucket-quota-non-existing-bucket",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/set-bucket-quota?bucket=other&size=10", nil),
+			},
+			wantErr:    false,
+			statusCode: 404,
+		},
+		{
+			name: "Set-bucket-quota-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/set-bucket-quota?bucket=bucket&size=10&objects=100", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Get-bucket-quota-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/get-bucket-quota?bucket=bucket", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+	}
+	for _, tt := range tests {
+		resp, err := tt.app.Test(tt.args.req)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AdminController.BucketQuota() %v error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("AdminController.BucketQuota() %v statusCode = %v, wantStatusCode = %v", tt.name, resp.StatusCode, tt.statusCode)
+		}
+	}
+}
+
+func TestAdminController_MultipartUploads(t *testing.T) {
+	type args struct {
+		req *http.Request
+	}
+	initiated := time.Now().Add(-48 * time.Hour).UTC().Format(backend.RFC3339TimeFormat)
+	adminController := AdminController{
+		be: &BackendMock{
+			ListBucketsAndOwnersFunc: func(contextMoqParam context.Context) ([]s3response.Bucket, error) {
+				return []s3response.Bucket{{Name: "bucket1", Owner: "user1"}}, nil
+			},
+			ListMultipartUploadsFunc: func(contextMoqParam context.Context, listMultipartUploadsInput *s3.ListMultipartUploadsInput) (s3response.ListMultipartUploadsResult, error) {
+				return s3response.ListMultipartUploadsResult{
+					Uploads: []s3response.Upload{{Key: "obj", UploadID: "id", Initiated: initiated}},
+				}, nil
+			},
+			ListPartsFunc: func(contextMoqParam context.Context, listPartsInput *s3.ListPartsInput) (s3response.ListPartsResult, error) {
+				return s3response.ListPartsResult{Parts: []s3response.Part{{PartNumber: 1, Size: 5}}}, nil
+			},
+			AbortMultipartUploadFunc: func(contextMoqParam context.Context, abortMultipartUploadInput *s3.AbortMultipartUploadInput) error {
+				return nil
+			},
+		},
+	}
+
+	app := fiber.New()
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	app.Patch("/list-multipart-uploads", adminController.ListMultipartUploads)
+	app.Patch("/abort-multipart-uploads", adminController.AbortMultipartUploads)
+
+	appRoleErr := fiber.New()
+
+	appRoleErr.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "user1", Secret: "secret", Role: "user"})
+		return ctx.Next()
+	})
+
+	appRoleErr.Patch("/abort-multipart-uploads", adminController.AbortMultipartUploads)
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		args       args
+		wantErr    bool
+		statusCode int
+		uploads    int
+	}{
+		{
+			name: "Abort-multipart-uploads-incorrect-role",
+			app:  appRoleErr,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/abort-multipart-uploads?older-than=24h", nil),
+			},
+			wantErr:    false,
+			statusCode: 403,
+		},
+		{
+			name: "Abort-multipart-uploads-missing-older-than",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/abort-multipart-uploads", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "List-multipart-uploads-invalid-older-than",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/list-multipart-uploads?older-than=1day", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "List-multipart-uploads-newer",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/list-multipart-uploads?older-than=72h", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+			uploads:    0,
+		},
+		{
+			name: "List-multipart-uploads-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/list-multipart-uploads", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+			uploads:    1,
+		},
+		{
+			name: "Abort-multipart-uploads-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/abort-multipart-uploads?older-than=24h", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+			uploads:    1,
+		},
+	}
+	for _, tt := range tests {
+		resp, err := tt.app.Test(tt.args.req)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AdminController.MultipartUploads() %v error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("AdminController.MultipartUploads() %v statusCode = %v, wantStatusCode = %v", tt.name, resp.StatusCode, tt.statusCode)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		var uploads []s3response.MultipartUploadInfo
+		if err := json.NewDecoder(resp.Body).Decode(&uploads); err != nil {
+			t.Errorf("AdminController.MultipartUploads() %v decode: %v", tt.name, err)
+		}
+		if len(uploads) != tt.uploads {
+			t.Errorf("AdminController.MultipartUploads() %v uploads = %v, want %v", tt.name, len(uploads), tt.uploads)
+		}
+		if len(uploads) == 1 && uploads[0].Size != 5 {
+			t.Errorf("AdminController.MultipartUploads() %v size = %v, want 5", tt.name, uploads[0].Size)
+		}
+	}
+}
+
+func TestAdminController_SetMaintenance(t *testing.T) {
+	type args struct {
+		req *http.Request
+	}
+	m := new(Maintenance)
+	adminController := AdminController{maintenance: m}
+
+	app := fiber.New()
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	app.Patch("/maintenance", adminController.SetMaintenance)
+
+	appNoMaintenance := fiber.New()
+
+	appNoMaintenance.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	appNoMaintenance.Patch("/maintenance", AdminController{}.SetMaintenance)
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		args       args
+		wantErr    bool
+		statusCode int
+		enabled    bool
+	}{
+		{
+			name: "Set-maintenance-not-supported",
+			app:  appNoMaintenance,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/maintenance?enabled=true", nil),
+			},
+			wantErr:    false,
+			statusCode: 501,
+		},
+		{
+			name: "Set-maintenance-invalid-enabled",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/maintenance?enabled=maybe", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Set-maintenance-enable",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/maintenance?enabled=true", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+			enabled:    true,
+		},
+		{
+			name: "Get-maintenance",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/maintenance", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+			enabled:    true,
+		},
+		{
+			name: "Set-maintenance-disable",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/maintenance?enabled=false", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+			enabled:    false,
+		},
+	}
+	for _, tt := range tests {
+		resp, err := tt.app.Test(tt.args.req)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AdminController.SetMaintenance() %v error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("AdminController.SetMaintenance() %v statusCode = %v, wantStatusCode = %v", tt.name, resp.StatusCode, tt.statusCode)
+		}
+
+		if resp.StatusCode == http.StatusOK && m.Enabled() != tt.enabled {
+			t.Errorf("AdminController.SetMaintenance() %v enabled = %v, want %v", tt.name, m.Enabled(), tt.enabled)
+		}
+	}
+}
+
+func TestAdminController_GetIPFilterStats(t *testing.T) {
+	fs := new(IPFilterStats)
+	fs.Listener("127.0.0.1:7070").Deny()
+	fs.Listener("127.0.0.1:7071")
+
+	newApp := func(c AdminController, role auth.Role) *fiber.App {
+		app := fiber.New()
+		app.Use(func(ctx *fiber.Ctx) error {
+			ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: role})
+			return ctx.Next()
+		})
+		app.Patch("/ip-filter-stats", c.GetIPFilterStats)
+		return app
+	}
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		statusCode int
+		stats      []IPFilterListenerStats
+	}{
+		{
+			name:       "Get-ip-filter-stats-not-enabled",
+			app:        newApp(AdminController{}, "admin"),
+			statusCode: 501,
+		},
+		{
+			name:       "Get-ip-filter-stats-non-admin",
+			app:        newApp(AdminController{ipFilterStats: fs}, "user"),
+			statusCode: 403,
+		},
+		{
+			name:       "Get-ip-filter-stats-success",
+			app:        newApp(AdminController{ipFilterStats: fs}, "admin"),
+			statusCode: 200,
+			stats: []IPFilterListenerStats{
+				{Listener: "127.0.0.1:7070", Denied: 1},
+				{Listener: "127.0.0.1:7071", Denied: 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		resp, err := tt.app.Test(httptest.NewRequest(http.MethodPatch, "/ip-filter-stats", nil))
+		if err != nil {
+			t.Fatalf("AdminController.GetIPFilterStats() %v error = %v", tt.name, err)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("AdminController.GetIPFilterStats() %v statusCode = %v, wantStatusCode = %v", tt.name, resp.StatusCode, tt.statusCode)
+		}
+
+		if tt.stats == nil {
+			continue
+		}
+		var stats []IPFilterListenerStats
+		if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+			t.Fatalf("AdminController.GetIPFilterStats() %v decode: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(stats, tt.stats) {
+			t.Errorf("AdminController.GetIPFilterStats() %v stats = %v, want %v", tt.name, stats, tt.stats)
+		}
+	}
+}
+
+func TestAdminController_ErrorResponse(t *testing.T) {
+	adminController := AdminController{}
+
+	app := fiber.New()
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "user1", Secret: "secret", Role: "user"})
+		return ctx.Next()
+	})
+
+	app.Patch("/list-buckets", adminController.ListBuckets)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPatch, "/list-buckets", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("AdminController error statusCode = %v, wantStatusCode = %v", resp.StatusCode, http.StatusForbidden)
+	}
+
+	var body AdminErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode error response: %v", err)
+	}
+
+	if body.Version != AdminErrorVersion {
+		t.Errorf("AdminController error version = %q, want %q", body.Version, AdminErrorVersion)
+	}
+	if body.Error.Code != "AccessDenied" {
+		t.Errorf("AdminController error code = %q, want %q", body.Error.Code, "AccessDenied")
+	}
+	if body.Error.RequestID == "" {
+		t.Errorf("AdminController error request id is empty")
+	}
+}
+
+func TestAdminController_CreateGroup(t *testing.T) {
+	type args struct {
+		req *http.Request
+	}
+
+	iam, err := auth.NewInternal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adminController := AdminController{
+		iam: iam,
+	}
+	adminControllerNoGroups := AdminController{
+		iam: &IAMServiceMock{},
+	}
+
+	app := fiber.New()
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	app.Patch("/create-group", adminController.CreateGroup)
+
+	appNoGroups := fiber.New()
+
+	appNoGroups.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	appNoGroups.Patch("/create-group", adminControllerNoGroups.CreateGroup)
+
+	appRoleErr := fiber.New()
+
+	appRoleErr.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "user1", Secret: "secret", Role: "user"})
+		return ctx.Next()
+	})
+
+	appRoleErr.Patch("/create-group", adminController.CreateGroup)
+
+	groupBody := `{"name": "developers", "role": "userplus"}`
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		args       args
+		wantErr    bool
+		statusCode int
+	}{
+		{
+			name: "Create-group-incorrect-role",
+			app:  appRoleErr,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/create-group", strings.NewReader(groupBody)),
+			},
+			wantErr:    false,
+			statusCode: 403,
+		},
+		{
+			name: "Create-group-invalid-body",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/create-group", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Create-group-invalid-name",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/create-group", strings.NewReader(`{"name": "dev/ops", "role": "user"}`)),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Create-group-invalid-group-role",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/create-group", strings.NewReader(`{"name": "devops", "role": "superuser"}`)),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Create-group-not-supported",
+			app:  appNoGroups,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/create-group", strings.NewReader(groupBody)),
+			},
+			wantErr:    false,
+			statusCode: 501,
+		},
+		{
+			name: "Create-group-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/create-group", strings.NewReader(groupBody)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Create-group-already-exists",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/create-group", strings.NewReader(groupBody)),
+			},
+			wantErr:    false,
+			statusCode: 409,
+		},
+	}
+	for _, tt := range tests {
+		resp, err := tt.app.Test(tt.args.req)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AdminController.CreateGroup() error = %v, wantErr %v", err, tt.wantErr)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("AdminController.CreateGroup() statusCode = %v, wantStatusCode = %v", resp.StatusCode, tt.statusCode)
+		}
+	}
+}
+
+func TestAdminController_InvalidateIAMCache(t *testing.T) {
+	type args struct {
+		req *http.Request
+	}
+
+	cache := auth.NewCache(&IAMServiceMock{
+		GetUserAccountFunc: func(access string) (auth.Account, error) {
+			return auth.Account{Access: access}, nil
+		},
+	}, time.Minute, time.Minute, 0)
+	defer cache.Shutdown()
+
+	adminController := AdminController{
+		iam: cache,
+	}
+	adminControllerNoCache := AdminController{
+		iam: &IAMServiceMock{},
+	}
+
+	app := fiber.New()
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	app.Patch("/invalidate-iam-cache", adminController.InvalidateIAMCache)
+
+	appNoCache := fiber.New()
+
+	appNoCache.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	appNoCache.Patch("/invalidate-iam-cache", adminControllerNoCache.InvalidateIAMCache)
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		args       args
+		wantErr    bool
+		statusCode int
+		entries    int
+	}{
+		{
+			name: "Invalidate-iam-cache-disabled",
+			app:  appNoCache,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/invalidate-iam-cache", nil),
+			},
+			wantErr:    false,
+			statusCode: 501,
+			entries:    2,
+		},
+		{
+			name: "Invalidate-iam-cache-account",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/invalidate-iam-cache?access=user1", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+			entries:    1,
+		},
+		{
+			name: "Invalidate-iam-cache-all",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/invalidate-iam-cache", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+			entries:    0,
+		},
+	}
+	for _, tt := range tests {
+		cache.InvalidateAll()
+		for _, access := range []string{"user1", "user2"} {
+			if _, err := cache.GetUserAccount(access); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		resp, err := tt.app.Test(tt.args.req)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AdminController.InvalidateIAMCache() error = %v, wantErr %v", err, tt.wantErr)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("AdminController.InvalidateIAMCache() statusCode = %v, wantStatusCode = %v", resp.StatusCode, tt.statusCode)
+		}
+
+		if entries := cache.Stats().Entries; entries != tt.entries {
+			t.Errorf("AdminController.InvalidateIAMCache() entries = %v, wantEntries = %v", entries, tt.entries)
+		}
+	}
+}
+
+func TestAdminController_AuditLog(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "admin-audit.log")
+	al, err := s3log.InitAdminAuditLogger(&s3log.AdminAuditConfig{LogFile: logfile})
+	if err != nil {
+		t.Fatalf("init admin audit logger: %v", err)
+	}
+
+	adminController := NewAdminController(&IAMServiceMock{
+		DeleteUserAccountFunc: func(access string) error {
+			return nil
+		},
+	}, nil, al, nil, nil)
+
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+	app.Patch("/delete-user", adminController.DeleteUser)
+
+	appErr := fiber.New()
+	appErr.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "user1", Secret: "secret", Role: "user"})
+		return ctx.Next()
+	})
+	appErr.Patch("/delete-user", adminController.DeleteUser)
+
+	_, err = app.Test(httptest.NewRequest(http.MethodPatch, "/delete-user?access=test", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = appErr.Test(httptest.NewRequest(http.MethodPatch, "/delete-user?access=test", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := al.Shutdown(); err != nil {
+		t.Fatalf("shutdown admin audit logger: %v", err)
+	}
+
+	data, err := os.ReadFile(logfile)
+	if err != nil {
+		t.Fatalf("read admin audit log: %v", err)
+	}
+
+	var entries []s3log.AdminAuditEntry
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var entry s3log.AdminAuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("parse admin audit entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %v", len(entries))
+	}
+
+	if entries[0].Actor != "admin1" || entries[0].Action != "DeleteUser" ||
+		entries[0].Target.User != "test" || entries[0].Result != s3log.AdminAuditResultSuccess {
+		t.Errorf("unexpected audit entry: %+v", entries[0])
+	}
+	if entries[1].Actor != "user1" || entries[1].Result != s3log.AdminAuditResultFailure || entries[1].Error == "" {
+		t.Errorf("unexpected audit entry: %+v", entries[1])
+	}
+}
@@ -29,6 +29,9 @@ var _ backend.Backend = &BackendMock{}
 //			ChangeBucketOwnerFunc: func(contextMoqParam context.Context, bucket string, newOwner string) error {
 //				panic("mock out the ChangeBucketOwner method")
 //			},
+//			ChownBucketFunc: func(contextMoqParam context.Context, bucket string, uid int, gid int) error {
+//				panic("mock out the ChownBucket method")
+//			},
 //			CompleteMultipartUploadFunc: func(contextMoqParam context.Context, completeMultipartUploadInput *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
 //				panic("mock out the CompleteMultipartUpload method")
 //			},
@@ -59,22 +62,37 @@ var _ backend.Backend = &BackendMock{}
 //			DeleteObjectsFunc: func(contextMoqParam context.Context, deleteObjectsInput *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
 //				panic("mock out the DeleteObjects method")
 //			},
+//			DeletePublicAccessBlockFunc: func(contextMoqParam context.Context, bucket string) error {
+//				panic("mock out the DeletePublicAccessBlock method")
+//			},
 //			GetBucketAclFunc: func(contextMoqParam context.Context, getBucketAclInput *s3.GetBucketAclInput) ([]byte, error) {
 //				panic("mock out the GetBucketAcl method")
 //			},
+//			GetBucketLoggingFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+//				panic("mock out the GetBucketLogging method")
+//			},
+//			GetBucketNotificationConfigurationFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+//				panic("mock out the GetBucketNotificationConfiguration method")
+//			},
 //			GetBucketPolicyFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
 //				panic("mock out the GetBucketPolicy method")
 //			},
+//			GetBucketQuotaFunc: func(contextMoqParam context.Context, bucket string) (s3response.BucketQuota, error) {
+//				panic("mock out the GetBucketQuota method")
+//			},
 //			GetBucketTaggingFunc: func(contextMoqParam context.Context, bucket string) (map[string]string, error) {
 //				panic("mock out the GetBucketTagging method")
 //			},
-//			GetBucketVersioningFunc: func(contextMoqParam context.Context, bucket string) (*s3.GetBucketVersioningOutput, error) {
+//			GetBucketUsageFunc: func(contextMoqParam context.Context, bucket string) (s3response.BucketUsage, error) {
+//				panic("mock out the GetBucketUsage method")
+//			},
+//			GetBucketVersioningFunc: func(contextMoqParam context.Context, bucket string) (s3response.GetBucketVersioningOutput, error) {
 //				panic("mock out the GetBucketVersioning method")
 //			},
 //			GetObjectFunc: func(contextMoqParam context.Context, getObjectInput *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, error) {
 //				panic("mock out the GetObject method")
 //			},
-//			GetObjectAclFunc: func(contextMoqParam context.Context, getObjectAclInput *s3.GetObjectAclInput) (*s3.GetObjectAclOutput, error) {
+//			GetObjectAclFunc: func(contextMoqParam context.Context, getObjectAclInput *s3.GetObjectAclInput) ([]byte, error) {
 //				panic("mock out the GetObjectAcl method")
 //			},
 //			GetObjectAttributesFunc: func(contextMoqParam context.Context, getObjectAttributesInput *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error) {
@@ -92,6 +110,12 @@ var _ backend.Backend = &BackendMock{}
 //			GetObjectTaggingFunc: func(contextMoqParam context.Context, bucket string, object string) (map[string]string, error) {
 //				panic("mock out the GetObjectTagging method")
 //			},
+//			GetPublicAccessBlockFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+//				panic("mock out the GetPublicAccessBlock method")
+//			},
+//			GetScrubStatusFunc: func(contextMoqParam context.Context) (s3response.ScrubStatus, error) {
+//				panic("mock out the GetScrubStatus method")
+//			},
 //			HeadBucketFunc: func(contextMoqParam context.Context, headBucketInput *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
 //				panic("mock out the HeadBucket method")
 //			},
@@ -107,7 +131,7 @@ var _ backend.Backend = &BackendMock{}
 //			ListMultipartUploadsFunc: func(contextMoqParam context.Context, listMultipartUploadsInput *s3.ListMultipartUploadsInput) (s3response.ListMultipartUploadsResult, error) {
 //				panic("mock out the ListMultipartUploads method")
 //			},
-//			ListObjectVersionsFunc: func(contextMoqParam context.Context, listObjectVersionsInput *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+//			ListObjectVersionsFunc: func(contextMoqParam context.Context, listObjectVersionsInput *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
 //				panic("mock out the ListObjectVersions method")
 //			},
 //			ListObjectsFunc: func(contextMoqParam context.Context, listObjectsInput *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
@@ -119,12 +143,27 @@ var _ backend.Backend = &BackendMock{}
 //			ListPartsFunc: func(contextMoqParam context.Context, listPartsInput *s3.ListPartsInput) (s3response.ListPartsResult, error) {
 //				panic("mock out the ListParts method")
 //			},
+//			ListTrashFunc: func(contextMoqParam context.Context, bucket string) ([]s3response.TrashEntry, error) {
+//				panic("mock out the ListTrash method")
+//			},
+//			PurgeTrashFunc: func(contextMoqParam context.Context, bucket string, id string) error {
+//				panic("mock out the PurgeTrash method")
+//			},
 //			PutBucketAclFunc: func(contextMoqParam context.Context, bucket string, data []byte) error {
 //				panic("mock out the PutBucketAcl method")
 //			},
+//			PutBucketLoggingFunc: func(contextMoqParam context.Context, bucket string, config []byte) error {
+//				panic("mock out the PutBucketLogging method")
+//			},
+//			PutBucketNotificationConfigurationFunc: func(contextMoqParam context.Context, bucket string, config []byte) error {
+//				panic("mock out the PutBucketNotificationConfiguration method")
+//			},
 //			PutBucketPolicyFunc: func(contextMoqParam context.Context, bucket string, policy []byte) error {
 //				panic("mock out the PutBucketPolicy method")
 //			},
+//			PutBucketQuotaFunc: func(contextMoqParam context.Context, bucket string, quota s3response.BucketQuota) error {
+//				panic("mock out the PutBucketQuota method")
+//			},
 //			PutBucketTaggingFunc: func(contextMoqParam context.Context, bucket string, tags map[string]string) error {
 //				panic("mock out the PutBucketTagging method")
 //			},
@@ -134,7 +173,7 @@ var _ backend.Backend = &BackendMock{}
 //			PutObjectFunc: func(contextMoqParam context.Context, putObjectInput *s3.PutObjectInput) (string, error) {
 //				panic("mock out the PutObject method")
 //			},
-//			PutObjectAclFunc: func(contextMoqParam context.Context, putObjectAclInput *s3.PutObjectAclInput) error {
+//			PutObjectAclFunc: func(contextMoqParam context.Context, bucket string, object string, data []byte) error {
 //				panic("mock out the PutObjectAcl method")
 //			},
 //			PutObjectLegalHoldFunc: func(contextMoqParam context.Context, bucket string, object string, versionId string, status bool) error {
@@ -149,9 +188,15 @@ var _ backend.Backend = &BackendMock{}
 //			PutObjectTaggingFunc: func(contextMoqParam context.Context, bucket string, object string, tags map[string]string) error {
 //				panic("mock out the PutObjectTagging method")
 //			},
+//			PutPublicAccessBlockFunc: func(contextMoqParam context.Context, bucket string, config []byte) error {
+//				panic("mock out the PutPublicAccessBlock method")
+//			},
 //			RestoreObjectFunc: func(contextMoqParam context.Context, restoreObjectInput *s3.RestoreObjectInput) error {
 //				panic("mock out the RestoreObject method")
 //			},
+//			RestoreTrashFunc: func(contextMoqParam context.Context, bucket string, id string) error {
+//				panic("mock out the RestoreTrash method")
+//			},
 //			SelectObjectContentFunc: func(ctx context.Context, input *s3.SelectObjectContentInput) func(w *bufio.Writer) {
 //				panic("mock out the SelectObjectContent method")
 //			},
@@ -180,6 +225,9 @@ type BackendMock struct {
 	// ChangeBucketOwnerFunc mocks the ChangeBucketOwner method.
 	ChangeBucketOwnerFunc func(contextMoqParam context.Context, bucket string, newOwner string) error
 
+	// ChownBucketFunc mocks the ChownBucket method.
+	ChownBucketFunc func(contextMoqParam context.Context, bucket string, uid int, gid int) error
+
 	// CompleteMultipartUploadFunc mocks the CompleteMultipartUpload method.
 	CompleteMultipartUploadFunc func(contextMoqParam context.Context, completeMultipartUploadInput *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error)
 
@@ -210,23 +258,38 @@ type BackendMock struct {
 	// DeleteObjectsFunc mocks the DeleteObjects method.
 	DeleteObjectsFunc func(contextMoqParam context.Context, deleteObjectsInput *s3.DeleteObjectsInput) (s3response.DeleteResult, error)
 
+	// DeletePublicAccessBlockFunc mocks the DeletePublicAccessBlock method.
+	DeletePublicAccessBlockFunc func(contextMoqParam context.Context, bucket string) error
+
 	// GetBucketAclFunc mocks the GetBucketAcl method.
 	GetBucketAclFunc func(contextMoqParam context.Context, getBucketAclInput *s3.GetBucketAclInput) ([]byte, error)
 
+	// GetBucketLoggingFunc mocks the GetBucketLogging method.
+	GetBucketLoggingFunc func(contextMoqParam context.Context, bucket string) ([]byte, error)
+
+	// GetBucketNotificationConfigurationFunc mocks the GetBucketNotificationConfiguration method.
+	GetBucketNotificationConfigurationFunc func(contextMoqParam context.Context, bucket string) ([]byte, error)
+
 	// GetBucketPolicyFunc mocks the GetBucketPolicy method.
 	GetBucketPolicyFunc func(contextMoqParam context.Context, bucket string) ([]byte, error)
 
+	// GetBucketQuotaFunc mocks the GetBucketQuota method.
+	GetBucketQuotaFunc func(contextMoqParam context.Context, bucket string) (s3response.BucketQuota, error)
+
 	// GetBucketTaggingFunc mocks the GetBucketTagging method.
 	GetBucketTaggingFunc func(contextMoqParam context.Context, bucket string) (map[string]string, error)
 
+	// GetBucketUsageFunc mocks the GetBucketUsage method.
+	GetBucketUsageFunc func(contextMoqParam context.Context, bucket string) (s3response.BucketUsage, error)
+
 	// GetBucketVersioningFunc mocks the GetBucketVersioning method.
-	GetBucketVersioningFunc func(contextMoqParam context.Context, bucket string) (*s3.GetBucketVersioningOutput, error)
+	GetBucketVersioningFunc func(contextMoqParam context.Context, bucket string) (s3response.GetBucketVersioningOutput, error)
 
 	// GetObjectFunc mocks the GetObject method.
 	GetObjectFunc func(contextMoqParam context.Context, getObjectInput *s3.GetObjectInput, writer io.Writer) (*s3.GetObjectOutput, error)
 
 	// GetObjectAclFunc mocks the GetObjectAcl method.
-	GetObjectAclFunc func(contextMoqParam context.Context, getObjectAclInput *s3.GetObjectAclInput) (*s3.GetObjectAclOutput, error)
+	GetObjectAclFunc func(contextMoqParam context.Context, getObjectAclInput *s3.GetObjectAclInput) ([]byte, error)
 
 	// GetObjectAttributesFunc mocks the GetObjectAttributes method.
 	GetObjectAttributesFunc func(contextMoqParam context.Context, getObjectAttributesInput *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error)
@@ -243,6 +306,12 @@ type BackendMock struct {
 	// GetObjectTaggingFunc mocks the GetObjectTagging method.
 	GetObjectTaggingFunc func(contextMoqParam context.Context, bucket string, object string) (map[string]string, error)
 
+	// GetPublicAccessBlockFunc mocks the GetPublicAccessBlock method.
+	GetPublicAccessBlockFunc func(contextMoqParam context.Context, bucket string) ([]byte, error)
+
+	// GetScrubStatusFunc mocks the GetScrubStatus method.
+	GetScrubStatusFunc func(contextMoqParam context.Context) (s3response.ScrubStatus, error)
+
 	// HeadBucketFunc mocks the HeadBucket method.
 	HeadBucketFunc func(contextMoqParam context.Context, headBucketInput *s3.HeadBucketInput) (*s3.HeadBucketOutput, error)
 
@@ -259,7 +328,7 @@ type BackendMock struct {
 	ListMultipartUploadsFunc func(contextMoqParam context.Context, listMultipartUploadsInput *s3.ListMultipartUploadsInput) (s3response.ListMultipartUploadsResult, error)
 
 	// ListObjectVersionsFunc mocks the ListObjectVersions method.
-	ListObjectVersionsFunc func(contextMoqParam context.Context, listObjectVersionsInput *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error)
+	ListObjectVersionsFunc func(contextMoqParam context.Context, listObjectVersionsInput *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error)
 
 	// ListObjectsFunc mocks the ListObjects method.
 	ListObjectsFunc func(contextMoqParam context.Context, listObjectsInput *s3.ListObjectsInput) (*s3.ListObjectsOutput, error)
@@ -270,12 +339,27 @@ type BackendMock struct {
 	// ListPartsFunc mocks the ListParts method.
 	ListPartsFunc func(contextMoqParam context.Context, listPartsInput *s3.ListPartsInput) (s3response.ListPartsResult, error)
 
+	// ListTrashFunc mocks the ListTrash method.
+	ListTrashFunc func(contextMoqParam context.Context, bucket string) ([]s3response.TrashEntry, error)
+
+	// PurgeTrashFunc mocks the PurgeTrash method.
+	PurgeTrashFunc func(contextMoqParam context.Context, bucket string, id string) error
+
 	// PutBucketAclFunc mocks the PutBucketAcl method.
 	PutBucketAclFunc func(contextMoqParam context.Context, bucket string, data []byte) error
 
+	// PutBucketLoggingFunc mocks the PutBucketLogging method.
+	PutBucketLoggingFunc func(contextMoqParam context.Context, bucket string, config []byte) error
+
+	// PutBucketNotificationConfigurationFunc mocks the PutBucketNotificationConfiguration method.
+	PutBucketNotificationConfigurationFunc func(contextMoqParam context.Context, bucket string, config []byte) error
+
 	// PutBucketPolicyFunc mocks the PutBucketPolicy method.
 	PutBucketPolicyFunc func(contextMoqParam context.Context, bucket string, policy []byte) error
 
+	// PutBucketQuotaFunc mocks the PutBucketQuota method.
+	PutBucketQuotaFunc func(contextMoqParam context.Context, bucket string, quota s3response.BucketQuota) error
+
 	// PutBucketTaggingFunc mocks the PutBucketTagging method.
 	PutBucketTaggingFunc func(contextMoqParam context.Context, bucket string, tags map[string]string) error
 
@@ -286,7 +370,7 @@ type BackendMock struct {
 	PutObjectFunc func(contextMoqParam context.Context, putObjectInput *s3.PutObjectInput) (string, error)
 
 	// PutObjectAclFunc mocks the PutObjectAcl method.
-	PutObjectAclFunc func(contextMoqParam context.Context, putObjectAclInput *s3.PutObjectAclInput) error
+	PutObjectAclFunc func(contextMoqParam context.Context, bucket string, object string, data []byte) error
 
 	// PutObjectLegalHoldFunc mocks the PutObjectLegalHold method.
 	PutObjectLegalHoldFunc func(contextMoqParam context.Context, bucket string, object string, versionId string, status bool) error
@@ -300,9 +384,15 @@ type BackendMock struct {
 	// PutObjectTaggingFunc mocks the PutObjectTagging method.
 	PutObjectTaggingFunc func(contextMoqParam context.Context, bucket string, object string, tags map[string]string) error
 
+	// PutPublicAccessBlockFunc mocks the PutPublicAccessBlock method.
+	PutPublicAccessBlockFunc func(contextMoqParam context.Context, bucket string, config []byte) error
+
 	// RestoreObjectFunc mocks the RestoreObject method.
 	RestoreObjectFunc func(contextMoqParam context.Context, restoreObjectInput *s3.RestoreObjectInput) error
 
+	// RestoreTrashFunc mocks the RestoreTrash method.
+	RestoreTrashFunc func(contextMoqParam context.Context, bucket string, id string) error
+
 	// SelectObjectContentFunc mocks the SelectObjectContent method.
 	SelectObjectContentFunc func(ctx context.Context, input *s3.SelectObjectContentInput) func(w *bufio.Writer)
 
@@ -336,6 +426,17 @@ type BackendMock struct {
 			// NewOwner is the newOwner argument value.
 			NewOwner string
 		}
+		// ChownBucket holds details about calls to the ChownBucket method.
+		ChownBucket []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// UID is the uid argument value.
+			UID int
+			// Gid is the gid argument value.
+			Gid int
+		}
 		// CompleteMultipartUpload holds details about calls to the CompleteMultipartUpload method.
 		CompleteMultipartUpload []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -410,6 +511,13 @@ type BackendMock struct {
 			// DeleteObjectsInput is the deleteObjectsInput argument value.
 			DeleteObjectsInput *s3.DeleteObjectsInput
 		}
+		// DeletePublicAccessBlock holds details about calls to the DeletePublicAccessBlock method.
+		DeletePublicAccessBlock []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+		}
 		// GetBucketAcl holds details about calls to the GetBucketAcl method.
 		GetBucketAcl []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -417,6 +525,20 @@ type BackendMock struct {
 			// GetBucketAclInput is the getBucketAclInput argument value.
 			GetBucketAclInput *s3.GetBucketAclInput
 		}
+		// GetBucketLogging holds details about calls to the GetBucketLogging method.
+		GetBucketLogging []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+		}
+		// GetBucketNotificationConfiguration holds details about calls to the GetBucketNotificationConfiguration method.
+		GetBucketNotificationConfiguration []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+		}
 		// GetBucketPolicy holds details about calls to the GetBucketPolicy method.
 		GetBucketPolicy []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -424,6 +546,13 @@ type BackendMock struct {
 			// Bucket is the bucket argument value.
 			Bucket string
 		}
+		// GetBucketQuota holds details about calls to the GetBucketQuota method.
+		GetBucketQuota []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+		}
 		// GetBucketTagging holds details about calls to the GetBucketTagging method.
 		GetBucketTagging []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -431,6 +560,13 @@ type BackendMock struct {
 			// Bucket is the bucket argument value.
 			Bucket string
 		}
+		// GetBucketUsage holds details about calls to the GetBucketUsage method.
+		GetBucketUsage []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+		}
 		// GetBucketVersioning holds details about calls to the GetBucketVersioning method.
 		GetBucketVersioning []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -499,6 +635,18 @@ type BackendMock struct {
 			// Object is the object argument value.
 			Object string
 		}
+		// GetPublicAccessBlock holds details about calls to the GetPublicAccessBlock method.
+		GetPublicAccessBlock []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+		}
+		// GetScrubStatus holds details about calls to the GetScrubStatus method.
+		GetScrubStatus []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+		}
 		// HeadBucket holds details about calls to the HeadBucket method.
 		HeadBucket []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -562,6 +710,22 @@ type BackendMock struct {
 			// ListPartsInput is the listPartsInput argument value.
 			ListPartsInput *s3.ListPartsInput
 		}
+		// ListTrash holds details about calls to the ListTrash method.
+		ListTrash []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+		}
+		// PurgeTrash holds details about calls to the PurgeTrash method.
+		PurgeTrash []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// ID is the id argument value.
+			ID string
+		}
 		// PutBucketAcl holds details about calls to the PutBucketAcl method.
 		PutBucketAcl []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -571,6 +735,24 @@ type BackendMock struct {
 			// Data is the data argument value.
 			Data []byte
 		}
+		// PutBucketLogging holds details about calls to the PutBucketLogging method.
+		PutBucketLogging []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Config is the config argument value.
+			Config []byte
+		}
+		// PutBucketNotificationConfiguration holds details about calls to the PutBucketNotificationConfiguration method.
+		PutBucketNotificationConfiguration []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Config is the config argument value.
+			Config []byte
+		}
 		// PutBucketPolicy holds details about calls to the PutBucketPolicy method.
 		PutBucketPolicy []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -580,6 +762,15 @@ type BackendMock struct {
 			// Policy is the policy argument value.
 			Policy []byte
 		}
+		// PutBucketQuota holds details about calls to the PutBucketQuota method.
+		PutBucketQuota []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Quota is the quota argument value.
+			Quota s3response.BucketQuota
+		}
 		// PutBucketTagging holds details about calls to the PutBucketTagging method.
 		PutBucketTagging []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -607,8 +798,12 @@ type BackendMock struct {
 		PutObjectAcl []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
 			ContextMoqParam context.Context
-			// PutObjectAclInput is the putObjectAclInput argument value.
-			PutObjectAclInput *s3.PutObjectAclInput
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Object is the object argument value.
+			Object string
+			// Data is the data argument value.
+			Data []byte
 		}
 		// PutObjectLegalHold holds details about calls to the PutObjectLegalHold method.
 		PutObjectLegalHold []struct {
@@ -656,6 +851,15 @@ type BackendMock struct {
 			// Tags is the tags argument value.
 			Tags map[string]string
 		}
+		// PutPublicAccessBlock holds details about calls to the PutPublicAccessBlock method.
+		PutPublicAccessBlock []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Config is the config argument value.
+			Config []byte
+		}
 		// RestoreObject holds details about calls to the RestoreObject method.
 		RestoreObject []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
@@ -663,6 +867,15 @@ type BackendMock struct {
 			// RestoreObjectInput is the restoreObjectInput argument value.
 			RestoreObjectInput *s3.RestoreObjectInput
 		}
+		// RestoreTrash holds details about calls to the RestoreTrash method.
+		RestoreTrash []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// ID is the id argument value.
+			ID string
+		}
 		// SelectObjectContent holds details about calls to the SelectObjectContent method.
 		SelectObjectContent []struct {
 			// Ctx is the ctx argument value.
@@ -691,54 +904,69 @@ type BackendMock struct {
 			UploadPartCopyInput *s3.UploadPartCopyInput
 		}
 	}
-	lockAbortMultipartUpload       sync.RWMutex
-	lockChangeBucketOwner          sync.RWMutex
-	lockCompleteMultipartUpload    sync.RWMutex
-	lockCopyObject                 sync.RWMutex
-	lockCreateBucket               sync.RWMutex
-	lockCreateMultipartUpload      sync.RWMutex
-	lockDeleteBucket               sync.RWMutex
-	lockDeleteBucketPolicy         sync.RWMutex
-	lockDeleteBucketTagging        sync.RWMutex
-	lockDeleteObject               sync.RWMutex
-	lockDeleteObjectTagging        sync.RWMutex
-	lockDeleteObjects              sync.RWMutex
-	lockGetBucketAcl               sync.RWMutex
-	lockGetBucketPolicy            sync.RWMutex
-	lockGetBucketTagging           sync.RWMutex
-	lockGetBucketVersioning        sync.RWMutex
-	lockGetObject                  sync.RWMutex
-	lockGetObjectAcl               sync.RWMutex
-	lockGetObjectAttributes        sync.RWMutex
-	lockGetObjectLegalHold         sync.RWMutex
-	lockGetObjectLockConfiguration sync.RWMutex
-	lockGetObjectRetention         sync.RWMutex
-	lockGetObjectTagging           sync.RWMutex
-	lockHeadBucket                 sync.RWMutex
-	lockHeadObject                 sync.RWMutex
-	lockListBuckets                sync.RWMutex
-	lockListBucketsAndOwners       sync.RWMutex
-	lockListMultipartUploads       sync.RWMutex
-	lockListObjectVersions         sync.RWMutex
-	lockListObjects                sync.RWMutex
-	lockListObjectsV2              sync.RWMutex
-	lockListParts                  sync.RWMutex
-	lockPutBucketAcl               sync.RWMutex
-	lockPutBucketPolicy            sync.RWMutex
-	lockPutBucketTagging           sync.RWMutex
-	lockPutBucketVersioning        sync.RWMutex
-	lockPutObject                  sync.RWMutex
-	lockPutObjectAcl               sync.RWMutex
-	lockPutObjectLegalHold         sync.RWMutex
-	lockPutObjectLockConfiguration sync.RWMutex
-	lockPutObjectRetention         sync.RWMutex
-	lockPutObjectTagging           sync.RWMutex
-	lockRestoreObject              sync.RWMutex
-	lockSelectObjectContent        sync.RWMutex
-	lockShutdown                   sync.RWMutex
-	lockString                     sync.RWMutex
-	lockUploadPart                 sync.RWMutex
-	lockUploadPartCopy             sync.RWMutex
+	lockAbortMultipartUpload               sync.RWMutex
+	lockChangeBucketOwner                  sync.RWMutex
+	lockChownBucket                        sync.RWMutex
+	lockCompleteMultipartUpload            sync.RWMutex
+	lockCopyObject                         sync.RWMutex
+	lockCreateBucket                       sync.RWMutex
+	lockCreateMultipartUpload              sync.RWMutex
+	lockDeleteBucket                       sync.RWMutex
+	lockDeleteBucketPolicy                 sync.RWMutex
+	lockDeleteBucketTagging                sync.RWMutex
+	lockDeleteObject                       sync.RWMutex
+	lockDeleteObjectTagging                sync.RWMutex
+	lockDeleteObjects                      sync.RWMutex
+	lockDeletePublicAccessBlock            sync.RWMutex
+	lockGetBucketAcl                       sync.RWMutex
+	lockGetBucketLogging                   sync.RWMutex
+	lockGetBucketNotificationConfiguration sync.RWMutex
+	lockGetBucketPolicy                    sync.RWMutex
+	lockGetBucketQuota                     sync.RWMutex
+	lockGetBucketTagging                   sync.RWMutex
+	lockGetBucketUsage                     sync.RWMutex
+	lockGetBucketVersioning                sync.RWMutex
+	lockGetObject                          sync.RWMutex
+	lockGetObjectAcl                       sync.RWMutex
+	lockGetObjectAttributes                sync.RWMutex
+	lockGetObjectLegalHold                 sync.RWMutex
+	lockGetObjectLockConfiguration         sync.RWMutex
+	lockGetObjectRetention                 sync.RWMutex
+	lockGetObjectTagging                   sync.RWMutex
+	lockGetPublicAccessBlock               sync.RWMutex
+	lockGetScrubStatus                     sync.RWMutex
+	lockHeadBucket                         sync.RWMutex
+	lockHeadObject                         sync.RWMutex
+	lockListBuckets                        sync.RWMutex
+	lockListBucketsAndOwners               sync.RWMutex
+	lockListMultipartUploads               sync.RWMutex
+	lockListObjectVersions                 sync.RWMutex
+	lockListObjects                        sync.RWMutex
+	lockListObjectsV2                      sync.RWMutex
+	lockListParts                          sync.RWMutex
+	lockListTrash                          sync.RWMutex
+	lockPurgeTrash                         sync.RWMutex
+	lockPutBucketAcl                       sync.RWMutex
+	lockPutBucketLogging                   sync.RWMutex
+	lockPutBucketNotificationConfiguration sync.RWMutex
+	lockPutBucketPolicy                    sync.RWMutex
+	lockPutBucketQuota                     sync.RWMutex
+	lockPutBucketTagging                   sync.RWMutex
+	lockPutBucketVersioning                sync.RWMutex
+	lockPutObject                          sync.RWMutex
+	lockPutObjectAcl                       sync.RWMutex
+	lockPutObjectLegalHold                 sync.RWMutex
+	lockPutObjectLockConfiguration         sync.RWMutex
+	lockPutObjectRetention                 sync.RWMutex
+	lockPutObjectTagging                   sync.RWMutex
+	lockPutPublicAccessBlock               sync.RWMutex
+	lockRestoreObject                      sync.RWMutex
+	lockRestoreTrash                       sync.RWMutex
+	lockSelectObjectContent                sync.RWMutex
+	lockShutdown                           sync.RWMutex
+	lockString                             sync.RWMutex
+	lockUploadPart                         sync.RWMutex
+	lockUploadPartCopy                     sync.RWMutex
 }
 
 // AbortMultipartUpload calls AbortMultipartUploadFunc.
@@ -817,6 +1045,50 @@ func (mock *BackendMock) ChangeBucketOwnerCalls() []struct {
 	return calls
 }
 
+// ChownBucket calls ChownBucketFunc.
+func (mock *BackendMock) ChownBucket(contextMoqParam context.Context, bucket string, uid int, gid int) error {
+	if mock.ChownBucketFunc == nil {
+		panic("BackendMock.ChownBucketFunc: method is nil but Backend.ChownBucket was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		UID             int
+		Gid             int
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+		UID:             uid,
+		Gid:             gid,
+	}
+	mock.lockChownBucket.Lock()
+	mock.calls.ChownBucket = append(mock.calls.ChownBucket, callInfo)
+	mock.lockChownBucket.Unlock()
+	return mock.ChownBucketFunc(contextMoqParam, bucket, uid, gid)
+}
+
+// ChownBucketCalls gets all the calls that were made to ChownBucket.
+// Check the length with:
+//
+//	len(mockedBackend.ChownBucketCalls())
+func (mock *BackendMock) ChownBucketCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+	UID             int
+	Gid             int
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		UID             int
+		Gid             int
+	}
+	mock.lockChownBucket.RLock()
+	calls = mock.calls.ChownBucket
+	mock.lockChownBucket.RUnlock()
+	return calls
+}
+
 // CompleteMultipartUpload calls CompleteMultipartUploadFunc.
 func (mock *BackendMock) CompleteMultipartUpload(contextMoqParam context.Context, completeMultipartUploadInput *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
 	if mock.CompleteMultipartUploadFunc == nil {
@@ -1185,6 +1457,42 @@ func (mock *BackendMock) DeleteObjectsCalls() []struct {
 	return calls
 }
 
+// DeletePublicAccessBlock calls DeletePublicAccessBlockFunc.
+func (mock *BackendMock) DeletePublicAccessBlock(contextMoqParam context.Context, bucket string) error {
+	if mock.DeletePublicAccessBlockFunc == nil {
+		panic("BackendMock.DeletePublicAccessBlockFunc: method is nil but Backend.DeletePublicAccessBlock was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+	}
+	mock.lockDeletePublicAccessBlock.Lock()
+	mock.calls.DeletePublicAccessBlock = append(mock.calls.DeletePublicAccessBlock, callInfo)
+	mock.lockDeletePublicAccessBlock.Unlock()
+	return mock.DeletePublicAccessBlockFunc(contextMoqParam, bucket)
+}
+
+// DeletePublicAccessBlockCalls gets all the calls that were made to DeletePublicAccessBlock.
+// Check the length with:
+//
+//	len(mockedBackend.DeletePublicAccessBlockCalls())
+func (mock *BackendMock) DeletePublicAccessBlockCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}
+	mock.lockDeletePublicAccessBlock.RLock()
+	calls = mock.calls.DeletePublicAccessBlock
+	mock.lockDeletePublicAccessBlock.RUnlock()
+	return calls
+}
+
 // GetBucketAcl calls GetBucketAclFunc.
 func (mock *BackendMock) GetBucketAcl(contextMoqParam context.Context, getBucketAclInput *s3.GetBucketAclInput) ([]byte, error) {
 	if mock.GetBucketAclFunc == nil {
@@ -1221,6 +1529,78 @@ func (mock *BackendMock) GetBucketAclCalls() []struct {
 	return calls
 }
 
+// GetBucketLogging calls GetBucketLoggingFunc.
+func (mock *BackendMock) GetBucketLogging(contextMoqParam context.Context, bucket string) ([]byte, error) {
+	if mock.GetBucketLoggingFunc == nil {
+		panic("BackendMock.GetBucketLoggingFunc: method is nil but Backend.GetBucketLogging was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+	}
+	mock.lockGetBucketLogging.Lock()
+	mock.calls.GetBucketLogging = append(mock.calls.GetBucketLogging, callInfo)
+	mock.lockGetBucketLogging.Unlock()
+	return mock.GetBucketLoggingFunc(contextMoqParam, bucket)
+}
+
+// GetBucketLoggingCalls gets all the calls that were made to GetBucketLogging.
+// Check the length with:
+//
+//	len(mockedBackend.GetBucketLoggingCalls())
+func (mock *BackendMock) GetBucketLoggingCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}
+	mock.lockGetBucketLogging.RLock()
+	calls = mock.calls.GetBucketLogging
+	mock.lockGetBucketLogging.RUnlock()
+	return calls
+}
+
+// GetBucketNotificationConfiguration calls GetBucketNotificationConfigurationFunc.
+func (mock *BackendMock) GetBucketNotificationConfiguration(contextMoqParam context.Context, bucket string) ([]byte, error) {
+	if mock.GetBucketNotificationConfigurationFunc == nil {
+		panic("BackendMock.GetBucketNotificationConfigurationFunc: method is nil but Backend.GetBucketNotificationConfiguration was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+	}
+	mock.lockGetBucketNotificationConfiguration.Lock()
+	mock.calls.GetBucketNotificationConfiguration = append(mock.calls.GetBucketNotificationConfiguration, callInfo)
+	mock.lockGetBucketNotificationConfiguration.Unlock()
+	return mock.GetBucketNotificationConfigurationFunc(contextMoqParam, bucket)
+}
+
+// GetBucketNotificationConfigurationCalls gets all the calls that were made to GetBucketNotificationConfiguration.
+// Check the length with:
+//
+//	len(mockedBackend.GetBucketNotificationConfigurationCalls())
+func (mock *BackendMock) GetBucketNotificationConfigurationCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}
+	mock.lockGetBucketNotificationConfiguration.RLock()
+	calls = mock.calls.GetBucketNotificationConfiguration
+	mock.lockGetBucketNotificationConfiguration.RUnlock()
+	return calls
+}
+
 // GetBucketPolicy calls GetBucketPolicyFunc.
 func (mock *BackendMock) GetBucketPolicy(contextMoqParam context.Context, bucket string) ([]byte, error) {
 	if mock.GetBucketPolicyFunc == nil {
@@ -1257,6 +1637,42 @@ func (mock *BackendMock) GetBucketPolicyCalls() []struct {
 	return calls
 }
 
+// GetBucketQuota calls GetBucketQuotaFunc.
+func (mock *BackendMock) GetBucketQuota(contextMoqParam context.Context, bucket string) (s3response.BucketQuota, error) {
+	if mock.GetBucketQuotaFunc == nil {
+		panic("BackendMock.GetBucketQuotaFunc: method is nil but Backend.GetBucketQuota was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+	}
+	mock.lockGetBucketQuota.Lock()
+	mock.calls.GetBucketQuota = append(mock.calls.GetBucketQuota, callInfo)
+	mock.lockGetBucketQuota.Unlock()
+	return mock.GetBucketQuotaFunc(contextMoqParam, bucket)
+}
+
+// GetBucketQuotaCalls gets all the calls that were made to GetBucketQuota.
+// Check the length with:
+//
+//	len(mockedBackend.GetBucketQuotaCalls())
+func (mock *BackendMock) GetBucketQuotaCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}
+	mock.lockGetBucketQuota.RLock()
+	calls = mock.calls.GetBucketQuota
+	mock.lockGetBucketQuota.RUnlock()
+	return calls
+}
+
 // GetBucketTagging calls GetBucketTaggingFunc.
 func (mock *BackendMock) GetBucketTagging(contextMoqParam context.Context, bucket string) (map[string]string, error) {
 	if mock.GetBucketTaggingFunc == nil {
@@ -1293,8 +1709,44 @@ func (mock *BackendMock) GetBucketTaggingCalls() []struct {
 	return calls
 }
 
+// GetBucketUsage calls GetBucketUsageFunc.
+func (mock *BackendMock) GetBucketUsage(contextMoqParam context.Context, bucket string) (s3response.BucketUsage, error) {
+	if mock.GetBucketUsageFunc == nil {
+		panic("BackendMock.GetBucketUsageFunc: method is nil but Backend.GetBucketUsage was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+	}
+	mock.lockGetBucketUsage.Lock()
+	mock.calls.GetBucketUsage = append(mock.calls.GetBucketUsage, callInfo)
+	mock.lockGetBucketUsage.Unlock()
+	return mock.GetBucketUsageFunc(contextMoqParam, bucket)
+}
+
+// GetBucketUsageCalls gets all the calls that were made to GetBucketUsage.
+// Check the length with:
+//
+//	len(mockedBackend.GetBucketUsageCalls())
+func (mock *BackendMock) GetBucketUsageCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}
+	mock.lockGetBucketUsage.RLock()
+	calls = mock.calls.GetBucketUsage
+	mock.lockGetBucketUsage.RUnlock()
+	return calls
+}
+
 // GetBucketVersioning calls GetBucketVersioningFunc.
-func (mock *BackendMock) GetBucketVersioning(contextMoqParam context.Context, bucket string) (*s3.GetBucketVersioningOutput, error) {
+func (mock *BackendMock) GetBucketVersioning(contextMoqParam context.Context, bucket string) (s3response.GetBucketVersioningOutput, error) {
 	if mock.GetBucketVersioningFunc == nil {
 		panic("BackendMock.GetBucketVersioningFunc: method is nil but Backend.GetBucketVersioning was just called")
 	}
@@ -1370,7 +1822,7 @@ func (mock *BackendMock) GetObjectCalls() []struct {
 }
 
 // GetObjectAcl calls GetObjectAclFunc.
-func (mock *BackendMock) GetObjectAcl(contextMoqParam context.Context, getObjectAclInput *s3.GetObjectAclInput) (*s3.GetObjectAclOutput, error) {
+func (mock *BackendMock) GetObjectAcl(contextMoqParam context.Context, getObjectAclInput *s3.GetObjectAclInput) ([]byte, error) {
 	if mock.GetObjectAclFunc == nil {
 		panic("BackendMock.GetObjectAclFunc: method is nil but Backend.GetObjectAcl was just called")
 	}
@@ -1605,6 +2057,74 @@ func (mock *BackendMock) GetObjectTaggingCalls() []struct {
 	return calls
 }
 
+// GetPublicAccessBlock calls GetPublicAccessBlockFunc.
+func (mock *BackendMock) GetPublicAccessBlock(contextMoqParam context.Context, bucket string) ([]byte, error) {
+	if mock.GetPublicAccessBlockFunc == nil {
+		panic("BackendMock.GetPublicAccessBlockFunc: method is nil but Backend.GetPublicAccessBlock was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+	}
+	mock.lockGetPublicAccessBlock.Lock()
+	mock.calls.GetPublicAccessBlock = append(mock.calls.GetPublicAccessBlock, callInfo)
+	mock.lockGetPublicAccessBlock.Unlock()
+	return mock.GetPublicAccessBlockFunc(contextMoqParam, bucket)
+}
+
+// GetPublicAccessBlockCalls gets all the calls that were made to GetPublicAccessBlock.
+// Check the length with:
+//
+//	len(mockedBackend.GetPublicAccessBlockCalls())
+func (mock *BackendMock) GetPublicAccessBlockCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}
+	mock.lockGetPublicAccessBlock.RLock()
+	calls = mock.calls.GetPublicAccessBlock
+	mock.lockGetPublicAccessBlock.RUnlock()
+	return calls
+}
+
+// GetScrubStatus calls GetScrubStatusFunc.
+func (mock *BackendMock) GetScrubStatus(contextMoqParam context.Context) (s3response.ScrubStatus, error) {
+	if mock.GetScrubStatusFunc == nil {
+		panic("BackendMock.GetScrubStatusFunc: method is nil but Backend.GetScrubStatus was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+	}{
+		ContextMoqParam: contextMoqParam,
+	}
+	mock.lockGetScrubStatus.Lock()
+	mock.calls.GetScrubStatus = append(mock.calls.GetScrubStatus, callInfo)
+	mock.lockGetScrubStatus.Unlock()
+	return mock.GetScrubStatusFunc(contextMoqParam)
+}
+
+// GetScrubStatusCalls gets all the calls that were made to GetScrubStatus.
+// Check the length with:
+//
+//	len(mockedBackend.GetScrubStatusCalls())
+func (mock *BackendMock) GetScrubStatusCalls() []struct {
+	ContextMoqParam context.Context
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+	}
+	mock.lockGetScrubStatus.RLock()
+	calls = mock.calls.GetScrubStatus
+	mock.lockGetScrubStatus.RUnlock()
+	return calls
+}
+
 // HeadBucket calls HeadBucketFunc.
 func (mock *BackendMock) HeadBucket(contextMoqParam context.Context, headBucketInput *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
 	if mock.HeadBucketFunc == nil {
@@ -1786,7 +2306,7 @@ func (mock *BackendMock) ListMultipartUploadsCalls() []struct {
 }
 
 // ListObjectVersions calls ListObjectVersionsFunc.
-func (mock *BackendMock) ListObjectVersions(contextMoqParam context.Context, listObjectVersionsInput *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+func (mock *BackendMock) ListObjectVersions(contextMoqParam context.Context, listObjectVersionsInput *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
 	if mock.ListObjectVersionsFunc == nil {
 		panic("BackendMock.ListObjectVersionsFunc: method is nil but Backend.ListObjectVersions was just called")
 	}
@@ -1929,6 +2449,82 @@ func (mock *BackendMock) ListPartsCalls() []struct {
 	return calls
 }
 
+// ListTrash calls ListTrashFunc.
+func (mock *BackendMock) ListTrash(contextMoqParam context.Context, bucket string) ([]s3response.TrashEntry, error) {
+	if mock.ListTrashFunc == nil {
+		panic("BackendMock.ListTrashFunc: method is nil but Backend.ListTrash was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+	}
+	mock.lockListTrash.Lock()
+	mock.calls.ListTrash = append(mock.calls.ListTrash, callInfo)
+	mock.lockListTrash.Unlock()
+	return mock.ListTrashFunc(contextMoqParam, bucket)
+}
+
+// ListTrashCalls gets all the calls that were made to ListTrash.
+// Check the length with:
+//
+//	len(mockedBackend.ListTrashCalls())
+func (mock *BackendMock) ListTrashCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+	}
+	mock.lockListTrash.RLock()
+	calls = mock.calls.ListTrash
+	mock.lockListTrash.RUnlock()
+	return calls
+}
+
+// PurgeTrash calls PurgeTrashFunc.
+func (mock *BackendMock) PurgeTrash(contextMoqParam context.Context, bucket string, id string) error {
+	if mock.PurgeTrashFunc == nil {
+		panic("BackendMock.PurgeTrashFunc: method is nil but Backend.PurgeTrash was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		ID              string
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+		ID:              id,
+	}
+	mock.lockPurgeTrash.Lock()
+	mock.calls.PurgeTrash = append(mock.calls.PurgeTrash, callInfo)
+	mock.lockPurgeTrash.Unlock()
+	return mock.PurgeTrashFunc(contextMoqParam, bucket, id)
+}
+
+// PurgeTrashCalls gets all the calls that were made to PurgeTrash.
+// Check the length with:
+//
+//	len(mockedBackend.PurgeTrashCalls())
+func (mock *BackendMock) PurgeTrashCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+	ID              string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		ID              string
+	}
+	mock.lockPurgeTrash.RLock()
+	calls = mock.calls.PurgeTrash
+	mock.lockPurgeTrash.RUnlock()
+	return calls
+}
+
 // PutBucketAcl calls PutBucketAclFunc.
 func (mock *BackendMock) PutBucketAcl(contextMoqParam context.Context, bucket string, data []byte) error {
 	if mock.PutBucketAclFunc == nil {
@@ -1969,6 +2565,86 @@ func (mock *BackendMock) PutBucketAclCalls() []struct {
 	return calls
 }
 
+// PutBucketLogging calls PutBucketLoggingFunc.
+func (mock *BackendMock) PutBucketLogging(contextMoqParam context.Context, bucket string, config []byte) error {
+	if mock.PutBucketLoggingFunc == nil {
+		panic("BackendMock.PutBucketLoggingFunc: method is nil but Backend.PutBucketLogging was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		Config          []byte
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+		Config:          config,
+	}
+	mock.lockPutBucketLogging.Lock()
+	mock.calls.PutBucketLogging = append(mock.calls.PutBucketLogging, callInfo)
+	mock.lockPutBucketLogging.Unlock()
+	return mock.PutBucketLoggingFunc(contextMoqParam, bucket, config)
+}
+
+// PutBucketLoggingCalls gets all the calls that were made to PutBucketLogging.
+// Check the length with:
+//
+//	len(mockedBackend.PutBucketLoggingCalls())
+func (mock *BackendMock) PutBucketLoggingCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+	Config          []byte
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		Config          []byte
+	}
+	mock.lockPutBucketLogging.RLock()
+	calls = mock.calls.PutBucketLogging
+	mock.lockPutBucketLogging.RUnlock()
+	return calls
+}
+
+// PutBucketNotificationConfiguration calls PutBucketNotificationConfigurationFunc.
+func (mock *BackendMock) PutBucketNotificationConfiguration(contextMoqParam context.Context, bucket string, config []byte) error {
+	if mock.PutBucketNotificationConfigurationFunc == nil {
+		panic("BackendMock.PutBucketNotificationConfigurationFunc: method is nil but Backend.PutBucketNotificationConfiguration was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		Config          []byte
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+		Config:          config,
+	}
+	mock.lockPutBucketNotificationConfiguration.Lock()
+	mock.calls.PutBucketNotificationConfiguration = append(mock.calls.PutBucketNotificationConfiguration, callInfo)
+	mock.lockPutBucketNotificationConfiguration.Unlock()
+	return mock.PutBucketNotificationConfigurationFunc(contextMoqParam, bucket, config)
+}
+
+// PutBucketNotificationConfigurationCalls gets all the calls that were made to PutBucketNotificationConfiguration.
+// Check the length with:
+//
+//	len(mockedBackend.PutBucketNotificationConfigurationCalls())
+func (mock *BackendMock) PutBucketNotificationConfigurationCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+	Config          []byte
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		Config          []byte
+	}
+	mock.lockPutBucketNotificationConfiguration.RLock()
+	calls = mock.calls.PutBucketNotificationConfiguration
+	mock.lockPutBucketNotificationConfiguration.RUnlock()
+	return calls
+}
+
 // PutBucketPolicy calls PutBucketPolicyFunc.
 func (mock *BackendMock) PutBucketPolicy(contextMoqParam context.Context, bucket string, policy []byte) error {
 	if mock.PutBucketPolicyFunc == nil {
@@ -2009,6 +2685,46 @@ func (mock *BackendMock) PutBucketPolicyCalls() []struct {
 	return calls
 }
 
+// PutBucketQuota calls PutBucketQuotaFunc.
+func (mock *BackendMock) PutBucketQuota(contextMoqParam context.Context, bucket string, quota s3response.BucketQuota) error {
+	if mock.PutBucketQuotaFunc == nil {
+		panic("BackendMock.PutBucketQuotaFunc: method is nil but Backend.PutBucketQuota was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		Quota           s3response.BucketQuota
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+		Quota:           quota,
+	}
+	mock.lockPutBucketQuota.Lock()
+	mock.calls.PutBucketQuota = append(mock.calls.PutBucketQuota, callInfo)
+	mock.lockPutBucketQuota.Unlock()
+	return mock.PutBucketQuotaFunc(contextMoqParam, bucket, quota)
+}
+
+// PutBucketQuotaCalls gets all the calls that were made to PutBucketQuota.
+// Check the length with:
+//
+//	len(mockedBackend.PutBucketQuotaCalls())
+func (mock *BackendMock) PutBucketQuotaCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+	Quota           s3response.BucketQuota
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		Quota           s3response.BucketQuota
+	}
+	mock.lockPutBucketQuota.RLock()
+	calls = mock.calls.PutBucketQuota
+	mock.lockPutBucketQuota.RUnlock()
+	return calls
+}
+
 // PutBucketTagging calls PutBucketTaggingFunc.
 func (mock *BackendMock) PutBucketTagging(contextMoqParam context.Context, bucket string, tags map[string]string) error {
 	if mock.PutBucketTaggingFunc == nil {
@@ -2122,21 +2838,25 @@ func (mock *BackendMock) PutObjectCalls() []struct {
 }
 
 // PutObjectAcl calls PutObjectAclFunc.
-func (mock *BackendMock) PutObjectAcl(contextMoqParam context.Context, putObjectAclInput *s3.PutObjectAclInput) error {
+func (mock *BackendMock) PutObjectAcl(contextMoqParam context.Context, bucket string, object string, data []byte) error {
 	if mock.PutObjectAclFunc == nil {
 		panic("BackendMock.PutObjectAclFunc: method is nil but Backend.PutObjectAcl was just called")
 	}
 	callInfo := struct {
-		ContextMoqParam   context.Context
-		PutObjectAclInput *s3.PutObjectAclInput
+		ContextMoqParam context.Context
+		Bucket          string
+		Object          string
+		Data            []byte
 	}{
-		ContextMoqParam:   contextMoqParam,
-		PutObjectAclInput: putObjectAclInput,
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+		Object:          object,
+		Data:            data,
 	}
 	mock.lockPutObjectAcl.Lock()
 	mock.calls.PutObjectAcl = append(mock.calls.PutObjectAcl, callInfo)
 	mock.lockPutObjectAcl.Unlock()
-	return mock.PutObjectAclFunc(contextMoqParam, putObjectAclInput)
+	return mock.PutObjectAclFunc(contextMoqParam, bucket, object, data)
 }
 
 // PutObjectAclCalls gets all the calls that were made to PutObjectAcl.
@@ -2144,12 +2864,16 @@ func (mock *BackendMock) PutObjectAcl(contextMoqParam context.Context, putObject
 //
 //	len(mockedBackend.PutObjectAclCalls())
 func (mock *BackendMock) PutObjectAclCalls() []struct {
-	ContextMoqParam   context.Context
-	PutObjectAclInput *s3.PutObjectAclInput
+	ContextMoqParam context.Context
+	Bucket          string
+	Object          string
+	Data            []byte
 } {
 	var calls []struct {
-		ContextMoqParam   context.Context
-		PutObjectAclInput *s3.PutObjectAclInput
+		ContextMoqParam context.Context
+		Bucket          string
+		Object          string
+		Data            []byte
 	}
 	mock.lockPutObjectAcl.RLock()
 	calls = mock.calls.PutObjectAcl
@@ -2337,6 +3061,46 @@ func (mock *BackendMock) PutObjectTaggingCalls() []struct {
 	return calls
 }
 
+// PutPublicAccessBlock calls PutPublicAccessBlockFunc.
+func (mock *BackendMock) PutPublicAccessBlock(contextMoqParam context.Context, bucket string, config []byte) error {
+	if mock.PutPublicAccessBlockFunc == nil {
+		panic("BackendMock.PutPublicAccessBlockFunc: method is nil but Backend.PutPublicAccessBlock was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		Config          []byte
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+		Config:          config,
+	}
+	mock.lockPutPublicAccessBlock.Lock()
+	mock.calls.PutPublicAccessBlock = append(mock.calls.PutPublicAccessBlock, callInfo)
+	mock.lockPutPublicAccessBlock.Unlock()
+	return mock.PutPublicAccessBlockFunc(contextMoqParam, bucket, config)
+}
+
+// PutPublicAccessBlockCalls gets all the calls that were made to PutPublicAccessBlock.
+// Check the length with:
+//
+//	len(mockedBackend.PutPublicAccessBlockCalls())
+func (mock *BackendMock) PutPublicAccessBlockCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+	Config          []byte
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		Config          []byte
+	}
+	mock.lockPutPublicAccessBlock.RLock()
+	calls = mock.calls.PutPublicAccessBlock
+	mock.lockPutPublicAccessBlock.RUnlock()
+	return calls
+}
+
 // RestoreObject calls RestoreObjectFunc.
 func (mock *BackendMock) RestoreObject(contextMoqParam context.Context, restoreObjectInput *s3.RestoreObjectInput) error {
 	if mock.RestoreObjectFunc == nil {
@@ -2373,6 +3137,46 @@ func (mock *BackendMock) RestoreObjectCalls() []struct {
 	return calls
 }
 
+// RestoreTrash calls RestoreTrashFunc.
+func (mock *BackendMock) RestoreTrash(contextMoqParam context.Context, bucket string, id string) error {
+	if mock.RestoreTrashFunc == nil {
+		panic("BackendMock.RestoreTrashFunc: method is nil but Backend.RestoreTrash was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		ID              string
+	}{
+		ContextMoqParam: contextMoqParam,
+		Bucket:          bucket,
+		ID:              id,
+	}
+	mock.lockRestoreTrash.Lock()
+	mock.calls.RestoreTrash = append(mock.calls.RestoreTrash, callInfo)
+	mock.lockRestoreTrash.Unlock()
+	return mock.RestoreTrashFunc(contextMoqParam, bucket, id)
+}
+
+// RestoreTrashCalls gets all the calls that were made to RestoreTrash.
+// Check the length with:
+//
+//	len(mockedBackend.RestoreTrashCalls())
+func (mock *BackendMock) RestoreTrashCalls() []struct {
+	ContextMoqParam context.Context
+	Bucket          string
+	ID              string
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+		Bucket          string
+		ID              string
+	}
+	mock.lockRestoreTrash.RLock()
+	calls = mock.calls.RestoreTrash
+	mock.lockRestoreTrash.RUnlock()
+	return calls
+}
+
 // SelectObjectContent calls SelectObjectContentFunc.
 func (mock *BackendMock) SelectObjectContent(ctx context.Context, input *s3.SelectObjectContentInput) func(w *bufio.Writer) {
 	if mock.SelectObjectContentFunc == nil {
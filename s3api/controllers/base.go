@@ -16,12 +16,14 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +33,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/kms"
 	"github.com/versity/versitygw/s3api/utils"
 	"github.com/versity/versitygw/s3err"
 	"github.com/versity/versitygw/s3event"
@@ -43,20 +46,20 @@ type S3ApiController struct {
 	iam      auth.IAMService
 	logger   s3log.AuditLogger
 	evSender s3event.S3EventSender
+	kms      kms.Provider
+	quota    *auth.QuotaTracker
 	debug    bool
 	readonly bool
 }
 
-const (
-	iso8601Format = "20060102T150405Z"
-)
-
-func New(be backend.Backend, iam auth.IAMService, logger s3log.AuditLogger, evs s3event.S3EventSender, debug bool, readonly bool) S3ApiController {
+func New(be backend.Backend, iam auth.IAMService, logger s3log.AuditLogger, evs s3event.S3EventSender, kmsProvider kms.Provider, quota *auth.QuotaTracker, debug bool, readonly bool) S3ApiController {
 	return S3ApiController{
 		be:       be,
 		iam:      iam,
 		logger:   logger,
 		evSender: evs,
+		kms:      kmsProvider,
+		quota:    quota,
 		debug:    debug,
 		readonly: readonly,
 	}
@@ -64,6 +67,13 @@ func New(be backend.Backend, iam auth.IAMService, logger s3log.AuditLogger, evs
 
 func (c S3ApiController) ListBuckets(ctx *fiber.Ctx) error {
 	acct := ctx.Locals("account").(auth.Account)
+	if acct.IsAnonymous() {
+		return SendXMLResponse(ctx, nil, s3err.GetAPIError(s3err.ErrAccessDenied),
+			&MetaOpts{
+				Logger: c.logger,
+				Action: "ListBucket",
+			})
+	}
 	res, err := c.be.ListBuckets(ctx.Context(), acct.Access, acct.Role == "admin")
 	return SendXMLResponse(ctx, res, err,
 		&MetaOpts{
@@ -165,6 +175,10 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 		}
 
 		retention, err := auth.ParseObjectLockRetentionOutput(data)
+		if err == nil && retention.Mode == "" {
+			// the object retention was removed
+			err = s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)
+		}
 		return SendXMLResponse(ctx, retention, err,
 			&MetaOpts{
 				Logger:      c.logger,
@@ -268,7 +282,7 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 	}
 
 	if ctx.Request().URI().QueryArgs().Has("acl") {
-		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+		err := auth.VerifyObjectAccess(ctx.Context(), c.be, auth.AccessOptions{
 			Readonly:      c.readonly,
 			Acl:           parsedAcl,
 			AclPermission: types.PermissionReadAcp,
@@ -286,10 +300,20 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 					BucketOwner: parsedAcl.Owner,
 				})
 		}
-		res, err := c.be.GetObjectAcl(ctx.Context(), &s3.GetObjectAclInput{
+		data, err := c.be.GetObjectAcl(ctx.Context(), &s3.GetObjectAclInput{
 			Bucket: &bucket,
 			Key:    &key,
 		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetObjectAcl",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		res, err := auth.ParseObjectACLOutput(data)
 		return SendXMLResponse(ctx, res, err,
 			&MetaOpts{
 				Logger:      c.logger,
@@ -354,7 +378,7 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 			})
 	}
 
-	err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+	err := auth.VerifyObjectAccess(ctx.Context(), c.be, auth.AccessOptions{
 		Readonly:      c.readonly,
 		Acl:           parsedAcl,
 		AclPermission: types.PermissionRead,
@@ -373,14 +397,58 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	var partNumber *int32
+	if ctx.Request().URI().QueryArgs().Has("partNumber") {
+		partNumberQuery := int32(ctx.QueryInt("partNumber", -1))
+		if partNumberQuery < 1 || partNumberQuery > 10000 {
+			if c.debug {
+				log.Printf("invalid part number: %d", partNumberQuery)
+			}
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidPartNumber),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+		if acceptRange != "" {
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrRangeWithPartNumber),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		partNumber = &partNumberQuery
+	}
+
+	var w io.Writer = ctx.Response().BodyWriter()
+	if c.kms != nil {
+		w = c.kmsDecryptWriter(ctx, bucket, key, versionId, acceptRange, w)
+	}
+
+	conditions := utils.ParseConditionalHeaders(ctx, "")
+
+	var rng *string
+	if acceptRange != "" {
+		rng = &acceptRange
+	}
+
 	ctx.Locals("logResBody", false)
 	res, err := c.be.GetObject(ctx.Context(), &s3.GetObjectInput{
-		Bucket:    &bucket,
-		Key:       &key,
-		Range:     &acceptRange,
-		VersionId: &versionId,
-	}, ctx.Response().BodyWriter())
+		Bucket:            &bucket,
+		Key:               &key,
+		Range:             rng,
+		PartNumber:        partNumber,
+		VersionId:         &versionId,
+		IfMatch:           conditions.IfMatch,
+		IfNoneMatch:       conditions.IfNoneMatch,
+		IfModifiedSince:   conditions.IfModifiedSince,
+		IfUnmodifiedSince: conditions.IfUnmodifiedSince,
+	}, w)
 	if err != nil {
+		c.setUnsatisfiableRange(ctx, bucket, key, versionId, err)
 		return SendResponse(ctx, err,
 			&MetaOpts{
 				Logger:      c.logger,
@@ -397,7 +465,14 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	setSSEHeaders(ctx, res.Metadata)
 	utils.SetMetaHeaders(ctx, res.Metadata)
+	setObjectHeaders(ctx, objectHeaders{
+		CacheControl:       res.CacheControl,
+		ContentDisposition: res.ContentDisposition,
+		ContentLanguage:    res.ContentLanguage,
+		Expires:            res.Expires,
+	})
 	var lastmod string
 	if res.LastModified != nil {
 		lastmod = res.LastModified.Format(timefmt)
@@ -446,13 +521,329 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 			},
 		})
 	}
+	if res.PartsCount != nil {
+		utils.SetResponseHeaders(ctx, []utils.CustomHeader{
+			{
+				Key:   "x-amz-mp-parts-count",
+				Value: fmt.Sprint(*res.PartsCount),
+			},
+		})
+	}
+
+	status := http.StatusOK
+	if getstring(res.ContentRange) != "" {
+		status = http.StatusPartialContent
+	}
 
 	return SendResponse(ctx, err,
 		&MetaOpts{
 			Logger:      c.logger,
 			Action:      "GetObject",
 			BucketOwner: parsedAcl.Owner,
+			Status:      status,
+		})
+}
+
+// kmsEncryptBody wraps the request body with SSE-KMS encryption when
+// requested, and adds the wrapped data key to the object metadata
+func (c S3ApiController) kmsEncryptBody(ctx *fiber.Ctx, body io.Reader, metadata map[string]string) (io.Reader, error) {
+	if ctx.Get("X-Amz-Server-Side-Encryption") != string(types.ServerSideEncryptionAwsKms) {
+		return body, nil
+	}
+	if c.kms == nil {
+		return nil, s3err.GetAPIError(s3err.ErrKMSNotConfigured)
+	}
+
+	keyID := ctx.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id")
+	if keyID == "" {
+		keyID = c.kms.DefaultKeyID()
+	}
+	if keyID == "" {
+		return nil, s3err.GetAPIError(s3err.ErrKMSKeyNotFound)
+	}
+
+	r, meta, err := kms.EncryptObject(ctx.Context(), c.kms, keyID, body)
+	if errors.Is(err, kms.ErrKeyNotFound) {
+		return nil, s3err.GetAPIError(s3err.ErrKMSKeyNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kms encrypt: %w", err)
+	}
+
+	for k, v := range meta {
+		metadata[k] = v
+	}
+
+	return r, nil
+}
+
+// setUnsatisfiableRange sets the Content-Range header of a 416 response to
+// the current object size when err rejects the requested range
+func (c S3ApiController) setUnsatisfiableRange(ctx *fiber.Ctx, bucket, key, versionId string, err error) {
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrInvalidRange)) {
+		return
+	}
+
+	res, err := c.be.HeadObject(ctx.Context(),
+		&s3.HeadObjectInput{
+			Bucket:    &bucket,
+			Key:       &key,
+			VersionId: &versionId,
 		})
+	if err != nil || res == nil || res.ContentLength == nil {
+		return
+	}
+
+	ctx.Set("Content-Range",
+		backend.UnsatisfiableContentRange(*res.ContentLength))
+}
+
+// kmsDecrypter decrypts the object data written by GetObject when the
+// object was stored with SSE-KMS. The backend passes the object metadata
+// before writing the data, the metadata is read with HeadObject on the
+// first write for backends that do not.
+type kmsDecrypter struct {
+	ctx         context.Context
+	be          backend.Backend
+	kms         kms.Provider
+	bucket      string
+	key         string
+	versionId   string
+	acceptRange string
+	w           io.Writer
+	// dw is the writer of the object data once the metadata is known
+	dw io.Writer
+}
+
+var _ backend.ObjectMetadataWriter = &kmsDecrypter{}
+
+// kmsDecryptWriter wraps w to decrypt the object data when the object was
+// stored with SSE-KMS
+func (c S3ApiController) kmsDecryptWriter(ctx *fiber.Ctx, bucket, key, versionId, acceptRange string, w io.Writer) io.Writer {
+	return &kmsDecrypter{
+		ctx:         ctx.Context(),
+		be:          c.be,
+		kms:         c.kms,
+		bucket:      bucket,
+		key:         key,
+		versionId:   versionId,
+		acceptRange: acceptRange,
+		w:           w,
+	}
+}
+
+func (d *kmsDecrypter) SetObjectMetadata(meta map[string]string, offset int64) error {
+	if !kms.IsEncrypted(meta) {
+		d.dw = d.w
+		return nil
+	}
+
+	dw, err := kms.DecryptWriter(d.ctx, d.kms, meta, d.w, offset)
+	if errors.Is(err, kms.ErrKeyNotFound) {
+		return s3err.GetAPIError(s3err.ErrKMSKeyNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("kms decrypt: %w", err)
+	}
+
+	d.dw = dw
+	return nil
+}
+
+func (d *kmsDecrypter) Write(p []byte) (int, error) {
+	if d.dw == nil {
+		res, err := d.be.HeadObject(d.ctx,
+			&s3.HeadObjectInput{
+				Bucket:    &d.bucket,
+				Key:       &d.key,
+				VersionId: &d.versionId,
+			})
+		if err != nil {
+			return 0, err
+		}
+
+		offset, _, err := backend.ParseRange(getint64(res.ContentLength), d.acceptRange)
+		if err != nil {
+			return 0, err
+		}
+
+		err = d.SetObjectMetadata(res.Metadata, offset)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return d.dw.Write(p)
+}
+
+// kmsCopySourceMetadata returns the metadata of the copy source object
+func (c S3ApiController) kmsCopySourceMetadata(ctx *fiber.Ctx, copySource string) (map[string]string, error) {
+	srcBucket, srcObject, versionId, err := backend.ParseCopySource(&copySource)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.be.HeadObject(ctx.Context(),
+		&s3.HeadObjectInput{
+			Bucket:    &srcBucket,
+			Key:       &srcObject,
+			VersionId: &versionId,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Metadata, nil
+}
+
+// kmsCopyMetadata carries the kms metadata of an encrypted copy source
+// over to the destination metadata so the copied data remains readable
+func (c S3ApiController) kmsCopyMetadata(ctx *fiber.Ctx, copySource string, metadata map[string]string) error {
+	srcMeta, err := c.kmsCopySourceMetadata(ctx, copySource)
+	if err != nil {
+		return err
+	}
+
+	kms.CopyMetadata(metadata, srcMeta)
+	return nil
+}
+
+// setSSEHeaders sets the server side encryption response headers for kms
+// encrypted objects, and removes the kms metadata from the user metadata
+func setSSEHeaders(ctx *fiber.Ctx, meta map[string]string) {
+	if kms.IsEncrypted(meta) {
+		utils.SetResponseHeaders(ctx, []utils.CustomHeader{
+			{
+				Key:   "x-amz-server-side-encryption",
+				Value: string(types.ServerSideEncryptionAwsKms),
+			},
+			{
+				Key:   "x-amz-server-side-encryption-aws-kms-key-id",
+				Value: kms.KeyID(meta),
+			},
+		})
+	}
+	kms.StripMetadata(meta)
+}
+
+// setChecksumHeaders sets the x-amz-checksum-* response header of the
+// object checksum
+func setChecksumHeaders(ctx *fiber.Ctx, checksum *backend.Checksum) {
+	if checksum == nil {
+		return
+	}
+	ctx.Set(utils.ChecksumHeader(checksum.Algorithm), checksum.Value)
+}
+
+// objectHeaders are the standard http headers stored with an object on
+// upload and returned unchanged on get and head object
+type objectHeaders struct {
+	CacheControl       *string
+	ContentDisposition *string
+	ContentLanguage    *string
+	Expires            *time.Time
+}
+
+// parseObjectHeaders reads the object headers from the request, an
+// Expires that isn't a valid http date is ignored
+func parseObjectHeaders(ctx *fiber.Ctx) objectHeaders {
+	var h objectHeaders
+	if v := ctx.Get("Cache-Control"); v != "" {
+		h.CacheControl = &v
+	}
+	if v := ctx.Get("Content-Disposition"); v != "" {
+		h.ContentDisposition = &v
+	}
+	if v := ctx.Get("Content-Language"); v != "" {
+		h.ContentLanguage = &v
+	}
+	if v := ctx.Get("Expires"); v != "" {
+		if t, err := http.ParseTime(v); err == nil {
+			h.Expires = &t
+		}
+	}
+	return h
+}
+
+// setObjectHeaders sets the response headers for the object headers
+// stored with the object
+func setObjectHeaders(ctx *fiber.Ctx, h objectHeaders) {
+	var headers []utils.CustomHeader
+	if h.CacheControl != nil {
+		headers = append(headers, utils.CustomHeader{
+			Key:   "Cache-Control",
+			Value: *h.CacheControl,
+		})
+	}
+	if h.ContentDisposition != nil {
+		headers = append(headers, utils.CustomHeader{
+			Key:   "Content-Disposition",
+			Value: *h.ContentDisposition,
+		})
+	}
+	if h.ContentLanguage != nil {
+		headers = append(headers, utils.CustomHeader{
+			Key:   "Content-Language",
+			Value: *h.ContentLanguage,
+		})
+	}
+	if h.Expires != nil {
+		headers = append(headers, utils.CustomHeader{
+			Key:   "Expires",
+			Value: h.Expires.UTC().Format(timefmt),
+		})
+	}
+	utils.SetResponseHeaders(ctx, headers)
+}
+
+// objectLockHeaders are the object lock settings requested with a new
+// object
+type objectLockHeaders struct {
+	Mode            types.ObjectLockMode
+	RetainUntilDate *time.Time
+	LegalHold       types.ObjectLockLegalHoldStatus
+}
+
+// parseObjectLockHeaders reads and validates the x-amz-object-lock-*
+// request headers
+func parseObjectLockHeaders(ctx *fiber.Ctx) (objectLockHeaders, error) {
+	legalHoldHdr := ctx.Get("X-Amz-Object-Lock-Legal-Hold")
+	objLockModeHdr := ctx.Get("X-Amz-Object-Lock-Mode")
+	objLockDate := ctx.Get("X-Amz-Object-Lock-Retain-Until-Date")
+
+	if (objLockDate != "" && objLockModeHdr == "") || (objLockDate == "" && objLockModeHdr != "") {
+		return objectLockHeaders{}, s3err.GetAPIError(s3err.ErrObjectLockInvalidHeaders)
+	}
+
+	var retainUntilDate *time.Time
+	if objLockDate != "" {
+		rDate, err := time.Parse(time.RFC3339, objLockDate)
+		if err != nil {
+			return objectLockHeaders{}, s3err.GetAPIError(s3err.ErrInvalidRequest)
+		}
+		if rDate.Before(time.Now()) {
+			return objectLockHeaders{}, s3err.GetAPIError(s3err.ErrPastObjectLockRetainDate)
+		}
+		retainUntilDate = &rDate
+	}
+
+	if objLockModeHdr != "" &&
+		objLockModeHdr != string(types.ObjectLockModeCompliance) &&
+		objLockModeHdr != string(types.ObjectLockModeGovernance) {
+		return objectLockHeaders{}, s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
+
+	return objectLockHeaders{
+		Mode:            types.ObjectLockMode(objLockModeHdr),
+		RetainUntilDate: retainUntilDate,
+		LegalHold:       types.ObjectLockLegalHoldStatus(legalHoldHdr),
+	}, nil
+}
+
+// bypassGovernance reports whether the request asks to bypass GOVERNANCE
+// mode object lock retention
+func bypassGovernance(ctx *fiber.Ctx) bool {
+	return strings.EqualFold(ctx.Get("X-Amz-Bypass-Governance-Retention"), "true")
 }
 
 func getstring(s *string) string {
@@ -481,6 +872,7 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 	maxUploadsStr := ctx.Query("max-uploads")
 	uploadIdMarker := ctx.Query("upload-id-marker")
 	versionIdMarker := ctx.Query("version-id-marker")
+	fetchOwner := ctx.QueryBool("fetch-owner")
 	acct := ctx.Locals("account").(auth.Account)
 	isRoot := ctx.Locals("isRoot").(bool)
 	parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
@@ -530,6 +922,78 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	if ctx.Request().URI().QueryArgs().Has("logging") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Readonly:      c.readonly,
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionReadAcp,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.GetBucketLoggingAction,
+		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetBucketLogging",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		data, err := c.be.GetBucketLogging(ctx.Context(), bucket)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetBucketLogging",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		config, err := s3log.ParseBucketLoggingStatus(data)
+		return SendXMLResponse(ctx, config, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "GetBucketLogging",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("location") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Readonly:      c.readonly,
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionRead,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.GetBucketLocationAction,
+		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetBucketLocation",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		// the buckets are all in the gateway region, and like in
+		// S3 us-east-1 is reported as an empty location
+		region, _ := ctx.Locals("region").(string)
+		if region == "us-east-1" {
+			region = ""
+		}
+
+		return SendXMLResponse(ctx, s3response.LocationConstraint{Value: region}, nil,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "GetBucketLocation",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
 	if ctx.Request().URI().QueryArgs().Has("versioning") {
 		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
 			Readonly:      c.readonly,
@@ -595,6 +1059,82 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	if ctx.Request().URI().QueryArgs().Has("publicAccessBlock") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Readonly:      c.readonly,
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionReadAcp,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.GetBucketPublicAccessBlockAction,
+		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetPublicAccessBlock",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		data, err := c.be.GetPublicAccessBlock(ctx.Context(), bucket)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetPublicAccessBlock",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		config, err := auth.ParsePublicAccessBlockOutput(data)
+		return SendXMLResponse(ctx, config, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "GetPublicAccessBlock",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("notification") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Readonly:      c.readonly,
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionRead,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.GetBucketNotificationAction,
+		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetBucketNotificationConfiguration",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		data, err := c.be.GetBucketNotificationConfiguration(ctx.Context(), bucket)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetBucketNotificationConfiguration",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		config, err := s3event.ParseNotificationConfiguration(data)
+		return SendXMLResponse(ctx, config, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "GetBucketNotificationConfiguration",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
 	if ctx.Request().URI().QueryArgs().Has("versions") {
 		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
 			Readonly:      c.readonly,
@@ -628,6 +1168,16 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 				})
 		}
 
+		encodingType, err := utils.ParseEncodingType(ctx)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "ListObjectVersions",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
 		data, err := c.be.ListObjectVersions(ctx.Context(),
 			&s3.ListObjectVersionsInput{
 				Bucket:          &bucket,
@@ -637,6 +1187,9 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 				Prefix:          &prefix,
 				VersionIdMarker: &versionIdMarker,
 			})
+		if err == nil && encodingType == types.EncodingTypeUrl {
+			utils.EncodeListVersionsResult(&data)
+		}
 		return SendXMLResponse(ctx, data, err,
 			&MetaOpts{
 				Logger:      c.logger,
@@ -750,6 +1303,16 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 				BucketOwner: parsedAcl.Owner,
 			})
 		}
+		encodingType, err := utils.ParseEncodingType(ctx)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "ListMultipartUploads",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
 		res, err := c.be.ListMultipartUploads(ctx.Context(),
 			&s3.ListMultipartUploadsInput{
 				Bucket:         &bucket,
@@ -759,6 +1322,9 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 				MaxUploads:     &maxUploads,
 				KeyMarker:      &keyMarker,
 			})
+		if err == nil && encodingType == types.EncodingTypeUrl {
+			utils.EncodeListMultipartUploadsResult(&res)
+		}
 		return SendXMLResponse(ctx, res, err,
 			&MetaOpts{
 				Logger:      c.logger,
@@ -798,6 +1364,16 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 					BucketOwner: parsedAcl.Owner,
 				})
 		}
+		encodingType, err := utils.ParseEncodingType(ctx)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "ListObjectsV2",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
 		res, err := c.be.ListObjectsV2(ctx.Context(),
 			&s3.ListObjectsV2Input{
 				Bucket:            &bucket,
@@ -806,7 +1382,11 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 				Delimiter:         &delimiter,
 				MaxKeys:           &maxkeys,
 				StartAfter:        &sAfter,
+				FetchOwner:        &fetchOwner,
 			})
+		if err == nil && encodingType == types.EncodingTypeUrl {
+			utils.EncodeListObjectsV2Output(res)
+		}
 		return SendXMLResponse(ctx, res, err,
 			&MetaOpts{
 				Logger:      c.logger,
@@ -847,6 +1427,16 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	encodingType, err := utils.ParseEncodingType(ctx)
+	if err != nil {
+		return SendXMLResponse(ctx, nil, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "ListObjects",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
 	res, err := c.be.ListObjects(ctx.Context(),
 		&s3.ListObjectsInput{
 			Bucket:    &bucket,
@@ -855,6 +1445,9 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 			Delimiter: &delimiter,
 			MaxKeys:   &maxkeys,
 		})
+	if err == nil && encodingType == types.EncodingTypeUrl {
+		utils.EncodeListObjectsOutput(res)
+	}
 	return SendXMLResponse(ctx, struct {
 		*s3.ListObjectsOutput
 		XMLName struct{} `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
@@ -972,6 +1565,19 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 				})
 		}
 
+		if versioningConf.Status != types.BucketVersioningStatusEnabled &&
+			versioningConf.Status != types.BucketVersioningStatusSuspended {
+			if c.debug {
+				log.Printf("invalid versioning status: %q", versioningConf.Status)
+			}
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrMalformedXML),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketVersioning",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
 		err = c.be.PutBucketVersioning(ctx.Context(),
 			&s3.PutBucketVersioningInput{
 				Bucket:                  &bucket,
@@ -1017,16 +1623,169 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 				})
 		}
 
-		err = c.be.PutObjectLockConfiguration(ctx.Context(), bucket, config)
+		err = c.be.PutObjectLockConfiguration(ctx.Context(), bucket, config)
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutObjectLockConfiguration",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("policy") {
+		parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Readonly:      c.readonly,
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.PutBucketPolicyAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketPolicy",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = auth.ValidatePolicyDocument(ctx.Body(), bucket, c.iam)
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketPolicy",
+					BucketOwner: parsedAcl.Owner,
+				},
+			)
+		}
+
+		err = auth.VerifyPublicPolicy(ctx.Context(), c.be, bucket, ctx.Body())
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketPolicy",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.PutBucketPolicy(ctx.Context(), bucket, ctx.Body())
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutBucketPolicy",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("publicAccessBlock") {
+		parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Readonly:      c.readonly,
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWriteAcp,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.PutBucketPublicAccessBlockAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutPublicAccessBlock",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		config, err := auth.ParsePublicAccessBlockInput(ctx.Body())
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutPublicAccessBlock",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.PutPublicAccessBlock(ctx.Context(), bucket, config)
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutPublicAccessBlock",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("logging") {
+		parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Readonly:      c.readonly,
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWriteAcp,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.PutBucketLoggingAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketLogging",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		config, err := s3log.ParseBucketLoggingStatus(ctx.Body())
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketLogging",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		var data []byte
+		if config.LoggingEnabled != nil {
+			// the access logs are only delivered to buckets
+			// with the same owner
+			err = c.verifyLoggingTarget(ctx, config.LoggingEnabled.TargetBucket, parsedAcl.Owner)
+			if err != nil {
+				return SendResponse(ctx, err,
+					&MetaOpts{
+						Logger:      c.logger,
+						Action:      "PutBucketLogging",
+						BucketOwner: parsedAcl.Owner,
+					})
+			}
+
+			data, err = xml.Marshal(config)
+			if err != nil {
+				return SendResponse(ctx, err,
+					&MetaOpts{
+						Logger:      c.logger,
+						Action:      "PutBucketLogging",
+						BucketOwner: parsedAcl.Owner,
+					})
+			}
+		}
+
+		err = c.be.PutBucketLogging(ctx.Context(), bucket, data)
 		return SendResponse(ctx, err,
 			&MetaOpts{
 				Logger:      c.logger,
-				Action:      "PutObjectLockConfiguration",
+				Action:      "PutBucketLogging",
 				BucketOwner: parsedAcl.Owner,
 			})
 	}
 
-	if ctx.Request().URI().QueryArgs().Has("policy") {
+	if ctx.Request().URI().QueryArgs().Has("notification") {
 		parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
 		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
 			Readonly:      c.readonly,
@@ -1035,33 +1794,67 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 			IsRoot:        isRoot,
 			Acc:           acct,
 			Bucket:        bucket,
-			Action:        auth.PutBucketPolicyAction,
+			Action:        auth.PutBucketNotificationAction,
 		})
 		if err != nil {
 			return SendResponse(ctx, err,
 				&MetaOpts{
 					Logger:      c.logger,
-					Action:      "PutBucketPolicy",
+					Action:      "PutBucketNotificationConfiguration",
 					BucketOwner: parsedAcl.Owner,
 				})
 		}
 
-		err = auth.ValidatePolicyDocument(ctx.Body(), bucket, c.iam)
+		config, err := s3event.ParseNotificationConfiguration(ctx.Body())
 		if err != nil {
+			if c.debug {
+				log.Printf("invalid notification configuration: %v", err)
+			}
 			return SendResponse(ctx, err,
 				&MetaOpts{
 					Logger:      c.logger,
-					Action:      "PutBucketPolicy",
+					Action:      "PutBucketNotificationConfiguration",
 					BucketOwner: parsedAcl.Owner,
-				},
-			)
+				})
 		}
 
-		err = c.be.PutBucketPolicy(ctx.Context(), bucket, ctx.Body())
+		checker, isChecker := c.evSender.(s3event.NotificationConfigChecker)
+		if isChecker {
+			err = checker.CheckConfiguration(config)
+			if err != nil {
+				if c.debug {
+					log.Printf("notification configuration destination not allowed: %v", err)
+				}
+				return SendResponse(ctx, err,
+					&MetaOpts{
+						Logger:      c.logger,
+						Action:      "PutBucketNotificationConfiguration",
+						BucketOwner: parsedAcl.Owner,
+					})
+			}
+		}
+
+		var data []byte
+		if !config.IsEmpty() {
+			data, err = xml.Marshal(config)
+			if err != nil {
+				return SendResponse(ctx, err,
+					&MetaOpts{
+						Logger:      c.logger,
+						Action:      "PutBucketNotificationConfiguration",
+						BucketOwner: parsedAcl.Owner,
+					})
+			}
+		}
+
+		err = c.be.PutBucketNotificationConfiguration(ctx.Context(), bucket, data)
+		if err == nil && isChecker {
+			checker.ConfigurationChanged(bucket)
+		}
 		return SendResponse(ctx, err,
 			&MetaOpts{
 				Logger:      c.logger,
-				Action:      "PutBucketPolicy",
+				Action:      "PutBucketNotificationConfiguration",
 				BucketOwner: parsedAcl.Owner,
 			})
 	}
@@ -1130,7 +1923,7 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 			}
 		}
 		if acl != "" {
-			if acl != "private" && acl != "public-read" && acl != "public-read-write" {
+			if !auth.IsValidBucketCannedACL(acl) {
 				if c.debug {
 					log.Printf("invalid acl: %q", acl)
 				}
@@ -1189,6 +1982,16 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 				})
 		}
 
+		err = c.verifyPublicACL(ctx, bucket, updAcl)
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketAcl",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
 		err = c.be.PutBucketAcl(ctx.Context(), bucket, updAcl)
 		return SendResponse(ctx, err,
 			&MetaOpts{
@@ -1218,6 +2021,18 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	if acl != "" && !auth.IsValidBucketCannedACL(acl) {
+		if c.debug {
+			log.Printf("invalid acl: %q", acl)
+		}
+		return SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidRequest),
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "CreateBucket",
+				BucketOwner: acct.Access,
+			})
+	}
+
 	defACL := auth.ACL{
 		Owner: acct.Access,
 	}
@@ -1242,6 +2057,33 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	if len(ctx.Body()) > 0 {
+		var config s3response.CreateBucketConfiguration
+		err := xml.Unmarshal(ctx.Body(), &config)
+		if err != nil {
+			if c.debug {
+				log.Printf("error unmarshalling bucket configuration: %v", err)
+			}
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrMalformedXML),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CreateBucket",
+					BucketOwner: acct.Access,
+				})
+		}
+
+		// buckets can only be created in the gateway region
+		region, _ := ctx.Locals("region").(string)
+		if config.LocationConstraint != "" && config.LocationConstraint != region {
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidLocationConstraint),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CreateBucket",
+					BucketOwner: acct.Access,
+				})
+		}
+	}
+
 	lockHeader := ctx.Get("X-Amz-Bucket-Object-Lock-Enabled")
 	// CLI provides "True", SDK - "true"
 	lockEnabled := lockHeader == "True" || lockHeader == "true"
@@ -1259,6 +2101,28 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 		})
 }
 
+// verifyLoggingTarget checks that the access log target bucket exists
+// and is owned by owner
+func (c S3ApiController) verifyLoggingTarget(ctx *fiber.Ctx, target, owner string) error {
+	data, err := c.be.GetBucketAcl(ctx.Context(), &s3.GetBucketAclInput{Bucket: &target})
+	if err != nil {
+		if errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)) {
+			return s3err.GetAPIError(s3err.ErrInvalidTargetBucketForLogging)
+		}
+		return err
+	}
+
+	acl, err := auth.ParseACL(data)
+	if err != nil {
+		return err
+	}
+	if acl.Owner != owner {
+		return s3err.GetAPIError(s3err.ErrInvalidTargetBucketForLogging)
+	}
+
+	return nil
+}
+
 func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 	bucket := ctx.Params("bucket")
 	keyStart := ctx.Params("key")
@@ -1272,10 +2136,7 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 
 	// Copy source headers
 	copySource := ctx.Get("X-Amz-Copy-Source")
-	copySrcIfMatch := ctx.Get("X-Amz-Copy-Source-If-Match")
-	copySrcIfNoneMatch := ctx.Get("X-Amz-Copy-Source-If-None-Match")
-	copySrcModifSince := ctx.Get("X-Amz-Copy-Source-If-Modified-Since")
-	copySrcUnmodifSince := ctx.Get("X-Amz-Copy-Source-If-Unmodified-Since")
+	copySrcConditions := utils.ParseConditionalHeaders(ctx, "X-Amz-Copy-Source-")
 	copySrcRange := ctx.Get("X-Amz-Copy-Source-Range")
 
 	// Permission headers
@@ -1291,6 +2152,11 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 	if contentLengthStr == "" {
 		contentLengthStr = "0"
 	}
+	// The object data size of aws-chunked uploads excludes the
+	// chunk metadata
+	if decodedLength := ctx.Get("X-Amz-Decoded-Content-Length"); decodedLength != "" {
+		contentLengthStr = decodedLength
+	}
 	bucketOwner := ctx.Get("X-Amz-Expected-Bucket-Owner")
 
 	grants := grantFullControl + grantRead + grantReadACP + granWrite + grantWriteACP
@@ -1318,22 +2184,18 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 				})
 		}
 
-		tags := make(map[string]string, len(objTagging.TagSet.Tags))
-
-		for _, tag := range objTagging.TagSet.Tags {
-			if len(tag.Key) > 128 || len(tag.Value) > 256 {
-				if c.debug {
-					log.Printf("invalid tag key/value len: %q %q",
-						tag.Key, tag.Value)
-				}
-				return SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidTag),
-					&MetaOpts{
-						Logger:      c.logger,
-						Action:      "PutObjectTagging",
-						BucketOwner: parsedAcl.Owner,
-					})
+		tags, err := backend.ObjectTags(objTagging.TagSet.Tags)
+		if err != nil {
+			if c.debug {
+				log.Printf("invalid object tag set %+v: %v",
+					objTagging.TagSet.Tags, err)
 			}
-			tags[tag.Key] = tag.Value
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectTagging",
+					BucketOwner: parsedAcl.Owner,
+				})
 		}
 
 		err = auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
@@ -1394,6 +2256,15 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			})
 		}
 
+		err = auth.CheckRetentionUpdate(ctx.Context(), c.be, bucket, keyStart, versionId, acct.Access, retention, bypassGovernance(ctx), isRoot || acct.Role == auth.RoleAdmin)
+		if err != nil {
+			return SendResponse(ctx, err, &MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutObjectRetention",
+				BucketOwner: parsedAcl.Owner,
+			})
+		}
+
 		err = c.be.PutObjectRetention(ctx.Context(), bucket, keyStart, versionId, retention)
 		return SendResponse(ctx, err, &MetaOpts{
 			Logger:      c.logger,
@@ -1474,15 +2345,36 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 				})
 		}
 
+		if c.kms != nil {
+			// multipart uploads are not encrypted, the copied part
+			// would hold the source ciphertext without its data key
+			srcMeta, err := c.kmsCopySourceMetadata(ctx, copySource)
+			if err == nil && kms.IsEncrypted(srcMeta) {
+				err = s3err.GetAPIError(s3err.ErrKMSEncryptedCopySource)
+			}
+			if err != nil {
+				return SendXMLResponse(ctx, nil, err,
+					&MetaOpts{
+						Logger:      c.logger,
+						Action:      "UploadPartCopy",
+						BucketOwner: parsedAcl.Owner,
+					})
+			}
+		}
+
 		resp, err := c.be.UploadPartCopy(ctx.Context(),
 			&s3.UploadPartCopyInput{
-				Bucket:              &bucket,
-				Key:                 &keyStart,
-				CopySource:          &copySource,
-				PartNumber:          &partNumber,
-				UploadId:            &uploadId,
-				ExpectedBucketOwner: &bucketOwner,
-				CopySourceRange:     &copySrcRange,
+				Bucket:                      &bucket,
+				Key:                         &keyStart,
+				CopySource:                  &copySource,
+				PartNumber:                  &partNumber,
+				UploadId:                    &uploadId,
+				ExpectedBucketOwner:         &bucketOwner,
+				CopySourceRange:             &copySrcRange,
+				CopySourceIfMatch:           copySrcConditions.IfMatch,
+				CopySourceIfNoneMatch:       copySrcConditions.IfNoneMatch,
+				CopySourceIfModifiedSince:   copySrcConditions.IfModifiedSince,
+				CopySourceIfUnmodifiedSince: copySrcConditions.IfUnmodifiedSince,
 			})
 		return SendXMLResponse(ctx, resp, err,
 			&MetaOpts{
@@ -1541,6 +2433,16 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 				})
 		}
 
+		err = c.quota.Check(ctx.Context(), parsedAcl.Owner, contentLength)
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "UploadPart",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
 		var body io.Reader
 		bodyi := ctx.Locals("body-reader")
 		if bodyi != nil {
@@ -1549,16 +2451,38 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			body = bytes.NewReader([]byte{})
 		}
 
+		checksum, err := utils.ParseChecksumHeaders(ctx)
+		if err == nil && checksum != nil {
+			body, err = utils.NewChecksumReader(body, checksum)
+		}
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "UploadPart",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+		checksumCRC32, checksumCRC32C, checksumSHA1, checksumSHA256 := checksum.Fields()
+
 		ctx.Locals("logReqBody", false)
 		etag, err := c.be.UploadPart(ctx.Context(),
 			&s3.UploadPartInput{
-				Bucket:        &bucket,
-				Key:           &keyStart,
-				UploadId:      &uploadId,
-				PartNumber:    &partNumber,
-				ContentLength: &contentLength,
-				Body:          body,
+				Bucket:         &bucket,
+				Key:            &keyStart,
+				UploadId:       &uploadId,
+				PartNumber:     &partNumber,
+				ContentLength:  &contentLength,
+				Body:           body,
+				ChecksumCRC32:  checksumCRC32,
+				ChecksumCRC32C: checksumCRC32C,
+				ChecksumSHA1:   checksumSHA1,
+				ChecksumSHA256: checksumSHA256,
 			})
+		if err == nil {
+			c.quota.Add(parsedAcl.Owner, contentLength)
+			setChecksumHeaders(ctx, checksum)
+		}
 		ctx.Response().Header.Set("Etag", etag)
 		return SendResponse(ctx, err,
 			&MetaOpts{
@@ -1571,6 +2495,49 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 	if ctx.Request().URI().QueryArgs().Has("acl") {
 		var input *s3.PutObjectAclInput
 
+		err := auth.VerifyObjectAccess(ctx.Context(), c.be,
+			auth.AccessOptions{
+				Readonly:      c.readonly,
+				Acl:           parsedAcl,
+				AclPermission: types.PermissionWriteAcp,
+				IsRoot:        isRoot,
+				Acc:           acct,
+				Bucket:        bucket,
+				Object:        keyStart,
+				Action:        auth.PutObjectAclAction,
+			})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectAcl",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		data, err := c.be.GetObjectAcl(ctx.Context(), &s3.GetObjectAclInput{
+			Bucket: &bucket,
+			Key:    &keyStart,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectAcl",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		objAcl, err := auth.ParseACL(data)
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectAcl",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
 		if len(ctx.Body()) > 0 {
 			if grants+acl != "" {
 				if c.debug {
@@ -1587,7 +2554,7 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			}
 
 			var accessControlPolicy auth.AccessControlPolicy
-			err := xml.Unmarshal(ctx.Body(), &accessControlPolicy)
+			err = xml.Unmarshal(ctx.Body(), &accessControlPolicy)
 			if err != nil {
 				if c.debug {
 					log.Printf("error unmarshalling access control policy: %v",
@@ -1613,7 +2580,7 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			}
 		}
 		if acl != "" {
-			if acl != "private" && acl != "public-read" && acl != "public-read-write" {
+			if !auth.IsValidObjectCannedACL(acl) {
 				if c.debug {
 					log.Printf("invalid acl: %q", acl)
 				}
@@ -1644,7 +2611,7 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 				Key:    &keyStart,
 				ACL:    types.ObjectCannedACL(acl),
 				AccessControlPolicy: &types.AccessControlPolicy{
-					Owner: &types.Owner{ID: &bucketOwner},
+					Owner: &types.Owner{ID: &objAcl.Owner},
 				},
 			}
 		}
@@ -1658,13 +2625,33 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 				GrantWrite:       &granWrite,
 				GrantWriteACP:    &grantWriteACP,
 				AccessControlPolicy: &types.AccessControlPolicy{
-					Owner: &types.Owner{ID: &bucketOwner},
+					Owner: &types.Owner{ID: &objAcl.Owner},
 				},
 				ACL: "",
 			}
 		}
 
-		err := c.be.PutObjectAcl(ctx.Context(), input)
+		updAcl, err := auth.UpdateObjectACL(input, objAcl, parsedAcl.Owner, c.iam)
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectAcl",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.verifyPublicACL(ctx, bucket, updAcl)
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectAcl",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.PutObjectAcl(ctx.Context(), bucket, keyStart, updAcl)
 		return SendResponse(ctx, err,
 			&MetaOpts{
 				Logger:      c.logger,
@@ -1675,6 +2662,14 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	// object acls have no WRITE permission to grant
+	objGrants := auth.Grants{
+		FullControl: &grantFullControl,
+		Read:        &grantRead,
+		ReadACP:     &grantReadACP,
+		WriteACP:    &grantWriteACP,
+	}
+
 	if copySource != "" {
 		err := auth.VerifyObjectCopyAccess(ctx.Context(), c.be, copySource,
 			auth.AccessOptions{
@@ -1695,56 +2690,122 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 				})
 		}
 
-		var mtime *time.Time
-		var umtime *time.Time
-		if copySrcModifSince != "" {
-			tm, err := time.Parse(iso8601Format, copySrcModifSince)
-			if err != nil {
-				if c.debug {
-					log.Printf("error parsing copy source modified since %q: %v",
-						copySrcModifSince, err)
-				}
-				return SendXMLResponse(ctx, nil,
-					s3err.GetAPIError(s3err.ErrInvalidCopySource),
-					&MetaOpts{
-						Logger:      c.logger,
-						Action:      "CopyObject",
-						BucketOwner: parsedAcl.Owner,
-					})
-			}
-			mtime = &tm
+		err = auth.CheckObjectAccess(ctx.Context(), bucket, acct.Access, []string{keyStart}, bypassGovernance(ctx), isRoot || acct.Role == auth.RoleAdmin, c.be)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CopyObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		if ctx.Get("X-Amz-Server-Side-Encryption") == string(types.ServerSideEncryptionAwsKms) {
+			return SendXMLResponse(ctx, nil,
+				s3err.GetAPIError(s3err.ErrNotImplemented),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CopyObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = auth.ValidateObjectACLRequest(acl, objGrants, c.iam)
+		if err == nil {
+			err = auth.VerifyPublicACL(ctx.Context(), c.be, bucket,
+				auth.ACL{ACL: types.BucketCannedACL(acl)})
+		}
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CopyObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		metaDirective := types.MetadataDirective(ctx.Get("X-Amz-Metadata-Directive"))
+		if metaDirective != "" &&
+			metaDirective != types.MetadataDirectiveCopy &&
+			metaDirective != types.MetadataDirectiveReplace {
+			return SendXMLResponse(ctx, nil,
+				s3err.GetAPIError(s3err.ErrInvalidMetadataDirective),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CopyObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+		tagDirective := types.TaggingDirective(ctx.Get("X-Amz-Tagging-Directive"))
+		if tagDirective != "" &&
+			tagDirective != types.TaggingDirectiveCopy &&
+			tagDirective != types.TaggingDirectiveReplace {
+			return SendXMLResponse(ctx, nil,
+				s3err.GetAPIError(s3err.ErrInvalidTaggingDirective),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CopyObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		lock, err := parseObjectLockHeaders(ctx)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CopyObject",
+					BucketOwner: parsedAcl.Owner,
+				})
 		}
-		if copySrcUnmodifSince != "" {
-			tm, err := time.Parse(iso8601Format, copySrcUnmodifSince)
+
+		metadata := utils.GetUserMetaData(&ctx.Request().Header)
+		kms.StripMetadata(metadata)
+		if c.kms != nil {
+			err := c.kmsCopyMetadata(ctx, copySource, metadata)
 			if err != nil {
-				if c.debug {
-					log.Printf("error parsing copy source unmodified since %q: %v",
-						copySrcUnmodifSince, err)
-				}
-				return SendXMLResponse(ctx, nil,
-					s3err.GetAPIError(s3err.ErrInvalidCopySource),
+				return SendXMLResponse(ctx, nil, err,
 					&MetaOpts{
 						Logger:      c.logger,
 						Action:      "CopyObject",
 						BucketOwner: parsedAcl.Owner,
 					})
 			}
-			umtime = &tm
 		}
 
-		metadata := utils.GetUserMetaData(&ctx.Request().Header)
+		objHdrs := parseObjectHeaders(ctx)
+		contentType := ctx.Get("Content-Type")
+		contentEncoding := utils.StripAwsChunked(ctx.Get("Content-Encoding"))
 
 		res, err := c.be.CopyObject(ctx.Context(),
 			&s3.CopyObjectInput{
 				Bucket:                      &bucket,
 				Key:                         &keyStart,
 				CopySource:                  &copySource,
-				CopySourceIfMatch:           &copySrcIfMatch,
-				CopySourceIfNoneMatch:       &copySrcIfNoneMatch,
-				CopySourceIfModifiedSince:   mtime,
-				CopySourceIfUnmodifiedSince: umtime,
+				CopySourceIfMatch:           copySrcConditions.IfMatch,
+				CopySourceIfNoneMatch:       copySrcConditions.IfNoneMatch,
+				CopySourceIfModifiedSince:   copySrcConditions.IfModifiedSince,
+				CopySourceIfUnmodifiedSince: copySrcConditions.IfUnmodifiedSince,
 				ExpectedBucketOwner:         &acct.Access,
+				MetadataDirective:           metaDirective,
 				Metadata:                    metadata,
+				ContentType:                 &contentType,
+				ContentEncoding:             &contentEncoding,
+				CacheControl:                objHdrs.CacheControl,
+				ContentDisposition:          objHdrs.ContentDisposition,
+				ContentLanguage:             objHdrs.ContentLanguage,
+				Expires:                     objHdrs.Expires,
+				TaggingDirective:            tagDirective,
+				Tagging:                     &tagging,
+				ObjectLockMode:              lock.Mode,
+				ObjectLockRetainUntilDate:   lock.RetainUntilDate,
+				ObjectLockLegalHoldStatus:   lock.LegalHold,
+				StorageClass:                types.StorageClass(ctx.Get("X-Amz-Storage-Class")),
+				ACL:                         types.ObjectCannedACL(acl),
+				GrantFullControl:            objGrants.FullControl,
+				GrantRead:                   objGrants.Read,
+				GrantReadACP:                objGrants.ReadACP,
+				GrantWriteACP:               objGrants.WriteACP,
 			})
 		if err == nil {
 			return SendXMLResponse(ctx, res.CopyObjectResult, err,
@@ -1768,6 +2829,7 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 	}
 
 	metadata := utils.GetUserMetaData(&ctx.Request().Header)
+	kms.StripMetadata(metadata)
 
 	err := auth.VerifyAccess(ctx.Context(), c.be,
 		auth.AccessOptions{
@@ -1789,7 +2851,7 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			})
 	}
 
-	err = auth.CheckObjectAccess(ctx.Context(), bucket, acct.Access, []string{keyStart}, isRoot || acct.Role == auth.RoleAdmin, c.be)
+	err = auth.CheckObjectAccess(ctx.Context(), bucket, acct.Access, []string{keyStart}, bypassGovernance(ctx), isRoot || acct.Role == auth.RoleAdmin, c.be)
 	if err != nil {
 		return SendResponse(ctx, err,
 			&MetaOpts{
@@ -1813,12 +2875,9 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			})
 	}
 
-	legalHoldHdr := ctx.Get("X-Amz-Object-Lock-Legal-Hold")
-	objLockModeHdr := ctx.Get("X-Amz-Object-Lock-Mode")
-	objLockDate := ctx.Get("X-Amz-Object-Lock-Retain-Until-Date")
-
-	if (objLockDate != "" && objLockModeHdr == "") || (objLockDate == "" && objLockModeHdr != "") {
-		return SendResponse(ctx, s3err.GetAPIError(s3err.ErrObjectLockInvalidHeaders),
+	lock, err := parseObjectLockHeaders(ctx)
+	if err != nil {
+		return SendResponse(ctx, err,
 			&MetaOpts{
 				Logger:      c.logger,
 				Action:      "PutObject",
@@ -1826,32 +2885,16 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			})
 	}
 
-	var retainUntilDate *time.Time
-	if objLockDate != "" {
-		rDate, err := time.Parse(time.RFC3339, objLockDate)
-		if err != nil {
-			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidRequest),
-				&MetaOpts{
-					Logger:      c.logger,
-					Action:      "PutObject",
-					BucketOwner: parsedAcl.Owner,
-				})
-		}
-		if rDate.Before(time.Now()) {
-			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrPastObjectLockRetainDate),
-				&MetaOpts{
-					Logger:      c.logger,
-					Action:      "PutObject",
-					BucketOwner: parsedAcl.Owner,
-				})
-		}
-		retainUntilDate = &rDate
+	err = auth.ValidateObjectACLRequest(acl, objGrants, c.iam)
+	if err == nil {
+		err = auth.VerifyPublicACL(ctx.Context(), c.be, bucket,
+			auth.ACL{ACL: types.BucketCannedACL(acl)})
 	}
-
-	if objLockModeHdr != "" &&
-		objLockModeHdr != string(types.ObjectLockModeCompliance) &&
-		objLockModeHdr != string(types.ObjectLockModeGovernance) {
-		return SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidRequest),
+	if err == nil {
+		err = c.quota.Check(ctx.Context(), parsedAcl.Owner, contentLength)
+	}
+	if err != nil {
+		return SendResponse(ctx, err,
 			&MetaOpts{
 				Logger:      c.logger,
 				Action:      "PutObject",
@@ -1867,20 +2910,68 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 		body = bytes.NewReader([]byte{})
 	}
 
+	checksum, err := utils.ParseChecksumHeaders(ctx)
+	if err == nil && checksum != nil {
+		body, err = utils.NewChecksumReader(body, checksum)
+	}
+	if err != nil {
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	body, err = c.kmsEncryptBody(ctx, body, metadata)
+	if err != nil {
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	objHdrs := parseObjectHeaders(ctx)
+	contentType := ctx.Get("Content-Type")
+	contentEncoding := utils.StripAwsChunked(ctx.Get("Content-Encoding"))
+	checksumCRC32, checksumCRC32C, checksumSHA1, checksumSHA256 := checksum.Fields()
+
 	ctx.Locals("logReqBody", false)
 	etag, err := c.be.PutObject(ctx.Context(),
 		&s3.PutObjectInput{
 			Bucket:                    &bucket,
 			Key:                       &keyStart,
 			ContentLength:             &contentLength,
+			ContentType:               &contentType,
+			ContentEncoding:           &contentEncoding,
 			Metadata:                  metadata,
+			CacheControl:              objHdrs.CacheControl,
+			ContentDisposition:        objHdrs.ContentDisposition,
+			ContentLanguage:           objHdrs.ContentLanguage,
+			Expires:                   objHdrs.Expires,
 			Body:                      body,
+			ChecksumCRC32:             checksumCRC32,
+			ChecksumCRC32C:            checksumCRC32C,
+			ChecksumSHA1:              checksumSHA1,
+			ChecksumSHA256:            checksumSHA256,
 			Tagging:                   &tagging,
-			ObjectLockRetainUntilDate: retainUntilDate,
-			ObjectLockMode:            types.ObjectLockMode(objLockModeHdr),
-			ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatus(legalHoldHdr),
+			ObjectLockRetainUntilDate: lock.RetainUntilDate,
+			ObjectLockMode:            lock.Mode,
+			ObjectLockLegalHoldStatus: lock.LegalHold,
+			ACL:                       types.ObjectCannedACL(acl),
+			GrantFullControl:          objGrants.FullControl,
+			GrantRead:                 objGrants.Read,
+			GrantReadACP:              objGrants.ReadACP,
+			GrantWriteACP:             objGrants.WriteACP,
 		})
 	ctx.Response().Header.Set("ETag", etag)
+	if err == nil {
+		setSSEHeaders(ctx, metadata)
+		setChecksumHeaders(ctx, checksum)
+		c.quota.Add(parsedAcl.Owner, contentLength)
+	}
 	return SendResponse(ctx, err,
 		&MetaOpts{
 			Logger:      c.logger,
@@ -1929,6 +3020,36 @@ func (c S3ApiController) DeleteBucket(ctx *fiber.Ctx) error {
 			})
 	}
 
+	if ctx.Request().URI().QueryArgs().Has("publicAccessBlock") {
+		err := auth.VerifyAccess(ctx.Context(), c.be,
+			auth.AccessOptions{
+				Readonly:      c.readonly,
+				Acl:           parsedAcl,
+				AclPermission: types.PermissionWriteAcp,
+				IsRoot:        isRoot,
+				Acc:           acct,
+				Bucket:        bucket,
+				Action:        auth.PutBucketPublicAccessBlockAction,
+			})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "DeletePublicAccessBlock",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.DeletePublicAccessBlock(ctx.Context(), bucket)
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "DeletePublicAccessBlock",
+				BucketOwner: parsedAcl.Owner,
+				Status:      http.StatusNoContent,
+			})
+	}
+
 	if ctx.Request().URI().QueryArgs().Has("policy") {
 		err := auth.VerifyAccess(ctx.Context(), c.be,
 			auth.AccessOptions{
@@ -1991,6 +3112,164 @@ func (c S3ApiController) DeleteBucket(ctx *fiber.Ctx) error {
 		})
 }
 
+func (c S3ApiController) PostBucketActions(ctx *fiber.Ctx) error {
+	if _, ok := ctx.Locals("postForm").(*utils.PostForm); ok {
+		return c.PostObject(ctx)
+	}
+
+	return c.DeleteObjects(ctx)
+}
+
+// PostObject handles browser based multipart/form-data uploads, the
+// form and policy have already been verified by the auth middleware
+func (c S3ApiController) PostObject(ctx *fiber.Ctx) error {
+	bucket := ctx.Params("bucket")
+	acct := ctx.Locals("account").(auth.Account)
+	isRoot := ctx.Locals("isRoot").(bool)
+	parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
+	form := ctx.Locals("postForm").(*utils.PostForm)
+	key := form.Key()
+
+	err := auth.VerifyAccess(ctx.Context(), c.be,
+		auth.AccessOptions{
+			Readonly:      c.readonly,
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Object:        key,
+			Action:        auth.PutObjectAction,
+		})
+	if err != nil {
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PostObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	err = auth.CheckObjectAccess(ctx.Context(), bucket, acct.Access, []string{key}, false, isRoot || acct.Role == auth.RoleAdmin, c.be)
+	if err != nil {
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PostObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	f, err := form.File.Open()
+	if err != nil {
+		return SendResponse(ctx, fmt.Errorf("open form file: %w", err),
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PostObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+	defer f.Close()
+
+	metadata := form.Metadata
+	kms.StripMetadata(metadata)
+
+	size := form.File.Size
+	input := &s3.PutObjectInput{
+		Bucket:        &bucket,
+		Key:           &key,
+		ContentLength: &size,
+		Metadata:      metadata,
+		Body:          f,
+	}
+	if ct := form.Fields["content-type"]; ct != "" {
+		input.ContentType = &ct
+	}
+	if ce := form.Fields["content-encoding"]; ce != "" {
+		input.ContentEncoding = &ce
+	}
+	if tagging := form.Fields["tagging"]; tagging != "" {
+		input.Tagging = &tagging
+	}
+	if acl := form.Fields["acl"]; acl != "" {
+		if !auth.IsValidObjectCannedACL(acl) {
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidRequest),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PostObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+		err = auth.VerifyPublicACL(ctx.Context(), c.be, bucket,
+			auth.ACL{ACL: types.BucketCannedACL(acl)})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PostObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+		input.ACL = types.ObjectCannedACL(acl)
+	}
+
+	etag, err := c.be.PutObject(ctx.Context(), input)
+	if err != nil {
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PostObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	ctx.Response().Header.Set("ETag", etag)
+	location := fmt.Sprintf("/%v/%v", bucket, key)
+	ctx.Response().Header.Set("Location", location)
+
+	meta := &MetaOpts{
+		Logger:      c.logger,
+		EvSender:    c.evSender,
+		Action:      "PostObject",
+		BucketOwner: parsedAcl.Owner,
+		ObjectETag:  &etag,
+		ObjectSize:  size,
+		ObjectKey:   key,
+		EventName:   s3event.EventObjectCreatedPost,
+		Status:      http.StatusNoContent,
+	}
+
+	if redirect := form.Fields["success_action_redirect"]; redirect != "" {
+		u, err := url.Parse(redirect)
+		if err == nil {
+			q := u.Query()
+			q.Set("bucket", bucket)
+			q.Set("key", key)
+			q.Set("etag", etag)
+			u.RawQuery = q.Encode()
+			ctx.Response().Header.Set("Location", u.String())
+			meta.Status = http.StatusSeeOther
+			return SendResponse(ctx, nil, meta)
+		}
+	}
+
+	switch form.Fields["success_action_status"] {
+	case "200":
+		meta.Status = http.StatusOK
+	case "201":
+		meta.Status = http.StatusCreated
+		ctx.Status(http.StatusCreated)
+		return SendXMLResponse(ctx, s3response.PostResponse{
+			Location: location,
+			Bucket:   bucket,
+			Key:      key,
+			ETag:     etag,
+		}, nil, meta)
+	}
+
+	return SendResponse(ctx, nil, meta)
+}
+
 func (c S3ApiController) DeleteObjects(ctx *fiber.Ctx) error {
 	bucket := ctx.Params("bucket")
 	acct := ctx.Locals("account").(auth.Account)
@@ -2030,7 +3309,7 @@ func (c S3ApiController) DeleteObjects(ctx *fiber.Ctx) error {
 			})
 	}
 
-	err = auth.CheckObjectAccess(ctx.Context(), bucket, acct.Access, utils.ParseDeleteObjects(dObj.Objects), isRoot || acct.Role == auth.RoleAdmin, c.be)
+	err = auth.CheckObjectAccess(ctx.Context(), bucket, acct.Access, utils.ParseDeleteObjects(dObj.Objects), bypassGovernance(ctx), isRoot || acct.Role == auth.RoleAdmin, c.be)
 	if err != nil {
 		return SendResponse(ctx, err,
 			&MetaOpts{
@@ -2045,15 +3324,23 @@ func (c S3ApiController) DeleteObjects(ctx *fiber.Ctx) error {
 			Bucket: &bucket,
 			Delete: &types.Delete{
 				Objects: dObj.Objects,
+				Quiet:   dObj.Quiet,
 			},
 		})
+	// only the objects that were removed generate events
+	deleted := res.Deleted
+	if dObj.Quiet != nil && *dObj.Quiet {
+		// quiet mode only reports the keys that failed to delete
+		res.Deleted = nil
+	}
 	return SendXMLResponse(ctx, res, err,
 		&MetaOpts{
-			Logger:      c.logger,
-			Action:      "DeleteObjects",
-			BucketOwner: parsedAcl.Owner,
-			EvSender:    c.evSender,
-			EventName:   s3event.EventObjectRemovedDeleteObjects,
+			Logger:         c.logger,
+			Action:         "DeleteObjects",
+			BucketOwner:    parsedAcl.Owner,
+			EvSender:       c.evSender,
+			EventName:      s3event.EventObjectRemovedDeleteObjects,
+			DeletedObjects: deleted,
 		})
 }
 
@@ -2165,7 +3452,7 @@ func (c S3ApiController) DeleteActions(ctx *fiber.Ctx) error {
 			})
 	}
 
-	err = auth.CheckObjectAccess(ctx.Context(), bucket, acct.Access, []string{key}, isRoot || acct.Role == auth.RoleAdmin, c.be)
+	err = auth.CheckObjectAccess(ctx.Context(), bucket, acct.Access, []string{key}, bypassGovernance(ctx), isRoot || acct.Role == auth.RoleAdmin, c.be)
 	if err != nil {
 		return SendResponse(ctx, err,
 			&MetaOpts{
@@ -2274,7 +3561,7 @@ func (c S3ApiController) HeadObject(ctx *fiber.Ctx) error {
 		partNumber = &partNumberQuery
 	}
 
-	err := auth.VerifyAccess(ctx.Context(), c.be,
+	err := auth.VerifyObjectAccess(ctx.Context(), c.be,
 		auth.AccessOptions{
 			Readonly:      c.readonly,
 			Acl:           parsedAcl,
@@ -2294,11 +3581,18 @@ func (c S3ApiController) HeadObject(ctx *fiber.Ctx) error {
 			})
 	}
 
+	conditions := utils.ParseConditionalHeaders(ctx, "")
+
 	res, err := c.be.HeadObject(ctx.Context(),
 		&s3.HeadObjectInput{
-			Bucket:     &bucket,
-			Key:        &key,
-			PartNumber: partNumber,
+			Bucket:            &bucket,
+			Key:               &key,
+			PartNumber:        partNumber,
+			ChecksumMode:      types.ChecksumMode(ctx.Get("X-Amz-Checksum-Mode")),
+			IfMatch:           conditions.IfMatch,
+			IfNoneMatch:       conditions.IfNoneMatch,
+			IfModifiedSince:   conditions.IfModifiedSince,
+			IfUnmodifiedSince: conditions.IfUnmodifiedSince,
 		})
 	if err != nil {
 		return SendResponse(ctx, err,
@@ -2317,7 +3611,14 @@ func (c S3ApiController) HeadObject(ctx *fiber.Ctx) error {
 			})
 	}
 
+	setSSEHeaders(ctx, res.Metadata)
 	utils.SetMetaHeaders(ctx, res.Metadata)
+	setObjectHeaders(ctx, objectHeaders{
+		CacheControl:       res.CacheControl,
+		ContentDisposition: res.ContentDisposition,
+		ContentLanguage:    res.ContentLanguage,
+		Expires:            res.Expires,
+	})
 	headers := []utils.CustomHeader{
 		{
 			Key:   "Content-Length",
@@ -2336,6 +3637,16 @@ func (c S3ApiController) HeadObject(ctx *fiber.Ctx) error {
 			Value: getstring(res.Restore),
 		},
 	}
+	if res.ArchiveStatus != "" {
+		headers = append(headers, utils.CustomHeader{
+			Key:   "x-amz-archive-status",
+			Value: string(res.ArchiveStatus),
+		})
+	}
+	if ctx.Get("X-Amz-Checksum-Mode") == string(types.ChecksumModeEnabled) {
+		setChecksumHeaders(ctx, backend.ChecksumFromFields(res.ChecksumCRC32,
+			res.ChecksumCRC32C, res.ChecksumSHA1, res.ChecksumSHA256))
+	}
 	if res.ObjectLockMode != "" {
 		headers = append(headers, utils.CustomHeader{
 			Key:   "x-amz-object-lock-mode",
@@ -2550,6 +3861,28 @@ func (c S3ApiController) CreateActions(ctx *fiber.Ctx) error {
 				})
 		}
 
+		err = auth.CheckObjectAccess(ctx.Context(), bucket, acct.Access, []string{key}, bypassGovernance(ctx), isRoot || acct.Role == auth.RoleAdmin, c.be)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CompleteMultipartUpload",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		// the parts were accounted for when uploaded, only reject the
+		// completion once the quota is already exceeded
+		err = c.quota.Check(ctx.Context(), parsedAcl.Owner, 0)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CompleteMultipartUpload",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
 		res, err := c.be.CompleteMultipartUpload(ctx.Context(),
 			&s3.CompleteMultipartUploadInput{
 				Bucket:   &bucket,
@@ -2599,11 +3932,90 @@ func (c S3ApiController) CreateActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	if ctx.Get("X-Amz-Server-Side-Encryption") == string(types.ServerSideEncryptionAwsKms) {
+		return SendXMLResponse(ctx, nil,
+			s3err.GetAPIError(s3err.ErrNotImplemented),
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "CreateMultipartUpload",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	acl := ctx.Get("X-Amz-Acl")
+	grantFullControl := ctx.Get("X-Amz-Grant-Full-Control")
+	grantRead := ctx.Get("X-Amz-Grant-Read")
+	grantReadACP := ctx.Get("X-Amz-Grant-Read-Acp")
+	grantWriteACP := ctx.Get("X-Amz-Grant-Write-Acp")
+
+	err = auth.ValidateObjectACLRequest(acl, auth.Grants{
+		FullControl: &grantFullControl,
+		Read:        &grantRead,
+		ReadACP:     &grantReadACP,
+		WriteACP:    &grantWriteACP,
+	}, c.iam)
+	if err == nil {
+		err = auth.VerifyPublicACL(ctx.Context(), c.be, bucket,
+			auth.ACL{ACL: types.BucketCannedACL(acl)})
+	}
+	if err != nil {
+		return SendXMLResponse(ctx, nil, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "CreateMultipartUpload",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	checksumAlgorithm, err := utils.ParseChecksumAlgorithm(ctx.Get("X-Amz-Checksum-Algorithm"))
+	if err != nil {
+		return SendXMLResponse(ctx, nil, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "CreateMultipartUpload",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	lock, err := parseObjectLockHeaders(ctx)
+	if err != nil {
+		return SendXMLResponse(ctx, nil, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "CreateMultipartUpload",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	objHdrs := parseObjectHeaders(ctx)
+	contentType := ctx.Get("Content-Type")
+	contentEncoding := ctx.Get("Content-Encoding")
+	tagging := ctx.Get("X-Amz-Tagging")
+
 	res, err := c.be.CreateMultipartUpload(ctx.Context(),
 		&s3.CreateMultipartUploadInput{
-			Bucket: &bucket,
-			Key:    &key,
+			Bucket:                    &bucket,
+			Key:                       &key,
+			ContentType:               &contentType,
+			ContentEncoding:           &contentEncoding,
+			CacheControl:              objHdrs.CacheControl,
+			ContentDisposition:        objHdrs.ContentDisposition,
+			ContentLanguage:           objHdrs.ContentLanguage,
+			Expires:                   objHdrs.Expires,
+			Tagging:                   &tagging,
+			ObjectLockMode:            lock.Mode,
+			ObjectLockRetainUntilDate: lock.RetainUntilDate,
+			ObjectLockLegalHoldStatus: lock.LegalHold,
+			ACL:                       types.ObjectCannedACL(acl),
+			GrantFullControl:          &grantFullControl,
+			GrantRead:                 &grantRead,
+			GrantReadACP:              &grantReadACP,
+			GrantWriteACP:             &grantWriteACP,
+			ChecksumAlgorithm:         checksumAlgorithm,
 		})
+	if err == nil && checksumAlgorithm != "" {
+		ctx.Set("x-amz-checksum-algorithm", string(checksumAlgorithm))
+	}
 	return SendXMLResponse(ctx, res, err,
 		&MetaOpts{
 			Logger:      c.logger,
@@ -2612,6 +4024,17 @@ func (c S3ApiController) CreateActions(ctx *fiber.Ctx) error {
 		})
 }
 
+// verifyPublicACL rejects the encoded acl if it grants public access and
+// the bucket blocks public acls
+func (c S3ApiController) verifyPublicACL(ctx *fiber.Ctx, bucket string, data []byte) error {
+	acl, err := auth.ParseACL(data)
+	if err != nil {
+		return err
+	}
+
+	return auth.VerifyPublicACL(ctx.Context(), c.be, bucket, acl)
+}
+
 type MetaOpts struct {
 	Logger      s3log.AuditLogger
 	EvSender    s3event.S3EventSender
@@ -2621,7 +4044,10 @@ type MetaOpts struct {
 	EventName   s3event.EventType
 	ObjectETag  *string
 	VersionId   *string
+	ObjectKey   string
 	Status      int
+	// DeletedObjects are reported in the DeleteObjects events
+	DeletedObjects []types.DeletedObject
 }
 
 func SendResponse(ctx *fiber.Ctx, err error, l *MetaOpts) error {
@@ -2651,6 +4077,7 @@ func SendResponse(ctx *fiber.Ctx, err error, l *MetaOpts) error {
 			EventName:   l.EventName,
 			BucketOwner: l.BucketOwner,
 			VersionId:   l.VersionId,
+			ObjectKey:   l.ObjectKey,
 		})
 	}
 
@@ -2725,11 +4152,13 @@ func SendXMLResponse(ctx *fiber.Ctx, resp any, err error, l *MetaOpts) error {
 
 	if l.EvSender != nil {
 		l.EvSender.SendEvent(ctx, s3event.EventMeta{
-			BucketOwner: l.BucketOwner,
-			ObjectSize:  l.ObjectSize,
-			ObjectETag:  l.ObjectETag,
-			VersionId:   l.VersionId,
-			EventName:   l.EventName,
+			BucketOwner:    l.BucketOwner,
+			ObjectSize:     l.ObjectSize,
+			ObjectETag:     l.ObjectETag,
+			VersionId:      l.VersionId,
+			EventName:      l.EventName,
+			ObjectKey:      l.ObjectKey,
+			DeletedObjects: l.DeletedObjects,
 		})
 	}
 
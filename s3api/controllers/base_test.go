@@ -16,10 +16,13 @@ package controllers
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -33,6 +36,8 @@ import (
 	"github.com/valyala/fasthttp"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/kms"
+	"github.com/versity/versitygw/s3api/utils"
 	"github.com/versity/versitygw/s3err"
 	"github.com/versity/versitygw/s3response"
 )
@@ -77,7 +82,7 @@ func TestNew(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := New(tt.args.be, tt.args.iam, nil, nil, false, false)
+			got := New(tt.args.be, tt.args.iam, nil, nil, nil, nil, false, false)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("New() = %v, want %v", got, tt.want)
 			}
@@ -123,6 +128,15 @@ func TestS3ApiController_ListBuckets(t *testing.T) {
 	})
 	appErr.Get("/", s3ApiControllerErr.ListBuckets)
 
+	// Anonymous case
+	appAnon := fiber.New()
+	appAnon.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{})
+		ctx.Locals("isDebug", false)
+		return ctx.Next()
+	})
+	appAnon.Get("/", s3ApiController.ListBuckets)
+
 	tests := []struct {
 		name       string
 		args       args
@@ -139,6 +153,15 @@ func TestS3ApiController_ListBuckets(t *testing.T) {
 			wantErr:    false,
 			statusCode: 405,
 		},
+		{
+			name: "List-bucket-anonymous",
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/", nil),
+			},
+			app:        appAnon,
+			wantErr:    false,
+			statusCode: 403,
+		},
 		{
 			name: "list-bucket-success",
 			args: args{
@@ -185,8 +208,8 @@ func TestS3ApiController_GetActions(t *testing.T) {
 			ListPartsFunc: func(context.Context, *s3.ListPartsInput) (s3response.ListPartsResult, error) {
 				return s3response.ListPartsResult{}, nil
 			},
-			GetObjectAclFunc: func(context.Context, *s3.GetObjectAclInput) (*s3.GetObjectAclOutput, error) {
-				return &s3.GetObjectAclOutput{}, nil
+			GetObjectAclFunc: func(context.Context, *s3.GetObjectAclInput) ([]byte, error) {
+				return acldata, nil
 			},
 			GetObjectAttributesFunc: func(context.Context, *s3.GetObjectAttributesInput) (s3response.GetObjectAttributesResult, error) {
 				return s3response.GetObjectAttributesResult{}, nil
@@ -383,11 +406,11 @@ func TestS3ApiController_ListActions(t *testing.T) {
 			GetBucketTaggingFunc: func(contextMoqParam context.Context, bucket string) (map[string]string, error) {
 				return map[string]string{}, nil
 			},
-			GetBucketVersioningFunc: func(contextMoqParam context.Context, bucket string) (*s3.GetBucketVersioningOutput, error) {
-				return &s3.GetBucketVersioningOutput{}, nil
+			GetBucketVersioningFunc: func(contextMoqParam context.Context, bucket string) (s3response.GetBucketVersioningOutput, error) {
+				return s3response.GetBucketVersioningOutput{}, nil
 			},
-			ListObjectVersionsFunc: func(contextMoqParam context.Context, listObjectVersionsInput *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
-				return &s3.ListObjectVersionsOutput{}, nil
+			ListObjectVersionsFunc: func(contextMoqParam context.Context, listObjectVersionsInput *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
+				return s3response.ListVersionsResult{}, nil
 			},
 			GetBucketPolicyFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
 				return []byte{}, nil
@@ -395,6 +418,15 @@ func TestS3ApiController_ListActions(t *testing.T) {
 			GetObjectLockConfigurationFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
 				return objectLockResult, nil
 			},
+			GetBucketNotificationConfigurationFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+				return []byte{}, nil
+			},
+			GetPublicAccessBlockFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+				return []byte(`{"BlockPublicAcls":true}`), nil
+			},
+			GetBucketLoggingFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+				return []byte{}, nil
+			},
 		},
 	}
 
@@ -457,6 +489,33 @@ func TestS3ApiController_ListActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 200,
 		},
+		{
+			name: "Get-bucket-logging-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/my-bucket?logging", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Get-bucket-location-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/my-bucket?location", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Get-public-access-block-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/my-bucket?publicAccessBlock", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
 		{
 			name: "Get-object-lock-configuration-success",
 			app:  app,
@@ -466,6 +525,15 @@ func TestS3ApiController_ListActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 200,
 		},
+		{
+			name: "Get-bucket-notification-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/my-bucket?notification", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
 		{
 			name: "Get-bucket-acl-success",
 			app:  app,
@@ -612,6 +680,15 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 	</Tagging>
 	`
 
+	publicAccessBlockBody := `
+	<PublicAccessBlockConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+		<BlockPublicAcls>true</BlockPublicAcls>
+		<IgnorePublicAcls>false</IgnorePublicAcls>
+		<BlockPublicPolicy>true</BlockPublicPolicy>
+		<RestrictPublicBuckets>false</RestrictPublicBuckets>
+	</PublicAccessBlockConfiguration>
+	`
+
 	versioningBody := `
 	<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"> 
 		<Status>Enabled</Status> 
@@ -619,6 +696,16 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 	</VersioningConfiguration>
 	`
 
+	notificationBody := `
+	<NotificationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+		<TopicConfiguration>
+			<Id>hook</Id>
+			<Topic>https://example.com/hook</Topic>
+			<Event>s3:ObjectCreated:*</Event>
+		</TopicConfiguration>
+	</NotificationConfiguration>
+	`
+
 	policyBody := `
 	{
 		"Statement": [
@@ -646,12 +733,18 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 
 	s3ApiController := S3ApiController{
 		be: &BackendMock{
-			GetBucketAclFunc: func(context.Context, *s3.GetBucketAclInput) ([]byte, error) {
+			GetBucketAclFunc: func(_ context.Context, input *s3.GetBucketAclInput) ([]byte, error) {
+				if *input.Bucket == "missing-bucket" {
+					return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+				}
 				return acldata, nil
 			},
 			PutBucketAclFunc: func(context.Context, string, []byte) error {
 				return nil
 			},
+			PutBucketLoggingFunc: func(contextMoqParam context.Context, bucket string, config []byte) error {
+				return nil
+			},
 			CreateBucketFunc: func(context.Context, *s3.CreateBucketInput, []byte) error {
 				return nil
 			},
@@ -667,6 +760,15 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 			PutObjectLockConfigurationFunc: func(contextMoqParam context.Context, bucket string, config []byte) error {
 				return nil
 			},
+			PutBucketNotificationConfigurationFunc: func(contextMoqParam context.Context, bucket string, config []byte) error {
+				return nil
+			},
+			GetPublicAccessBlockFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+				return nil, s3err.GetAPIError(s3err.ErrNoSuchPublicAccessBlockConfiguration)
+			},
+			PutPublicAccessBlockFunc: func(contextMoqParam context.Context, bucket string, config []byte) error {
+				return nil
+			},
 		},
 	}
 	// Mock ctx.Locals
@@ -675,6 +777,7 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 		ctx.Locals("isRoot", true)
 		ctx.Locals("isDebug", false)
 		ctx.Locals("parsedAcl", auth.ACL{Owner: "valid access"})
+		ctx.Locals("region", "us-west-2")
 		return ctx.Next()
 	})
 	app.Put("/:bucket", s3ApiController.PutBucketActions)
@@ -752,6 +855,42 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 400,
 		},
+		{
+			name: "Put-bucket-versioning-invalid-status",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?versioning", strings.NewReader(strings.Replace(versioningBody, "Enabled", "Invalid", 1))),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-bucket-notification-invalid-body",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?notification", strings.NewReader("invalid_body")),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-bucket-notification-invalid-destination",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?notification", strings.NewReader(strings.Replace(notificationBody, "https://example.com/hook", "arn:aws:sns:us-east-1:123456789012:topic", 1))),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-bucket-notification-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?notification", strings.NewReader(notificationBody)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
 		{
 			name: "Put-bucket-versioning-success",
 			app:  app,
@@ -770,6 +909,24 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 400,
 		},
+		{
+			name: "Put-public-access-block-invalid-body",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?publicAccessBlock", strings.NewReader("invalid_body")),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-public-access-block-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?publicAccessBlock", strings.NewReader(publicAccessBlockBody)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
 		{
 			name: "Put-bucket-policy-success",
 			app:  app,
@@ -842,6 +999,69 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 200,
 		},
+		{
+			name: "Put-bucket-logging-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?logging", strings.NewReader(`<BucketLoggingStatus xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><LoggingEnabled><TargetBucket>log-bucket</TargetBucket><TargetPrefix>logs/</TargetPrefix></LoggingEnabled></BucketLoggingStatus>`)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Put-bucket-logging-disable-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?logging", strings.NewReader(`<BucketLoggingStatus xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></BucketLoggingStatus>`)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Put-bucket-logging-invalid-target",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?logging", strings.NewReader(`<BucketLoggingStatus xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><LoggingEnabled><TargetBucket>missing-bucket</TargetBucket></LoggingEnabled></BucketLoggingStatus>`)),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-bucket-logging-malformed",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?logging", strings.NewReader("invalid xml")),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-bucket-location-constraint-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket", strings.NewReader(`<CreateBucketConfiguration><LocationConstraint>us-west-2</LocationConstraint></CreateBucketConfiguration>`)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Put-bucket-invalid-location-constraint",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket", strings.NewReader(`<CreateBucketConfiguration><LocationConstraint>eu-west-1</LocationConstraint></CreateBucketConfiguration>`)),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-bucket-malformed-configuration",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket", strings.NewReader("invalid xml")),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
 	}
 	for _, tt := range tests {
 		resp, err := tt.app.Test(tt.args.req)
@@ -890,7 +1110,7 @@ func TestS3ApiController_PutActions(t *testing.T) {
 	retentionBody := `
 	<Retention xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
 		<Mode>GOVERNANCE</Mode>
-		<RetainUntilDate>2025-01-01T00:00:00Z</RetainUntilDate>
+		<RetainUntilDate>2099-01-01T00:00:00Z</RetainUntilDate>
 	</Retention>
 	`
 
@@ -906,7 +1126,10 @@ func TestS3ApiController_PutActions(t *testing.T) {
 			GetBucketAclFunc: func(context.Context, *s3.GetBucketAclInput) ([]byte, error) {
 				return acldata, nil
 			},
-			PutObjectAclFunc: func(context.Context, *s3.PutObjectAclInput) error {
+			GetObjectAclFunc: func(context.Context, *s3.GetObjectAclInput) ([]byte, error) {
+				return []byte(`{"Owner":"hello"}`), nil
+			},
+			PutObjectAclFunc: func(context.Context, string, string, []byte) error {
 				return nil
 			},
 			CopyObjectFunc: func(context.Context, *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
@@ -932,10 +1155,18 @@ func TestS3ApiController_PutActions(t *testing.T) {
 			PutObjectRetentionFunc: func(contextMoqParam context.Context, bucket, object, versionId string, retention []byte) error {
 				return nil
 			},
+			GetObjectRetentionFunc: func(contextMoqParam context.Context, bucket, object, versionId string) ([]byte, error) {
+				return nil, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)
+			},
 			GetObjectLockConfigurationFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
 				return nil, s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotFound)
 			},
 		},
+		iam: &IAMServiceMock{
+			GetUserAccountFunc: func(access string) (auth.Account, error) {
+				return auth.Account{Access: access}, nil
+			},
+		},
 	}
 	app.Use(func(ctx *fiber.Ctx) error {
 		ctx.Locals("account", auth.Account{Access: "valid access"})
@@ -975,6 +1206,15 @@ func TestS3ApiController_PutActions(t *testing.T) {
 	errAclReq.Header.Set("X-Amz-Acl", "private")
 	errAclReq.Header.Set("X-Amz-Grant-Read", "hello")
 
+	// PutObject invalid canned acl
+	invCannedAclReq := httptest.NewRequest(http.MethodPut, "/my-bucket/my-key", nil)
+	invCannedAclReq.Header.Set("X-Amz-Acl", "invalid")
+
+	// PutObject canned acl with grants
+	cannedAclGrtReq := httptest.NewRequest(http.MethodPut, "/my-bucket/my-key", nil)
+	cannedAclGrtReq.Header.Set("X-Amz-Acl", "bucket-owner-full-control")
+	cannedAclGrtReq.Header.Set("X-Amz-Grant-Read", "hello")
+
 	// invalid body & grt case
 	invAclBodyGrtReq := httptest.NewRequest(http.MethodPut, "/my-bucket/my-key?acl", strings.NewReader(body))
 	invAclBodyGrtReq.Header.Set("X-Amz-Grant-Read", "hello")
@@ -1022,6 +1262,15 @@ func TestS3ApiController_PutActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 400,
 		},
+		{
+			name: "put-object-retention-missing-retain-until-date",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket/my-key?retention", strings.NewReader(`<Retention><Mode>GOVERNANCE</Mode></Retention>`)),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
 		{
 			name: "put-object-retention-success",
 			app:  app,
@@ -1094,6 +1343,15 @@ func TestS3ApiController_PutActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 200,
 		},
+		{
+			name: "Put-object-acl-owner-mismatch",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket/my-key?acl", strings.NewReader(strings.ReplaceAll(body, "<ID>hello</ID>", "<ID>other</ID>"))),
+			},
+			wantErr:    false,
+			statusCode: 403,
+		},
 		{
 			name: "Put-object-acl-success-grt-case",
 			app:  app,
@@ -1130,6 +1388,24 @@ func TestS3ApiController_PutActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 200,
 		},
+		{
+			name: "Put-object-invalid-canned-acl",
+			app:  app,
+			args: args{
+				req: invCannedAclReq,
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-object-canned-acl-with-grants",
+			app:  app,
+			args: args{
+				req: cannedAclGrtReq,
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
 		{
 			name: "Put-object-success",
 			app:  app,
@@ -1169,6 +1445,9 @@ func TestS3ApiController_DeleteBucket(t *testing.T) {
 			DeleteBucketTaggingFunc: func(contextMoqParam context.Context, bucket string) error {
 				return nil
 			},
+			DeletePublicAccessBlockFunc: func(contextMoqParam context.Context, bucket string) error {
+				return nil
+			},
 		},
 	}
 
@@ -1207,6 +1486,15 @@ func TestS3ApiController_DeleteBucket(t *testing.T) {
 			wantErr:    false,
 			statusCode: 204,
 		},
+		{
+			name: "Delete-public-access-block-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodDelete, "/my-bucket?publicAccessBlock", nil),
+			},
+			wantErr:    false,
+			statusCode: 204,
+		},
 	}
 	for _, tt := range tests {
 		resp, err := tt.app.Test(tt.args.req)
@@ -1295,6 +1583,191 @@ func TestS3ApiController_DeleteObjects(t *testing.T) {
 	}
 }
 
+func TestS3ApiController_DeleteObjectsQuiet(t *testing.T) {
+	app := fiber.New()
+	s3ApiController := S3ApiController{
+		be: &BackendMock{
+			GetBucketAclFunc: func(context.Context, *s3.GetBucketAclInput) ([]byte, error) {
+				return acldata, nil
+			},
+			DeleteObjectsFunc: func(_ context.Context, input *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
+				var res s3response.DeleteResult
+				for _, obj := range input.Delete.Objects {
+					if strings.HasPrefix(*obj.Key, "locked") {
+						res.Error = append(res.Error, types.Error{Key: obj.Key, Code: getPtr("AccessDenied")})
+						continue
+					}
+					res.Deleted = append(res.Deleted, types.DeletedObject{Key: obj.Key})
+				}
+				return res, nil
+			},
+			GetObjectLockConfigurationFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+				return nil, s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotFound)
+			},
+		},
+	}
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "valid access"})
+		ctx.Locals("isRoot", true)
+		ctx.Locals("isDebug", false)
+		ctx.Locals("parsedAcl", auth.ACL{})
+		return ctx.Next()
+	})
+	app.Post("/:bucket", s3ApiController.DeleteObjects)
+
+	tests := []struct {
+		name    string
+		quiet   string
+		deleted []string
+	}{
+		{name: "Delete-Objects-verbose", deleted: []string{"obj1", "obj2"}},
+		{name: "Delete-Objects-quiet-false", quiet: "<Quiet>false</Quiet>", deleted: []string{"obj1", "obj2"}},
+		{name: "Delete-Objects-quiet", quiet: "<Quiet>true</Quiet>"},
+	}
+	for _, tt := range tests {
+		body := `<Delete>` + tt.quiet + `<Object><Key>obj1</Key></Object><Object><Key>locked1</Key></Object><Object><Key>obj2</Key></Object></Delete>`
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/my-bucket?delete", strings.NewReader(body)))
+		if err != nil {
+			t.Fatalf("S3ApiController.DeleteObjects() %v error = %v", tt.name, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("S3ApiController.DeleteObjects() %v statusCode = %v, wantStatusCode = 200", tt.name, resp.StatusCode)
+		}
+
+		var res s3response.DeleteResult
+		if err := xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatalf("S3ApiController.DeleteObjects() %v decode: %v", tt.name, err)
+		}
+
+		// quiet mode only reports the failed keys
+		var deleted []string
+		for _, d := range res.Deleted {
+			deleted = append(deleted, *d.Key)
+		}
+		if !reflect.DeepEqual(deleted, tt.deleted) {
+			t.Errorf("S3ApiController.DeleteObjects() %v deleted = %v, want %v", tt.name, deleted, tt.deleted)
+		}
+		if len(res.Error) != 1 || *res.Error[0].Key != "locked1" {
+			t.Errorf("S3ApiController.DeleteObjects() %v errors = %+v, want locked1", tt.name, res.Error)
+		}
+	}
+}
+
+func TestS3ApiController_PostObject(t *testing.T) {
+	type args struct {
+		req *http.Request
+	}
+
+	var gotKey, gotContentType string
+	app := fiber.New()
+	s3ApiController := S3ApiController{
+		be: &BackendMock{
+			GetBucketAclFunc: func(context.Context, *s3.GetBucketAclInput) ([]byte, error) {
+				return acldata, nil
+			},
+			PutObjectFunc: func(_ context.Context, input *s3.PutObjectInput) (string, error) {
+				gotKey = *input.Key
+				gotContentType = *input.ContentType
+				return "ETag", nil
+			},
+			GetObjectLockConfigurationFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+				return nil, s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotFound)
+			},
+		},
+	}
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "valid access"})
+		ctx.Locals("isRoot", true)
+		ctx.Locals("isDebug", false)
+		ctx.Locals("parsedAcl", auth.ACL{})
+		form, err := utils.ParsePostForm(ctx)
+		if err == nil {
+			ctx.Locals("postForm", form)
+		}
+		return ctx.Next()
+	})
+	app.Post("/:bucket", s3ApiController.PostBucketActions)
+
+	newPostRequest := func(fields map[string]string) *http.Request {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		for k, v := range fields {
+			w.WriteField(k, v)
+		}
+		fw, _ := w.CreateFormFile("file", "photo.jpg")
+		fw.Write([]byte("data"))
+		w.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/my-bucket", &buf)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		return req
+	}
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		args       args
+		wantErr    bool
+		statusCode int
+	}{
+		{
+			name: "Post-object-success",
+			app:  app,
+			args: args{
+				req: newPostRequest(map[string]string{
+					"key":          "uploads/${filename}",
+					"Content-Type": "image/jpeg",
+				}),
+			},
+			wantErr:    false,
+			statusCode: 204,
+		},
+		{
+			name: "Post-object-success-status-201",
+			app:  app,
+			args: args{
+				req: newPostRequest(map[string]string{
+					"key":                   "uploads/${filename}",
+					"Content-Type":          "image/jpeg",
+					"success_action_status": "201",
+				}),
+			},
+			wantErr:    false,
+			statusCode: 201,
+		},
+		{
+			name: "Post-object-redirect",
+			app:  app,
+			args: args{
+				req: newPostRequest(map[string]string{
+					"key":                     "uploads/${filename}",
+					"Content-Type":            "image/jpeg",
+					"success_action_redirect": "http://example.com/done",
+				}),
+			},
+			wantErr:    false,
+			statusCode: 303,
+		},
+	}
+	for _, tt := range tests {
+		resp, err := tt.app.Test(tt.args.req)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("S3ApiController.PostObject() error = %v, wantErr %v", err, tt.wantErr)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("S3ApiController.PostObject() statusCode = %v, wantStatusCode = %v", resp.StatusCode, tt.statusCode)
+		}
+
+		if gotKey != "uploads/photo.jpg" || gotContentType != "image/jpeg" {
+			t.Errorf("S3ApiController.PostObject() key = %v, content type = %v", gotKey, gotContentType)
+		}
+	}
+}
+
 func TestS3ApiController_DeleteActions(t *testing.T) {
 	type args struct {
 		req *http.Request
@@ -1620,6 +2093,9 @@ func TestS3ApiController_CreateActions(t *testing.T) {
 			CompleteMultipartUploadFunc: func(context.Context, *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
 				return &s3.CompleteMultipartUploadOutput{}, nil
 			},
+			GetObjectLockConfigurationFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+				return nil, s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotFound)
+			},
 			CreateMultipartUploadFunc: func(context.Context, *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
 				return &s3.CreateMultipartUploadOutput{}, nil
 			},
@@ -1892,3 +2368,93 @@ func Test_response(t *testing.T) {
 		})
 	}
 }
+
+func TestKMSDecryptWriter(t *testing.T) {
+	provider, err := kms.NewStatic(map[string][]byte{"key1": bytes.Repeat([]byte{1}, 32)}, "key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(strings.Repeat("0123456789", 10))
+	r, meta, err := kms.EncryptObject(context.Background(), provider, "key1", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var heads int
+	s3ApiController := S3ApiController{
+		kms: provider,
+		be: &BackendMock{
+			HeadObjectFunc: func(contextMoqParam context.Context, headObjectInput *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				heads++
+				size := int64(len(ciphertext))
+				return &s3.HeadObjectOutput{ContentLength: &size, Metadata: meta}, nil
+			},
+		},
+	}
+
+	app := fiber.New()
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	tests := []struct {
+		name        string
+		setMeta     bool
+		meta        map[string]string
+		acceptRange string
+		offset      int64
+		written     []byte
+		want        []byte
+		wantHeads   int
+	}{
+		{
+			name:      "backend-metadata",
+			setMeta:   true,
+			meta:      meta,
+			offset:    10,
+			written:   ciphertext[10:],
+			want:      data[10:],
+			wantHeads: 0,
+		},
+		{
+			name:        "head-object-fallback",
+			acceptRange: "bytes=25-",
+			written:     ciphertext[25:],
+			want:        data[25:],
+			wantHeads:   1,
+		},
+		{
+			name:      "not-encrypted",
+			setMeta:   true,
+			written:   data,
+			want:      data,
+			wantHeads: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heads = 0
+			var out bytes.Buffer
+			w := s3ApiController.kmsDecryptWriter(ctx, "my-bucket", "my-obj", "", tt.acceptRange, &out)
+			if tt.setMeta {
+				if err := backend.SetWriterMetadata(w, tt.meta, tt.offset); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := w.Write(tt.written); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, out.Bytes())
+			}
+			if heads != tt.wantHeads {
+				t.Errorf("expected %v HeadObject calls, got %v", tt.wantHeads, heads)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@
 package middlewares
 
 import (
+	"net"
 	"net/http"
 	"regexp"
 	"strings"
@@ -38,6 +39,19 @@ func AclParser(be backend.Backend, logger s3log.AuditLogger, readonly bool) fibe
 		path := ctx.Path()
 		pathParts := strings.Split(path, "/")
 		bucket := pathParts[1]
+
+		// store the request properties bucket policy conditions are
+		// evaluated against
+		reqCtx := auth.RequestContext{
+			SourceIP:        net.ParseIP(ctx.IP()),
+			SecureTransport: ctx.Secure(),
+		}
+		if singlePath.MatchString(path) && ctx.Method() == http.MethodGet {
+			prefix := ctx.Query("prefix")
+			reqCtx.Prefix = &prefix
+		}
+		ctx.Locals("requestContext", reqCtx)
+
 		if path == "/" && ctx.Method() == http.MethodGet {
 			return ctx.Next()
 		}
@@ -50,7 +64,10 @@ func AclParser(be backend.Backend, logger s3log.AuditLogger, readonly bool) fibe
 			!ctx.Request().URI().QueryArgs().Has("tagging") &&
 			!ctx.Request().URI().QueryArgs().Has("versioning") &&
 			!ctx.Request().URI().QueryArgs().Has("policy") &&
-			!ctx.Request().URI().QueryArgs().Has("object-lock") {
+			!ctx.Request().URI().QueryArgs().Has("object-lock") &&
+			!ctx.Request().URI().QueryArgs().Has("notification") &&
+			!ctx.Request().URI().QueryArgs().Has("logging") &&
+			!ctx.Request().URI().QueryArgs().Has("publicAccessBlock") {
 			if err := auth.MayCreateBucket(acct, isRoot); err != nil {
 				return controllers.SendXMLResponse(ctx, nil, err, &controllers.MetaOpts{Logger: logger, Action: "CreateBucket"})
 			}
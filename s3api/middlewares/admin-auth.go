@@ -0,0 +1,68 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3api/controllers"
+)
+
+// VerifyAdminToken authenticates the admin api requests with the bearer
+// token, or with the verified tls client certificate when clientCert is
+// set, instead of SigV4. The authenticated requests act as an admin
+// account named after the certificate common name, or "admin-token"
+// for token requests.
+func VerifyAdminToken(token string, clientCert bool) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		actor := "admin-token"
+
+		if clientCert {
+			state := ctx.Context().TLSConnectionState()
+			if state == nil || len(state.PeerCertificates) == 0 {
+				return sendAdminAuthError(ctx, "a verified tls client certificate is required")
+			}
+			actor = state.PeerCertificates[0].Subject.CommonName
+		}
+
+		if token != "" {
+			bearer, ok := strings.CutPrefix(ctx.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+				return sendAdminAuthError(ctx, "missing or invalid bearer token")
+			}
+		}
+
+		ctx.Locals("isRoot", true)
+		ctx.Locals("account", auth.Account{Access: actor, Role: auth.RoleAdmin})
+		return ctx.Next()
+	}
+}
+
+func sendAdminAuthError(ctx *fiber.Ctx, msg string) error {
+	ctx.Set("WWW-Authenticate", "Bearer")
+	return ctx.Status(http.StatusUnauthorized).JSON(controllers.AdminErrorResponse{
+		Version: controllers.AdminErrorVersion,
+		Error: controllers.AdminError{
+			Code:      "Unauthorized",
+			Message:   msg,
+			RequestID: uuid.NewString(),
+		},
+	})
+}
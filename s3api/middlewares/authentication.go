@@ -40,7 +40,10 @@ type RootUserConfig struct {
 	Secret string
 }
 
-func VerifyV4Signature(root RootUserConfig, iam auth.IAMService, logger s3log.AuditLogger, region string, debug bool) fiber.Handler {
+// VerifyV4Signature verifies the request signature. When anonymous is set,
+// unsigned requests are authorized as the anonymous account and only get
+// the access granted to everyone by bucket policies and acls.
+func VerifyV4Signature(root RootUserConfig, iam auth.IAMService, logger s3log.AuditLogger, region string, debug, anonymous bool) fiber.Handler {
 	acct := accounts{root: root, iam: iam}
 
 	return func(ctx *fiber.Ctx) error {
@@ -53,6 +56,11 @@ func VerifyV4Signature(root RootUserConfig, iam auth.IAMService, logger s3log.Au
 		ctx.Locals("region", region)
 		ctx.Locals("startTime", time.Now())
 		authorization := ctx.Get("Authorization")
+		if authorization == "" && anonymous {
+			ctx.Locals("isRoot", false)
+			ctx.Locals("account", auth.Account{})
+			return ctx.Next()
+		}
 		if authorization == "" {
 			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrAuthHeaderEmpty), logger)
 		}
@@ -119,8 +127,9 @@ func VerifyV4Signature(root RootUserConfig, iam auth.IAMService, logger s3log.Au
 
 		hashPayload := ctx.Get("X-Amz-Content-Sha256")
 		if !utils.IsSpecialPayload(hashPayload) {
-			// Calculate the hash of the request payload
-			hashedPayload := sha256.Sum256(ctx.Body())
+			// Calculate the hash of the request payload as sent, the
+			// payload isn't decoded by its content encoding
+			hashedPayload := sha256.Sum256(ctx.Request().Body())
 			hexPayload := hex.EncodeToString(hashedPayload[:])
 
 			// Compare the calculated hash with the hash provided
@@ -0,0 +1,97 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/gofiber/fiber/v2"
+)
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestVerifyV4SignatureContentEncoding(t *testing.T) {
+	root := RootUserConfig{Access: "user", Secret: "pass"}
+	region := "us-east-1"
+
+	app := fiber.New()
+	app.Use(VerifyV4Signature(root, nil, nil, region, false, false))
+	app.All("/*", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     []byte
+		encoding string
+	}{
+		// the create multipart upload content encoding applies to
+		// the object, the request itself has no body
+		{name: "gzip-empty-body", method: http.MethodPost, target: "/bucket/obj?uploads", encoding: "gzip"},
+		{name: "gzip-body", method: http.MethodPut, target: "/bucket?tagging", body: gzipData(t, "<Tagging/>"), encoding: "gzip"},
+		{name: "plain-body", method: http.MethodPut, target: "/bucket?tagging", body: []byte("<Tagging/>")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			// the payload hash covers the body as sent
+			sum := sha256.Sum256(tt.body)
+			hash := hex.EncodeToString(sum[:])
+			req.Header.Set("X-Amz-Content-Sha256", hash)
+			err := v4.NewSigner().SignHTTP(context.Background(),
+				aws.Credentials{AccessKeyID: root.Access, SecretAccessKey: root.Secret},
+				req, hash, "s3", region, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status = %v, want %v", resp.StatusCode, http.StatusOK)
+			}
+		})
+	}
+}
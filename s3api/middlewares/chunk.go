@@ -16,11 +16,13 @@ package middlewares
 
 import (
 	"io"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/s3api/utils"
+	"github.com/versity/versitygw/s3err"
 	"github.com/versity/versitygw/s3log"
 )
 
@@ -28,11 +30,15 @@ import (
 // request appears to be a chunked upload
 func ProcessChunkedBody(root RootUserConfig, iam auth.IAMService, logger s3log.AuditLogger, region string) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
-		decodedLength := ctx.Get("X-Amz-Decoded-Content-Length")
-		if decodedLength == "" {
+		payload := ctx.Get("X-Amz-Content-Sha256")
+		if !strings.HasPrefix(payload, "STREAMING-") {
 			return ctx.Next()
 		}
-		// TODO: validate content length
+		// Only the signed chunk payload without trailing headers is
+		// currently supported
+		if payload != utils.StreamingPayload {
+			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrNotImplemented), logger)
+		}
 
 		authData, err := utils.ParseAuthorization(ctx.Get("Authorization"))
 		if err != nil {
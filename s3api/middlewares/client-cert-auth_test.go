@@ -0,0 +1,234 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testCA{cert: cert, key: key}
+}
+
+// issue signs a leaf certificate for the common name
+func (ca testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestVerifyClientCert(t *testing.T) {
+	trusted := newTestCA(t, "trusted")
+	untrusted := newTestCA(t, "untrusted")
+
+	iam, err := auth.NewInternal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = iam.CreateAccount(auth.Account{Access: "user1", Secret: "secret1", Role: auth.RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := RootUserConfig{Access: "root", Secret: "rootsecret"}
+
+	app := fiber.New()
+	app.Use(VerifyClientCert(root, iam, nil, "us-east-1"))
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		acct, ok := ctx.Locals("account").(auth.Account)
+		if !ok {
+			return ctx.SendString("unauthenticated")
+		}
+		isRoot, _ := ctx.Locals("isRoot").(bool)
+		if isRoot {
+			return ctx.SendString("root:" + acct.Access + ":" + string(acct.Role))
+		}
+		return ctx.SendString(acct.Access + ":" + string(acct.Role))
+	})
+
+	cas := x509.NewCertPool()
+	cas.AddCert(trusted.cert)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln = tls.NewListener(ln, &tls.Config{
+		Certificates: []tls.Certificate{trusted.issue(t, "server", x509.ExtKeyUsageServerAuth)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    cas,
+		MinVersion:   tls.VersionTLS12,
+	})
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	tests := []struct {
+		name      string
+		cert      *tls.Certificate
+		authHdr   string
+		wantErr   bool
+		status    int
+		body      string
+		bodyMatch string
+	}{
+		{
+			name:   "user-account",
+			cert:   ptr(trusted.issue(t, "user1", x509.ExtKeyUsageClientAuth)),
+			status: http.StatusOK,
+			body:   "user1:user",
+		},
+		{
+			name:   "root-account",
+			cert:   ptr(trusted.issue(t, "root", x509.ExtKeyUsageClientAuth)),
+			status: http.StatusOK,
+			body:   "root:root:admin",
+		},
+		{
+			name:      "unknown-account",
+			cert:      ptr(trusted.issue(t, "nobody", x509.ExtKeyUsageClientAuth)),
+			status:    http.StatusForbidden,
+			bodyMatch: "InvalidAccessKeyId",
+		},
+		{
+			name:    "signed-request-left-to-sigv4",
+			cert:    ptr(trusted.issue(t, "user1", x509.ExtKeyUsageClientAuth)),
+			authHdr: "AWS4-HMAC-SHA256 Credential=user1/20240101/us-east-1/s3/aws4_request",
+			status:  http.StatusOK,
+			body:    "unauthenticated",
+		},
+		{
+			name:    "untrusted-ca",
+			cert:    ptr(untrusted.issue(t, "user1", x509.ExtKeyUsageClientAuth)),
+			wantErr: true,
+		},
+		{
+			name:    "no-certificate",
+			wantErr: true,
+		},
+	}
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(trusted.cert)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &tls.Config{RootCAs: rootCAs}
+			if tt.cert != nil {
+				config.Certificates = []tls.Certificate{*tt.cert}
+			}
+			client := &http.Client{
+				Transport: &http.Transport{TLSClientConfig: config},
+				Timeout:   5 * time.Second,
+			}
+			defer client.CloseIdleConnections()
+
+			req, err := http.NewRequest(http.MethodGet, "https://"+ln.Addr().String()+"/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.authHdr != "" {
+				req.Header.Set("Authorization", tt.authHdr)
+			}
+
+			resp, err := client.Do(req)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("expected the tls handshake to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var body strings.Builder
+			if _, err := io.Copy(&body, resp.Body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %v, want %v", resp.StatusCode, tt.status)
+			}
+			if tt.body != "" && body.String() != tt.body {
+				t.Errorf("body = %q, want %q", body.String(), tt.body)
+			}
+			if tt.bodyMatch != "" && !strings.Contains(body.String(), tt.bodyMatch) {
+				t.Errorf("body = %q, want %q", body.String(), tt.bodyMatch)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/s3api/controllers"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+)
+
+// IPFilter is the CIDR allow and deny list of a listener. The denied
+// networks take precedence, and when allowed networks are set the client
+// address must be in one of them.
+type IPFilter struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// NewIPFilter parses the allowed and denied CIDRs, a single address is
+// taken as a host network
+func NewIPFilter(allow, deny []string) (*IPFilter, error) {
+	f := &IPFilter{}
+	var err error
+	f.allow, err = parseCIDRs(allow)
+	if err != nil {
+		return nil, err
+	}
+	f.deny, err = parseCIDRs(deny)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip address %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q: %w", cidr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// Allows returns true if the filter lets the ip through
+func (f *IPFilter) Allows(ip net.IP) bool {
+	for _, n := range f.deny {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, n := range f.allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Listener tags the connections accepted by ln with the filter, the
+// FilterIP middleware applies it to their requests and counts the
+// rejected ones in the optional listener stats
+func (f *IPFilter) Listener(ln net.Listener, stats *controllers.ListenerStats) net.Listener {
+	return filterListener{Listener: ln, filter: f, stats: stats}
+}
+
+type filterListener struct {
+	net.Listener
+	filter *IPFilter
+	stats  *controllers.ListenerStats
+}
+
+func (l filterListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return filterConn{Conn: conn, filter: l.filter, stats: l.stats}, nil
+}
+
+type filterConn struct {
+	net.Conn
+	filter *IPFilter
+	stats  *controllers.ListenerStats
+}
+
+// FilterIP rejects the requests from the client addresses not allowed
+// by the ip filter of the listener they were received on. It runs
+// before the authentication so the rejected clients can't probe the
+// credentials.
+func FilterIP(logger s3log.AuditLogger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		conn := ctx.Context().Conn()
+		if tc, ok := conn.(*tls.Conn); ok {
+			conn = tc.NetConn()
+		}
+		fc, ok := conn.(filterConn)
+		if !ok {
+			return ctx.Next()
+		}
+
+		if !fc.filter.Allows(ctx.Context().RemoteIP()) {
+			if fc.stats != nil {
+				fc.stats.Deny()
+			}
+			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrAccessDenied), logger)
+		}
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"github.com/versity/versitygw/s3api/controllers"
+)
+
+func TestNewIPFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "cidrs", allow: []string{"10.0.0.0/8", " 192.168.1.0/24 "}, deny: []string{"fd00::/8"}},
+		{name: "single-addresses", allow: []string{"10.0.0.1", "::1"}},
+		{name: "invalid-allow-cidr", allow: []string{"10.0.0.0/33"}, wantErr: true},
+		{name: "invalid-deny-address", deny: []string{"10.0.0"}, wantErr: true},
+		{name: "hostname", allow: []string{"localhost"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewIPFilter(tt.allow, tt.deny)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewIPFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIPFilterAllows(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		ip    string
+		want  bool
+	}{
+		{name: "no-lists", ip: "203.0.113.7", want: true},
+		{name: "allowed-cidr", allow: []string{"10.0.0.0/8"}, ip: "10.1.2.3", want: true},
+		{name: "outside-allowed-cidr", allow: []string{"10.0.0.0/8"}, ip: "11.1.2.3", want: false},
+		{name: "denied-cidr", deny: []string{"192.168.0.0/16"}, ip: "192.168.4.4", want: false},
+		{name: "outside-denied-cidr", deny: []string{"192.168.0.0/16"}, ip: "192.169.4.4", want: true},
+		{name: "deny-takes-precedence", allow: []string{"10.0.0.0/8"}, deny: []string{"10.0.0.0/24"}, ip: "10.0.0.9", want: false},
+		{name: "allowed-next-to-denied", allow: []string{"10.0.0.0/8"}, deny: []string{"10.0.0.0/24"}, ip: "10.0.1.9", want: true},
+		{name: "single-address", allow: []string{"10.0.0.1"}, ip: "10.0.0.1", want: true},
+		{name: "single-address-neighbour", allow: []string{"10.0.0.1"}, ip: "10.0.0.2", want: false},
+		{name: "ipv6-allowed", allow: []string{"2001:db8::/32"}, ip: "2001:db8::1", want: true},
+		{name: "ipv6-outside", allow: []string{"2001:db8::/32"}, ip: "2001:db9::1", want: false},
+		{name: "ipv4-mapped-ipv6", allow: []string{"10.0.0.0/8"}, ip: "::ffff:10.0.0.1", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewIPFilter(tt.allow, tt.deny)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Allows(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("Allows(%v) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+// addrConn is a connection only reporting its remote address
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr { return c.remote }
+func (c addrConn) LocalAddr() net.Addr  { return c.remote }
+
+func TestFilterIP(t *testing.T) {
+	f, err := NewIPFilter([]string{"10.0.0.0/8"}, []string{"10.9.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		ip       string
+		filtered bool
+		want     int
+	}{
+		{name: "allowed", ip: "10.1.1.1", filtered: true, want: http.StatusOK},
+		{name: "denied", ip: "10.9.1.1", filtered: true, want: http.StatusForbidden},
+		{name: "not-allowed", ip: "172.16.1.1", filtered: true, want: http.StatusForbidden},
+		{name: "unfiltered-listener", ip: "172.16.1.1", want: http.StatusOK},
+	}
+
+	stats := new(controllers.IPFilterStats)
+	lstats := stats.Listener("127.0.0.1:7070")
+	stats.Listener("127.0.0.1:7071")
+
+	app := fiber.New()
+	app.Use(FilterIP(nil))
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+	handler := app.Handler()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conn net.Conn = addrConn{remote: &net.TCPAddr{IP: net.ParseIP(tt.ip), Port: 1234}}
+			if tt.filtered {
+				conn = filterConn{Conn: conn, filter: f, stats: lstats}
+			}
+
+			var rctx fasthttp.RequestCtx
+			rctx.Init2(conn, nil, false)
+			rctx.Request.SetRequestURI("/")
+			handler(&rctx)
+
+			if got := rctx.Response.StatusCode(); got != tt.want {
+				t.Errorf("status = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the rejected requests are counted per listener
+	want := []controllers.IPFilterListenerStats{
+		{Listener: "127.0.0.1:7070", Denied: 2},
+		{Listener: "127.0.0.1:7071", Denied: 0},
+	}
+	if got := stats.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("stats = %v, want %v", got, want)
+	}
+}
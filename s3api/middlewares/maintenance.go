@@ -0,0 +1,49 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/s3api/controllers"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+)
+
+// CheckMaintenance rejects the mutating requests while the gateway is
+// in maintenance mode
+func CheckMaintenance(m *controllers.Maintenance, logger s3log.AuditLogger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if !m.Enabled() || !isMutating(ctx) {
+			return ctx.Next()
+		}
+
+		return controllers.SendResponse(ctx, s3err.GetAPIError(s3err.ErrServiceUnavailable), &controllers.MetaOpts{Logger: logger})
+	}
+}
+
+// isMutating returns true for the requests modifying buckets or objects,
+// the admin api PATCH requests are not
+func isMutating(ctx *fiber.Ctx) bool {
+	switch ctx.Method() {
+	case http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		// SelectObjectContent only reads the object
+		return !ctx.Request().URI().QueryArgs().Has("select")
+	}
+	return false
+}
@@ -32,6 +32,10 @@ func VerifyMD5Body(logger s3log.AuditLogger) fiber.Handler {
 			return ctx.Next()
 		}
 
+		if !utils.IsValidMd5Sum(incomingSum) {
+			return controllers.SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidDigest), &controllers.MetaOpts{Logger: logger})
+		}
+
 		if utils.IsBigDataAction(ctx) {
 			var err error
 			wrapBodyReader(ctx, func(r io.Reader) io.Reader {
@@ -44,11 +48,13 @@ func VerifyMD5Body(logger s3log.AuditLogger) fiber.Handler {
 			return ctx.Next()
 		}
 
-		sum := md5.Sum(ctx.Body())
+		// the digest covers the body as sent, not decoded by its
+		// content encoding
+		sum := md5.Sum(ctx.Request().Body())
 		calculatedSum := utils.Md5SumString(sum[:])
 
 		if incomingSum != calculatedSum {
-			return controllers.SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidDigest), &controllers.MetaOpts{Logger: logger})
+			return controllers.SendResponse(ctx, s3err.GetAPIError(s3err.ErrBadDigest), &controllers.MetaOpts{Logger: logger})
 		}
 
 		return ctx.Next()
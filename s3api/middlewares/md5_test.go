@@ -0,0 +1,75 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestVerifyMD5Body(t *testing.T) {
+	app := fiber.New()
+	app.Use(VerifyMD5Body(nil))
+	app.All("/*", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	md5sum := func(b []byte) string {
+		sum := md5.Sum(b)
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	policy := []byte(`{"Statement":[]}`)
+	gzipped := gzipData(t, string(policy))
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		md5      string
+		want     int
+	}{
+		{name: "plain-body", body: policy, md5: md5sum(policy), want: http.StatusOK},
+		{name: "plain-body-mismatch", body: policy, md5: md5sum([]byte("other")), want: http.StatusBadRequest},
+		// the digest covers the body as sent, not decoded by its
+		// content encoding
+		{name: "gzip-body", body: gzipped, encoding: "gzip", md5: md5sum(gzipped), want: http.StatusOK},
+		{name: "gzip-body-decoded-digest", body: gzipped, encoding: "gzip", md5: md5sum(policy), want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/bucket?policy", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Md5", tt.md5)
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %v, want %v", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3api/utils"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+)
+
+// VerifyPostPolicy authenticates browser based POST object uploads
+// with the signed policy document sent within the multipart form
+func VerifyPostPolicy(root RootUserConfig, iam auth.IAMService, logger s3log.AuditLogger, region string) fiber.Handler {
+	acct := accounts{root: root, iam: iam}
+
+	return func(ctx *fiber.Ctx) error {
+		if !isPostObject(ctx) {
+			return ctx.Next()
+		}
+
+		ctx.Locals("region", region)
+		ctx.Locals("startTime", time.Now())
+
+		form, err := utils.ParsePostForm(ctx)
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+
+		authData, err := utils.ParsePostCredential(form, region)
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+
+		ctx.Locals("isRoot", authData.Access == root.Access)
+		account, err := acct.getAccount(authData.Access)
+		if err == auth.ErrNoSuchUser {
+			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidAccessKeyID), logger)
+		}
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+
+		err = utils.CheckPostSignature(form, authData, account.Secret)
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+
+		policy, err := utils.ParsePostPolicy(form.Fields["policy"])
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+
+		bucket := strings.Split(ctx.Path(), "/")[1]
+		err = policy.Validate(form, bucket)
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+
+		ctx.Locals("account", account)
+		ctx.Locals("postForm", form)
+
+		return ctx.Next()
+	}
+}
+
+// isPostObject returns true for the multipart/form-data bucket POST
+// requests that are not otherwise authorized
+func isPostObject(ctx *fiber.Ctx) bool {
+	if ctx.Method() != http.MethodPost {
+		return false
+	}
+	path := strings.Split(strings.Trim(ctx.Path(), "/"), "/")
+	if len(path) != 1 || path[0] == "" {
+		return false
+	}
+	if ctx.Request().URI().QueryArgs().Len() != 0 || ctx.Get("Authorization") != "" {
+		return false
+	}
+
+	return strings.HasPrefix(ctx.Get("Content-Type"), fiber.MIMEMultipartForm)
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3api/controllers"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+)
+
+// RateLimitConfig is the request rate limit configuration. Rate is
+// the sustained requests per second and Burst the number of requests
+// that may be served at once. Authenticated requests are limited per
+// access key, anonymous requests per client ip.
+type RateLimitConfig struct {
+	Rate      float64
+	Burst     int
+	AnonRate  float64
+	AnonBurst int
+}
+
+// rateLimiterIdle is how long an unused bucket is kept around
+const rateLimiterIdle = 10 * time.Minute
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = int(math.Ceil(rate))
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token for key, returning the time to wait for the
+// next token if none is available
+func (r *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+
+	if now.Sub(r.lastPrune) > rateLimiterIdle {
+		for k, b := range r.buckets {
+			if now.Sub(b.last) > rateLimiterIdle {
+				delete(r.buckets, k)
+			}
+		}
+		r.lastPrune = now
+	}
+
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: r.burst, last: now}
+		r.buckets[key] = b
+	}
+
+	b.tokens = math.Min(r.burst, b.tokens+now.Sub(b.last).Seconds()*r.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / r.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// RateLimit rejects requests exceeding the configured rates with
+// SlowDown. It must run after the authentication middlewares.
+func RateLimit(cfg RateLimitConfig, logger s3log.AuditLogger) fiber.Handler {
+	accts := newRateLimiter(cfg.Rate, cfg.Burst)
+	anon := newRateLimiter(cfg.AnonRate, cfg.AnonBurst)
+
+	return func(ctx *fiber.Ctx) error {
+		acct, _ := ctx.Locals("account").(auth.Account)
+
+		limiter, key := accts, acct.Access
+		if acct.IsAnonymous() {
+			limiter, key = anon, ctx.IP()
+		}
+		if limiter == nil {
+			return ctx.Next()
+		}
+
+		ok, wait := limiter.allow(key, time.Now())
+		if ok {
+			return ctx.Next()
+		}
+
+		ctx.Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return controllers.SendResponse(ctx, s3err.GetAPIError(s3err.ErrSlowDown), &controllers.MetaOpts{Logger: logger})
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	type step struct {
+		key     string
+		after   time.Duration
+		allowed bool
+		wait    time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		steps []step
+	}{
+		{
+			name:  "burst-exhaustion",
+			rate:  1,
+			burst: 3,
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "a", allowed: true},
+				{key: "a", allowed: true},
+				{key: "a", allowed: false, wait: time.Second},
+			},
+		},
+		{
+			name:  "refill",
+			rate:  2,
+			burst: 1,
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "a", allowed: false, wait: 500 * time.Millisecond},
+				{key: "a", after: 250 * time.Millisecond, allowed: false, wait: 250 * time.Millisecond},
+				{key: "a", after: 250 * time.Millisecond, allowed: true},
+				{key: "a", allowed: false, wait: 500 * time.Millisecond},
+			},
+		},
+		{
+			name:  "refill-capped-at-burst",
+			rate:  10,
+			burst: 2,
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "a", allowed: true},
+				{key: "a", after: time.Hour, allowed: true},
+				{key: "a", allowed: true},
+				{key: "a", allowed: false, wait: 100 * time.Millisecond},
+			},
+		},
+		{
+			name:  "keys-limited-separately",
+			rate:  1,
+			burst: 1,
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "a", allowed: false, wait: time.Second},
+				{key: "b", allowed: true},
+				{key: "b", allowed: false, wait: time.Second},
+			},
+		},
+		{
+			name: "default-burst-is-rate",
+			rate: 2.5,
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "a", allowed: true},
+				{key: "a", allowed: true},
+				{key: "a", allowed: false, wait: 400 * time.Millisecond},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRateLimiter(tt.rate, tt.burst)
+			now := time.Now()
+			for i, s := range tt.steps {
+				now = now.Add(s.after)
+				allowed, wait := r.allow(s.key, now)
+				if allowed != s.allowed {
+					t.Fatalf("step %v: allowed = %v, want %v", i, allowed, s.allowed)
+				}
+				if diff := wait - s.wait; diff < -time.Millisecond || diff > time.Millisecond {
+					t.Fatalf("step %v: wait = %v, want %v", i, wait, s.wait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	if r := newRateLimiter(0, 10); r != nil {
+		t.Errorf("expected no limiter for a zero rate")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    RateLimitConfig
+		access string
+		want   []int
+	}{
+		{
+			name:   "account-exhausted",
+			cfg:    RateLimitConfig{Rate: 0.001, Burst: 2},
+			access: "user",
+			want:   []int{http.StatusOK, http.StatusOK, http.StatusServiceUnavailable},
+		},
+		{
+			name: "anonymous-exhausted",
+			cfg:  RateLimitConfig{Rate: 0.001, Burst: 5, AnonRate: 0.001, AnonBurst: 1},
+			want: []int{http.StatusOK, http.StatusServiceUnavailable},
+		},
+		{
+			name: "anonymous-unlimited",
+			cfg:  RateLimitConfig{Rate: 0.001, Burst: 1},
+			want: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(func(ctx *fiber.Ctx) error {
+				ctx.Locals("account", auth.Account{Access: tt.access})
+				return ctx.Next()
+			})
+			app.Use(RateLimit(tt.cfg, nil))
+			app.Get("/", func(ctx *fiber.Ctx) error {
+				return ctx.SendStatus(http.StatusOK)
+			})
+
+			for i, want := range tt.want {
+				resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != want {
+					t.Fatalf("request %v: status = %v, want %v", i, resp.StatusCode, want)
+				}
+				if want == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") == "" {
+					t.Errorf("request %v: missing Retry-After", i)
+				}
+			}
+		})
+	}
+}
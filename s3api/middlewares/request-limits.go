@@ -0,0 +1,91 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+)
+
+// MaxKeyLength is the maximum object key length in bytes
+const MaxKeyLength = 1024
+
+// RequestLimits are the object request size limits, zero values mean
+// no limit
+type RequestLimits struct {
+	// MaxObjectSize is the maximum body size of the object and part
+	// uploads
+	MaxObjectSize int64
+	// MaxMetadataSize is the maximum total size of the user metadata
+	// header names and values
+	MaxMetadataSize int
+}
+
+// CheckRequestLimits rejects the object uploads exceeding the key length,
+// object size or metadata size limits before they reach the backend
+func CheckRequestLimits(limits RequestLimits, logger s3log.AuditLogger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		method := ctx.Method()
+		if method != http.MethodPut && method != http.MethodPost {
+			return ctx.Next()
+		}
+
+		// only the object requests, the path is /<bucket>/<key>
+		pathParts := strings.SplitN(strings.TrimPrefix(ctx.Path(), "/"), "/", 2)
+		if len(pathParts) != 2 || pathParts[1] == "" {
+			return ctx.Next()
+		}
+
+		if len(pathParts[1]) > MaxKeyLength {
+			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrKeyTooLong), logger)
+		}
+
+		if limits.MaxObjectSize > 0 && method == http.MethodPut &&
+			contentLength(ctx) > limits.MaxObjectSize {
+			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrEntityTooLarge), logger)
+		}
+
+		if limits.MaxMetadataSize > 0 {
+			var size int
+			ctx.Request().Header.VisitAll(func(key, value []byte) {
+				if strings.HasPrefix(strings.ToLower(string(key)), "x-amz-meta-") {
+					size += len(key) - len("x-amz-meta-") + len(value)
+				}
+			})
+			if size > limits.MaxMetadataSize {
+				return sendResponse(ctx, s3err.GetAPIError(s3err.ErrMetadataTooLarge), logger)
+			}
+		}
+
+		return ctx.Next()
+	}
+}
+
+// contentLength returns the object data size of the request, which is
+// the decoded length for the chunked signed uploads
+func contentLength(ctx *fiber.Ctx) int64 {
+	if decoded := ctx.Get("X-Amz-Decoded-Content-Length"); decoded != "" {
+		size, err := strconv.ParseInt(decoded, 10, 64)
+		if err == nil {
+			return size
+		}
+	}
+	return int64(ctx.Request().Header.ContentLength())
+}
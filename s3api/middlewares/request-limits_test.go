@@ -0,0 +1,178 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCheckRequestLimits(t *testing.T) {
+	limits := RequestLimits{
+		MaxObjectSize:   10,
+		MaxMetadataSize: 8,
+	}
+
+	tests := []struct {
+		name     string
+		limits   RequestLimits
+		method   string
+		path     string
+		body     string
+		headers  map[string]string
+		wantCode string
+	}{
+		{
+			name:   "key-at-limit",
+			method: http.MethodPut,
+			path:   "/bucket/" + strings.Repeat("k", MaxKeyLength),
+		},
+		{
+			name:     "key-over-limit",
+			method:   http.MethodPut,
+			path:     "/bucket/" + strings.Repeat("k", MaxKeyLength+1),
+			wantCode: "KeyTooLongError",
+		},
+		{
+			name:     "key-over-limit-post",
+			method:   http.MethodPost,
+			path:     "/bucket/" + strings.Repeat("k", MaxKeyLength+1),
+			wantCode: "KeyTooLongError",
+		},
+		{
+			name:   "key-over-limit-get",
+			method: http.MethodGet,
+			path:   "/bucket/" + strings.Repeat("k", MaxKeyLength+1),
+		},
+		{
+			name:   "bucket-request",
+			limits: limits,
+			method: http.MethodPut,
+			path:   "/bucket",
+			body:   strings.Repeat("b", 11),
+		},
+		{
+			name:   "object-at-size-limit",
+			limits: limits,
+			method: http.MethodPut,
+			path:   "/bucket/obj",
+			body:   strings.Repeat("b", 10),
+		},
+		{
+			name:     "object-over-size-limit",
+			limits:   limits,
+			method:   http.MethodPut,
+			path:     "/bucket/obj",
+			body:     strings.Repeat("b", 11),
+			wantCode: "EntityTooLarge",
+		},
+		{
+			name:   "object-size-unlimited",
+			method: http.MethodPut,
+			path:   "/bucket/obj",
+			body:   strings.Repeat("b", 11),
+		},
+		{
+			name:     "decoded-length-over-size-limit",
+			limits:   limits,
+			method:   http.MethodPut,
+			path:     "/bucket/obj",
+			body:     strings.Repeat("b", 5),
+			headers:  map[string]string{"X-Amz-Decoded-Content-Length": "11"},
+			wantCode: "EntityTooLarge",
+		},
+		{
+			name:    "decoded-length-at-size-limit",
+			limits:  limits,
+			method:  http.MethodPut,
+			path:    "/bucket/obj",
+			body:    strings.Repeat("b", 20),
+			headers: map[string]string{"X-Amz-Decoded-Content-Length": "10"},
+		},
+		{
+			name:   "post-over-size-limit",
+			limits: limits,
+			method: http.MethodPost,
+			path:   "/bucket/obj",
+			body:   strings.Repeat("b", 11),
+		},
+		{
+			name:    "metadata-at-limit",
+			limits:  limits,
+			method:  http.MethodPut,
+			path:    "/bucket/obj",
+			headers: map[string]string{"X-Amz-Meta-Ab": "cd", "X-Amz-Meta-E": "fgh"},
+		},
+		{
+			name:     "metadata-over-limit",
+			limits:   limits,
+			method:   http.MethodPut,
+			path:     "/bucket/obj",
+			headers:  map[string]string{"X-Amz-Meta-Ab": "cd", "X-Amz-Meta-E": "fghi"},
+			wantCode: "MetadataTooLarge",
+		},
+		{
+			name:    "other-headers-not-metadata",
+			limits:  limits,
+			method:  http.MethodPut,
+			path:    "/bucket/obj",
+			headers: map[string]string{"X-Amz-Tagging": "key=a-long-tag-value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(CheckRequestLimits(tt.limits, nil))
+			app.All("/*", func(ctx *fiber.Ctx) error {
+				return ctx.SendStatus(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantCode == "" {
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("status = %v, want %v: %s", resp.StatusCode, http.StatusOK, body)
+				}
+				return
+			}
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("status = %v, want %v", resp.StatusCode, http.StatusBadRequest)
+			}
+			if !strings.Contains(string(body), "<Code>"+tt.wantCode+"</Code>") {
+				t.Errorf("body = %s, want code %v", body, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3api/controllers"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+	"github.com/versity/versitygw/s3response"
+)
+
+const assumeRoleWithWebIdentity = "AssumeRoleWithWebIdentity"
+
+// AssumeRoleWithWebIdentity serves the sts AssumeRoleWithWebIdentity
+// action exchanging identity tokens for temporary credentials. The
+// request is unsigned, the identity token authenticates it.
+func AssumeRoleWithWebIdentity(iam auth.IAMService, logger s3log.AuditLogger, region string) fiber.Handler {
+	svc, ok := iam.(auth.WebIdentityService)
+
+	return func(ctx *fiber.Ctx) error {
+		if !ok || ctx.Method() != http.MethodPost || ctx.Path() != "/" ||
+			ctx.FormValue("Action") != assumeRoleWithWebIdentity {
+			return ctx.Next()
+		}
+
+		ctx.Locals("region", region)
+		ctx.Locals("startTime", time.Now())
+
+		opts := &controllers.MetaOpts{Logger: logger, Action: assumeRoleWithWebIdentity}
+
+		token := ctx.FormValue("WebIdentityToken")
+		if token == "" {
+			return controllers.SendXMLResponse(ctx, nil, s3err.GetAPIError(s3err.ErrInvalidRequest), opts)
+		}
+
+		var duration time.Duration
+		if str := ctx.FormValue("DurationSeconds"); str != "" {
+			seconds, err := strconv.Atoi(str)
+			if err != nil || seconds <= 0 {
+				return controllers.SendXMLResponse(ctx, nil, s3err.GetAPIError(s3err.ErrInvalidRequest), opts)
+			}
+			duration = time.Duration(seconds) * time.Second
+		}
+
+		creds, err := svc.AssumeRoleWithWebIdentity(token, duration)
+		if err != nil {
+			return controllers.SendXMLResponse(ctx, nil, err, opts)
+		}
+
+		return controllers.SendXMLResponse(ctx, s3response.AssumeRoleWithWebIdentityResponse{
+			Result: s3response.AssumeRoleWithWebIdentityResult{
+				Credentials: s3response.STSCredentials{
+					AccessKeyId:     creds.AccessKeyID,
+					SecretAccessKey: creds.SecretAccessKey,
+					SessionToken:    creds.SessionToken,
+					Expiration:      creds.Expiration.UTC(),
+				},
+				SubjectFromWebIdentityToken: creds.Subject,
+			},
+		}, nil, opts)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/versity/versitygw/auth"
+)
+
+// newTestOIDCProvider serves the discovery document and the signing key
+// of an OpenID Connect provider
+func newTestOIDCProvider(t *testing.T, key *ecdsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer":   srv.URL,
+				"jwks_uri": srv.URL + "/keys",
+			})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]any{
+				"keys": []map[string]string{{
+					"kid": "key1",
+					"kty": "EC",
+					"use": "sig",
+					"crv": "P-256",
+					"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+					"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+				}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return srv
+}
+
+func TestAssumeRoleWithWebIdentity(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := newTestOIDCProvider(t, key)
+	defer provider.Close()
+
+	internal, err := auth.NewInternal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	iam, err := auth.NewOIDCService(internal, provider.URL, "gateway", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(modify func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":                provider.URL,
+			"aud":                "gateway",
+			"sub":                "subject1",
+			"exp":                time.Now().Add(time.Hour).Unix(),
+			"preferred_username": "user1",
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+	sign := func(c jwt.MapClaims, signKey *ecdsa.PrivateKey) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, c)
+		token.Header["kid"] = "key1"
+		str, err := token.SignedString(signKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return str
+	}
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(nil)).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noneToken, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims(nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		status   int
+		wantCode string
+	}{
+		{
+			name:   "valid-token",
+			form:   url.Values{"WebIdentityToken": {sign(claims(nil), key)}},
+			status: http.StatusOK,
+		},
+		{
+			name:     "missing-token",
+			form:     url.Values{},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidRequest",
+		},
+		{
+			name:     "malformed-token",
+			form:     url.Values{"WebIdentityToken": {"not.a.token"}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidIdentityToken",
+		},
+		{
+			name:     "unknown-signing-key",
+			form:     url.Values{"WebIdentityToken": {sign(claims(nil), otherKey)}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidIdentityToken",
+		},
+		{
+			name:     "expired-token",
+			form:     url.Values{"WebIdentityToken": {sign(claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), key)}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidIdentityToken",
+		},
+		{
+			name:     "missing-expiration",
+			form:     url.Values{"WebIdentityToken": {sign(claims(func(c jwt.MapClaims) { delete(c, "exp") }), key)}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidIdentityToken",
+		},
+		{
+			name:     "wrong-audience",
+			form:     url.Values{"WebIdentityToken": {sign(claims(func(c jwt.MapClaims) { c["aud"] = "other" }), key)}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidIdentityToken",
+		},
+		{
+			name:     "wrong-issuer",
+			form:     url.Values{"WebIdentityToken": {sign(claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }), key)}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidIdentityToken",
+		},
+		{
+			name:     "missing-account-claim",
+			form:     url.Values{"WebIdentityToken": {sign(claims(func(c jwt.MapClaims) { delete(c, "preferred_username") }), key)}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidIdentityToken",
+		},
+		{
+			name:     "hmac-signed-token",
+			form:     url.Values{"WebIdentityToken": {hmacToken}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidIdentityToken",
+		},
+		{
+			name:     "unsigned-token",
+			form:     url.Values{"WebIdentityToken": {noneToken}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidIdentityToken",
+		},
+		{
+			name:     "invalid-duration",
+			form:     url.Values{"WebIdentityToken": {sign(claims(nil), key)}, "DurationSeconds": {"-5"}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidRequest",
+		},
+		{
+			name:     "duration-too-short",
+			form:     url.Values{"WebIdentityToken": {sign(claims(nil), key)}, "DurationSeconds": {"60"}},
+			status:   http.StatusBadRequest,
+			wantCode: "InvalidRequest",
+		},
+	}
+
+	app := fiber.New()
+	app.Use(AssumeRoleWithWebIdentity(iam, nil, "us-east-1"))
+	app.All("/*", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusTeapot)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.form.Set("Action", "AssumeRoleWithWebIdentity")
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %v, want %v: %s", resp.StatusCode, tt.status, body)
+			}
+			if tt.wantCode != "" && !strings.Contains(string(body), "<Code>"+tt.wantCode+"</Code>") {
+				t.Errorf("body = %s, want code %v", body, tt.wantCode)
+			}
+			if tt.wantCode == "" {
+				if !strings.Contains(string(body), "<AccessKeyId>ASIA") {
+					t.Fatalf("missing credentials: %s", body)
+				}
+				if !strings.Contains(string(body), "<SubjectFromWebIdentityToken>subject1<") {
+					t.Errorf("missing subject: %s", body)
+				}
+			}
+		})
+	}
+
+	// other requests are passed on
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("Action=GetCallerIdentity"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("other action status = %v, want %v", resp.StatusCode, http.StatusTeapot)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/kms"
 	"github.com/versity/versitygw/s3api/controllers"
 	"github.com/versity/versitygw/s3event"
 	"github.com/versity/versitygw/s3log"
@@ -25,13 +26,19 @@ import (
 
 type S3ApiRouter struct {
 	WithAdmSrv bool
+	AdminAudit s3log.AdminAuditLogger
+	Quota      *auth.QuotaTracker
+	// Maintenance is toggled by the admin api
+	Maintenance *controllers.Maintenance
+	// IPFilterStats are reported by the admin api
+	IPFilterStats *controllers.IPFilterStats
 }
 
-func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMService, logger s3log.AuditLogger, evs s3event.S3EventSender, debug bool, readonly bool) {
-	s3ApiController := controllers.New(be, iam, logger, evs, debug, readonly)
+func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMService, logger s3log.AuditLogger, evs s3event.S3EventSender, kmsProvider kms.Provider, debug bool, readonly bool) {
+	s3ApiController := controllers.New(be, iam, logger, evs, kmsProvider, sa.Quota, debug, readonly)
 
 	if sa.WithAdmSrv {
-		adminController := controllers.NewAdminController(iam, be)
+		adminController := controllers.NewAdminController(iam, be, sa.AdminAudit, sa.Maintenance, sa.IPFilterStats)
 
 		// CreateUser admin api
 		app.Patch("/create-user", adminController.CreateUser)
@@ -47,6 +54,54 @@ func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMServ
 
 		// ListBucketsAndOwners admin api
 		app.Patch("/list-buckets", adminController.ListBuckets)
+
+		// CreateGroup admin api
+		app.Patch("/create-group", adminController.CreateGroup)
+
+		// DeleteGroup admin api
+		app.Patch("/delete-group", adminController.DeleteGroup)
+
+		// ListGroups admin api
+		app.Patch("/list-groups", adminController.ListGroups)
+
+		// AddGroupMember admin api
+		app.Patch("/add-group-member", adminController.AddGroupMember)
+
+		// RemoveGroupMember admin api
+		app.Patch("/remove-group-member", adminController.RemoveGroupMember)
+
+		// SetAccountQuota admin api
+		app.Patch("/set-account-quota", adminController.SetAccountQuota)
+
+		// GetAccountUsage admin api
+		app.Patch("/get-account-usage", adminController.GetAccountUsage)
+
+		// InvalidateIAMCache admin api
+		app.Patch("/invalidate-iam-cache", adminController.InvalidateIAMCache)
+
+		// SetBucketQuota admin api
+		app.Patch("/set-bucket-quota", adminController.SetBucketQuota)
+
+		// GetBucketQuota admin api
+		app.Patch("/get-bucket-quota", adminController.GetBucketQuota)
+
+		// GetBucketUsage admin api
+		app.Patch("/bucket-usage", adminController.GetBucketUsage)
+
+		// ListMultipartUploads admin api
+		app.Patch("/list-multipart-uploads", adminController.ListMultipartUploads)
+
+		// AbortMultipartUploads admin api
+		app.Patch("/abort-multipart-uploads", adminController.AbortMultipartUploads)
+
+		// SetMaintenance admin api
+		app.Patch("/maintenance", adminController.SetMaintenance)
+
+		// GetIAMCacheStats admin api
+		app.Patch("/iam-cache-stats", adminController.GetIAMCacheStats)
+
+		// GetIPFilterStats admin api
+		app.Patch("/ip-filter-stats", adminController.GetIPFilterStats)
 	}
 
 	// ListBuckets action
@@ -85,7 +140,8 @@ func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMServ
 	app.Delete("/:bucket/:key/*", s3ApiController.DeleteActions)
 
 	// DeleteObjects action
-	app.Post("/:bucket", s3ApiController.DeleteObjects)
+	// PostObject action
+	app.Post("/:bucket", s3ApiController.PostBucketActions)
 
 	// CompleteMultipartUpload action
 	// CreateMultipartUpload
@@ -15,28 +15,55 @@
 package s3api
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/valyala/fasthttp"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/kms"
+	"github.com/versity/versitygw/s3api/controllers"
 	"github.com/versity/versitygw/s3api/middlewares"
+	"github.com/versity/versitygw/s3api/utils"
 	"github.com/versity/versitygw/s3event"
 	"github.com/versity/versitygw/s3log"
 )
 
 type S3ApiServer struct {
-	app      *fiber.App
-	backend  backend.Backend
-	router   *S3ApiRouter
-	port     string
-	cert     *tls.Certificate
-	quiet    bool
-	debug    bool
-	readonly bool
-	health   string
+	app       *fiber.App
+	backend   backend.Backend
+	router    *S3ApiRouter
+	port      string
+	cert      *utils.CertStorage
+	clientCAs *x509.CertPool
+	listeners []listener
+	// ipFilter applies to the gateway port and the TLS listeners, and
+	// to the plain listeners unless they have their own plainIPFilter
+	ipFilter      *middlewares.IPFilter
+	plainIPFilter *middlewares.IPFilter
+	ipFilterStats *controllers.IPFilterStats
+	quiet         bool
+	debug         bool
+	readonly      bool
+	anonymous     bool
+	// quotaInterval is the account quota usage reconciliation
+	// interval, account quotas are not enforced if not set
+	quotaInterval time.Duration
+	rateLimit     *middlewares.RateLimitConfig
+	limits        middlewares.RequestLimits
+	// bodyTimeout replaces the server read and write timeouts for the
+	// requests streaming object data
+	bodyTimeout time.Duration
+	maintenance *controllers.Maintenance
+	health      string
+	kms         kms.Provider
 }
 
 func New(app *fiber.App, be backend.Backend, root middlewares.RootUserConfig, port, region string, iam auth.IAMService, l s3log.AuditLogger, evs s3event.S3EventSender, opts ...Option) (*S3ApiServer, error) {
@@ -51,10 +78,17 @@ func New(app *fiber.App, be backend.Backend, root middlewares.RootUserConfig, po
 		opt(server)
 	}
 
+	if server.bodyTimeout > 0 {
+		app.Server().HeaderReceived = bodyTimeouts(server.bodyTimeout)
+	}
+
 	// Logging middlewares
 	if !server.quiet {
 		app.Use(logger.New())
 	}
+	if server.ipFilter != nil || server.plainIPFilter != nil {
+		app.Use(middlewares.FilterIP(l))
+	}
 	// Set up health endpoint if specified
 	if server.health != "" {
 		app.Get(server.health, func(ctx *fiber.Ctx) error {
@@ -65,13 +99,33 @@ func New(app *fiber.App, be backend.Backend, root middlewares.RootUserConfig, po
 	app.Use(middlewares.RequestLogger(server.debug))
 
 	// Authentication middlewares
+	app.Use(middlewares.AssumeRoleWithWebIdentity(iam, l, region))
+	app.Use(middlewares.VerifyPostPolicy(root, iam, l, region))
+	if server.clientCAs != nil {
+		app.Use(middlewares.VerifyClientCert(root, iam, l, region))
+	}
 	app.Use(middlewares.VerifyPresignedV4Signature(root, iam, l, region, server.debug))
-	app.Use(middlewares.VerifyV4Signature(root, iam, l, region, server.debug))
+	app.Use(middlewares.VerifyV4Signature(root, iam, l, region, server.debug, server.anonymous))
+	if server.rateLimit != nil {
+		app.Use(middlewares.RateLimit(*server.rateLimit, l))
+	}
+	if server.maintenance != nil {
+		app.Use(middlewares.CheckMaintenance(server.maintenance, l))
+		server.router.Maintenance = server.maintenance
+	}
+	server.router.IPFilterStats = server.ipFilterStats
+	app.Use(middlewares.CheckRequestLimits(server.limits, l))
 	app.Use(middlewares.ProcessChunkedBody(root, iam, l, region))
 	app.Use(middlewares.VerifyMD5Body(l))
 	app.Use(middlewares.AclParser(be, l, server.readonly))
 
-	server.router.Init(app, be, iam, l, evs, server.debug, server.readonly)
+	if server.quotaInterval > 0 {
+		tracker := auth.NewQuotaTracker(be, iam)
+		go tracker.Run(context.Background(), server.quotaInterval)
+		server.router.Quota = tracker
+	}
+
+	server.router.Init(app, be, iam, l, evs, server.kms, server.debug, server.readonly)
 
 	return server, nil
 }
@@ -79,9 +133,53 @@ func New(app *fiber.App, be backend.Backend, root middlewares.RootUserConfig, po
 // Option sets various options for New()
 type Option func(*S3ApiServer)
 
-// WithTLS sets TLS Credentials
-func WithTLS(cert tls.Certificate) Option {
-	return func(s *S3ApiServer) { s.cert = &cert }
+// WithTLS sets TLS Credentials, the new connections get the current
+// certificate of the cert storage so reloads apply without a restart
+func WithTLS(cs *utils.CertStorage) Option {
+	return func(s *S3ApiServer) { s.cert = cs }
+}
+
+// WithClientCAs requires the clients to present a certificate signed by
+// one of the CAs, the unsigned requests are authenticated as the account
+// named by the certificate common name
+func WithClientCAs(cas *x509.CertPool) Option {
+	return func(s *S3ApiServer) { s.clientCAs = cas }
+}
+
+// WithListener adds a listen address served along with the gateway
+// port, with TLS if the gateway has a certificate unless plain is set
+func WithListener(addr string, plain bool) Option {
+	return func(s *S3ApiServer) { s.listeners = append(s.listeners, listener{addr: addr, plain: plain}) }
+}
+
+// WithRequestLimits sets the object and metadata size limits of the
+// object uploads
+func WithRequestLimits(limits middlewares.RequestLimits) Option {
+	return func(s *S3ApiServer) { s.limits = limits }
+}
+
+// WithBodyTimeout sets the read and write timeouts of the GET, PUT and
+// POST requests, which stream the object data and usually need longer
+// than the server timeouts
+func WithBodyTimeout(d time.Duration) Option {
+	return func(s *S3ApiServer) { s.bodyTimeout = d }
+}
+
+// WithIPFilter restricts the client addresses of the gateway listeners
+func WithIPFilter(f *middlewares.IPFilter) Option {
+	return func(s *S3ApiServer) { s.ipFilter = f }
+}
+
+// WithPlainIPFilter restricts the client addresses of the plain
+// listeners instead of the gateway ip filter
+func WithPlainIPFilter(f *middlewares.IPFilter) Option {
+	return func(s *S3ApiServer) { s.plainIPFilter = f }
+}
+
+// WithIPFilterStats counts the requests rejected by the ip filters per
+// listener, the counters are reported by the admin api
+func WithIPFilterStats(s *controllers.IPFilterStats) Option {
+	return func(srv *S3ApiServer) { srv.ipFilterStats = s }
 }
 
 // WithAdminServer runs admin endpoints with the gateway in the same network
@@ -89,6 +187,12 @@ func WithAdminServer() Option {
 	return func(s *S3ApiServer) { s.router.WithAdmSrv = true }
 }
 
+// WithAdminAuditLog sets the audit logger for the admin endpoints
+// served with the gateway
+func WithAdminAuditLog(l s3log.AdminAuditLogger) Option {
+	return func(s *S3ApiServer) { s.router.AdminAudit = l }
+}
+
 // WithDebug sets debug output
 func WithDebug() Option {
 	return func(s *S3ApiServer) { s.debug = true }
@@ -108,9 +212,116 @@ func WithReadOnly() Option {
 	return func(s *S3ApiServer) { s.readonly = true }
 }
 
+// WithAccountQuotas enforces the account storage quotas, the account
+// usage is reconciled with the backend at every interval
+func WithAccountQuotas(interval time.Duration) Option {
+	return func(s *S3ApiServer) { s.quotaInterval = interval }
+}
+
+// WithMaintenance enables the maintenance mode support, the mode is
+// toggled by the admin api
+func WithMaintenance(m *controllers.Maintenance) Option {
+	return func(s *S3ApiServer) { s.maintenance = m }
+}
+
+// WithRateLimit limits the request rate per access key and for
+// anonymous clients
+func WithRateLimit(cfg middlewares.RateLimitConfig) Option {
+	return func(s *S3ApiServer) { s.rateLimit = &cfg }
+}
+
+// WithAnonymousAccess lets unsigned requests through as the anonymous
+// account, limited to what the bucket policies and acls allow to everyone
+func WithAnonymousAccess() Option {
+	return func(s *S3ApiServer) { s.anonymous = true }
+}
+
+// WithKMS sets the kms provider used for SSE-KMS requests
+func WithKMS(p kms.Provider) Option {
+	return func(s *S3ApiServer) { s.kms = p }
+}
+
 func (sa *S3ApiServer) Serve() (err error) {
-	if sa.cert != nil {
-		return sa.app.ListenTLSWithCertificate(sa.port, *sa.cert)
+	listeners := append([]listener{{addr: sa.port}}, sa.listeners...)
+
+	// bind all the addresses first so that a bad address fails the
+	// startup instead of leaving the gateway partly listening
+	lns := make([]net.Listener, 0, len(listeners))
+	for _, l := range listeners {
+		ln, err := sa.listen(l)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return err
+		}
+		lns = append(lns, ln)
+	}
+
+	errs := make(chan error, len(lns))
+	for _, ln := range lns {
+		go func(ln net.Listener) { errs <- sa.app.Listener(ln) }(ln)
+	}
+	return <-errs
+}
+
+// bodyTimeouts returns the fasthttp header hook applying the timeout
+// to the requests that may stream object data
+func bodyTimeouts(d time.Duration) func(*fasthttp.RequestHeader) fasthttp.RequestConfig {
+	return func(h *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		if h.IsGet() || h.IsPut() || h.IsPost() {
+			return fasthttp.RequestConfig{ReadTimeout: d, WriteTimeout: d}
+		}
+		return fasthttp.RequestConfig{}
+	}
+}
+
+// listener is a gateway listen address, the plain listeners don't use
+// TLS even if the gateway has a certificate
+type listener struct {
+	addr  string
+	plain bool
+}
+
+func (sa *S3ApiServer) listen(l listener) (net.Listener, error) {
+	ln, err := net.Listen(sa.app.Config().Network, l.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	filter := sa.ipFilter
+	if l.plain && sa.plainIPFilter != nil {
+		filter = sa.plainIPFilter
+	}
+	if filter != nil {
+		var stats *controllers.ListenerStats
+		if sa.ipFilterStats != nil {
+			stats = sa.ipFilterStats.Listener(ln.Addr().String())
+		}
+		ln = filter.Listener(ln, stats)
+	}
+
+	if sa.cert == nil || l.plain {
+		return ln, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: sa.cert.GetCertificate,
+	}
+	if sa.clientCAs != nil {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = sa.clientCAs
+	}
+	return tls.NewListener(ln, config), nil
+}
+
+// listenTLS serves the app with the tls config, unlike the fiber TLS
+// listeners the certificates can be provided by GetCertificate
+func listenTLS(app *fiber.App, addr string, config *tls.Config) error {
+	ln, err := tls.Listen(app.Config().Network, addr, config)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
 	}
-	return sa.app.Listen(sa.port)
+	return app.Listener(ln)
 }
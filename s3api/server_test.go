@@ -15,7 +15,6 @@
 package s3api
 
 import (
-	"crypto/tls"
 	"reflect"
 	"testing"
 
@@ -23,6 +22,7 @@ import (
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/s3api/middlewares"
+	"github.com/versity/versitygw/s3api/utils"
 )
 
 func TestNew(t *testing.T) {
@@ -100,7 +100,18 @@ func TestS3ApiServer_Serve(t *testing.T) {
 				backend: backend.BackendUnsupported{},
 				port:    "Invalid address",
 				router:  &S3ApiRouter{},
-				cert:    &tls.Certificate{},
+				cert:    &utils.CertStorage{},
+			},
+		},
+		{
+			name:    "Serve-invalid-extra-listener",
+			wantErr: true,
+			sa: &S3ApiServer{
+				app:       fiber.New(),
+				backend:   backend.BackendUnsupported{},
+				port:      "127.0.0.1:0",
+				router:    &S3ApiRouter{},
+				listeners: []listener{{addr: "Invalid address", plain: true}},
 			},
 		},
 	}
@@ -0,0 +1,81 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// CertStorage holds the TLS certificate loaded from the cert and key
+// files, which can be reloaded while serving, e.g. after a renewal
+type CertStorage struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+}
+
+// NewCertStorage loads the certificate from the cert and key files
+func NewCertStorage(certFile, keyFile string) (*CertStorage, error) {
+	cs := &CertStorage{certFile: certFile, keyFile: keyFile}
+	err := cs.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// Reload loads the certificate files again, the current certificate
+// is kept if they fail to load
+func (cs *CertStorage) Reload() error {
+	cert, err := tls.LoadX509KeyPair(cs.certFile, cs.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: load certs: %w", err)
+	}
+	cs.cert.Store(&cert)
+	return nil
+}
+
+// GetCertificate returns the current certificate, it is meant to be
+// used as the tls.Config GetCertificate
+func (cs *CertStorage) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := cs.cert.Load()
+	if cert == nil {
+		return nil, fmt.Errorf("tls: no certificate loaded")
+	}
+	return cert, nil
+}
+
+// Run reloads the certificate at every interval until ctx is done
+func (cs *CertStorage) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := cs.Reload()
+			if err != nil {
+				log.Printf("reload certificate: %v", err)
+			}
+		}
+	}
+}
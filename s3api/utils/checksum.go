@@ -0,0 +1,121 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+// checksumHeaders are the x-amz-checksum-* headers carrying the object
+// checksum of each checksum algorithm
+var checksumHeaders = []struct {
+	algo   types.ChecksumAlgorithm
+	header string
+}{
+	{types.ChecksumAlgorithmCrc32, "X-Amz-Checksum-Crc32"},
+	{types.ChecksumAlgorithmCrc32c, "X-Amz-Checksum-Crc32c"},
+	{types.ChecksumAlgorithmSha1, "X-Amz-Checksum-Sha1"},
+	{types.ChecksumAlgorithmSha256, "X-Amz-Checksum-Sha256"},
+}
+
+// ParseChecksumHeaders returns the checksum sent in the x-amz-checksum-*
+// request headers, or nil when the request has no checksum. Only a single
+// checksum may be sent, and it must be a valid digest of its algorithm.
+func ParseChecksumHeaders(ctx *fiber.Ctx) (*backend.Checksum, error) {
+	var checksum *backend.Checksum
+	for _, c := range checksumHeaders {
+		value := ctx.Get(c.header)
+		if value == "" {
+			continue
+		}
+		if checksum != nil {
+			return nil, s3err.GetAPIError(s3err.ErrMultipleChecksumHeaders)
+		}
+		if !backend.IsValidChecksum(c.algo, value) {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidChecksumHeader)
+		}
+		checksum = &backend.Checksum{Algorithm: c.algo, Value: value}
+	}
+	return checksum, nil
+}
+
+// ParseChecksumAlgorithm parses the x-amz-checksum-algorithm header value
+// selecting the checksum algorithm of a multipart upload, an empty value
+// selects no checksum
+func ParseChecksumAlgorithm(value string) (types.ChecksumAlgorithm, error) {
+	if value == "" {
+		return "", nil
+	}
+	algo := types.ChecksumAlgorithm(strings.ToUpper(value))
+	_, err := backend.NewChecksumHash(algo)
+	if err != nil {
+		return "", s3err.GetAPIError(s3err.ErrInvalidChecksumAlgorithm)
+	}
+	return algo, nil
+}
+
+// ChecksumHeader returns the x-amz-checksum-* header of the checksum
+// algorithm
+func ChecksumHeader(algo types.ChecksumAlgorithm) string {
+	for _, c := range checksumHeaders {
+		if c.algo == algo {
+			return strings.ToLower(c.header)
+		}
+	}
+	return ""
+}
+
+// ChecksumReader is an io.Reader that verifies the data read against
+// the expected checksum
+type ChecksumReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	expected string
+}
+
+// NewChecksumReader returns a reader of r that returns an error instead
+// of io.EOF when the data does not match the checksum
+func NewChecksumReader(r io.Reader, checksum *backend.Checksum) (*ChecksumReader, error) {
+	h, err := backend.NewChecksumHash(checksum.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChecksumReader{
+		r:        r,
+		hash:     h,
+		expected: checksum.Value,
+	}, nil
+}
+
+// Read allows *ChecksumReader to be used as an io.Reader
+func (cr *ChecksumReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.hash.Write(p[:n])
+	if errors.Is(err, io.EOF) &&
+		base64.StdEncoding.EncodeToString(cr.hash.Sum(nil)) != cr.expected {
+		return n, s3err.GetAPIError(s3err.ErrChecksumMismatch)
+	}
+	return n, err
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+func TestParseChecksumHeaders(t *testing.T) {
+	crc := base64.StdEncoding.EncodeToString([]byte{1, 2, 3, 4})
+	sha := sha256.Sum256([]byte("data"))
+	shaSum := base64.StdEncoding.EncodeToString(sha[:])
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    *backend.Checksum
+		wantErr error
+	}{
+		{
+			name: "no-checksum",
+		},
+		{
+			name:    "crc32",
+			headers: map[string]string{"X-Amz-Checksum-Crc32": crc},
+			want:    &backend.Checksum{Algorithm: types.ChecksumAlgorithmCrc32, Value: crc},
+		},
+		{
+			name:    "sha256",
+			headers: map[string]string{"X-Amz-Checksum-Sha256": shaSum},
+			want:    &backend.Checksum{Algorithm: types.ChecksumAlgorithmSha256, Value: shaSum},
+		},
+		{
+			name:    "wrong-length",
+			headers: map[string]string{"X-Amz-Checksum-Sha1": crc},
+			wantErr: s3err.GetAPIError(s3err.ErrInvalidChecksumHeader),
+		},
+		{
+			name:    "invalid-base64",
+			headers: map[string]string{"X-Amz-Checksum-Crc32c": "not base64"},
+			wantErr: s3err.GetAPIError(s3err.ErrInvalidChecksumHeader),
+		},
+		{
+			name: "multiple-checksums",
+			headers: map[string]string{
+				"X-Amz-Checksum-Crc32":  crc,
+				"X-Amz-Checksum-Sha256": shaSum,
+			},
+			wantErr: s3err.GetAPIError(s3err.ErrMultipleChecksumHeaders),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(ctx)
+			for k, v := range tt.headers {
+				ctx.Request().Header.Set(k, v)
+			}
+
+			got, err := ParseChecksumHeaders(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseChecksumHeaders() error = %v, want %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("ParseChecksumHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecksumReader(t *testing.T) {
+	data := "hello world"
+	sum := binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE([]byte(data)))
+	validSum := base64.StdEncoding.EncodeToString(sum)
+	otherSum := base64.StdEncoding.EncodeToString([]byte{0, 0, 0, 0})
+
+	tests := []struct {
+		name    string
+		sum     string
+		wantErr error
+	}{
+		{
+			name: "matching-checksum",
+			sum:  validSum,
+		},
+		{
+			name:    "mismatched-checksum",
+			sum:     otherSum,
+			wantErr: s3err.GetAPIError(s3err.ErrChecksumMismatch),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, err := NewChecksumReader(strings.NewReader(data),
+				&backend.Checksum{Algorithm: types.ChecksumAlgorithmCrc32, Value: tt.sum})
+			if err != nil {
+				t.Fatalf("NewChecksumReader() error = %v", err)
+			}
+
+			b, err := io.ReadAll(cr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("read error = %v, want %v", err, tt.wantErr)
+			}
+			if string(b) != data {
+				t.Errorf("read data = %q, want %q", b, data)
+			}
+		})
+	}
+}
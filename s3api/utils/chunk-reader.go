@@ -15,15 +15,15 @@
 package utils
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"hash"
 	"io"
-	"math"
+	"net/http"
 	"strconv"
 	"time"
 
@@ -42,22 +42,28 @@ const (
 	awsS3Service      = "s3"
 	awsV4Request      = "aws4_request"
 	streamPayloadAlgo = "AWS4-HMAC-SHA256-PAYLOAD"
+
+	// StreamingPayload is the x-amz-content-sha256 value for signed
+	// aws-chunked uploads
+	StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
 )
 
 // ChunkReader reads from chunked upload request body, and returns
 // object data stream
 type ChunkReader struct {
-	r                io.Reader
-	signingKey       []byte
-	prevSig          string
-	parsedSig        string
-	currentChunkSize int64
-	chunkDataLeft    int64
-	trailerExpected  int
-	stash            []byte
-	chunkHash        hash.Hash
-	strToSignPrefix  string
-	skipcheck        bool
+	r               *bufio.Reader
+	signingKey      []byte
+	prevSig         string
+	parsedSig       string
+	chunkDataLeft   int64
+	inChunk         bool
+	chunkHash       hash.Hash
+	strToSignPrefix string
+	// decodedLen is the expected object data size from the
+	// X-Amz-Decoded-Content-Length header
+	decodedLen int64
+	dataRead   int64
+	finished   bool
 }
 
 // NewChunkReader reads from request body io.Reader and parses out the
@@ -65,8 +71,14 @@ type ChunkReader struct {
 // Reading from the chunk reader will read only the object data stream
 // without the chunk headers/trailers.
 func NewChunkReader(ctx *fiber.Ctx, r io.Reader, authdata AuthData, region, secret string, date time.Time) (*ChunkReader, error) {
+	decodedLen, err := strconv.ParseInt(ctx.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+	if err != nil || decodedLen < 0 {
+		return nil, s3err.GetAPIError(s3err.ErrMissingContentLength)
+	}
+
 	return &ChunkReader{
-		r:          r,
+		r:          bufio.NewReaderSize(r, maxHeaderSize),
+		decodedLen: decodedLen,
 		signingKey: getSigningKey(secret, region, date),
 		// the authdata.Signature is validated in the auth-reader,
 		// so we can use that here without any other checks
@@ -78,26 +90,139 @@ func NewChunkReader(ctx *fiber.Ctx, r io.Reader, authdata AuthData, region, secr
 
 // Read satisfies the io.Reader for this type
 func (cr *ChunkReader) Read(p []byte) (int, error) {
-	n, err := cr.r.Read(p)
-	if err != nil && err != io.EOF {
-		return n, err
+	if cr.finished {
+		return 0, io.EOF
 	}
 
-	if cr.chunkDataLeft < int64(n) {
-		chunkSize := cr.chunkDataLeft
-		if chunkSize > 0 {
-			cr.chunkHash.Write(p[:chunkSize])
+	for !cr.inChunk {
+		err := cr.readChunkHeader()
+		if err == io.EOF {
+			// the body must be terminated with the final zero
+			// length chunk, and the object data must match the
+			// decoded length
+			if cr.dataRead != cr.decodedLen {
+				return 0, s3err.GetAPIError(s3err.ErrIncompleteBody)
+			}
+			cr.finished = true
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
 		}
-		n, err := cr.parseAndRemoveChunkInfo(p[chunkSize:n])
-		n += int(chunkSize)
-		return n, err
 	}
 
-	cr.chunkDataLeft -= int64(n)
+	if int64(len(p)) > cr.chunkDataLeft {
+		p = p[:cr.chunkDataLeft]
+	}
+
+	n, err := cr.r.Read(p)
 	cr.chunkHash.Write(p[:n])
+	cr.chunkDataLeft -= int64(n)
+	cr.dataRead += int64(n)
+
+	if cr.chunkDataLeft == 0 {
+		cr.inChunk = false
+		if verr := cr.verifyChunk(); verr != nil {
+			return n, verr
+		}
+		if verr := cr.readDelim(); verr != nil {
+			return n, verr
+		}
+	}
+
+	if err == io.EOF {
+		if cr.inChunk {
+			return n, s3err.GetAPIError(s3err.ErrIncompleteBody)
+		}
+		err = nil
+	}
+
 	return n, err
 }
 
+// readChunkHeader parses the next "<hex size>;chunk-signature=<sig>\r\n"
+// chunk header. io.EOF is returned after the final zero length chunk
+// has been validated.
+func (cr *ChunkReader) readChunkHeader() error {
+	line, err := cr.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return errInvalidChunkFormat
+	}
+	if err == io.EOF {
+		return s3err.GetAPIError(s3err.ErrIncompleteBody)
+	}
+	if err != nil {
+		return err
+	}
+
+	header, ok := bytes.CutSuffix(line, []byte(chunkHdrDelim))
+	if !ok {
+		return errInvalidChunkFormat
+	}
+
+	sizeStr, sig, ok := bytes.Cut(header, []byte(chunkHdrStr))
+	if !ok {
+		return errInvalidChunkFormat
+	}
+
+	chunkSize, err := strconv.ParseInt(string(sizeStr), 16, 64)
+	if err != nil || chunkSize < 0 {
+		return errInvalidChunkFormat
+	}
+
+	cr.parsedSig = string(sig)
+	cr.chunkDataLeft = chunkSize
+	cr.chunkHash.Reset()
+
+	if chunkSize == 0 {
+		// the final chunk signature is calculated over the empty
+		// chunk data
+		if err := cr.verifyChunk(); err != nil {
+			return err
+		}
+		// the final chunk may optionally be followed by the
+		// delimiter
+		_, err := cr.r.Discard(len(chunkHdrDelim))
+		if err != nil && err != io.EOF {
+			return err
+		}
+		return io.EOF
+	}
+
+	cr.inChunk = true
+	return nil
+}
+
+// verifyChunk validates the signature of the chunk data hashed so far
+// against the signature from the chunk header
+func (cr *ChunkReader) verifyChunk() error {
+	sigstr := getChunkStringToSign(cr.strToSignPrefix, cr.prevSig, cr.chunkHash.Sum(nil))
+	cr.prevSig = hex.EncodeToString(hmac256(cr.signingKey, []byte(sigstr)))
+
+	if !hmac.Equal([]byte(cr.prevSig), []byte(cr.parsedSig)) {
+		return s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
+	}
+
+	return nil
+}
+
+// readDelim consumes the delimiter following the chunk data
+func (cr *ChunkReader) readDelim() error {
+	var delim [len(chunkHdrDelim)]byte
+	_, err := io.ReadFull(cr.r, delim[:])
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return s3err.GetAPIError(s3err.ErrIncompleteBody)
+	}
+	if err != nil {
+		return err
+	}
+	if string(delim[:]) != chunkHdrDelim {
+		return errInvalidChunkFormat
+	}
+
+	return nil
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html#sigv4-chunked-body-definition
 // This part is the same for all chunks,
 // only the previous signature and hash of current chunk changes
@@ -126,85 +251,6 @@ func getChunkStringToSign(prefix, prevSig string, chunkHash []byte) string {
 		hex.EncodeToString(chunkHash))
 }
 
-// The provided p should have all of the previous chunk data and trailer
-// consumed already. The positioning here is expected that p[0] starts the
-// new chunk size with the ";chunk-signature=" following. The only exception
-// is if we started consuming the trailer, but hit the end of the read buffer.
-// In this case, parseAndRemoveChunkInfo is called with skipcheck=true to
-// finish consuming the final trailer bytes.
-// This parses the chunk metadata in situ without allocating an extra buffer.
-// It will just read and validate the chunk metadata and then move the
-// following chunk data to overwrite the metadata in the provided buffer.
-func (cr *ChunkReader) parseAndRemoveChunkInfo(p []byte) (int, error) {
-	n := len(p)
-
-	if !cr.skipcheck && cr.parsedSig != "" {
-		chunkhash := cr.chunkHash.Sum(nil)
-		cr.chunkHash.Reset()
-
-		sigstr := getChunkStringToSign(cr.strToSignPrefix, cr.prevSig, chunkhash)
-		cr.prevSig = hex.EncodeToString(hmac256(cr.signingKey, []byte(sigstr)))
-
-		if cr.currentChunkSize != 0 && cr.prevSig != cr.parsedSig {
-			return 0, s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
-		}
-	}
-
-	if cr.trailerExpected != 0 {
-		if len(p) < len(chunkHdrDelim) {
-			// This is the special case where we need to consume the
-			// trailer, but instead hit the end of the buffer. The
-			// subsequent call will finish consuming the trailer.
-			cr.chunkDataLeft = 0
-			cr.trailerExpected -= len(p)
-			cr.skipcheck = true
-			return 0, nil
-		}
-		// move data up to remove trailer
-		copy(p, p[cr.trailerExpected:])
-		n -= cr.trailerExpected
-	}
-
-	cr.skipcheck = false
-
-	chunkSize, sig, bufOffset, err := cr.parseChunkHeaderBytes(p[:n])
-	cr.currentChunkSize = chunkSize
-	cr.parsedSig = sig
-	if err == errskipHeader {
-		cr.chunkDataLeft = 0
-		return 0, nil
-	}
-	if err != nil {
-		return 0, err
-	}
-	if chunkSize == 0 {
-		return 0, io.EOF
-	}
-
-	cr.trailerExpected = len(chunkHdrDelim)
-
-	// move data up to remove chunk header
-	copy(p, p[bufOffset:n])
-	n -= bufOffset
-
-	// if remaining buffer larger than chunk data,
-	// parse next header in buffer
-	if int64(n) > chunkSize {
-		cr.chunkDataLeft = 0
-		cr.chunkHash.Write(p[:chunkSize])
-		n, err := cr.parseAndRemoveChunkInfo(p[chunkSize:n])
-		if (chunkSize + int64(n)) > math.MaxInt {
-			return 0, s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
-		}
-		return n + int(chunkSize), err
-	}
-
-	cr.chunkDataLeft = chunkSize - int64(n)
-	cr.chunkHash.Write(p[:n])
-
-	return n, nil
-}
-
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
 // Task 3: Calculate Signature
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html#signing-request-intro
@@ -223,52 +269,13 @@ func hmac256(key []byte, data []byte) []byte {
 }
 
 var (
-	errInvalidChunkFormat = errors.New("invalid chunk header format")
-	errskipHeader         = errors.New("skip to next header")
+	errInvalidChunkFormat = s3err.APIError{
+		Code:           "IncompleteBody",
+		Description:    "The request body chunk encoding is invalid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
 )
 
 const (
 	maxHeaderSize = 1024
 )
-
-// Theis returns the chunk payload size, signature, data start offset, and
-// error if any. See the AWS documentation for the chunk header format. The
-// header[0] byte is expected to be the first byte of the chunk size here.
-func (cr *ChunkReader) parseChunkHeaderBytes(header []byte) (int64, string, int, error) {
-	stashLen := len(cr.stash)
-	if cr.stash != nil {
-		tmp := make([]byte, maxHeaderSize)
-		copy(tmp, cr.stash)
-		copy(tmp[len(cr.stash):], header)
-		header = tmp
-		cr.stash = nil
-	}
-
-	semicolonIndex := bytes.Index(header, []byte(chunkHdrStr))
-	if semicolonIndex == -1 {
-		cr.stash = make([]byte, len(header))
-		copy(cr.stash, header)
-		cr.trailerExpected = 0
-		return 0, "", 0, errskipHeader
-	}
-
-	sigIndex := semicolonIndex + len(chunkHdrStr)
-	sigEndIndex := bytes.Index(header[sigIndex:], []byte(chunkHdrDelim))
-	if sigEndIndex == -1 {
-		cr.stash = make([]byte, len(header))
-		copy(cr.stash, header)
-		cr.trailerExpected = 0
-		return 0, "", 0, errskipHeader
-	}
-
-	chunkSizeBytes := header[:semicolonIndex]
-	chunkSize, err := strconv.ParseInt(string(chunkSizeBytes), 16, 64)
-	if err != nil {
-		return 0, "", 0, errInvalidChunkFormat
-	}
-
-	signature := string(header[sigIndex:(sigIndex + sigEndIndex)])
-	dataStartOffset := sigIndex + sigEndIndex + len(chunkHdrDelim)
-
-	return chunkSize, signature, dataStartOffset - stashLen, nil
-}
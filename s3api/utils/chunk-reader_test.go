@@ -0,0 +1,122 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"github.com/versity/versitygw/s3err"
+)
+
+// buildChunkedBody encodes data as a signed aws-chunked stream
+func buildChunkedBody(data []byte, chunkSize int, seedSig, secret, region string, date time.Time) []byte {
+	signingKey := getSigningKey(secret, region, date)
+	prefix := getStringToSignPrefix(date, region)
+	prevSig := seedSig
+
+	var buf bytes.Buffer
+	writeChunk := func(chunk []byte) {
+		hash := sha256.Sum256(chunk)
+		sig := hex.EncodeToString(hmac256(signingKey,
+			[]byte(getChunkStringToSign(prefix, prevSig, hash[:]))))
+		fmt.Fprintf(&buf, "%x%s%s%s", len(chunk), chunkHdrStr, sig, chunkHdrDelim)
+		buf.Write(chunk)
+		buf.WriteString(chunkHdrDelim)
+		prevSig = sig
+	}
+
+	for len(data) > 0 {
+		n := min(chunkSize, len(data))
+		writeChunk(data[:n])
+		data = data[n:]
+	}
+	writeChunk(nil)
+
+	return buf.Bytes()
+}
+
+func TestChunkReader(t *testing.T) {
+	secret := "secret"
+	region := "us-east-1"
+	seedSig := "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9"
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1000)
+
+	body := buildChunkedBody(data, 4096, seedSig, secret, region, date)
+
+	truncated := body[:len(body)-90]
+
+	tampered := bytes.Clone(body)
+	tampered[len(tampered)-10] ^= 1
+
+	tests := []struct {
+		name       string
+		body       []byte
+		decodedLen int
+		oneByte    bool
+		err        error
+	}{
+		{name: "valid", body: body, decodedLen: len(data)},
+		{name: "valid-one-byte-reads", body: body, decodedLen: len(data), oneByte: true},
+		{name: "truncated", body: truncated, decodedLen: len(data), err: s3err.GetAPIError(s3err.ErrIncompleteBody)},
+		{name: "invalid-final-signature", body: tampered, decodedLen: len(data), err: s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)},
+		{name: "decoded-length-mismatch", body: body, decodedLen: len(data) + 1, err: s3err.GetAPIError(s3err.ErrIncompleteBody)},
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(ctx)
+			ctx.Request().Header.Set("X-Amz-Decoded-Content-Length", strconv.Itoa(tt.decodedLen))
+
+			var r io.Reader = bytes.NewReader(tt.body)
+			if tt.oneByte {
+				r = iotest.OneByteReader(r)
+			}
+
+			cr, err := NewChunkReader(ctx, r, AuthData{Signature: seedSig}, region, secret, date)
+			if err != nil {
+				t.Fatalf("new chunk reader: %v", err)
+			}
+
+			got, err := io.ReadAll(cr)
+			if tt.err != nil {
+				var apierr s3err.APIError
+				if !errors.As(err, &apierr) || apierr.Code != tt.err.(s3err.APIError).Code {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("read chunked body: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("decoded data mismatch: got %v bytes, expected %v", len(got), len(data))
+			}
+		})
+	}
+}
@@ -61,6 +61,9 @@ func NewHashReader(r io.Reader, expectedSum string, ht HashType) (*HashReader, e
 	var hash hash.Hash
 	switch ht {
 	case HashTypeMd5:
+		if expectedSum != "" && !IsValidMd5Sum(expectedSum) {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidDigest)
+		}
 		hash = md5.New()
 	case HashTypeSha256:
 		hash = sha256.New()
@@ -90,7 +93,7 @@ func (hr *HashReader) Read(p []byte) (int, error) {
 		case HashTypeMd5:
 			sum := base64.StdEncoding.EncodeToString(hr.hash.Sum(nil))
 			if sum != hr.sum {
-				return n, s3err.GetAPIError(s3err.ErrInvalidDigest)
+				return n, s3err.GetAPIError(s3err.ErrBadDigest)
 			}
 		case HashTypeSha256:
 			sum := hex.EncodeToString(hr.hash.Sum(nil))
@@ -116,6 +119,13 @@ func (hr *HashReader) Sum() string {
 	}
 }
 
+// IsValidMd5Sum checks that the value is a base64 encoded 128-bit digest
+// as expected in the Content-MD5 header
+func IsValidMd5Sum(sum string) bool {
+	b, err := base64.StdEncoding.DecodeString(sum)
+	return err == nil && len(b) == md5.Size
+}
+
 // Md5SumString converts the hash bytes to the string checksum value
 func Md5SumString(b []byte) string {
 	return base64.StdEncoding.EncodeToString(b)
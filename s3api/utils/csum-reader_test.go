@@ -0,0 +1,86 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/versity/versitygw/s3err"
+)
+
+func TestHashReader_Md5(t *testing.T) {
+	data := "hello world"
+	sum := md5.Sum([]byte(data))
+	validSum := base64.StdEncoding.EncodeToString(sum[:])
+	otherSum := md5.Sum([]byte("something else"))
+
+	tests := []struct {
+		name       string
+		sum        string
+		wantNewErr error
+		wantErr    error
+	}{
+		{
+			name: "matching-digest",
+			sum:  validSum,
+		},
+		{
+			name: "empty-digest",
+			sum:  "",
+		},
+		{
+			name:    "mismatched-digest",
+			sum:     base64.StdEncoding.EncodeToString(otherSum[:]),
+			wantErr: s3err.GetAPIError(s3err.ErrBadDigest),
+		},
+		{
+			name:       "invalid-base64",
+			sum:        "sadfasdf87sad6f87==",
+			wantNewErr: s3err.GetAPIError(s3err.ErrInvalidDigest),
+		},
+		{
+			name:       "wrong-digest-length",
+			sum:        base64.StdEncoding.EncodeToString([]byte("short")),
+			wantNewErr: s3err.GetAPIError(s3err.ErrInvalidDigest),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hr, err := NewHashReader(strings.NewReader(data), tt.sum, HashTypeMd5)
+			if !errors.Is(err, tt.wantNewErr) {
+				t.Fatalf("NewHashReader() error = %v, want %v", err, tt.wantNewErr)
+			}
+			if err != nil {
+				return
+			}
+
+			b, err := io.ReadAll(hr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("read error = %v, want %v", err, tt.wantErr)
+			}
+			if string(b) != data {
+				t.Errorf("read data = %q, want %q", b, data)
+			}
+			if hr.Sum() != validSum {
+				t.Errorf("Sum() = %v, want %v", hr.Sum(), validSum)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/s3err"
+)
+
+const (
+	postPolicyCondEq         = "eq"
+	postPolicyCondStartsWith = "starts-with"
+	postPolicyCondLength     = "content-length-range"
+)
+
+// PostForm is the parsed browser based POST upload form
+type PostForm struct {
+	// Fields are the form fields keyed by lowercase field name
+	Fields map[string]string
+	// Metadata are the x-amz-meta-* fields with the prefix removed
+	Metadata map[string]string
+	File     *multipart.FileHeader
+}
+
+// ParsePostForm parses the multipart/form-data POST upload request body
+func ParsePostForm(ctx *fiber.Ctx) (*PostForm, error) {
+	mf, err := ctx.MultipartForm()
+	if err != nil {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedPOSTRequest)
+	}
+
+	files := mf.File["file"]
+	if len(files) != 1 {
+		return nil, s3err.GetAPIError(s3err.ErrPOSTFileRequired)
+	}
+
+	form := &PostForm{
+		Fields:   make(map[string]string),
+		Metadata: make(map[string]string),
+		File:     files[0],
+	}
+
+	for name, vals := range mf.Value {
+		if len(vals) == 0 {
+			continue
+		}
+		lname := strings.ToLower(name)
+		form.Fields[lname] = vals[0]
+		if strings.HasPrefix(lname, "x-amz-meta-") {
+			form.Metadata[name[len("x-amz-meta-"):]] = vals[0]
+		}
+	}
+
+	if form.Fields["key"] == "" {
+		return nil, s3err.APIError{
+			Code:           "InvalidArgument",
+			Description:    "Bucket POST must contain a field named 'key'.  If it is specified, please check the order of the fields.",
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	}
+
+	return form, nil
+}
+
+// Key returns the object key with ${filename} replaced by the name of
+// the uploaded file
+func (pf *PostForm) Key() string {
+	return strings.ReplaceAll(pf.Fields["key"], "${filename}", pf.File.Filename)
+}
+
+// PostPolicy is the decoded POST policy document
+type PostPolicy struct {
+	Expiration time.Time
+	Conditions []PostPolicyCondition
+}
+
+// PostPolicyCondition is a single POST policy condition. Exact match
+// conditions ({"field": "value"}) are stored as "eq" conditions.
+type PostPolicyCondition struct {
+	Op    string
+	Field string
+	Value string
+	Min   int64
+	Max   int64
+}
+
+type postPolicyDoc struct {
+	Expiration string            `json:"expiration"`
+	Conditions []json.RawMessage `json:"conditions"`
+}
+
+func postPolicyError(format string, args ...any) error {
+	return s3err.APIError{
+		Code:           "AccessDenied",
+		Description:    "Invalid according to Policy: " + fmt.Sprintf(format, args...),
+		HTTPStatusCode: http.StatusForbidden,
+	}
+}
+
+func malformedPostPolicy(format string, args ...any) error {
+	return s3err.APIError{
+		Code:           "InvalidPolicyDocument",
+		Description:    "Invalid Policy: " + fmt.Sprintf(format, args...),
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+}
+
+// ParsePostPolicy decodes the base64 encoded POST policy document
+func ParsePostPolicy(policy string) (*PostPolicy, error) {
+	data, err := base64.StdEncoding.DecodeString(policy)
+	if err != nil {
+		return nil, malformedPostPolicy("invalid base64 encoding")
+	}
+
+	var doc postPolicyDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, malformedPostPolicy("invalid JSON")
+	}
+
+	if doc.Expiration == "" {
+		return nil, malformedPostPolicy("Policy missing expiration")
+	}
+	exp, err := time.Parse(time.RFC3339, doc.Expiration)
+	if err != nil {
+		return nil, malformedPostPolicy("Invalid 'expiration' value: '%v'", doc.Expiration)
+	}
+
+	pp := &PostPolicy{Expiration: exp}
+
+	for _, raw := range doc.Conditions {
+		var exact map[string]string
+		if err := json.Unmarshal(raw, &exact); err == nil {
+			for field, val := range exact {
+				pp.Conditions = append(pp.Conditions, PostPolicyCondition{
+					Op:    postPolicyCondEq,
+					Field: strings.ToLower(strings.TrimPrefix(field, "$")),
+					Value: val,
+				})
+			}
+			continue
+		}
+
+		var cond []any
+		if err := json.Unmarshal(raw, &cond); err != nil || len(cond) != 3 {
+			return nil, malformedPostPolicy("Invalid condition %v", string(raw))
+		}
+
+		op, ok := cond[0].(string)
+		if !ok {
+			return nil, malformedPostPolicy("Invalid condition %v", string(raw))
+		}
+		op = strings.ToLower(op)
+
+		switch op {
+		case postPolicyCondEq, postPolicyCondStartsWith:
+			field, ok1 := cond[1].(string)
+			val, ok2 := cond[2].(string)
+			if !ok1 || !ok2 || !strings.HasPrefix(field, "$") {
+				return nil, malformedPostPolicy("Invalid condition %v", string(raw))
+			}
+			pp.Conditions = append(pp.Conditions, PostPolicyCondition{
+				Op:    op,
+				Field: strings.ToLower(field[1:]),
+				Value: val,
+			})
+		case postPolicyCondLength:
+			min, err1 := policyInt(cond[1])
+			max, err2 := policyInt(cond[2])
+			if err1 != nil || err2 != nil || min < 0 || min > max {
+				return nil, malformedPostPolicy("Invalid content-length-range %v", string(raw))
+			}
+			pp.Conditions = append(pp.Conditions, PostPolicyCondition{
+				Op:  op,
+				Min: min,
+				Max: max,
+			})
+		default:
+			return nil, malformedPostPolicy("Invalid condition operator %v", op)
+		}
+	}
+
+	return pp, nil
+}
+
+func policyInt(v any) (int64, error) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), nil
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	default:
+		return 0, fmt.Errorf("invalid number %v", v)
+	}
+}
+
+// fields that are not required to be covered by a policy condition
+var postPolicyIgnoredFields = map[string]struct{}{
+	"policy":           {},
+	"x-amz-signature":  {},
+	"file":             {},
+	"x-amz-algorithm":  {},
+	"x-amz-credential": {},
+	"x-amz-date":       {},
+	"bucket":           {},
+}
+
+// Validate checks the form against the policy expiration and conditions.
+// Every form field must be covered by a policy condition.
+func (pp *PostPolicy) Validate(form *PostForm, bucket string) error {
+	if time.Now().After(pp.Expiration) {
+		return postPolicyError("Policy expired.")
+	}
+
+	values := make(map[string]string, len(form.Fields)+1)
+	for k, v := range form.Fields {
+		values[k] = v
+	}
+	values["bucket"] = bucket
+
+	covered := make(map[string]struct{})
+	for _, cond := range pp.Conditions {
+		switch cond.Op {
+		case postPolicyCondLength:
+			if form.File.Size < cond.Min {
+				return s3err.GetAPIError(s3err.ErrEntityTooSmall)
+			}
+			if form.File.Size > cond.Max {
+				return s3err.GetAPIError(s3err.ErrEntityTooLarge)
+			}
+			continue
+		case postPolicyCondEq:
+			if values[cond.Field] != cond.Value {
+				return postPolicyError("Policy Condition failed: [\"eq\", \"$%v\", \"%v\"]", cond.Field, cond.Value)
+			}
+		case postPolicyCondStartsWith:
+			if !policyStartsWith(cond.Field, values[cond.Field], cond.Value) {
+				return postPolicyError("Policy Condition failed: [\"starts-with\", \"$%v\", \"%v\"]", cond.Field, cond.Value)
+			}
+		}
+		covered[cond.Field] = struct{}{}
+	}
+
+	for field := range form.Fields {
+		if _, ok := postPolicyIgnoredFields[field]; ok {
+			continue
+		}
+		if strings.HasPrefix(field, "x-ignore-") {
+			continue
+		}
+		if _, ok := covered[field]; !ok {
+			return postPolicyError("Extra input fields: %v", field)
+		}
+	}
+
+	return nil
+}
+
+// policyStartsWith checks the starts-with condition, Content-Type allows
+// a comma separated list of values that each must match
+func policyStartsWith(field, value, prefix string) bool {
+	if field == "content-type" {
+		for _, v := range strings.Split(value, ",") {
+			if !strings.HasPrefix(strings.TrimSpace(v), prefix) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return strings.HasPrefix(value, prefix)
+}
+
+// ParsePostCredential parses and validates the POST form signature
+// fields and returns the auth data for the request
+func ParsePostCredential(form *PostForm, region string) (AuthData, error) {
+	a := AuthData{}
+
+	algo := form.Fields["x-amz-algorithm"]
+	creds := form.Fields["x-amz-credential"]
+	date := form.Fields["x-amz-date"]
+	signature := form.Fields["x-amz-signature"]
+	policy := form.Fields["policy"]
+	if algo == "" || creds == "" || date == "" || signature == "" || policy == "" {
+		return a, s3err.GetAPIError(s3err.ErrMissingFields)
+	}
+	if algo != "AWS4-HMAC-SHA256" {
+		return a, s3err.GetAPIError(s3err.ErrSignatureVersionNotSupported)
+	}
+
+	credParts := strings.Split(creds, "/")
+	if len(credParts) != 5 {
+		return a, s3err.GetAPIError(s3err.ErrCredMalformed)
+	}
+	if credParts[3] != "s3" {
+		return a, s3err.GetAPIError(s3err.ErrSignatureIncorrService)
+	}
+	if credParts[4] != "aws4_request" {
+		return a, s3err.GetAPIError(s3err.ErrSignatureTerminationStr)
+	}
+	if _, err := time.Parse(yyyymmdd, credParts[1]); err != nil {
+		return a, s3err.GetAPIError(s3err.ErrSignatureDateDoesNotMatch)
+	}
+	if _, err := time.Parse(iso8601Format, date); err != nil {
+		return a, s3err.GetAPIError(s3err.ErrMalformedDate)
+	}
+	if date[:8] != credParts[1] {
+		return a, s3err.GetAPIError(s3err.ErrSignatureDateDoesNotMatch)
+	}
+	if credParts[2] != region {
+		return a, s3err.APIError{
+			Code:           "SignatureDoesNotMatch",
+			Description:    fmt.Sprintf("Credential should be scoped to a valid Region, not %v", credParts[2]),
+			HTTPStatusCode: http.StatusForbidden,
+		}
+	}
+
+	a.Algorithm = algo
+	a.Access = credParts[0]
+	a.Region = credParts[2]
+	a.Signature = signature
+	a.Date = credParts[1]
+
+	return a, nil
+}
+
+// CheckPostSignature validates the signature of the base64 encoded
+// POST policy document
+func CheckPostSignature(form *PostForm, auth AuthData, secret string) error {
+	date, err := time.Parse(yyyymmdd, auth.Date)
+	if err != nil {
+		return s3err.GetAPIError(s3err.ErrSignatureDateDoesNotMatch)
+	}
+
+	signingKey := getSigningKey(secret, auth.Region, date)
+	signature := hex.EncodeToString(hmac256(signingKey, []byte(form.Fields["policy"])))
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(auth.Signature)) != 1 {
+		return s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
+	}
+
+	return nil
+}
@@ -15,6 +15,7 @@
 package utils
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"io"
@@ -115,7 +116,7 @@ func CheckPresignedSignature(ctx *fiber.Ctx, auth AuthData, secret string, debug
 	}
 
 	signature := urlParts.Query().Get("X-Amz-Signature")
-	if signature != auth.Signature {
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(auth.Signature)) != 1 {
 		return s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
 	}
 
@@ -198,6 +199,11 @@ func ParsePresignedURIParts(ctx *fiber.Ctx) (AuthData, error) {
 		return a, s3err.GetAPIError(s3err.ErrInvalidQueryParams)
 	}
 
+	err = validatePresignedSignedHeaders(ctx, signedHdrs)
+	if err != nil {
+		return a, err
+	}
+
 	// Validate X-Amz-Expires query param and check if request is expired
 	err = validateExpiration(ctx.Query("X-Amz-Expires"), tdate)
 	if err != nil {
@@ -233,6 +239,12 @@ func validateExpiration(str string, date time.Time) error {
 	}
 
 	now := time.Now()
+
+	// Allow for the same clock skew as header authentication
+	if date.Unix()-now.Unix() > timeExpirationSec {
+		return s3err.GetAPIError(s3err.ErrPresignedRequestNotYetValid)
+	}
+
 	passed := int(now.Sub(date).Seconds())
 
 	if passed > exp {
@@ -241,3 +253,33 @@ func validateExpiration(str string, date time.Time) error {
 
 	return nil
 }
+
+// validatePresignedSignedHeaders checks that the host header is signed
+// and that every x-amz-* header sent with the request is included in
+// the signed headers list
+func validatePresignedSignedHeaders(ctx *fiber.Ctx, signedHdrs string) error {
+	signed := make(map[string]struct{})
+	for _, hdr := range strings.Split(signedHdrs, ";") {
+		signed[strings.ToLower(hdr)] = struct{}{}
+	}
+
+	if _, ok := signed["host"]; !ok {
+		return s3err.GetAPIError(s3err.ErrSignedHeadersMissingHost)
+	}
+
+	var unsigned bool
+	ctx.Request().Header.VisitAll(func(key, _ []byte) {
+		hdr := strings.ToLower(string(key))
+		if !strings.HasPrefix(hdr, "x-amz-") || hdr == "x-amz-content-sha256" {
+			return
+		}
+		if _, ok := signed[hdr]; !ok {
+			unsigned = true
+		}
+	})
+	if unsigned {
+		return s3err.GetAPIError(s3err.ErrUnsignedHeaders)
+	}
+
+	return nil
+}
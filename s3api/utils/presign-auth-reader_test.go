@@ -18,6 +18,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
 	"github.com/versity/versitygw/s3err"
 )
 
@@ -71,6 +73,14 @@ func Test_validateExpiration(t *testing.T) {
 			},
 			err: s3err.GetAPIError(s3err.ErrExpiredPresignRequest),
 		},
+		{
+			name: "future date",
+			args: args{
+				str:  "300",
+				date: time.Now().Add(time.Hour),
+			},
+			err: s3err.GetAPIError(s3err.ErrPresignedRequestNotYetValid),
+		},
 		{
 			name: "valid expiration",
 			args: args{
@@ -98,3 +108,52 @@ func Test_validateExpiration(t *testing.T) {
 		})
 	}
 }
+
+func Test_validatePresignedSignedHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		signedHdrs string
+		headers    map[string]string
+		err        error
+	}{
+		{
+			name:       "missing-host",
+			signedHdrs: "content-type",
+			err:        s3err.GetAPIError(s3err.ErrSignedHeadersMissingHost),
+		},
+		{
+			name:       "unsigned-amz-header",
+			signedHdrs: "host",
+			headers:    map[string]string{"X-Amz-Meta-Key": "value"},
+			err:        s3err.GetAPIError(s3err.ErrUnsignedHeaders),
+		},
+		{
+			name:       "signed-amz-header",
+			signedHdrs: "host;x-amz-meta-key",
+			headers:    map[string]string{"X-Amz-Meta-Key": "value", "Content-Type": "text/plain"},
+			err:        nil,
+		},
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(ctx)
+			for k, v := range tt.headers {
+				ctx.Request().Header.Set(k, v)
+			}
+
+			err := validatePresignedSignedHeaders(ctx, tt.signedHdrs)
+			if tt.err == nil {
+				if err != nil {
+					t.Errorf("Expected nil error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.err.Error() {
+				t.Errorf("Expected error: %v, got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -21,24 +21,20 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go/encoding/httpbinding"
 	"github.com/gofiber/fiber/v2"
 	"github.com/valyala/fasthttp"
+	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/s3err"
 	"github.com/versity/versitygw/s3response"
 )
 
-var (
-	bucketNameRegexp   = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]+[a-z0-9]$`)
-	bucketNameIpRegexp = regexp.MustCompile(`^(?:[0-9]{1,3}\.){3}[0-9]{1,3}$`)
-)
-
 func GetUserMetaData(headers *fasthttp.RequestHeader) (metadata map[string]string) {
 	metadata = make(map[string]string)
 	headers.DisableNormalizing()
@@ -190,20 +186,22 @@ func SetResponseHeaders(ctx *fiber.Ctx, headers []CustomHeader) {
 	}
 }
 
-func IsValidBucketName(bucket string) bool {
-	if len(bucket) < 3 || len(bucket) > 63 {
-		return false
-	}
-	// Checks to contain only digits, lowercase letters, dot, hyphen.
-	// Checks to start and end with only digits and lowercase letters.
-	if !bucketNameRegexp.MatchString(bucket) {
-		return false
-	}
-	// Checks not to be a valid IP address
-	if bucketNameIpRegexp.MatchString(bucket) {
-		return false
+// StripAwsChunked removes the aws-chunked transfer coding from the
+// Content-Encoding of an upload, leaving the encodings of the object data
+func StripAwsChunked(contentEncoding string) string {
+	var encodings []string
+	for _, enc := range strings.Split(contentEncoding, ",") {
+		enc = strings.TrimSpace(enc)
+		if enc == "" || strings.EqualFold(enc, "aws-chunked") {
+			continue
+		}
+		encodings = append(encodings, enc)
 	}
-	return true
+	return strings.Join(encodings, ",")
+}
+
+func IsValidBucketName(bucket string) bool {
+	return backend.IsValidBucketName(bucket)
 }
 
 func includeHeader(hdr string, signedHdrs []string) bool {
@@ -252,6 +250,9 @@ func FilterObjectAttributes(attrs map[types.ObjectAttributes]struct{}, output s3
 	if _, ok := attrs[types.ObjectAttributesEtag]; !ok {
 		output.ETag = nil
 	}
+	if _, ok := attrs[types.ObjectAttributesChecksum]; !ok {
+		output.Checksum = nil
+	}
 	if _, ok := attrs[types.ObjectAttributesObjectParts]; !ok {
 		output.ObjectParts = nil
 	}
@@ -278,3 +279,135 @@ func ParseObjectAttributes(ctx *fiber.Ctx) map[types.ObjectAttributes]struct{} {
 
 	return attrs
 }
+
+// ConditionalHeaders holds the parsed conditional request headers
+type ConditionalHeaders struct {
+	IfMatch           *string
+	IfNoneMatch       *string
+	IfModifiedSince   *time.Time
+	IfUnmodifiedSince *time.Time
+}
+
+// ParseConditionalHeaders parses the If-Match, If-None-Match,
+// If-Modified-Since and If-Unmodified-Since headers with the given
+// header prefix, such as "X-Amz-Copy-Source-". Headers with invalid
+// dates are ignored.
+func ParseConditionalHeaders(ctx *fiber.Ctx, prefix string) ConditionalHeaders {
+	var hdrs ConditionalHeaders
+
+	if v := ctx.Get(prefix + "If-Match"); v != "" {
+		hdrs.IfMatch = &v
+	}
+	if v := ctx.Get(prefix + "If-None-Match"); v != "" {
+		hdrs.IfNoneMatch = &v
+	}
+	if t, err := http.ParseTime(ctx.Get(prefix + "If-Modified-Since")); err == nil {
+		hdrs.IfModifiedSince = &t
+	}
+	if t, err := http.ParseTime(ctx.Get(prefix + "If-Unmodified-Since")); err == nil {
+		hdrs.IfUnmodifiedSince = &t
+	}
+
+	return hdrs
+}
+
+// ParseEncodingType validates the encoding-type query parameter of the
+// listing requests, "url" is the only supported encoding
+func ParseEncodingType(ctx *fiber.Ctx) (types.EncodingType, error) {
+	if !ctx.Request().URI().QueryArgs().Has("encoding-type") {
+		return "", nil
+	}
+
+	encodingType := types.EncodingType(ctx.Query("encoding-type"))
+	if encodingType != types.EncodingTypeUrl {
+		return "", s3err.GetAPIError(s3err.ErrInvalidEncodingMethod)
+	}
+
+	return encodingType, nil
+}
+
+// URLEncodeKey encodes the object key the same way as AWS does for
+// encoding-type=url listings, leaving the "/" separators unencoded
+func URLEncodeKey(key string) string {
+	return strings.ReplaceAll(url.QueryEscape(key), "%2F", "/")
+}
+
+func urlEncodePtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	enc := URLEncodeKey(*s)
+	return &enc
+}
+
+// EncodeListObjectsOutput url encodes the keys and key related fields
+// of the ListObjects result
+func EncodeListObjectsOutput(out *s3.ListObjectsOutput) {
+	if out == nil {
+		return
+	}
+	out.EncodingType = types.EncodingTypeUrl
+	out.Prefix = urlEncodePtr(out.Prefix)
+	out.Delimiter = urlEncodePtr(out.Delimiter)
+	out.Marker = urlEncodePtr(out.Marker)
+	out.NextMarker = urlEncodePtr(out.NextMarker)
+	for i := range out.Contents {
+		out.Contents[i].Key = urlEncodePtr(out.Contents[i].Key)
+	}
+	for i := range out.CommonPrefixes {
+		out.CommonPrefixes[i].Prefix = urlEncodePtr(out.CommonPrefixes[i].Prefix)
+	}
+}
+
+// EncodeListObjectsV2Output url encodes the keys and key related fields
+// of the ListObjectsV2 result
+func EncodeListObjectsV2Output(out *s3.ListObjectsV2Output) {
+	if out == nil {
+		return
+	}
+	out.EncodingType = types.EncodingTypeUrl
+	out.Prefix = urlEncodePtr(out.Prefix)
+	out.Delimiter = urlEncodePtr(out.Delimiter)
+	out.StartAfter = urlEncodePtr(out.StartAfter)
+	for i := range out.Contents {
+		out.Contents[i].Key = urlEncodePtr(out.Contents[i].Key)
+	}
+	for i := range out.CommonPrefixes {
+		out.CommonPrefixes[i].Prefix = urlEncodePtr(out.CommonPrefixes[i].Prefix)
+	}
+}
+
+// EncodeListVersionsResult url encodes the keys and key related fields
+// of the ListObjectVersions result
+func EncodeListVersionsResult(out *s3response.ListVersionsResult) {
+	out.EncodingType = string(types.EncodingTypeUrl)
+	out.Prefix = URLEncodeKey(out.Prefix)
+	out.Delimiter = URLEncodeKey(out.Delimiter)
+	out.KeyMarker = URLEncodeKey(out.KeyMarker)
+	out.NextKeyMarker = URLEncodeKey(out.NextKeyMarker)
+	for i := range out.Versions {
+		out.Versions[i].Key = urlEncodePtr(out.Versions[i].Key)
+	}
+	for i := range out.DeleteMarkers {
+		out.DeleteMarkers[i].Key = urlEncodePtr(out.DeleteMarkers[i].Key)
+	}
+	for i := range out.CommonPrefixes {
+		out.CommonPrefixes[i].Prefix = urlEncodePtr(out.CommonPrefixes[i].Prefix)
+	}
+}
+
+// EncodeListMultipartUploadsResult url encodes the keys and key related
+// fields of the ListMultipartUploads result
+func EncodeListMultipartUploadsResult(out *s3response.ListMultipartUploadsResult) {
+	out.EncodingType = string(types.EncodingTypeUrl)
+	out.Prefix = URLEncodeKey(out.Prefix)
+	out.Delimiter = URLEncodeKey(out.Delimiter)
+	out.KeyMarker = URLEncodeKey(out.KeyMarker)
+	out.NextKeyMarker = URLEncodeKey(out.NextKeyMarker)
+	for i := range out.Uploads {
+		out.Uploads[i].Key = URLEncodeKey(out.Uploads[i].Key)
+	}
+	for i := range out.CommonPrefixes {
+		out.CommonPrefixes[i].Prefix = URLEncodeKey(out.CommonPrefixes[i].Prefix)
+	}
+}
@@ -18,8 +18,10 @@ import (
 	"bytes"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/gofiber/fiber/v2"
 	"github.com/valyala/fasthttp"
@@ -207,6 +209,48 @@ func TestIsValidBucketName(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "IsValidBucketName-adjacent-dots",
+			args: args{
+				bucket: "my..bucket",
+			},
+			want: false,
+		},
+		{
+			name: "IsValidBucketName-ip-address",
+			args: args{
+				bucket: "192.168.5.4",
+			},
+			want: false,
+		},
+		{
+			name: "IsValidBucketName-upper-case",
+			args: args{
+				bucket: "MyBucket",
+			},
+			want: false,
+		},
+		{
+			name: "IsValidBucketName-long-name",
+			args: args{
+				bucket: strings.Repeat("a", 64),
+			},
+			want: false,
+		},
+		{
+			name: "IsValidBucketName-reserved-prefix",
+			args: args{
+				bucket: "xn--bucket",
+			},
+			want: false,
+		},
+		{
+			name: "IsValidBucketName-reserved-suffix",
+			args: args{
+				bucket: "bucket-s3alias",
+			},
+			want: false,
+		},
 		{
 			name: "IsValidBucketName-valid-bucket-name",
 			args: args{
@@ -214,6 +258,13 @@ func TestIsValidBucketName(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "IsValidBucketName-valid-dotted-name",
+			args: args{
+				bucket: "my.bucket.1",
+			},
+			want: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -335,3 +386,74 @@ func TestFilterObjectAttributes(t *testing.T) {
 		})
 	}
 }
+
+func TestURLEncodeKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{name: "plain", key: "my-obj", want: "my-obj"},
+		{name: "keeps-separators", key: "dir/sub/obj", want: "dir/sub/obj"},
+		{name: "space", key: "my obj", want: "my+obj"},
+		{name: "control-chars", key: "a\x01b\nc", want: "a%01b%0Ac"},
+		{name: "special-chars", key: "a&b<c>%", want: "a%26b%3Cc%3E%25"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := URLEncodeKey(tt.key); got != tt.want {
+				t.Errorf("URLEncodeKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripAwsChunked(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  string
+		want string
+	}{
+		{name: "empty", enc: "", want: ""},
+		{name: "only-chunked", enc: "aws-chunked", want: ""},
+		{name: "chunked-first", enc: "aws-chunked,gzip", want: "gzip"},
+		{name: "chunked-last", enc: "gzip, aws-chunked", want: "gzip"},
+		{name: "no-chunked", enc: "gzip,br", want: "gzip,br"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripAwsChunked(tt.enc); got != tt.want {
+				t.Errorf("StripAwsChunked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeListObjectsV2Output(t *testing.T) {
+	key, prefix, delim := "dir/a\x01b", "dir/", "/"
+	cp := "dir/sub dir/"
+	out := &s3.ListObjectsV2Output{
+		Prefix:         &prefix,
+		Delimiter:      &delim,
+		Contents:       []types.Object{{Key: &key}},
+		CommonPrefixes: []types.CommonPrefix{{Prefix: &cp}},
+	}
+
+	EncodeListObjectsV2Output(out)
+
+	if out.EncodingType != types.EncodingTypeUrl {
+		t.Errorf("expected encoding type url, got %v", out.EncodingType)
+	}
+	if *out.Contents[0].Key != "dir/a%01b" {
+		t.Errorf("expected encoded key, got %v", *out.Contents[0].Key)
+	}
+	if *out.CommonPrefixes[0].Prefix != "dir/sub+dir/" {
+		t.Errorf("expected encoded common prefix, got %v", *out.CommonPrefixes[0].Prefix)
+	}
+	if *out.Prefix != prefix || *out.Delimiter != delim {
+		t.Errorf("unexpected prefix/delimiter %v/%v", *out.Prefix, *out.Delimiter)
+	}
+	if out.StartAfter != nil {
+		t.Errorf("expected nil start after, got %v", *out.StartAfter)
+	}
+}
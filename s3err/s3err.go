@@ -122,12 +122,45 @@ const (
 	ErrBucketTaggingNotFound
 	ErrObjectLockInvalidHeaders
 	ErrRequestTimeTooSkewed
+	ErrInvalidVersionIdMarker
+	ErrKMSNotConfigured
+	ErrKMSKeyNotFound
+	ErrPresignedRequestNotYetValid
+	ErrSignedHeadersMissingHost
+	ErrIncompleteBody
+	ErrMissingContentLength
+	ErrBadDigest
+	ErrNotModified
+	ErrInvalidEncodingMethod
+	ErrNoSuchPublicAccessBlockConfiguration
+	ErrInvalidIdentityToken
+	ErrSlowDown
+	ErrServiceUnavailable
+	ErrKeyTooLong
+	ErrMetadataTooLarge
+	ErrInvalidLocationConstraint
+	ErrInvalidTargetBucketForLogging
 
 	// Non-AWS errors
 	ErrExistingObjectIsDirectory
 	ErrObjectParentIsFile
 	ErrDirectoryObjectContainsData
 	ErrQuotaExceeded
+	ErrInvalidMetadataDirective
+	ErrInvalidTaggingDirective
+	ErrNoSuchTrashEntry
+	ErrTrashRestoreConflict
+	ErrInvalidPartNumberRange
+	ErrRangeWithPartNumber
+	ErrMultipleChecksumHeaders
+	ErrInvalidChecksumHeader
+	ErrChecksumMismatch
+	ErrInvalidPartOrder
+	ErrInvalidChecksumAlgorithm
+	ErrChecksumAlgorithmMismatch
+	ErrTooManyObjectTags
+	ErrDuplicateTagKey
+	ErrKMSEncryptedCopySource
 )
 
 var errorCodeResponse = map[ErrorCode]APIError{
@@ -466,6 +499,96 @@ var errorCodeResponse = map[ErrorCode]APIError{
 		Description:    "The difference between the request time and the server's time is too large.",
 		HTTPStatusCode: http.StatusForbidden,
 	},
+	ErrInvalidVersionIdMarker: {
+		Code:           "InvalidArgument",
+		Description:    "A version-id marker cannot be specified without a key marker.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrKMSNotConfigured: {
+		Code:           "InvalidArgument",
+		Description:    "Server Side Encryption with AWS KMS managed key is not configured.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrKMSKeyNotFound: {
+		Code:           "KMS.NotFoundException",
+		Description:    "Invalid keyId",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrPresignedRequestNotYetValid: {
+		Code:           "AccessDenied",
+		Description:    "Request is not valid yet",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrSignedHeadersMissingHost: {
+		Code:           "AuthorizationQueryParametersError",
+		Description:    "X-Amz-SignedHeaders must contain host",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrIncompleteBody: {
+		Code:           "IncompleteBody",
+		Description:    "You did not provide the number of bytes specified by the Content-Length HTTP header.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrMissingContentLength: {
+		Code:           "MissingContentLength",
+		Description:    "You must provide the Content-Length HTTP header.",
+		HTTPStatusCode: http.StatusLengthRequired,
+	},
+	ErrBadDigest: {
+		Code:           "BadDigest",
+		Description:    "The Content-MD5 you specified did not match what we received.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNotModified: {
+		Code:           "NotModified",
+		Description:    "Not Modified",
+		HTTPStatusCode: http.StatusNotModified,
+	},
+	ErrInvalidEncodingMethod: {
+		Code:           "InvalidArgument",
+		Description:    "Invalid Encoding Method specified in Request",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchPublicAccessBlockConfiguration: {
+		Code:           "NoSuchPublicAccessBlockConfiguration",
+		Description:    "The public access block configuration was not found",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidIdentityToken: {
+		Code:           "InvalidIdentityToken",
+		Description:    "The web identity token that was passed could not be validated.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrSlowDown: {
+		Code:           "SlowDown",
+		Description:    "Please reduce your request rate.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrServiceUnavailable: {
+		Code:           "ServiceUnavailable",
+		Description:    "The service is in maintenance and only serves read requests, please retry later.",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrKeyTooLong: {
+		Code:           "KeyTooLongError",
+		Description:    "Your key is too long.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrMetadataTooLarge: {
+		Code:           "MetadataTooLarge",
+		Description:    "Your metadata headers exceed the maximum allowed metadata size.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidLocationConstraint: {
+		Code:           "IllegalLocationConstraintException",
+		Description:    "The location constraint is incompatible for the region specific endpoint this request was sent to.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidTargetBucketForLogging: {
+		Code:           "InvalidTargetBucketForLogging",
+		Description:    "The target bucket for logging does not exist or is not owned by you.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 
 	// non aws errors
 	ErrExistingObjectIsDirectory: {
@@ -488,6 +611,81 @@ var errorCodeResponse = map[ErrorCode]APIError{
 		Description:    "Your request was denied due to quota exceeded.",
 		HTTPStatusCode: http.StatusForbidden,
 	},
+	ErrInvalidMetadataDirective: {
+		Code:           "InvalidArgument",
+		Description:    "Unknown metadata directive.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidTaggingDirective: {
+		Code:           "InvalidArgument",
+		Description:    "Unknown tagging directive.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchTrashEntry: {
+		Code:           "NoSuchTrashEntry",
+		Description:    "The specified trash entry does not exist.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrTrashRestoreConflict: {
+		Code:           "ObjectAlreadyExists",
+		Description:    "An object already exists at the key of the trashed object.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrInvalidPartNumberRange: {
+		Code:           "InvalidPartNumber",
+		Description:    "The requested partnumber is not satisfiable",
+		HTTPStatusCode: http.StatusRequestedRangeNotSatisfiable,
+	},
+	ErrRangeWithPartNumber: {
+		Code:           "InvalidRequest",
+		Description:    "Cannot specify both Range header and partNumber query parameter",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrMultipleChecksumHeaders: {
+		Code:           "InvalidRequest",
+		Description:    "Expecting a single x-amz-checksum- header. Multiple checksum Types are not allowed.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidChecksumHeader: {
+		Code:           "InvalidRequest",
+		Description:    "The value specified in the x-amz-checksum- header is invalid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrChecksumMismatch: {
+		Code:           "BadDigest",
+		Description:    "The checksum you specified did not match the calculated checksum.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidPartOrder: {
+		Code:           "InvalidPartOrder",
+		Description:    "The list of parts was not in ascending order. Parts must be ordered by part number.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidChecksumAlgorithm: {
+		Code:           "InvalidRequest",
+		Description:    "Checksum algorithm provided is unsupported. Please try again with any of the valid types: [CRC32, CRC32C, SHA1, SHA256]",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrChecksumAlgorithmMismatch: {
+		Code:           "InvalidRequest",
+		Description:    "Checksum Type mismatch occurred, the checksum does not match the checksum algorithm of the multipart upload.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrTooManyObjectTags: {
+		Code:           "BadRequest",
+		Description:    "Object tags cannot be greater than 10",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrDuplicateTagKey: {
+		Code:           "InvalidTag",
+		Description:    "Cannot provide multiple Tags with the same key",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrKMSEncryptedCopySource: {
+		Code:           "InvalidRequest",
+		Description:    "Copying a part from a source object encrypted with SSE-KMS is not supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 }
 
 // GetAPIError provides API Error for input API error code.
@@ -0,0 +1,420 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+const (
+	notifyRetries    = 3
+	notifyBackoff    = 500 * time.Millisecond
+	notifyMaxBackoff = 5 * time.Second
+
+	defaultNotifyWorkers   = 4
+	defaultNotifyQueueSize = 1000
+	defaultNotifyConfigTTL = time.Minute
+)
+
+// BucketNotifierOpts are the gateway admin settings for the per bucket
+// webhook notifications
+type BucketNotifierOpts struct {
+	// AllowedDestinations are the webhook urls bucket notification
+	// configurations may send events to. A configured destination is
+	// allowed when its scheme and host match an allowed url, and its
+	// path is within the allowed url path. No destinations are allowed
+	// when this is empty.
+	AllowedDestinations []string
+	// Workers is the number of concurrent webhook deliveries
+	Workers int
+	// QueueSize is the number of events waiting for delivery, before
+	// new events are dropped
+	QueueSize int
+	// ConfigTTL is how long a bucket notification configuration is
+	// cached before it is read again from the backend
+	ConfigTTL time.Duration
+	// Debug enables logging of failed deliveries
+	Debug bool
+}
+
+type delivery struct {
+	url  string
+	body []byte
+}
+
+type cachedTargets struct {
+	targets []webhookTarget
+	expires time.Time
+}
+
+// BucketNotifier delivers events to the webhook destinations configured
+// per bucket with PutBucketNotificationConfiguration. Events are also
+// passed along to the gateway wide event sender when one is configured.
+type BucketNotifier struct {
+	be         backend.Backend
+	next       S3EventSender
+	client     *http.Client
+	allowed    []*url.URL
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	configTTL  time.Duration
+	debug      bool
+
+	cacheMu sync.Mutex
+	cache   map[string]cachedTargets
+
+	queueMu sync.RWMutex
+	queue   chan delivery
+	closed  bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+var _ S3EventSender = &BucketNotifier{}
+var _ NotificationConfigChecker = &BucketNotifier{}
+
+// NotificationConfigChecker is implemented by the event senders delivering
+// the per bucket notifications, to validate new bucket notification
+// configurations and to pick up the changes once these are stored
+type NotificationConfigChecker interface {
+	CheckConfiguration(cfg *NotificationConfiguration) error
+	ConfigurationChanged(bucket string)
+}
+
+// NewBucketNotifier creates a bucket notification dispatcher, next is the
+// optional gateway wide event sender
+func NewBucketNotifier(be backend.Backend, next S3EventSender, opts BucketNotifierOpts) (*BucketNotifier, error) {
+	var allowed []*url.URL
+	for _, dest := range opts.AllowedDestinations {
+		u, err := url.Parse(dest)
+		if err != nil {
+			return nil, fmt.Errorf("parse notification destination %q: %w", dest, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid notification destination %q: expected http(s) url", dest)
+		}
+		allowed = append(allowed, u)
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultNotifyWorkers
+	}
+	queueSize := opts.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultNotifyQueueSize
+	}
+	configTTL := opts.ConfigTTL
+	if configTTL <= 0 {
+		configTTL = defaultNotifyConfigTTL
+	}
+
+	bn := &BucketNotifier{
+		be:   be,
+		next: next,
+		client: &http.Client{
+			Timeout: 3 * time.Second,
+			// redirects could lead the deliveries outside of the
+			// allowed destinations
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowed:    allowed,
+		retries:    notifyRetries,
+		backoff:    notifyBackoff,
+		maxBackoff: notifyMaxBackoff,
+		configTTL:  configTTL,
+		debug:      opts.Debug,
+		cache:      make(map[string]cachedTargets),
+		queue:      make(chan delivery, queueSize),
+		done:       make(chan struct{}),
+	}
+
+	bn.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go bn.worker()
+	}
+
+	return bn, nil
+}
+
+// CheckConfiguration validates the notification configuration destinations
+// against the allowed destinations
+func (bn *BucketNotifier) CheckConfiguration(cfg *NotificationConfiguration) error {
+	for _, t := range cfg.targets() {
+		if !bn.isAllowed(t.url) {
+			return s3err.APIError{
+				Code:           "InvalidArgument",
+				Description:    fmt.Sprintf("Unable to validate the following destination configurations: %v", t.url),
+				HTTPStatusCode: http.StatusBadRequest,
+			}
+		}
+	}
+	return nil
+}
+
+// ConfigurationChanged drops the cached notification configuration of the
+// bucket, so that the next event reads the new configuration
+func (bn *BucketNotifier) ConfigurationChanged(bucket string) {
+	bn.cacheMu.Lock()
+	delete(bn.cache, bucket)
+	bn.cacheMu.Unlock()
+}
+
+func (bn *BucketNotifier) SendEvent(ctx *fiber.Ctx, meta EventMeta) {
+	if bn.next != nil {
+		bn.next.SendEvent(ctx, meta)
+	}
+
+	if meta.EventName == "" {
+		return
+	}
+
+	path := strings.Split(ctx.Path(), "/")
+	bucket, object := path[1], strings.Join(path[2:], "/")
+	if meta.ObjectKey != "" {
+		object = meta.ObjectKey
+	}
+
+	targets := bn.bucketTargets(ctx.Context(), bucket)
+	if len(targets) == 0 {
+		return
+	}
+
+	type objectEvent struct {
+		key       string
+		versionId *string
+	}
+
+	eventName := meta.EventName
+	events := []objectEvent{{key: object, versionId: meta.VersionId}}
+
+	if meta.EventName == EventObjectRemovedDeleteObjects {
+		// DeleteObjects is reported as a delete event for each
+		// object that was removed
+		eventName = EventObjectRemovedDelete
+		events = events[:0]
+		for _, obj := range meta.DeletedObjects {
+			if obj.Key == nil {
+				continue
+			}
+			events = append(events, objectEvent{key: *obj.Key, versionId: obj.VersionId})
+		}
+	}
+
+	for _, t := range targets {
+		for _, ev := range events {
+			if !t.matches(eventName, ev.key) {
+				continue
+			}
+
+			meta.EventName = eventName
+			schema := createEventSchema(ctx, meta, ConfigurationId(t.id))
+			schema.Records[0].S3.Object.Key = ev.key
+			schema.Records[0].S3.Object.VersionId = ev.versionId
+
+			body, err := json.Marshal(schema)
+			if err != nil {
+				if bn.debug {
+					log.Printf("failed to parse event data: %v", err)
+				}
+				continue
+			}
+
+			bn.enqueue(delivery{url: t.url, body: body})
+		}
+	}
+}
+
+// Close stops accepting events and waits for the queued deliveries to
+// finish before closing the gateway wide event sender. Pending retries
+// are abandoned.
+func (bn *BucketNotifier) Close() error {
+	bn.queueMu.Lock()
+	if !bn.closed {
+		bn.closed = true
+		close(bn.done)
+		close(bn.queue)
+	}
+	bn.queueMu.Unlock()
+
+	bn.wg.Wait()
+	if bn.next != nil {
+		return bn.next.Close()
+	}
+	return nil
+}
+
+// bucketTargets returns the allowed webhook targets of the bucket
+// notification configuration, reading the configuration from the backend
+// only when the cached entry is missing or expired
+func (bn *BucketNotifier) bucketTargets(ctx context.Context, bucket string) []webhookTarget {
+	now := time.Now()
+
+	bn.cacheMu.Lock()
+	cached, ok := bn.cache[bucket]
+	bn.cacheMu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.targets
+	}
+
+	var targets []webhookTarget
+	data, err := bn.be.GetBucketNotificationConfiguration(ctx, bucket)
+	if err == nil && len(data) != 0 {
+		cfg, err := ParseNotificationConfiguration(data)
+		if err != nil {
+			if bn.debug {
+				log.Printf("invalid notification configuration for bucket %v: %v",
+					bucket, err)
+			}
+		} else {
+			for _, t := range cfg.targets() {
+				if !bn.isAllowed(t.url) {
+					if bn.debug {
+						log.Printf("bucket %v notification destination %v is not allowed",
+							bucket, t.url)
+					}
+					continue
+				}
+				targets = append(targets, t)
+			}
+		}
+	}
+
+	// buckets without a configuration are cached as well, so that
+	// events on these do not look up the configuration each time
+	bn.cacheMu.Lock()
+	bn.cache[bucket] = cachedTargets{targets: targets, expires: now.Add(bn.configTTL)}
+	bn.cacheMu.Unlock()
+
+	return targets
+}
+
+// isAllowed checks the destination against the allowed destinations
+func (bn *BucketNotifier) isAllowed(dest string) bool {
+	u, err := url.Parse(dest)
+	if err != nil || u.User != nil {
+		return false
+	}
+
+	for _, a := range bn.allowed {
+		if u.Scheme != a.Scheme || !strings.EqualFold(u.Host, a.Host) {
+			continue
+		}
+		prefix := strings.TrimSuffix(a.Path, "/")
+		if u.Path == a.Path || u.Path == prefix ||
+			strings.HasPrefix(u.Path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue adds the delivery to the queue, dropping it when the queue is
+// full or the notifier is closed
+func (bn *BucketNotifier) enqueue(d delivery) {
+	bn.queueMu.RLock()
+	defer bn.queueMu.RUnlock()
+
+	if bn.closed {
+		return
+	}
+
+	select {
+	case bn.queue <- d:
+	default:
+		if bn.debug {
+			log.Printf("bucket notification queue full, dropping event for %v", d.url)
+		}
+	}
+}
+
+func (bn *BucketNotifier) worker() {
+	defer bn.wg.Done()
+	for d := range bn.queue {
+		bn.deliver(d.url, d.body)
+	}
+}
+
+// deliver posts the event to the webhook, retrying failed attempts with
+// exponential backoff
+func (bn *BucketNotifier) deliver(url string, body []byte) {
+	backoff := bn.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := bn.post(url, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= bn.retries {
+			if bn.debug {
+				log.Printf("failed to send bucket notification to %v: %v", url, err)
+			}
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-bn.done:
+			if bn.debug {
+				log.Printf("failed to send bucket notification to %v: %v", url, err)
+			}
+			return
+		}
+		backoff *= 2
+		if backoff > bn.maxBackoff {
+			backoff = bn.maxBackoff
+		}
+	}
+}
+
+// post sends a single delivery attempt, and reports whether a failure
+// may succeed on retry
+func (bn *BucketNotifier) post(url string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := bn.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook response status %v", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook response status %v", resp.StatusCode)
+	}
+}
@@ -0,0 +1,194 @@
+package s3event
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+)
+
+func TestBucketNotifierAllowedDestinations(t *testing.T) {
+	bn, err := NewBucketNotifier(nil, nil, BucketNotifierOpts{
+		AllowedDestinations: []string{
+			"https://hooks.example.com/events/",
+			"http://10.0.0.5:8080",
+		},
+	})
+	if err != nil {
+		t.Fatalf("new bucket notifier: %v", err)
+	}
+	defer bn.Close()
+
+	tests := []struct {
+		dest    string
+		allowed bool
+	}{
+		{"https://hooks.example.com/events/", true},
+		{"https://hooks.example.com/events", true},
+		{"https://hooks.example.com/events/bucket1", true},
+		{"https://HOOKS.example.com/events/bucket1", true},
+		{"https://hooks.example.com/eventsx", false},
+		{"https://hooks.example.com/", false},
+		{"http://hooks.example.com/events/", false},
+		{"https://hooks.example.com:8443/events/", false},
+		{"https://hooks.example.com@169.254.169.254/events/", false},
+		{"https://user@hooks.example.com/events/", false},
+		{"http://10.0.0.5:8080/any/path", true},
+		{"http://10.0.0.5/any/path", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+	}
+
+	for _, tt := range tests {
+		if got := bn.isAllowed(tt.dest); got != tt.allowed {
+			t.Errorf("%v: expected allowed %v, got %v", tt.dest, tt.allowed, got)
+		}
+	}
+}
+
+func TestBucketNotifierNoDestinations(t *testing.T) {
+	bn, err := NewBucketNotifier(nil, nil, BucketNotifierOpts{})
+	if err != nil {
+		t.Fatalf("new bucket notifier: %v", err)
+	}
+	defer bn.Close()
+
+	cfg, err := ParseNotificationConfiguration([]byte(`<NotificationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+	<QueueConfiguration>
+		<Queue>http://localhost:8080/events</Queue>
+		<Event>s3:ObjectCreated:*</Event>
+	</QueueConfiguration>
+</NotificationConfiguration>`))
+	if err != nil {
+		t.Fatalf("failed to parse notification configuration: %v", err)
+	}
+
+	if err := bn.CheckConfiguration(cfg); err == nil {
+		t.Fatalf("expected destination to be rejected")
+	}
+	if err := bn.CheckConfiguration(&NotificationConfiguration{}); err != nil {
+		t.Fatalf("empty configuration: %v", err)
+	}
+}
+
+func TestBucketNotifierInvalidDestination(t *testing.T) {
+	for _, dest := range []string{"ftp://example.com", "example.com/events", "http://"} {
+		if _, err := NewBucketNotifier(nil, nil, BucketNotifierOpts{
+			AllowedDestinations: []string{dest},
+		}); err == nil {
+			t.Errorf("%v: expected error", dest)
+		}
+	}
+}
+
+func TestBucketNotifierQueueBound(t *testing.T) {
+	var received atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		received.Add(1)
+	}))
+	defer srv.Close()
+
+	bn, err := NewBucketNotifier(nil, nil, BucketNotifierOpts{
+		AllowedDestinations: []string{srv.URL},
+		Workers:             1,
+		QueueSize:           1,
+	})
+	if err != nil {
+		t.Fatalf("new bucket notifier: %v", err)
+	}
+
+	// with the single worker blocked on the first delivery and one
+	// queued, the remaining events are dropped
+	for i := 0; i < 10; i++ {
+		bn.enqueue(delivery{url: srv.URL, body: []byte("{}")})
+	}
+	close(release)
+	bn.Close()
+
+	if n := received.Load(); n < 1 || n > 2 {
+		t.Fatalf("expected at most 2 deliveries, got %v", n)
+	}
+}
+
+func TestBucketNotifierDeleteObjects(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var schema EventSchema
+		if err := json.NewDecoder(r.Body).Decode(&schema); err != nil {
+			t.Errorf("decode event: %v", err)
+			return
+		}
+		mu.Lock()
+		for _, rec := range schema.Records {
+			if rec.EventName != EventObjectRemovedDelete {
+				t.Errorf("unexpected event %v", rec.EventName)
+			}
+			keys = append(keys, rec.S3.Object.Key)
+		}
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	bn, err := NewBucketNotifier(nil, nil, BucketNotifierOpts{
+		AllowedDestinations: []string{srv.URL},
+	})
+	if err != nil {
+		t.Fatalf("new bucket notifier: %v", err)
+	}
+
+	cfg, err := ParseNotificationConfiguration([]byte(`<NotificationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+	<QueueConfiguration>
+		<Queue>` + srv.URL + `</Queue>
+		<Event>s3:ObjectRemoved:*</Event>
+	</QueueConfiguration>
+</NotificationConfiguration>`))
+	if err != nil {
+		t.Fatalf("failed to parse notification configuration: %v", err)
+	}
+	bn.cache["bucket"] = cachedTargets{
+		targets: cfg.targets(),
+		expires: time.Now().Add(time.Hour),
+	}
+
+	app := fiber.New()
+	app.Post("/:bucket", func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "user"})
+		ctx.Locals("region", "us-east-1")
+		// "locked" is in the request but failed to delete
+		bn.SendEvent(ctx, EventMeta{
+			EventName: EventObjectRemovedDeleteObjects,
+			DeletedObjects: []types.DeletedObject{
+				{Key: aws.String("a")},
+				{Key: aws.String("b")},
+			},
+		})
+		return nil
+	})
+
+	body := `<Delete><Object><Key>a</Key></Object><Object><Key>locked</Key></Object><Object><Key>b</Key></Object></Delete>`
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/bucket?delete", strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	bn.Close()
+
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "a,b" {
+		t.Fatalf("expected events for a,b, got %v", keys)
+	}
+}
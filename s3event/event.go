@@ -20,6 +20,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/gofiber/fiber/v2"
 	"github.com/versity/versitygw/auth"
 )
@@ -35,6 +36,12 @@ type EventMeta struct {
 	ObjectSize  int64
 	ObjectETag  *string
 	VersionId   *string
+	// ObjectKey is set when the object key is not part of the
+	// request path, such as browser based POST uploads
+	ObjectKey string
+	// DeletedObjects are the objects a DeleteObjects request removed,
+	// the keys that failed to delete are not included
+	DeletedObjects []types.DeletedObject
 }
 
 type EventSchema struct {
@@ -142,6 +149,9 @@ func InitEventSender(cfg *EventConfig) (S3EventSender, error) {
 func createEventSchema(ctx *fiber.Ctx, meta EventMeta, configId ConfigurationId) EventSchema {
 	path := strings.Split(ctx.Path(), "/")
 	bucket, object := path[1], strings.Join(path[2:], "/")
+	if meta.ObjectKey != "" {
+		object = meta.ObjectKey
+	}
 	acc := ctx.Locals("account").(auth.Account)
 
 	return EventSchema{
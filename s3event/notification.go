@@ -0,0 +1,184 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3event
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/versity/versitygw/s3err"
+)
+
+// NotificationConfiguration is the per bucket event notification
+// configuration. The AWS destination ARNs are not supported, instead
+// the topic, queue or function destination must be an http(s) webhook
+// URL that events are posted to.
+type NotificationConfiguration struct {
+	XMLName                      xml.Name                      `xml:"http://s3.amazonaws.com/doc/2006-03-01/ NotificationConfiguration"`
+	TopicConfigurations          []TopicConfiguration          `xml:"TopicConfiguration,omitempty"`
+	QueueConfigurations          []QueueConfiguration          `xml:"QueueConfiguration,omitempty"`
+	LambdaFunctionConfigurations []LambdaFunctionConfiguration `xml:"CloudFunctionConfiguration,omitempty"`
+}
+
+type TopicConfiguration struct {
+	Id     string              `xml:"Id,omitempty"`
+	Topic  string              `xml:"Topic"`
+	Events []EventType         `xml:"Event"`
+	Filter *NotificationFilter `xml:"Filter,omitempty"`
+}
+
+type QueueConfiguration struct {
+	Id     string              `xml:"Id,omitempty"`
+	Queue  string              `xml:"Queue"`
+	Events []EventType         `xml:"Event"`
+	Filter *NotificationFilter `xml:"Filter,omitempty"`
+}
+
+type LambdaFunctionConfiguration struct {
+	Id                string              `xml:"Id,omitempty"`
+	LambdaFunctionArn string              `xml:"CloudFunction"`
+	Events            []EventType         `xml:"Event"`
+	Filter            *NotificationFilter `xml:"Filter,omitempty"`
+}
+
+type NotificationFilter struct {
+	Key KeyFilter `xml:"S3Key"`
+}
+
+type KeyFilter struct {
+	FilterRules []FilterRule `xml:"FilterRule"`
+}
+
+type FilterRule struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+// webhookTarget is a single notification destination
+type webhookTarget struct {
+	id     string
+	url    string
+	events []EventType
+	filter *NotificationFilter
+}
+
+// ParseNotificationConfiguration parses and validates the bucket
+// notification configuration xml
+func ParseNotificationConfiguration(data []byte) (*NotificationConfiguration, error) {
+	var cfg NotificationConfiguration
+	if len(data) == 0 {
+		return &cfg, nil
+	}
+	if err := xml.Unmarshal(data, &cfg); err != nil {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, s3err.APIError{
+			Code:           "InvalidArgument",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	}
+
+	return &cfg, nil
+}
+
+// Validate checks that all destinations are webhook URLs and that the
+// events and filter rules are supported
+func (nc *NotificationConfiguration) Validate() error {
+	for _, t := range nc.targets() {
+		u, err := url.Parse(t.url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("unsupported notification destination %q: only http(s) webhook urls are supported", t.url)
+		}
+		if len(t.events) == 0 {
+			return fmt.Errorf("no events specified for destination %q", t.url)
+		}
+		for _, ev := range t.events {
+			if !ev.IsValid() {
+				return fmt.Errorf("unsupported event: %v", ev)
+			}
+		}
+		if t.filter != nil {
+			for _, rule := range t.filter.Key.FilterRules {
+				name := strings.ToLower(rule.Name)
+				if name != "prefix" && name != "suffix" {
+					return fmt.Errorf("invalid filter rule name: %v", rule.Name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// IsEmpty returns true if no notification destinations are configured
+func (nc *NotificationConfiguration) IsEmpty() bool {
+	return len(nc.TopicConfigurations) == 0 &&
+		len(nc.QueueConfigurations) == 0 &&
+		len(nc.LambdaFunctionConfigurations) == 0
+}
+
+func (nc *NotificationConfiguration) targets() []webhookTarget {
+	var targets []webhookTarget
+	for _, c := range nc.TopicConfigurations {
+		targets = append(targets, webhookTarget{id: c.Id, url: c.Topic, events: c.Events, filter: c.Filter})
+	}
+	for _, c := range nc.QueueConfigurations {
+		targets = append(targets, webhookTarget{id: c.Id, url: c.Queue, events: c.Events, filter: c.Filter})
+	}
+	for _, c := range nc.LambdaFunctionConfigurations {
+		targets = append(targets, webhookTarget{id: c.Id, url: c.LambdaFunctionArn, events: c.Events, filter: c.Filter})
+	}
+	return targets
+}
+
+// matches returns true if the event for object key should be sent to
+// this target
+func (t webhookTarget) matches(event EventType, key string) bool {
+	found := false
+	for _, ev := range t.events {
+		if ev == event || (strings.HasSuffix(string(ev), "*") &&
+			strings.HasPrefix(string(event), strings.TrimSuffix(string(ev), "*"))) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	if t.filter == nil {
+		return true
+	}
+
+	for _, rule := range t.filter.Key.FilterRules {
+		switch strings.ToLower(rule.Name) {
+		case "prefix":
+			if !strings.HasPrefix(key, rule.Value) {
+				return false
+			}
+		case "suffix":
+			if !strings.HasSuffix(key, rule.Value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,63 @@
+package s3event
+
+import "testing"
+
+func TestParseNotificationConfiguration(t *testing.T) {
+	data := []byte(`<NotificationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+	<QueueConfiguration>
+		<Id>images</Id>
+		<Queue>http://localhost:8080/events</Queue>
+		<Event>s3:ObjectCreated:*</Event>
+		<Filter>
+			<S3Key>
+				<FilterRule><Name>prefix</Name><Value>images/</Value></FilterRule>
+				<FilterRule><Name>suffix</Name><Value>.jpg</Value></FilterRule>
+			</S3Key>
+		</Filter>
+	</QueueConfiguration>
+</NotificationConfiguration>`)
+
+	cfg, err := ParseNotificationConfiguration(data)
+	if err != nil {
+		t.Fatalf("failed to parse notification configuration: %v", err)
+	}
+
+	targets := cfg.targets()
+	if len(targets) != 1 {
+		t.Fatalf("expected 1 target, got %v", len(targets))
+	}
+
+	tests := []struct {
+		event EventType
+		key   string
+		match bool
+	}{
+		{EventObjectCreatedPut, "images/cat.jpg", true},
+		{EventCompleteMultipartUpload, "images/dog.jpg", true},
+		{EventObjectCreatedPut, "images/cat.png", false},
+		{EventObjectCreatedPut, "docs/cat.jpg", false},
+		{EventObjectRemovedDelete, "images/cat.jpg", false},
+	}
+
+	for _, tt := range tests {
+		if got := targets[0].matches(tt.event, tt.key); got != tt.match {
+			t.Errorf("matches(%v, %v) = %v, expected %v", tt.event, tt.key, got, tt.match)
+		}
+	}
+}
+
+func TestParseNotificationConfigurationInvalid(t *testing.T) {
+	invalid := []string{
+		"invalid xml",
+		`<NotificationConfiguration><TopicConfiguration><Topic>arn:aws:sns:us-east-1:1:t</Topic><Event>s3:ObjectCreated:*</Event></TopicConfiguration></NotificationConfiguration>`,
+		`<NotificationConfiguration><TopicConfiguration><Topic>https://example.com</Topic><Event>s3:Invalid</Event></TopicConfiguration></NotificationConfiguration>`,
+		`<NotificationConfiguration><TopicConfiguration><Topic>https://example.com</Topic></TopicConfiguration></NotificationConfiguration>`,
+	}
+
+	for _, data := range invalid {
+		_, err := ParseNotificationConfiguration([]byte(data))
+		if err == nil {
+			t.Errorf("expected error for %v", data)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build windows || plan9
+
+package s3log
+
+import "errors"
+
+type adminAuditSyslog struct{}
+
+func newAdminAuditSyslog(string) (*adminAuditSyslog, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
+
+func (a *adminAuditSyslog) write([]byte) error { return nil }
+
+func (a *adminAuditSyslog) hangUp() error { return nil }
+
+func (a *adminAuditSyslog) close() error { return nil }
@@ -0,0 +1,108 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3log
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// adminAuditFile writes one JSON entry per line to a local file
+type adminAuditFile struct {
+	mu      sync.Mutex
+	logfile string
+	f       *os.File
+}
+
+func newAdminAuditFile(logname string) (*adminAuditFile, error) {
+	f, err := os.OpenFile(logname, os.O_APPEND|os.O_CREATE|os.O_WRONLY, logFileMode)
+	if err != nil {
+		return nil, fmt.Errorf("open admin audit log: %w", err)
+	}
+
+	return &adminAuditFile{logfile: logname, f: f}, nil
+}
+
+func (a *adminAuditFile) write(entry []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, err := a.f.Write(append(entry, '\n'))
+	return err
+}
+
+func (a *adminAuditFile) hangUp() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	err := a.f.Close()
+	if err != nil {
+		return fmt.Errorf("close admin audit log: %w", err)
+	}
+
+	a.f, err = os.OpenFile(a.logfile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, logFileMode)
+	if err != nil {
+		return fmt.Errorf("open admin audit log: %w", err)
+	}
+
+	return nil
+}
+
+func (a *adminAuditFile) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.f.Close()
+}
+
+// adminAuditWebhook posts each JSON entry to a webhook URL
+type adminAuditWebhook struct {
+	url string
+}
+
+func newAdminAuditWebhook(url string) (*adminAuditWebhook, error) {
+	client := &http.Client{
+		Timeout: 3 * time.Second,
+	}
+	_, err := client.Post(url, "application/json", nil)
+	if err != nil {
+		if err, ok := err.(net.Error); ok && !err.Timeout() {
+			return nil, fmt.Errorf("unreachable admin audit webhook url: %w", err)
+		}
+	}
+
+	return &adminAuditWebhook{url: url}, nil
+}
+
+func (a *adminAuditWebhook) write(entry []byte) error {
+	req, err := http.NewRequest(http.MethodPost, a.url, bytes.NewReader(entry))
+	if err != nil {
+		return fmt.Errorf("create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	go makeRequest(req)
+
+	return nil
+}
+
+func (a *adminAuditWebhook) hangUp() error { return nil }
+
+func (a *adminAuditWebhook) close() error { return nil }
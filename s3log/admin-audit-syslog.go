@@ -0,0 +1,46 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build !windows && !plan9
+
+package s3log
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// adminAuditSyslog sends each JSON entry to the local syslog daemon
+type adminAuditSyslog struct {
+	w *syslog.Writer
+}
+
+func newAdminAuditSyslog(tag string) (*adminAuditSyslog, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, fmt.Errorf("connect syslog: %w", err)
+	}
+
+	return &adminAuditSyslog{w: w}, nil
+}
+
+func (a *adminAuditSyslog) write(entry []byte) error {
+	return a.w.Info(string(entry))
+}
+
+func (a *adminAuditSyslog) hangUp() error { return nil }
+
+func (a *adminAuditSyslog) close() error {
+	return a.w.Close()
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+)
+
+const (
+	AdminAuditResultSuccess = "success"
+	AdminAuditResultFailure = "failure"
+)
+
+// AdminAuditLogger records admin api calls and iam mutations
+type AdminAuditLogger interface {
+	Log(ctx *fiber.Ctx, err error, meta AdminAuditMeta)
+	HangUp() error
+	Shutdown() error
+}
+
+// AdminAuditMeta describes the target of an admin operation
+type AdminAuditMeta struct {
+	Action string
+	Target AdminAuditTarget
+}
+
+type AdminAuditTarget struct {
+	User   string `json:"user,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Bucket string `json:"bucket,omitempty"`
+	Owner  string `json:"owner,omitempty"`
+	Group  string `json:"group,omitempty"`
+}
+
+// AdminAuditEntry is a single JSON encoded audit record
+type AdminAuditEntry struct {
+	Time      time.Time        `json:"time"`
+	RequestID string           `json:"requestId"`
+	Actor     string           `json:"actor"`
+	ActorRole string           `json:"actorRole,omitempty"`
+	RemoteIP  string           `json:"remoteIp"`
+	Action    string           `json:"action"`
+	Target    AdminAuditTarget `json:"target"`
+	Result    string           `json:"result"`
+	Error     string           `json:"error,omitempty"`
+}
+
+type AdminAuditConfig struct {
+	LogFile    string
+	WebhookURL string
+	SyslogTag  string
+}
+
+// adminAuditSink is the destination for encoded audit entries
+type adminAuditSink interface {
+	write(entry []byte) error
+	hangUp() error
+	close() error
+}
+
+// AdminAuditLog encodes admin audit entries as JSON and writes them
+// to all of the configured sinks
+type AdminAuditLog struct {
+	sinks []adminAuditSink
+}
+
+var _ AdminAuditLogger = &AdminAuditLog{}
+
+// InitAdminAuditLogger initializes the admin audit log with all of the
+// configured sinks, nil is returned if no sinks are configured
+func InitAdminAuditLogger(cfg *AdminAuditConfig) (AdminAuditLogger, error) {
+	var sinks []adminAuditSink
+
+	if cfg.LogFile != "" {
+		s, err := newAdminAuditFile(cfg.LogFile)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if cfg.SyslogTag != "" {
+		s, err := newAdminAuditSyslog(cfg.SyslogTag)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if cfg.WebhookURL != "" {
+		s, err := newAdminAuditWebhook(cfg.WebhookURL)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+
+	return &AdminAuditLog{sinks: sinks}, nil
+}
+
+// Log records the result of an admin operation
+func (al *AdminAuditLog) Log(ctx *fiber.Ctx, err error, meta AdminAuditMeta) {
+	entry := AdminAuditEntry{
+		Time:      time.Now().UTC(),
+		RequestID: genID(),
+		Actor:     "-",
+		RemoteIP:  ctx.IP(),
+		Action:    meta.Action,
+		Target:    meta.Target,
+		Result:    AdminAuditResultSuccess,
+	}
+
+	// the admin controller shares the request id with its responses
+	if id, ok := ctx.Locals("requestId").(string); ok {
+		entry.RequestID = id
+	}
+
+	acct, ok := ctx.Locals("account").(auth.Account)
+	if ok {
+		entry.Actor = acct.Access
+		entry.ActorRole = string(acct.Role)
+	}
+
+	if err != nil {
+		entry.Result = AdminAuditResultFailure
+		entry.Error = err.Error()
+	}
+
+	al.write(entry)
+}
+
+func (al *AdminAuditLog) write(entry AdminAuditEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode admin audit entry: %v\n", err)
+		return
+	}
+
+	for _, s := range al.sinks {
+		err := s.write(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write admin audit entry: %v\n", err)
+		}
+	}
+}
+
+// HangUp reopens the file based sinks, typically needed for log rotations
+func (al *AdminAuditLog) HangUp() error {
+	var errs []error
+	for _, s := range al.sinks {
+		errs = append(errs, s.hangUp())
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown closes all of the sinks
+func (al *AdminAuditLog) Shutdown() error {
+	var errs []error
+	for _, s := range al.sinks {
+		errs = append(errs, s.close())
+	}
+	return errors.Join(errs...)
+}
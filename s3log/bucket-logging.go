@@ -0,0 +1,255 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+// BucketLoggingStatus is the bucket access logging configuration, the
+// logging is disabled without LoggingEnabled
+type BucketLoggingStatus struct {
+	XMLName        xml.Name        `xml:"http://s3.amazonaws.com/doc/2006-03-01/ BucketLoggingStatus" json:"-"`
+	LoggingEnabled *LoggingEnabled `xml:"LoggingEnabled,omitempty"`
+}
+
+// LoggingEnabled is the target bucket and the key prefix of the access
+// log objects
+type LoggingEnabled struct {
+	TargetBucket string `xml:"TargetBucket"`
+	TargetPrefix string `xml:"TargetPrefix"`
+}
+
+// ParseBucketLoggingStatus parses the bucket logging configuration xml
+func ParseBucketLoggingStatus(data []byte) (*BucketLoggingStatus, error) {
+	var cfg BucketLoggingStatus
+	if len(data) == 0 {
+		return &cfg, nil
+	}
+	if err := xml.Unmarshal(data, &cfg); err != nil {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+	if cfg.LoggingEnabled != nil && cfg.LoggingEnabled.TargetBucket == "" {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	return &cfg, nil
+}
+
+// BucketLogger delivers the access logs of the buckets with logging
+// enabled into their target buckets. The records are batched and written
+// as objects named like the AWS log objects at every interval, and are
+// also passed to the next audit logger if any.
+type BucketLogger struct {
+	be       backend.Backend
+	next     AuditLogger
+	interval time.Duration
+
+	mu      sync.Mutex
+	targets map[string]cachedTarget
+	batches map[LoggingEnabled]*bytes.Buffer
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type cachedTarget struct {
+	target  *LoggingEnabled
+	expires time.Time
+}
+
+var _ AuditLogger = &BucketLogger{}
+
+// NewBucketLogger starts the log delivery at every interval, the bucket
+// logging configuration changes also apply within an interval
+func NewBucketLogger(be backend.Backend, next AuditLogger, interval time.Duration) *BucketLogger {
+	bl := &BucketLogger{
+		be:       be,
+		next:     next,
+		interval: interval,
+		targets:  make(map[string]cachedTarget),
+		batches:  make(map[LoggingEnabled]*bytes.Buffer),
+		done:     make(chan struct{}),
+	}
+
+	bl.wg.Add(1)
+	go bl.run()
+
+	return bl
+}
+
+// Log adds the request record to the target bucket batch of the request
+// bucket
+func (bl *BucketLogger) Log(ctx *fiber.Ctx, err error, body []byte, meta LogMeta) {
+	if bl.next != nil {
+		bl.next.Log(ctx, err, body, meta)
+	}
+
+	bucket := strings.Split(ctx.Path(), "/")[1]
+	if bucket == "" {
+		return
+	}
+
+	target := bl.target(bucket)
+	if target == nil {
+		return
+	}
+
+	lf := newLogFields(ctx, err, body, meta)
+	// the AWS log records quote the fields that may contain spaces
+	lf.RequestURI = fmt.Sprintf("%q", fmt.Sprintf("%v %v %v",
+		ctx.Method(), lf.RequestURI, string(ctx.Request().Header.Protocol())))
+	lf.Referer = fmt.Sprintf("%q", orDash(lf.Referer))
+	lf.UserAgent = fmt.Sprintf("%q", orDash(lf.UserAgent))
+	line := lf.line()
+
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	batch, ok := bl.batches[*target]
+	if !ok {
+		batch = new(bytes.Buffer)
+		bl.batches[*target] = batch
+	}
+	batch.WriteString(line)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// target returns the logging target of the bucket, the configurations
+// are cached for an interval
+func (bl *BucketLogger) target(bucket string) *LoggingEnabled {
+	bl.mu.Lock()
+	cached, ok := bl.targets[bucket]
+	bl.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.target
+	}
+
+	var target *LoggingEnabled
+	data, err := bl.be.GetBucketLogging(context.Background(), bucket)
+	if err == nil {
+		cfg, err := ParseBucketLoggingStatus(data)
+		if err == nil {
+			target = cfg.LoggingEnabled
+		}
+	}
+
+	bl.mu.Lock()
+	bl.targets[bucket] = cachedTarget{
+		target:  target,
+		expires: time.Now().Add(bl.interval),
+	}
+	bl.mu.Unlock()
+
+	return target
+}
+
+func (bl *BucketLogger) run() {
+	defer bl.wg.Done()
+
+	ticker := time.NewTicker(bl.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bl.done:
+			bl.flush()
+			return
+		case <-ticker.C:
+			bl.flush()
+		}
+	}
+}
+
+// flush writes the batched records into the log objects
+func (bl *BucketLogger) flush() {
+	bl.mu.Lock()
+	batches := bl.batches
+	bl.batches = make(map[LoggingEnabled]*bytes.Buffer)
+	// drop the expired configurations of the buckets no longer used
+	now := time.Now()
+	for bucket, cached := range bl.targets {
+		if now.After(cached.expires) {
+			delete(bl.targets, bucket)
+		}
+	}
+	bl.mu.Unlock()
+
+	for target, batch := range batches {
+		key := fmt.Sprintf("%v%v-%v", target.TargetPrefix,
+			time.Now().UTC().Format("2006-01-02-15-04-05"), genID())
+		size := int64(batch.Len())
+		bucket := target.TargetBucket
+
+		// the log objects are owned by the target bucket owner
+		ctx := context.Background()
+		data, err := bl.be.GetBucketAcl(ctx, &s3.GetBucketAclInput{Bucket: &bucket})
+		if err == nil {
+			acl, err := auth.ParseACL(data)
+			if err == nil {
+				ctx = context.WithValue(ctx, "account", auth.Account{Access: acl.Owner})
+			}
+		}
+
+		_, err = bl.be.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        &bucket,
+			Key:           &key,
+			ContentLength: &size,
+			Body:          batch,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "deliver access log %v/%v: %v\n", bucket, key, err)
+		}
+	}
+}
+
+// HangUp hangs up the next audit logger
+func (bl *BucketLogger) HangUp() error {
+	if bl.next != nil {
+		return bl.next.HangUp()
+	}
+	return nil
+}
+
+// Shutdown delivers the pending records and shuts down the next audit
+// logger
+func (bl *BucketLogger) Shutdown() error {
+	close(bl.done)
+	bl.wg.Wait()
+
+	if bl.next != nil {
+		return bl.next.Shutdown()
+	}
+	return nil
+}
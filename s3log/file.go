@@ -15,16 +15,12 @@
 package s3log
 
 import (
-	"crypto/tls"
 	"fmt"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/versity/versitygw/auth"
-	"github.com/versity/versitygw/s3err"
 )
 
 const (
@@ -63,142 +59,12 @@ func (f *FileLogger) Log(ctx *fiber.Ctx, err error, body []byte, meta LogMeta) {
 		return
 	}
 
-	lf := LogFields{}
-
-	access := "-"
-	reqURI := ctx.OriginalURL()
-	path := strings.Split(ctx.Path(), "/")
-	bucket, object := path[1], strings.Join(path[2:], "/")
-	errorCode := ""
-	httpStatus := 200
-	startTime := ctx.Locals("startTime").(time.Time)
-	tlsConnState := ctx.Context().TLSConnectionState()
-	if tlsConnState != nil {
-		lf.CipherSuite = tls.CipherSuiteName(tlsConnState.CipherSuite)
-		lf.TLSVersion = getTLSVersionName(tlsConnState.Version)
-	}
-
-	if err != nil {
-		serr, ok := err.(s3err.APIError)
-		if ok {
-			errorCode = serr.Code
-			httpStatus = serr.HTTPStatusCode
-		} else {
-			errorCode = err.Error()
-			httpStatus = 500
-		}
-	}
-
-	switch ctx.Locals("account").(type) {
-	case auth.Account:
-		access = ctx.Locals("account").(auth.Account).Access
-	}
-
-	lf.BucketOwner = meta.BucketOwner
-	lf.Bucket = bucket
-	lf.Time = time.Now()
-	lf.RemoteIP = ctx.IP()
-	lf.Requester = access
-	lf.RequestID = genID()
-	lf.Operation = meta.Action
-	lf.Key = object
-	lf.RequestURI = reqURI
-	lf.HttpStatus = httpStatus
-	lf.ErrorCode = errorCode
-	lf.BytesSent = len(body)
-	lf.ObjectSize = meta.ObjectSize
-	lf.TotalTime = time.Since(startTime).Milliseconds()
-	lf.TurnAroundTime = time.Since(startTime).Milliseconds()
-	lf.Referer = ctx.Get("Referer")
-	lf.UserAgent = ctx.Get("User-Agent")
-	lf.VersionID = ctx.Query("versionId")
-	lf.HostID = ctx.Get("X-Amz-Id-2")
-	lf.SignatureVersion = "SigV4"
-	lf.AuthenticationType = "AuthHeader"
-	lf.HostHeader = fmt.Sprintf("s3.%v.amazonaws.com", ctx.Locals("region").(string))
-	lf.AccessPointARN = fmt.Sprintf("arn:aws:s3:::%v", strings.Join(path, "/"))
-	lf.AclRequired = "Yes"
-
+	lf := newLogFields(ctx, err, body, meta)
 	f.writeLog(lf)
 }
 
 func (f *FileLogger) writeLog(lf LogFields) {
-	if lf.BucketOwner == "" {
-		lf.BucketOwner = "-"
-	}
-	if lf.Bucket == "" {
-		lf.Bucket = "-"
-	}
-	if lf.RemoteIP == "" {
-		lf.RemoteIP = "-"
-	}
-	if lf.Requester == "" {
-		lf.Requester = "-"
-	}
-	if lf.Operation == "" {
-		lf.Operation = "-"
-	}
-	if lf.Key == "" {
-		lf.Key = "-"
-	}
-	if lf.RequestURI == "" {
-		lf.RequestURI = "-"
-	}
-	if lf.ErrorCode == "" {
-		lf.ErrorCode = "-"
-	}
-	if lf.Referer == "" {
-		lf.Referer = "-"
-	}
-	if lf.UserAgent == "" {
-		lf.UserAgent = "-"
-	}
-	if lf.VersionID == "" {
-		lf.VersionID = "-"
-	}
-	if lf.HostID == "" {
-		lf.HostID = "-"
-	}
-	if lf.CipherSuite == "" {
-		lf.CipherSuite = "-"
-	}
-	if lf.HostHeader == "" {
-		lf.HostHeader = "-"
-	}
-	if lf.TLSVersion == "" {
-		lf.TLSVersion = "-"
-	}
-
-	log := fmt.Sprintf("%v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v\n",
-		lf.BucketOwner,
-		lf.Bucket,
-		fmt.Sprintf("[%v]", lf.Time.Format(timeFormat)),
-		lf.RemoteIP,
-		lf.Requester,
-		lf.RequestID,
-		lf.Operation,
-		lf.Key,
-		lf.RequestURI,
-		lf.HttpStatus,
-		lf.ErrorCode,
-		lf.BytesSent,
-		lf.ObjectSize,
-		lf.TotalTime,
-		lf.TurnAroundTime,
-		lf.Referer,
-		lf.UserAgent,
-		lf.VersionID,
-		lf.HostID,
-		lf.SignatureVersion,
-		lf.CipherSuite,
-		lf.AuthenticationType,
-		lf.HostHeader,
-		lf.TLSVersion,
-		lf.AccessPointARN,
-		lf.AclRequired,
-	)
-
-	_, err := f.f.WriteString(log)
+	_, err := f.f.WriteString(lf.line())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error writing to log file: %v\n", err)
 		// TODO: do we need to terminate on log error?
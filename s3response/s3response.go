@@ -44,7 +44,7 @@ type ListPartsResult struct {
 	StorageClass string
 
 	PartNumberMarker     int
-	NextPartNumberMarker int
+	NextPartNumberMarker int `xml:",omitempty"`
 	MaxParts             int
 	IsTruncated          bool
 
@@ -54,6 +54,7 @@ type ListPartsResult struct {
 
 type GetObjectAttributesResult struct {
 	ETag         *string
+	Checksum     *types.Checksum
 	LastModified *time.Time
 	ObjectSize   *int64
 	StorageClass *types.StorageClass
@@ -91,6 +92,37 @@ type ListMultipartUploadsResult struct {
 	CommonPrefixes []CommonPrefix
 }
 
+// ListVersionsResult - s3 api list object versions response.
+type ListVersionsResult struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListVersionsResult" json:"-"`
+
+	Name                string
+	Prefix              string
+	KeyMarker           string
+	VersionIdMarker     string
+	NextKeyMarker       string `xml:"NextKeyMarker,omitempty"`
+	NextVersionIdMarker string `xml:"NextVersionIdMarker,omitempty"`
+	Delimiter           string `xml:"Delimiter,omitempty"`
+	EncodingType        string `xml:"EncodingType,omitempty"`
+	MaxKeys             int32
+	IsTruncated         bool
+
+	// List of object versions and delete markers, ordered by key and
+	// then from newest to oldest.
+	Versions      []types.ObjectVersion     `xml:"Version"`
+	DeleteMarkers []types.DeleteMarkerEntry `xml:"DeleteMarker"`
+
+	// Delimed common prefixes.
+	CommonPrefixes []types.CommonPrefix
+}
+
+// GetBucketVersioningOutput - s3 api get bucket versioning response.
+type GetBucketVersioningOutput struct {
+	XMLName   xml.Name                      `xml:"http://s3.amazonaws.com/doc/2006-03-01/ VersioningConfiguration" json:"-"`
+	MFADelete *types.MFADeleteStatus        `xml:"MfaDelete,omitempty"`
+	Status    *types.BucketVersioningStatus `xml:"Status,omitempty"`
+}
+
 // Upload describes in progress multipart upload
 type Upload struct {
 	Key          string
@@ -133,8 +165,20 @@ type TaggingInput struct {
 	TagSet TagSet `xml:"TagSet"`
 }
 
+// LocationConstraint is the GetBucketLocation result, the region is
+// empty for us-east-1
+type LocationConstraint struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LocationConstraint" json:"-"`
+	Value   string   `xml:",chardata"`
+}
+
+type CreateBucketConfiguration struct {
+	LocationConstraint string `xml:"LocationConstraint"`
+}
+
 type DeleteObjects struct {
 	Objects []types.ObjectIdentifier `xml:"Object"`
+	Quiet   *bool                    `xml:"Quiet"`
 }
 
 type DeleteResult struct {
@@ -163,6 +207,76 @@ type Bucket struct {
 	Owner string `json:"owner"`
 }
 
+// BucketUsage is the storage usage of a bucket, MultipartSize is the
+// size of the parts uploaded to the in progress multipart uploads
+type BucketUsage struct {
+	Bucket        string    `json:"bucket"`
+	Objects       int64     `json:"objects"`
+	Size          int64     `json:"size"`
+	MultipartSize int64     `json:"multipartSize"`
+	LastModified  time.Time `json:"lastModified"`
+}
+
+// BucketQuota is the bucket storage quota in bytes and object count,
+// zero is unlimited. The usage accounted against the quota is reported
+// when getting the quota.
+type BucketQuota struct {
+	Size        int64 `json:"size"`
+	Objects     int64 `json:"objects"`
+	UsedSize    int64 `json:"usedSize"`
+	UsedObjects int64 `json:"usedObjects"`
+}
+
+// MultipartUploadInfo is an in progress multipart upload as reported
+// by the admin api, Age is in seconds
+type MultipartUploadInfo struct {
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	UploadID  string    `json:"uploadId"`
+	Owner     string    `json:"owner"`
+	Initiated time.Time `json:"initiated"`
+	Age       int64     `json:"age"`
+	Size      int64     `json:"size"`
+}
+
+// TrashEntry is an object deleted into the bucket trash, it is purged
+// at Expires unless restored first
+type TrashEntry struct {
+	Bucket  string    `json:"bucket"`
+	Key     string    `json:"key"`
+	ID      string    `json:"id"`
+	Size    int64     `json:"size"`
+	Deleted time.Time `json:"deleted"`
+	Expires time.Time `json:"expires"`
+}
+
+// ScrubStatus is the progress of the background scrubber verifying the
+// object data against the stored etags, the counters are totals since
+// startup and Mismatches are the most recent objects failing the check
+type ScrubStatus struct {
+	Running     bool            `json:"running"`
+	Passes      int64           `json:"passes"`
+	LastStart   time.Time       `json:"lastStart"`
+	LastFinish  time.Time       `json:"lastFinish"`
+	Objects     int64           `json:"objects"`
+	Bytes       int64           `json:"bytes"`
+	Skipped     int64           `json:"skipped"`
+	Mismatched  int64           `json:"mismatched"`
+	Quarantined int64           `json:"quarantined"`
+	Mismatches  []ScrubMismatch `json:"mismatches"`
+}
+
+// ScrubMismatch is an object whose data does not match its etag,
+// Quarantined is set when the object was moved out of the bucket
+type ScrubMismatch struct {
+	Bucket      string    `json:"bucket"`
+	Key         string    `json:"key"`
+	ETag        string    `json:"etag"`
+	Computed    string    `json:"computed"`
+	Detected    time.Time `json:"detected"`
+	Quarantined bool      `json:"quarantined"`
+}
+
 type ListAllMyBucketsResult struct {
 	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult" json:"-"`
 	Owner   CanonicalUser
@@ -189,6 +303,14 @@ type CopyObjectResult struct {
 	ETag         string
 }
 
+type PostResponse struct {
+	XMLName  xml.Name `xml:"PostResponse" json:"-"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
 type AccessControlPolicy struct {
 	XMLName           xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ AccessControlPolicy" json:"-"`
 	Owner             CanonicalUser
@@ -217,3 +339,23 @@ type Grantee struct {
 	ID          string
 	DisplayName string
 }
+
+// AssumeRoleWithWebIdentityResponse is the sts response with the
+// temporary credentials minted for a web identity
+type AssumeRoleWithWebIdentityResponse struct {
+	XMLName xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ AssumeRoleWithWebIdentityResponse"`
+
+	Result AssumeRoleWithWebIdentityResult `xml:"AssumeRoleWithWebIdentityResult"`
+}
+
+type AssumeRoleWithWebIdentityResult struct {
+	Credentials                 STSCredentials
+	SubjectFromWebIdentityToken string
+}
+
+type STSCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
@@ -106,10 +106,24 @@ func TestDeleteBucketTagging(s *S3Conf) {
 	DeleteBucketTagging_success(s)
 }
 
+func TestPutBucketLogging(s *S3Conf) {
+	PutBucketLogging_non_existing_target_bucket(s)
+	PutBucketLogging_success(s)
+}
+
+func TestGetBucketLogging(s *S3Conf) {
+	GetBucketLogging_non_existing_bucket(s)
+	GetBucketLogging_unset(s)
+	GetBucketLogging_success(s)
+	GetBucketLogging_disabled(s)
+}
+
 func TestPutObject(s *S3Conf) {
 	PutObject_non_existing_bucket(s)
 	PutObject_special_chars(s)
 	PutObject_invalid_long_tags(s)
+	PutObject_invalid_tags(s)
+	PutObject_encoded_tags(s)
 	PutObject_missing_object_lock_retention_config(s)
 	PutObject_with_object_lock(s)
 	PutObject_success(s)
@@ -181,6 +195,7 @@ func TestCopyObject(s *S3Conf) {
 func TestPutObjectTagging(s *S3Conf) {
 	PutObjectTagging_non_existing_object(s)
 	PutObjectTagging_long_tags(s)
+	PutObjectTagging_invalid_tags(s)
 	PutObjectTagging_success(s)
 }
 
@@ -227,6 +242,7 @@ func TestListParts(s *S3Conf) {
 	ListParts_incorrect_uploadId(s)
 	ListParts_incorrect_object_key(s)
 	ListParts_success(s)
+	ListParts_truncated(s)
 }
 
 func TestListMultipartUploads(s *S3Conf) {
@@ -251,6 +267,8 @@ func TestCompleteMultipartUpload(s *S3Conf) {
 	CompletedMultipartUpload_non_existing_bucket(s)
 	CompleteMultipartUpload_invalid_part_number(s)
 	CompleteMultipartUpload_invalid_ETag(s)
+	CompleteMultipartUpload_small_part(s)
+	CompleteMultipartUpload_invalid_part_order(s)
 	CompleteMultipartUpload_success(s)
 }
 
@@ -328,6 +346,8 @@ func TestPutObjectRetention(s *S3Conf) {
 	PutObjectRetention_disabled_bucket_object_lock_config(s)
 	PutObjectRetention_expired_retain_until_date(s)
 	PutObjectRetention_success(s)
+	PutObjectRetention_compliance_not_weakened(s)
+	PutObjectRetention_governance_bypass(s)
 }
 
 func TestGetObjectRetention(s *S3Conf) {
@@ -356,11 +376,13 @@ func TestGetObjectLegalHold(s *S3Conf) {
 func TestWORMProtection(s *S3Conf) {
 	WORMProtection_bucket_object_lock_configuration_compliance_mode(s)
 	WORMProtection_bucket_object_lock_governance_root_overwrite(s)
+	WORMProtection_bucket_object_lock_default_retention(s)
 	WORMProtection_object_lock_retention_compliance_root_access_denied(s)
 	WORMProtection_object_lock_retention_governance_root_overwrite(s)
 	WORMProtection_object_lock_retention_governance_user_access_denied(s)
 	WORMProtection_object_lock_legal_hold_user_access_denied(s)
-	WORMProtection_object_lock_legal_hold_root_overwrite(s)
+	WORMProtection_object_lock_legal_hold_root_access_denied(s)
+	WORMProtection_object_lock_retention_governance_user_bypass(s)
 }
 
 func TestFullFlow(s *S3Conf) {
@@ -373,6 +395,8 @@ func TestFullFlow(s *S3Conf) {
 	TestPutBucketTagging(s)
 	TestGetBucketTagging(s)
 	TestDeleteBucketTagging(s)
+	TestPutBucketLogging(s)
+	TestGetBucketLogging(s)
 	TestPutObject(s)
 	TestHeadObject(s)
 	TestGetObjectAttributes(s)
@@ -501,9 +525,17 @@ func GetIntTests() IntTests {
 		"DeleteBucketTagging_non_existing_object":                            DeleteBucketTagging_non_existing_object,
 		"DeleteBucketTagging_success_status":                                 DeleteBucketTagging_success_status,
 		"DeleteBucketTagging_success":                                        DeleteBucketTagging_success,
+		"PutBucketLogging_non_existing_target_bucket":                        PutBucketLogging_non_existing_target_bucket,
+		"PutBucketLogging_success":                                           PutBucketLogging_success,
+		"GetBucketLogging_non_existing_bucket":                               GetBucketLogging_non_existing_bucket,
+		"GetBucketLogging_unset":                                             GetBucketLogging_unset,
+		"GetBucketLogging_success":                                           GetBucketLogging_success,
+		"GetBucketLogging_disabled":                                          GetBucketLogging_disabled,
 		"PutObject_non_existing_bucket":                                      PutObject_non_existing_bucket,
 		"PutObject_special_chars":                                            PutObject_special_chars,
 		"PutObject_invalid_long_tags":                                        PutObject_invalid_long_tags,
+		"PutObject_invalid_tags":                                             PutObject_invalid_tags,
+		"PutObject_encoded_tags":                                             PutObject_encoded_tags,
 		"PutObject_success":                                                  PutObject_success,
 		"HeadObject_non_existing_object":                                     HeadObject_non_existing_object,
 		"HeadObject_invalid_part_number":                                     HeadObject_invalid_part_number,
@@ -545,6 +577,7 @@ func GetIntTests() IntTests {
 		"CopyObject_success":                                                 CopyObject_success,
 		"PutObjectTagging_non_existing_object":                               PutObjectTagging_non_existing_object,
 		"PutObjectTagging_long_tags":                                         PutObjectTagging_long_tags,
+		"PutObjectTagging_invalid_tags":                                      PutObjectTagging_invalid_tags,
 		"PutObjectTagging_success":                                           PutObjectTagging_success,
 		"GetObjectTagging_non_existing_object":                               GetObjectTagging_non_existing_object,
 		"GetObjectTagging_unset_tags":                                        GetObjectTagging_unset_tags,
@@ -573,6 +606,7 @@ func GetIntTests() IntTests {
 		"ListParts_incorrect_uploadId":                                       ListParts_incorrect_uploadId,
 		"ListParts_incorrect_object_key":                                     ListParts_incorrect_object_key,
 		"ListParts_success":                                                  ListParts_success,
+		"ListParts_truncated":                                                ListParts_truncated,
 		"ListMultipartUploads_non_existing_bucket":                           ListMultipartUploads_non_existing_bucket,
 		"ListMultipartUploads_empty_result":                                  ListMultipartUploads_empty_result,
 		"ListMultipartUploads_invalid_max_uploads":                           ListMultipartUploads_invalid_max_uploads,
@@ -588,6 +622,8 @@ func GetIntTests() IntTests {
 		"CompletedMultipartUpload_non_existing_bucket":                       CompletedMultipartUpload_non_existing_bucket,
 		"CompleteMultipartUpload_invalid_part_number":                        CompleteMultipartUpload_invalid_part_number,
 		"CompleteMultipartUpload_invalid_ETag":                               CompleteMultipartUpload_invalid_ETag,
+		"CompleteMultipartUpload_small_part":                                 CompleteMultipartUpload_small_part,
+		"CompleteMultipartUpload_invalid_part_order":                         CompleteMultipartUpload_invalid_part_order,
 		"CompleteMultipartUpload_success":                                    CompleteMultipartUpload_success,
 		"PutBucketAcl_non_existing_bucket":                                   PutBucketAcl_non_existing_bucket,
 		"PutBucketAcl_invalid_acl_canned_and_acp":                            PutBucketAcl_invalid_acl_canned_and_acp,
@@ -641,6 +677,8 @@ func GetIntTests() IntTests {
 		"PutObjectRetention_disabled_bucket_object_lock_config":              PutObjectRetention_disabled_bucket_object_lock_config,
 		"PutObjectRetention_expired_retain_until_date":                       PutObjectRetention_expired_retain_until_date,
 		"PutObjectRetention_success":                                         PutObjectRetention_success,
+		"PutObjectRetention_compliance_not_weakened":                         PutObjectRetention_compliance_not_weakened,
+		"PutObjectRetention_governance_bypass":                               PutObjectRetention_governance_bypass,
 		"GetObjectRetention_non_existing_bucket":                             GetObjectRetention_non_existing_bucket,
 		"GetObjectRetention_non_existing_object":                             GetObjectRetention_non_existing_object,
 		"GetObjectRetention_unset_config":                                    GetObjectRetention_unset_config,
@@ -657,11 +695,13 @@ func GetIntTests() IntTests {
 		"GetObjectLegalHold_success":                                         GetObjectLegalHold_success,
 		"WORMProtection_bucket_object_lock_configuration_compliance_mode":    WORMProtection_bucket_object_lock_configuration_compliance_mode,
 		"WORMProtection_bucket_object_lock_governance_root_overwrite":        WORMProtection_bucket_object_lock_governance_root_overwrite,
+		"WORMProtection_bucket_object_lock_default_retention":                WORMProtection_bucket_object_lock_default_retention,
 		"WORMProtection_object_lock_retention_compliance_root_access_denied": WORMProtection_object_lock_retention_compliance_root_access_denied,
 		"WORMProtection_object_lock_retention_governance_root_overwrite":     WORMProtection_object_lock_retention_governance_root_overwrite,
 		"WORMProtection_object_lock_retention_governance_user_access_denied": WORMProtection_object_lock_retention_governance_user_access_denied,
 		"WORMProtection_object_lock_legal_hold_user_access_denied":           WORMProtection_object_lock_legal_hold_user_access_denied,
-		"WORMProtection_object_lock_legal_hold_root_overwrite":               WORMProtection_object_lock_legal_hold_root_overwrite,
+		"WORMProtection_object_lock_legal_hold_root_access_denied":           WORMProtection_object_lock_legal_hold_root_access_denied,
+		"WORMProtection_object_lock_retention_governance_user_bypass":        WORMProtection_object_lock_retention_governance_user_bypass,
 		"PutObject_overwrite_dir_obj":                                        PutObject_overwrite_dir_obj,
 		"PutObject_overwrite_file_obj":                                       PutObject_overwrite_file_obj,
 		"PutObject_dir_obj_with_data":                                        PutObject_dir_obj_with_data,
@@ -2387,6 +2387,195 @@ func DeleteBucketTagging_success(s *S3Conf) error {
 	})
 }
 
+func PutBucketLogging_non_existing_target_bucket(s *S3Conf) error {
+	testName := "PutBucketLogging_non_existing_target_bucket"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketLogging(ctx, &s3.PutBucketLoggingInput{
+			Bucket: &bucket,
+			BucketLoggingStatus: &types.BucketLoggingStatus{
+				LoggingEnabled: &types.LoggingEnabled{
+					TargetBucket: getPtr(getBucketName()),
+					TargetPrefix: getPtr("logs/"),
+				},
+			},
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrInvalidTargetBucketForLogging)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func PutBucketLogging_success(s *S3Conf) error {
+	testName := "PutBucketLogging_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		target := getBucketName()
+		err := setup(s, target)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketLogging(ctx, &s3.PutBucketLoggingInput{
+			Bucket: &bucket,
+			BucketLoggingStatus: &types.BucketLoggingStatus{
+				LoggingEnabled: &types.LoggingEnabled{
+					TargetBucket: &target,
+					TargetPrefix: getPtr("logs/"),
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		return teardown(s, target)
+	})
+}
+
+func GetBucketLogging_non_existing_bucket(s *S3Conf) error {
+	testName := "GetBucketLogging_non_existing_bucket"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.GetBucketLogging(ctx, &s3.GetBucketLoggingInput{
+			Bucket: getPtr(getBucketName()),
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func GetBucketLogging_unset(s *S3Conf) error {
+	testName := "GetBucketLogging_unset"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetBucketLogging(ctx, &s3.GetBucketLoggingInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if out.LoggingEnabled != nil {
+			return fmt.Errorf("expected logging to be disabled, instead got target %v",
+				getString(out.LoggingEnabled.TargetBucket))
+		}
+
+		return nil
+	})
+}
+
+func GetBucketLogging_success(s *S3Conf) error {
+	testName := "GetBucketLogging_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		target := getBucketName()
+		err := setup(s, target)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketLogging(ctx, &s3.PutBucketLoggingInput{
+			Bucket: &bucket,
+			BucketLoggingStatus: &types.BucketLoggingStatus{
+				LoggingEnabled: &types.LoggingEnabled{
+					TargetBucket: &target,
+					TargetPrefix: getPtr("logs/"),
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetBucketLogging(ctx, &s3.GetBucketLoggingInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if out.LoggingEnabled == nil {
+			return fmt.Errorf("expected logging to be enabled")
+		}
+		if getString(out.LoggingEnabled.TargetBucket) != target {
+			return fmt.Errorf("expected the target bucket to be %v, instead got %v",
+				target, getString(out.LoggingEnabled.TargetBucket))
+		}
+		if getString(out.LoggingEnabled.TargetPrefix) != "logs/" {
+			return fmt.Errorf("expected the target prefix to be logs/, instead got %v",
+				getString(out.LoggingEnabled.TargetPrefix))
+		}
+
+		return teardown(s, target)
+	})
+}
+
+func GetBucketLogging_disabled(s *S3Conf) error {
+	testName := "GetBucketLogging_disabled"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		target := getBucketName()
+		err := setup(s, target)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketLogging(ctx, &s3.PutBucketLoggingInput{
+			Bucket: &bucket,
+			BucketLoggingStatus: &types.BucketLoggingStatus{
+				LoggingEnabled: &types.LoggingEnabled{
+					TargetBucket: &target,
+					TargetPrefix: getPtr("logs/"),
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		// an empty logging status disables the logging
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketLogging(ctx, &s3.PutBucketLoggingInput{
+			Bucket:              &bucket,
+			BucketLoggingStatus: &types.BucketLoggingStatus{},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetBucketLogging(ctx, &s3.GetBucketLoggingInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if out.LoggingEnabled != nil {
+			return fmt.Errorf("expected logging to be disabled, instead got target %v",
+				getString(out.LoggingEnabled.TargetBucket))
+		}
+
+		return teardown(s, target)
+	})
+}
+
 func PutObject_non_existing_bucket(s *S3Conf) error {
 	testName := "PutObject_non_existing_bucket"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
@@ -2445,6 +2634,79 @@ func PutObject_invalid_long_tags(s *S3Conf) error {
 	})
 }
 
+func PutObject_invalid_tags(s *S3Conf) error {
+	testName := "PutObject_invalid_tags"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		key := "my-obj"
+		var tags []string
+		for i := 0; i < 11; i++ {
+			tags = append(tags, fmt.Sprintf("key%v=val", i))
+		}
+
+		for _, test := range []struct {
+			tagging string
+			err     s3err.APIError
+		}{
+			{strings.Join(tags, "&"), s3err.GetAPIError(s3err.ErrTooManyObjectTags)},
+			{"key=val1&key=val2", s3err.GetAPIError(s3err.ErrDuplicateTagKey)},
+			{"key%23=val", s3err.GetAPIError(s3err.ErrInvalidTag)},
+			{"key=val%3F", s3err.GetAPIError(s3err.ErrInvalidTag)},
+		} {
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			_, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+				Bucket:  &bucket,
+				Key:     &key,
+				Tagging: &test.tagging,
+			})
+			cancel()
+			if err := checkApiErr(err, test.err); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func PutObject_encoded_tags(s *S3Conf) error {
+	testName := "PutObject_encoded_tags"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		key := "my-obj"
+		tagging := "my%20key=some%2Fval%2Bue&key2=value+2"
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:  &bucket,
+			Key:     &key,
+			Tagging: &tagging,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+			Bucket: &bucket,
+			Key:    &key,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		expected := []types.Tag{
+			{Key: getPtr("key2"), Value: getPtr("value 2")},
+			{Key: getPtr("my key"), Value: getPtr("some/val+ue")},
+		}
+		if !areTagsSame(expected, out.TagSet) {
+			return fmt.Errorf("expected tags %v, instead got %v", expected, out.TagSet)
+		}
+
+		return nil
+	})
+}
+
 func PutObject_missing_object_lock_retention_config(s *S3Conf) error {
 	testName := "PutObject_missing_object_lock_retention_config"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
@@ -3974,6 +4236,44 @@ func PutObjectTagging_long_tags(s *S3Conf) error {
 	})
 }
 
+func PutObjectTagging_invalid_tags(s *S3Conf) error {
+	testName := "PutObjectTagging_invalid_tags"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		err := putObjects(s3client, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		var tooMany []types.Tag
+		for i := 0; i < 11; i++ {
+			tooMany = append(tooMany, types.Tag{Key: getPtr(fmt.Sprintf("key%v", i)), Value: getPtr("val")})
+		}
+
+		for _, test := range []struct {
+			tags []types.Tag
+			err  s3err.APIError
+		}{
+			{tooMany, s3err.GetAPIError(s3err.ErrTooManyObjectTags)},
+			{[]types.Tag{{Key: getPtr("key"), Value: getPtr("val1")}, {Key: getPtr("key"), Value: getPtr("val2")}}, s3err.GetAPIError(s3err.ErrDuplicateTagKey)},
+			{[]types.Tag{{Key: getPtr("key*"), Value: getPtr("val")}}, s3err.GetAPIError(s3err.ErrInvalidTag)},
+			{[]types.Tag{{Key: getPtr(""), Value: getPtr("val")}}, s3err.GetAPIError(s3err.ErrInvalidTag)},
+		} {
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			_, err = s3client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+				Bucket:  &bucket,
+				Key:     &obj,
+				Tagging: &types.Tagging{TagSet: test.tags}})
+			cancel()
+			if err := checkApiErr(err, test.err); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 func PutObjectTagging_success(s *S3Conf) error {
 	testName := "PutObjectTagging_success"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
@@ -4856,6 +5156,72 @@ func ListParts_success(s *S3Conf) error {
 	})
 }
 
+func ListParts_truncated(s *S3Conf) error {
+	testName := "ListParts_truncated"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		out, err := createMp(s3client, bucket, obj)
+		if err != nil {
+			return err
+		}
+
+		parts, err := uploadParts(s3client, 5*1024, 5, bucket, obj, *out.UploadId)
+		if err != nil {
+			return err
+		}
+
+		maxParts := int32(3)
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		res, err := s3client.ListParts(ctx, &s3.ListPartsInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: out.UploadId,
+			MaxParts: &maxParts,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if res.IsTruncated == nil || !*res.IsTruncated {
+			return fmt.Errorf("expected the listing to be truncated")
+		}
+		if res.StorageClass != types.StorageClassStandard {
+			return fmt.Errorf("expected storage class to be %v, instead got %v", types.StorageClassStandard, res.StorageClass)
+		}
+		if res.NextPartNumberMarker == nil || *res.NextPartNumberMarker != fmt.Sprint(*parts[2].PartNumber) {
+			return fmt.Errorf("expected next part number marker to be %v, instead got %v", *parts[2].PartNumber, getString(res.NextPartNumberMarker))
+		}
+		if ok := compareParts(parts[:3], res.Parts); !ok {
+			return fmt.Errorf("expected parts %+v, instead got %+v", parts[:3], res.Parts)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		res, err = s3client.ListParts(ctx, &s3.ListPartsInput{
+			Bucket:           &bucket,
+			Key:              &obj,
+			UploadId:         out.UploadId,
+			PartNumberMarker: res.NextPartNumberMarker,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if res.IsTruncated != nil && *res.IsTruncated {
+			return fmt.Errorf("expected the listing not to be truncated")
+		}
+		if res.NextPartNumberMarker != nil && *res.NextPartNumberMarker != "" {
+			return fmt.Errorf("expected empty next part number marker, instead got %v", *res.NextPartNumberMarker)
+		}
+		if ok := compareParts(parts[3:], res.Parts); !ok {
+			return fmt.Errorf("expected parts %+v, instead got %+v", parts[3:], res.Parts)
+		}
+
+		return nil
+	})
+}
+
 func ListMultipartUploads_non_existing_bucket(s *S3Conf) error {
 	testName := "ListMultipartUploads_non_existing_bucket"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
@@ -5299,8 +5665,8 @@ func CompleteMultipartUpload_invalid_ETag(s *S3Conf) error {
 	})
 }
 
-func CompleteMultipartUpload_success(s *S3Conf) error {
-	testName := "CompleteMultipartUpload_success"
+func CompleteMultipartUpload_small_part(s *S3Conf) error {
+	testName := "CompleteMultipartUpload_small_part"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
 		obj := "my-obj"
 		out, err := createMp(s3client, bucket, obj)
@@ -5308,8 +5674,7 @@ func CompleteMultipartUpload_success(s *S3Conf) error {
 			return err
 		}
 
-		objSize := 5 * 1024 * 1024
-		parts, err := uploadParts(s3client, objSize, 5, bucket, obj, *out.UploadId)
+		parts, err := uploadParts(s3client, 5*1024*1024, 5, bucket, obj, *out.UploadId)
 		if err != nil {
 			return err
 		}
@@ -5323,7 +5688,7 @@ func CompleteMultipartUpload_success(s *S3Conf) error {
 		}
 
 		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
-		res, err := s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		_, err = s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
 			Bucket:   &bucket,
 			Key:      &obj,
 			UploadId: out.UploadId,
@@ -5332,12 +5697,94 @@ func CompleteMultipartUpload_success(s *S3Conf) error {
 			},
 		})
 		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrEntityTooSmall)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func CompleteMultipartUpload_invalid_part_order(s *S3Conf) error {
+	testName := "CompleteMultipartUpload_invalid_part_order"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		out, err := createMp(s3client, bucket, obj)
 		if err != nil {
 			return err
 		}
 
-		if *res.Key != obj {
-			return fmt.Errorf("expected object key to be %v, instead got %v", obj, *res.Key)
+		parts, err := uploadParts(s3client, 1024, 2, bucket, obj, *out.UploadId)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: out.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: []types.CompletedPart{
+					{
+						ETag:       parts[1].ETag,
+						PartNumber: parts[1].PartNumber,
+					},
+					{
+						ETag:       parts[0].ETag,
+						PartNumber: parts[0].PartNumber,
+					},
+				},
+			},
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrInvalidPartOrder)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func CompleteMultipartUpload_success(s *S3Conf) error {
+	testName := "CompleteMultipartUpload_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		out, err := createMp(s3client, bucket, obj)
+		if err != nil {
+			return err
+		}
+
+		objSize := 25 * 1024 * 1024
+		parts, err := uploadParts(s3client, objSize, 5, bucket, obj, *out.UploadId)
+		if err != nil {
+			return err
+		}
+
+		compParts := []types.CompletedPart{}
+		for _, el := range parts {
+			compParts = append(compParts, types.CompletedPart{
+				ETag:       el.ETag,
+				PartNumber: el.PartNumber,
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		res, err := s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: out.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: compParts,
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if *res.Key != obj {
+			return fmt.Errorf("expected object key to be %v, instead got %v", obj, *res.Key)
 		}
 
 		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
@@ -6666,6 +7113,144 @@ func PutObjectRetention_success(s *S3Conf) error {
 	}, withLock())
 }
 
+func PutObjectRetention_compliance_not_weakened(s *S3Conf) error {
+	testName := "PutObjectRetention_compliance_not_weakened"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		if err := changeBucketObjectLockStatus(s3client, bucket, true); err != nil {
+			return err
+		}
+
+		key := "my-obj"
+		if err := putObjects(s3client, []string{key}, bucket); err != nil {
+			return err
+		}
+
+		date := time.Now().Add(time.Hour * 3)
+		earlier := time.Now().Add(time.Hour)
+		later := time.Now().Add(time.Hour * 5)
+
+		putRetention := func(retention *types.ObjectLockRetention, bypass bool) error {
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			_, err := s3client.PutObjectRetention(ctx, &s3.PutObjectRetentionInput{
+				Bucket:                    &bucket,
+				Key:                       &key,
+				Retention:                 retention,
+				BypassGovernanceRetention: &bypass,
+			})
+			cancel()
+			return err
+		}
+
+		err := putRetention(&types.ObjectLockRetention{
+			Mode:            types.ObjectLockRetentionModeCompliance,
+			RetainUntilDate: &date,
+		}, false)
+		if err != nil {
+			return err
+		}
+
+		for _, retention := range []*types.ObjectLockRetention{
+			{Mode: types.ObjectLockRetentionModeCompliance, RetainUntilDate: &earlier},
+			{Mode: types.ObjectLockRetentionModeGovernance, RetainUntilDate: &later},
+			{},
+		} {
+			err := putRetention(retention, true)
+			if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectLocked)); err != nil {
+				return err
+			}
+		}
+
+		// extending the retention is allowed
+		err = putRetention(&types.ObjectLockRetention{
+			Mode:            types.ObjectLockRetentionModeCompliance,
+			RetainUntilDate: &later,
+		}, false)
+		if err != nil {
+			return err
+		}
+
+		if err := changeBucketObjectLockStatus(s3client, bucket, false); err != nil {
+			return err
+		}
+
+		return nil
+	}, withLock())
+}
+
+func PutObjectRetention_governance_bypass(s *S3Conf) error {
+	testName := "PutObjectRetention_governance_bypass"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		if err := changeBucketObjectLockStatus(s3client, bucket, true); err != nil {
+			return err
+		}
+
+		key := "my-obj"
+		if err := putObjects(s3client, []string{key}, bucket); err != nil {
+			return err
+		}
+
+		date := time.Now().Add(time.Hour * 3)
+		earlier := time.Now().Add(time.Hour)
+
+		putRetention := func(retention *types.ObjectLockRetention, bypass bool) error {
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			_, err := s3client.PutObjectRetention(ctx, &s3.PutObjectRetentionInput{
+				Bucket:                    &bucket,
+				Key:                       &key,
+				Retention:                 retention,
+				BypassGovernanceRetention: &bypass,
+			})
+			cancel()
+			return err
+		}
+
+		err := putRetention(&types.ObjectLockRetention{
+			Mode:            types.ObjectLockRetentionModeGovernance,
+			RetainUntilDate: &date,
+		}, false)
+		if err != nil {
+			return err
+		}
+
+		shortened := &types.ObjectLockRetention{
+			Mode:            types.ObjectLockRetentionModeGovernance,
+			RetainUntilDate: &earlier,
+		}
+		err = putRetention(shortened, false)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectLocked)); err != nil {
+			return err
+		}
+		if err := putRetention(shortened, true); err != nil {
+			return err
+		}
+
+		// removing the retention also requires the bypass
+		err = putRetention(&types.ObjectLockRetention{}, false)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectLocked)); err != nil {
+			return err
+		}
+		if err := putRetention(&types.ObjectLockRetention{}, true); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.GetObjectRetention(ctx, &s3.GetObjectRetentionInput{
+			Bucket: &bucket,
+			Key:    &key,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)); err != nil {
+			return err
+		}
+
+		if err := changeBucketObjectLockStatus(s3client, bucket, false); err != nil {
+			return err
+		}
+
+		return nil
+	}, withLock())
+}
+
 func GetObjectRetention_non_existing_bucket(s *S3Conf) error {
 	testName := "GetObjectRetention_non_existing_bucket"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
@@ -7104,11 +7689,100 @@ func WORMProtection_bucket_object_lock_governance_root_overwrite(s *S3Conf) erro
 			return err
 		}
 
-		// overwrite the object
+		if err := checkGovernanceBypass(s3client, bucket, object); err != nil {
+			return err
+		}
+
+		if err := changeBucketObjectLockStatus(s3client, bucket, false); err != nil {
+			return err
+		}
+
+		return nil
+	}, withLock())
+}
+
+func WORMProtection_bucket_object_lock_default_retention(s *S3Conf) error {
+	testName := "WORMProtection_bucket_object_lock_default_retention"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		var days int32 = 2
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutObjectLockConfiguration(ctx, &s3.PutObjectLockConfigurationInput{
+			Bucket: &bucket,
+			ObjectLockConfiguration: &types.ObjectLockConfiguration{
+				ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+				Rule: &types.ObjectLockRule{
+					DefaultRetention: &types.DefaultRetention{
+						Mode: types.ObjectLockRetentionModeGovernance,
+						Days: &days,
+					},
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		// allow for clock skew and second precision of the stored dates
+		before := time.Now().AddDate(0, 0, int(days)).Add(-time.Minute)
+
+		object, mpObject := "my-obj", "my-mp-obj"
 		if err := putObjects(s3client, []string{object}, bucket); err != nil {
 			return err
 		}
 
+		out, err := createMp(s3client, bucket, mpObject)
+		if err != nil {
+			return err
+		}
+		parts, err := uploadParts(s3client, 5*1024, 1, bucket, mpObject, *out.UploadId)
+		if err != nil {
+			return err
+		}
+		compParts := []types.CompletedPart{}
+		for _, el := range parts {
+			compParts = append(compParts, types.CompletedPart{
+				ETag:       el.ETag,
+				PartNumber: el.PartNumber,
+			})
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &mpObject,
+			UploadId: out.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: compParts,
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		after := time.Now().AddDate(0, 0, int(days)).Add(time.Minute)
+
+		for _, obj := range []string{object, mpObject} {
+			ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+			resp, err := s3client.GetObjectRetention(ctx, &s3.GetObjectRetentionInput{
+				Bucket: &bucket,
+				Key:    &obj,
+			})
+			cancel()
+			if err != nil {
+				return err
+			}
+			if resp.Retention.Mode != types.ObjectLockRetentionModeGovernance {
+				return fmt.Errorf("expected %v retention mode to be %v, instead got %v",
+					obj, types.ObjectLockRetentionModeGovernance, resp.Retention.Mode)
+			}
+			date := resp.Retention.RetainUntilDate
+			if date == nil || date.Before(before) || date.After(after) {
+				return fmt.Errorf("expected %v retain until date between %v and %v, instead got %v",
+					obj, before, after, date)
+			}
+		}
+
 		if err := changeBucketObjectLockStatus(s3client, bucket, false); err != nil {
 			return err
 		}
@@ -7184,7 +7858,7 @@ func WORMProtection_object_lock_retention_governance_root_overwrite(s *S3Conf) e
 			return err
 		}
 
-		if err := putObjects(s3client, []string{object}, bucket); err != nil {
+		if err := checkGovernanceBypass(s3client, bucket, object); err != nil {
 			return err
 		}
 
@@ -7308,8 +7982,8 @@ func WORMProtection_object_lock_legal_hold_user_access_denied(s *S3Conf) error {
 	}, withLock())
 }
 
-func WORMProtection_object_lock_legal_hold_root_overwrite(s *S3Conf) error {
-	testName := "WORMProtection_object_lock_legal_hold_root_overwrite"
+func WORMProtection_object_lock_legal_hold_root_access_denied(s *S3Conf) error {
+	testName := "WORMProtection_object_lock_legal_hold_root_access_denied"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
 		if err := changeBucketObjectLockStatus(s3client, bucket, true); err != nil {
 			return err
@@ -7334,10 +8008,117 @@ func WORMProtection_object_lock_legal_hold_root_overwrite(s *S3Conf) error {
 			return err
 		}
 
+		if err := checkWORMProtection(s3client, bucket, object); err != nil {
+			return err
+		}
+
+		// a legal hold can not be bypassed
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:                    &bucket,
+			Key:                       &object,
+			BypassGovernanceRetention: getBoolPtr(true),
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectLocked)); err != nil {
+			return err
+		}
+
+		if err := changeBucketObjectLockStatus(s3client, bucket, false); err != nil {
+			return err
+		}
+
+		return nil
+	}, withLock())
+}
+
+func WORMProtection_object_lock_retention_governance_user_bypass(s *S3Conf) error {
+	testName := "WORMProtection_object_lock_retention_governance_user_bypass"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		if err := changeBucketObjectLockStatus(s3client, bucket, true); err != nil {
+			return err
+		}
+
+		object := "my-obj"
+
 		if err := putObjects(s3client, []string{object}, bucket); err != nil {
 			return err
 		}
 
+		date := time.Now().Add(time.Hour * 3)
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutObjectRetention(ctx, &s3.PutObjectRetentionInput{
+			Bucket: &bucket,
+			Key:    &object,
+			Retention: &types.ObjectLockRetention{
+				Mode:            types.ObjectLockRetentionModeGovernance,
+				RetainUntilDate: &date,
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		usr := user{
+			access: "grt1",
+			secret: "grt1secret",
+			role:   "user",
+		}
+		if err := createUsers(s, []user{usr}); err != nil {
+			return err
+		}
+		if err := changeBucketsOwner(s, []string{bucket}, usr.access); err != nil {
+			return err
+		}
+
+		userClient := getUserS3Client(usr, s)
+
+		// the bypass header is not enough without the permission
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:                    &bucket,
+			Key:                       &object,
+			BypassGovernanceRetention: getBoolPtr(true),
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectLocked)); err != nil {
+			return err
+		}
+
+		doc := genPolicyDoc("Allow", fmt.Sprintf(`"%v"`, usr.access), `"s3:BypassGovernanceRetention"`, fmt.Sprintf(`"arn:aws:s3:::%v/*"`, bucket))
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		// the permission is not enough without the bypass header
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: &bucket,
+			Key:    &object,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectLocked)); err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:                    &bucket,
+			Key:                       &object,
+			BypassGovernanceRetention: getBoolPtr(true),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
 		if err := changeBucketObjectLockStatus(s3client, bucket, false); err != nil {
 			return err
 		}
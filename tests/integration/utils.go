@@ -37,6 +37,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/versity/versitygw/s3err"
 	"github.com/versity/versitygw/s3response"
 )
@@ -411,6 +412,10 @@ func getPtr(str string) *string {
 	return &str
 }
 
+func getBoolPtr(b bool) *bool {
+	return &b
+}
+
 func areMapsSame(mp1, mp2 map[string]string) bool {
 	if len(mp1) != len(mp2) {
 		return false
@@ -696,6 +701,47 @@ func changeBucketObjectLockStatus(client *s3.Client, bucket string, status bool)
 	return nil
 }
 
+// checkGovernanceBypass verifies that an object under GOVERNANCE retention
+// can only be overwritten and deleted with the bypass governance retention
+// header set
+func checkGovernanceBypass(client *s3.Client, bucket, object string) error {
+	if err := checkWORMProtection(client, bucket, object); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+	_, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: &bucket,
+		Key:    &object,
+	}, withBypassGovernance)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+	_, err = client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: &bucket,
+		Delete: &types.Delete{
+			Objects: []types.ObjectIdentifier{
+				{
+					Key: &object,
+				},
+			},
+		},
+		BypassGovernanceRetention: getBoolPtr(true),
+	})
+	cancel()
+	return err
+}
+
+// withBypassGovernance adds the bypass governance retention header to
+// requests that have no input field for it
+func withBypassGovernance(o *s3.Options) {
+	o.APIOptions = append(o.APIOptions,
+		smithyhttp.AddHeaderValue("X-Amz-Bypass-Governance-Retention", "true"))
+}
+
 func checkWORMProtection(client *s3.Client, bucket, object string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
 	_, err := client.PutObject(ctx, &s3.PutObjectInput{